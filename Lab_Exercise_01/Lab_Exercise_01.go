@@ -2,7 +2,80 @@
 
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Department types
+type Department int
+
+const (
+	IT Department = iota
+	HR
+	Finance
+	Operations
+	Marketing
+)
+
+var departmentNames = [...]string{"IT", "HR", "Finance", "Operations", "Marketing"}
+
+// AllDepartments returns every department in order
+func AllDepartments() []Department {
+	all := make([]Department, len(departmentNames))
+	for i := range all {
+		all[i] = Department(i)
+	}
+	return all
+}
+
+// Valid reports whether d is one of the departments
+func (d Department) Valid() bool {
+	return d >= 0 && int(d) < len(departmentNames)
+}
+
+func (d Department) String() string {
+	if !d.Valid() {
+		return "Unknown"
+	}
+	return departmentNames[d]
+}
+
+// ParseDepartment returns the department with a name, ignoring case
+func ParseDepartment(name string) (Department, error) {
+	for _, d := range AllDepartments() {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return -1, fmt.Errorf("invalid department %q: must be one of %v", name, AllDepartments())
+}
+
+// Set parses a department name, so that *Department is a flag.Value
+func (d *Department) Set(name string) error {
+	dept, err := ParseDepartment(name)
+	if err != nil {
+		return err
+	}
+	*d = dept
+	return nil
+}
+
+func (d Department) MarshalJSON() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("invalid department %d", int(d))
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Department) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid department %s: must be a name", data)
+	}
+	return d.Set(name)
+}
 
 func main() {
 	fmt.Print("Testing")
@@ -12,7 +85,8 @@ func main() {
 		employeeID     int32
 		salary         float64
 		isActive       bool
-		department     string
+		department     Department
+		departmentName string
 		yearsOfService int
 		choice         int
 		isEmployeeSet  bool = false
@@ -47,7 +121,11 @@ func main() {
 				fmt.Print("Enter Years of Service : ")
 				fmt.Scan(&yearsOfService)
 				fmt.Print("Enter Department       : ")
-				fmt.Scan(&department)
+				fmt.Scan(&departmentName)
+				if err := department.Set(departmentName); err != nil {
+					fmt.Println("\n❌", err)
+					continue
+				}
 				isActive = true
 				isEmployeeSet = true
 				fmt.Println("\n✅ Employee added successfully!")
@@ -82,7 +160,11 @@ func main() {
 				fmt.Print("Enter new Salary       : ")
 				fmt.Scan(&salary)
 				fmt.Print("Enter new Department   : ")
-				fmt.Scan(&department)
+				fmt.Scan(&departmentName)
+				if err := department.Set(departmentName); err != nil {
+					fmt.Println("\n❌", err)
+					continue
+				}
 				fmt.Print("Enter new Service Years: ")
 				fmt.Scan(&yearsOfService)
 				fmt.Println("\n✅ Employee updated successfully!")
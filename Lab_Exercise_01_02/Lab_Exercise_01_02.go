@@ -2,7 +2,80 @@
 
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Department types
+type Department int
+
+const (
+	IT Department = iota
+	HR
+	Finance
+	Operations
+	Marketing
+)
+
+var departmentNames = [...]string{"IT", "HR", "Finance", "Operations", "Marketing"}
+
+// AllDepartments returns every department in order
+func AllDepartments() []Department {
+	all := make([]Department, len(departmentNames))
+	for i := range all {
+		all[i] = Department(i)
+	}
+	return all
+}
+
+// Valid reports whether d is one of the departments
+func (d Department) Valid() bool {
+	return d >= 0 && int(d) < len(departmentNames)
+}
+
+func (d Department) String() string {
+	if !d.Valid() {
+		return "Unknown"
+	}
+	return departmentNames[d]
+}
+
+// ParseDepartment returns the department with a name, ignoring case
+func ParseDepartment(name string) (Department, error) {
+	for _, d := range AllDepartments() {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return -1, fmt.Errorf("invalid department %q: must be one of %v", name, AllDepartments())
+}
+
+// Set parses a department name, so that *Department is a flag.Value
+func (d *Department) Set(name string) error {
+	dept, err := ParseDepartment(name)
+	if err != nil {
+		return err
+	}
+	*d = dept
+	return nil
+}
+
+func (d Department) MarshalJSON() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("invalid department %d", int(d))
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Department) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid department %s: must be a name", data)
+	}
+	return d.Set(name)
+}
 
 func main() {
 
@@ -19,21 +92,13 @@ func main() {
 		BaseEmployeeID = 100
 	)
 
-	// Department types
-	const (
-		IT         = "IT"
-		HR         = "HR"
-		Finance    = "Finance"
-		Operations = "Operations"
-		Marketing  = "Marketing"
-	)
-
 	var (
 		employeeName   string
 		employeeID     int32
 		salary         float64
 		isActive       bool
-		department     string
+		department     Department
+		departmentName string
 		yearsOfService int
 		choice         int
 		isEmployeeSet  bool = false
@@ -48,11 +113,9 @@ func main() {
 	fmt.Println("==================================")
 	fmt.Println("|    AVAIABLE DEPARTMENTS        |")
 	fmt.Println("==================================")
-	fmt.Println("|IT                              |")
-	fmt.Println("|HR                              |")
-	fmt.Println("|Finance                         |")
-	fmt.Println("|Operations                      |")
-	fmt.Println("|Marketing                       |")
+	for _, d := range AllDepartments() {
+		fmt.Printf("|%-32s|\n", d)
+	}
 	fmt.Println("==================================")
 
 	for {
@@ -106,14 +169,10 @@ func main() {
 				fmt.Scan(&yearsOfService)
 
 				fmt.Print("Enter Department       : ")
-				fmt.Scan(&department)
+				fmt.Scan(&departmentName)
 
 				// self learning component: Department validation
-				switch department {
-				case IT, HR, Finance, Operations, Marketing:
-					isActive = true
-					isEmployeeSet = true
-				default:
+				if err := department.Set(departmentName); err != nil {
 					fmt.Println("\n❌ Invalid department! Please choose from available departments")
 					continue
 				}
@@ -158,14 +217,10 @@ func main() {
 					continue
 				}
 				fmt.Print("Enter new Department   : ")
-				fmt.Scan(&department)
+				fmt.Scan(&departmentName)
 
 				// self learning component: Department validation
-				switch department {
-				case IT, HR, Finance, Operations, Marketing:
-					isActive = true
-					isEmployeeSet = true
-				default:
+				if err := department.Set(departmentName); err != nil {
 					fmt.Println("\n❌ Invalid department! Please choose from available departments")
 					continue
 				}
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 )
 
 type MenuOption int
@@ -11,30 +20,175 @@ const (
 	AddEmployee MenuOption = iota + 1
 	DisplayEmployees
 	UpdateEmployeeSalary
+	TransferEmployee
 	ExitProgram
 )
 
+// Department is a department of the company. It prints, encodes as JSON and
+// parses from flags by name.
+type Department int
+
+const (
+	IT Department = iota
+	HR
+	Finance
+	Marketing
+)
+
+var departmentNames = [...]string{"IT", "HR", "Finance", "Marketing"}
+
+// AllDepartments returns every department in order
+func AllDepartments() []Department {
+	all := make([]Department, len(departmentNames))
+	for i := range all {
+		all[i] = Department(i)
+	}
+	return all
+}
+
+// Valid reports whether d is one of the departments
+func (d Department) Valid() bool {
+	return d >= 0 && int(d) < len(departmentNames)
+}
+
+func (d Department) String() string {
+	if !d.Valid() {
+		return "Unknown"
+	}
+	return departmentNames[d]
+}
+
+// ParseDepartment returns the department with a name, ignoring case
+func ParseDepartment(name string) (Department, error) {
+	for _, d := range AllDepartments() {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return -1, fmt.Errorf("invalid department %q: must be one of %v", name, AllDepartments())
+}
+
+// Set parses a department flag; with String it makes *Department a flag.Value
+func (d *Department) Set(name string) error {
+	dept, err := ParseDepartment(name)
+	if err != nil {
+		return err
+	}
+	*d = dept
+	return nil
+}
+
+func (d Department) MarshalJSON() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("invalid department %d", int(d))
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Department) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid department %s: must be a name", data)
+	}
+	return d.Set(name)
+}
+
 type Employee struct {
 	ID         int
 	Name       string
-	Department string
+	Department Department
 	Salary     float64
 	Position   string
 }
 
-var (
-	departments      = [4]string{"IT", "HR", "Finance", "Marketing"}
-	employeesList    []*Employee               // Store pointers to reflect updates
-	employees        = make(map[int]*Employee) // Map of Employee pointers
-	deptEmployees    = make(map[string][]*Employee)
-	salaryThresholds = map[string]float64{
-		"Junior":   30000,
-		"Senior":   50000,
-		"Lead":     80000,
-		"Manager":  100000,
-		"Director": 150000,
+// Position is a rung of the career ladder: its title, its level, the salary
+// band it pays and the departments that have it
+type Position struct {
+	Title       string
+	Level       int
+	MinSalary   float64      // where the band starts; it ends where the next level's starts
+	Departments []Department // nil if every department has the position
+}
+
+// OpenTo reports whether a department has the position
+func (p Position) OpenTo(dept Department) bool {
+	if p.Departments == nil {
+		return true
 	}
-)
+	for _, d := range p.Departments {
+		if d == dept {
+			return true
+		}
+	}
+	return false
+}
+
+// positions is the position registry, from the lowest level up. Junior
+// starts at 0, the lowest salary validate accepts, and every department has
+// it, so every valid salary is in a band; only IT and Finance have team leads.
+var positions = []Position{
+	{Title: "Junior", Level: 1, MinSalary: 0},
+	{Title: "Senior", Level: 2, MinSalary: 50000},
+	{Title: "Lead", Level: 3, MinSalary: 80000, Departments: []Department{IT, Finance}},
+	{Title: "Manager", Level: 4, MinSalary: 100000},
+	{Title: "Director", Level: 5, MinSalary: 150000},
+}
+
+// findPosition looks up a position in the registry by title
+func findPosition(title string) (Position, bool) {
+	for _, p := range positions {
+		if p.Title == title {
+			return p, true
+		}
+	}
+	return Position{}, false
+}
+
+// validateBand checks that a department has a position whose band pays salary
+func validateBand(salary float64, dept Department) error {
+	for _, p := range positions {
+		if !p.OpenTo(dept) {
+			continue
+		}
+		if salary < p.MinSalary {
+			return fmt.Errorf("invalid salary: must be at least %.2f, the start of the %s band in %s", p.MinSalary, p.Title, dept)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s has no positions", dept)
+}
+
+type TransferRecord struct {
+	EmployeeID     int
+	FromDepartment Department
+	ToDepartment   Department
+	EffectiveDate  time.Time
+}
+
+// EmployeeManager is the single source of truth for employees. Each employee
+// is stored once, by ID; the list in the order employees were added and the
+// index by department are derived from it and kept in step by every change
+// the manager makes. It hands out copies, so callers cannot change an
+// employee behind its back.
+type EmployeeManager struct {
+	employees map[int]*Employee
+	order     []int                // IDs in the order employees were added
+	byDept    map[Department][]int // IDs in each department, in the order they joined it
+	transfers []TransferRecord
+	debug     bool // check after every change that the list and the index agree with the employees
+	// panicOnInconsistency makes a failed check panic instead of warning, so
+	// tests stop at the change that broke the records
+	panicOnInconsistency bool
+}
+
+func NewEmployeeManager() *EmployeeManager {
+	return &EmployeeManager{
+		employees: make(map[int]*Employee),
+		byDept:    make(map[Department][]int),
+	}
+}
+
+var manager = NewEmployeeManager()
 
 // Validate input data
 func validate(field string, value interface{}, isUpdate bool) error {
@@ -45,11 +199,11 @@ func validate(field string, value interface{}, isUpdate bool) error {
 			return fmt.Errorf("invalid ID: must be positive")
 		}
 		if !isUpdate {
-			if _, exists := employees[id]; exists {
+			if _, exists := manager.Get(id); exists {
 				return fmt.Errorf("employee ID %d already exists", id)
 			}
 		} else {
-			if _, exists := employees[id]; !exists {
+			if _, exists := manager.Get(id); !exists {
 				return fmt.Errorf("employee ID %d not found", id)
 			}
 		}
@@ -67,205 +221,474 @@ func validate(field string, value interface{}, isUpdate bool) error {
 		}
 
 	case "department":
-		dept, ok := value.(string)
-		if !ok {
-			return fmt.Errorf("invalid department")
-		}
-		valid := false
-		for _, d := range departments {
-			if d == dept {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			return fmt.Errorf("invalid department: must be one of %v", departments)
+		dept, ok := value.(Department)
+		if !ok || !dept.Valid() {
+			return fmt.Errorf("invalid department: must be one of %v", AllDepartments())
 		}
 	}
 	return nil
 }
 
-// Check if employee is eligible for promotion (based only on salary)
-func (e *Employee) checkPromotion() bool {
-	currentPosition := e.Position
-	switch {
-	case currentPosition == "Junior" && e.Salary >= salaryThresholds["Senior"]:
-		e.Position = "Senior"
-	case currentPosition == "Senior" && e.Salary >= salaryThresholds["Lead"]:
-		e.Position = "Lead"
-	case currentPosition == "Lead" && e.Salary >= salaryThresholds["Manager"]:
-		e.Position = "Manager"
-	case currentPosition == "Manager" && e.Salary >= salaryThresholds["Director"]:
-		e.Position = "Director"
-	default:
+// isPromotion reports whether moving between two positions is a step up the ladder
+func isPromotion(from, to string) bool {
+	f, _ := findPosition(from)
+	t, _ := findPosition(to)
+	return t.Level > f.Level
+}
+
+// ANSI SGR parameters of each kind of message, by theme
+var themes = map[string]map[string]string{
+	"default":       {"error": "31", "success": "32", "warning": "33", "header": "1;36"},
+	"high-contrast": {"error": "1;91", "success": "1;92", "warning": "1;93", "header": "1;97;44"},
+	"mono":          {"error": "1", "warning": "4", "header": "1"},
+}
+
+// colorTheme colors console output, or is nil to print plain text
+var colorTheme map[string]string
+
+// colorsSupported reports whether standard output should be colored. Colors
+// are left out when NO_COLOR is set, when TERM is dumb and when output is not
+// a terminal.
+func colorsSupported() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
 		return false
 	}
-	return true
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-// Display all employees
-func displayAllEmployees() {
-	if len(employeesList) == 0 {
-		fmt.Println("No employees found!")
+// paint colors s as the given kind of message, unless colors are off
+func paint(kind, s string) string {
+	sgr := colorTheme[kind]
+	if sgr == "" {
+		return s
+	}
+	return "\x1b[" + sgr + "m" + s + "\x1b[0m"
+}
+
+func errorText(s string) string   { return paint("error", s) }
+func successText(s string) string { return paint("success", s) }
+func warningText(s string) string { return paint("warning", s) }
+func headerText(s string) string  { return paint("header", s) }
+
+// Table columns an employee list can show, selected with -columns
+var (
+	tableColumns  = []string{"id", "name", "department", "position", "salary"}
+	tableHeaders  = map[string]string{"id": "ID", "name": "Name", "department": "Department", "position": "Position", "salary": "Salary"}
+	tableMarkdown = false
+)
+
+// employeeRow returns the cells of an employee for the selected columns
+func employeeRow(emp *Employee) []string {
+	row := make([]string, len(tableColumns))
+	for i, column := range tableColumns {
+		switch column {
+		case "id":
+			row[i] = strconv.Itoa(emp.ID)
+		case "name":
+			row[i] = emp.Name
+		case "department":
+			row[i] = emp.Department.String()
+		case "position":
+			row[i] = emp.Position
+		case "salary":
+			row[i] = fmt.Sprintf("%.2f", emp.Salary)
+		}
+	}
+	return row
+}
+
+// parseColumns checks a comma-separated list of column names
+func parseColumns(list string) ([]string, error) {
+	columns := make([]string, 0)
+	for _, column := range strings.Split(list, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column == "" {
+			continue
+		}
+		if _, ok := tableHeaders[column]; !ok {
+			return nil, fmt.Errorf("unknown column %q: must be id, name, department, position or salary", column)
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("select at least one column")
+	}
+	return columns, nil
+}
+
+// printTable prints rows under the headers of the selected columns. Each
+// column is as wide as its widest cell, so long names are never cut off.
+func printTable(rows [][]string) {
+	headers := make([]string, len(tableColumns))
+	for i, column := range tableColumns {
+		headers[i] = tableHeaders[column]
+	}
+
+	if tableMarkdown {
+		escape := strings.NewReplacer("|", `\|`)
+		fmt.Printf("| %s |\n", strings.Join(headers, " | "))
+		fmt.Printf("|%s\n", strings.Repeat("---|", len(headers)))
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = escape.Replace(cell)
+			}
+			fmt.Printf("| %s |\n", strings.Join(cells, " | "))
+		}
 		return
 	}
 
-	deptCounts := make(map[string]int)
-	for _, emp := range employeesList {
-		deptCounts[emp.Department]++
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = utf8.RuneCountInString(header)
+		for _, row := range rows {
+			if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
 	}
 
-	fmt.Println("\n+-----+------------------+---------------+------------+-------------+")
-	fmt.Printf("| %-3s | %-16s | %-13s | %-10s | %-11s |\n",
-		"ID", "Name", "Department", "Position", "Salary")
-	fmt.Println("+-----+------------------+---------------+------------+-------------+")
+	border := "+"
+	for _, width := range widths {
+		border += strings.Repeat("-", width+2) + "+"
+	}
+	printRow := func(cells []string) {
+		line := "|"
+		for i, cell := range cells {
+			line += " " + cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)) + " |"
+		}
+		fmt.Println(line)
+	}
 
-	for _, emp := range employeesList {
-		fmt.Printf("| %-3d | %-16s | %-13s | %-10s | %-11.2f |\n",
-			emp.ID,
-			emp.Name,
-			emp.Department,
-			emp.Position,
-			emp.Salary)
+	fmt.Println(border)
+	printRow(headers)
+	fmt.Println(border)
+	for _, row := range rows {
+		printRow(row)
+	}
+	fmt.Println(border)
+}
+
+// Display all employees
+func displayAllEmployees() {
+	list := manager.List()
+	if len(list) == 0 {
+		fmt.Println("No employees found!")
+		return
 	}
 
-	fmt.Println("+-----+------------------+---------------+------------+-------------+")
-	fmt.Printf("Total Employees: %d\n", len(employeesList))
+	rows := make([][]string, 0, len(list))
+	for i := range list {
+		rows = append(rows, employeeRow(&list[i]))
+	}
+	fmt.Println()
+	printTable(rows)
+	fmt.Printf("Total Employees: %d\n", len(list))
 
+	// Departments are listed in their fixed order so the report is the same on every run
 	fmt.Println("\nDepartment Breakdown:")
-	for dept, count := range deptCounts {
-		fmt.Printf("%s: %d employees\n", dept, count)
+	for _, dept := range AllDepartments() {
+		if count := len(manager.InDepartment(dept)); count > 0 {
+			fmt.Printf("%s: %d employees\n", dept, count)
+		}
 	}
 }
 
-func checkPosition(salary float64) string {
-	oldPosition := ""
-	newPosition := ""
+// checkPosition returns the position an employee of a department holds at a
+// salary: the highest of the department's positions whose band the salary
+// reaches, or its lowest position
+func checkPosition(salary float64, dept Department) string {
+	title := ""
+	for _, p := range positions {
+		if p.OpenTo(dept) && (title == "" || salary >= p.MinSalary) {
+			title = p.Title
+		}
+	}
+	return title
+}
 
-	// Determine position based on salary
-	switch {
-	case salary >= salaryThresholds["Director"]:
-		newPosition = "Director"
-	case salary >= salaryThresholds["Manager"]:
-		newPosition = "Manager"
-	case salary >= salaryThresholds["Lead"]:
-		newPosition = "Lead"
-	case salary >= salaryThresholds["Senior"]:
-		newPosition = "Senior"
-	default:
-		newPosition = "Junior"
+// Get returns a copy of an employee
+func (m *EmployeeManager) Get(id int) (Employee, bool) {
+	emp, exists := m.employees[id]
+	if !exists {
+		return Employee{}, false
+	}
+	return *emp, true
+}
+
+// List returns copies of all employees in the order they were added
+func (m *EmployeeManager) List() []Employee {
+	list := make([]Employee, 0, len(m.order))
+	for _, id := range m.order {
+		list = append(list, *m.employees[id])
 	}
+	return list
+}
 
-	// Return new position
-	if oldPosition != "" && oldPosition != newPosition {
-		fmt.Printf("Position changed from %s to %s\n", oldPosition, newPosition)
+// InDepartment returns copies of the employees of a department, in the order they joined it
+func (m *EmployeeManager) InDepartment(dept Department) []Employee {
+	list := make([]Employee, 0, len(m.byDept[dept]))
+	for _, id := range m.byDept[dept] {
+		list = append(list, *m.employees[id])
 	}
-	return newPosition
+	return list
 }
 
-func addEmployee(id int, name string, department string, salary float64) error {
-	position := checkPosition(salary)
-	emp := &Employee{
+// Add stores a new employee, whose position follows from the salary
+func (m *EmployeeManager) Add(id int, name string, department Department, salary float64) error {
+	if _, exists := m.employees[id]; exists {
+		return fmt.Errorf("employee ID %d already exists", id)
+	}
+	if err := validateBand(salary, department); err != nil {
+		return err
+	}
+	m.employees[id] = &Employee{
 		ID:         id,
 		Name:       name,
 		Department: department,
 		Salary:     salary,
-		Position:   position,
+		Position:   checkPosition(salary, department),
 	}
-	employeesList = append(employeesList, emp)
-	employees[id] = emp
-	deptEmployees[department] = append(deptEmployees[department], emp)
+	m.order = append(m.order, id)
+	m.byDept[department] = append(m.byDept[department], id)
+	m.verify(fmt.Sprintf("adding ID %d", id))
 	return nil
 }
 
-// Update employee salary and check for promotion
-func updateEmployee(id int, salary float64) bool {
-	if emp, exists := employees[id]; exists {
-		emp.Salary = salary
-		if emp.checkPromotion() {
-			fmt.Printf("🎉 Congratulations! %s has been promoted to %s\n", emp.Name, emp.Position)
-		}
-		return true
+// UpdateSalary changes an employee's salary and moves them to the position it
+// pays, returning the employee before and after
+func (m *EmployeeManager) UpdateSalary(id int, salary float64) (before, after Employee, err error) {
+	emp, exists := m.employees[id]
+	if !exists {
+		return before, after, fmt.Errorf("employee ID %d not found", id)
 	}
-	return false
+	if err := validateBand(salary, emp.Department); err != nil {
+		return before, after, err
+	}
+	before = *emp
+	emp.Salary = salary
+	emp.Position = checkPosition(salary, emp.Department)
+	m.verify(fmt.Sprintf("updating the salary of ID %d", id))
+	return before, *emp, nil
 }
 
-func updateEmployeeSalary(id int, newSalary float64) error {
-	emp, exists := employees[id]
+// Transfer moves an employee to another department and records it in the
+// history, returning the employee before and after. An employee whose
+// position the new department does not have takes the one their salary pays there.
+func (m *EmployeeManager) Transfer(id int, newDept Department, effectiveDate time.Time) (before, after Employee, err error) {
+	emp, exists := m.employees[id]
 	if !exists {
-		return fmt.Errorf("employee ID %d not found", id)
+		return before, after, fmt.Errorf("employee ID %d not found", id)
+	}
+	if err := validate("department", newDept, true); err != nil {
+		return before, after, err
+	}
+	if emp.Department == newDept {
+		return before, after, fmt.Errorf("employee is already in %s", newDept)
+	}
+	if err := validateBand(emp.Salary, newDept); err != nil {
+		return before, after, err
 	}
 
-	oldPosition := emp.Position
-	newPosition := checkPosition(newSalary)
-
-	// Update employee details
-	emp.Salary = newSalary
-	emp.Position = newPosition
+	before = *emp
+	m.unindex(id, emp.Department)
+	m.byDept[newDept] = append(m.byDept[newDept], id)
+	emp.Department = newDept
+	if p, ok := findPosition(emp.Position); !ok || !p.OpenTo(newDept) {
+		emp.Position = checkPosition(emp.Salary, newDept)
+	}
 
-	// Update maps
-	employees[id] = emp
+	m.transfers = append(m.transfers, TransferRecord{
+		EmployeeID:     id,
+		FromDepartment: before.Department,
+		ToDepartment:   newDept,
+		EffectiveDate:  effectiveDate,
+	})
+	m.verify(fmt.Sprintf("transferring ID %d", id))
+	return before, *emp, nil
+}
 
-	// Update department map
-	for i, e := range deptEmployees[emp.Department] {
-		if e.ID == id {
-			deptEmployees[emp.Department][i].Salary = newSalary
-			deptEmployees[emp.Department][i].Position = newPosition
-			break
+// Inconsistencies compares the list and the department index with the
+// employees, returning what they disagree on. The list and the index hold
+// IDs, so they reach the same *Employee as long as each ID is stored under
+// its own record.
+func (m *EmployeeManager) Inconsistencies() []string {
+	ids := make([]int, 0, len(m.employees))
+	for id := range m.employees {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var problems []string
+	for _, id := range ids {
+		if emp := m.employees[id]; emp == nil {
+			problems = append(problems, fmt.Sprintf("- ID %d has no record", id))
+		} else if emp.ID != id {
+			problems = append(problems, fmt.Sprintf("~ ID %d is stored under the record of ID %d", id, emp.ID))
 		}
 	}
 
-	if oldPosition != newPosition {
-		fmt.Printf("Employee %d position updated: %s -> %s\n", id, oldPosition, newPosition)
+	listed := make(map[int]int, len(m.order))
+	for _, id := range m.order {
+		listed[id]++
+		if _, exists := m.employees[id]; !exists && listed[id] == 1 {
+			problems = append(problems, fmt.Sprintf("- ID %d is listed but is not an employee", id))
+		}
+	}
+	indexed := make(map[int]int, len(m.employees))
+	for _, dept := range AllDepartments() {
+		for _, id := range m.byDept[dept] {
+			indexed[id]++
+			emp, exists := m.employees[id]
+			switch {
+			case !exists:
+				problems = append(problems, fmt.Sprintf("- ID %d is indexed under %s but is not an employee", id, dept))
+			case emp != nil && emp.Department != dept:
+				problems = append(problems, fmt.Sprintf("~ ID %d is indexed under %s but works in %s", id, dept, emp.Department))
+			}
+		}
+	}
+	for dept := range m.byDept {
+		if !dept.Valid() {
+			problems = append(problems, fmt.Sprintf("- the index has unknown department %d", int(dept)))
+		}
 	}
 
-	return nil
+	for _, id := range ids {
+		switch n := listed[id]; {
+		case n == 0:
+			problems = append(problems, fmt.Sprintf("+ ID %d is an employee but is not listed", id))
+		case n > 1:
+			problems = append(problems, fmt.Sprintf("- ID %d is listed %d times", id, n))
+		}
+		switch n := indexed[id]; {
+		case n == 0:
+			problems = append(problems, fmt.Sprintf("+ ID %d is an employee but is not indexed by department", id))
+		case n > 1:
+			problems = append(problems, fmt.Sprintf("- ID %d is indexed %d times", id, n))
+		}
+	}
+	return problems
 }
 
-func displayEmployees() {
-	if len(employees) == 0 {
-		fmt.Println("No employees to display")
+// verify checks the manager after a change when debugging. What disagrees
+// is a bug in the manager: it panics with the difference if
+// panicOnInconsistency is set, and is otherwise reported while the program
+// carries on.
+func (m *EmployeeManager) verify(change string) {
+	if !m.debug {
 		return
 	}
+	problems := m.Inconsistencies()
+	if len(problems) == 0 {
+		return
+	}
+	diff := fmt.Sprintf("employee records disagree after %s:\n  %s", change, strings.Join(problems, "\n  "))
+	if m.panicOnInconsistency {
+		panic(diff)
+	}
+	fmt.Fprintf(os.Stderr, "\n%s %s\n", warningText("Warning:"), diff)
+}
 
-	fmt.Println("\n=== Employee List ===")
-	fmt.Printf("%-5s %-20s %-15s %-12s %-10s\n", "ID", "Name", "Department", "Salary", "Position")
-	fmt.Println(strings.Repeat("-", 65))
-
-	for _, emp := range employees {
-		// Ensure position is up to date with current salary
-		currentPosition := checkPosition(emp.Salary)
-		if emp.Position != currentPosition {
-			emp.Position = currentPosition
-			// Update position in deptEmployees
-			for i, deptEmp := range deptEmployees[emp.Department] {
-				if deptEmp.ID == emp.ID {
-					deptEmployees[emp.Department][i].Position = currentPosition
-				}
-			}
+// unindex removes an employee from a department's index
+func (m *EmployeeManager) unindex(id int, dept Department) {
+	ids := m.byDept[dept]
+	for i, other := range ids {
+		if other == id {
+			m.byDept[dept] = append(ids[:i], ids[i+1:]...)
+			break
 		}
-		fmt.Printf("%-5d %-20s %-15s %-12.2f %-10s\n",
-			emp.ID, emp.Name, emp.Department, emp.Salary, emp.Position)
 	}
-	fmt.Println(strings.Repeat("-", 65))
+	if len(m.byDept[dept]) == 0 {
+		delete(m.byDept, dept)
+	}
+}
+
+func addEmployee(id int, name string, department Department, salary float64) error {
+	return manager.Add(id, name, department, salary)
+}
+
+// Update employee salary and announce a promotion or other change of position
+func updateEmployee(id int, salary float64) error {
+	before, after, err := manager.UpdateSalary(id, salary)
+	if err != nil {
+		return err
+	}
+	switch {
+	case isPromotion(before.Position, after.Position):
+		fmt.Printf("%s %s has been promoted to %s\n", successText("Congratulations!"), after.Name, after.Position)
+	case before.Position != after.Position:
+		fmt.Printf("Employee %d position updated: %s -> %s\n", id, before.Position, after.Position)
+	}
+	return nil
+}
+
+// Transfer employee to another department and record it in the history
+func transferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	before, after, err := manager.Transfer(id, newDept, effectiveDate)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s has been transferred from %s to %s (effective %s)\n", warningText("Notice:"),
+		after.Name, before.Department, after.Department, effectiveDate.Format("2006-01-02"))
+	return nil
 }
 
 // Display menu
 func displayMenu() {
 	fmt.Println("\n==================================")
-	fmt.Println("|      EMPLOYEE MANAGEMENT       |")
+	fmt.Println(headerText("|      EMPLOYEE MANAGEMENT       |"))
 	fmt.Println("==================================")
 	fmt.Println("| 1. Add Employee                |")
 	fmt.Println("| 2. Display All Employees       |")
 	fmt.Println("| 3. Update Employee Salary      |")
-	fmt.Println("| 4. Exit                        |")
+	fmt.Println("| 4. Transfer Employee           |")
+	fmt.Println("| 5. Exit                        |")
 	fmt.Println("==================================")
-	fmt.Printf("\nAvailable Departments: %v\n", departments)
-	fmt.Print("\nEnter your choice (1-4): ")
+	fmt.Printf("\nAvailable Departments: %v\n", AllDepartments())
+	fmt.Print("\nEnter your choice (1-5): ")
 }
 
 // Main function
 func main() {
+	columns := flag.String("columns", strings.Join(tableColumns, ","), "columns of employee lists (id, name, department, position, salary)")
+	markdown := flag.Bool("markdown", false, "print employee lists as Markdown tables")
+	theme := flag.String("theme", "default", "color theme (default, high-contrast, mono)")
+	noColor := flag.Bool("no-color", false, "print plain text without colors; also set by the NO_COLOR environment variable")
+	debug := flag.Bool("debug", false, "check after every change that the employee list and department index agree with the employees, reporting any difference")
+	flag.Parse()
+	manager.debug = *debug
+
+	if _, ok := themes[*theme]; !ok {
+		fmt.Printf("%s unknown theme %q: must be default, high-contrast or mono\n", errorText("Error:"), *theme)
+		os.Exit(2)
+	}
+	if !*noColor && colorsSupported() {
+		colorTheme = themes[*theme]
+	}
+
+	selected, err := parseColumns(*columns)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorText("Error:"), err)
+		os.Exit(2)
+	}
+	tableColumns = selected
+	tableMarkdown = *markdown
+
+	// Ctrl-C or SIGTERM exits with the status a shell expects, 128 plus the
+	// signal number, after resetting any color left on by the interrupted output
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		if colorTheme != nil {
+			fmt.Print("\x1b[0m")
+		}
+		fmt.Printf("\nReceived %v, exiting. Employees entered in this session are not saved.\n", sig)
+		os.Exit(128 + int(sig.(syscall.Signal)))
+	}()
+
 	for {
 		displayMenu()
 		var choice MenuOption
@@ -274,18 +697,19 @@ func main() {
 		switch choice {
 		case AddEmployee:
 			fmt.Println("\n==================================")
-			fmt.Println("|         ADD EMPLOYEE           |")
+			fmt.Println(headerText("|         ADD EMPLOYEE           |"))
 			fmt.Println("==================================")
 
 			var id int
-			var name, dept string
+			var name string
+			var dept Department
 			var salary float64
 
 			for {
 				fmt.Print("Enter Employee ID: ")
 				fmt.Scan(&id)
 				if err := validate("id", id, false); err != nil {
-					fmt.Printf("\n❌ Error: %v\n", err)
+					fmt.Printf("\n%s %v\n", errorText("Error:"), err)
 					continue
 				}
 				break
@@ -295,7 +719,7 @@ func main() {
 				fmt.Print("Enter Employee Name: ")
 				fmt.Scan(&name)
 				if err := validate("name", name, false); err != nil {
-					fmt.Printf("\n❌ Error: %v\n", err)
+					fmt.Printf("\n%s %v\n", errorText("Error:"), err)
 					continue
 				}
 				break
@@ -303,9 +727,10 @@ func main() {
 
 			for {
 				fmt.Print("Enter Department: ")
-				fmt.Scan(&dept)
-				if err := validate("department", dept, false); err != nil {
-					fmt.Printf("\n❌ Error: %v\n", err)
+				var deptName string
+				fmt.Scan(&deptName)
+				if err := dept.Set(deptName); err != nil {
+					fmt.Printf("\n%s %v\n", errorText("Error:"), err)
 					continue
 				}
 				break
@@ -314,19 +739,26 @@ func main() {
 			for {
 				fmt.Print("Enter Salary: ")
 				fmt.Scan(&salary)
-				if err := validate("salary", salary, false); err != nil {
-					fmt.Printf("\n❌ Error: %v\n", err)
+				err := validate("salary", salary, false)
+				if err == nil {
+					err = validateBand(salary, dept)
+				}
+				if err != nil {
+					fmt.Printf("\n%s %v\n", errorText("Error:"), err)
 					continue
 				}
 				break
 			}
 
-			addEmployee(id, name, dept, salary)
-			fmt.Println("\n✅ Employee added successfully!")
+			if err := addEmployee(id, name, dept, salary); err != nil {
+				fmt.Printf("\n%s %v\n", errorText("Error:"), err)
+				continue
+			}
+			fmt.Println("\n" + successText("Employee added successfully!"))
 
 		case DisplayEmployees:
 			fmt.Println("\n==================================")
-			fmt.Println("|      EMPLOYEE DETAILS          |")
+			fmt.Println(headerText("|      EMPLOYEE DETAILS          |"))
 			fmt.Println("==================================")
 			displayAllEmployees()
 
@@ -338,18 +770,45 @@ func main() {
 			fmt.Print("Enter New Salary: ")
 			fmt.Scan(&salary)
 
-			if updateEmployee(id, salary) {
-				fmt.Println("\n✅ Salary updated successfully!")
+			if err := updateEmployee(id, salary); err != nil {
+				fmt.Printf("\n%s %v\n", errorText("Error:"), err)
+			} else {
+				fmt.Println("\n" + successText("Salary updated successfully!"))
+			}
+
+		case TransferEmployee:
+			var id int
+			var deptName, date string
+			fmt.Print("Enter Employee ID: ")
+			fmt.Scan(&id)
+			fmt.Print("Enter New Department: ")
+			fmt.Scan(&deptName)
+			fmt.Print("Enter Effective Date (YYYY-MM-DD): ")
+			fmt.Scan(&date)
+
+			dept, err := ParseDepartment(deptName)
+			if err != nil {
+				fmt.Printf("\n%s %v\n", errorText("Error:"), err)
+				continue
+			}
+			effectiveDate, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				fmt.Println("\n" + errorText("Error:") + " invalid date, expected YYYY-MM-DD")
+				continue
+			}
+
+			if err := transferEmployee(id, dept, effectiveDate); err != nil {
+				fmt.Printf("\n%s %v\n", errorText("Error:"), err)
 			} else {
-				fmt.Println("\n❌ Employee not found!")
+				fmt.Println("\n" + successText("Employee transferred successfully!"))
 			}
 
 		case ExitProgram:
-			fmt.Println("\n👋 Goodbye!")
+			fmt.Println("\nGoodbye!")
 			return
 
 		default:
-			fmt.Println("\n❌ Invalid choice! Please enter 1-4")
+			fmt.Println("\n" + errorText("Error:") + " invalid choice! Please enter 1-5")
 		}
 	}
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInconsistentRecordsPanicWithTheDifference(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(m *EmployeeManager)
+		want    string
+	}{
+		{
+			name:    "indexed under the wrong department",
+			corrupt: func(m *EmployeeManager) { m.byDept[HR] = append(m.byDept[HR], 1) },
+			want:    "~ ID 1 is indexed under HR but works in IT",
+		},
+		{
+			name:    "not indexed",
+			corrupt: func(m *EmployeeManager) { m.byDept[IT] = nil },
+			want:    "+ ID 1 is an employee but is not indexed by department",
+		},
+		{
+			name:    "listed twice",
+			corrupt: func(m *EmployeeManager) { m.order = append(m.order, 1) },
+			want:    "- ID 1 is listed 2 times",
+		},
+		{
+			name:    "listed but not an employee",
+			corrupt: func(m *EmployeeManager) { m.order = append(m.order, 7) },
+			want:    "- ID 7 is listed but is not an employee",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewEmployeeManager()
+			m.debug = true
+			m.panicOnInconsistency = true
+			if err := m.Add(1, "Ann", IT, 60000); err != nil {
+				t.Fatal(err)
+			}
+			tt.corrupt(m)
+
+			defer func() {
+				got := fmt.Sprint(recover())
+				if !strings.HasPrefix(got, "employee records disagree after updating the salary of ID 1:") {
+					t.Fatalf("panicked with %q, want the records to disagree after the salary update", got)
+				}
+				if !strings.Contains(got, "\n  "+tt.want) {
+					t.Errorf("the difference is %q, want it to include %q", got, tt.want)
+				}
+			}()
+			m.UpdateSalary(1, 65000)
+		})
+	}
+}
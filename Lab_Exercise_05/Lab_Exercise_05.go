@@ -3,58 +3,549 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 	"unicode"
-)
 
-const (
-	MinSalary = 20000
-	MaxSalary = 2000000
+	"github.com/JoeDkhar/Go/errorsx"
 )
 
+// Benefit is a benefit employers must provide, costing a share of gross pay
+type Benefit struct {
+	Name string  `json:"name"`
+	Rate float64 `json:"rate"` // employer cost as a fraction of gross pay, e.g. 0.05
+}
+
+// RulePack holds the compliance rules of a jurisdiction: the salary range,
+// when overtime starts and how it is paid, and the benefits employers must pay for
+type RulePack struct {
+	Jurisdiction       string    `json:"jurisdiction"`
+	MinimumWage        float64   `json:"minimum_wage"`   // lowest annual salary
+	MaximumSalary      float64   `json:"maximum_salary"` // highest annual salary, or 0 for no limit
+	StandardHours      float64   `json:"standard_hours"` // weekly hours before overtime
+	OvertimeMultiplier float64   `json:"overtime_multiplier"`
+	MandatoryBenefits  []Benefit `json:"mandatory_benefits"`
+}
+
+// defaultRulePack is used unless -rules loads another jurisdiction
+var defaultRulePack = RulePack{
+	Jurisdiction:       "default",
+	MinimumWage:        20000,
+	MaximumSalary:      2000000,
+	StandardHours:      40,
+	OvertimeMultiplier: 1.5,
+}
+
+// complianceRules are the rules salaries are validated and paid by. main
+// sets them from the -rules and -jurisdiction flags.
+var complianceRules = defaultRulePack
+
+// validate checks that a rule pack can be used
+func (r RulePack) validate() error {
+	switch {
+	case r.Jurisdiction == "":
+		return errors.New("a rule pack needs a jurisdiction")
+	case r.MinimumWage < 0 || (r.MaximumSalary != 0 && r.MaximumSalary < r.MinimumWage):
+		return fmt.Errorf("%s: the salary range %.2f - %.2f is invalid", r.Jurisdiction, r.MinimumWage, r.MaximumSalary)
+	case r.StandardHours <= 0:
+		return fmt.Errorf("%s: standard hours must be positive", r.Jurisdiction)
+	case r.OvertimeMultiplier < 1:
+		return fmt.Errorf("%s: the overtime multiplier must be at least 1", r.Jurisdiction)
+	}
+	for _, b := range r.MandatoryBenefits {
+		if b.Name == "" || b.Rate < 0 {
+			return fmt.Errorf("%s: benefit %q needs a name and a rate of 0 or more", r.Jurisdiction, b.Name)
+		}
+	}
+	return nil
+}
+
+// loadRulePack reads a JSON array of rule packs and returns the one for jurisdiction
+func loadRulePack(path, jurisdiction string) (RulePack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RulePack{}, err
+	}
+	var packs []RulePack
+	if err := json.Unmarshal(data, &packs); err != nil {
+		return RulePack{}, fmt.Errorf("reading rule packs %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(packs))
+	for _, pack := range packs {
+		if strings.EqualFold(pack.Jurisdiction, jurisdiction) {
+			return pack, pack.validate()
+		}
+		names = append(names, pack.Jurisdiction)
+	}
+	return RulePack{}, fmt.Errorf("no rule pack for %q in %s (available: %s)", jurisdiction, path, strings.Join(names, ", "))
+}
+
+// Payslip is one employee's pay for a week under a rule pack
+type Payslip struct {
+	Employee      Employee
+	Hours         float64
+	RegularPay    float64
+	OvertimePay   float64
+	BenefitsCost  float64 // paid by the employer on top of gross pay
+	GrossPay      float64
+	EmployerTotal float64
+}
+
+// Pay computes an employee's pay for a week with the given hours worked.
+// The hourly rate is the annual salary over 52 weeks of standard hours.
+func (r RulePack) Pay(emp Employee, hours float64) Payslip {
+	rate := emp.Salary / 52 / r.StandardHours
+	regular := math.Min(hours, r.StandardHours)
+	overtime := math.Max(hours-r.StandardHours, 0)
+
+	slip := Payslip{
+		Employee:    emp,
+		Hours:       hours,
+		RegularPay:  regular * rate,
+		OvertimePay: overtime * rate * r.OvertimeMultiplier,
+	}
+	slip.GrossPay = slip.RegularPay + slip.OvertimePay
+	for _, b := range r.MandatoryBenefits {
+		slip.BenefitsCost += slip.GrossPay * b.Rate
+	}
+	slip.EmployerTotal = slip.GrossPay + slip.BenefitsCost
+	return slip
+}
+
+// Clock provides the current time so the system can be tested deterministically
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is a Clock that reads the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// displayLocation is the time zone used to show timestamps; they are stored in UTC
+var displayLocation = time.Local
+
+// Distribution describes the spread of a set of values
+type Distribution struct {
+	Mean   float64
+	StdDev float64
+	P25    float64
+	P50    float64
+	P75    float64
+	P90    float64
+}
+
 type PositionStats struct {
 	AvgPerformance float64
 	EmployeeCount  int
 	TotalSalary    float64
+	Salary         Distribution
+	Performance    Distribution
+	Ratings        [6]int // how many ratings were given at each score, rounded to 0-5
 	LastUpdated    time.Time
 }
 
+// calibrationThreshold is how far, in rating points, a position's average
+// rating can be from the company's before it is reported as rating high or low
+const calibrationThreshold = 0.5
+
+// PositionCalibration compares the ratings of a position with the company mean
+type PositionCalibration struct {
+	Position string
+	Rated    int     // employees with at least one rating
+	Mean     float64 // average rating of the rated employees
+	Offset   float64 // Mean minus the company mean
+}
+
+// Bias describes whether a position rates systematically high or low. Positions
+// with a single rated employee are too small to tell.
+func (c PositionCalibration) Bias() string {
+	switch {
+	case c.Rated < 2:
+		return "too few ratings"
+	case c.Offset >= calibrationThreshold:
+		return "rates high"
+	case c.Offset <= -calibrationThreshold:
+		return "rates low"
+	default:
+		return "in line"
+	}
+}
+
+// CalibratedScore is an employee's rating with their position's offset from
+// the company mean removed
+type CalibratedScore struct {
+	Employee  Employee
+	Suggested float64
+}
+
+// percentile returns the p-th percentile (0-100) of sorted values, interpolating between ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// distribution computes the mean, standard deviation and percentiles of values
+func distribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var squares float64
+	for _, v := range sorted {
+		squares += (v - mean) * (v - mean)
+	}
+
+	return Distribution{
+		Mean:   mean,
+		StdDev: math.Sqrt(squares / float64(len(sorted))),
+		P25:    percentile(sorted, 25),
+		P50:    percentile(sorted, 50),
+		P75:    percentile(sorted, 75),
+		P90:    percentile(sorted, 90),
+	}
+}
+
+// Position is a rung of the career ladder: its title, its level and the
+// salary band it pays. Lab_Exercise_03_04 has the same ladder and also
+// records which departments have each position; there are no departments here.
+type Position struct {
+	Title     string
+	Level     int
+	MinSalary float64
+	MaxSalary float64 // 0 for no upper limit
+}
+
+// positions is the position registry, from the lowest level up. Junior has
+// no lower limit of its own: it starts at the minimum wage of the compliance
+// rules, which validateSalary checks, so a salary the rules allow always has
+// a band whichever rule pack is loaded.
+var positions = []Position{
+	{Title: "Junior", Level: 1, MinSalary: 0, MaxSalary: 50000},
+	{Title: "Senior", Level: 2, MinSalary: 50000, MaxSalary: 80000},
+	{Title: "Lead", Level: 3, MinSalary: 80000, MaxSalary: 100000},
+	{Title: "Manager", Level: 4, MinSalary: 100000, MaxSalary: 150000},
+	{Title: "Director", Level: 5, MinSalary: 150000},
+}
+
+// positionTitles lists the registered titles for prompts and messages
+func positionTitles() string {
+	titles := make([]string, len(positions))
+	for i, p := range positions {
+		titles[i] = p.Title
+	}
+	return strings.Join(titles, ", ")
+}
+
+// ParsePosition looks up a position in the registry by title, ignoring case
+func ParsePosition(title string) (Position, error) {
+	for _, p := range positions {
+		if strings.EqualFold(strings.TrimSpace(title), p.Title) {
+			return p, nil
+		}
+	}
+	return Position{}, ErrInvalidPosition.WithValue(title)
+}
+
+// positionAt returns the position of a level of the ladder
+func positionAt(level int) (Position, bool) {
+	for _, p := range positions {
+		if p.Level == level {
+			return p, true
+		}
+	}
+	return Position{}, false
+}
+
+// bandSalary returns the salary nearest to salary that a position's band pays
+func bandSalary(salary float64, p Position) float64 {
+	if salary < p.MinSalary {
+		return p.MinSalary
+	}
+	if p.MaxSalary != 0 && salary > p.MaxSalary {
+		return p.MaxSalary
+	}
+	return salary
+}
+
+// What the promotion policy does when an average rating crosses a threshold
+const (
+	promotionsOff     = "off"     // nothing
+	promotionsPropose = "propose" // proposes the level change for review
+	promotionsAuto    = "auto"    // applies promotions; demotions are still proposed
+)
+
+// PromotionPolicy moves employees along the ladder by performance, as
+// Lab_Exercise_03_04 moves them by salary. When an employee's average
+// rating rises to PromoteAt, the next level up is proposed; when it falls
+// below DemoteAt, the level below is. Only crossing a threshold counts, so
+// an average that stays above it does not propose another level.
+type PromotionPolicy struct {
+	Mode       string  // off, propose or auto
+	PromoteAt  float64 // average rating that earns the next level up
+	DemoteAt   float64 // average rating below which the level below is proposed; 0 for never
+	MinRatings int     // ratings an employee needs before their average counts
+}
+
+// promotionPolicy decides level changes by performance. main sets it from
+// the -promotions, -promote-at, -demote-at and -promotion-ratings flags.
+var promotionPolicy = PromotionPolicy{
+	Mode:       promotionsPropose,
+	PromoteAt:  4.5,
+	DemoteAt:   1.5,
+	MinRatings: 3,
+}
+
+// validate checks that a promotion policy can be used
+func (p PromotionPolicy) validate() error {
+	switch {
+	case p.Mode != promotionsOff && p.Mode != promotionsPropose && p.Mode != promotionsAuto:
+		return fmt.Errorf("promotions must be off, propose or auto, not %q", p.Mode)
+	case p.PromoteAt <= MinRating || p.PromoteAt > MaxRating:
+		return fmt.Errorf("the promotion threshold must be above %.0f and at most %.0f, not %.2f", MinRating, MaxRating, p.PromoteAt)
+	case p.DemoteAt < 0 || p.DemoteAt >= p.PromoteAt:
+		return fmt.Errorf("the demotion threshold must be 0 or more and below the promotion threshold, not %.2f", p.DemoteAt)
+	case p.MinRatings < 1:
+		return fmt.Errorf("promotions need at least 1 rating, not %d", p.MinRatings)
+	}
+	return nil
+}
+
+// LevelChange is a move of an employee up or down the ladder because of
+// their average rating, proposed or applied
+type LevelChange struct {
+	EmployeeID int
+	Name       string
+	From       string
+	To         string
+	Salary     float64 // the salary in the new position: the old one, brought within its band
+	Average    float64 // the average rating that crossed the threshold
+	Applied    bool
+	Time       time.Time
+}
+
+// IsPromotion reports whether the change is a step up the ladder
+func (c LevelChange) IsPromotion() bool {
+	from, _ := ParsePosition(c.From)
+	to, _ := ParsePosition(c.To)
+	return to.Level > from.Level
+}
+
+// Rating is a performance rating and when it was given
+type Rating struct {
+	Value float64
+	Time  time.Time
+}
+
+// RatingWeighting decides how much each rating counts towards an employee's
+// performance, so that it reflects how they perform now rather than their
+// whole history. A rating HalfLife old counts half as much as one given
+// today, and ratings older than Window are left out, except the latest,
+// which always counts. Zero for both gives the plain average.
+type RatingWeighting struct {
+	HalfLife time.Duration // 0 for no decay
+	Window   time.Duration // 0 for every rating
+}
+
+// ratingWeighting weights performance ratings by age. main sets it from the
+// -rating-half-life and -rating-window flags.
+var ratingWeighting RatingWeighting
+
+// validate checks that a rating weighting can be used
+func (w RatingWeighting) validate() error {
+	if w.HalfLife < 0 || w.Window < 0 {
+		return fmt.Errorf("the rating half-life and window must be 0 or more")
+	}
+	return nil
+}
+
+// Average returns the weighted average of ratings, oldest first, as of now
+func (w RatingWeighting) Average(ratings []Rating, now time.Time) float64 {
+	if len(ratings) == 0 {
+		return 0
+	}
+	var total, weights float64
+	for i, r := range ratings {
+		age := now.Sub(r.Time)
+		if age < 0 {
+			age = 0
+		}
+		if w.Window > 0 && age > w.Window && i < len(ratings)-1 {
+			continue
+		}
+		weight := 1.0
+		if w.HalfLife > 0 {
+			weight = math.Exp2(-float64(age) / float64(w.HalfLife))
+		}
+		total += weight * r.Value
+		weights += weight
+	}
+	if weights == 0 {
+		// Every weight underflowed. Decay keeps the ratings' proportions, so
+		// weighing them as of the latest rating gives the same average.
+		return w.Average(ratings, ratings[len(ratings)-1].Time)
+	}
+	return total / weights
+}
+
 type Employee struct {
 	ID          int
 	Name        string
 	Position    string
 	Salary      float64
 	Performance float64
+	Potential   Potential
 	LastUpdated time.Time
 }
 
+// Potential is a manager's assessment of how far an employee can grow
+type Potential int
+
+const (
+	PotentialUnassessed Potential = iota
+	PotentialLow
+	PotentialMedium
+	PotentialHigh
+)
+
+func (p Potential) String() string {
+	switch p {
+	case PotentialLow:
+		return "Low"
+	case PotentialMedium:
+		return "Medium"
+	case PotentialHigh:
+		return "High"
+	default:
+		return "Not assessed"
+	}
+}
+
+// Performance ratings below mediumPerformance are low and ratings from
+// highPerformance up are high on the nine-box grid
+const (
+	mediumPerformance = 2.5
+	highPerformance   = 4.0
+)
+
+// performanceLevel places a rating on the low (1), medium (2) or high (3) column of the grid
+func performanceLevel(rating float64) int {
+	switch {
+	case rating >= highPerformance:
+		return 3
+	case rating >= mediumPerformance:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// nineBoxCells names the cells of the nine-box grid by potential, then
+// performance, from low to high
+var nineBoxCells = [3][3]string{
+	{"Underperformer", "Effective", "Trusted Professional"},
+	{"Inconsistent Player", "Core Employee", "High Performer"},
+	{"Rough Diamond", "Growth Employee", "Star"},
+}
+
+// nineBoxLevels names the rows and columns of the nine-box grid
+var nineBoxLevels = [3]string{"Low", "Medium", "High"}
+
+// NineBox is the talent grid: the employees in each cell, by potential, then performance
+type NineBox [3][3][]Employee
+
 type EmployeeSystem struct {
 	employees     map[int]Employee
-	performance   map[int][]float64
+	performance   map[int][]Rating // each employee's ratings, oldest first
 	positionStats map[string]PositionStats
+	proposals     map[int]LevelChange // level changes awaiting review, by employee
+	onLevelChange func(LevelChange)   // told of every level change proposed or applied
 	mutex         sync.RWMutex
 	learningChan  chan Employee
-	done          chan struct{} // Add this channel for cleanup
+	done          chan struct{} // closed when the learning goroutine has stopped
 	ctx           context.Context
 	cancel        context.CancelFunc
+	clock         Clock
 }
 
+// Sentinel errors, each of one of the kinds in errorsx
 var (
-	ErrEmployeeNotFound = errors.New("employee not found")
-	ErrInvalidID        = errors.New("ID must be 100 or greater")
-	ErrDuplicateID      = errors.New("employee ID already exists")
-	ErrInvalidName      = errors.New("name must be 2-50 characters and contain only letters")
-	ErrInvalidPosition  = errors.New("position must be 2-50 characters")
-	ErrInvalidSalary    = errors.New("salary must be between 30000 and 500000")
-	ErrInvalidRating    = errors.New("performance rating must be between 0 and 5")
+	ErrEmployeeNotFound = errorsx.New(errorsx.ErrNotFound, "id", "employee not found")
+	ErrInvalidID        = errorsx.New(errorsx.ErrValidation, "id", "ID must be 100 or greater")
+	ErrDuplicateID      = errorsx.New(errorsx.ErrConflict, "id", "employee ID already exists")
+	ErrInvalidName      = errorsx.New(errorsx.ErrValidation, "name", "name must be 2-50 characters and contain only letters")
+	ErrInvalidPosition  = errorsx.New(errorsx.ErrValidation, "position", "position must be one of "+positionTitles())
+	ErrInvalidPotential = errorsx.New(errorsx.ErrValidation, "potential", "potential must be low, medium or high")
+	ErrNoLevelChange    = errorsx.New(errorsx.ErrNotFound, "id", "no level change is proposed for the employee")
+	ErrStaleLevelChange = errorsx.New(errorsx.ErrConflict, "position", "the employee's position has changed since the level change was proposed")
 )
 
+// Bounds of a performance rating
+const (
+	MinRating = 0.0
+	MaxRating = 5.0
+)
+
+// RangeError is a value outside its allowed range. The message is rendered
+// from the bounds in force, so it always states the range actually checked.
+type RangeError struct {
+	Field string
+	Min   float64
+	Max   float64 // 0 for no upper bound
+	Value float64
+}
+
+func (e *RangeError) Error() string {
+	if e.Max == 0 {
+		return fmt.Sprintf("%s must be at least %.2f, not %.2f", e.Field, e.Min, e.Value)
+	}
+	return fmt.Sprintf("%s must be between %.2f and %.2f, not %.2f", e.Field, e.Min, e.Max, e.Value)
+}
+
+// Is reports that a range error is a validation error
+func (e *RangeError) Is(target error) bool {
+	return target == errorsx.ErrValidation
+}
+
+// checkRange returns a RangeError if value lies outside [min, max]; a max of
+// 0 leaves it unbounded above
+func checkRange(field string, value, min, max float64) error {
+	if value < min || (max != 0 && value > max) {
+		return &RangeError{Field: field, Min: min, Max: max, Value: value}
+	}
+	return nil
+}
+
 // Input handling functions
 func readString(prompt string) string {
 	fmt.Print(prompt)
@@ -77,40 +568,58 @@ func readFloat(prompt string) (float64, error) {
 func validateName(name string) error {
 	name = strings.TrimSpace(name)
 	if len(name) < 2 || len(name) > 50 {
-		return ErrInvalidName
+		return ErrInvalidName.WithValue(name)
 	}
 	for _, r := range name {
 		if !unicode.IsLetter(r) && !unicode.IsSpace(r) {
-			return ErrInvalidName
+			return ErrInvalidName.WithValue(name)
 		}
 	}
 	return nil
 }
 
+// validateSalary checks a salary against the range of the compliance rules
 func validateSalary(salary float64) error {
-	if salary < MinSalary || salary > MaxSalary {
-		return fmt.Errorf("salary must be between %.2f and %.2f", MinSalary, MaxSalary)
+	r := complianceRules
+	if err := checkRange("salary", salary, r.MinimumWage, r.MaximumSalary); err != nil {
+		return fmt.Errorf("%w (%s rules)", err, r.Jurisdiction)
 	}
 	return nil
 }
 
-func validateRating(rating float64) error {
-	if rating < 0 || rating > 5 {
-		return ErrInvalidRating
+// validatePosition checks that a position is registered and that its band
+// pays the salary, returning the registered position
+func validatePosition(title string, salary float64) (Position, error) {
+	p, err := ParsePosition(title)
+	if err != nil {
+		return Position{}, err
 	}
-	return nil
+	if err := checkRange("salary", salary, p.MinSalary, p.MaxSalary); err != nil {
+		return Position{}, fmt.Errorf("%w (%s band)", err, p.Title)
+	}
+	return p, nil
+}
+
+func validateRating(rating float64) error {
+	return checkRange("performance rating", rating, MinRating, MaxRating)
 }
 
 func NewEmployeeSystem() *EmployeeSystem {
+	return NewEmployeeSystemWithClock(systemClock{})
+}
+
+func NewEmployeeSystemWithClock(clock Clock) *EmployeeSystem {
 	ctx, cancel := context.WithCancel(context.Background())
 	system := &EmployeeSystem{
 		employees:     make(map[int]Employee),
-		performance:   make(map[int][]float64),
+		performance:   make(map[int][]Rating),
 		positionStats: make(map[string]PositionStats),
+		proposals:     make(map[int]LevelChange),
 		learningChan:  make(chan Employee, 100),
 		done:          make(chan struct{}), // Initialize done channel
 		ctx:           ctx,
 		cancel:        cancel,
+		clock:         clock,
 	}
 	go system.selfLearning()
 	return system
@@ -118,7 +627,7 @@ func NewEmployeeSystem() *EmployeeSystem {
 
 func (es *EmployeeSystem) AddEmployee(emp Employee) error {
 	if emp.ID < 100 {
-		return ErrInvalidID
+		return ErrInvalidID.WithValue(emp.ID)
 	}
 	if err := validateName(emp.Name); err != nil {
 		return err
@@ -126,17 +635,22 @@ func (es *EmployeeSystem) AddEmployee(emp Employee) error {
 	if err := validateSalary(emp.Salary); err != nil {
 		return err
 	}
+	position, err := validatePosition(emp.Position, emp.Salary)
+	if err != nil {
+		return err
+	}
+	emp.Position = position.Title
 
 	es.mutex.Lock()
 	defer es.mutex.Unlock()
 
 	if _, exists := es.employees[emp.ID]; exists {
-		return ErrDuplicateID
+		return ErrDuplicateID.WithValue(emp.ID)
 	}
 
-	emp.LastUpdated = time.Now()
+	emp.LastUpdated = es.clock.Now()
 	es.employees[emp.ID] = emp
-	es.performance[emp.ID] = []float64{}
+	es.performance[emp.ID] = []Rating{}
 
 	select {
 	case es.learningChan <- emp:
@@ -148,7 +662,7 @@ func (es *EmployeeSystem) AddEmployee(emp Employee) error {
 
 func (es *EmployeeSystem) UpdateEmployee(emp Employee) error {
 	if emp.ID < 100 {
-		return ErrInvalidID
+		return ErrInvalidID.WithValue(emp.ID)
 	}
 	if err := validateName(emp.Name); err != nil {
 		return err
@@ -156,15 +670,20 @@ func (es *EmployeeSystem) UpdateEmployee(emp Employee) error {
 	if err := validateSalary(emp.Salary); err != nil {
 		return err
 	}
+	position, err := validatePosition(emp.Position, emp.Salary)
+	if err != nil {
+		return err
+	}
+	emp.Position = position.Title
 
 	es.mutex.Lock()
 	defer es.mutex.Unlock()
 
 	if _, exists := es.employees[emp.ID]; !exists {
-		return ErrEmployeeNotFound
+		return ErrEmployeeNotFound.WithValue(emp.ID)
 	}
 
-	emp.LastUpdated = time.Now()
+	emp.LastUpdated = es.clock.Now()
 	es.employees[emp.ID] = emp
 	return nil
 }
@@ -175,32 +694,32 @@ func (es *EmployeeSystem) GetEmployee(id int) (Employee, error) {
 
 	emp, exists := es.employees[id]
 	if !exists {
-		return Employee{}, ErrEmployeeNotFound
+		return Employee{}, ErrEmployeeNotFound.WithValue(id)
 	}
 	return emp, nil
 }
 
+// UpdatePerformance records a rating and updates the employee's average,
+// weighted by the age of their ratings.
+// When the average crosses a threshold of the promotion policy, the level
+// change is proposed, or applied, and the level change handler is told.
 func (es *EmployeeSystem) UpdatePerformance(id int, rating float64) error {
 	if err := validateRating(rating); err != nil {
 		return err
 	}
 
 	es.mutex.Lock()
-	defer es.mutex.Unlock()
-
 	emp, exists := es.employees[id]
 	if !exists {
-		return ErrEmployeeNotFound
+		es.mutex.Unlock()
+		return ErrEmployeeNotFound.WithValue(id)
 	}
 
-	es.performance[id] = append(es.performance[id], rating)
-
-	total := 0.0
-	for _, r := range es.performance[id] {
-		total += r
-	}
-	emp.Performance = total / float64(len(es.performance[id]))
-	emp.LastUpdated = time.Now()
+	now := es.clock.Now()
+	previous := emp.Performance
+	es.performance[id] = append(es.performance[id], Rating{Value: rating, Time: now})
+	emp.Performance = ratingWeighting.Average(es.performance[id], now)
+	emp.LastUpdated = now
 	es.employees[id] = emp
 
 	select {
@@ -208,9 +727,262 @@ func (es *EmployeeSystem) UpdatePerformance(id int, rating float64) error {
 	default:
 		// Non-blocking send to learning channel
 	}
+	change, changed := es.levelChangeLocked(emp, previous)
+	handler := es.onLevelChange
+	es.mutex.Unlock()
+
+	if changed && handler != nil {
+		handler(change)
+	}
+	return nil
+}
+
+// levelChangeLocked proposes, or applies, the level change an employee's
+// new average earns under the promotion policy, given their previous
+// average. The caller must hold the mutex.
+func (es *EmployeeSystem) levelChangeLocked(emp Employee, previous float64) (LevelChange, bool) {
+	policy := promotionPolicy
+	rated := len(es.performance[emp.ID])
+	if policy.Mode == promotionsOff || rated < policy.MinRatings {
+		return LevelChange{}, false
+	}
+	counted := rated-1 >= policy.MinRatings // whether the previous average counted too
+	current, err := ParsePosition(emp.Position)
+	if err != nil {
+		return LevelChange{}, false
+	}
+
+	var to Position
+	var found bool
+	switch {
+	case emp.Performance >= policy.PromoteAt && (!counted || previous < policy.PromoteAt):
+		to, found = positionAt(current.Level + 1)
+	case emp.Performance < policy.DemoteAt && (!counted || previous >= policy.DemoteAt):
+		to, found = positionAt(current.Level - 1)
+	}
+	if !found {
+		return LevelChange{}, false
+	}
+
+	change := LevelChange{
+		EmployeeID: emp.ID,
+		Name:       emp.Name,
+		From:       current.Title,
+		To:         to.Title,
+		Salary:     bandSalary(emp.Salary, to),
+		Average:    emp.Performance,
+		Time:       es.clock.Now(),
+	}
+	// A promotion the compliance rules cannot pay for is left for review
+	if policy.Mode == promotionsAuto && change.IsPromotion() && es.applyLocked(change) == nil {
+		change.Applied = true
+		return change, true
+	}
+	es.proposals[emp.ID] = change
+	return change, true
+}
+
+// applyLocked moves an employee to the position of a level change. The
+// caller must hold the mutex.
+func (es *EmployeeSystem) applyLocked(change LevelChange) error {
+	emp, exists := es.employees[change.EmployeeID]
+	if !exists {
+		delete(es.proposals, change.EmployeeID)
+		return ErrEmployeeNotFound.WithValue(change.EmployeeID)
+	}
+	if emp.Position != change.From {
+		delete(es.proposals, change.EmployeeID)
+		return ErrStaleLevelChange.WithValue(emp.Position)
+	}
+	if err := validateSalary(change.Salary); err != nil {
+		return err
+	}
+
+	before := emp
+	emp.Position = change.To
+	emp.Salary = change.Salary
+	emp.LastUpdated = es.clock.Now()
+	es.employees[emp.ID] = emp
+	delete(es.proposals, emp.ID)
+
+	// Both positions' statistics change
+	for _, e := range []Employee{before, emp} {
+		select {
+		case es.learningChan <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+// OnLevelChange sets the handler told of every level change the promotion
+// policy proposes or applies. It is called after the change is made, outside the lock.
+func (es *EmployeeSystem) OnLevelChange(handler func(LevelChange)) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	es.onLevelChange = handler
+}
+
+// LevelChanges returns the level changes awaiting review, by employee ID
+func (es *EmployeeSystem) LevelChanges() []LevelChange {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+
+	changes := make([]LevelChange, 0, len(es.proposals))
+	for _, c := range es.proposals {
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].EmployeeID < changes[j].EmployeeID })
+	return changes
+}
+
+// ApplyLevelChange applies the level change proposed for an employee and
+// tells the level change handler. A proposal the employee's position has
+// moved on from is dropped.
+func (es *EmployeeSystem) ApplyLevelChange(id int) (LevelChange, error) {
+	es.mutex.Lock()
+	change, exists := es.proposals[id]
+	if !exists {
+		es.mutex.Unlock()
+		return LevelChange{}, ErrNoLevelChange.WithValue(id)
+	}
+	err := es.applyLocked(change)
+	handler := es.onLevelChange
+	es.mutex.Unlock()
+	if err != nil {
+		return LevelChange{}, err
+	}
+
+	change.Applied = true
+	change.Time = es.clock.Now()
+	if handler != nil {
+		handler(change)
+	}
+	return change, nil
+}
+
+// DismissLevelChange drops the level change proposed for an employee
+func (es *EmployeeSystem) DismissLevelChange(id int) error {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	if _, exists := es.proposals[id]; !exists {
+		return ErrNoLevelChange.WithValue(id)
+	}
+	delete(es.proposals, id)
+	return nil
+}
+
+// announceLevelChange prints a level change as it is proposed or applied
+func announceLevelChange(c LevelChange) {
+	switch {
+	case c.Applied && c.IsPromotion():
+		fmt.Printf("Congratulations! %s has been promoted to %s (average rating %.2f, salary %.2f)\n", c.Name, c.To, c.Average, c.Salary)
+	case c.Applied:
+		fmt.Printf("%s has moved from %s to %s (average rating %.2f, salary %.2f)\n", c.Name, c.From, c.To, c.Average, c.Salary)
+	default:
+		fmt.Printf("Proposed: %s (ID %d) from %s to %s for an average rating of %.2f; review it under Review Level Changes\n",
+			c.Name, c.EmployeeID, c.From, c.To, c.Average)
+	}
+}
+
+// SetPotential records an employee's assessed potential
+func (es *EmployeeSystem) SetPotential(id int, potential Potential) error {
+	if potential < PotentialLow || potential > PotentialHigh {
+		return ErrInvalidPotential.WithValue(potential)
+	}
+
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	emp, exists := es.employees[id]
+	if !exists {
+		return ErrEmployeeNotFound.WithValue(id)
+	}
+	emp.Potential = potential
+	emp.LastUpdated = es.clock.Now()
+	es.employees[id] = emp
 	return nil
 }
 
+// TalentReview places employees on the nine-box grid by performance and
+// potential. Employees without a rating or a potential assessment cannot be
+// placed and are returned separately.
+func (es *EmployeeSystem) TalentReview() (NineBox, []Employee) {
+	var grid NineBox
+	var unplaced []Employee
+
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+	for _, emp := range es.employees {
+		if emp.Potential == PotentialUnassessed || len(es.performance[emp.ID]) == 0 {
+			unplaced = append(unplaced, emp)
+			continue
+		}
+		row, col := int(emp.Potential)-1, performanceLevel(emp.Performance)-1
+		grid[row][col] = append(grid[row][col], emp)
+	}
+
+	byID := func(emps []Employee) {
+		sort.Slice(emps, func(i, j int) bool { return emps[i].ID < emps[j].ID })
+	}
+	for row := range grid {
+		for col := range grid[row] {
+			byID(grid[row][col])
+		}
+	}
+	byID(unplaced)
+	return grid, unplaced
+}
+
+// WriteCSV writes one row per employee on the grid with the cell they are in
+func (g NineBox) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"cell", "potential", "performance", "id", "name", "position", "rating"}); err != nil {
+		return err
+	}
+	for row := range g {
+		for col, emps := range g[row] {
+			for _, emp := range emps {
+				record := []string{
+					nineBoxCells[row][col], nineBoxLevels[row], nineBoxLevels[col],
+					strconv.Itoa(emp.ID), emp.Name, emp.Position,
+					strconv.FormatFloat(emp.Performance, 'f', 2, 64),
+				}
+				if err := out.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// printNineBox shows the number of employees in each cell, high potential at the top.
+// Cells are numbered 1-9 for drill-down, from the top left.
+func printNineBox(grid NineBox) {
+	fmt.Printf("%-12s| %-28s| %-28s| %-28s\n", "Potential", "Low Performance", "Medium Performance", "High Performance")
+	fmt.Println(strings.Repeat("-", 102))
+	for i := 0; i < 3; i++ {
+		row := 2 - i
+		fmt.Printf("%-12s", Potential(row+1))
+		for col := 0; col < 3; col++ {
+			cell := fmt.Sprintf("%d. %s (%d)", i*3+col+1, nineBoxCells[row][col], len(grid[row][col]))
+			fmt.Printf("| %-28s", cell)
+		}
+		fmt.Println()
+	}
+}
+
+// nineBoxCell returns the row and column of a cell numbered as printNineBox shows it
+func nineBoxCell(n int) (row, col int, ok bool) {
+	if n < 1 || n > 9 {
+		return 0, 0, false
+	}
+	return 2 - (n-1)/3, (n - 1) % 3, true
+}
+
 func (es *EmployeeSystem) GetAllEmployees() []Employee {
 	es.mutex.RLock()
 	defer es.mutex.RUnlock()
@@ -219,56 +991,267 @@ func (es *EmployeeSystem) GetAllEmployees() []Employee {
 	for _, emp := range es.employees {
 		employees = append(employees, emp)
 	}
+	// Map iteration order is random, so return employees by ID
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
 	return employees
 }
 
+// RunPayroll pays every employee for a week under the compliance rules.
+// hours holds the hours each employee worked; others worked standard hours.
+func (es *EmployeeSystem) RunPayroll(hours map[int]float64) []Payslip {
+	employees := es.GetAllEmployees()
+	slips := make([]Payslip, 0, len(employees))
+	for _, emp := range employees {
+		worked, ok := hours[emp.ID]
+		if !ok {
+			worked = complianceRules.StandardHours
+		}
+		slips = append(slips, complianceRules.Pay(emp, worked))
+	}
+	return slips
+}
+
+// Shutdown stops the learning goroutine and waits for it to finish the
+// updates already queued. It is safe to call more than once.
 func (es *EmployeeSystem) Shutdown() {
-	close(es.done) // Signal the goroutine to stop
+	es.cancel()
+	<-es.done
+}
+
+// SaveSnapshot writes all employees to a JSON file that Lab_Exercise_06's
+// migrate command can read. The file is written under a temporary name and
+// renamed, so an interrupted save never leaves a half-written snapshot.
+func (es *EmployeeSystem) SaveSnapshot(path string) error {
+	data, err := json.MarshalIndent(es.GetAllEmployees(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
 func (es *EmployeeSystem) selfLearning() {
+	defer close(es.done)
 	for {
 		select {
 		case emp := <-es.learningChan:
-			es.mutex.Lock()
-			stats := PositionStats{
-				LastUpdated: time.Now(),
+			es.learn(emp)
+		case <-es.ctx.Done():
+			// Finish the updates already queued before stopping
+			for {
+				select {
+				case emp := <-es.learningChan:
+					es.learn(emp)
+				default:
+					return
+				}
 			}
+		}
+	}
+}
 
-			var totalPerf float64
-			var count int
-			var totalSalary float64
+// positionStatsLocked computes the statistics of a position. The caller must hold the mutex.
+func (es *EmployeeSystem) positionStatsLocked(position string) PositionStats {
+	stats := PositionStats{
+		LastUpdated: es.clock.Now(),
+	}
 
-			for _, e := range es.employees {
-				if e.Position == emp.Position {
-					totalPerf += e.Performance
-					totalSalary += e.Salary
-					count++
-				}
-			}
+	var salaries, performances []float64
 
-			if count > 0 {
-				stats.AvgPerformance = totalPerf / float64(count)
-				stats.EmployeeCount = count
-				stats.TotalSalary = totalSalary
-				es.positionStats[emp.Position] = stats
+	for _, e := range es.employees {
+		if e.Position == position {
+			stats.TotalSalary += e.Salary
+			salaries = append(salaries, e.Salary)
+			performances = append(performances, e.Performance)
+			for _, rating := range es.performance[e.ID] {
+				stats.Ratings[int(math.Round(rating.Value))]++
 			}
-			es.mutex.Unlock()
+		}
+	}
+
+	stats.EmployeeCount = len(salaries)
+	stats.Salary = distribution(salaries)
+	stats.Performance = distribution(performances)
+	stats.AvgPerformance = stats.Performance.Mean
+	return stats
+}
+
+// learn updates the statistics of an employee's position and reports them
+func (es *EmployeeSystem) learn(emp Employee) {
+	es.mutex.Lock()
+	stats := es.positionStatsLocked(emp.Position)
+	count := stats.EmployeeCount
+	if count > 0 {
+		es.positionStats[emp.Position] = stats
+	}
+	es.mutex.Unlock()
+
+	fmt.Printf("\n🤖 Learning System Update:\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("Position: %s\n", emp.Position)
+	fmt.Printf("Employees in Position: %d\n", count)
+	fmt.Printf("Average Performance: %.2f\n", stats.AvgPerformance)
+	if count > 0 {
+		fmt.Printf("Average Salary: %.2f (std dev %.2f)\n", stats.Salary.Mean, stats.Salary.StdDev)
+		fmt.Printf("Salary P25/P50/P75/P90: %.2f / %.2f / %.2f / %.2f\n",
+			stats.Salary.P25, stats.Salary.P50, stats.Salary.P75, stats.Salary.P90)
+		fmt.Printf("Performance Median: %.2f (std dev %.2f)\n", stats.Performance.P50, stats.Performance.StdDev)
+		fmt.Printf("Ratings 0/1/2/3/4/5: %s\n", ratingCounts(stats.Ratings))
+	}
+	fmt.Printf("Last Updated: %s\n", stats.LastUpdated.In(displayLocation).Format("15:04:05"))
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+}
+
+// Repair recomputes what is derived from the employees and their ratings:
+// each employee's performance, the average of their ratings, and the
+// statistics of every position. The learning system only updates the
+// position of the employee it is told about, so statistics go stale when
+// an employee moves to another position; with -rating-window, performance
+// also goes stale as ratings leave the window, and Repair weights them as of
+// now. Repair returns what it corrected.
+func (es *EmployeeSystem) Repair() []string {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
 
-			fmt.Printf("\n🤖 Learning System Update:\n")
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-			fmt.Printf("Position: %s\n", emp.Position)
-			fmt.Printf("Employees in Position: %d\n", count)
-			fmt.Printf("Average Performance: %.2f\n", stats.AvgPerformance)
-			if count > 0 {
-				fmt.Printf("Average Salary: %.2f\n", totalSalary/float64(count))
+	var fixed []string
+	now := es.clock.Now()
+	ids := make([]int, 0, len(es.employees))
+	for id := range es.employees {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	positions := make(map[string]bool)
+	for _, id := range ids {
+		emp := es.employees[id]
+		positions[emp.Position] = true
+		ratings, ok := es.performance[id]
+		if !ok {
+			es.performance[id] = []Rating{}
+			fixed = append(fixed, fmt.Sprintf("%s (ID %d) had no rating history; started an empty one", emp.Name, id))
+			continue
+		}
+		if len(ratings) == 0 {
+			continue
+		}
+		if average := ratingWeighting.Average(ratings, now); math.Abs(emp.Performance-average) > 1e-9 {
+			fixed = append(fixed, fmt.Sprintf("%s (ID %d) had performance %.2f; the average of their ratings is %.2f", emp.Name, id, emp.Performance, average))
+			emp.Performance = average
+			es.employees[id] = emp
+		}
+	}
+	for id := range es.performance {
+		if _, exists := es.employees[id]; !exists {
+			delete(es.performance, id)
+			fixed = append(fixed, fmt.Sprintf("dropped the ratings of ID %d, who is not an employee", id))
+		}
+	}
+
+	names := make([]string, 0, len(positions)+len(es.positionStats))
+	for position := range positions {
+		names = append(names, position)
+	}
+	for position := range es.positionStats {
+		if !positions[position] {
+			names = append(names, position)
+		}
+	}
+	sort.Strings(names)
+	for _, position := range names {
+		old, had := es.positionStats[position]
+		if !positions[position] {
+			delete(es.positionStats, position)
+			fixed = append(fixed, fmt.Sprintf("dropped the statistics of %s, which no one holds", position))
+			continue
+		}
+		stats := es.positionStatsLocked(position)
+		switch {
+		case !had:
+			fixed = append(fixed, fmt.Sprintf("computed the missing statistics of %s", position))
+		case old.EmployeeCount != stats.EmployeeCount || math.Abs(old.TotalSalary-stats.TotalSalary) > 0.005 ||
+			math.Abs(old.AvgPerformance-stats.AvgPerformance) > 1e-9 || old.Ratings != stats.Ratings:
+			fixed = append(fixed, fmt.Sprintf("%s had %d employee(s) paid %.2f with average performance %.2f; it has %d paid %.2f with %.2f",
+				position, old.EmployeeCount, old.TotalSalary, old.AvgPerformance, stats.EmployeeCount, stats.TotalSalary, stats.AvgPerformance))
+		}
+		es.positionStats[position] = stats
+	}
+	return fixed
+}
+
+// ratingCounts formats how many ratings were given at each score
+func ratingCounts(ratings [6]int) string {
+	counts := make([]string, len(ratings))
+	for i, n := range ratings {
+		counts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(counts, " / ")
+}
+
+// Calibrate compares the average rating of each position with the company
+// mean and suggests scores with each position's offset removed. Only employees
+// who have been rated are included, so a missing rating does not count as 0.
+func (es *EmployeeSystem) Calibrate() (float64, []PositionCalibration, []CalibratedScore) {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+
+	var total float64
+	byPosition := make(map[string][]Employee)
+	for _, emp := range es.employees {
+		if len(es.performance[emp.ID]) == 0 {
+			continue
+		}
+		total += emp.Performance
+		byPosition[emp.Position] = append(byPosition[emp.Position], emp)
+	}
+
+	rated := 0
+	for _, emps := range byPosition {
+		rated += len(emps)
+	}
+	if rated == 0 {
+		return 0, nil, nil
+	}
+	companyMean := total / float64(rated)
+
+	positions := make([]PositionCalibration, 0, len(byPosition))
+	var scores []CalibratedScore
+	for position, emps := range byPosition {
+		var sum float64
+		for _, emp := range emps {
+			sum += emp.Performance
+		}
+		c := PositionCalibration{Position: position, Rated: len(emps), Mean: sum / float64(len(emps))}
+		c.Offset = c.Mean - companyMean
+		positions = append(positions, c)
+
+		for _, emp := range emps {
+			suggested := emp.Performance
+			if c.Rated >= 2 {
+				suggested = math.Max(MinRating, math.Min(MaxRating, emp.Performance-c.Offset))
 			}
-			fmt.Printf("Last Updated: %s\n", stats.LastUpdated.Format("15:04:05"))
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-		case <-es.ctx.Done():
-			return // Exit goroutine cleanly
+			scores = append(scores, CalibratedScore{Employee: emp, Suggested: suggested})
 		}
 	}
+
+	// Positions that rate furthest from the mean come first
+	sort.Slice(positions, func(i, j int) bool {
+		if a, b := math.Abs(positions[i].Offset), math.Abs(positions[j].Offset); a != b {
+			return a > b
+		}
+		return positions[i].Position < positions[j].Position
+	})
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Employee.ID < scores[j].Employee.ID })
+	return companyMean, positions, scores
 }
 
 func getEmployeeInput() (Employee, error) {
@@ -282,9 +1265,9 @@ func getEmployeeInput() (Employee, error) {
 		return Employee{}, err
 	}
 
-	position := readString("Enter Position: ")
-	if len(position) < 2 {
-		return Employee{}, ErrInvalidPosition
+	position := readString(fmt.Sprintf("Enter Position (%s): ", positionTitles()))
+	if _, err := ParsePosition(position); err != nil {
+		return Employee{}, err
 	}
 
 	salary, err := readFloat("Enter Salary: ")
@@ -303,12 +1286,95 @@ func getEmployeeInput() (Employee, error) {
 	}, nil
 }
 
+// exitCode is the status a shell reports for a process killed by sig, 128 plus the signal number
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// shutdown stops the learning system and saves a snapshot if one was requested.
+// It is called on every exit, including when the program is interrupted.
+func shutdown(system *EmployeeSystem, snapshot string) error {
+	system.Shutdown()
+	if snapshot == "" {
+		return nil
+	}
+	if err := system.SaveSnapshot(snapshot); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	fmt.Printf("Employees saved to %s\n", snapshot)
+	return nil
+}
+
 func main() {
+	timeZone := flag.String("tz", os.Getenv("EMS_TIMEZONE"), "time zone for displaying timestamps (e.g. Asia/Kolkata)")
+	snapshot := flag.String("snapshot", "", "save all employees to this JSON file on exit, including when interrupted")
+	rulesFile := flag.String("rules", "", "JSON file of compliance rule packs (minimum wage, overtime, mandatory benefits) per jurisdiction")
+	jurisdiction := flag.String("jurisdiction", "", "jurisdiction whose rule pack from -rules applies")
+	flag.StringVar(&promotionPolicy.Mode, "promotions", promotionPolicy.Mode, "what an average rating crossing a threshold does: off, propose the level change, or auto to apply promotions")
+	flag.Float64Var(&promotionPolicy.PromoteAt, "promote-at", promotionPolicy.PromoteAt, "average rating that earns the next level up")
+	flag.Float64Var(&promotionPolicy.DemoteAt, "demote-at", promotionPolicy.DemoteAt, "average rating below which the level below is proposed (0 for never)")
+	flag.IntVar(&promotionPolicy.MinRatings, "promotion-ratings", promotionPolicy.MinRatings, "ratings an employee needs before their average changes their level")
+	flag.DurationVar(&ratingWeighting.HalfLife, "rating-half-life", 0, "age at which a rating counts half as much as a new one, e.g. 4380h for six months (0 for no decay)")
+	flag.DurationVar(&ratingWeighting.Window, "rating-window", 0, "leave ratings older than this out of performance, except the latest, e.g. 8760h for a year (0 for every rating)")
+	flag.Parse()
+
+	if err := promotionPolicy.validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := ratingWeighting.validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *rulesFile != "" || *jurisdiction != "" {
+		if *rulesFile == "" || *jurisdiction == "" {
+			fmt.Println("Error: -rules and -jurisdiction must be given together")
+			os.Exit(2)
+		}
+		rules, err := loadRulePack(*rulesFile, *jurisdiction)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		complianceRules = rules
+	}
+	if *timeZone != "" {
+		loc, err := time.LoadLocation(*timeZone)
+		if err != nil {
+			fmt.Printf("Error: unknown time zone %q\n", *timeZone)
+			os.Exit(2)
+		}
+		displayLocation = loc
+	}
+
 	system := NewEmployeeSystem()
-	defer system.Shutdown() // Ensure cleanup happens
+	system.OnLevelChange(announceLevelChange)
+
+	// Ctrl-C or SIGTERM stops the learning system and saves the snapshot
+	// before exiting, instead of killing the program in the middle of an update
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals) // a second Ctrl-C kills the program
+		fmt.Printf("\nReceived %v, shutting down...\n", sig)
+		if err := shutdown(system, *snapshot); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		os.Exit(exitCode(sig))
+	}()
 
 	fmt.Printf("\nWelcome to Employee Management System\n")
-	fmt.Printf("Valid salary range: %.2f - %.2f\n", MinSalary, MaxSalary)
+	fmt.Printf("Compliance rules: %s\n", complianceRules.Jurisdiction)
+	if complianceRules.MaximumSalary != 0 {
+		fmt.Printf("Valid salary range: %.2f - %.2f\n", complianceRules.MinimumWage, complianceRules.MaximumSalary)
+	} else {
+		fmt.Printf("Minimum salary: %.2f\n", complianceRules.MinimumWage)
+	}
 
 	for {
 		fmt.Println("\n=== Employee Management System ===")
@@ -317,9 +1383,15 @@ func main() {
 		fmt.Println("3. View Employee")
 		fmt.Println("4. Update Performance")
 		fmt.Println("5. View All Employees")
-		fmt.Println("6. Exit")
+		fmt.Println("6. Run Payroll")
+		fmt.Println("7. Calibration Report")
+		fmt.Println("8. Assess Potential")
+		fmt.Println("9. Talent Review (Nine-Box)")
+		fmt.Println("10. Repair Statistics")
+		fmt.Println("11. Review Level Changes")
+		fmt.Println("12. Exit")
 
-		choice, err := readInt("Enter your choice (1-6): ")
+		choice, err := readInt("Enter your choice (1-12): ")
 		if err != nil {
 			fmt.Println("Invalid input. Please enter a number.")
 			continue
@@ -366,7 +1438,8 @@ func main() {
 				fmt.Printf("Position: %s\n", emp.Position)
 				fmt.Printf("Salary: %.2f\n", emp.Salary)
 				fmt.Printf("Performance: %.2f\n", emp.Performance)
-				fmt.Printf("Last Updated: %s\n", emp.LastUpdated.Format("2006-01-02 15:04:05"))
+				fmt.Printf("Potential: %s\n", emp.Potential)
+				fmt.Printf("Last Updated: %s\n", emp.LastUpdated.In(displayLocation).Format("2006-01-02 15:04:05"))
 			}
 
 		case 4:
@@ -375,7 +1448,7 @@ func main() {
 				fmt.Println("Invalid ID format")
 				continue
 			}
-			rating, err := readFloat("Enter Performance Rating (0-5): ")
+			rating, err := readFloat(fmt.Sprintf("Enter Performance Rating (%.0f-%.0f): ", MinRating, MaxRating))
 			if err != nil {
 				fmt.Println("Invalid rating format")
 				continue
@@ -400,17 +1473,170 @@ func main() {
 				fmt.Printf("Position: %s\n", emp.Position)
 				fmt.Printf("Salary: %.2f\n", emp.Salary)
 				fmt.Printf("Performance: %.2f\n", emp.Performance)
-				fmt.Printf("Last Updated: %s\n", emp.LastUpdated.Format("2006-01-02 15:04:05"))
+				fmt.Printf("Last Updated: %s\n", emp.LastUpdated.In(displayLocation).Format("2006-01-02 15:04:05"))
 				fmt.Println("----------------------------------------")
 			}
 
 		case 6:
+			employees := system.GetAllEmployees()
+			if len(employees) == 0 {
+				fmt.Println("No employees found!")
+				continue
+			}
+			hours := make(map[int]float64)
+			for _, emp := range employees {
+				input := readString(fmt.Sprintf("Hours worked this week by %s (blank for %.0f): ", emp.Name, complianceRules.StandardHours))
+				if input == "" {
+					continue
+				}
+				worked, err := strconv.ParseFloat(input, 64)
+				if err != nil || worked < 0 {
+					fmt.Println("Invalid hours, using standard hours")
+					continue
+				}
+				hours[emp.ID] = worked
+			}
+
+			fmt.Printf("\nWeekly Payroll (%s rules):\n", complianceRules.Jurisdiction)
+			fmt.Println("----------------------------------------")
+			var total float64
+			for _, slip := range system.RunPayroll(hours) {
+				fmt.Printf("%s (ID %d): %.1f hours\n", slip.Employee.Name, slip.Employee.ID, slip.Hours)
+				fmt.Printf("  Regular: %.2f  Overtime: %.2f  Gross: %.2f\n", slip.RegularPay, slip.OvertimePay, slip.GrossPay)
+				fmt.Printf("  Mandatory benefits: %.2f  Employer total: %.2f\n", slip.BenefitsCost, slip.EmployerTotal)
+				total += slip.EmployerTotal
+			}
+			fmt.Println("----------------------------------------")
+			fmt.Printf("Total employer cost: %.2f\n", total)
+
+		case 7:
+			companyMean, positions, scores := system.Calibrate()
+			if len(positions) == 0 {
+				fmt.Println("No employees have been rated yet!")
+				continue
+			}
+			fmt.Printf("\nPerformance Calibration (company mean %.2f):\n", companyMean)
+			fmt.Println("----------------------------------------")
+			for _, c := range positions {
+				fmt.Printf("%s: %d rated, mean %.2f (%+.2f) - %s\n", c.Position, c.Rated, c.Mean, c.Offset, c.Bias())
+			}
+			fmt.Println("----------------------------------------")
+			fmt.Println("Suggested normalized scores:")
+			for _, score := range scores {
+				fmt.Printf("%s (ID %d, %s): %.2f -> %.2f\n", score.Employee.Name, score.Employee.ID,
+					score.Employee.Position, score.Employee.Performance, score.Suggested)
+			}
+
+		case 8:
+			id, err := readInt("Enter Employee ID: ")
+			if err != nil {
+				fmt.Println("Invalid ID format")
+				continue
+			}
+			var potential Potential
+			switch strings.ToLower(readString("Enter Potential (low/medium/high): ")) {
+			case "low", "l":
+				potential = PotentialLow
+			case "medium", "m":
+				potential = PotentialMedium
+			case "high", "h":
+				potential = PotentialHigh
+			}
+			if err := system.SetPotential(id, potential); err != nil {
+				fmt.Printf("Error assessing potential: %v\n", err)
+			} else {
+				fmt.Println("Potential assessed successfully!")
+			}
+
+		case 9:
+			grid, unplaced := system.TalentReview()
+			fmt.Println("\nTalent Review:")
+			printNineBox(grid)
+			if len(unplaced) > 0 {
+				fmt.Printf("%d employee(s) need a rating and a potential assessment to be placed\n", len(unplaced))
+			}
+
+			if path := readString("Export grid to CSV file (blank to skip): "); path != "" {
+				f, err := os.Create(path)
+				if err == nil {
+					err = grid.WriteCSV(f)
+					if closeErr := f.Close(); err == nil {
+						err = closeErr
+					}
+				}
+				if err != nil {
+					fmt.Printf("Error exporting grid: %v\n", err)
+				} else {
+					fmt.Printf("Grid exported to %s\n", path)
+				}
+			}
+
+			for {
+				input := readString("Cell to list (1-9, blank to return): ")
+				if input == "" {
+					break
+				}
+				n, _ := strconv.Atoi(input)
+				row, col, ok := nineBoxCell(n)
+				if !ok {
+					fmt.Println("Invalid cell! Please enter a number between 1 and 9.")
+					continue
+				}
+				emps := grid[row][col]
+				fmt.Printf("\n%s - %s potential, %s performance:\n", nineBoxCells[row][col], nineBoxLevels[row], nineBoxLevels[col])
+				if len(emps) == 0 {
+					fmt.Println("No employees in this cell")
+				}
+				for _, emp := range emps {
+					fmt.Printf("%d: %s, %s, rating %.2f\n", emp.ID, emp.Name, emp.Position, emp.Performance)
+				}
+			}
+
+		case 10:
+			fixed := system.Repair()
+			if len(fixed) == 0 {
+				fmt.Println("The statistics agree with the employees and their ratings.")
+				continue
+			}
+			fmt.Printf("\nRepaired %d problem(s):\n", len(fixed))
+			for _, f := range fixed {
+				fmt.Printf("- %s\n", f)
+			}
+
+		case 11:
+			changes := system.LevelChanges()
+			if len(changes) == 0 {
+				fmt.Println("No level changes are awaiting review.")
+				continue
+			}
+			fmt.Printf("\n%d level change(s) awaiting review:\n", len(changes))
+			for _, c := range changes {
+				fmt.Printf("\n%s (ID %d): %s -> %s, average rating %.2f, salary %.2f, proposed %s\n", c.Name, c.EmployeeID,
+					c.From, c.To, c.Average, c.Salary, c.Time.In(displayLocation).Format("2006-01-02 15:04"))
+				switch strings.ToLower(readString("Apply it? (y = apply, n = dismiss, blank to decide later): ")) {
+				case "y", "yes":
+					if _, err := system.ApplyLevelChange(c.EmployeeID); err != nil {
+						fmt.Printf("Error applying level change: %v\n", err)
+					}
+				case "n", "no":
+					if err := system.DismissLevelChange(c.EmployeeID); err != nil {
+						fmt.Printf("Error dismissing level change: %v\n", err)
+					} else {
+						fmt.Println("Level change dismissed.")
+					}
+				}
+			}
+
+		case 12:
 			fmt.Println("Thank you for using the Employee Management System!")
-			system.Shutdown()
+			if err := shutdown(system, *snapshot); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 			return
 
 		default:
-			fmt.Println("Invalid choice! Please enter a number between 1 and 6.")
+			fmt.Println("Invalid choice! Please enter a number between 1 and 12.")
 		}
 	}
 }
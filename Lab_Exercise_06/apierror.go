@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// API error codes. They are part of the API: clients match on them, so a
+// code is never renamed or reused for a different error.
+const (
+	CodeNotFound             = "NOT_FOUND"
+	CodeConflict             = "CONFLICT"
+	CodeEmployeeNotFound     = "EMPLOYEE_NOT_FOUND"
+	CodeDuplicateID          = "DUPLICATE_ID"
+	CodeInvalidID            = "INVALID_ID"
+	CodeValidationFailed     = "VALIDATION_FAILED"
+	CodePermissionDenied     = "PERMISSION_DENIED"
+	CodeUnauthenticated      = "UNAUTHENTICATED"
+	CodeReadOnly             = "READ_ONLY"
+	CodeBudgetExceeded       = "BUDGET_EXCEEDED"
+	CodePendingApproval      = "PENDING_APPROVAL"
+	CodePreconditionFailed   = "PRECONDITION_FAILED"
+	CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	CodeRequestInProgress    = "REQUEST_IN_PROGRESS"
+	CodeUnsupportedMedia     = "UNSUPPORTED_MEDIA_TYPE"
+	CodeNotImplemented       = "NOT_IMPLEMENTED"
+	CodeInternal             = "INTERNAL_ERROR"
+)
+
+// apiErrorCodes map the sentinel errors to their status and code. The first
+// that matches an error is used; errors without a code of their own fall
+// back to the code of their kind.
+var apiErrorCodes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrEmployeeNotFound, http.StatusNotFound, CodeEmployeeNotFound},
+	{ErrDuplicateID, http.StatusConflict, CodeDuplicateID},
+	{ErrInvalidID, http.StatusBadRequest, CodeInvalidID},
+	{ErrInvalidInput, http.StatusBadRequest, CodeValidationFailed},
+	{ErrPermissionDenied, http.StatusForbidden, CodePermissionDenied},
+	{ErrUnauthenticated, http.StatusUnauthorized, CodeUnauthenticated},
+	{ErrReadOnly, http.StatusForbidden, CodeReadOnly},
+	{ErrBudgetExceeded, http.StatusUnprocessableEntity, CodeBudgetExceeded},
+	{ErrPendingApproval, http.StatusAccepted, CodePendingApproval},
+	{errorsx.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{errorsx.ErrValidation, http.StatusBadRequest, CodeValidationFailed},
+	{errorsx.ErrConflict, http.StatusConflict, CodeConflict},
+}
+
+// apiErrorJSON is the JSON representation of an error in the HTTP API
+type apiErrorJSON struct {
+	Code    string           `json:"code"`
+	Message string           `json:"error"`            // in the client's language
+	Detail  string           `json:"detail,omitempty"` // the underlying error, in English
+	Fields  []fieldErrorJSON `json:"fields,omitempty"`
+}
+
+// fieldErrorJSON is the JSON representation of an invalid field
+type fieldErrorJSON struct {
+	Field   string `json:"field"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"` // in the client's language
+}
+
+// apiLanguages are the languages of API messages, the first being the default
+var apiLanguages = []string{"en", "es", "fr", "de"}
+
+// apiMessages holds the message for each error code and field reason, by language
+var apiMessages = map[string]map[string]string{
+	"en": {
+		CodeNotFound:             "Not found.",
+		CodeConflict:             "The request conflicts with the current state.",
+		CodeEmployeeNotFound:     "Employee not found.",
+		CodeDuplicateID:          "An employee with this ID already exists.",
+		CodeInvalidID:            "The employee ID is not valid.",
+		CodeValidationFailed:     "The request is not valid.",
+		CodePermissionDenied:     "You do not have permission to do this.",
+		CodeUnauthenticated:      "Send a valid API key to do this.",
+		CodeReadOnly:             "This server is read-only.",
+		CodeBudgetExceeded:       "This would exceed the salary budget.",
+		CodePendingApproval:      "The change was submitted for approval.",
+		CodePreconditionFailed:   "The employee has changed since it was read; fetch it again and retry.",
+		CodeIdempotencyKeyReused: "This idempotency key was already used for a different request.",
+		CodeRequestInProgress:    "A request with this idempotency key is in progress.",
+		CodeUnsupportedMedia:     "The request body is not in a supported format.",
+		CodeNotImplemented:       "This is not available for the server's storage.",
+		CodeInternal:             "Something went wrong on the server.",
+		ReasonRequired:           "This field is required.",
+		ReasonInvalidNumber:      "This field must be a number.",
+		ReasonNegative:           "This field cannot be negative.",
+		ReasonUnknownDepartment:  "Unknown department.",
+		ReasonInvalidDate:        "This field must be a date in YYYY-MM-DD format.",
+		ReasonFutureDate:         "This date cannot be in the future.",
+		ReasonImmutable:          "This field cannot be changed.",
+		ReasonInvalidType:        "This field has the wrong type.",
+	},
+	"es": {
+		CodeNotFound:             "No se encontró.",
+		CodeConflict:             "La solicitud entra en conflicto con el estado actual.",
+		CodeEmployeeNotFound:     "No se encontró el empleado.",
+		CodeDuplicateID:          "Ya existe un empleado con este ID.",
+		CodeInvalidID:            "El ID de empleado no es válido.",
+		CodeValidationFailed:     "La solicitud no es válida.",
+		CodePermissionDenied:     "No tiene permiso para hacer esto.",
+		CodeUnauthenticated:      "Envíe una clave de API válida para hacer esto.",
+		CodeReadOnly:             "Este servidor es de solo lectura.",
+		CodeBudgetExceeded:       "Esto superaría el presupuesto salarial.",
+		CodePendingApproval:      "El cambio se envió para su aprobación.",
+		CodePreconditionFailed:   "El empleado ha cambiado desde que se leyó; vuelva a obtenerlo e inténtelo de nuevo.",
+		CodeIdempotencyKeyReused: "Esta clave de idempotencia ya se usó para otra solicitud.",
+		CodeRequestInProgress:    "Hay una solicitud en curso con esta clave de idempotencia.",
+		CodeUnsupportedMedia:     "El cuerpo de la solicitud no tiene un formato compatible.",
+		CodeNotImplemented:       "Esto no está disponible para el almacenamiento del servidor.",
+		CodeInternal:             "Se produjo un error en el servidor.",
+		ReasonRequired:           "Este campo es obligatorio.",
+		ReasonInvalidNumber:      "Este campo debe ser un número.",
+		ReasonNegative:           "Este campo no puede ser negativo.",
+		ReasonUnknownDepartment:  "Departamento desconocido.",
+		ReasonInvalidDate:        "Este campo debe ser una fecha con el formato AAAA-MM-DD.",
+		ReasonFutureDate:         "Esta fecha no puede estar en el futuro.",
+		ReasonImmutable:          "Este campo no se puede cambiar.",
+		ReasonInvalidType:        "Este campo tiene un tipo incorrecto.",
+	},
+	"fr": {
+		CodeNotFound:             "Introuvable.",
+		CodeConflict:             "La requête est en conflit avec l'état actuel.",
+		CodeEmployeeNotFound:     "Employé introuvable.",
+		CodeDuplicateID:          "Un employé avec cet identifiant existe déjà.",
+		CodeInvalidID:            "L'identifiant de l'employé n'est pas valide.",
+		CodeValidationFailed:     "La requête n'est pas valide.",
+		CodePermissionDenied:     "Vous n'avez pas l'autorisation de faire cela.",
+		CodeUnauthenticated:      "Envoyez une clé d'API valide pour faire cela.",
+		CodeReadOnly:             "Ce serveur est en lecture seule.",
+		CodeBudgetExceeded:       "Cela dépasserait le budget salarial.",
+		CodePendingApproval:      "La modification a été soumise pour approbation.",
+		CodePreconditionFailed:   "L'employé a changé depuis sa lecture ; récupérez-le à nouveau et réessayez.",
+		CodeIdempotencyKeyReused: "Cette clé d'idempotence a déjà servi pour une autre requête.",
+		CodeRequestInProgress:    "Une requête avec cette clé d'idempotence est en cours.",
+		CodeUnsupportedMedia:     "Le corps de la requête n'est pas dans un format pris en charge.",
+		CodeNotImplemented:       "Ceci n'est pas disponible pour le stockage du serveur.",
+		CodeInternal:             "Une erreur s'est produite sur le serveur.",
+		ReasonRequired:           "Ce champ est obligatoire.",
+		ReasonInvalidNumber:      "Ce champ doit être un nombre.",
+		ReasonNegative:           "Ce champ ne peut pas être négatif.",
+		ReasonUnknownDepartment:  "Service inconnu.",
+		ReasonInvalidDate:        "Ce champ doit être une date au format AAAA-MM-JJ.",
+		ReasonFutureDate:         "Cette date ne peut pas être dans le futur.",
+		ReasonImmutable:          "Ce champ ne peut pas être modifié.",
+		ReasonInvalidType:        "Ce champ n'a pas le bon type.",
+	},
+	"de": {
+		CodeNotFound:             "Nicht gefunden.",
+		CodeConflict:             "Die Anfrage steht im Konflikt mit dem aktuellen Zustand.",
+		CodeEmployeeNotFound:     "Mitarbeiter nicht gefunden.",
+		CodeDuplicateID:          "Ein Mitarbeiter mit dieser ID existiert bereits.",
+		CodeInvalidID:            "Die Mitarbeiter-ID ist ungültig.",
+		CodeValidationFailed:     "Die Anfrage ist ungültig.",
+		CodePermissionDenied:     "Sie haben keine Berechtigung dafür.",
+		CodeUnauthenticated:      "Senden Sie einen gültigen API-Schlüssel, um dies zu tun.",
+		CodeReadOnly:             "Dieser Server ist schreibgeschützt.",
+		CodeBudgetExceeded:       "Dies würde das Gehaltsbudget überschreiten.",
+		CodePendingApproval:      "Die Änderung wurde zur Genehmigung eingereicht.",
+		CodePreconditionFailed:   "Der Mitarbeiter wurde seit dem Lesen geändert; rufen Sie ihn erneut ab und versuchen Sie es noch einmal.",
+		CodeIdempotencyKeyReused: "Dieser Idempotenzschlüssel wurde bereits für eine andere Anfrage verwendet.",
+		CodeRequestInProgress:    "Eine Anfrage mit diesem Idempotenzschlüssel wird gerade bearbeitet.",
+		CodeUnsupportedMedia:     "Der Anfragetext hat kein unterstütztes Format.",
+		CodeNotImplemented:       "Dies ist für den Speicher des Servers nicht verfügbar.",
+		CodeInternal:             "Auf dem Server ist ein Fehler aufgetreten.",
+		ReasonRequired:           "Dieses Feld ist erforderlich.",
+		ReasonInvalidNumber:      "Dieses Feld muss eine Zahl sein.",
+		ReasonNegative:           "Dieses Feld darf nicht negativ sein.",
+		ReasonUnknownDepartment:  "Unbekannte Abteilung.",
+		ReasonInvalidDate:        "Dieses Feld muss ein Datum im Format JJJJ-MM-TT sein.",
+		ReasonFutureDate:         "Dieses Datum darf nicht in der Zukunft liegen.",
+		ReasonImmutable:          "Dieses Feld kann nicht geändert werden.",
+		ReasonInvalidType:        "Dieses Feld hat den falschen Typ.",
+	},
+}
+
+// negotiateLanguage picks the language of API messages from an
+// Accept-Language header, preferring the languages the client weights
+// highest and falling back to the default
+func negotiateLanguage(header string) string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var wanted []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 {
+			wanted = append(wanted, weighted{primary, q})
+		}
+	}
+	sort.SliceStable(wanted, func(i, j int) bool { return wanted[i].q > wanted[j].q })
+	for _, w := range wanted {
+		if _, ok := apiMessages[w.lang]; ok {
+			return w.lang
+		}
+	}
+	return apiLanguages[0]
+}
+
+// localize returns the message for a code or reason in a language
+func localize(lang, key string) string {
+	if msg, ok := apiMessages[lang][key]; ok {
+		return msg
+	}
+	return apiMessages[apiLanguages[0]][key]
+}
+
+// writeAPIError writes an error response with a code, its message in the
+// client's language and the English detail
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...*FieldError) {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+	out := apiErrorJSON{Code: code, Message: localize(lang, code), Detail: detail}
+	for _, f := range fields {
+		out.Fields = append(out.Fields, fieldErrorJSON{Field: f.Field, Reason: f.Reason, Message: localize(lang, f.Reason)})
+	}
+	w.Header().Set("Content-Language", lang)
+	writeJSON(w, status, out)
+}
+
+// writeError writes an error as a JSON response, mapping known errors to
+// status codes and error codes
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusInternalServerError, CodeInternal
+	for _, c := range apiErrorCodes {
+		if errors.Is(err, c.err) {
+			status, code = c.status, c.code
+			break
+		}
+	}
+	appTelemetry.Error(err)
+	var fields []*FieldError
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		fields = append(fields, fe)
+	}
+	writeAPIError(w, r, status, code, err.Error(), fields...)
+}
+
+// decodeError describes a failure to decode a request body, naming the field
+// when a value has the wrong type
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		want := "string"
+		switch typeErr.Type.Kind() {
+		case reflect.Int, reflect.Float64:
+			want = "number"
+		}
+		return fieldError(typeErr.Field, ReasonInvalidType, "%s must be a %s, not %s", typeErr.Field, want, typeErr.Value)
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// APIKey is a key clients of the HTTP API send as a bearer token, and the
+// user it stands for, as written in an API keys file
+type APIKey struct {
+	Key        string `json:"key"`
+	User       string `json:"user"`
+	Role       string `json:"role"`
+	EmployeeID int    `json:"employee_id,omitempty"`
+}
+
+// APIKeys are the users of the API keys, by the SHA-256 hash of the key, so
+// keys are not compared byte by byte in memory
+type APIKeys map[[sha256.Size]byte]User
+
+// appAPIKeys are the keys the HTTP API accepts. main loads them from the
+// -api-keys flag; without it no request is authenticated.
+var appAPIKeys APIKeys
+
+// minAPIKeyLength is the length below which a key is too easy to guess
+const minAPIKeyLength = 32
+
+// LoadAPIKeys reads an API keys file
+func LoadAPIKeys(path string) (APIKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseAPIKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseAPIKeys reads a list of APIKey in JSON and returns the user of each key
+func ParseAPIKeys(data []byte) (APIKeys, error) {
+	var list []APIKey
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	keys := make(APIKeys, len(list))
+	for _, k := range list {
+		role, err := StringToRole(k.Role)
+		switch {
+		case strings.TrimSpace(k.User) == "":
+			return nil, fmt.Errorf("%w: every API key needs a user", ErrInvalidInput)
+		case len(k.Key) < minAPIKeyLength:
+			return nil, fmt.Errorf("%w: the API key of %q must be at least %d characters", ErrInvalidInput, k.User, minAPIKeyLength)
+		case err != nil:
+			return nil, fmt.Errorf("%w: the API key of %q has unknown role %q (employee, manager, hr, admin)", ErrInvalidInput, k.User, k.Role)
+		case role == RoleEmployee && k.EmployeeID == 0:
+			return nil, fmt.Errorf("%w: the API key of %q has the employee role, which needs an employee_id", ErrInvalidInput, k.User)
+		}
+		hash := sha256.Sum256([]byte(k.Key))
+		if _, ok := keys[hash]; ok {
+			return nil, fmt.Errorf("%w: the API key of %q is used twice", ErrInvalidInput, k.User)
+		}
+		keys[hash] = User{Name: k.User, Role: role, EmployeeID: k.EmployeeID}
+	}
+	return keys, nil
+}
+
+// authenticate returns the user whose API key a request carries in its
+// Authorization header, as a bearer token or as the password of basic
+// authentication, which browsers ask for to open the dashboard. Without API
+// keys no request is authenticated.
+func (s *Server) authenticate(r *http.Request) (User, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if _, password, basic := r.BasicAuth(); basic {
+		token, ok = password, true
+	}
+	if !ok || token == "" {
+		return User{}, fmt.Errorf("%w: send an API key as a bearer token", ErrUnauthenticated)
+	}
+	user, ok := s.apiKeys[sha256.Sum256([]byte(token))]
+	if !ok {
+		return User{}, fmt.Errorf("%w: the API key is not valid", ErrUnauthenticated)
+	}
+	return user, nil
+}
+
+// access decides whether an authenticated user may make a request. The
+// rules are the menus': the employee role only reaches its own record.
+type access func(user User, r *http.Request) error
+
+// staffOnly lets every role but the employee role make a request
+func staffOnly(user User, r *http.Request) error {
+	if user.SelfServiceOnly() {
+		return fmt.Errorf("%w: the employee role can only view its own record", ErrPermissionDenied)
+	}
+	return nil
+}
+
+// ownOrStaff also lets employees make a request for their own record, the
+// {id} of the path
+func ownOrStaff(user User, r *http.Request) error {
+	if id, err := strconv.Atoi(r.PathValue("id")); err == nil && user.SelfServiceOnly() && id == user.EmployeeID {
+		return nil
+	}
+	return staffOnly(user, r)
+}
+
+// ownNotifications lets users with PermManageNotifications reach their own
+// notification settings, the {user} of the path, since these hold their
+// webhooks and mail servers
+func ownNotifications(user User, r *http.Request) error {
+	if err := user.Require(PermManageNotifications); err != nil {
+		return err
+	}
+	if r.PathValue("user") != user.Name {
+		return fmt.Errorf("%w: users can only reach their own notification settings", ErrPermissionDenied)
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPendingApproval is returned when a change has been queued for approval instead of applied
+var ErrPendingApproval = errors.New("submitted for approval")
+
+// Approval request kind constants using iota
+const (
+	RequestSalaryChange = iota
+	RequestTransfer
+	RequestNewHire
+	RequestLeave
+)
+
+// RequestKindToString converts an approval request kind constant to string
+func RequestKindToString(kind int) string {
+	switch kind {
+	case RequestSalaryChange:
+		return "Salary Change"
+	case RequestTransfer:
+		return "Transfer"
+	case RequestNewHire:
+		return "New Hire"
+	case RequestLeave:
+		return "Leave"
+	default:
+		return "Unknown"
+	}
+}
+
+// Approval status constants using iota
+const (
+	StatusPending = iota
+	StatusApproved
+	StatusRejected
+)
+
+// ApprovalStatusToString converts an approval status constant to string
+func ApprovalStatusToString(status int) string {
+	switch status {
+	case StatusPending:
+		return "Pending"
+	case StatusApproved:
+		return "Approved"
+	case StatusRejected:
+		return "Rejected"
+	default:
+		return "Unknown"
+	}
+}
+
+// ApprovalPolicy decides which changes need approval
+type ApprovalPolicy struct {
+	SalaryChangePercent float64 // salary changes larger than this percentage need approval; 0 disables
+	Transfers           bool    // every transfer needs approval
+	// LeaveDays is the most working days of leave granted without approval;
+	// longer requests need it. 0 leaves every request to a leave approver.
+	LeaveDays float64
+}
+
+// ApprovalRequest is a change waiting for, or decided by, an approver
+type ApprovalRequest struct {
+	ID            int
+	Kind          int
+	Status        int
+	RequestedBy   string
+	RequestedAt   time.Time
+	DecidedBy     string
+	DecidedAt     time.Time
+	Reason        string     // given when rejected
+	Note          string     // why the change needs approval, if not the policy
+	Before        Employee   // the employee when the change was requested
+	After         Employee   // the proposed record, for salary changes, new hires and transfers made by updating it
+	NewDepartment Department // for transfers
+	EffectiveDate time.Time
+	Leave         *LeaveRequest // for leave, the request as made
+}
+
+// String returns a one-line summary of the request
+func (r *ApprovalRequest) String() string {
+	subject := fmt.Sprintf("for %s (ID %d)", r.Before.Name, r.Before.ID)
+	var change string
+	switch r.Kind {
+	case RequestSalaryChange:
+		change = fmt.Sprintf("salary $%.2f -> $%.2f", r.Before.Salary, r.After.Salary)
+	case RequestTransfer:
+		change = fmt.Sprintf("%s -> %s effective %s", r.Before.Department,
+			r.NewDepartment, formatDate(r.EffectiveDate))
+	case RequestNewHire:
+		subject = r.After.Name
+		change = fmt.Sprintf("%s in %s at $%.2f", r.After.Position, r.After.Department, r.After.Salary)
+	case RequestLeave:
+		change = fmt.Sprintf("%.1f day(s) of leave, %s", r.Leave.Days, r.Leave.Span())
+	}
+	summary := fmt.Sprintf("#%d %s %s: %s, requested by %s [%s]", r.ID, RequestKindToString(r.Kind),
+		subject, change, r.RequestedBy, ApprovalStatusToString(r.Status))
+	if r.Note != "" {
+		summary += " (" + r.Note + ")"
+	}
+	return summary
+}
+
+// ApprovalManager decorates an EmployeeManager so that salary changes and
+// transfers above the policy's thresholds are queued until an approver accepts
+// them. Queued writes return ErrPendingApproval; everything else is passed
+// through. Leave requests longer than the policy allows are queued too, once
+// QueueLeave links the leave.
+//
+// Requests cannot be decided by the person who made them, so the queue can be
+// kept in a JSON file shared by the sessions of requesters and approvers.
+type ApprovalManager struct {
+	EmployeeManager
+	policy ApprovalPolicy
+	user   User
+	path   string // file the queue is kept in, or "" to keep it in memory
+	leave  *Leave // set by QueueLeave
+
+	mu       sync.Mutex
+	requests []*ApprovalRequest
+	nextID   int
+}
+
+// NewApprovalManager creates an ApprovalManager that records user as the
+// requester of queued changes. If path is not empty the queue is loaded from
+// and saved to that file.
+func NewApprovalManager(inner EmployeeManager, policy ApprovalPolicy, user User, path string) (*ApprovalManager, error) {
+	a := &ApprovalManager{EmployeeManager: inner, policy: policy, user: user, path: path, nextID: 1}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// load reads the queue from the file, picking up requests made or decided in
+// other sessions. The caller must hold the lock, except during construction.
+func (a *ApprovalManager) load() error {
+	if a.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var requests []*ApprovalRequest
+	if err := json.Unmarshal(data, &requests); err != nil {
+		return fmt.Errorf("reading approval queue %s: %w", a.path, err)
+	}
+	a.requests = requests
+	a.nextID = 1
+	for _, r := range requests {
+		a.nextID = max(a.nextID, r.ID+1)
+	}
+	return nil
+}
+
+// save writes the queue to the file. The caller must hold the lock. The file
+// is written under a temporary name and renamed, and an interrupt waits for
+// the save, so the queue is never left half written.
+func (a *ApprovalManager) save() error {
+	if a.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(a.requests, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := a.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, a.path)
+	})
+}
+
+// QueueLeave has leave requests longer than the policy's LeaveDays queued
+// for approval, and shorter ones granted as they are made
+func (a *ApprovalManager) QueueLeave(l *Leave) {
+	a.leave, l.approvals = l, a
+}
+
+// Unwrap returns the wrapped manager
+func (a *ApprovalManager) Unwrap() EmployeeManager {
+	return a.EmployeeManager
+}
+
+// needsSalaryApproval reports whether a salary change exceeds the policy's threshold
+func (a *ApprovalManager) needsSalaryApproval(from, to float64) bool {
+	if a.policy.SalaryChangePercent <= 0 || from == to {
+		return false
+	}
+	if from == 0 {
+		return true
+	}
+	return math.Abs(to-from)/from*100 > a.policy.SalaryChangePercent
+}
+
+// submit adds a request to the queue and returns ErrPendingApproval describing it
+func (a *ApprovalManager) submit(r *ApprovalRequest) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(); err != nil {
+		return err
+	}
+	r.ID = a.nextID
+	a.nextID++
+	r.Status = StatusPending
+	r.RequestedBy = a.user.Name
+	r.RequestedAt = clockOf(a.EmployeeManager).Now()
+	a.requests = append(a.requests, r)
+	if err := a.save(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: request #%d", ErrPendingApproval, r.ID)
+}
+
+// UpdateEmployee applies the update, or queues it if the salary change
+// needs approval or it moves the employee to another department while
+// transfers do. A queued move is a transfer request that applies the whole
+// update when approved.
+func (a *ApprovalManager) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	current, err := a.EmployeeManager.GetEmployee(e.ID)
+	if err != nil {
+		return err
+	}
+	transfer := a.policy.Transfers && e.Department != current.Department
+	if !transfer && !a.needsSalaryApproval(current.Salary, e.Salary) {
+		return a.EmployeeManager.UpdateEmployee(e)
+	}
+	now := clockOf(a.EmployeeManager).Now()
+	if err := validateEmployee(e, now); err != nil {
+		return err
+	}
+	if transfer {
+		return a.submit(&ApprovalRequest{Kind: RequestTransfer, Before: *current, After: *e, NewDepartment: e.Department, EffectiveDate: now})
+	}
+	return a.submit(&ApprovalRequest{Kind: RequestSalaryChange, Before: *current, After: *e})
+}
+
+// TransferEmployee applies the transfer, or queues it if transfers need approval
+func (a *ApprovalManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !a.policy.Transfers {
+		return a.EmployeeManager.TransferEmployee(id, newDept, effectiveDate)
+	}
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+	current, err := a.EmployeeManager.GetEmployee(id)
+	if err != nil {
+		return err
+	}
+	if current.Department == newDept {
+		return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+	}
+	return a.submit(&ApprovalRequest{Kind: RequestTransfer, Before: *current, NewDepartment: newDept, EffectiveDate: effectiveDate})
+}
+
+// Requests returns the requests with the given status, oldest first
+func (a *ApprovalManager) Requests(status int) ([]ApprovalRequest, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	requests := make([]ApprovalRequest, 0)
+	for _, r := range a.requests {
+		if r.Status == status {
+			requests = append(requests, *r)
+		}
+	}
+	return requests, nil
+}
+
+// decide finds a pending request for an approver. The caller must hold the lock.
+func (a *ApprovalManager) decide(approver User, id int) (*ApprovalRequest, error) {
+	if err := approver.Require(PermApproveChanges); err != nil {
+		return nil, err
+	}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	for _, r := range a.requests {
+		if r.ID != id {
+			continue
+		}
+		if r.Status != StatusPending {
+			return nil, fmt.Errorf("%w: request #%d is already %s", ErrInvalidInput, id,
+				ApprovalStatusToString(r.Status))
+		}
+		if r.RequestedBy == approver.Name {
+			return nil, fmt.Errorf("%w: requests cannot be approved or rejected by the person who made them", ErrPermissionDenied)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("%w: no approval request #%d", ErrInvalidInput, id)
+}
+
+// Approve applies a pending request. If the employee has changed since the
+// request was made, the request stays pending so it can be rejected and resubmitted.
+func (a *ApprovalManager) Approve(approver User, id int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r, err := a.decide(approver, id)
+	if err != nil {
+		return err
+	}
+
+	if r.Kind != RequestNewHire && r.Kind != RequestLeave {
+		current, err := a.EmployeeManager.GetEmployee(r.Before.ID)
+		if err != nil {
+			return err
+		}
+		if len(DiffEmployees(&r.Before, current)) > 0 {
+			return fmt.Errorf("%w: %s has changed since request #%d was made", ErrInvalidInput, current.Name, id)
+		}
+	}
+
+	switch r.Kind {
+	case RequestSalaryChange:
+		after := r.After
+		err = a.EmployeeManager.UpdateEmployee(&after)
+	case RequestTransfer:
+		if r.After.ID != 0 {
+			after := r.After
+			err = a.EmployeeManager.UpdateEmployee(&after)
+		} else {
+			err = a.EmployeeManager.TransferEmployee(r.Before.ID, r.NewDepartment, r.EffectiveDate)
+		}
+	case RequestNewHire:
+		after := r.After
+		err = a.EmployeeManager.AddEmployee(&after)
+	case RequestLeave:
+		err = a.decideLeave(approver, r, true, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	r.Status = StatusApproved
+	r.DecidedBy = approver.Name
+	r.DecidedAt = clockOf(a.EmployeeManager).Now()
+	return a.save()
+}
+
+// Reject discards a pending request
+func (a *ApprovalManager) Reject(approver User, id int, reason string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r, err := a.decide(approver, id)
+	if err != nil {
+		return err
+	}
+	if r.Kind == RequestLeave {
+		if err := a.decideLeave(approver, r, false, reason); err != nil {
+			return err
+		}
+	}
+	r.Status = StatusRejected
+	r.DecidedBy = approver.Name
+	r.DecidedAt = clockOf(a.EmployeeManager).Now()
+	r.Reason = reason
+	return a.save()
+}
+
+// decideLeave approves or rejects the leave request a queued request is for
+func (a *ApprovalManager) decideLeave(approver User, r *ApprovalRequest, approve bool, reason string) error {
+	if a.leave == nil || r.Leave == nil {
+		return fmt.Errorf("%w: leave is not available to decide request #%d", ErrInvalidInput, r.ID)
+	}
+	var err error
+	if approve {
+		_, err = a.leave.approve(approver, r.Leave.ID, true)
+	} else {
+		_, err = a.leave.reject(approver, r.Leave.ID, reason, true)
+	}
+	return err
+}
+
+// approvalsInteractive lists pending requests and lets an approver decide them
+func approvalsInteractive(manager EmployeeManager, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Approval Queue ==="))
+
+	approvals, ok := capability[*ApprovalManager](manager)
+	if !ok {
+		return fmt.Errorf("%w: approvals are not enabled", ErrInvalidInput)
+	}
+
+	pending, err := approvals.Requests(StatusPending)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("\nNo requests are waiting for approval.")
+		return nil
+	}
+	for i := range pending {
+		fmt.Println(&pending[i])
+	}
+
+	if err := user.Require(PermApproveChanges); err != nil {
+		return err
+	}
+
+	id, err := readInt(reader, "\nRequest number to decide (blank to skip): ")
+	if err != nil || id == 0 {
+		return err
+	}
+
+	fmt.Println("\n1. Approve")
+	fmt.Println("2. Reject")
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		if err := approvals.Approve(user, id); err != nil {
+			return err
+		}
+		fmt.Printf("\nRequest #%d approved and applied.\n", id)
+	case 2:
+		reason, err := readString(reader, "Reason: ")
+		if err != nil {
+			return err
+		}
+		if err := approvals.Reject(user, id, reason); err != nil {
+			return err
+		}
+		fmt.Printf("\nRequest #%d rejected.\n", id)
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	// Employees hear of decisions on their leave as from the leave menu
+	for _, r := range pending {
+		if r.ID == id && r.Kind == RequestLeave && approvals.leave != nil {
+			if decided, ok := approvals.leave.request(r.Leave.ID); ok {
+				appNotifications.Publish(leaveNotification(&decided, manager))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpdateToAnotherDepartmentIsHeldForApproval(t *testing.T) {
+	store := NewInMemoryEmployeeManagerWithClock(NewFakeClock(dateOf(2024, 6, 1)))
+	ann := &Employee{Name: "Ann", Position: "Engineer", Salary: 90000, Department: Engineering, JoinDate: dateOf(2020, 3, 1)}
+	if err := store.AddEmployee(ann); err != nil {
+		t.Fatal(err)
+	}
+	approvals, err := NewApprovalManager(store, ApprovalPolicy{Transfers: true}, User{Name: "ann", Role: RoleHR}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"name":"Ann","position":"Engineer","salary":90000,"department":"Finance","join_date":"2020-03-01"}`
+	w := httptest.NewRecorder()
+	newTestServer(approvals).ServeHTTP(w, as("ann", httptest.NewRequest(http.MethodPut, "/employees/1", strings.NewReader(body))))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("PUT to another department returned %d, want 202: %s", w.Code, w.Body)
+	}
+	if e, _ := store.GetEmployee(ann.ID); e.Department != Engineering {
+		t.Fatalf("the employee was moved to %s before approval", e.Department)
+	}
+	pending, err := approvals.Requests(StatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Kind != RequestTransfer || pending[0].NewDepartment != Finance {
+		t.Fatalf("pending requests are %v, want one transfer to Finance", pending)
+	}
+
+	if err := approvals.Approve(User{Name: "bob", Role: RoleManager}, pending[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := store.GetEmployee(ann.ID); e.Department != Finance {
+		t.Errorf("approved transfer left the employee in %s", e.Department)
+	}
+
+	// Updates that keep the department are applied at once
+	ann.Position = "Lead"
+	ann.Department = Finance
+	if err := approvals.UpdateEmployee(ann); err != nil {
+		t.Errorf("update within the department returned %v, want it applied", err)
+	}
+}
+
+func TestLongLeaveIsQueuedForApproval(t *testing.T) {
+	store := NewInMemoryEmployeeManagerWithClock(NewFakeClock(dateOf(2024, 6, 1)))
+	ann := &Employee{Name: "Ann", Position: "Engineer", Salary: 90000, Department: Engineering, JoinDate: dateOf(2020, 3, 1)}
+	if err := store.AddEmployee(ann); err != nil {
+		t.Fatal(err)
+	}
+	hr, approver := User{Name: "ann", Role: RoleHR}, User{Name: "bob", Role: RoleManager}
+	approvals, err := NewApprovalManager(store, ApprovalPolicy{LeaveDays: 3}, hr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leave, err := NewLeave(approvals, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvals.QueueLeave(leave)
+	if err := leave.SetBalance(hr, ann.ID, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two days are granted at once
+	short, err := leave.Request(hr, ann.ID, dateOf(2024, 6, 3), dateOf(2024, 6, 4), false, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short.Status != StatusApproved || leave.balance(ann.ID) != 18 {
+		t.Errorf("short leave is %s with %.1f day(s) left, want approved with 18", ApprovalStatusToString(short.Status), leave.balance(ann.ID))
+	}
+
+	// A week waits in the approval queue, and only there
+	long, err := leave.Request(hr, ann.ID, dateOf(2024, 6, 10), dateOf(2024, 6, 14), false, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if long.Status != StatusPending || !long.Queued {
+		t.Fatalf("long leave is %s, want pending in the approval queue", ApprovalStatusToString(long.Status))
+	}
+	if _, err := leave.Approve(approver, long.ID); err == nil {
+		t.Error("a leave approver approved leave waiting in the approval queue")
+	}
+	pending, err := approvals.Requests(StatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Kind != RequestLeave || pending[0].Leave.ID != long.ID {
+		t.Fatalf("pending requests are %v, want the leave request", pending)
+	}
+	if err := approvals.Approve(approver, pending[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if decided, _ := leave.request(long.ID); decided.Status != StatusApproved || leave.balance(ann.ID) != 13 {
+		t.Errorf("approved leave is %s with %.1f day(s) left, want approved with 13", ApprovalStatusToString(decided.Status), leave.balance(ann.ID))
+	}
+}
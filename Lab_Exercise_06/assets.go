@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// Errors returned by the asset register
+var (
+	ErrAssetNotFound     = errorsx.New(errorsx.ErrNotFound, "tag", "asset not issued")
+	ErrAssetIssued       = errorsx.New(errorsx.ErrConflict, "tag", "asset is already issued")
+	ErrAssetsOutstanding = errorsx.New(errorsx.ErrConflict, "", "company assets have not been returned")
+)
+
+// Asset kind constants using iota
+const (
+	AssetLaptop = iota
+	AssetBadge
+	AssetPhone
+	AssetOther
+)
+
+// AssetKindToString converts an asset kind constant to string
+func AssetKindToString(kind int) string {
+	switch kind {
+	case AssetLaptop:
+		return "Laptop"
+	case AssetBadge:
+		return "Badge"
+	case AssetPhone:
+		return "Phone"
+	case AssetOther:
+		return "Other"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToAssetKind converts string to asset kind constant
+func StringToAssetKind(kind string) (int, error) {
+	switch strings.ToLower(kind) {
+	case "laptop":
+		return AssetLaptop, nil
+	case "badge":
+		return AssetBadge, nil
+	case "phone":
+		return AssetPhone, nil
+	case "other":
+		return AssetOther, nil
+	default:
+		return -1, fmt.Errorf("%w: asset kind must be laptop, badge, phone or other", ErrInvalidInput)
+	}
+}
+
+// AssetAssignment is a company asset issued to an employee. It is
+// outstanding until it is returned or written off as lost.
+type AssetAssignment struct {
+	Tag         string    `json:"tag"` // asset tag or serial number
+	Kind        int       `json:"kind"`
+	Description string    `json:"description,omitempty"`
+	EmployeeID  int       `json:"employee_id"`
+	Issued      time.Time `json:"issued"`
+	Returned    time.Time `json:"returned,omitempty"` // zero while outstanding
+	Lost        bool      `json:"lost,omitempty"`     // written off instead of returned
+}
+
+// Outstanding reports whether the employee still has the asset
+func (a *AssetAssignment) Outstanding() bool {
+	return a.Returned.IsZero()
+}
+
+// Assets is the register of company assets issued to employees. Employees
+// cannot be removed while they have assets outstanding, so offboarding
+// collects everything first.
+type Assets struct {
+	mu          sync.Mutex
+	manager     EmployeeManager
+	assignments []*AssetAssignment
+	path        string // JSON file the register is kept in, or "" for memory only
+}
+
+// NewAssets creates an asset register for employees of the given manager,
+// reading it from the file at path if there is one
+func NewAssets(manager EmployeeManager, path string) (*Assets, error) {
+	a := &Assets{manager: manager, path: path}
+	if path == "" {
+		return a, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &a.assignments); err != nil {
+		return nil, fmt.Errorf("reading assets %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// save writes the register to the file. The caller must hold the lock. The
+// file is written under a temporary name and renamed, and an interrupt
+// waits for the save, so it is never left half written.
+func (a *Assets) save() error {
+	if a.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(a.assignments, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := a.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, a.path)
+	})
+}
+
+// outstanding returns the assignment of an asset that has not come back,
+// or nil. The caller must hold the lock.
+func (a *Assets) outstanding(tag string) *AssetAssignment {
+	for _, as := range a.assignments {
+		if strings.EqualFold(as.Tag, tag) && as.Outstanding() {
+			return as
+		}
+	}
+	return nil
+}
+
+// Issue records an asset handed to an employee
+func (a *Assets) Issue(employeeID, kind int, tag, description string) (*AssetAssignment, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return nil, fmt.Errorf("%w: asset tag cannot be empty", ErrInvalidInput)
+	}
+	if AssetKindToString(kind) == "Unknown" {
+		return nil, fmt.Errorf("%w: please select a valid asset kind", ErrInvalidInput)
+	}
+	if _, err := a.manager.GetEmployee(employeeID); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if held := a.outstanding(tag); held != nil {
+		return nil, fmt.Errorf("%w: %s is with employee %d", ErrAssetIssued.WithValue(tag), tag, held.EmployeeID)
+	}
+	as := &AssetAssignment{
+		Tag:         tag,
+		Kind:        kind,
+		Description: strings.TrimSpace(description),
+		EmployeeID:  employeeID,
+		Issued:      clockOf(a.manager).Now(),
+	}
+	a.assignments = append(a.assignments, as)
+	if err := a.save(); err != nil {
+		a.assignments = a.assignments[:len(a.assignments)-1]
+		return nil, err
+	}
+	copied := *as
+	return &copied, nil
+}
+
+// Return records an issued asset as handed back, or written off as lost
+func (a *Assets) Return(tag string, lost bool) (*AssetAssignment, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	as := a.outstanding(strings.TrimSpace(tag))
+	if as == nil {
+		return nil, ErrAssetNotFound.WithValue(tag)
+	}
+	as.Returned, as.Lost = clockOf(a.manager).Now(), lost
+	if err := a.save(); err != nil {
+		as.Returned, as.Lost = time.Time{}, false
+		return nil, err
+	}
+	copied := *as
+	return &copied, nil
+}
+
+// ForEmployee returns the assets ever issued to an employee, oldest first
+func (a *Assets) ForEmployee(employeeID int) []AssetAssignment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var list []AssetAssignment
+	for _, as := range a.assignments {
+		if as.EmployeeID == employeeID {
+			list = append(list, *as)
+		}
+	}
+	return list
+}
+
+// Outstanding returns every asset not yet returned, by employee and then
+// by when it was issued
+func (a *Assets) Outstanding() []AssetAssignment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var list []AssetAssignment
+	for _, as := range a.assignments {
+		if as.Outstanding() {
+			list = append(list, *as)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].EmployeeID < list[j].EmployeeID
+	})
+	return list
+}
+
+// OutstandingFor returns the assets an employee has not returned, oldest first
+func (a *Assets) OutstandingFor(employeeID int) []AssetAssignment {
+	var list []AssetAssignment
+	for _, as := range a.ForEmployee(employeeID) {
+		if as.Outstanding() {
+			list = append(list, as)
+		}
+	}
+	return list
+}
+
+// CheckReturned stops an employee being removed while they still have
+// company assets. It is registered as a HookedManager.BeforeRemove hook.
+func (a *Assets) CheckReturned(e *Employee) error {
+	held := a.OutstandingFor(e.ID)
+	if len(held) == 0 {
+		return nil
+	}
+	tags := make([]string, len(held))
+	for i, as := range held {
+		tags[i] = as.Tag
+	}
+	return fmt.Errorf("%w: %s still has %s; return or write them off first",
+		ErrAssetsOutstanding.WithValue(tags), e.Name, strings.Join(tags, ", "))
+}
+
+// assetStatus describes where an asset is
+func assetStatus(as AssetAssignment) string {
+	switch {
+	case as.Outstanding():
+		return "Outstanding"
+	case as.Lost:
+		return "Lost " + formatDate(as.Returned)
+	default:
+		return "Returned " + formatDate(as.Returned)
+	}
+}
+
+// writeEmployeeAssets writes the assets issued to an employee
+func writeEmployeeAssets(w io.Writer, list []AssetAssignment) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "\nNo assets issued.")
+		return
+	}
+	fmt.Fprintf(w, "\n%-16s %-8s %-24s %-12s %s\n", "Tag", "Kind", "Description", "Issued", "Status")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	for _, as := range list {
+		fmt.Fprintf(w, "%-16s %-8s %-24s %-12s %s\n", as.Tag, AssetKindToString(as.Kind),
+			as.Description, formatDate(as.Issued), assetStatus(as))
+	}
+}
+
+// assetDays returns how many whole days an asset has been out at now
+func assetDays(as AssetAssignment, now time.Time) int {
+	return int(now.Sub(as.Issued).Hours() / 24)
+}
+
+// writeOutstandingAssets writes the outstanding assets report, naming each
+// holder from the manager
+func writeOutstandingAssets(w io.Writer, list []AssetAssignment, manager EmployeeManager, now time.Time) {
+	fmt.Fprintln(w, "\n=== Outstanding Assets ===")
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No assets outstanding.")
+		return
+	}
+	fmt.Fprintf(w, "%-6s %-20s %-12s %-16s %-8s %-12s %5s\n", "ID", "Employee", "Department", "Tag", "Kind", "Issued", "Days")
+	fmt.Fprintln(w, strings.Repeat("-", 86))
+	for _, as := range list {
+		name, dept := "(removed)", ""
+		if e, err := manager.GetEmployee(as.EmployeeID); err == nil {
+			name, dept = e.Name, e.Department.String()
+		}
+		fmt.Fprintf(w, "%-6d %-20s %-12s %-16s %-8s %-12s %5d\n", as.EmployeeID, name, dept,
+			as.Tag, AssetKindToString(as.Kind), formatDate(as.Issued), assetDays(as, now))
+	}
+	fmt.Fprintf(w, "\nTotal outstanding: %d\n", len(list))
+}
+
+// writeOutstandingAssetsCSV writes the outstanding assets as CSV with a header row
+func writeOutstandingAssetsCSV(w io.Writer, list []AssetAssignment, manager EmployeeManager, now time.Time) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"employee_id", "employee", "department", "tag", "kind", "description", "issued", "days_out"})
+	for _, as := range list {
+		var name, dept string
+		if e, err := manager.GetEmployee(as.EmployeeID); err == nil {
+			name, dept = e.Name, e.Department.String()
+		}
+		out.Write([]string{
+			strconv.Itoa(as.EmployeeID),
+			name,
+			dept,
+			as.Tag,
+			AssetKindToString(as.Kind),
+			as.Description,
+			formatDate(as.Issued),
+			strconv.Itoa(assetDays(as, now)),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// assetsInteractive issues and returns assets and reports those outstanding
+// through user interaction
+func assetsInteractive(assets *Assets, manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Company Assets ==="))
+	fmt.Println("1. Issue asset")
+	fmt.Println("2. Return asset")
+	fmt.Println("3. Write off lost asset")
+	fmt.Println("4. View employee's assets")
+	fmt.Println("5. Outstanding assets report")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		kind, err := readValue(reader, "Kind (laptop/badge/phone/other): ", StringToAssetKind)
+		if err != nil {
+			return err
+		}
+		tag, err := readString(reader, "Asset tag or serial number: ")
+		if err != nil {
+			return err
+		}
+		description, err := readString(reader, "Description (optional): ")
+		if err != nil {
+			return err
+		}
+		as, err := assets.Issue(id, kind, tag, description)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("%s %s issued to employee %d", AssetKindToString(as.Kind), as.Tag, as.EmployeeID)))
+
+	case 2, 3:
+		tag, err := readString(reader, "Asset tag or serial number: ")
+		if err != nil {
+			return err
+		}
+		lost := option == 3
+		if lost {
+			ok, err := confirm(reader, fmt.Sprintf("Write off %s as lost?", tag), true)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("\n" + warningText("Operation cancelled."))
+				return nil
+			}
+		}
+		as, err := assets.Return(tag, lost)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("%s %s: %s", AssetKindToString(as.Kind), as.Tag, assetStatus(*as))))
+
+	case 4:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		writeEmployeeAssets(os.Stdout, assets.ForEmployee(id))
+
+	case 5:
+		list := assets.Outstanding()
+		now := clockOf(manager).Now()
+		writeOutstandingAssets(os.Stdout, list, manager, now)
+		if len(list) == 0 {
+			return nil
+		}
+		path, err := readString(reader, "\nSave as CSV (file path; blank to skip): ")
+		if err != nil || path == "" {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = writeOutstandingAssetsCSV(f, list, manager, now)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Report saved to %s\n", path)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,713 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+var (
+	ErrClockedIn    = errorsx.New(errorsx.ErrConflict, "employee_id", "already clocked in")
+	ErrNotClockedIn = errorsx.New(errorsx.ErrConflict, "employee_id", "not clocked in")
+)
+
+// Attendance anomaly thresholds
+const (
+	lateGrace           = 10 * time.Minute // how long after the start of the working day a clock-in is still on time
+	chronicLateDays     = 3                // late days in a report period that make lateness chronic
+	overtimeWeeklyHours = 10               // overtime hours a week above which overtime is excessive
+	maxShift            = 24 * time.Hour   // longest time entry that can be recorded
+)
+
+// TimeEntry is a stretch of work from clocking in to clocking out
+type TimeEntry struct {
+	ID         int       `json:"id"`
+	EmployeeID int       `json:"employee_id"`
+	In         time.Time `json:"in"`
+	Out        time.Time `json:"out"` // zero while the employee is clocked in
+}
+
+// Hours returns the hours worked, or 0 while the employee is clocked in
+func (t *TimeEntry) Hours() float64 {
+	if t.Out.IsZero() {
+		return 0
+	}
+	return t.Out.Sub(t.In).Hours()
+}
+
+// overlaps reports whether two entries share any time. An open entry runs
+// on without end.
+func (t *TimeEntry) overlaps(o *TimeEntry) bool {
+	return (o.Out.IsZero() || t.In.Before(o.Out)) && (t.Out.IsZero() || o.In.Before(t.Out))
+}
+
+// TimeClock records when employees clock in and out, from the menu or
+// imported from a time-tracking system
+type TimeClock struct {
+	mu      sync.Mutex
+	manager EmployeeManager
+	entries []*TimeEntry
+	nextID  int
+	path    string // JSON file the entries are kept in, or "" for memory only
+}
+
+// NewTimeClock creates the time clock of employees of the given manager,
+// reading its entries from the file at path if there is one
+func NewTimeClock(manager EmployeeManager, path string) (*TimeClock, error) {
+	c := &TimeClock{manager: manager, nextID: 1, path: path}
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("reading time entries %s: %w", path, err)
+	}
+	for _, t := range c.entries {
+		c.nextID = max(c.nextID, t.ID+1)
+	}
+	return c, nil
+}
+
+// save writes the entries to the file. The caller must hold the lock. The
+// file is written under a temporary name and renamed, and an interrupt
+// waits for the save, so it is never left half written.
+func (c *TimeClock) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := c.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, c.path)
+	})
+}
+
+// open returns the entry an employee is clocked in on, or nil. The caller
+// must hold the lock.
+func (c *TimeClock) open(employeeID int) *TimeEntry {
+	for _, t := range c.entries {
+		if t.EmployeeID == employeeID && t.Out.IsZero() {
+			return t
+		}
+	}
+	return nil
+}
+
+// add records a new entry that overlaps none of the employee's others. The
+// caller must hold the lock.
+func (c *TimeClock) add(entry *TimeEntry) (*TimeEntry, error) {
+	for _, t := range c.entries {
+		if t.EmployeeID == entry.EmployeeID && t.overlaps(entry) {
+			return nil, fmt.Errorf("%w: employee ID %d already has time recorded from %s", ErrInvalidInput,
+				entry.EmployeeID, formatDateTime(t.In))
+		}
+	}
+	entry.ID = c.nextID
+	c.entries = append(c.entries, entry)
+	c.nextID++
+	if err := c.save(); err != nil {
+		c.entries = c.entries[:len(c.entries)-1]
+		c.nextID--
+		return nil, err
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+// ClockIn starts an entry for an employee. Employees can clock themselves
+// in, and HR anyone.
+func (c *TimeClock) ClockIn(user User, employeeID int, at time.Time) (*TimeEntry, error) {
+	if err := user.RequireOwnOr(PermEditPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	if _, err := c.manager.GetEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t := c.open(employeeID); t != nil {
+		return nil, ErrClockedIn.WithValue(formatDateTime(t.In))
+	}
+	return c.add(&TimeEntry{EmployeeID: employeeID, In: at})
+}
+
+// ClockOut ends the entry an employee is clocked in on
+func (c *TimeClock) ClockOut(user User, employeeID int, at time.Time) (*TimeEntry, error) {
+	if err := user.RequireOwnOr(PermEditPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.open(employeeID)
+	switch {
+	case t == nil:
+		return nil, ErrNotClockedIn.WithValue(employeeID)
+	case !at.After(t.In):
+		return nil, fmt.Errorf("%w: clocking out at %s is not after clocking in at %s", ErrInvalidInput,
+			formatDateTime(at), formatDateTime(t.In))
+	case at.Sub(t.In) > maxShift:
+		return nil, fmt.Errorf("%w: clocked in since %s; record the hours worked instead", ErrInvalidInput, formatDateTime(t.In))
+	}
+	t.Out = at
+	if err := c.save(); err != nil {
+		t.Out = time.Time{}
+		return nil, err
+	}
+	copied := *t
+	return &copied, nil
+}
+
+// Record adds an entry with both ends, for work that was not clocked
+func (c *TimeClock) Record(user User, employeeID int, in, out time.Time) (*TimeEntry, error) {
+	if err := user.RequireOwnOr(PermEditPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	if _, err := c.manager.GetEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	switch {
+	case !out.After(in):
+		return nil, fmt.Errorf("%w: the end of a time entry must be after its start", ErrInvalidInput)
+	case out.Sub(in) > maxShift:
+		return nil, fmt.Errorf("%w: a time entry cannot be longer than %s", ErrInvalidInput, maxShift)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.add(&TimeEntry{EmployeeID: employeeID, In: in, Out: out})
+}
+
+// Import records the entries of a CSV export of a time-tracking system,
+// with employee_id, in and out columns in YYYY-MM-DD HH:MM. It continues
+// past rows that cannot be recorded, returning how many were and the
+// errors of the rest.
+func (c *TimeClock) Import(user User, path string) (int, []error) {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return 0, []error{err}
+	}
+	rows, err := readCSV(path)
+	if err != nil {
+		return 0, []error{err}
+	}
+	if len(rows) == 0 {
+		return 0, []error{fmt.Errorf("%w: %s is empty", ErrInvalidInput, path)}
+	}
+	column := make(map[string]int)
+	for i, name := range rows[0] {
+		column[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"employee_id", "in", "out"} {
+		if _, ok := column[name]; !ok {
+			return 0, []error{fmt.Errorf("%w: %s has no %s column", ErrInvalidInput, path, name)}
+		}
+	}
+
+	added := 0
+	errs := make([]error, 0)
+	for i, row := range rows[1:] {
+		cell := func(name string) string {
+			if j := column[name]; j < len(row) {
+				return strings.TrimSpace(row[j])
+			}
+			return ""
+		}
+		id, err := strconv.Atoi(cell("employee_id"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w: %q is not an employee ID", i+2, ErrInvalidInput, cell("employee_id")))
+			continue
+		}
+		in, errIn := parseDateTime(cell("in"))
+		out, errOut := parseDateTime(cell("out"))
+		if errIn != nil || errOut != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w: times must be in YYYY-MM-DD HH:MM format", i+2, ErrInvalidInput))
+			continue
+		}
+		if _, err := c.Record(user, id, in, out); err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", i+2, err))
+			continue
+		}
+		added++
+	}
+	return added, errs
+}
+
+// ForEmployee returns an employee's entries from one day to another,
+// earliest first
+func (c *TimeClock) ForEmployee(user User, employeeID int, from, to time.Time) ([]TimeEntry, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	var list []TimeEntry
+	for _, t := range c.between(from, to) {
+		if t.EmployeeID == employeeID {
+			list = append(list, t)
+		}
+	}
+	return list, nil
+}
+
+// between returns the entries clocked in from midnight of one day to the
+// end of another, earliest first, for reports
+func (c *TimeClock) between(from, to time.Time) []TimeEntry {
+	end := to.In(userLocation).AddDate(0, 0, 1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var list []TimeEntry
+	for _, t := range c.entries {
+		if !t.In.Before(from) && t.In.Before(end) {
+			list = append(list, *t)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].In.Before(list[j].In) })
+	return list
+}
+
+// Forget returns an event subscriber that drops the time entries of removed employees
+func (c *TimeClock) Forget() func(Event) {
+	return func(ev Event) {
+		if ev.Type != EventEmployeeRemoved {
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		kept := c.entries[:0]
+		for _, t := range c.entries {
+			if t.EmployeeID != ev.EmployeeID {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) != len(c.entries) {
+			c.entries = kept
+			c.save()
+		}
+	}
+}
+
+// AttendanceRecord is an employee's attendance over a report period
+type AttendanceRecord struct {
+	Employee      Employee
+	WorkingDays   int
+	Absences      []time.Time // working days with no time worked, not fully on leave
+	Late          []time.Time // working days the employee clocked in late
+	Hours         float64     // worked
+	Overtime      float64     // hours worked beyond the working day, and on days off
+	OvertimeLimit float64     // overtime in the period above which it is excessive
+}
+
+// ChronicLate reports whether the employee was late often enough to be chronic
+func (r *AttendanceRecord) ChronicLate() bool {
+	return len(r.Late) >= chronicLateDays
+}
+
+// ExcessiveOvertime reports whether the employee worked too much overtime
+func (r *AttendanceRecord) ExcessiveOvertime() bool {
+	return r.Overtime > r.OvertimeLimit
+}
+
+// Anomalous reports whether anything about the attendance needs looking into
+func (r *AttendanceRecord) Anomalous() bool {
+	return len(r.Absences) > 0 || r.ChronicLate() || r.ExcessiveOvertime()
+}
+
+// AttendanceReport works out each employee's attendance from one day to
+// another, up to the day of now, from their time entries and approved
+// leave under a calendar. Not having clocked in today is not yet an absence. Days are those of the user's time zone, but
+// lateness is judged by the clock of the employee's own time zone.
+func AttendanceReport(employees []Employee, entries []TimeEntry, leave []LeaveRequest, c *HolidayCalendar, from, to, now time.Time) []AttendanceRecord {
+	local := now.In(userLocation)
+	today := dateOf(local.Year(), local.Month(), local.Day())
+	if to.After(today) {
+		to = today
+	}
+	var days []time.Time
+	for first, i := from.In(userLocation), 0; ; i++ {
+		day := dateOf(first.Year(), first.Month(), first.Day()+i)
+		if day.After(to) {
+			break
+		}
+		days = append(days, day)
+	}
+
+	worked := make(map[int]map[string][]TimeEntry) // entries by employee and day
+	for _, t := range entries {
+		if worked[t.EmployeeID] == nil {
+			worked[t.EmployeeID] = make(map[string][]TimeEntry)
+		}
+		worked[t.EmployeeID][formatDate(t.In)] = append(worked[t.EmployeeID][formatDate(t.In)], t)
+	}
+	onLeave := make(map[int][]LeaveRequest)
+	for _, r := range leave {
+		onLeave[r.EmployeeID] = append(onLeave[r.EmployeeID], r)
+	}
+
+	records := make([]AttendanceRecord, 0, len(employees))
+	for _, e := range employees {
+		loc := userLocation
+		if e.TimeZone != "" {
+			if l, err := loadTimeZone(e.TimeZone); err == nil {
+				loc = l
+			}
+		}
+		r := AttendanceRecord{Employee: e}
+		employed := 0
+		for _, day := range days {
+			if day.Before(dateOf(e.JoinDate.In(userLocation).Date())) {
+				continue
+			}
+			employed++
+			var morningOff, afternoonOff bool
+			for _, l := range onLeave[e.ID] {
+				morning, afternoon := l.Covers(day)
+				morningOff, afternoonOff = morningOff || morning, afternoonOff || afternoon
+			}
+
+			dayEntries := worked[e.ID][formatDate(day)]
+			hours := 0.0
+			for _, t := range dayEntries {
+				hours += t.Hours()
+			}
+			r.Hours += hours
+			if !c.IsWorkingDay(day) {
+				r.Overtime += hours
+				continue
+			}
+
+			r.WorkingDays++
+			expected := c.WorkHours
+			if morningOff {
+				expected -= c.WorkHours / 2
+			}
+			if afternoonOff {
+				expected -= c.WorkHours / 2
+			}
+			r.Overtime += max(hours-expected, 0)
+			switch {
+			case morningOff && afternoonOff:
+			case len(dayEntries) == 0:
+				if day.Equal(today) {
+					continue // they may yet clock in
+				}
+				r.Absences = append(r.Absences, day)
+			case !morningOff:
+				in := dayEntries[0].In.In(loc)
+				start := time.Date(in.Year(), in.Month(), in.Day(), 0, 0, 0, 0, loc).Add(c.start + lateGrace)
+				if in.After(start) {
+					r.Late = append(r.Late, day)
+				}
+			}
+		}
+		r.OvertimeLimit = overtimeWeeklyHours * float64(employed) / 7
+		records = append(records, r)
+	}
+	return records
+}
+
+// DepartmentAttendance totals the attendance of a department's employees
+type DepartmentAttendance struct {
+	Department  Department
+	Employees   int
+	Absences    int
+	Late        int
+	ChronicLate int // employees who are chronically late
+	Overtime    float64
+	Excessive   int // employees with excessive overtime
+}
+
+// AttendanceByDepartment totals attendance records by department
+func AttendanceByDepartment(records []AttendanceRecord) []DepartmentAttendance {
+	byDept := make(map[Department]*DepartmentAttendance)
+	for _, r := range records {
+		d := byDept[r.Employee.Department]
+		if d == nil {
+			d = &DepartmentAttendance{Department: r.Employee.Department}
+			byDept[r.Employee.Department] = d
+		}
+		d.Employees++
+		d.Absences += len(r.Absences)
+		d.Late += len(r.Late)
+		d.Overtime += r.Overtime
+		if r.ChronicLate() {
+			d.ChronicLate++
+		}
+		if r.ExcessiveOvertime() {
+			d.Excessive++
+		}
+	}
+	out := make([]DepartmentAttendance, 0, len(byDept))
+	for _, d := range byDept {
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Department < out[j].Department })
+	return out
+}
+
+// attendanceFlags describes what is unusual about an employee's attendance
+func attendanceFlags(r *AttendanceRecord) string {
+	var flags []string
+	if len(r.Absences) > 0 {
+		flags = append(flags, "unexplained absence")
+	}
+	if r.ChronicLate() {
+		flags = append(flags, "chronic lateness")
+	}
+	if r.ExcessiveOvertime() {
+		flags = append(flags, "excessive overtime")
+	}
+	return strings.Join(flags, ", ")
+}
+
+// writeAttendanceReport writes the employees whose attendance is anomalous,
+// then the totals of each department
+func writeAttendanceReport(w io.Writer, records []AttendanceRecord, from, to time.Time) {
+	fmt.Fprintf(w, "\n=== Attendance Anomalies %s to %s ===\n", formatDate(from), formatDate(to))
+	fmt.Fprintf(w, "%-6s %-20s %-12s %8s %6s %9s  %s\n", "ID", "Employee", "Department", "Absences", "Late", "Overtime", "Anomalies")
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	anomalous := 0
+	for i := range records {
+		r := &records[i]
+		if !r.Anomalous() {
+			continue
+		}
+		anomalous++
+		fmt.Fprintf(w, "%-6d %-20s %-12s %8d %6d %8.1fh  %s\n", r.Employee.ID, r.Employee.Name, r.Employee.Department,
+			len(r.Absences), len(r.Late), r.Overtime, attendanceFlags(r))
+		if len(r.Absences) > 0 {
+			dates := make([]string, len(r.Absences))
+			for i, day := range r.Absences {
+				dates[i] = formatDate(day)
+			}
+			fmt.Fprintf(w, "%-6s absent: %s\n", "", strings.Join(dates, ", "))
+		}
+	}
+	if anomalous == 0 {
+		fmt.Fprintln(w, "No anomalies.")
+	}
+
+	fmt.Fprintln(w, "\nBy department:")
+	fmt.Fprintf(w, "%-12s %9s %8s %6s %8s %9s %10s\n", "Department", "Employees", "Absences", "Late", "Chronic", "Overtime", "Excessive")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, d := range AttendanceByDepartment(records) {
+		fmt.Fprintf(w, "%-12s %9d %8d %6d %8d %8.1fh %10d\n", d.Department, d.Employees, d.Absences, d.Late, d.ChronicLate, d.Overtime, d.Excessive)
+	}
+}
+
+// writeAttendanceReportCSV writes every employee's attendance as CSV with a header row
+func writeAttendanceReportCSV(w io.Writer, records []AttendanceRecord) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"employee_id", "employee", "department", "working_days", "absences", "late_days", "hours", "overtime_hours", "anomalies"})
+	for i := range records {
+		r := &records[i]
+		out.Write([]string{
+			strconv.Itoa(r.Employee.ID),
+			r.Employee.Name,
+			r.Employee.Department.String(),
+			strconv.Itoa(r.WorkingDays),
+			strconv.Itoa(len(r.Absences)),
+			strconv.Itoa(len(r.Late)),
+			strconv.FormatFloat(r.Hours, 'f', 2, 64),
+			strconv.FormatFloat(r.Overtime, 'f', 2, 64),
+			attendanceFlags(r),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeTimeEntries writes an employee's time entries
+func writeTimeEntries(w io.Writer, list []TimeEntry) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "\nNo time recorded.")
+		return
+	}
+	fmt.Fprintf(w, "\n%-6s %-20s %-20s %7s\n", "#", "In", "Out", "Hours")
+	fmt.Fprintln(w, strings.Repeat("-", 56))
+	total := 0.0
+	for _, t := range list {
+		out := "(clocked in)"
+		if !t.Out.IsZero() {
+			out = formatDateTime(t.Out)
+		}
+		fmt.Fprintf(w, "%-6d %-20s %-20s %7.2f\n", t.ID, formatDateTime(t.In), out, t.Hours())
+		total += t.Hours()
+	}
+	fmt.Fprintf(w, "%-48s %7.2f\n", "Total", total)
+}
+
+// readTime reads a time of day on a date, or now if blank
+func readTime(reader *bufio.Reader, prompt string, now time.Time) (time.Time, error) {
+	return readValue(reader, prompt+" (YYYY-MM-DD HH:MM, blank for now): ", func(input string) (time.Time, error) {
+		if input == "" {
+			return now, nil
+		}
+		t, err := parseDateTime(input)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: please enter a time in YYYY-MM-DD HH:MM format", ErrInvalidInput)
+		}
+		return t, nil
+	})
+}
+
+// attendanceInteractive records time and reports attendance anomalies
+// through user interaction
+func attendanceInteractive(clock *TimeClock, leave *Leave, manager EmployeeManager, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Time & Attendance ==="))
+	fmt.Println("1. Clock in")
+	fmt.Println("2. Clock out")
+	fmt.Println("3. Record hours worked")
+	fmt.Println("4. Import time entries (CSV)")
+	fmt.Println("5. View employee's time")
+	fmt.Println("6. Attendance anomaly report")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+	now := clockOf(manager).Now()
+
+	switch option {
+	case 1, 2:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		at, err := readTime(reader, "Time", now)
+		if err != nil {
+			return err
+		}
+		if option == 1 {
+			if _, err := clock.ClockIn(user, id, at); err != nil {
+				return err
+			}
+			fmt.Println("\n" + successText(fmt.Sprintf("Clocked in at %s", formatDateTime(at))))
+			return nil
+		}
+		t, err := clock.ClockOut(user, id, at)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Clocked out at %s after %.2f hours", formatDateTime(at), t.Hours())))
+
+	case 3:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		in, err := readTime(reader, "Started", now)
+		if err != nil {
+			return err
+		}
+		out, err := readTime(reader, "Finished", now)
+		if err != nil {
+			return err
+		}
+		t, err := clock.Record(user, id, in, out)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Recorded %.2f hours", t.Hours())))
+
+	case 4:
+		path, err := readString(reader, "CSV file (employee_id, in, out columns): ")
+		if err != nil {
+			return err
+		}
+		added, errs := clock.Import(user, path)
+		for _, err := range errs {
+			fmt.Println(warningText("Skipped:"), err)
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Imported %d time entries", added)))
+
+	case 5:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		from, err := readDate(reader, "From")
+		if err != nil {
+			return err
+		}
+		to, err := readDate(reader, "To")
+		if err != nil {
+			return err
+		}
+		list, err := clock.ForEmployee(user, id, from, to)
+		if err != nil {
+			return err
+		}
+		writeTimeEntries(os.Stdout, list)
+
+	case 6:
+		if err := user.Require(PermViewPersonalData); err != nil {
+			return err
+		}
+		from, err := readValue(reader, "From (YYYY-MM-DD, blank for 4 weeks ago): ", func(input string) (time.Time, error) {
+			if input == "" {
+				return today().AddDate(0, 0, -27), nil
+			}
+			date, err := parseDate(input)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%w: please enter a valid date in YYYY-MM-DD format", ErrInvalidInput)
+			}
+			return date, nil
+		})
+		if err != nil {
+			return err
+		}
+		to, err := readDate(reader, "To")
+		if err != nil {
+			return err
+		}
+		if to.Before(from) {
+			return fmt.Errorf("%w: the report cannot end before it starts", ErrInvalidInput)
+		}
+		employees, err := employeeValues(manager)
+		if err != nil {
+			return err
+		}
+		sortEmployeeValues(employees, OrderByID)
+		records := AttendanceReport(employees, clock.between(from, to), leave.approved(from, to), holidayCalendar, from, to, now)
+		writeAttendanceReport(os.Stdout, records, from, to)
+
+		path, err := readString(reader, "\nSave as CSV (file path; blank to skip): ")
+		if err != nil || path == "" {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = writeAttendanceReportCSV(f, records)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Report saved to %s\n", path)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
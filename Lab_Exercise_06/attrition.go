@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exit reason constants using iota
+const (
+	ExitCareerGrowth = iota
+	ExitCompensation
+	ExitManagement
+	ExitWorkLifeBalance
+	ExitRelocation
+	ExitRetirement
+	ExitLayoff
+	ExitDismissal
+	ExitOther
+)
+
+// ExitReasonToString converts an exit reason constant to string
+func ExitReasonToString(reason int) string {
+	switch reason {
+	case ExitCareerGrowth:
+		return "Career growth"
+	case ExitCompensation:
+		return "Compensation"
+	case ExitManagement:
+		return "Management"
+	case ExitWorkLifeBalance:
+		return "Work-life balance"
+	case ExitRelocation:
+		return "Relocation"
+	case ExitRetirement:
+		return "Retirement"
+	case ExitLayoff:
+		return "Layoff"
+	case ExitDismissal:
+		return "Dismissal"
+	case ExitOther:
+		return "Other"
+	default:
+		return "Unknown"
+	}
+}
+
+// ExitInterview is why an employee left, captured when they are removed
+type ExitInterview struct {
+	Reasons []int  // the first is the primary reason
+	Notes   string // optional notes from the interview
+}
+
+// ExitRecord is an employee who left, as they were on their last day
+type ExitRecord struct {
+	Employee Employee
+	ExitDate time.Time
+	ExitInterview
+}
+
+// TenureBand returns the label of the experience bucket the employee's tenure fell into on leaving
+func (r ExitRecord) TenureBand() string {
+	return experienceBuckets[experienceBucket(r.Employee.ExperienceAt(r.ExitDate))].Label
+}
+
+// Attrition keeps the exit interviews of employees who left. An interview is
+// held until the store reports the removal, so removals that are only
+// previewed or that fail are never recorded.
+type Attrition struct {
+	pending map[int]ExitRecord
+	records []ExitRecord
+}
+
+// NewAttrition creates an empty attrition history
+func NewAttrition() *Attrition {
+	return &Attrition{pending: make(map[int]ExitRecord)}
+}
+
+// Expect validates and holds the exit interview of an employee about to be removed
+func (a *Attrition) Expect(employee Employee, interview ExitInterview) error {
+	if len(interview.Reasons) == 0 {
+		return fmt.Errorf("%w: at least one exit reason is required", ErrInvalidInput)
+	}
+	for _, reason := range interview.Reasons {
+		if ExitReasonToString(reason) == "Unknown" {
+			return fmt.Errorf("%w: please select a valid exit reason", ErrInvalidInput)
+		}
+	}
+	interview.Notes = strings.TrimSpace(interview.Notes)
+	a.pending[employee.ID] = ExitRecord{Employee: employee, ExitInterview: interview}
+	return nil
+}
+
+// Cancel drops the exit interview held for an employee who was not removed
+func (a *Attrition) Cancel(employeeID int) {
+	delete(a.pending, employeeID)
+}
+
+// Records returns the exits recorded so far, oldest first
+func (a *Attrition) Records() []ExitRecord {
+	return append([]ExitRecord(nil), a.records...)
+}
+
+// Watch returns an event subscriber that records the exit of removed
+// employees with the interview held for them
+func (a *Attrition) Watch() func(Event) {
+	return func(ev Event) {
+		if ev.Type != EventEmployeeRemoved {
+			return
+		}
+		record, ok := a.pending[ev.EmployeeID]
+		if !ok {
+			return
+		}
+		delete(a.pending, ev.EmployeeID)
+		record.ExitDate = ev.Time
+		a.records = append(a.records, record)
+	}
+}
+
+// AttritionCount is the number of exits in a group
+type AttritionCount struct {
+	Name  string
+	Count int
+}
+
+// countAttrition counts the exits in each group, largest group first
+func countAttrition(records []ExitRecord, group func(ExitRecord) string) []AttritionCount {
+	counts := make(map[string]int)
+	for _, r := range records {
+		counts[group(r)]++
+	}
+	result := make([]AttritionCount, 0, len(counts))
+	for name, n := range counts {
+		result = append(result, AttritionCount{name, n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// writeAttritionReport writes the number of exits by primary reason, department and tenure band
+func writeAttritionReport(w io.Writer, records []ExitRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "\nNo exits recorded.")
+		return
+	}
+
+	sections := []struct {
+		title string
+		group func(ExitRecord) string
+	}{
+		{"Attrition by Reason", func(r ExitRecord) string { return ExitReasonToString(r.Reasons[0]) }},
+		{"Attrition by Department", func(r ExitRecord) string { return r.Employee.Department.String() }},
+		{"Attrition by Tenure", ExitRecord.TenureBand},
+	}
+	for _, s := range sections {
+		fmt.Fprintf(w, "\n=== %s ===\n", s.title)
+		for _, c := range countAttrition(records, s.group) {
+			fmt.Fprintf(w, "%-24s %5d %5.1f%%\n", c.Name, c.Count, 100*float64(c.Count)/float64(len(records)))
+		}
+	}
+
+	fmt.Fprintln(w, "\n=== Exit Interviews ===")
+	for _, r := range records {
+		reasons := make([]string, len(r.Reasons))
+		for i, reason := range r.Reasons {
+			reasons[i] = ExitReasonToString(reason)
+		}
+		fmt.Fprintf(w, "%s %s (ID %d), %s, %s: %s\n", formatDate(r.ExitDate), r.Employee.Name, r.Employee.ID,
+			r.Employee.Department, r.TenureBand(), strings.Join(reasons, ", "))
+		if r.Notes != "" {
+			fmt.Fprintf(w, "  %s\n", r.Notes)
+		}
+	}
+}
+
+// readExitInterview asks why an employee is leaving
+func readExitInterview(reader *bufio.Reader) (ExitInterview, error) {
+	fmt.Println("\nExit reasons:")
+	for r := ExitCareerGrowth; r <= ExitOther; r++ {
+		fmt.Printf("%d. %s\n", r+1, ExitReasonToString(r))
+	}
+
+	reasons, err := readValue(reader, "Select reasons, primary first (e.g. 2,1): ", func(input string) ([]int, error) {
+		var reasons []int
+		for _, field := range strings.Split(input, ",") {
+			choice, err := parseInt(strings.TrimSpace(field))
+			if err != nil {
+				return nil, err
+			}
+			if ExitReasonToString(choice-1) == "Unknown" {
+				return nil, fmt.Errorf("%w: please select a valid exit reason", ErrInvalidInput)
+			}
+			reasons = append(reasons, choice-1)
+		}
+		return reasons, nil
+	})
+	if err != nil {
+		return ExitInterview{}, err
+	}
+
+	notes, err := readString(reader, "Exit interview notes (optional): ")
+	if err != nil {
+		return ExitInterview{}, err
+	}
+	return ExitInterview{Reasons: reasons, Notes: notes}, nil
+}
+
+// attritionReportInteractive shows the attrition report
+func attritionReportInteractive(attrition *Attrition) error {
+	fmt.Println("\n" + headerText("=== Attrition ==="))
+	writeAttritionReport(os.Stdout, attrition.Records())
+	return nil
+}
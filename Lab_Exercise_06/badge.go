@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Badge formats using iota
+const (
+	BadgePNG = iota
+	BadgePDF
+)
+
+// BadgeFormatToString converts a badge format constant to string, which is also its file extension
+func BadgeFormatToString(format int) string {
+	switch format {
+	case BadgePNG:
+		return "png"
+	case BadgePDF:
+		return "pdf"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToBadgeFormat converts string to badge format constant
+func StringToBadgeFormat(format string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "png", "":
+		return BadgePNG, nil
+	case "pdf":
+		return BadgePDF, nil
+	default:
+		return -1, fmt.Errorf("%w: badge format must be png or pdf", ErrInvalidInput)
+	}
+}
+
+// badgeContentType is the MIME type of each badge format
+var badgeContentType = map[int]string{
+	BadgePNG: "image/png",
+	BadgePDF: "application/pdf",
+}
+
+// employeeURL is the address of an employee in the HTTP API served at baseURL
+func employeeURL(baseURL string, id int) string {
+	return strings.TrimRight(baseURL, "/") + "/employees/" + strconv.Itoa(id)
+}
+
+// badgeColors are the colors of the band at the top of each department's badges
+var badgeColors = map[Department]color.RGBA{
+	HR:          {0x8e, 0x24, 0xaa, 0xff},
+	Engineering: {0x15, 0x65, 0xc0, 0xff},
+	Finance:     {0x2e, 0x7d, 0x32, 0xff},
+	Marketing:   {0xe6, 0x51, 0x00, 0xff},
+	Operations:  {0x45, 0x5a, 0x64, 0xff},
+}
+
+// WriteBadge writes a printable ID badge for an employee, showing their name,
+// ID, position and department, with a QR code of their lookup URL
+func WriteBadge(w io.Writer, format int, e *Employee, baseURL string) error {
+	qr, err := EncodeQR([]byte(employeeURL(baseURL, e.ID)))
+	if err != nil {
+		return err
+	}
+	switch format {
+	case BadgePNG:
+		return png.Encode(w, badgeImage(e, qr))
+	case BadgePDF:
+		return writeBadgePDF(w, e, qr)
+	default:
+		return fmt.Errorf("%w: unknown badge format %d", ErrInvalidInput, format)
+	}
+}
+
+// badgeFont is a 5x7 bitmap font for the PNG badge; lowercase letters are
+// drawn in uppercase and characters it lacks as '?'
+var badgeFont = map[rune][7]string{
+	' ':  {".....", ".....", ".....", ".....", ".....", ".....", "....."},
+	'A':  {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B':  {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C':  {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D':  {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E':  {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F':  {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G':  {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H':  {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I':  {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J':  {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K':  {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L':  {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M':  {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N':  {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O':  {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P':  {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q':  {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R':  {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S':  {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T':  {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U':  {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V':  {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W':  {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X':  {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y':  {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z':  {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0':  {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1':  {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2':  {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3':  {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4':  {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5':  {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6':  {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7':  {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8':  {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9':  {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'-':  {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'.':  {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	',':  {".....", ".....", ".....", ".....", ".##..", "..#..", ".#..."},
+	'\'': {"..#..", "..#..", ".#...", ".....", ".....", ".....", "....."},
+	'(':  {"...#.", "..#..", ".#...", ".#...", ".#...", "..#..", "...#."},
+	')':  {".#...", "..#..", "...#.", "...#.", "...#.", "..#..", ".#..."},
+	'/':  {".....", "....#", "...#.", "..#..", ".#...", "#....", "....."},
+	':':  {".....", ".##..", ".##..", ".....", ".##..", ".##..", "....."},
+	'&':  {".##..", "#..#.", "#.#..", ".#...", "#.#.#", "#..#.", ".##.#"},
+	'#':  {".#.#.", ".#.#.", "#####", ".#.#.", "#####", ".#.#.", ".#.#."},
+	'?':  {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+}
+
+// fillRect fills a rectangle of an image
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, c)
+		}
+	}
+}
+
+// drawText draws text with the bitmap font at (x, y), each font pixel scale
+// pixels wide, cutting it short with "..." if it is wider than maxWidth
+func drawText(img *image.RGBA, x, y, scale, maxWidth int, text string, c color.Color) {
+	advance := 6 * scale
+	runes := []rune(strings.ToUpper(text))
+	if fit := maxWidth / advance; len(runes) > fit && fit > 3 {
+		runes = append(runes[:fit-3], '.', '.', '.')
+	}
+	for i, r := range runes {
+		glyph, ok := badgeFont[r]
+		if !ok {
+			glyph = badgeFont['?']
+		}
+		for row, line := range glyph {
+			for col, pixel := range line {
+				if pixel == '#' {
+					left, top := x+i*advance+col*scale, y+row*scale
+					fillRect(img, image.Rect(left, top, left+scale, top+scale), c)
+				}
+			}
+		}
+	}
+}
+
+// badgeImage draws a badge the shape of an ID card
+func badgeImage(e *Employee, qr *QRCode) *image.RGBA {
+	const width, height, band = 640, 404, 72
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	black := color.RGBA{0x21, 0x21, 0x21, 0xff}
+	grey := color.RGBA{0x61, 0x61, 0x61, 0xff}
+	fillRect(img, img.Bounds(), white)
+
+	accent, ok := badgeColors[e.Department]
+	if !ok {
+		accent = black
+	}
+	fillRect(img, image.Rect(0, 0, width, band), accent)
+	drawText(img, 28, 22, 4, width-56, "Employee", white)
+
+	// The QR code on the right, with the quiet zone of four modules it needs
+	module := 240 / (qr.Size + 8)
+	qrLeft, qrTop := width-28-module*(qr.Size+8), band+(height-band-module*(qr.Size+8))/2
+	for r, row := range qr.Modules {
+		for c, dark := range row {
+			if dark {
+				left, top := qrLeft+(c+4)*module, qrTop+(r+4)*module
+				fillRect(img, image.Rect(left, top, left+module, top+module), black)
+			}
+		}
+	}
+
+	textWidth := qrLeft - 28
+	drawText(img, 28, band+40, 4, textWidth, e.Name, black)
+	drawText(img, 28, band+110, 3, textWidth, "ID "+strconv.Itoa(e.ID), black)
+	drawText(img, 28, band+160, 3, textWidth, e.Position, grey)
+	drawText(img, 28, band+200, 3, textWidth, e.Department.String(), accent)
+	return img
+}
+
+// pdfText escapes text for a PDF string in WinAnsiEncoding, replacing
+// characters outside Latin-1 with '?'
+func pdfText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// writeBadgePDF writes a one-page PDF badge the size of an ID card, 85.6 by
+// 54 mm, using the standard Helvetica fonts every PDF reader has
+func writeBadgePDF(w io.Writer, e *Employee, qr *QRCode) error {
+	const width, height, band = 242.6, 153.0, 28.0
+	accent, ok := badgeColors[e.Department]
+	if !ok {
+		accent = color.RGBA{0x21, 0x21, 0x21, 0xff}
+	}
+	rgb := func(c color.RGBA) string {
+		return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+	}
+	fit := func(text string, size, width float64) string {
+		// Helvetica averages about half its size per character
+		if limit := int(width / (size * 0.55)); len([]rune(text)) > limit && limit > 3 {
+			return string([]rune(text)[:limit-3]) + "..."
+		}
+		return text
+	}
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "%s rg 0 %.1f %.1f %.1f re f\n", rgb(accent), height-band, width, band)
+	fmt.Fprintf(&content, "BT 1 1 1 rg /F2 14 Tf 12 %.1f Td (EMPLOYEE) Tj ET\n", height-band+9)
+
+	module := 90.0 / float64(qr.Size+8)
+	qrLeft, qrBottom := width-12-module*float64(qr.Size+8), (height-band-module*float64(qr.Size+8))/2
+	content.WriteString("0.129 0.129 0.129 rg\n")
+	for r, row := range qr.Modules {
+		for c, dark := range row {
+			if dark {
+				fmt.Fprintf(&content, "%.2f %.2f %.2f %.2f re\n",
+					qrLeft+float64(c+4)*module, qrBottom+float64(qr.Size+3-r)*module, module, module)
+			}
+		}
+	}
+	content.WriteString("f\n")
+
+	textWidth := qrLeft - 12
+	lines := []struct {
+		font  string
+		size  float64
+		y     float64
+		color string
+		text  string
+	}{
+		{"F2", 13, height - band - 30, "0.129 0.129 0.129", e.Name},
+		{"F1", 10, height - band - 52, "0.129 0.129 0.129", "ID " + strconv.Itoa(e.ID)},
+		{"F1", 9, height - band - 72, "0.380 0.380 0.380", e.Position},
+		{"F2", 9, height - band - 88, rgb(accent), e.Department.String()},
+	}
+	for _, l := range lines {
+		fmt.Fprintf(&content, "BT %s rg /%s %.0f Tf 12 %.1f Td (%s) Tj ET\n",
+			l.color, l.font, l.size, l.y, pdfText(fit(l.text, l.size, textWidth)))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.1f %.1f] /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> /Contents 4 0 R >>", width, height),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(pdf.Bytes())
+	return err
+}
+
+// handleBadge serves an employee's badge, as PNG unless the format parameter
+// asks for PDF. The QR code links back to the server the badge was fetched from.
+func (s *Server) handleBadge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, fmt.Errorf("%w: employee ID must be a number", ErrInvalidInput))
+		return
+	}
+	format, err := StringToBadgeFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	employee, err := s.managerFor(r).GetEmployee(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	var badge bytes.Buffer
+	if err := WriteBadge(&badge, format, employee, scheme+"://"+r.Host); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", badgeContentType[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=badge-%d.%s", id, BadgeFormatToString(format)))
+	w.Write(badge.Bytes())
+}
+
+// runBadge implements the badge command, which writes printable badges for
+// one employee or for all of them
+func runBadge(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("badge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to read employees from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	id := fs.Int("id", 0, "employee to make a badge for (default all employees)")
+	formatName := fs.String("format", "png", "badge format (png, pdf)")
+	baseURL := fs.String("url", "http://localhost:8080", "address of the HTTP API the QR codes link to")
+	out := fs.String("out", "", "file to write for one employee, or directory for all (default badge-<id>.<format> in the current directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	format, err := StringToBadgeFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var employees []*Employee
+	dir := *out
+	if *id != 0 {
+		employee, err := store.GetEmployee(*id)
+		if err != nil {
+			return err
+		}
+		employees = []*Employee{employee}
+		dir = ""
+	} else {
+		if employees, err = store.ListEmployees(); err != nil {
+			return err
+		}
+		sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+		if dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range employees {
+		path := filepath.Join(dir, fmt.Sprintf("badge-%d.%s", e.ID, BadgeFormatToString(format)))
+		if *id != 0 && *out != "" {
+			path = *out
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = WriteBadge(f, format, e, *baseURL)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("badge for employee %d: %w", e.ID, err)
+		}
+	}
+	fmt.Fprintf(stdout, "Wrote %d badge(s)\n", len(employees))
+	return nil
+}
@@ -0,0 +1,715 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/JoeDkhar/Go/errorsx"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrMissingBankDetails means employees in a payroll run have no bank account to pay
+var ErrMissingBankDetails = errorsx.New(errorsx.ErrValidation, "bank_account", "no bank account on file")
+
+// Account type constants using iota
+const (
+	AccountChecking = iota
+	AccountSavings
+)
+
+// AccountTypeToString converts an account type constant to string
+func AccountTypeToString(t int) string {
+	switch t {
+	case AccountChecking:
+		return "Checking"
+	case AccountSavings:
+		return "Savings"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToAccountType converts string to account type constant
+func StringToAccountType(t string) (int, error) {
+	for a := AccountChecking; a <= AccountSavings; a++ {
+		if strings.EqualFold(strings.TrimSpace(t), AccountTypeToString(a)) {
+			return a, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: account type %q must be checking or savings", ErrInvalidInput, t)
+}
+
+// BankAccount is the account an employee's pay goes to
+type BankAccount struct {
+	Holder   string `json:"holder"`
+	BankCode string `json:"bank_code"` // routing number, sort code or similar
+	Number   string `json:"number"`
+	Type     int    `json:"type"`
+}
+
+// Masked returns the account with all but the last four digits of its number hidden
+func (a BankAccount) Masked() string {
+	number := a.Number
+	if len(number) > 4 {
+		number = strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+	}
+	return fmt.Sprintf("%s, %s %s at %s", a.Holder, AccountTypeToString(a.Type), number, a.BankCode)
+}
+
+// validate checks that the account can be paid into
+func (a BankAccount) validate() error {
+	alphanumeric := func(s string) bool {
+		return strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z')
+		}) < 0
+	}
+	switch {
+	case strings.TrimSpace(a.Holder) == "":
+		return fmt.Errorf("%w: account holder cannot be empty", ErrInvalidInput)
+	case len(a.BankCode) < 4 || len(a.BankCode) > 11 || !alphanumeric(a.BankCode):
+		return fmt.Errorf("%w: bank code must be 4 to 11 letters and digits", ErrInvalidInput)
+	case len(a.Number) < 4 || len(a.Number) > 34 || !alphanumeric(a.Number):
+		return fmt.Errorf("%w: account number must be 4 to 34 letters and digits", ErrInvalidInput)
+	case AccountTypeToString(a.Type) == "Unknown":
+		return fmt.Errorf("%w: please select a valid account type", ErrInvalidInput)
+	}
+	return nil
+}
+
+// validRoutingNumber reports whether s is a US ABA routing number: nine
+// digits whose weighted sum, by 3, 7 and 1 in turn, is a multiple of ten
+func validRoutingNumber(s string) bool {
+	if len(s) != 9 {
+		return false
+	}
+	sum := 0
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+		sum += int(r-'0') * [3]int{3, 7, 1}[i%3]
+	}
+	return sum%10 == 0
+}
+
+// bankKey returns the key bank accounts are encrypted with, 32 bytes given
+// as hex in the EMS_BANK_KEY environment variable. Without one, accounts
+// kept only in memory get a random key; a bank file needs the real one.
+func bankKey(path string) ([]byte, error) {
+	text := os.Getenv("EMS_BANK_KEY")
+	if text == "" {
+		if path != "" {
+			return nil, fmt.Errorf("%w: the bank file %s needs its key in EMS_BANK_KEY", ErrInvalidInput, path)
+		}
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		return key, err
+	}
+	key, err := hex.DecodeString(text)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%w: EMS_BANK_KEY must be 64 hex digits", ErrInvalidInput)
+	}
+	return key, nil
+}
+
+// BankDetails stores employees' bank accounts, each encrypted with AES-GCM
+// and bound to its employee's ID, so accounts cannot be read without the key
+// or moved between employees in the file. Every operation is restricted to
+// users allowed to handle personal data.
+type BankDetails struct {
+	mu      sync.Mutex
+	manager EmployeeManager
+	aead    cipher.AEAD
+	sealed  map[int][]byte // employee ID -> nonce followed by the encrypted account
+	path    string         // JSON file the sealed accounts are kept in, or "" for memory only
+}
+
+// NewBankDetails creates bank details for employees of the given manager,
+// reading the sealed accounts from the file at path if there is one
+func NewBankDetails(manager EmployeeManager, key []byte, path string) (*BankDetails, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	b := &BankDetails{manager: manager, aead: aead, sealed: make(map[int][]byte), path: path}
+	if path == "" {
+		return b, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b.sealed); err != nil {
+		return nil, fmt.Errorf("reading bank details %s: %w", path, err)
+	}
+	for id := range b.sealed {
+		if _, err := b.open(id); err != nil {
+			return nil, fmt.Errorf("reading bank details %s: %w", path, err)
+		}
+	}
+	return b, nil
+}
+
+// additionalData binds a sealed account to its employee
+func additionalData(employeeID int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(employeeID))
+}
+
+// open decrypts an employee's account. The caller must hold the lock.
+func (b *BankDetails) open(employeeID int) (BankAccount, error) {
+	sealed := b.sealed[employeeID]
+	size := b.aead.NonceSize()
+	if len(sealed) < size {
+		return BankAccount{}, fmt.Errorf("bank account of employee %d is damaged", employeeID)
+	}
+	plain, err := b.aead.Open(nil, sealed[:size], sealed[size:], additionalData(employeeID))
+	if err != nil {
+		return BankAccount{}, fmt.Errorf("bank account of employee %d cannot be decrypted; is EMS_BANK_KEY right?", employeeID)
+	}
+	var account BankAccount
+	err = json.Unmarshal(plain, &account)
+	return account, err
+}
+
+// save writes the sealed accounts to the file. The caller must hold the
+// lock. The file is written under a temporary name and renamed, and an
+// interrupt waits for the save, so it is never left half written.
+func (b *BankDetails) save() error {
+	if b.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(b.sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := b.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, b.path)
+	})
+}
+
+// SetAccount sets the bank account an employee is paid into
+func (b *BankDetails) SetAccount(user User, employeeID int, account BankAccount) error {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return err
+	}
+	if _, err := b.manager.GetEmployee(employeeID); err != nil {
+		return err
+	}
+	account.BankCode = strings.ToUpper(strings.TrimSpace(account.BankCode))
+	account.Number = strings.ToUpper(strings.ReplaceAll(account.Number, " ", ""))
+	if err := account.validate(); err != nil {
+		return err
+	}
+	plain, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sealed[employeeID] = b.aead.Seal(nonce, nonce, plain, additionalData(employeeID))
+	return b.save()
+}
+
+// Account returns the bank account of an employee, and whether there is one
+func (b *BankDetails) Account(user User, employeeID int) (BankAccount, bool, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return BankAccount{}, false, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.sealed[employeeID]; !ok {
+		return BankAccount{}, false, nil
+	}
+	account, err := b.open(employeeID)
+	return account, err == nil, err
+}
+
+// RemoveAccount removes the bank account of an employee
+func (b *BankDetails) RemoveAccount(user User, employeeID int) error {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.sealed[employeeID]; !ok {
+		return ErrMissingBankDetails.WithValue(employeeID)
+	}
+	delete(b.sealed, employeeID)
+	return b.save()
+}
+
+// Forget returns an event subscriber that drops the accounts of removed employees
+func (b *BankDetails) Forget() func(Event) {
+	return func(ev Event) {
+		if ev.Type == EventEmployeeRemoved {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.sealed[ev.EmployeeID]; ok {
+				delete(b.sealed, ev.EmployeeID)
+				b.save()
+			}
+		}
+	}
+}
+
+// Payment is an employee's net pay and the account it goes to
+type Payment struct {
+	Employee *Employee
+	Account  BankAccount
+	Amount   int64 // in minor units of the run's currency
+}
+
+// Payments returns a payment for each payslip of the run with net pay,
+// and the IDs of the employees who have no bank account on file
+func (b *BankDetails) Payments(user User, run *PayrollRun) ([]Payment, []int, error) {
+	if err := user.Require(PermViewPersonalData); err != nil {
+		return nil, nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var payments []Payment
+	var missing []int
+	for _, p := range run.Payslips {
+		if p.Net <= 0 {
+			continue
+		}
+		if _, ok := b.sealed[p.Employee.ID]; !ok {
+			missing = append(missing, p.Employee.ID)
+			continue
+		}
+		account, err := b.open(p.Employee.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		payments = append(payments, Payment{Employee: p.Employee, Account: account, Amount: p.Net})
+	}
+	return payments, missing, nil
+}
+
+// paymentColumns are the columns a CSV payment file can have
+var paymentColumns = []string{"employee_id", "name", "holder", "bank_code", "account", "account_type", "amount", "currency", "reference"}
+
+// PaymentConfig lays out payment files for the bank
+type PaymentConfig struct {
+	// CSVColumns are the columns of CSV payment files, in order; all of
+	// paymentColumns if empty
+	CSVColumns   []string `json:"csv_columns"`
+	CSVDelimiter string   `json:"csv_delimiter"` // one character; a comma if empty
+	CSVHeader    *bool    `json:"csv_header"`    // whether to write a header row; true if not given
+	// The rest identify the company to the bank in NACHA files
+	CompanyName        string `json:"company_name"`
+	CompanyID          string `json:"company_id"`          // up to 10 characters, often "1" and the tax ID
+	OriginRouting      string `json:"origin_routing"`      // routing number of the company's bank
+	DestinationRouting string `json:"destination_routing"` // routing number of the bank receiving the file
+	DestinationName    string `json:"destination_name"`
+}
+
+// validate checks the CSV layout; the NACHA fields are checked when a
+// NACHA file is written
+func (c PaymentConfig) validate() error {
+	for _, column := range c.CSVColumns {
+		if !slices.Contains(paymentColumns, column) {
+			return fmt.Errorf("%w: unknown payment column %q (available: %s)", ErrInvalidInput, column, strings.Join(paymentColumns, ", "))
+		}
+	}
+	if c.CSVDelimiter != "" && utf8.RuneCountInString(c.CSVDelimiter) != 1 {
+		return fmt.Errorf("%w: the payment CSV delimiter must be one character", ErrInvalidInput)
+	}
+	return nil
+}
+
+// paymentReference is the reference payments of a run carry, such as PAYROLL 2026-03
+func paymentReference(run *PayrollRun) string {
+	return "PAYROLL " + run.Month.In(userLocation).Format(monthLayout)
+}
+
+// writePaymentsCSV writes payments as CSV in the configured layout, amounts
+// in the currency's major units
+func writePaymentsCSV(w io.Writer, run *PayrollRun, payments []Payment, cfg PaymentConfig) error {
+	columns := cfg.CSVColumns
+	if len(columns) == 0 {
+		columns = paymentColumns
+	}
+	out := csv.NewWriter(w)
+	if cfg.CSVDelimiter != "" {
+		out.Comma, _ = utf8.DecodeRuneInString(cfg.CSVDelimiter)
+	}
+	if cfg.CSVHeader == nil || *cfg.CSVHeader {
+		out.Write(columns)
+	}
+	for _, p := range payments {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			switch column {
+			case "employee_id":
+				record[i] = strconv.Itoa(p.Employee.ID)
+			case "name":
+				record[i] = p.Employee.Name
+			case "holder":
+				record[i] = p.Account.Holder
+			case "bank_code":
+				record[i] = p.Account.BankCode
+			case "account":
+				record[i] = p.Account.Number
+			case "account_type":
+				record[i] = strings.ToLower(AccountTypeToString(p.Account.Type))
+			case "amount":
+				record[i] = run.Currency.Format(p.Amount)
+			case "currency":
+				record[i] = run.Currency.Code
+			case "reference":
+				record[i] = paymentReference(run)
+			}
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// nachaField fits text to a fixed-width field of a NACHA record, in upper
+// case, left aligned and padded with spaces
+func nachaField(text string, width int) string {
+	text = strings.ToUpper(pdfText(text))
+	if len(text) > width {
+		return text[:width]
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+// writePaymentsNACHA writes payments as a NACHA file of PPD credits, the
+// fixed-width format US banks take for direct deposit: a file header, one
+// batch of entries with its control record, a file control record, and
+// lines of nines filling the last block of ten records
+func writePaymentsNACHA(w io.Writer, run *PayrollRun, payments []Payment, cfg PaymentConfig, now time.Time) error {
+	switch {
+	case run.Currency.Code != "USD":
+		return fmt.Errorf("%w: NACHA files pay US dollars, not %s", ErrInvalidInput, run.Currency.Code)
+	case cfg.CompanyName == "" || cfg.CompanyID == "" || len(cfg.CompanyID) > 10:
+		return fmt.Errorf("%w: NACHA files need the payment company_name and a company_id of up to 10 characters", ErrInvalidInput)
+	case !validRoutingNumber(cfg.OriginRouting) || !validRoutingNumber(cfg.DestinationRouting):
+		return fmt.Errorf("%w: NACHA files need valid payment origin_routing and destination_routing numbers", ErrInvalidInput)
+	}
+
+	local := now.In(userLocation)
+	effective := run.Month.In(userLocation).AddDate(0, 1, -1)
+	odfi := cfg.OriginRouting[:8]
+	var records []string
+	record := func(format string, args ...interface{}) { records = append(records, fmt.Sprintf(format, args...)) }
+
+	record("101 %s%s%s%sA094101%s%s%s", cfg.DestinationRouting, " "+cfg.OriginRouting, local.Format("060102"), local.Format("1504"),
+		nachaField(cfg.DestinationName, 23), nachaField(cfg.CompanyName, 23), nachaField("", 8))
+	record("5220%s%s%sPPD%s%s%s   1%s%07d", nachaField(cfg.CompanyName, 16), nachaField("", 20), nachaField(cfg.CompanyID, 10),
+		nachaField("PAYROLL", 10), effective.Format("Jan 06"), effective.Format("060102"), odfi, 1)
+
+	var hash, total int64
+	for i, p := range payments {
+		if !validRoutingNumber(p.Account.BankCode) {
+			return fmt.Errorf("%w: employee %d's bank code %s is not a US routing number", ErrInvalidInput, p.Employee.ID, p.Account.BankCode)
+		}
+		if len(p.Account.Number) > 17 {
+			return fmt.Errorf("%w: employee %d's account number is too long for a NACHA file", ErrInvalidInput, p.Employee.ID)
+		}
+		code := 22 // checking credit
+		if p.Account.Type == AccountSavings {
+			code = 32
+		}
+		routing, _ := strconv.ParseInt(p.Account.BankCode[:8], 10, 64)
+		hash += routing
+		total += p.Amount
+		record("6%d%s%s%010d%s%s  0%s%07d", code, p.Account.BankCode, nachaField(p.Account.Number, 17), p.Amount,
+			nachaField(strconv.Itoa(p.Employee.ID), 15), nachaField(p.Account.Holder, 22), odfi, i+1)
+	}
+	hash %= 10_000_000_000
+
+	record("8220%06d%010d%012d%012d%s%s%s%s%07d", len(payments), hash, 0, total,
+		nachaField(cfg.CompanyID, 10), nachaField("", 19), nachaField("", 6), odfi, 1)
+	blocks := (len(records) + 1 + 9) / 10
+	record("9%06d%06d%08d%010d%012d%012d%s", 1, blocks, len(payments), hash, 0, total, nachaField("", 39))
+	for len(records)%10 != 0 {
+		records = append(records, strings.Repeat("9", 94))
+	}
+
+	for _, r := range records {
+		if len(r) != 94 {
+			return fmt.Errorf("NACHA record of %d characters instead of 94: %q", len(r), r)
+		}
+		if _, err := io.WriteString(w, r+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePaymentFile writes the payments of a payroll run in a format, csv or
+// nacha, laid out as the run's payroll config says. Unless skipMissing,
+// employees without a bank account are an error.
+func writePaymentFile(w io.Writer, banks *BankDetails, user User, run *PayrollRun, config *PayrollConfig, format string, skipMissing bool) (int, error) {
+	payments, missing, err := banks.Payments(user, run)
+	if err != nil {
+		return 0, err
+	}
+	if len(missing) > 0 && !skipMissing {
+		return 0, fmt.Errorf("%w for employee(s) %s", ErrMissingBankDetails, strings.Trim(fmt.Sprint(missing), "[]"))
+	}
+	if format == "nacha" {
+		return len(payments), writePaymentsNACHA(w, run, payments, config.Payment, appClock.Now())
+	}
+	return len(payments), writePaymentsCSV(w, run, payments, config.Payment)
+}
+
+// readAccount reads a bank account from the user
+func readAccount(reader *bufio.Reader) (BankAccount, error) {
+	var a BankAccount
+	var err error
+	if a.Holder, err = readString(reader, "Account holder: "); err != nil {
+		return a, err
+	}
+	if a.BankCode, err = readString(reader, "Bank code (routing number, sort code or similar): "); err != nil {
+		return a, err
+	}
+	if a.Number, err = readString(reader, "Account number: "); err != nil {
+		return a, err
+	}
+	a.Type, err = readValue(reader, "Account type (checking, savings): ", StringToAccountType)
+	return a, err
+}
+
+// bankDetailsInteractive manages bank accounts and payment files through user interaction
+func bankDetailsInteractive(banks *BankDetails, loans *Loans, manager EmployeeManager, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Bank Details ==="))
+
+	// Check access before asking for any input
+	if err := user.Require(PermViewPersonalData); err != nil {
+		return err
+	}
+
+	fmt.Println("1. Set bank account")
+	fmt.Println("2. View bank account")
+	fmt.Println("3. Remove bank account")
+	fmt.Println("4. Write payment file for a month's payroll")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1, 2, 3:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		switch option {
+		case 1:
+			account, err := readAccount(reader)
+			if err != nil {
+				return err
+			}
+			if err := banks.SetAccount(user, id, account); err != nil {
+				return err
+			}
+			fmt.Println("\n" + successText("Bank account saved successfully!"))
+		case 2:
+			account, ok, err := banks.Account(user, id)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("\nNo bank account on file.")
+				return nil
+			}
+			fmt.Println("\n" + account.Masked())
+		case 3:
+			ok, err := confirm(reader, fmt.Sprintf("\nAre you sure you want to remove the bank account of employee %d?", id), true)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("\n" + warningText("Operation cancelled."))
+				return nil
+			}
+			if err := banks.RemoveAccount(user, id); err != nil {
+				return err
+			}
+			fmt.Println("\n" + successText("Bank account removed successfully!"))
+		}
+
+	case 4:
+		entity, err := readEntity(reader, true)
+		if err != nil {
+			return err
+		}
+		now := clockOf(manager).Now()
+		month, err := readValue(reader, "Month (YYYY-MM; blank for this month): ", func(input string) (time.Time, error) {
+			return parsePayrollMonth(input, now)
+		})
+		if err != nil {
+			return err
+		}
+		format, err := readValue(reader, "Format (csv, nacha) [csv]: ", func(input string) (string, error) {
+			switch input = strings.ToLower(input); input {
+			case "":
+				return "csv", nil
+			case "csv", "nacha":
+				return input, nil
+			}
+			return "", fmt.Errorf("%w: format must be csv or nacha", ErrInvalidInput)
+		})
+		if err != nil {
+			return err
+		}
+		path, err := readString(reader, "Payment file: ")
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return fmt.Errorf("%w: payment file cannot be empty", ErrInvalidInput)
+		}
+
+		employees, _, err := payrollEmployees(manager, month)
+		if err != nil {
+			return err
+		}
+		run, err := RunPayroll(entity.Employees(employees), month, entity.Config(), loans)
+		if err != nil {
+			return err
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		written, err := writePaymentFile(file, banks, user, run, entity.Config(), format, false)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+		fmt.Printf("\nWrote %d payment(s) totalling %s %s to %s\n", written, run.Currency.Format(run.Net), run.Currency.Code, path)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	return nil
+}
+
+// runPayments implements the payments command, which writes the payment
+// file of a month's payroll for the bank
+func runPayments(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("payments", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to read employees from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	monthText := fs.String("month", "", "month of the payroll to pay, as YYYY-MM (default this month)")
+	configPath := fs.String("payroll", "", "JSON file of the payroll currency, rounding, deductions, tax and payment file layout")
+	bankFile := fs.String("bank-file", "", "file of encrypted bank accounts, whose key is in EMS_BANK_KEY")
+	loansFile := fs.String("loans-file", "", "file of loans whose installments are deducted")
+	entitiesPath := fs.String("entities", "", "JSON file of the group's legal entities, each with its own payroll")
+	entityName := fs.String("entity", "", "legal entity to pay, required with -entities")
+	formatName := fs.String("format", "csv", "payment file format (csv, nacha)")
+	skipMissing := fs.Bool("skip-missing", false, "leave out employees without a bank account instead of failing")
+	out := fs.String("out", "", "file to write (default standard output)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	if *formatName != "csv" && *formatName != "nacha" {
+		return fmt.Errorf("%w: format must be csv or nacha", ErrInvalidInput)
+	}
+	if *bankFile == "" {
+		return fmt.Errorf("%w: payments need the -bank-file of bank accounts", ErrInvalidInput)
+	}
+	month, err := parsePayrollMonth(*monthText, appClock.Now())
+	if err != nil {
+		return err
+	}
+	if *configPath != "" {
+		if payrollConfig, err = LoadPayrollConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	entity, err := commandEntity(*entitiesPath, *entityName, true)
+	if err != nil {
+		return err
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	key, err := bankKey(*bankFile)
+	if err != nil {
+		return err
+	}
+	banks, err := NewBankDetails(store, key, *bankFile)
+	if err != nil {
+		return err
+	}
+	loans, err := NewLoans(store, *loansFile)
+	if err != nil {
+		return err
+	}
+	employees, _, err := payrollEmployees(store, month)
+	if err != nil {
+		return err
+	}
+	run, err := RunPayroll(entity.Employees(employees), month, entity.Config(), loans)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout = f
+	}
+	// Whoever holds the bank key can read the accounts, so the command acts for HR
+	written, err := writePaymentFile(stdout, banks, User{Name: "payments", Role: RoleHR}, run, entity.Config(), *formatName, *skipMissing)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Wrote %d payment(s) for %s\n", written, month.In(userLocation).Format("January 2006"))
+	return nil
+}
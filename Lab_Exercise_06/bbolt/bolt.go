@@ -0,0 +1,505 @@
+//go:build bbolt
+
+// The bolt backend needs go.etcd.io/bbolt, so it is kept out of the default
+// build. To use it, copy this file next to main.go in a module that requires
+// go.etcd.io/bbolt and build with -tags bbolt; then select it with
+// -storage bolt -dsn employees.db.
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket names used by BoltEmployeeManager
+var (
+	boltEmployeesBucket   = []byte("employees")   // ID -> Employee JSON
+	boltDepartmentsBucket = []byte("departments") // department -> bucket of IDs
+	boltTransfersBucket   = []byte("transfers")   // ID -> bucket of sequence -> TransferRecord JSON
+	boltMetaBucket        = []byte("meta")        // file settings such as the schema version
+)
+
+// boltSchemaVersionKey holds the file's schema version in the meta bucket
+var boltSchemaVersionKey = []byte("schema_version")
+
+// boltMigrations upgrade the file one schema version at a time. Files
+// created before versioning have the version 1 buckets and no meta bucket;
+// migration 1 only creates what is missing, so it brings them up to date.
+var boltMigrations = []Migration[*bolt.Tx]{
+	{Version: 1, Description: "employees, departments and transfers buckets", Up: func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltEmployeesBucket, boltDepartmentsBucket, boltTransfersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+}
+
+// boltCompactThreshold is how much free space the file may hold before it is
+// compacted when the manager is closed
+const boltCompactThreshold = 4 << 20
+
+// BoltEmployeeManager implements EmployeeManager on an embedded bbolt file,
+// for single-binary deployments. Each department has its own bucket of
+// employee IDs, used as a secondary index.
+type BoltEmployeeManager struct {
+	db       *bolt.DB
+	path     string
+	readOnly bool
+	events   EventBus
+	clock    Clock
+}
+
+// NewBoltEmployeeManager opens or creates the bbolt file at path
+func NewBoltEmployeeManager(path string, clock Clock) (*BoltEmployeeManager, error) {
+	db, err := openBolt(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltEmployeeManager{db: db, path: path, clock: clock}, nil
+}
+
+// NewReadOnlyBoltEmployeeManager opens an existing bbolt file with a shared
+// lock. bbolt allows one writer or any number of readers, so it waits for
+// the writing process to close the file; it suits reporting processes that
+// run alongside each other rather than alongside the primary.
+func NewReadOnlyBoltEmployeeManager(path string, clock Clock) (*BoltEmployeeManager, error) {
+	db, err := openBolt(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltEmployeeManager{db: db, path: path, readOnly: true, clock: clock}, nil
+}
+
+// openBolt opens the file and upgrades it to the latest schema version, or
+// only checks the version when it is opened read-only
+func openBolt(path string, readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt file: %w", err)
+	}
+	if readOnly {
+		err = db.View(func(tx *bolt.Tx) error {
+			return checkSchemaVersion("bolt", boltSchemaVersion(tx), latestVersion(boltMigrations))
+		})
+	} else {
+		err = migrateBolt(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// boltSchemaVersion reads the file's schema version, 0 for an unversioned file
+func boltSchemaVersion(tx *bolt.Tx) int {
+	meta := tx.Bucket(boltMetaBucket)
+	if meta == nil {
+		return 0
+	}
+	version, _ := strconv.Atoi(string(meta.Get(boltSchemaVersionKey)))
+	return version
+}
+
+// migrateBolt applies the schema migrations the file has not had, each in
+// the same write transaction as the version it records
+func migrateBolt(db *bolt.DB) error {
+	var current int
+	db.View(func(tx *bolt.Tx) error {
+		current = boltSchemaVersion(tx)
+		return nil
+	})
+
+	return applyMigrations("bolt", current, boltMigrations, func(m Migration[*bolt.Tx]) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			meta, err := tx.CreateBucketIfNotExists(boltMetaBucket)
+			if err != nil {
+				return err
+			}
+			return meta.Put(boltSchemaVersionKey, []byte(strconv.Itoa(m.Version)))
+		})
+	})
+}
+
+// boltKey encodes an ID so keys sort numerically
+func boltKey(id int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+// boltID decodes a key written by boltKey
+func boltID(key []byte) int {
+	return int(binary.BigEndian.Uint64(key))
+}
+
+// boltDepartmentKey returns the name of a department's index bucket
+func boltDepartmentKey(dept Department) []byte {
+	return []byte(strconv.Itoa(int(dept)))
+}
+
+// getEmployee reads an employee within a transaction
+func boltGetEmployee(tx *bolt.Tx, id int) (*Employee, error) {
+	data := tx.Bucket(boltEmployeesBucket).Get(boltKey(id))
+	if data == nil {
+		return nil, ErrEmployeeNotFound
+	}
+	var e Employee
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// boltPutEmployee writes an employee and moves it to its department's index
+func boltPutEmployee(tx *bolt.Tx, e *Employee, previousDept Department) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltEmployeesBucket).Put(boltKey(e.ID), data); err != nil {
+		return err
+	}
+
+	departments := tx.Bucket(boltDepartmentsBucket)
+	if previousDept >= 0 && previousDept != e.Department {
+		if index := departments.Bucket(boltDepartmentKey(previousDept)); index != nil {
+			if err := index.Delete(boltKey(e.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	index, err := departments.CreateBucketIfNotExists(boltDepartmentKey(e.Department))
+	if err != nil {
+		return err
+	}
+	return index.Put(boltKey(e.ID), nil)
+}
+
+// Clock returns the clock the manager uses for validation and events
+func (m *BoltEmployeeManager) Clock() Clock {
+	return m.clock
+}
+
+// Subscribe registers a function that is notified of manager events
+func (m *BoltEmployeeManager) Subscribe(fn func(Event)) {
+	m.events.Subscribe(fn)
+}
+
+// EventBacklog returns the number of the manager's events still being delivered
+func (m *BoltEmployeeManager) EventBacklog() int {
+	return m.events.Backlog()
+}
+
+// publish notifies subscribers of a change
+func (m *BoltEmployeeManager) publish(c Change) {
+	c.Time = m.clock.Now()
+	m.events.Publish(c.notification())
+}
+
+// AddEmployee adds a new employee, assigning the next ID if it is 0
+func (m *BoltEmployeeManager) AddEmployee(e *Employee) error {
+	if e == nil {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+	employeeCopy.Version = 1
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		employees := tx.Bucket(boltEmployeesBucket)
+		if employeeCopy.ID == 0 {
+			// Auto-assign ID if not provided, skipping IDs that were set explicitly
+			for {
+				seq, err := employees.NextSequence()
+				if err != nil {
+					return err
+				}
+				if employees.Get(boltKey(int(seq))) == nil {
+					employeeCopy.ID = int(seq)
+					break
+				}
+			}
+		} else if employees.Get(boltKey(employeeCopy.ID)) != nil {
+			return ErrDuplicateID
+		}
+		return boltPutEmployee(tx, &employeeCopy, -1)
+	})
+	if err != nil {
+		return err
+	}
+
+	e.ID, e.Version = employeeCopy.ID, employeeCopy.Version
+	m.publish(Change{Type: EventEmployeeAdded, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// RemoveEmployee removes an employee by ID
+func (m *BoltEmployeeManager) RemoveEmployee(id int) error {
+	var removed *Employee
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		removed, err = boltGetEmployee(tx, id)
+		if err != nil {
+			return err
+		}
+		if index := tx.Bucket(boltDepartmentsBucket).Bucket(boltDepartmentKey(removed.Department)); index != nil {
+			if err := index.Delete(boltKey(id)); err != nil {
+				return err
+			}
+		}
+		transfers := tx.Bucket(boltTransfersBucket)
+		if transfers.Bucket(boltKey(id)) != nil {
+			if err := transfers.DeleteBucket(boltKey(id)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltEmployeesBucket).Delete(boltKey(id))
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(Change{Type: EventEmployeeRemoved, EmployeeID: id, Employee: *removed})
+	return nil
+}
+
+// UpdateEmployee updates an existing employee
+func (m *BoltEmployeeManager) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		existing, err := boltGetEmployee(tx, e.ID)
+		if err != nil {
+			return err
+		}
+		employeeCopy.Version = existing.Version + 1
+		return boltPutEmployee(tx, &employeeCopy, existing.Department)
+	})
+	if err != nil {
+		return err
+	}
+	e.Version = employeeCopy.Version
+
+	m.publish(Change{Type: EventEmployeeUpdated, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// GetEmployee retrieves an employee by ID
+func (m *BoltEmployeeManager) GetEmployee(id int) (*Employee, error) {
+	var employee *Employee
+	err := m.db.View(func(tx *bolt.Tx) error {
+		var err error
+		employee, err = boltGetEmployee(tx, id)
+		return err
+	})
+	return employee, err
+}
+
+// ListEmployees returns a list of all employees, ordered by ID
+func (m *BoltEmployeeManager) ListEmployees() ([]*Employee, error) {
+	employees := make([]*Employee, 0)
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltEmployeesBucket).ForEach(func(_, data []byte) error {
+			var e Employee
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			employees = append(employees, &e)
+			return nil
+		})
+	})
+	return employees, err
+}
+
+// FilterEmployees returns employees that match the filter criteria.
+// It returns no employees if the file cannot be read.
+func (m *BoltEmployeeManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	employees, err := m.ListEmployees()
+	if err != nil {
+		return make([]*Employee, 0)
+	}
+
+	result := make([]*Employee, 0)
+	for _, emp := range employees {
+		if filter(emp) {
+			result = append(result, emp)
+		}
+	}
+	return result
+}
+
+// ListByDepartment returns the employees in a department using its index bucket
+func (m *BoltEmployeeManager) ListByDepartment(dept Department) []*Employee {
+	result := make([]*Employee, 0)
+	m.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket(boltDepartmentsBucket).Bucket(boltDepartmentKey(dept))
+		if index == nil {
+			return nil
+		}
+		return index.ForEach(func(key, _ []byte) error {
+			e, err := boltGetEmployee(tx, boltID(key))
+			if err != nil {
+				return err
+			}
+			result = append(result, e)
+			return nil
+		})
+	})
+	return result
+}
+
+// TransferEmployee moves an employee to another department and records the transfer
+func (m *BoltEmployeeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+
+	var transferred *Employee
+	var record TransferRecord
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		employee, err := boltGetEmployee(tx, id)
+		if err != nil {
+			return err
+		}
+		if employee.Department == newDept {
+			return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+		}
+
+		record = TransferRecord{
+			EmployeeID:     id,
+			FromDepartment: employee.Department,
+			ToDepartment:   newDept,
+			EffectiveDate:  effectiveDate.UTC(),
+			RecordedAt:     m.clock.Now(),
+		}
+		history, err := tx.Bucket(boltTransfersBucket).CreateBucketIfNotExists(boltKey(id))
+		if err != nil {
+			return err
+		}
+		seq, err := history.NextSequence()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if err := history.Put(boltKey(int(seq)), data); err != nil {
+			return err
+		}
+
+		previousDept := employee.Department
+		employee.Department = newDept
+		employee.Version++
+		transferred = employee
+		return boltPutEmployee(tx, employee, previousDept)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(Change{
+		Type:       EventEmployeeTransferred,
+		EmployeeID: id,
+		Employee:   *transferred,
+		Transfer:   &record,
+	})
+	return nil
+}
+
+// TransferHistory returns the transfers recorded for an employee, oldest first
+func (m *BoltEmployeeManager) TransferHistory(id int) []TransferRecord {
+	history := make([]TransferRecord, 0)
+	m.db.View(func(tx *bolt.Tx) error {
+		transfers := tx.Bucket(boltTransfersBucket).Bucket(boltKey(id))
+		if transfers == nil {
+			return nil
+		}
+		return transfers.ForEach(func(_, data []byte) error {
+			var t TransferRecord
+			if err := json.Unmarshal(data, &t); err != nil {
+				return err
+			}
+			history = append(history, t)
+			return nil
+		})
+	})
+	return history
+}
+
+// Compact rewrites the file without its free pages, shrinking it after removals
+func (m *BoltEmployeeManager) Compact() error {
+	tmpPath := m.path + ".compact"
+	dst, err := bolt.Open(tmpPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, m.db, 1<<16); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := m.db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return err
+	}
+	m.db, err = openBolt(m.path, false)
+	return err
+}
+
+// Close compacts the file if it holds a lot of free space, then closes it.
+// Files opened read-only are never compacted.
+func (m *BoltEmployeeManager) Close() error {
+	free := int64(m.db.Stats().FreePageN) * int64(m.db.Info().PageSize)
+	if free > boltCompactThreshold && !m.readOnly {
+		if err := m.Compact(); err != nil {
+			return fmt.Errorf("compacting bolt file: %w", err)
+		}
+	}
+	return m.db.Close()
+}
+
+func init() {
+	RegisterStorage("bolt", func(dsn string, clock Clock) (Storage, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("%w: the bolt backend needs a -dsn file path", ErrInvalidInput)
+		}
+		return NewBoltEmployeeManager(dsn, clock)
+	})
+	RegisterReadOnlyStorage("bolt", func(dsn string, clock Clock) (Storage, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("%w: the bolt backend needs a -dsn file path", ErrInvalidInput)
+		}
+		return NewReadOnlyBoltEmployeeManager(dsn, clock)
+	})
+}
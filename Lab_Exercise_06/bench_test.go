@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchSizes lists the store sizes the benchmarks run at, for example
+// go test -bench . -sizes 1000,100000,1000000
+var benchSizes = flag.String("sizes", "1000,100000", "store sizes to benchmark, separated by commas")
+
+// benchStore is an EmployeeManager implementation compared by the benchmarks
+type benchStore struct {
+	name string
+	open func(capacity int) EmployeeManager
+}
+
+// benchStores lists the implementations the benchmarks compare
+var benchStores = []benchStore{
+	{"map", func(int) EmployeeManager { return NewInMemoryEmployeeManagerWithClock(SystemClock{}) }},
+	{"columnar", func(capacity int) EmployeeManager { return NewColumnarEmployeeManager(capacity, SystemClock{}) }},
+	{"sharded", func(int) EmployeeManager { return NewShardedEmployeeManager(0, SystemClock{}) }},
+}
+
+// benchEmployee returns a synthetic employee for benchmarks
+func benchEmployee(i int) *Employee {
+	return &Employee{
+		Name:       fmt.Sprintf("Employee %d", i),
+		Position:   "Engineer",
+		Salary:     float64(40000 + i%80000),
+		Department: Department(i % len(departmentNames)),
+		JoinDate:   dateOf(2015+i%10, 1, 1+i%28),
+	}
+}
+
+// filledStores holds the stores the read benchmarks share, by store and
+// size, since filling a large store takes much longer than the benchmarks
+var filledStores = make(map[string]EmployeeManager)
+
+// benchFilled returns a store holding n synthetic employees, filling it on first use
+func benchFilled(store benchStore, n int) EmployeeManager {
+	key := store.name + "/" + strconv.Itoa(n)
+	if manager, ok := filledStores[key]; ok {
+		return manager
+	}
+	manager := store.open(n)
+	for i := 0; i < n; i++ {
+		manager.AddEmployee(benchEmployee(i))
+	}
+	filledStores[key] = manager
+	return manager
+}
+
+// runStores runs a benchmark against every store at every size in -sizes,
+// as sub-benchmarks named store/n=size
+func runStores(b *testing.B, fn func(b *testing.B, store benchStore, n int)) {
+	for _, field := range strings.Split(*benchSizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			b.Fatalf("-sizes must be positive numbers separated by commas, not %q", *benchSizes)
+		}
+		for _, store := range benchStores {
+			b.Run(fmt.Sprintf("%s/n=%d", store.name, n), func(b *testing.B) {
+				fn(b, store, n)
+			})
+		}
+	}
+}
+
+// runFilled runs a read benchmark against every store filled to every size
+func runFilled(b *testing.B, fn func(b *testing.B, manager EmployeeManager, n int)) {
+	runStores(b, func(b *testing.B, store benchStore, n int) {
+		manager := benchFilled(store, n)
+		b.ReportAllocs()
+		b.ResetTimer()
+		fn(b, manager, n)
+	})
+}
+
+func BenchmarkAdd(b *testing.B) {
+	// Adds start from an empty store, so the size is only the capacity hint
+	runStores(b, func(b *testing.B, store benchStore, n int) {
+		b.ReportAllocs()
+		manager := store.open(n)
+		for i := 0; i < b.N; i++ {
+			manager.AddEmployee(benchEmployee(i))
+		}
+	})
+}
+
+func BenchmarkGet(b *testing.B) {
+	runFilled(b, func(b *testing.B, manager EmployeeManager, n int) {
+		for i := 0; i < b.N; i++ {
+			manager.GetEmployee(i%n + 1)
+		}
+	})
+}
+
+func BenchmarkList(b *testing.B) {
+	runFilled(b, func(b *testing.B, manager EmployeeManager, n int) {
+		for i := 0; i < b.N; i++ {
+			manager.ListEmployees()
+		}
+	})
+}
+
+func BenchmarkValues(b *testing.B) {
+	runFilled(b, func(b *testing.B, manager EmployeeManager, n int) {
+		for i := 0; i < b.N; i++ {
+			employeeValues(manager)
+		}
+	})
+}
+
+func BenchmarkFilter(b *testing.B) {
+	runFilled(b, func(b *testing.B, manager EmployeeManager, n int) {
+		for i := 0; i < b.N; i++ {
+			manager.FilterEmployees(func(e *Employee) bool { return e.Department == Engineering })
+		}
+	})
+}
+
+// The parallel benchmarks measure lock contention across GOMAXPROCS goroutines
+
+func BenchmarkParallelAdd(b *testing.B) {
+	runStores(b, func(b *testing.B, store benchStore, n int) {
+		manager := store.open(n)
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				manager.AddEmployee(benchEmployee(i))
+			}
+		})
+	})
+}
+
+func BenchmarkParallelMixed(b *testing.B) {
+	runFilled(b, func(b *testing.B, manager EmployeeManager, n int) {
+		b.RunParallel(func(pb *testing.PB) {
+			// Nine reads for every update
+			for i := 0; pb.Next(); i++ {
+				id := i%n + 1
+				if i%10 == 0 {
+					e := benchEmployee(id)
+					e.ID = id
+					manager.UpdateEmployee(e)
+				} else {
+					manager.GetEmployee(id)
+				}
+			}
+		})
+	})
+}
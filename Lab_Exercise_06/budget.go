@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when a change would take a department over a
+// salary budget that rejects overruns
+var ErrBudgetExceeded = errors.New("salary budget exceeded")
+
+// Budget enforcement constants using iota
+const (
+	EnforceWarn     = iota // the change is made and a budget warning printed
+	EnforceApproval        // the change is queued for approval
+	EnforceReject          // the change is refused
+)
+
+// EnforcementToString converts a budget enforcement constant to string
+func EnforcementToString(enforcement int) string {
+	switch enforcement {
+	case EnforceWarn:
+		return "warn"
+	case EnforceApproval:
+		return "approve"
+	case EnforceReject:
+		return "reject"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToEnforcement converts string to budget enforcement constant
+func StringToEnforcement(enforcement string) (int, error) {
+	switch strings.ToLower(enforcement) {
+	case "warn", "":
+		return EnforceWarn, nil
+	case "approve", "approval":
+		return EnforceApproval, nil
+	case "reject":
+		return EnforceReject, nil
+	default:
+		return -1, fmt.Errorf("%w: enforcement must be warn, approve or reject", ErrInvalidInput)
+	}
+}
+
+// DepartmentBudget holds the planned headcount and salary budget of a
+// department, and what happens to changes that would overrun the salary budget
+type DepartmentBudget struct {
+	Department   Department
+	Headcount    int
+	SalaryBudget float64
+	Enforcement  int
+}
+
+// BudgetLine compares a department's budget with its actual employees
+type BudgetLine struct {
+	Department        Department
+	BudgetedHeadcount int
+	ActualHeadcount   int
+	OpenPositions     int
+	SalaryBudget      float64
+	ActualSalary      float64
+	Enforcement       int
+}
+
+// HeadcountVariance returns budgeted minus actual headcount (negative means over budget)
+func (l BudgetLine) HeadcountVariance() int {
+	return l.BudgetedHeadcount - l.ActualHeadcount
+}
+
+// SalaryVariance returns budgeted minus actual salary (negative means over budget)
+func (l BudgetLine) SalaryVariance() float64 {
+	return l.SalaryBudget - l.ActualSalary
+}
+
+// OverBudget reports whether headcount or salary exceed the budget
+func (l BudgetLine) OverBudget() bool {
+	return l.HeadcountVariance() < 0 || l.SalaryVariance() < 0
+}
+
+// BudgetPlan stores department budgets and compares them with actual data
+type BudgetPlan struct {
+	budgets map[Department]DepartmentBudget
+}
+
+// NewBudgetPlan creates an empty BudgetPlan
+func NewBudgetPlan() *BudgetPlan {
+	return &BudgetPlan{
+		budgets: make(map[Department]DepartmentBudget),
+	}
+}
+
+// SetBudget defines or replaces the budget of a department
+func (p *BudgetPlan) SetBudget(b DepartmentBudget) error {
+	if !b.Department.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+	if b.Headcount < 0 || b.SalaryBudget < 0 {
+		return fmt.Errorf("%w: budget values cannot be negative", ErrInvalidInput)
+	}
+	if EnforcementToString(b.Enforcement) == "Unknown" {
+		return fmt.Errorf("%w: unknown budget enforcement %d", ErrInvalidInput, b.Enforcement)
+	}
+	p.budgets[b.Department] = b
+	return nil
+}
+
+// Budget returns the budget of a department, if one was defined
+func (p *BudgetPlan) Budget(dept Department) (DepartmentBudget, bool) {
+	b, ok := p.budgets[dept]
+	return b, ok
+}
+
+// Line computes the budget line of a single department
+func (p *BudgetPlan) Line(manager EmployeeManager, dept Department) BudgetLine {
+	b := p.budgets[dept]
+	line := BudgetLine{
+		Department:        dept,
+		BudgetedHeadcount: b.Headcount,
+		SalaryBudget:      b.SalaryBudget,
+		Enforcement:       b.Enforcement,
+	}
+
+	for _, emp := range manager.FilterEmployees(func(e *Employee) bool {
+		return e.Department == dept
+	}) {
+		line.ActualHeadcount++
+		line.ActualSalary += emp.Salary
+	}
+
+	if open := line.HeadcountVariance(); open > 0 {
+		line.OpenPositions = open
+	}
+	return line
+}
+
+// Report returns the budget lines of all departments with a budget, ordered by department
+func (p *BudgetPlan) Report(manager EmployeeManager) []BudgetLine {
+	lines := make([]BudgetLine, 0, len(p.budgets))
+	for _, dept := range AllDepartments() {
+		if _, ok := p.budgets[dept]; ok {
+			lines = append(lines, p.Line(manager, dept))
+		}
+	}
+	return lines
+}
+
+// Watch returns an event subscriber that warns when a change pushes a department over budget
+func (p *BudgetPlan) Watch(manager EmployeeManager) func(Event) {
+	return func(ev Event) {
+		switch ev.Type {
+		case EventEmployeeAdded, EventEmployeeUpdated, EventEmployeeRemoved, EventEmployeeTransferred:
+		default:
+			return
+		}
+		if _, ok := p.budgets[ev.Department]; !ok {
+			return
+		}
+
+		line := p.Line(manager, ev.Department)
+		if line.OverBudget() {
+			fmt.Printf(warningText("Budget warning:")+" %s is over budget (headcount %d/%d, salary $%.2f/$%.2f)\n",
+				line.Department, line.ActualHeadcount, line.BudgetedHeadcount,
+				line.ActualSalary, line.SalaryBudget)
+		}
+	}
+}
+
+// BudgetEnforcer decorates an EmployeeManager so that adds, updates and
+// transfers that would take a department over its salary budget are handled
+// by the budget's enforcement: let through (the plan's Watch warns), queued
+// with the ApprovalManager it wraps, or rejected with ErrBudgetExceeded.
+// Changes that lower a department's payroll are always let through, as are
+// departments without a salary budget.
+type BudgetEnforcer struct {
+	EmployeeManager
+	plan *BudgetPlan
+}
+
+// NewBudgetEnforcer creates a BudgetEnforcer checking changes against plan
+func NewBudgetEnforcer(inner EmployeeManager, plan *BudgetPlan) *BudgetEnforcer {
+	return &BudgetEnforcer{EmployeeManager: inner, plan: plan}
+}
+
+// Unwrap returns the wrapped manager
+func (b *BudgetEnforcer) Unwrap() EmployeeManager {
+	return b.EmployeeManager
+}
+
+// overrun returns the budget a change would overrun by adding delta to the
+// payroll of dept, and the payroll it would reach
+func (b *BudgetEnforcer) overrun(dept Department, delta float64) (DepartmentBudget, float64, bool) {
+	budget, ok := b.plan.Budget(dept)
+	if !ok || budget.SalaryBudget <= 0 || delta <= 0 {
+		return budget, 0, false
+	}
+	payroll := b.plan.Line(b.EmployeeManager, dept).ActualSalary + delta
+	return budget, payroll, payroll > budget.SalaryBudget
+}
+
+// enforce applies an approval or reject budget to a change that would overrun
+// it, returning ErrPendingApproval or ErrBudgetExceeded
+func (b *BudgetEnforcer) enforce(budget DepartmentBudget, payroll float64, request *ApprovalRequest) error {
+	overrun := fmt.Sprintf("%s payroll would be $%.2f of a $%.2f budget",
+		budget.Department, payroll, budget.SalaryBudget)
+	switch budget.Enforcement {
+	case EnforceApproval:
+		approvals, ok := capability[*ApprovalManager](b.EmployeeManager)
+		if !ok {
+			return fmt.Errorf("%w: %s, and approvals are not enabled to approve it", ErrBudgetExceeded, overrun)
+		}
+		request.Note = overrun
+		return approvals.submit(request)
+	default:
+		return fmt.Errorf("%w: %s", ErrBudgetExceeded, overrun)
+	}
+}
+
+// AddEmployee adds the employee unless it overruns its department's budget
+func (b *BudgetEnforcer) AddEmployee(e *Employee) error {
+	if e == nil {
+		return ErrInvalidInput
+	}
+	budget, payroll, over := b.overrun(e.Department, e.Salary)
+	if !over || budget.Enforcement == EnforceWarn {
+		return b.EmployeeManager.AddEmployee(e)
+	}
+	if err := validateEmployee(e, clockOf(b.EmployeeManager).Now()); err != nil {
+		return err
+	}
+	return b.enforce(budget, payroll, &ApprovalRequest{Kind: RequestNewHire, After: *e})
+}
+
+// UpdateEmployee applies the update unless it overruns its department's budget
+func (b *BudgetEnforcer) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	current, err := b.EmployeeManager.GetEmployee(e.ID)
+	if err != nil {
+		return err
+	}
+	delta := e.Salary
+	if e.Department == current.Department {
+		delta -= current.Salary
+	}
+	budget, payroll, over := b.overrun(e.Department, delta)
+	if !over || budget.Enforcement == EnforceWarn {
+		return b.EmployeeManager.UpdateEmployee(e)
+	}
+	if err := validateEmployee(e, clockOf(b.EmployeeManager).Now()); err != nil {
+		return err
+	}
+	return b.enforce(budget, payroll, &ApprovalRequest{Kind: RequestSalaryChange, Before: *current, After: *e})
+}
+
+// TransferEmployee applies the transfer unless it overruns the new department's budget
+func (b *BudgetEnforcer) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	current, err := b.EmployeeManager.GetEmployee(id)
+	if err != nil || current.Department == newDept {
+		return b.EmployeeManager.TransferEmployee(id, newDept, effectiveDate)
+	}
+	budget, payroll, over := b.overrun(newDept, current.Salary)
+	if !over || budget.Enforcement == EnforceWarn {
+		return b.EmployeeManager.TransferEmployee(id, newDept, effectiveDate)
+	}
+	request := &ApprovalRequest{Kind: RequestTransfer, Before: *current, NewDepartment: newDept, EffectiveDate: effectiveDate}
+	return b.enforce(budget, payroll, request)
+}
+
+// writeBudgetReport writes actual vs budget for every budgeted department
+func writeBudgetReport(w io.Writer, lines []BudgetLine) {
+	if len(lines) == 0 {
+		fmt.Fprintln(w, "\nNo department budgets defined.")
+		return
+	}
+
+	fmt.Fprintln(w, "\n=== Budget vs Actual ===")
+	fmt.Fprintf(w, "%-12s %8s %8s %8s %6s %14s %14s %14s  %s\n",
+		"Department", "Budget", "Actual", "Var", "Open", "Salary Budget", "Actual Salary", "Variance", "Overruns")
+	fmt.Fprintln(w, strings.Repeat("-", 102))
+	for _, l := range lines {
+		fmt.Fprintf(w, "%-12s %8d %8d %8d %6d %14.2f %14.2f %14.2f  %s\n",
+			l.Department, l.BudgetedHeadcount, l.ActualHeadcount,
+			l.HeadcountVariance(), l.OpenPositions, l.SalaryBudget, l.ActualSalary, l.SalaryVariance(),
+			EnforcementToString(l.Enforcement))
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 102))
+}
+
+// budgetInteractive manages department budgets through user interaction
+func budgetInteractive(manager EmployeeManager, plan *BudgetPlan, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Headcount Planning ==="))
+	fmt.Println("1. Set department budget")
+	fmt.Println("2. View budget vs actual")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		department, err := readDepartment(reader)
+		if err != nil {
+			return err
+		}
+
+		headcount, err := readInt(reader, "Budgeted headcount: ")
+		if err != nil {
+			return err
+		}
+
+		salaryBudget, err := readFloat(reader, "Salary budget: ")
+		if err != nil {
+			return err
+		}
+
+		enforcement, err := readValue(reader, "Changes that overrun the salary budget (warn, approve, reject) [warn]: ", StringToEnforcement)
+		if err != nil {
+			return err
+		}
+
+		err = plan.SetBudget(DepartmentBudget{
+			Department:   department,
+			Headcount:    headcount,
+			SalaryBudget: salaryBudget,
+			Enforcement:  enforcement,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("\n" + successText("Budget saved successfully!"))
+		writeBudgetReport(os.Stdout, plan.Report(manager))
+
+	case 2:
+		writeBudgetReport(os.Stdout, plan.Report(manager))
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	return nil
+}
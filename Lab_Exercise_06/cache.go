@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// cacheListKey is the cache key of the full employee list
+const cacheListKey = "employees:all"
+
+// cacheEmployeeKey returns the cache key of a single employee
+func cacheEmployeeKey(id int) string {
+	return "employees:" + strconv.Itoa(id)
+}
+
+// CachingManager decorates an EmployeeManager, serving GetEmployee and
+// ListEmployees from Redis. Entries expire after the TTL and are invalidated
+// by writes made through the decorator. Cache failures fall back to the
+// wrapped manager, so Redis being down only costs performance.
+type CachingManager struct {
+	inner  EmployeeManager
+	client *redisClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewCachingManager parses a redis://host:port URL and wraps inner with a cache.
+// The ttl and prefix query parameters set the entry lifetime (default 1m) and
+// a key prefix for sharing a Redis server between deployments.
+func NewCachingManager(inner EmployeeManager, rawURL string) (*CachingManager, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("%w: cache must be a URL such as redis://localhost:6379", ErrInvalidInput)
+	}
+
+	ttl := time.Minute
+	if value := u.Query().Get("ttl"); value != "" {
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("%w: cache ttl must be a positive duration such as 30s", ErrInvalidInput)
+		}
+	}
+
+	return &CachingManager{
+		inner:  inner,
+		client: newRedisClient(u.Host, 2*time.Second),
+		prefix: u.Query().Get("prefix"),
+		ttl:    ttl,
+	}, nil
+}
+
+// Unwrap returns the wrapped manager
+func (c *CachingManager) Unwrap() EmployeeManager {
+	return c.inner
+}
+
+// Close closes the Redis connection
+func (c *CachingManager) Close() error {
+	return c.client.Close()
+}
+
+// PingCache checks that Redis can be reached. The cache is optional, so a
+// failure only means reads are slower.
+func (c *CachingManager) PingCache() error {
+	_, err := c.client.Do("PING")
+	return err
+}
+
+// load reads a cached value into v, reporting whether it was found
+func (c *CachingManager) load(key string, v interface{}) bool {
+	data, err := c.client.Get(c.prefix + key)
+	if err != nil {
+		if err != errRedisNil {
+			log.Printf("cache: %v", err)
+		}
+		return false
+	}
+	return json.Unmarshal([]byte(data), v) == nil
+}
+
+// store caches a value
+func (c *CachingManager) store(key string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := c.client.Set(c.prefix+key, string(data), c.ttl); err != nil {
+		log.Printf("cache: %v", err)
+	}
+}
+
+// invalidate removes the cached list and the given employees
+func (c *CachingManager) invalidate(ids ...int) {
+	keys := []string{c.prefix + cacheListKey}
+	for _, id := range ids {
+		keys = append(keys, c.prefix+cacheEmployeeKey(id))
+	}
+	if err := c.client.Del(keys...); err != nil {
+		log.Printf("cache: %v", err)
+	}
+}
+
+// AddEmployee adds an employee and invalidates the cached list
+func (c *CachingManager) AddEmployee(e *Employee) error {
+	if err := c.inner.AddEmployee(e); err != nil {
+		return err
+	}
+	c.invalidate(e.ID)
+	return nil
+}
+
+// RemoveEmployee removes an employee and invalidates its cache entries
+func (c *CachingManager) RemoveEmployee(id int) error {
+	if err := c.inner.RemoveEmployee(id); err != nil {
+		return err
+	}
+	c.invalidate(id)
+	return nil
+}
+
+// UpdateEmployee updates an employee and invalidates its cache entries
+func (c *CachingManager) UpdateEmployee(e *Employee) error {
+	if err := c.inner.UpdateEmployee(e); err != nil {
+		return err
+	}
+	c.invalidate(e.ID)
+	return nil
+}
+
+// TransferEmployee transfers an employee and invalidates its cache entries
+func (c *CachingManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if err := c.inner.TransferEmployee(id, newDept, effectiveDate); err != nil {
+		return err
+	}
+	c.invalidate(id)
+	return nil
+}
+
+// GetEmployee retrieves an employee from the cache, or from the wrapped manager on a miss
+func (c *CachingManager) GetEmployee(id int) (*Employee, error) {
+	var cached Employee
+	if c.load(cacheEmployeeKey(id), &cached) {
+		return &cached, nil
+	}
+
+	employee, err := c.inner.GetEmployee(id)
+	if err != nil {
+		return nil, err
+	}
+	c.store(cacheEmployeeKey(id), employee)
+	return employee, nil
+}
+
+// ListEmployees lists employees from the cache, or from the wrapped manager on a miss
+func (c *CachingManager) ListEmployees() ([]*Employee, error) {
+	var cached []*Employee
+	if c.load(cacheListKey, &cached) {
+		return cached, nil
+	}
+
+	employees, err := c.inner.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	c.store(cacheListKey, employees)
+	return employees, nil
+}
+
+// FilterEmployees filters the employee list, which is served from the cache when possible
+func (c *CachingManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	employees, err := c.ListEmployees()
+	if err != nil {
+		return c.inner.FilterEmployees(filter)
+	}
+
+	result := make([]*Employee, 0)
+	for _, emp := range employees {
+		if filter(emp) {
+			result = append(result, emp)
+		}
+	}
+	return result
+}
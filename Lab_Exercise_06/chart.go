@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// asciiCharts draws charts with plain ASCII instead of Unicode block characters,
+// for terminals without Unicode fonts. main sets it from the -ascii flag.
+var asciiCharts = false
+
+// chartWidth is the number of columns used by the longest bar
+const chartWidth = 40
+
+// partialBlocks are the Unicode characters for 1/8 to 7/8 of a cell
+var partialBlocks = []rune("▏▎▍▌▋▊▉")
+
+// bar renders a bar of the given length in cells, which may be fractional
+func bar(length float64) string {
+	if asciiCharts {
+		return strings.Repeat("#", int(math.Round(length)))
+	}
+	full := int(length)
+	eighths := int(math.Round((length - float64(full)) * 8))
+	if eighths == 8 {
+		full++
+		eighths = 0
+	}
+	s := strings.Repeat("█", full)
+	if eighths > 0 {
+		s += string(partialBlocks[eighths-1])
+	}
+	return s
+}
+
+// writeBarChart writes a horizontal bar chart with one labelled bar per value,
+// scaled so the largest value fills chartWidth columns
+func writeBarChart(w io.Writer, labels []string, values []float64, format func(float64) string) {
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = max(labelWidth, len(label))
+	}
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+
+	for i, v := range values {
+		length := 0.0
+		if maxValue > 0 {
+			length = v / maxValue * chartWidth
+		}
+		fmt.Fprintf(w, "%-*s | %s %s\n", labelWidth, labels[i], bar(length), format(v))
+	}
+}
+
+// Histogram counts values in equal-width bins between Min and Max
+type Histogram struct {
+	Min    float64
+	Width  float64
+	Counts []int
+}
+
+// NewHistogram bins values into the given number of equal-width bins
+func NewHistogram(values []float64, bins int) Histogram {
+	if len(values) == 0 || bins < 1 {
+		return Histogram{}
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+
+	h := Histogram{Min: lo, Width: (hi - lo) / float64(bins), Counts: make([]int, bins)}
+	if h.Width == 0 {
+		// Every value is the same, so they all go in one bin
+		h.Counts = h.Counts[:1]
+		h.Counts[0] = len(values)
+		return h
+	}
+	for _, v := range values {
+		i := min(int((v-lo)/h.Width), bins-1)
+		h.Counts[i]++
+	}
+	return h
+}
+
+// writeHistogram writes a histogram as a bar chart with one bar per bin
+func writeHistogram(w io.Writer, h Histogram, format func(float64) string) {
+	labels := make([]string, len(h.Counts))
+	values := make([]float64, len(h.Counts))
+	for i, n := range h.Counts {
+		lo := h.Min + float64(i)*h.Width
+		labels[i] = fmt.Sprintf("%s - %s", format(lo), format(lo+h.Width))
+		values[i] = float64(n)
+	}
+	writeBarChart(w, labels, values, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time, so time-dependent logic can be tested
+// deterministically and simulated forward in demos
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock that reads the system time
+type SystemClock struct{}
+
+// Now returns the current system time in UTC
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// FakeClock is a Clock that only moves when told to
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock set to the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now.UTC()}
+}
+
+// Now returns the clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to the given time
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now.UTC()
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// AdvanceDays moves the clock forward by a number of calendar days
+func (c *FakeClock) AdvanceDays(days int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.AddDate(0, 0, days)
+}
+
+// appClock is the clock used where no manager is involved, such as defaulting
+// dates entered by the user. main replaces it when simulating time.
+var appClock Clock = SystemClock{}
+
+// clockOf returns the clock of a manager, falling back to appClock
+func clockOf(manager EmployeeManager) Clock {
+	if c, ok := capability[interface{ Clock() Clock }](manager); ok {
+		return c.Clock()
+	}
+	return appClock
+}
+
+// advanceClockInteractive moves the simulated clock forward through user interaction
+func advanceClockInteractive(clock *FakeClock, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Advance Simulated Clock ==="))
+
+	if clock == nil {
+		return fmt.Errorf("%w: clock simulation is off, start the program with -now YYYY-MM-DD", ErrInvalidInput)
+	}
+
+	fmt.Printf("Simulated date: %s\n", formatDate(clock.Now()))
+
+	days, err := readInt(reader, "Days to advance: ")
+	if err != nil {
+		return err
+	}
+	if days <= 0 {
+		return fmt.Errorf("%w: please enter a positive number of days", ErrInvalidInput)
+	}
+
+	clock.AdvanceDays(days)
+	fmt.Printf("\nSimulated date is now %s\n", formatDate(clock.Now()))
+	return nil
+}
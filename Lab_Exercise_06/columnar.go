@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ColumnarEmployeeManager implements EmployeeManager with one slice per field
+// instead of one heap object per employee. Rows are kept dense (removal moves
+// the last row into the gap), so List and Filter scan contiguous memory and
+// return all their results in a single allocation. It keeps no change log,
+// which makes it suited to very large datasets that do not need history.
+type ColumnarEmployeeManager struct {
+	mu     sync.RWMutex
+	rows   map[int]int // employee ID -> row
+	nextID int
+	events EventBus
+	clock  Clock
+
+	ids           []int
+	names         []string
+	positions     []string
+	salaries      []float64
+	departments   []Department
+	joinDates     []time.Time
+	birthDates    []time.Time
+	probationEnds []time.Time
+	locations     []string
+	workModes     []int
+	timeZones     []string
+	versions      []int
+
+	transfers map[int][]TransferRecord
+}
+
+// NewColumnarEmployeeManager creates a columnar manager with room for capacity
+// employees before any column has to grow
+func NewColumnarEmployeeManager(capacity int, clock Clock) *ColumnarEmployeeManager {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &ColumnarEmployeeManager{
+		rows:          make(map[int]int, capacity),
+		nextID:        1,
+		clock:         clock,
+		ids:           make([]int, 0, capacity),
+		names:         make([]string, 0, capacity),
+		positions:     make([]string, 0, capacity),
+		salaries:      make([]float64, 0, capacity),
+		departments:   make([]Department, 0, capacity),
+		joinDates:     make([]time.Time, 0, capacity),
+		birthDates:    make([]time.Time, 0, capacity),
+		probationEnds: make([]time.Time, 0, capacity),
+		locations:     make([]string, 0, capacity),
+		workModes:     make([]int, 0, capacity),
+		timeZones:     make([]string, 0, capacity),
+		versions:      make([]int, 0, capacity),
+		transfers:     make(map[int][]TransferRecord),
+	}
+}
+
+// row copies a row into e. The caller must hold the lock.
+func (m *ColumnarEmployeeManager) row(i int, e *Employee) {
+	*e = Employee{
+		ID:           m.ids[i],
+		Name:         m.names[i],
+		Position:     m.positions[i],
+		Salary:       m.salaries[i],
+		Department:   m.departments[i],
+		JoinDate:     m.joinDates[i],
+		BirthDate:    m.birthDates[i],
+		ProbationEnd: m.probationEnds[i],
+		Location:     m.locations[i],
+		WorkMode:     m.workModes[i],
+		TimeZone:     m.timeZones[i],
+		Version:      m.versions[i],
+	}
+}
+
+// setRow writes e into an existing row. The caller must hold the write lock.
+func (m *ColumnarEmployeeManager) setRow(i int, e *Employee) {
+	m.ids[i] = e.ID
+	m.names[i] = e.Name
+	m.positions[i] = e.Position
+	m.salaries[i] = e.Salary
+	m.departments[i] = e.Department
+	m.joinDates[i] = e.JoinDate
+	m.birthDates[i] = e.BirthDate
+	m.probationEnds[i] = e.ProbationEnd
+	m.locations[i] = e.Location
+	m.workModes[i] = e.WorkMode
+	m.timeZones[i] = e.TimeZone
+	m.versions[i] = e.Version
+}
+
+// Clock returns the clock the manager uses for validation and events
+func (m *ColumnarEmployeeManager) Clock() Clock {
+	return m.clock
+}
+
+// Subscribe registers a function that is notified of manager events
+func (m *ColumnarEmployeeManager) Subscribe(fn func(Event)) {
+	m.events.Subscribe(fn)
+}
+
+// EventBacklog returns the number of the manager's events still being delivered
+func (m *ColumnarEmployeeManager) EventBacklog() int {
+	return m.events.Backlog()
+}
+
+// Close releases the manager's resources. The columnar manager holds none.
+func (m *ColumnarEmployeeManager) Close() error {
+	return nil
+}
+
+// publish notifies subscribers of a change
+func (m *ColumnarEmployeeManager) publish(c Change) {
+	c.Time = m.clock.Now()
+	m.events.Publish(c.notification())
+}
+
+// AddEmployee adds a new employee
+func (m *ColumnarEmployeeManager) AddEmployee(e *Employee) error {
+	if e == nil {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if e.ID == 0 {
+		// Auto-assign ID if not provided, skipping IDs that were set explicitly
+		for {
+			if _, exists := m.rows[m.nextID]; !exists {
+				break
+			}
+			m.nextID++
+		}
+		e.ID = m.nextID
+		m.nextID++
+	} else if _, exists := m.rows[e.ID]; exists {
+		m.mu.Unlock()
+		return ErrDuplicateID
+	}
+
+	e.Version = 1
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+	m.rows[e.ID] = len(m.ids)
+	m.ids = append(m.ids, 0)
+	m.names = append(m.names, "")
+	m.positions = append(m.positions, "")
+	m.salaries = append(m.salaries, 0)
+	m.departments = append(m.departments, 0)
+	m.joinDates = append(m.joinDates, time.Time{})
+	m.birthDates = append(m.birthDates, time.Time{})
+	m.probationEnds = append(m.probationEnds, time.Time{})
+	m.locations = append(m.locations, "")
+	m.workModes = append(m.workModes, 0)
+	m.timeZones = append(m.timeZones, "")
+	m.versions = append(m.versions, 0)
+	m.setRow(m.rows[e.ID], &employeeCopy)
+	m.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeAdded, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// RemoveEmployee removes an employee by ID, moving the last row into its place
+func (m *ColumnarEmployeeManager) RemoveEmployee(id int) error {
+	m.mu.Lock()
+	i, exists := m.rows[id]
+	if !exists {
+		m.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+
+	var removed, last Employee
+	m.row(i, &removed)
+	n := len(m.ids) - 1
+	if i != n {
+		m.row(n, &last)
+		m.setRow(i, &last)
+		m.rows[last.ID] = i
+	}
+	m.ids = m.ids[:n]
+	m.names = m.names[:n]
+	m.positions = m.positions[:n]
+	m.salaries = m.salaries[:n]
+	m.departments = m.departments[:n]
+	m.joinDates = m.joinDates[:n]
+	m.birthDates = m.birthDates[:n]
+	m.probationEnds = m.probationEnds[:n]
+	m.locations = m.locations[:n]
+	m.workModes = m.workModes[:n]
+	m.timeZones = m.timeZones[:n]
+	m.versions = m.versions[:n]
+	delete(m.rows, id)
+	delete(m.transfers, id)
+	m.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeRemoved, EmployeeID: id, Employee: removed})
+	return nil
+}
+
+// UpdateEmployee updates an existing employee
+func (m *ColumnarEmployeeManager) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	i, exists := m.rows[e.ID]
+	if !exists {
+		m.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+	e.Version = m.versions[i] + 1
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+	m.setRow(i, &employeeCopy)
+	m.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeUpdated, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// GetEmployee retrieves an employee by ID
+func (m *ColumnarEmployeeManager) GetEmployee(id int) (*Employee, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i, exists := m.rows[id]
+	if !exists {
+		return nil, ErrEmployeeNotFound
+	}
+	var e Employee
+	m.row(i, &e)
+	return &e, nil
+}
+
+// ListEmployees returns a list of all employees. The results share one backing array.
+func (m *ColumnarEmployeeManager) ListEmployees() ([]*Employee, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := make([]Employee, len(m.ids))
+	employees := make([]*Employee, len(m.ids))
+	for i := range m.ids {
+		m.row(i, &values[i])
+		employees[i] = &values[i]
+	}
+	return employees, nil
+}
+
+// FilterEmployees returns employees that match the filter criteria. Each row is
+// tested in a scratch value and only matches are kept.
+func (m *ColumnarEmployeeManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := make([]int, 0)
+	var scratch Employee
+	for i := range m.ids {
+		m.row(i, &scratch)
+		if filter(&scratch) {
+			matches = append(matches, i)
+		}
+	}
+
+	values := make([]Employee, len(matches))
+	result := make([]*Employee, len(matches))
+	for j, i := range matches {
+		m.row(i, &values[j])
+		result[j] = &values[j]
+	}
+	return result
+}
+
+// ListByDepartment returns the employees in a department by scanning the department column
+func (m *ColumnarEmployeeManager) ListByDepartment(dept Department) []*Employee {
+	return m.FilterEmployees(func(e *Employee) bool { return e.Department == dept })
+}
+
+// RebuildIndexes rebuilds the map from employee IDs to rows from the ID column
+func (m *ColumnarEmployeeManager) RebuildIndexes() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rows = make(map[int]int, len(m.ids))
+	for i, id := range m.ids {
+		m.rows[id] = i
+	}
+	return nil
+}
+
+// TransferEmployee moves an employee to another department and records the transfer
+func (m *ColumnarEmployeeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+
+	m.mu.Lock()
+	i, exists := m.rows[id]
+	if !exists {
+		m.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+	if m.departments[i] == newDept {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+	}
+
+	record := TransferRecord{
+		EmployeeID:     id,
+		FromDepartment: m.departments[i],
+		ToDepartment:   newDept,
+		EffectiveDate:  effectiveDate.UTC(),
+		RecordedAt:     m.clock.Now(),
+	}
+	m.departments[i] = newDept
+	m.versions[i]++
+	m.transfers[id] = append(m.transfers[id], record)
+	var transferred Employee
+	m.row(i, &transferred)
+	m.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeTransferred, EmployeeID: id, Employee: transferred, Transfer: &record})
+	return nil
+}
+
+// TransferHistory returns the transfers recorded for an employee, oldest first
+func (m *ColumnarEmployeeManager) TransferHistory(id int) []TransferRecord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]TransferRecord, len(m.transfers[id]))
+	copy(history, m.transfers[id])
+	return history
+}
+
+func init() {
+	// The DSN is an optional capacity hint, e.g. -storage columnar -dsn 100000
+	RegisterStorage("columnar", func(dsn string, clock Clock) (Storage, error) {
+		capacity := 0
+		if dsn != "" {
+			n, err := strconv.Atoi(dsn)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w: the columnar backend's -dsn is a capacity hint such as 100000", ErrInvalidInput)
+			}
+			capacity = n
+		}
+		return NewColumnarEmployeeManager(capacity, clock), nil
+	})
+}
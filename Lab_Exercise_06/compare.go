@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCompared is the most employees a comparison lays out side by side
+const maxCompared = 8
+
+// Comparison is one employee's figures in a side-by-side comparison, as used
+// in calibration and promotion discussions
+type Comparison struct {
+	Employee   *Employee
+	Experience float64 // years of service on the day of the comparison
+	// SalaryHistory is the salary after each recorded change that altered
+	// it, oldest first, or nil if the store keeps no change history
+	SalaryHistory []float64
+	Band          PositionBand
+}
+
+// PositionBand is the spread of the salaries of everyone holding a position
+type PositionBand struct {
+	Count  int
+	Min    float64
+	Median float64
+	Max    float64
+}
+
+// CompaRatio returns the employee's salary as a fraction of the median of
+// their position, 1 meaning exactly the median
+func (c Comparison) CompaRatio() float64 {
+	if c.Band.Median == 0 {
+		return 0
+	}
+	return c.Employee.Salary / c.Band.Median
+}
+
+// SalaryTrend describes how the employee's salary has moved over its
+// recorded history
+func (c Comparison) SalaryTrend() string {
+	history := c.SalaryHistory
+	switch {
+	case history == nil:
+		return "not recorded"
+	case len(history) < 2:
+		return "no changes"
+	}
+	first, last := history[0], history[len(history)-1]
+	steps := make([]string, 0, 4)
+	if len(history) > 4 {
+		steps = append(steps, "…")
+		history = history[len(history)-3:]
+	}
+	for _, s := range history {
+		steps = append(steps, fmt.Sprintf("%.0f", s))
+	}
+	return fmt.Sprintf("%s (%+.1f%%)", strings.Join(steps, " → "), (last-first)/first*100)
+}
+
+// CompareEmployees gathers the figures of the employees with the given IDs,
+// in the order given
+func CompareEmployees(manager EmployeeManager, ids []int, now time.Time) ([]Comparison, error) {
+	if len(ids) < 2 || len(ids) > maxCompared {
+		return nil, fmt.Errorf("%w: compare between 2 and %d employees", ErrInvalidInput, maxCompared)
+	}
+	for i, id := range ids {
+		if slices.Contains(ids[:i], id) {
+			return nil, fmt.Errorf("%w: employee %d is listed twice", ErrInvalidInput, id)
+		}
+	}
+
+	all, err := manager.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string][]float64)
+	for _, e := range all {
+		key := strings.ToLower(e.Position)
+		positions[key] = append(positions[key], e.Salary)
+	}
+	history, hasHistory := capability[interface{ History(int) []Change }](manager)
+
+	comparisons := make([]Comparison, 0, len(ids))
+	for _, id := range ids {
+		e, err := manager.GetEmployee(id)
+		if err != nil {
+			return nil, fmt.Errorf("employee %d: %w", id, err)
+		}
+		band := Summarize(positions[strings.ToLower(e.Position)])
+		c := Comparison{
+			Employee:   e,
+			Experience: e.ExperienceAt(now),
+			Band:       PositionBand{Count: band.Count, Min: band.Min, Median: band.P50, Max: band.Max},
+		}
+		if hasHistory {
+			c.SalaryHistory = make([]float64, 0)
+			for _, change := range history.History(id) {
+				s := change.Employee.Salary
+				if change.Type != EventEmployeeRemoved && (len(c.SalaryHistory) == 0 || c.SalaryHistory[len(c.SalaryHistory)-1] != s) {
+					c.SalaryHistory = append(c.SalaryHistory, s)
+				}
+			}
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons, nil
+}
+
+// parseCompareIDs reads the IDs of the employees to compare, separated by
+// spaces or commas
+func parseCompareIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	for _, arg := range args {
+		for _, field := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == ' ' }) {
+			id, err := strconv.Atoi(field)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("%w: %q is not an employee ID", ErrInvalidInput, field)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// writeComparison writes the employees side by side, one column each, with
+// their computed fields at the bottom
+func writeComparison(w io.Writer, comparisons []Comparison, now time.Time, markdown bool) {
+	columns := []Column{{Header: ""}}
+	for _, c := range comparisons {
+		columns = append(columns, Column{Header: fmt.Sprintf("#%d", c.Employee.ID)})
+	}
+
+	var rows [][]string
+	row := func(label string, value func(c Comparison) string) {
+		cells := []string{label}
+		for _, c := range comparisons {
+			cells = append(cells, value(c))
+		}
+		rows = append(rows, cells)
+	}
+	row("Name", func(c Comparison) string { return c.Employee.Name })
+	row("Position", func(c Comparison) string { return c.Employee.Position })
+	row("Department", func(c Comparison) string { return c.Employee.Department.String() })
+	row("Salary", func(c Comparison) string { return fmt.Sprintf("%.2f", c.Employee.Salary) })
+	row("Joined", func(c Comparison) string { return formatDate(c.Employee.JoinDate) })
+	row("Tenure", func(c Comparison) string { return c.Employee.TenureAt(now).String() })
+	row("Salary Trend", Comparison.SalaryTrend)
+	row("Position Band", func(c Comparison) string {
+		return fmt.Sprintf("%.0f–%.0f (%d)", c.Band.Min, c.Band.Max, c.Band.Count)
+	})
+	row("Band Median", func(c Comparison) string { return fmt.Sprintf("%.2f", c.Band.Median) })
+	row("Compa-Ratio", func(c Comparison) string { return fmt.Sprintf("%.2f", c.CompaRatio()) })
+	for _, f := range computedFields {
+		row(f.Name, func(c Comparison) string {
+			v, err := f.Value(c.Employee, now)
+			if err != nil {
+				return ""
+			}
+			return formatComputedValue(v)
+		})
+	}
+	writeTable(w, columns, rows, markdown)
+}
+
+// compareInteractive lays out employees chosen by ID side by side
+func compareInteractive(manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Compare Employees ==="))
+	input, err := readString(reader, fmt.Sprintf("Employee IDs to compare (2 to %d, separated by spaces): ", maxCompared))
+	if err != nil {
+		return err
+	}
+	ids, err := parseCompareIDs([]string{input})
+	if err != nil {
+		return err
+	}
+	now := clockOf(manager).Now()
+	comparisons, err := CompareEmployees(manager, ids, now)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	writeComparison(os.Stdout, comparisons, now, listFormat == FormatMarkdown)
+	return nil
+}
+
+// comparisonJSON is the JSON representation of an employee in a comparison
+type comparisonJSON struct {
+	Employee        employeeJSON     `json:"employee"`
+	ExperienceYears float64          `json:"experience_years"`
+	SalaryHistory   []float64        `json:"salary_history,omitempty"`
+	PositionBand    positionBandJSON `json:"position_band"`
+	CompaRatio      float64          `json:"compa_ratio"`
+}
+
+// positionBandJSON is the JSON representation of a position band
+type positionBandJSON struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	Max    float64 `json:"max"`
+}
+
+// handleCompare lays out the employees named in the ids parameter, such as
+// ids=3,7,12, side by side
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseCompareIDs(r.URL.Query()["ids"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	manager := s.managerFor(r)
+	comparisons, err := CompareEmployees(manager, ids, clockOf(manager).Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	out := make([]comparisonJSON, len(comparisons))
+	// The band and salary history depend on other employees and past changes
+	// as well, so they are part of the tag
+	key := make([]interface{}, 0, len(comparisons))
+	for i, c := range comparisons {
+		key = append(key, c.Employee.ID, c.Employee.Version, c.SalaryHistory, c.Band)
+		out[i] = comparisonJSON{
+			Employee:        toEmployeeJSON(c.Employee),
+			ExperienceYears: c.Experience,
+			SalaryHistory:   c.SalaryHistory,
+			PositionBand:    positionBandJSON{c.Band.Count, c.Band.Min, c.Band.Median, c.Band.Max},
+			CompaRatio:      c.CompaRatio(),
+		}
+	}
+	writeCacheableJSON(w, r, out, etagOf(key...))
+}
+
+// runCompare implements the compare command, which lays out the employees
+// whose IDs follow the flags side by side
+func runCompare(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to read employees from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	formatName := fs.String("format", "table", "layout of the comparison (table, markdown)")
+	fieldsPath := fs.String("fields", "", "JSON file of computed fields to include")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: compare [flags] <id> <id> [<id> ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	format, err := StringToFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	ids, err := parseCompareIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *fieldsPath != "" {
+		if computedFields, err = LoadComputedFields(*fieldsPath); err != nil {
+			return err
+		}
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	now := appClock.Now()
+	comparisons, err := CompareEmployees(store, ids, now)
+	if err != nil {
+		return err
+	}
+	writeComparison(stdout, comparisons, now, format == FormatMarkdown)
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComputedField is a field an administrator defines with an expression over
+// the employee's stored fields, such as bonus = salary * 0.1. It is worked
+// out whenever employees are read, so it can be searched, shown as a column,
+// summarized in the reports and exported like a stored field. Expressions
+// are written as in a Rule, without old fields or aggregates, and can use
+// the computed fields defined before them.
+type ComputedField struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+
+	expr ruleExpr
+}
+
+// computedFields are the deployment's computed fields. main sets them from the -fields flag.
+var computedFields []*ComputedField
+
+// computedFieldsFile is the JSON a computed fields file holds
+type computedFieldsFile struct {
+	Fields []*ComputedField `json:"fields"`
+}
+
+// LoadComputedFields reads and compiles a computed fields file
+func LoadComputedFields(path string) ([]*ComputedField, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := ParseComputedFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fields, nil
+}
+
+// ParseComputedFields reads and compiles computed fields in JSON
+func ParseComputedFields(data []byte) ([]*ComputedField, error) {
+	var file computedFieldsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	for i, f := range file.Fields {
+		if err := checkComputedName(f.Name, file.Fields[:i]); err != nil {
+			return nil, err
+		}
+		var err error
+		if f.expr, err = compileRuleExpr(f.Expression, ruleNames{computed: file.Fields[:i]}); err != nil {
+			return nil, fmt.Errorf("%w: computed field %q: %v", ErrInvalidInput, f.Name, err)
+		}
+	}
+	return file.Fields, nil
+}
+
+// checkComputedName checks that a computed field's name can be used in
+// searches, columns and expressions without being taken for anything else
+func checkComputedName(name string, defined []*ComputedField) error {
+	if name == "" || strings.IndexFunc(name, func(r rune) bool { return (r < 'a' || r > 'z') && r != '_' }) >= 0 {
+		return fmt.Errorf("%w: computed field name %q must be lowercase letters and underscores", ErrInvalidInput, name)
+	}
+	_, function := ruleFunctions[name]
+	reserved := slices.Contains(ruleFields, name) || slices.Contains(queryFields, name) || function
+	for _, c := range tableColumns {
+		reserved = reserved || c.Name == name
+	}
+	switch name {
+	case "old", "department", "company", "true", "false", "and", "or", "not":
+		reserved = true
+	}
+	if reserved {
+		return fmt.Errorf("%w: computed field name %q is already taken", ErrInvalidInput, name)
+	}
+	if findComputedField(defined, name) != nil {
+		return fmt.Errorf("%w: computed field %q is defined twice", ErrInvalidInput, name)
+	}
+	return nil
+}
+
+// findComputedField returns the computed field with a name, or nil
+func findComputedField(fields []*ComputedField, name string) *ComputedField {
+	for _, f := range fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Value works out the field for an employee
+func (f *ComputedField) Value(e *Employee, now time.Time) (interface{}, error) {
+	return f.expr(&ruleScope{employee: e, now: now})
+}
+
+// computedValues works out every computed field for an employee, with
+// numbers rounded to the cent, leaving out those that cannot be worked out
+// for it, such as a division by a zero field. It returns nil if there are none.
+func computedValues(e *Employee, now time.Time) map[string]interface{} {
+	var values map[string]interface{}
+	for _, f := range computedFields {
+		v, err := f.Value(e, now)
+		if err != nil {
+			continue
+		}
+		if n, ok := v.(float64); ok {
+			v = roundCents(n)
+		}
+		if values == nil {
+			values = make(map[string]interface{}, len(computedFields))
+		}
+		values[f.Name] = v
+	}
+	return values
+}
+
+// formatComputedValue formats a computed value for display; numbers are
+// shown to the cent, like salaries
+func formatComputedValue(v interface{}) string {
+	if n, ok := v.(float64); ok {
+		return fmt.Sprintf("%.2f", n)
+	}
+	return fmt.Sprint(v)
+}
+
+// computedColumn is the employee table column of a computed field, empty
+// for employees it cannot be worked out for
+func computedColumn(f *ComputedField) Column {
+	return Column{f.Name, f.Name, true, func(e *Employee) string {
+		v, err := f.Value(e, appClock.Now())
+		if err != nil {
+			return ""
+		}
+		return formatComputedValue(v)
+	}}
+}
+
+// writeComputedStats writes the distribution of each numeric computed field by department
+func writeComputedStats(w io.Writer, fields []*ComputedField, employees []Employee, now time.Time) {
+	for _, f := range fields {
+		byDept := make(map[string][]float64)
+		var all []float64
+		for i := range employees {
+			v, err := f.Value(&employees[i], now)
+			if n, ok := v.(float64); err == nil && ok {
+				name := employees[i].Department.String()
+				byDept[name] = append(byDept[name], n)
+				all = append(all, n)
+			}
+		}
+
+		fmt.Fprintf(w, "\n=== %s by Department ===\n", f.Name)
+		if len(all) == 0 {
+			fmt.Fprintf(w, "%s = %s is not a number for any employee.\n", f.Name, f.Expression)
+			continue
+		}
+		names := make([]string, 0, len(byDept))
+		for name := range byDept {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(w, "%-24s %5s %13s %11s %11s %11s %11s\n",
+			"", "Count", "Total", "Mean", "Median", "Min", "Max")
+		row := func(name string, s Summary) {
+			fmt.Fprintf(w, "%-24s %5d %13.2f %11.2f %11.2f %11.2f %11.2f\n",
+				name, s.Count, s.Sum, s.Mean, s.P50, s.Min, s.Max)
+		}
+		for _, name := range names {
+			row(name, Summarize(byDept[name]))
+		}
+		row("All", Summarize(all))
+	}
+}
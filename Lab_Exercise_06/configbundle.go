@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// configBundleVersion is the layout of the bundles config export writes.
+// config import refuses bundles written by a later version.
+const configBundleVersion = 1
+
+// ConfigBundle is the configuration of a deployment in one YAML document, so
+// it can be kept in version control and copied from one deployment to
+// another. Sections whose file was not given are left out, and the
+// deployment keeps its built-in defaults for them.
+type ConfigBundle struct {
+	Version        int                `json:"version"`
+	Departments    []Department       `json:"departments"` // those in use, of the built-in ones
+	Positions      []PositionTemplate `json:"positions"`
+	Roles          []RoleGrant        `json:"roles"`
+	ComputedFields json.RawMessage    `json:"computed_fields,omitempty"`
+	Rules          json.RawMessage    `json:"rules,omitempty"`
+	Payroll        json.RawMessage    `json:"payroll,omitempty"`
+	CostCenters    json.RawMessage    `json:"cost_centers,omitempty"`
+	Entities       json.RawMessage    `json:"entities,omitempty"`
+	Holidays       json.RawMessage    `json:"holidays,omitempty"`
+	LeavePolicies  json.RawMessage    `json:"leave_policies,omitempty"`
+	DigestTemplate string             `json:"digest_template,omitempty"`
+	SavedSearches  *SavedSearches     `json:"saved_searches,omitempty"`
+	Storage        *StorageSettings   `json:"storage,omitempty"`
+	Admin          string             `json:"admin,omitempty"` // the user who runs as admin when no -role is given
+}
+
+// StorageSettings are the storage backend a deployment keeps employees in
+type StorageSettings struct {
+	Backend string `json:"backend"`
+	DSN     string `json:"dsn,omitempty"`
+}
+
+// SavedSearches are the searches a user has kept in their preferences
+type SavedSearches struct {
+	LastSearch string  `json:"last_search,omitempty"`
+	Watches    []Watch `json:"watches,omitempty"`
+}
+
+// configSection is a section of the bundle kept in a JSON file of its own
+type configSection struct {
+	key   string // in the bundle
+	flag  string // the flag that names its file
+	file  string // the file config import writes it to
+	field func(*ConfigBundle) *json.RawMessage
+	load  func([]byte) error // checks the section and makes it current; rules are only checked
+}
+
+// configSections lists the bundle's JSON sections in the order they are
+// checked: computed fields before the rules that use them, and payroll
+// before the entities that override it
+var configSections = []configSection{
+	{"computed_fields", "fields", "fields.json", func(b *ConfigBundle) *json.RawMessage { return &b.ComputedFields },
+		func(data []byte) (err error) { computedFields, err = ParseComputedFields(data); return err }},
+	{"rules", "rules", "rules.json", func(b *ConfigBundle) *json.RawMessage { return &b.Rules },
+		func(data []byte) (err error) { _, err = ParseRules(data); return err }},
+	{"payroll", "payroll", "payroll.json", func(b *ConfigBundle) *json.RawMessage { return &b.Payroll },
+		func(data []byte) (err error) { payrollConfig, err = ParsePayrollConfig(data); return err }},
+	{"cost_centers", "cost-centers", "cost-centers.json", func(b *ConfigBundle) *json.RawMessage { return &b.CostCenters },
+		func(data []byte) (err error) { costCenters, err = ParseCostCenters(data); return err }},
+	{"entities", "entities", "entities.json", func(b *ConfigBundle) *json.RawMessage { return &b.Entities },
+		func(data []byte) (err error) { legalEntities, err = ParseEntities(data); return err }},
+	{"holidays", "holidays", "holidays.json", func(b *ConfigBundle) *json.RawMessage { return &b.Holidays },
+		func(data []byte) (err error) { holidayCalendar, err = ParseHolidayCalendar(data); return err }},
+	{"leave_policies", "leave-policies", "leave-policies.json", func(b *ConfigBundle) *json.RawMessage { return &b.LeavePolicies },
+		func(data []byte) (err error) { leavePolicies, err = ParseLeavePolicies(data); return err }},
+}
+
+// Files config import writes the sections that are not in configSections to
+const (
+	positionsFile      = "positions.json"
+	rolesFile          = "roles.json"
+	digestTemplateFile = "digest.tmpl"
+)
+
+// ExportConfig gathers the configuration in effect into a bundle, starting
+// from the system configuration, if any. paths holds the files of the JSON
+// sections by flag name, which replace the system configuration's, and
+// prefs the preferences to take saved searches from, if any.
+func ExportConfig(system *ConfigBundle, paths map[string]string, digestPath string, prefs *Preferences) (*ConfigBundle, error) {
+	b := &ConfigBundle{}
+	if system != nil {
+		*b = *system
+	}
+	b.Version = configBundleVersion
+	if b.Departments == nil {
+		b.Departments = AllDepartments()
+	}
+	b.Positions = positionTemplates
+	b.Roles = RoleGrants()
+	for _, s := range configSections {
+		path := paths[s.flag]
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.load(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		*s.field(b) = json.RawMessage(data)
+	}
+	if digestPath != "" {
+		data, err := os.ReadFile(digestPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := ParseDigestTemplate(string(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", digestPath, err)
+		}
+		b.DigestTemplate = string(data)
+	}
+	if prefs != nil && (prefs.LastSearch != "" || len(prefs.Watches) > 0) {
+		b.SavedSearches = &SavedSearches{LastSearch: prefs.LastSearch, Watches: prefs.Watches}
+	}
+	return b, nil
+}
+
+// LoadConfigBundle reads a bundle file and makes its configuration current,
+// returning its rules
+func LoadConfigBundle(path string) (*ConfigBundle, *RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := ParseConfigBundle(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rules, err := b.Apply()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, rules, nil
+}
+
+// ParseConfigBundle reads a bundle and checks every section of it, so that
+// nothing is imported from a bundle with a mistake anywhere in it
+func ParseConfigBundle(data []byte) (*ConfigBundle, error) {
+	raw, err := yamlToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var b ConfigBundle
+	if err := dec.Decode(&b); err != nil {
+		if errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	switch {
+	case b.Version < 1:
+		return nil, fmt.Errorf("%w: the bundle has no version", ErrInvalidInput)
+	case b.Version > configBundleVersion:
+		return nil, fmt.Errorf("%w: the bundle is version %d, but this build reads up to version %d", ErrInvalidInput, b.Version, configBundleVersion)
+	}
+	if s := b.Storage; s != nil && !slices.Contains(StorageBackends(), s.Backend) {
+		return nil, fmt.Errorf("%w: storage: unknown backend %q (available: %v)", ErrInvalidInput, s.Backend, StorageBackends())
+	}
+	for _, t := range b.Positions {
+		if len(b.Departments) > 0 && !slices.Contains(b.Departments, t.Department) {
+			return nil, fmt.Errorf("%w: positions: %q is in %s, which is not among the departments", ErrInvalidInput, t.Position, t.Department)
+		}
+	}
+	if b.SavedSearches != nil {
+		for _, w := range b.SavedSearches.Watches {
+			if _, _, _, err := ParseWatchCondition(w.Condition()); err != nil {
+				return nil, fmt.Errorf("saved_searches: watch %q: %w", w.Name, err)
+			}
+		}
+	}
+
+	// Sections are checked by applying them, as later ones are checked
+	// against earlier ones, and the configuration is put back afterwards
+	defer saveConfigGlobals().restore()
+	if _, err := b.Apply(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Apply makes the bundle's configuration current, replacing the built-in
+// defaults of the sections it has, and returns its rules
+func (b *ConfigBundle) Apply() (*RuleSet, error) {
+	if b.Positions != nil {
+		data, _ := json.Marshal(b.Positions)
+		templates, err := ParsePositionTemplates(data)
+		if err != nil {
+			return nil, fmt.Errorf("positions: %w", err)
+		}
+		positionTemplates = templates
+	}
+	if b.Roles != nil {
+		data, _ := json.Marshal(b.Roles)
+		permissions, err := ParseRolePermissions(data)
+		if err != nil {
+			return nil, fmt.Errorf("roles: %w", err)
+		}
+		rolePermissions = permissions
+	}
+	for _, s := range configSections {
+		if data := *s.field(b); data != nil {
+			if err := s.load(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.key, err)
+			}
+		}
+	}
+	if b.DigestTemplate != "" {
+		t, err := ParseDigestTemplate(b.DigestTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("digest_template: %w", err)
+		}
+		digestTemplate = t
+	}
+	if b.Rules == nil {
+		return nil, nil
+	}
+	return ParseRules(b.Rules)
+}
+
+// configGlobals holds the configuration a bundle replaces, so it can be put back
+type configGlobals struct {
+	positions   []PositionTemplate
+	permissions map[int][]int
+	fields      []*ComputedField
+	payroll     *PayrollConfig
+	costCenters *CostCenterMap
+	entities    *EntityMap
+	holidays    *HolidayCalendar
+	leave       *LeavePolicies
+	digest      *template.Template
+}
+
+// saveConfigGlobals returns the configuration now in effect
+func saveConfigGlobals() configGlobals {
+	return configGlobals{positionTemplates, rolePermissions, computedFields, payrollConfig, costCenters, legalEntities, holidayCalendar, leavePolicies, digestTemplate}
+}
+
+// restore puts the saved configuration back in effect
+func (g configGlobals) restore() {
+	positionTemplates, rolePermissions, computedFields, payrollConfig = g.positions, g.permissions, g.fields, g.payroll
+	costCenters, legalEntities, holidayCalendar, leavePolicies, digestTemplate = g.costCenters, g.entities, g.holidays, g.leave, g.digest
+}
+
+// files returns the files a bundle is imported as, by name, with the flag
+// that loads each
+func (b *ConfigBundle) files() (names, flags []string, contents [][]byte) {
+	add := func(name, flag string, data []byte) {
+		names, flags, contents = append(names, name), append(flags, flag), append(contents, data)
+	}
+	indent := func(v interface{}) []byte {
+		data, _ := json.MarshalIndent(v, "", "  ")
+		return append(data, '\n')
+	}
+	if b.Positions != nil {
+		add(positionsFile, "positions", indent(b.Positions))
+	}
+	if b.Roles != nil {
+		add(rolesFile, "roles", indent(b.Roles))
+	}
+	for _, s := range configSections {
+		if data := *s.field(b); data != nil {
+			var buf bytes.Buffer
+			json.Indent(&buf, data, "", "  ")
+			buf.WriteByte('\n')
+			add(s.file, s.flag, buf.Bytes())
+		}
+	}
+	if b.DigestTemplate != "" {
+		add(digestTemplateFile, "digest-template", []byte(b.DigestTemplate))
+	}
+	return names, flags, contents
+}
+
+// runConfig runs the config command: config export writes the configuration
+// as a bundle, and config import writes a bundle back out as the files the
+// flags read
+func runConfig(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: usage: config export [flags] | config import [flags] BUNDLE", ErrInvalidInput)
+	}
+	switch args[0] {
+	case "export":
+		return runConfigExport(args[1:], stdout, stderr)
+	case "import":
+		return runConfigImport(args[1:], stdout, stderr)
+	default:
+		return fmt.Errorf("%w: unknown config command %q (export or import)", ErrInvalidInput, args[0])
+	}
+}
+
+// runConfigExport writes the configuration named by its flags as a bundle
+func runConfigExport(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("config export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "write the bundle to this file instead of standard output")
+	positionsPath := fs.String("positions", "", "positions file (default the built-in positions)")
+	rolesPath := fs.String("roles", "", "roles file (default the built-in roles)")
+	digestPath := fs.String("digest-template", "", "weekly digest template (default the built-in one, not exported)")
+	userName := fs.String("user", os.Getenv("USER"), "user whose saved searches are exported")
+	configPath := fs.String("config", "", "preferences file to take saved searches from (default the user's; off to leave them out)")
+	paths := make(map[string]*string, len(configSections))
+	for _, s := range configSections {
+		paths[s.flag] = fs.String(s.flag, "", fmt.Sprintf("%s file to export", strings.ReplaceAll(s.key, "_", " ")))
+	}
+	systemPath := fs.String("system-config", "", "system configuration to start from (default the one the setup wizard wrote; off for the built-in defaults)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", ErrInvalidInput, fs.Arg(0))
+	}
+
+	system, _, err := loadSystemConfig(*systemPath)
+	if err != nil {
+		return err
+	}
+	if *positionsPath != "" {
+		if positionTemplates, err = LoadPositionTemplates(*positionsPath); err != nil {
+			return err
+		}
+	}
+	if *rolesPath != "" {
+		if rolePermissions, err = LoadRolePermissions(*rolesPath); err != nil {
+			return err
+		}
+	}
+	if *configPath == "" {
+		*configPath, _ = defaultPreferencesPath(*userName)
+	} else if *configPath == preferencesOff {
+		*configPath = ""
+	}
+	prefs, err := LoadPreferences(*configPath)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]string, len(paths))
+	for name, path := range paths {
+		files[name] = *path
+	}
+
+	b, err := ExportConfig(system, files, *digestPath, prefs)
+	if err != nil {
+		return err
+	}
+	data, err := marshalYAML(b)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf("# Configuration bundle, exported %s. Use it with -system-config FILE, or split it into files with config import -dir DIR FILE\n",
+		appClock.Now().In(userLocation).Format("2006-01-02 15:04 MST"))
+	data = append([]byte(header), data...)
+	if *output == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Exported the configuration to %s\n", *output)
+	return nil
+}
+
+// runConfigImport checks a bundle and writes its sections to a directory,
+// then prints the flags that load them. Saved searches go into the user's
+// preferences.
+func runConfigImport(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("config import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", ".", "directory to write the configuration files to")
+	force := fs.Bool("force", false, "overwrite configuration files that already exist")
+	dryRun := fs.Bool("dry-run", false, "check the bundle and list the files without writing them")
+	userName := fs.String("user", os.Getenv("USER"), "user whose preferences receive the saved searches")
+	configPath := fs.String("config", "", "preferences file to save the saved searches to (default the user's; off to skip them)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: usage: config import [flags] BUNDLE", ErrInvalidInput)
+	}
+	path := fs.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	b, err := ParseConfigBundle(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	names, flags, contents := b.files()
+	var existing []string
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(*dir, name)); err == nil {
+			existing = append(existing, name)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if len(existing) > 0 && !*force && !*dryRun {
+		return fmt.Errorf("%w: %s already has %s; use -force to overwrite", errorsx.ErrConflict, *dir, strings.Join(existing, ", "))
+	}
+
+	if *configPath == "" {
+		*configPath, _ = defaultPreferencesPath(*userName)
+	} else if *configPath == preferencesOff {
+		*configPath = ""
+	}
+	if *dryRun {
+		fmt.Fprintf(stdout, "%s is valid. Importing it would write:\n", path)
+		for _, name := range names {
+			fmt.Fprintf(stdout, "  %s\n", filepath.Join(*dir, name))
+		}
+		if b.SavedSearches != nil && *configPath != "" {
+			fmt.Fprintf(stdout, "  %s (saved searches)\n", *configPath)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return err
+	}
+	var used []string
+	for i, name := range names {
+		file := filepath.Join(*dir, name)
+		if err := os.WriteFile(file, contents[i], 0o600); err != nil {
+			return err
+		}
+		used = append(used, fmt.Sprintf("-%s %s", flags[i], file))
+	}
+	if s := b.Storage; s != nil {
+		used = append(used, "-storage "+s.Backend)
+		if s.DSN != "" {
+			used = append(used, "-dsn "+s.DSN)
+		}
+	}
+	if b.SavedSearches != nil && *configPath != "" {
+		prefs, err := LoadPreferences(*configPath)
+		if err != nil {
+			return err
+		}
+		prefs.LastSearch, prefs.Watches = b.SavedSearches.LastSearch, b.SavedSearches.Watches
+		if err := prefs.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Saved the searches to %s\n", *configPath)
+	}
+	fmt.Fprintf(stdout, "Wrote %d configuration file(s) to %s. Start with:\n  %s\n", len(names), *dir, strings.Join(used, " "))
+	if b.Admin != "" {
+		fmt.Fprintf(stdout, "Admin account: %s (run as -user %s -role admin)\n", b.Admin, b.Admin)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// Confirmation policy constants using iota
+const (
+	ConfirmAlways      = iota // confirm every change
+	ConfirmDestructive        // confirm only removals and rejections
+	ConfirmNever              // never ask, for scripted use
+)
+
+// confirmPolicy decides which changes ask for confirmation.
+// main sets it from the -confirm and -yes flags.
+var confirmPolicy = ConfirmAlways
+
+// ConfirmPolicyToString converts a confirmation policy constant to string
+func ConfirmPolicyToString(policy int) string {
+	switch policy {
+	case ConfirmAlways:
+		return "always"
+	case ConfirmDestructive:
+		return "destructive"
+	case ConfirmNever:
+		return "never"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToConfirmPolicy converts string to confirmation policy constant
+func StringToConfirmPolicy(policy string) (int, error) {
+	switch strings.ToLower(policy) {
+	case "always":
+		return ConfirmAlways, nil
+	case "destructive", "only-destructive":
+		return ConfirmDestructive, nil
+	case "never":
+		return ConfirmNever, nil
+	default:
+		return -1, fmt.Errorf("%w: confirmation policy must be always, destructive or never", ErrInvalidInput)
+	}
+}
+
+// confirm asks the user to approve a change if the confirmation policy requires it.
+// It reports true when the change should go ahead.
+func confirm(reader *bufio.Reader, prompt string, destructive bool) (bool, error) {
+	switch confirmPolicy {
+	case ConfirmNever:
+		return true, nil
+	case ConfirmDestructive:
+		if !destructive {
+			return true, nil
+		}
+	}
+
+	answer, err := readString(reader, prompt+" (y/n): ")
+	if err != nil {
+		return false, err
+	}
+	return strings.ToLower(answer) == "y", nil
+}
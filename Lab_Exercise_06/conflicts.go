@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Conflict strategy constants using iota
+const (
+	ConflictPreferLocal = iota
+	ConflictPreferImport
+	ConflictNewestWins
+	ConflictManual
+)
+
+// ConflictStrategyToString converts a conflict strategy constant to string
+func ConflictStrategyToString(strategy int) string {
+	switch strategy {
+	case ConflictPreferLocal:
+		return "prefer-local"
+	case ConflictPreferImport:
+		return "prefer-import"
+	case ConflictNewestWins:
+		return "newest-wins"
+	case ConflictManual:
+		return "manual"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToConflictStrategy converts a string to a conflict strategy constant
+func StringToConflictStrategy(s string) (int, error) {
+	for strategy := ConflictPreferLocal; strategy <= ConflictManual; strategy++ {
+		if strings.EqualFold(s, ConflictStrategyToString(strategy)) {
+			return strategy, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: unknown conflict strategy %q (prefer-local, prefer-import, newest-wins or manual)", ErrInvalidInput, s)
+}
+
+// employeeFieldSetters copy one of the compared fields, by its name in
+// employeeFieldNames, from one employee to another
+var employeeFieldSetters = map[string]func(dst, src *Employee){
+	"Name":           func(dst, src *Employee) { dst.Name = src.Name },
+	"Position":       func(dst, src *Employee) { dst.Position = src.Position },
+	"Salary":         func(dst, src *Employee) { dst.Salary = src.Salary },
+	"Department":     func(dst, src *Employee) { dst.Department = src.Department },
+	"Join Date":      func(dst, src *Employee) { dst.JoinDate = src.JoinDate },
+	"Birth Date":     func(dst, src *Employee) { dst.BirthDate = src.BirthDate },
+	"Probation Ends": func(dst, src *Employee) { dst.ProbationEnd = src.ProbationEnd },
+	"Location":       func(dst, src *Employee) { dst.Location = src.Location },
+	"Work Mode":      func(dst, src *Employee) { dst.WorkMode = src.WorkMode },
+	"Time Zone":      func(dst, src *Employee) { dst.TimeZone = src.TimeZone },
+}
+
+// SyncBaseline is each employee as the last sync imported them. A field that
+// differs from the baseline both locally and in the import has conflicting
+// edits; without a baseline every difference is taken as a change in the import.
+type SyncBaseline struct {
+	Synced    time.Time        `json:"synced"`
+	Employees map[int]Employee `json:"employees"`
+}
+
+// LoadSyncBaseline reads a baseline file. A missing file is an empty baseline,
+// as before the first sync.
+func LoadSyncBaseline(path string) (*SyncBaseline, error) {
+	b := &SyncBaseline{Employees: make(map[int]Employee)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%w: reading sync baseline %s: %v", ErrInvalidInput, path, err)
+	}
+	if b.Employees == nil {
+		b.Employees = make(map[int]Employee)
+	}
+	return b, nil
+}
+
+// lookup returns an employee as the last sync imported them. A nil baseline has none.
+func (b *SyncBaseline) lookup(id int) (Employee, bool) {
+	if b == nil {
+		return Employee{}, false
+	}
+	e, ok := b.Employees[id]
+	return e, ok
+}
+
+// Save writes the baseline to a file
+func (b *SyncBaseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ImportConflict is an employee whose fields were edited both locally and in
+// the import, waiting for someone to choose which values to keep
+type ImportConflict struct {
+	EmployeeID int       `json:"employee_id"`
+	Fields     []string  `json:"fields"` // names from employeeFieldNames
+	Import     Employee  `json:"import"` // the employee as the import has them
+	Queued     time.Time `json:"queued"`
+}
+
+// LoadConflictQueue reads the conflicts waiting for resolution. A missing
+// file is an empty queue.
+func LoadConflictQueue(path string) ([]ImportConflict, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []ImportConflict
+	if err := json.Unmarshal(data, &conflicts); err != nil {
+		return nil, fmt.Errorf("%w: reading conflict queue %s: %v", ErrInvalidInput, path, err)
+	}
+	return conflicts, nil
+}
+
+// SaveConflictQueue writes the conflicts waiting for resolution, removing
+// the file once there are none
+func SaveConflictQueue(path string, conflicts []ImportConflict) error {
+	if len(conflicts) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(conflicts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// queueConflicts adds conflicts to a queue file, replacing any queued
+// earlier for the same employees
+func queueConflicts(path string, conflicts []ImportConflict) error {
+	queued, err := LoadConflictQueue(path)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		replaced := false
+		for i := range queued {
+			if queued[i].EmployeeID == c.EmployeeID {
+				queued[i], replaced = c, true
+			}
+		}
+		if !replaced {
+			queued = append(queued, c)
+		}
+	}
+	return SaveConflictQueue(path, queued)
+}
+
+// localUpdated returns when an employee was last changed in the store, or
+// the zero time for storage that keeps no history
+func localUpdated(manager EmployeeManager, id int) time.Time {
+	h, ok := capability[interface{ History(int) []Change }](manager)
+	if !ok {
+		return time.Time{}
+	}
+	history := h.History(id)
+	if len(history) == 0 {
+		return time.Time{}
+	}
+	return history[len(history)-1].Time
+}
+
+// fieldMerge is the result of merging an imported row into a local employee
+type fieldMerge struct {
+	Employee  *Employee // the local employee with the changes taken from the import
+	Resolved  int       // conflicting fields settled by the strategy
+	Conflicts []string  // conflicting fields left for manual resolution
+}
+
+// mergeFields merges the import into the local employee field by field. A
+// field changed only in the import takes the imported value, one changed
+// only locally keeps the local value, and one changed on both sides is
+// settled by the strategy. Newest-wins compares when the row and the local
+// employee were last updated and leaves the conflict for manual resolution
+// when either time is unknown.
+func mergeFields(local, imported, base *Employee, strategy int, localTime, importTime time.Time) fieldMerge {
+	merged := *local
+	result := fieldMerge{Employee: &merged}
+	localValues, importValues := employeeFieldValues(local), employeeFieldValues(imported)
+	var baseValues []string
+	if base != nil {
+		baseValues = employeeFieldValues(base)
+	}
+
+	for i, field := range employeeFieldNames {
+		if localValues[i] == importValues[i] {
+			continue
+		}
+		if base == nil || baseValues[i] == localValues[i] {
+			employeeFieldSetters[field](&merged, imported)
+			continue
+		}
+		if baseValues[i] == importValues[i] {
+			continue
+		}
+
+		switch {
+		case strategy == ConflictPreferLocal:
+			result.Resolved++
+		case strategy == ConflictPreferImport:
+			employeeFieldSetters[field](&merged, imported)
+			result.Resolved++
+		case strategy == ConflictNewestWins && !localTime.IsZero() && !importTime.IsZero():
+			if importTime.After(localTime) {
+				employeeFieldSetters[field](&merged, imported)
+			}
+			result.Resolved++
+		default:
+			result.Conflicts = append(result.Conflicts, field)
+		}
+	}
+	return result
+}
+
+// resolveConflict asks whether to keep the local or the imported value of
+// each conflicting field. It returns the employee with the chosen values,
+// or nil when the conflict is skipped.
+func resolveConflict(reader *bufio.Reader, w io.Writer, local *Employee, c ImportConflict) (*Employee, error) {
+	fmt.Fprintf(w, "\n%s (ID %d), queued %s\n", local.Name, local.ID, formatDate(c.Queued))
+	localValues, importValues := employeeFieldValues(local), employeeFieldValues(&c.Import)
+	resolved := *local
+	for i, field := range employeeFieldNames {
+		if !slices.Contains(c.Fields, field) {
+			continue
+		}
+		prompt := fmt.Sprintf("%s: keep local %s or take import %s? (l/i, blank to skip): ",
+			field, displayValue(localValues[i]), displayValue(importValues[i]))
+		choice, err := readValue(reader, prompt, func(input string) (string, error) {
+			switch strings.ToLower(input) {
+			case "", "l", "i":
+				return strings.ToLower(input), nil
+			}
+			return "", fmt.Errorf("%w: please enter l or i", ErrInvalidInput)
+		})
+		if err != nil || choice == "" {
+			return nil, err
+		}
+		if choice == "i" {
+			employeeFieldSetters[field](&resolved, &c.Import)
+		}
+	}
+	return &resolved, nil
+}
+
+// resolveConflicts walks through the queued conflicts, updating each
+// employee with the values chosen. Skipped conflicts, and those left when
+// input ends, stay queued.
+func resolveConflicts(reader *bufio.Reader, manager EmployeeManager, path string, w io.Writer) error {
+	queued, err := LoadConflictQueue(path)
+	if err != nil {
+		return err
+	}
+	if len(queued) == 0 {
+		fmt.Fprintln(w, "No conflicts are waiting for resolution.")
+		return nil
+	}
+
+	remaining := make([]ImportConflict, 0, len(queued))
+	for i, c := range queued {
+		local, err := manager.GetEmployee(c.EmployeeID)
+		if errors.Is(err, ErrEmployeeNotFound) {
+			fmt.Fprintf(w, "%s employee ID %d no longer exists\n", warningText("Dropping:"), c.EmployeeID)
+			continue
+		}
+		var resolved *Employee
+		if err == nil {
+			resolved, err = resolveConflict(reader, w, local, c)
+		}
+		if err != nil {
+			if saveErr := SaveConflictQueue(path, append(remaining, queued[i:]...)); saveErr != nil {
+				return saveErr
+			}
+			return err
+		}
+		if resolved == nil {
+			remaining = append(remaining, c)
+			continue
+		}
+		if len(DiffEmployees(local, resolved)) > 0 {
+			if err := manager.UpdateEmployee(resolved); err != nil {
+				fmt.Fprintf(w, "%s %v\n", errorText("Error:"), err)
+				remaining = append(remaining, c)
+			}
+		}
+	}
+
+	if err := SaveConflictQueue(path, remaining); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nResolved %d conflict(s), %d still queued.\n", len(queued)-len(remaining), len(remaining))
+	return nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CostCenter is a unit of the general ledger that payroll is charged to
+type CostCenter struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	GLAccount string `json:"gl_account"` // the ledger account salaries are booked to
+}
+
+// Allocation is the share of an employee's pay charged to a cost center
+type Allocation struct {
+	CostCenter string  `json:"cost_center"`
+	Percent    float64 `json:"percent"`
+}
+
+// CostCenterMap maps payroll to cost centers for finance. Each department
+// is charged to one cost center, and employees whose work is split between
+// cost centers have allocations of their own that add up to 100%.
+type CostCenterMap struct {
+	CostCenters []CostCenter         `json:"cost_centers"`
+	Departments map[string]string    `json:"departments"` // department name -> cost center code
+	Employees   map[int][]Allocation `json:"employees"`   // employee ID -> allocations
+
+	byCode      map[string]CostCenter
+	departments map[Department]string
+}
+
+// costCenters maps payroll to cost centers, or is nil if the deployment has
+// none. main sets it from the -cost-centers flag.
+var costCenters *CostCenterMap
+
+// LoadCostCenters reads and checks a cost center file
+func LoadCostCenters(path string) (*CostCenterMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseCostCenters(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseCostCenters reads and checks cost centers in JSON
+func ParseCostCenters(data []byte) (*CostCenterMap, error) {
+	var m CostCenterMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	m.byCode = make(map[string]CostCenter, len(m.CostCenters))
+	for _, c := range m.CostCenters {
+		if c.Code == "" {
+			return nil, fmt.Errorf("%w: every cost center needs a code", ErrInvalidInput)
+		}
+		if _, ok := m.byCode[c.Code]; ok {
+			return nil, fmt.Errorf("%w: cost center %s is defined twice", ErrInvalidInput, c.Code)
+		}
+		m.byCode[c.Code] = c
+	}
+
+	m.departments = make(map[Department]string, len(m.Departments))
+	for name, code := range m.Departments {
+		dept, err := ParseDepartment(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m.byCode[code]; !ok {
+			return nil, fmt.Errorf("%w: %s is charged to unknown cost center %q", ErrInvalidInput, dept, code)
+		}
+		m.departments[dept] = code
+	}
+
+	for id, allocations := range m.Employees {
+		total := 0.0
+		for _, a := range allocations {
+			if _, ok := m.byCode[a.CostCenter]; !ok {
+				return nil, fmt.Errorf("%w: employee %d is allocated to unknown cost center %q", ErrInvalidInput, id, a.CostCenter)
+			}
+			if a.Percent <= 0 {
+				return nil, fmt.Errorf("%w: employee %d has an allocation of %.2f%% to %s", ErrInvalidInput, id, a.Percent, a.CostCenter)
+			}
+			total += a.Percent
+		}
+		if math.Abs(total-100) > 0.01 {
+			return nil, fmt.Errorf("%w: the allocations of employee %d add up to %.2f%%, not 100%%", ErrInvalidInput, id, total)
+		}
+	}
+	return &m, nil
+}
+
+// Split returns the cost centers an employee's pay is charged to: their own
+// allocations, else all of it to their department's cost center. It is nil
+// for employees whose department has no cost center.
+func (m *CostCenterMap) Split(e *Employee) []Allocation {
+	if allocations, ok := m.Employees[e.ID]; ok && e.ID != 0 {
+		return allocations
+	}
+	if code, ok := m.departments[e.Department]; ok {
+		return []Allocation{{CostCenter: code, Percent: 100}}
+	}
+	return nil
+}
+
+// LedgerLine is the annual payroll a department charges to a cost center
+type LedgerLine struct {
+	CostCenter CostCenter // zero for payroll not charged to any
+	Department Department
+	FTE        float64 // employees, counting split ones by their share
+	Amount     float64
+}
+
+// Ledger charges the employees' salaries to cost centers, returning a line
+// for each cost center and department, ordered by cost center code with
+// unallocated payroll first
+func (m *CostCenterMap) Ledger(employees []Employee) []LedgerLine {
+	type key struct {
+		code string
+		dept Department
+	}
+	totals := make(map[key]*LedgerLine)
+	charge := func(code string, dept Department, share, amount float64) {
+		k := key{code, dept}
+		if totals[k] == nil {
+			totals[k] = &LedgerLine{CostCenter: m.byCode[code], Department: dept}
+		}
+		totals[k].FTE += share
+		totals[k].Amount += amount
+	}
+	for i := range employees {
+		e := &employees[i]
+		allocations := m.Split(e)
+		if allocations == nil {
+			charge("", e.Department, 1, e.Salary)
+		}
+		for _, a := range allocations {
+			charge(a.CostCenter, e.Department, a.Percent/100, e.Salary*a.Percent/100)
+		}
+	}
+
+	lines := make([]LedgerLine, 0, len(totals))
+	for _, l := range totals {
+		lines = append(lines, *l)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].CostCenter.Code != lines[j].CostCenter.Code {
+			return lines[i].CostCenter.Code < lines[j].CostCenter.Code
+		}
+		return lines[i].Department < lines[j].Department
+	})
+	return lines
+}
+
+// costCenterLabel names a cost center code in reports
+func costCenterLabel(code string) string {
+	if code == "" {
+		return "(unallocated)"
+	}
+	return code
+}
+
+// writeLedger writes ledger lines as a table, with the total payroll
+func writeLedger(w io.Writer, lines []LedgerLine) {
+	fmt.Fprintln(w, "\n=== Payroll by Cost Center ===")
+	fmt.Fprintf(w, "%-14s %-20s %-10s %-12s %7s %14s %13s\n",
+		"Cost Center", "Name", "GL Account", "Department", "FTE", "Annual", "Monthly")
+	fmt.Fprintln(w, strings.Repeat("-", 96))
+	total := 0.0
+	for _, l := range lines {
+		fmt.Fprintf(w, "%-14s %-20s %-10s %-12s %7.2f %14.2f %13.2f\n",
+			costCenterLabel(l.CostCenter.Code), l.CostCenter.Name, l.CostCenter.GLAccount,
+			l.Department, l.FTE, l.Amount, l.Amount/12)
+		total += l.Amount
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 96))
+	fmt.Fprintf(w, "%-14s %67.2f %13.2f\n", "Total", total, total/12)
+}
+
+// writeLedgerCSV writes ledger lines as CSV with a header row, for import
+// into the general ledger. Unallocated payroll has an empty cost center.
+func writeLedgerCSV(w io.Writer, lines []LedgerLine) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"cost_center", "cost_center_name", "gl_account", "department", "fte", "annual_amount", "monthly_amount"})
+	for _, l := range lines {
+		out.Write([]string{
+			l.CostCenter.Code,
+			l.CostCenter.Name,
+			l.CostCenter.GLAccount,
+			l.Department.String(),
+			strconv.FormatFloat(l.FTE, 'f', 2, 64),
+			strconv.FormatFloat(l.Amount, 'f', 2, 64),
+			strconv.FormatFloat(l.Amount/12, 'f', 2, 64),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// allocationJSON is the JSON representation of the share of an employee's
+// pay charged to a cost center
+type allocationJSON struct {
+	CostCenter string  `json:"cost_center"`
+	GLAccount  string  `json:"gl_account"`
+	Percent    float64 `json:"percent"`
+	Amount     float64 `json:"amount"`
+}
+
+// allocationsJSON returns the cost centers an employee's pay is charged to,
+// or nil if no cost centers are defined or none applies
+func allocationsJSON(e *Employee) []allocationJSON {
+	if costCenters == nil {
+		return nil
+	}
+	var out []allocationJSON
+	for _, a := range costCenters.Split(e) {
+		out = append(out, allocationJSON{
+			CostCenter: a.CostCenter,
+			GLAccount:  costCenters.byCode[a.CostCenter].GLAccount,
+			Percent:    a.Percent,
+			Amount:     roundCents(e.Salary * a.Percent / 100),
+		})
+	}
+	return out
+}
+
+// ledgerInteractive shows the payroll charged to each cost center, and can
+// save it as CSV
+func ledgerInteractive(employees []Employee, reader *bufio.Reader) error {
+	lines := costCenters.Ledger(employees)
+	writeLedger(os.Stdout, lines)
+
+	path, err := readString(reader, "\nSave as CSV (file path; blank to skip): ")
+	if err != nil || path == "" {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeLedgerCSV(f, lines)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Ledger saved to %s\n", path)
+	return nil
+}
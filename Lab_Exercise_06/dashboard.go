@@ -0,0 +1,204 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// dashboardHTML is the page served at /dashboard. It renders the reports API
+// in the browser, so it needs no templates on the server side.
+//
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// summaryJSON is the JSON representation of a distribution in the reports API
+type summaryJSON struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	P25    float64 `json:"p25"`
+	P50    float64 `json:"p50"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+}
+
+// toSummaryJSON converts a summary to its API representation
+func toSummaryJSON(s Summary) summaryJSON {
+	return summaryJSON{
+		Mean:   s.Mean,
+		StdDev: s.StdDev,
+		Min:    s.Min,
+		Max:    s.Max,
+		P25:    s.P25,
+		P50:    s.P50,
+		P75:    s.P75,
+		P90:    s.P90,
+	}
+}
+
+// departmentReportJSON is the JSON representation of one department in the summary report
+type departmentReportJSON struct {
+	Department string      `json:"department"`
+	Headcount  int         `json:"headcount"`
+	Payroll    float64     `json:"payroll"`
+	Salary     summaryJSON `json:"salary"`
+}
+
+// summaryReportJSON is the JSON representation of the summary report
+type summaryReportJSON struct {
+	Headcount   int                    `json:"headcount"`
+	Payroll     float64                `json:"payroll"`
+	Salary      summaryJSON            `json:"salary"`
+	Departments []departmentReportJSON `json:"departments"`
+}
+
+// changeJSON is the JSON representation of a recorded change in the reports API
+type changeJSON struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	EmployeeID int    `json:"employee_id"`
+	Time       string `json:"time"`
+	Message    string `json:"message"`
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// buildSummaryReport computes the summary report of all employees
+func buildSummaryReport(manager EmployeeManager) (summaryReportJSON, error) {
+	employees, err := employeeValues(manager)
+	if err != nil {
+		return summaryReportJSON{}, err
+	}
+
+	salaries := make([]float64, len(employees))
+	for i := range employees {
+		salaries[i] = employees[i].Salary
+	}
+	overall := Summarize(salaries)
+
+	out := summaryReportJSON{
+		Headcount:   overall.Count,
+		Payroll:     overall.Sum,
+		Salary:      toSummaryJSON(overall),
+		Departments: make([]departmentReportJSON, 0),
+	}
+	for _, g := range StatsByDepartment(employees, clockOf(manager).Now()) {
+		out.Departments = append(out.Departments, departmentReportJSON{
+			Department: g.Name,
+			Headcount:  g.Salary.Count,
+			Payroll:    g.Salary.Sum,
+			Salary:     toSummaryJSON(g.Salary),
+		})
+	}
+	return out, nil
+}
+
+// SummaryCache keeps the latest summary report, so a large store is not scanned
+// on every dashboard refresh. Any change to the manager invalidates it.
+type SummaryCache struct {
+	manager EmployeeManager
+
+	mu         sync.Mutex
+	report     *summaryReportJSON
+	generation int // incremented by every invalidation
+}
+
+// NewSummaryCache creates a summary cache, invalidated by the manager's events
+// if it publishes any
+func NewSummaryCache(manager EmployeeManager) *SummaryCache {
+	c := &SummaryCache{manager: manager}
+	if events, ok := capability[interface{ Subscribe(func(Event)) }](manager); ok {
+		events.Subscribe(func(Event) { c.Invalidate() })
+	}
+	return c
+}
+
+// Invalidate discards the cached report
+func (c *SummaryCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.report = nil
+	c.generation++
+}
+
+// Refresh recomputes the report. A report computed while a change came in is
+// not kept, since it may not include the change.
+func (c *SummaryCache) Refresh() (summaryReportJSON, error) {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	report, err := buildSummaryReport(c.manager)
+	if err != nil {
+		return summaryReportJSON{}, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.report = &report
+	}
+	c.mu.Unlock()
+	return report, nil
+}
+
+// Get returns the cached report, computing it if there is none
+func (c *SummaryCache) Get() (summaryReportJSON, error) {
+	c.mu.Lock()
+	report := c.report
+	c.mu.Unlock()
+
+	if report != nil {
+		return *report, nil
+	}
+	return c.Refresh()
+}
+
+func (s *Server) handleSummaryReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.summary.Get()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleChangesReport lists the most recent changes, newest first. The limit
+// parameter sets how many (20 by default).
+func (s *Server) handleChangesReport(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, r, ErrInvalidInput)
+			return
+		}
+		limit = n
+	}
+
+	log, ok := capability[interface{ ChangesSince(time.Time) []Change }](s.manager)
+	if !ok {
+		writeAPIError(w, r, http.StatusNotImplemented, CodeNotImplemented, "change history is not available for this storage")
+		return
+	}
+
+	changes := log.ChangesSince(time.Time{})
+	out := make([]changeJSON, 0, min(limit, len(changes)))
+	for i := len(changes) - 1; i >= 0 && len(out) < limit; i-- {
+		c := changes[i]
+		out = append(out, changeJSON{
+			Seq:        c.Seq,
+			Type:       c.Type,
+			EmployeeID: c.EmployeeID,
+			Time:       c.Time.Format(time.RFC3339),
+			Message:    c.Message(),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Department is a department of the company. It prints, encodes as JSON and
+// parses from flags by name.
+type Department int
+
+// Department constants using iota
+const (
+	HR Department = iota
+	Engineering
+	Finance
+	Marketing
+	Operations
+)
+
+// departmentNames are the names of the departments, in constant order
+var departmentNames = [...]string{"HR", "Engineering", "Finance", "Marketing", "Operations"}
+
+// AllDepartments returns every department in order
+func AllDepartments() []Department {
+	all := make([]Department, len(departmentNames))
+	for i := range all {
+		all[i] = Department(i)
+	}
+	return all
+}
+
+// Valid reports whether d is one of the departments
+func (d Department) Valid() bool {
+	return d >= 0 && int(d) < len(departmentNames)
+}
+
+// String returns the department's name, or "Unknown"
+func (d Department) String() string {
+	if !d.Valid() {
+		return "Unknown"
+	}
+	return departmentNames[d]
+}
+
+// ParseDepartment returns the department with a name, ignoring case
+func ParseDepartment(name string) (Department, error) {
+	for _, d := range AllDepartments() {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return -1, fieldError("department", ReasonUnknownDepartment, "unknown department %q", name)
+}
+
+// MarshalJSON encodes the department as its name
+func (d Department) MarshalJSON() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("%w: unknown department %d", ErrInvalidInput, int(d))
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a department from its name. Numbers are accepted
+// too, as data saved before departments were encoded by name holds them.
+func (d *Department) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var n int
+		if json.Unmarshal(data, &n) != nil || !Department(n).Valid() {
+			return fieldError("department", ReasonUnknownDepartment, "department must be one of %s", departmentList())
+		}
+		*d = Department(n)
+		return nil
+	}
+	dept, err := ParseDepartment(name)
+	if err != nil {
+		return err
+	}
+	*d = dept
+	return nil
+}
+
+// Set parses a department flag. With String it makes *Department a flag.Value.
+func (d *Department) Set(name string) error {
+	dept, err := ParseDepartment(name)
+	if err != nil {
+		return err
+	}
+	*d = dept
+	return nil
+}
+
+// departmentList lists the department names for messages
+func departmentList() string {
+	return strings.Join(departmentNames[:], ", ")
+}
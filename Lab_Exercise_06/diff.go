@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// FieldChange describes one field that differs between two employee versions
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// String returns a formatted string representation of the field change
+func (f FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", f.Field, displayValue(f.Old), displayValue(f.New))
+}
+
+// displayValue shows empty values explicitly
+func displayValue(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
+
+// formatOptionalDate renders a date, or an empty string for the zero time
+func formatOptionalDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatDate(t)
+}
+
+// employeeFieldNames lists the compared fields in display order
+var employeeFieldNames = []string{"Name", "Position", "Salary", "Department", "Join Date", "Birth Date", "Probation Ends",
+	"Location", "Work Mode", "Time Zone"}
+
+// employeeFieldValues returns the displayed value of each compared field.
+// A nil employee has no values.
+func employeeFieldValues(e *Employee) []string {
+	if e == nil {
+		return make([]string, len(employeeFieldNames))
+	}
+	return []string{
+		e.Name,
+		e.Position,
+		fmt.Sprintf("$%.2f", e.Salary),
+		e.Department.String(),
+		formatOptionalDate(e.JoinDate),
+		formatOptionalDate(e.BirthDate),
+		formatOptionalDate(e.ProbationEnd),
+		e.Location,
+		WorkModeToString(e.WorkMode),
+		e.TimeZone,
+	}
+}
+
+// DiffEmployees returns the fields that differ between two versions of an
+// employee. Either side may be nil, for an employee that did not exist.
+func DiffEmployees(old, new *Employee) []FieldChange {
+	oldValues, newValues := employeeFieldValues(old), employeeFieldValues(new)
+
+	changes := make([]FieldChange, 0)
+	for i, field := range employeeFieldNames {
+		if oldValues[i] != newValues[i] {
+			changes = append(changes, FieldChange{Field: field, Old: oldValues[i], New: newValues[i]})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Weekly digest settings
+const (
+	digestDays         = 7  // days back the digest covers
+	digestAheadDays    = 30 // days ahead it warns about expiring documents and probation
+	digestTopAnomalies = 5  // attendance anomalies it lists
+)
+
+// WeeklyDigest is what happened over the last week and what needs attention:
+// new hires, departures, changes and leave waiting for approval, documents
+// and probation periods about to run out, and the worst attendance
+type WeeklyDigest struct {
+	From, To   time.Time
+	NewHires   []Employee
+	Departures []Change // the removals; none if the store keeps no change history
+	History    bool     // whether departures could be found
+	Approvals  []ApprovalRequest
+	Leave      []LeaveRequest
+	Documents  []Document
+	Probations []Employee
+	Anomalies  []AttendanceRecord // the worst first
+
+	names map[int]string // employee names by ID, including those who left
+}
+
+// BuildWeeklyDigest gathers the digest of the week up to now. Approvals are
+// only included when the manager queues changes for approval.
+func BuildWeeklyDigest(manager EmployeeManager, documents *Documents, leave *Leave, clock *TimeClock, now time.Time) (*WeeklyDigest, error) {
+	employees, err := employeeValues(manager)
+	if err != nil {
+		return nil, err
+	}
+	sortEmployeeValues(employees, OrderByID)
+
+	local := now.In(userLocation)
+	to := dateOf(local.Year(), local.Month(), local.Day())
+	from := to.AddDate(0, 0, 1-digestDays)
+	until := to.AddDate(0, 0, digestAheadDays)
+	d := &WeeklyDigest{From: from, To: to, names: make(map[int]string)}
+	for _, e := range employees {
+		d.names[e.ID] = e.Name
+		if !e.JoinDate.Before(from) && !e.JoinDate.After(now) {
+			d.NewHires = append(d.NewHires, e)
+		}
+		if !e.ProbationEnd.IsZero() && !e.ProbationEnd.Before(to) && !e.ProbationEnd.After(until) {
+			d.Probations = append(d.Probations, e)
+		}
+	}
+
+	if log, ok := capability[interface{ ChangesSince(time.Time) []Change }](manager); ok {
+		d.History = true
+		for _, c := range log.ChangesSince(from) {
+			if c.Type == EventEmployeeRemoved {
+				d.Departures = append(d.Departures, c)
+				d.names[c.EmployeeID] = c.Employee.Name
+			}
+		}
+	}
+
+	if approvals, ok := capability[*ApprovalManager](manager); ok {
+		if d.Approvals, err = approvals.Requests(StatusPending); err != nil {
+			return nil, err
+		}
+	}
+	d.Leave = leave.pending()
+	d.Documents = documents.expiring(now, digestAheadDays)
+
+	// Without any time clocked, time tracking is not in use and everyone would look absent
+	entries := clock.between(from, to)
+	if len(entries) == 0 {
+		return d, nil
+	}
+	records := AttendanceReport(employees, entries, leave.approved(from, to), holidayCalendar, from, to, now)
+	for _, r := range records {
+		if r.Anomalous() {
+			d.Anomalies = append(d.Anomalies, r)
+		}
+	}
+	sort.SliceStable(d.Anomalies, func(i, j int) bool {
+		a, b := &d.Anomalies[i], &d.Anomalies[j]
+		if len(a.Absences) != len(b.Absences) {
+			return len(a.Absences) > len(b.Absences)
+		}
+		if len(a.Late) != len(b.Late) {
+			return len(a.Late) > len(b.Late)
+		}
+		return a.Overtime-a.OvertimeLimit > b.Overtime-b.OvertimeLimit
+	})
+	if len(d.Anomalies) > digestTopAnomalies {
+		d.Anomalies = d.Anomalies[:digestTopAnomalies]
+	}
+	return d, nil
+}
+
+// Summary returns a one-line count of what the digest holds
+func (d *WeeklyDigest) Summary() string {
+	departures := fmt.Sprintf("%d departure(s)", len(d.Departures))
+	if !d.History {
+		departures = "departures unknown"
+	}
+	return fmt.Sprintf("%d new hire(s), %s, %d approval(s) pending, %d document(s) and %d probation period(s) ending, %d employee(s) with attendance anomalies",
+		len(d.NewHires), departures, len(d.Approvals)+len(d.Leave), len(d.Documents), len(d.Probations), len(d.Anomalies))
+}
+
+// digestTemplateText is the default layout of the weekly digest. A template
+// given with -digest-template replaces it, and is shown a weeklyDigestView.
+const digestTemplateText = `Weekly digest, {{.From}} to {{.To}}
+
+New hires ({{len .NewHires}})
+{{range .NewHires}}  - {{.Name}} (ID {{.ID}}), {{.Position}} in {{.Department}}, joined {{.Date}}
+{{else}}  None
+{{end}}
+Departures{{if .History}} ({{len .Departures}}){{end}}
+{{if not .History}}  Unknown: the storage keeps no change history
+{{else}}{{range .Departures}}  - {{.Name}} (ID {{.ID}}), {{.Position}} in {{.Department}}, left {{.Date}}
+{{else}}  None
+{{end}}{{end}}
+Pending approvals ({{len .Approvals}})
+{{range .Approvals}}  - {{.}}
+{{else}}  None
+{{end}}
+Expiring in the next {{.AheadDays}} days ({{len .Expiring}})
+{{range .Expiring}}  - {{.Date}}  {{.Name}}: {{.What}}
+{{else}}  None
+{{end}}
+Top attendance anomalies ({{len .Anomalies}})
+{{range .Anomalies}}  - {{.Name}} ({{.Department}}): {{.Absences}} absence(s), {{.Late}} late, {{.Overtime}} overtime; {{.Flags}}
+{{else}}  None
+{{end}}`
+
+// digestTemplate lays out the weekly digest. main replaces it from the
+// -digest-template flag.
+var digestTemplate = template.Must(template.New("digest").Parse(digestTemplateText))
+
+// LoadDigestTemplate reads a weekly digest template, written in the
+// text/template language over a weeklyDigestView
+func LoadDigestTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ParseDigestTemplate(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseDigestTemplate parses and checks the text of a weekly digest template
+func ParseDigestTemplate(text string) (*template.Template, error) {
+	t, err := template.New("digest").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	// Render an empty digest so fields the view does not have are found now
+	// rather than when the digest is sent
+	if err := t.Execute(io.Discard, weeklyDigestView{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return t, nil
+}
+
+// weeklyDigestView is what digest templates show, with dates, requests and
+// anomalies already described
+type weeklyDigestView struct {
+	From, To             string
+	History              bool
+	NewHires, Departures []digestEmployeeView
+	Approvals            []string
+	AheadDays            int
+	Expiring             []struct{ Date, Name, What string } // documents and probation, soonest first
+	Anomalies            []digestAnomalyView
+}
+
+// digestEmployeeView is an employee who joined or left, on the date they did
+type digestEmployeeView struct {
+	ID                               int
+	Name, Position, Department, Date string
+}
+
+// digestAnomalyView is an employee whose attendance needs looking into
+type digestAnomalyView struct {
+	Name, Department, Overtime, Flags string
+	Absences, Late                    int
+}
+
+// view describes the digest for its template
+func (d *WeeklyDigest) view(now time.Time) weeklyDigestView {
+	v := weeklyDigestView{From: formatDate(d.From), To: formatDate(d.To), History: d.History, AheadDays: digestAheadDays}
+	for _, e := range d.NewHires {
+		v.NewHires = append(v.NewHires, digestEmployeeView{e.ID, e.Name, e.Position, e.Department.String(), formatDate(e.JoinDate)})
+	}
+	for _, c := range d.Departures {
+		e := c.Employee
+		v.Departures = append(v.Departures, digestEmployeeView{e.ID, e.Name, e.Position, e.Department.String(), formatDate(c.Time)})
+	}
+	for i := range d.Approvals {
+		v.Approvals = append(v.Approvals, d.Approvals[i].String())
+	}
+	for i := range d.Leave {
+		r := &d.Leave[i]
+		v.Approvals = append(v.Approvals, fmt.Sprintf("Leave #%d for %s: %s, %.1f day(s), requested by %s",
+			r.ID, d.names[r.EmployeeID], r.Span(), r.Days, r.RequestedBy))
+	}
+
+	type expiring struct {
+		at               time.Time
+		date, name, what string
+	}
+	var soon []expiring
+	for _, doc := range d.Documents {
+		soon = append(soon, expiring{doc.Expires, formatDate(doc.Expires), d.names[doc.EmployeeID],
+			fmt.Sprintf("%s %q %s", strings.ToLower(DocumentKindToString(doc.Kind)), doc.Title, documentExpiry(doc, now))})
+	}
+	for _, e := range d.Probations {
+		soon = append(soon, expiring{e.ProbationEnd, formatDate(e.ProbationEnd), e.Name, "probation ends"})
+	}
+	sort.SliceStable(soon, func(i, j int) bool { return soon[i].at.Before(soon[j].at) })
+	for _, s := range soon {
+		v.Expiring = append(v.Expiring, struct{ Date, Name, What string }{s.date, s.name, s.what})
+	}
+
+	for i := range d.Anomalies {
+		r := &d.Anomalies[i]
+		v.Anomalies = append(v.Anomalies, digestAnomalyView{
+			Name: r.Employee.Name, Department: r.Employee.Department.String(),
+			Overtime: fmt.Sprintf("%.1fh", r.Overtime), Flags: attendanceFlags(r),
+			Absences: len(r.Absences), Late: len(r.Late),
+		})
+	}
+	return v
+}
+
+// WriteWeeklyDigest renders the digest with the digest template
+func WriteWeeklyDigest(w io.Writer, d *WeeklyDigest, now time.Time) error {
+	return digestTemplate.Execute(w, d.view(now))
+}
+
+// Notification converts the digest to the notification users are sent
+func (d *WeeklyDigest) Notification(now time.Time) (Notification, error) {
+	var body strings.Builder
+	if err := WriteWeeklyDigest(&body, d, now); err != nil {
+		return Notification{}, err
+	}
+	return Notification{
+		Event:   NotifyWeeklyDigest,
+		Subject: fmt.Sprintf("Weekly digest, %s to %s", formatDate(d.From), formatDate(d.To)),
+		Text:    d.Summary(),
+		Detail:  strings.TrimRight(body.String(), "\n"),
+		Time:    now,
+		label:   "Weekly digest",
+	}, nil
+}
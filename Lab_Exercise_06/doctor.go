@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Doctor thresholds
+const (
+	maxClockSkew        = 5 * time.Minute // how far the clock may be from the database's, or behind recorded changes
+	doctorProblemsShown = 5               // problems listed per check; the rest are counted
+)
+
+// earliestSaneClock is a date the clock cannot be before, such as when a
+// machine without a clock battery starts at 1970
+var earliestSaneClock = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Outcomes of a doctor check
+const (
+	checkPass = "PASS"
+	checkFail = "FAIL"
+	checkSkip = "SKIP" // the check does not apply, such as to a file that is not used
+)
+
+// DoctorCheck is the outcome of one of the doctor command's checks, with
+// what to do about a failure
+type DoctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Hint   string // how to fix a failure
+}
+
+// passCheck is a check that passed
+func passCheck(name, detail string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: checkPass, Detail: detail}
+}
+
+// failCheck is a check that failed, with how to fix it
+func failCheck(name, detail, hint string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: checkFail, Detail: detail, Hint: hint}
+}
+
+// skipCheck is a check that does not apply
+func skipCheck(name, detail string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: checkSkip, Detail: detail}
+}
+
+// writeDoctorChecks writes the outcome of each check, and the hints of those
+// that failed, returning how many failed
+func writeDoctorChecks(w io.Writer, checks []DoctorCheck) int {
+	failed := 0
+	for _, c := range checks {
+		status := c.Status
+		switch c.Status {
+		case checkPass:
+			status = successText(status)
+		case checkFail:
+			status = errorText(status)
+			failed++
+		default:
+			status = warningText(status)
+		}
+		fmt.Fprintf(w, "%s  %-16s %s\n", status, c.Name, c.Detail)
+		if c.Hint != "" {
+			fmt.Fprintf(w, "      %-16s %s\n", "", c.Hint)
+		}
+	}
+	return failed
+}
+
+// summarizeProblems joins the first problems found, counting the rest
+func summarizeProblems(problems []string) string {
+	if len(problems) <= doctorProblemsShown {
+		return strings.Join(problems, "; ")
+	}
+	return fmt.Sprintf("%s; and %d more", strings.Join(problems[:doctorProblemsShown], "; "), len(problems)-doctorProblemsShown)
+}
+
+// checkSystemConfig loads and checks the system configuration, returning it
+// so its storage settings can be checked too
+func checkSystemConfig(path string) (*ConfigBundle, DoctorCheck) {
+	const name = "system config"
+	b, _, err := loadSystemConfig(path)
+	if err != nil {
+		return nil, failCheck(name, err.Error(), "Correct the file, or run setup -force to write it again.")
+	}
+	if b == nil {
+		return nil, skipCheck(name, "not set up; the built-in defaults are used (run setup to set the deployment up)")
+	}
+	if path == "" {
+		path, _ = defaultSystemConfigPath()
+	}
+	return b, passCheck(name, fmt.Sprintf("%s: %d department(s), %d position(s)", path, len(b.Departments), len(b.Positions)))
+}
+
+// checkConfigFile loads a configuration file given by a flag and checks it
+func checkConfigFile(flagName, path string, load func(path string) error) DoctorCheck {
+	name := "-" + flagName
+	if err := load(path); err != nil {
+		hint := "Correct the file; help options describes what it holds."
+		if errors.Is(err, os.ErrNotExist) {
+			hint = "Give the path of an existing file, or leave the flag out."
+		}
+		return failCheck(name, err.Error(), hint)
+	}
+	return passCheck(name, path)
+}
+
+// checkStorage opens the storage backend and reads every employee from it.
+// It returns the open store, or nil when it could not be used.
+func checkStorage(backend, dsn string) (Storage, DoctorCheck) {
+	const name = "storage"
+	where := backend
+	if dsn != "" {
+		where += " at " + redactSecrets(dsn)
+	}
+	hint := "Check -storage and -dsn; help options lists the backends."
+	if backend == "postgres" {
+		hint = "Check that the database is running and reachable from this host, and the user, password and database in -dsn."
+	}
+
+	store, err := OpenStorage(backend, dsn, appClock)
+	if err != nil {
+		return nil, failCheck(name, fmt.Sprintf("cannot open %s: %v", where, err), hint)
+	}
+	if db, ok := capability[interface{ Ping(context.Context) error }](store); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+		if err := db.Ping(ctx); err != nil {
+			store.Close()
+			return nil, failCheck(name, fmt.Sprintf("cannot reach %s: %v", where, err), hint)
+		}
+	}
+	employees, err := store.ListEmployees()
+	if err != nil {
+		store.Close()
+		return nil, failCheck(name, fmt.Sprintf("cannot read the employees of %s: %v", where, err), hint)
+	}
+	return store, passCheck(name, fmt.Sprintf("%s: %d employee(s)", where, len(employees)))
+}
+
+// checkIndexes compares the store's indexes with its data: looking each
+// employee up by ID must find the same record, and the department index must
+// list exactly the employees of each department
+func checkIndexes(store Storage) DoctorCheck {
+	const name = "indexes"
+	hint := "Run repair to rebuild the indexes from the employee records."
+	employees, err := store.ListEmployees()
+	if err != nil {
+		return failCheck(name, err.Error(), hint)
+	}
+
+	var problems []string
+	seen := make(map[int]bool, len(employees))
+	byDepartment := make(map[Department]map[int]bool)
+	for _, e := range employees {
+		if seen[e.ID] {
+			problems = append(problems, fmt.Sprintf("ID %d is listed twice", e.ID))
+			continue
+		}
+		seen[e.ID] = true
+		if byDepartment[e.Department] == nil {
+			byDepartment[e.Department] = make(map[int]bool)
+		}
+		byDepartment[e.Department][e.ID] = true
+
+		found, err := store.GetEmployee(e.ID)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("ID %d is listed but cannot be looked up: %v", e.ID, err))
+		case found.ID != e.ID || found.Name != e.Name || found.Department != e.Department:
+			problems = append(problems, fmt.Sprintf("ID %d is listed as %s of %s but looked up as %s (ID %d) of %s",
+				e.ID, e.Name, e.Department, found.Name, found.ID, found.Department))
+		}
+	}
+
+	indexed := "the ID lookup"
+	if lister, ok := capability[interface{ ListByDepartment(Department) []*Employee }](store); ok {
+		indexed += " and the department index"
+		for _, d := range AllDepartments() {
+			listed := make(map[int]bool)
+			for _, e := range lister.ListByDepartment(d) {
+				listed[e.ID] = true
+				if !byDepartment[d][e.ID] {
+					problems = append(problems, fmt.Sprintf("ID %d is indexed under %s but is not in it", e.ID, d))
+				}
+			}
+			var missing []int
+			for id := range byDepartment[d] {
+				if !listed[id] {
+					missing = append(missing, id)
+				}
+			}
+			sort.Ints(missing)
+			for _, id := range missing {
+				problems = append(problems, fmt.Sprintf("ID %d of %s is missing from its index", id, d))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return failCheck(name, summarizeProblems(problems), hint)
+	}
+	return passCheck(name, fmt.Sprintf("%s agree with the %d employee record(s)", indexed, len(employees)))
+}
+
+// checkClock checks that the clock is plausible: not reset to long ago, not
+// behind changes already recorded, and close to the database server's
+func checkClock(store Storage, timeZone string) DoctorCheck {
+	const name = "clock"
+	hint := "Set the system clock, and turn on network time synchronization."
+	if err := setUserLocation(timeZone); err != nil {
+		return failCheck(name, err.Error(), "Use a time zone name such as Asia/Kolkata, and check that the time zone database is installed.")
+	}
+	now := time.Now()
+	if now.Before(earliestSaneClock) {
+		return failCheck(name, fmt.Sprintf("the clock reads %s", now.Format(time.RFC3339)), hint)
+	}
+	if store == nil {
+		return passCheck(name, fmt.Sprintf("%s %s", now.In(userLocation).Format("2006-01-02 15:04:05"), userLocation))
+	}
+
+	// Changes recorded later than now mean the clock has gone back since
+	var latest time.Time
+	if log, ok := capability[interface{ ChangesSince(time.Time) []Change }](store); ok {
+		for _, c := range log.ChangesSince(time.Time{}) {
+			if c.Time.After(latest) {
+				latest = c.Time
+			}
+		}
+	}
+	if history, ok := capability[interface{ TransferHistory(int) []TransferRecord }](store); ok {
+		if employees, err := store.ListEmployees(); err == nil {
+			for _, e := range employees {
+				for _, t := range history.TransferHistory(e.ID) {
+					if t.RecordedAt.After(latest) {
+						latest = t.RecordedAt
+					}
+				}
+			}
+		}
+	}
+	if latest.Sub(now) > maxClockSkew {
+		return failCheck(name, fmt.Sprintf("the clock reads %s, but a change was recorded at %s", now.Format(time.RFC3339), latest.Format(time.RFC3339)), hint)
+	}
+
+	if db, ok := capability[interface {
+		ServerTime(context.Context) (time.Time, error)
+	}](store); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+		serverTime, err := db.ServerTime(ctx)
+		if err != nil {
+			return failCheck(name, fmt.Sprintf("cannot read the database's clock: %v", err), "Check that the database user may run SELECT now().")
+		}
+		if skew := now.Sub(serverTime); skew > maxClockSkew || skew < -maxClockSkew {
+			return failCheck(name, fmt.Sprintf("the clock is %s from the database's", skew.Round(time.Second)), hint+" Do the same on the database server.")
+		}
+	}
+	return passCheck(name, fmt.Sprintf("%s %s", now.In(userLocation).Format("2006-01-02 15:04:05"), userLocation))
+}
+
+// checkPendingWrites looks for writes that were cut short. The stores keep
+// no write-ahead log: files are written under a temporary name and renamed
+// into place, so a temporary file left behind is a save that never finished.
+func checkPendingWrites(files []string, dirs []string) DoctorCheck {
+	const name = "pending writes"
+	var left []string
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path + ".tmp"); err == nil {
+			left = append(left, path+".tmp")
+		}
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+		left = append(left, matches...)
+	}
+	if len(left) > 0 {
+		return failCheck(name, fmt.Sprintf("%d save(s) did not finish: %s", len(left), summarizeProblems(left)),
+			"The file beside each holds its last complete save. Check that it is intact, then delete the .tmp file.")
+	}
+	return passCheck(name, "no save was left unfinished")
+}
+
+// runDoctor implements the doctor command, which checks the configuration,
+// the store and the machine the system runs on, and says how to fix what it
+// finds. It fails when any check fails.
+func runDoctor(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to check (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to check (default the one the setup wizard wrote; off to skip it)")
+	timeZone := fs.String("tz", os.Getenv("EMS_TIMEZONE"), "time zone to check")
+	paths := make(map[string]*string, len(configSections))
+	for _, s := range configSections {
+		paths[s.flag] = fs.String(s.flag, "", fmt.Sprintf("%s file to check", strings.ReplaceAll(s.key, "_", " ")))
+	}
+	positionsPath := fs.String("positions", "", "positions file to check")
+	rolesPath := fs.String("roles", "", "roles file to check")
+	apiKeysPath := fs.String("api-keys", "", "API keys file to check")
+	digestPath := fs.String("digest-template", "", "weekly digest template to check")
+	dataFiles := make([]*string, 0)
+	for _, name := range []string{"bank-file", "loans-file", "assets-file", "documents-file", "leave-file", "time-file", "notifications-file", "approvals"} {
+		dataFiles = append(dataFiles, fs.String(name, "", "data file to check for unfinished saves"))
+	}
+	snapshotDir := fs.String("snapshot-dir", "", "snapshot directory to check for unfinished snapshots")
+	userName := fs.String("user", os.Getenv("USER"), "user whose preferences file is checked for unfinished saves")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", ErrInvalidInput, fs.Arg(0))
+	}
+
+	system, systemCheck := checkSystemConfig(*systemPath)
+	checks := []DoctorCheck{systemCheck}
+	// Only the files given are checked, in the order main loads them
+	checkFile := func(flagName, path string, load func(path string) error) {
+		if path != "" {
+			checks = append(checks, checkConfigFile(flagName, path, load))
+		}
+	}
+	for _, s := range configSections {
+		s := s
+		checkFile(s.flag, *paths[s.flag], func(path string) error {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := s.load(data); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			return nil
+		})
+	}
+	checkFile("positions", *positionsPath, func(path string) (err error) { _, err = LoadPositionTemplates(path); return err })
+	checkFile("roles", *rolesPath, func(path string) (err error) { _, err = LoadRolePermissions(path); return err })
+	checkFile("api-keys", *apiKeysPath, func(path string) (err error) { _, err = LoadAPIKeys(path); return err })
+	checkFile("digest-template", *digestPath, func(path string) (err error) { _, err = LoadDigestTemplate(path); return err })
+
+	backend, connection := systemStorage(system, *storageName, *dsn)
+	store, storageCheck := checkStorage(backend, connection)
+	checks = append(checks, storageCheck)
+	if store != nil {
+		defer store.Close()
+		checks = append(checks, checkIndexes(store))
+	} else {
+		checks = append(checks, skipCheck("indexes", "the store could not be read"))
+	}
+	checks = append(checks, checkClock(store, *timeZone))
+
+	var files []string
+	switch *systemPath {
+	case "":
+		path, _ := defaultSystemConfigPath()
+		files = append(files, path)
+	case preferencesOff:
+	default:
+		files = append(files, *systemPath)
+	}
+	if prefs, err := defaultPreferencesPath(*userName); err == nil {
+		files = append(files, prefs)
+	}
+	for _, f := range dataFiles {
+		files = append(files, *f)
+	}
+	if backend == "file" {
+		files = append(files, connection)
+	}
+	checks = append(checks, checkPendingWrites(files, []string{*snapshotDir}))
+
+	if failed := writeDoctorChecks(stdout, checks); failed > 0 {
+		return fmt.Errorf("%d of %d check(s) failed", failed, len(checks))
+	}
+	fmt.Fprintln(stdout, "\nEverything checked is healthy.")
+	return nil
+}
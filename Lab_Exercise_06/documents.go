@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrDocumentNotFound is returned when no document has the given ID
+var ErrDocumentNotFound = errorsx.New(errorsx.ErrNotFound, "document_id", "document not found")
+
+// Document kind constants using iota
+const (
+	DocumentWorkPermit = iota
+	DocumentVisa
+	DocumentPassport
+	DocumentCertification
+	DocumentOther
+)
+
+// DocumentKindToString converts a document kind constant to string
+func DocumentKindToString(kind int) string {
+	switch kind {
+	case DocumentWorkPermit:
+		return "Work permit"
+	case DocumentVisa:
+		return "Visa"
+	case DocumentPassport:
+		return "Passport"
+	case DocumentCertification:
+		return "Certification"
+	case DocumentOther:
+		return "Other"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToDocumentKind converts string to document kind constant
+func StringToDocumentKind(kind string) (int, error) {
+	switch strings.ToLower(strings.ReplaceAll(kind, " ", "-")) {
+	case "work-permit", "permit":
+		return DocumentWorkPermit, nil
+	case "visa":
+		return DocumentVisa, nil
+	case "passport":
+		return DocumentPassport, nil
+	case "certification", "cert":
+		return DocumentCertification, nil
+	case "other":
+		return DocumentOther, nil
+	default:
+		return -1, fmt.Errorf("%w: document kind must be work-permit, visa, passport, certification or other", ErrInvalidInput)
+	}
+}
+
+// Document is a dated document an employee must keep valid, such as a work
+// permit or a certification their role requires
+type Document struct {
+	ID         int       `json:"id"`
+	EmployeeID int       `json:"employee_id"`
+	Kind       int       `json:"kind"`
+	Title      string    `json:"title"`            // e.g. the visa class or certificate name
+	Number     string    `json:"number,omitempty"` // the document or certificate number
+	Expires    time.Time `json:"expires"`          // midnight of the expiry date in the user's time zone
+}
+
+// DaysLeft returns the days from the day of now until the document
+// expires, negative once it has expired
+func (d *Document) DaysLeft(now time.Time) int {
+	today := dateOf(now.In(userLocation).Date())
+	return int(d.Expires.Sub(today).Hours() / 24)
+}
+
+// Documents records employees' dated documents. Like other personal data,
+// they are restricted to users allowed to handle it; the scheduler alerts
+// on those about to expire.
+type Documents struct {
+	mu        sync.Mutex
+	manager   EmployeeManager
+	documents []*Document
+	nextID    int
+	path      string // JSON file the documents are kept in, or "" for memory only
+}
+
+// NewDocuments creates documents for employees of the given manager,
+// reading them from the file at path if there is one
+func NewDocuments(manager EmployeeManager, path string) (*Documents, error) {
+	d := &Documents{manager: manager, nextID: 1, path: path}
+	if path == "" {
+		return d, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &d.documents); err != nil {
+		return nil, fmt.Errorf("reading documents %s: %w", path, err)
+	}
+	for _, doc := range d.documents {
+		d.nextID = max(d.nextID, doc.ID+1)
+	}
+	return d, nil
+}
+
+// save writes the documents to the file. The caller must hold the lock. The
+// file is written under a temporary name and renamed, and an interrupt
+// waits for the save, so it is never left half written.
+func (d *Documents) save() error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.documents, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := d.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, d.path)
+	})
+}
+
+// find returns the document with an ID, or nil. The caller must hold the lock.
+func (d *Documents) find(id int) *Document {
+	for _, doc := range d.documents {
+		if doc.ID == id {
+			return doc
+		}
+	}
+	return nil
+}
+
+// Add records a document of an employee that expires on a day
+func (d *Documents) Add(user User, employeeID, kind int, title, number string, expires time.Time) (*Document, error) {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return nil, err
+	}
+	if _, err := d.manager.GetEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	title = strings.TrimSpace(title)
+	switch {
+	case DocumentKindToString(kind) == "Unknown":
+		return nil, fmt.Errorf("%w: please select a valid document kind", ErrInvalidInput)
+	case title == "":
+		return nil, fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
+	case expires.IsZero():
+		return nil, fmt.Errorf("%w: a document needs an expiry date", ErrInvalidInput)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc := &Document{
+		ID:         d.nextID,
+		EmployeeID: employeeID,
+		Kind:       kind,
+		Title:      title,
+		Number:     strings.TrimSpace(number),
+		Expires:    expires,
+	}
+	d.documents = append(d.documents, doc)
+	d.nextID++
+	if err := d.save(); err != nil {
+		d.documents = d.documents[:len(d.documents)-1]
+		d.nextID--
+		return nil, err
+	}
+	copied := *doc
+	return &copied, nil
+}
+
+// Renew moves a document's expiry date, when it has been renewed or replaced
+func (d *Documents) Renew(user User, id int, expires time.Time) (*Document, error) {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return nil, err
+	}
+	if expires.IsZero() {
+		return nil, fmt.Errorf("%w: a document needs an expiry date", ErrInvalidInput)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc := d.find(id)
+	if doc == nil {
+		return nil, ErrDocumentNotFound.WithValue(id)
+	}
+	old := doc.Expires
+	doc.Expires = expires
+	if err := d.save(); err != nil {
+		doc.Expires = old
+		return nil, err
+	}
+	copied := *doc
+	return &copied, nil
+}
+
+// Remove deletes a document that no longer applies
+func (d *Documents) Remove(user User, id int) error {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, doc := range d.documents {
+		if doc.ID == id {
+			d.documents = append(d.documents[:i], d.documents[i+1:]...)
+			if err := d.save(); err != nil {
+				d.documents = append(d.documents[:i], append([]*Document{doc}, d.documents[i:]...)...)
+				return err
+			}
+			return nil
+		}
+	}
+	return ErrDocumentNotFound.WithValue(id)
+}
+
+// ForEmployee returns the documents of an employee, soonest to expire first
+func (d *Documents) ForEmployee(user User, employeeID int) ([]Document, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var list []Document
+	for _, doc := range d.documents {
+		if doc.EmployeeID == employeeID {
+			list = append(list, *doc)
+		}
+	}
+	sortDocuments(list)
+	return list, nil
+}
+
+// Expiring returns the documents that have expired or expire within days
+// of now, soonest first
+func (d *Documents) Expiring(user User, now time.Time, days int) ([]Document, error) {
+	if err := user.Require(PermViewPersonalData); err != nil {
+		return nil, err
+	}
+	return d.expiring(now, days), nil
+}
+
+// expiring returns the documents that have expired or expire within days
+// of now, for the scheduler, which acts for no user
+func (d *Documents) expiring(now time.Time, days int) []Document {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var list []Document
+	for _, doc := range d.documents {
+		if doc.DaysLeft(now) <= days {
+			list = append(list, *doc)
+		}
+	}
+	sortDocuments(list)
+	return list
+}
+
+// sortDocuments orders documents by expiry date, then by employee
+func sortDocuments(list []Document) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if !list[i].Expires.Equal(list[j].Expires) {
+			return list[i].Expires.Before(list[j].Expires)
+		}
+		return list[i].EmployeeID < list[j].EmployeeID
+	})
+}
+
+// Forget returns an event subscriber that drops the documents of removed employees
+func (d *Documents) Forget() func(Event) {
+	return func(ev Event) {
+		if ev.Type != EventEmployeeRemoved {
+			return
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		kept := d.documents[:0]
+		for _, doc := range d.documents {
+			if doc.EmployeeID != ev.EmployeeID {
+				kept = append(kept, doc)
+			}
+		}
+		if len(kept) != len(d.documents) {
+			d.documents = kept
+			d.save()
+		}
+	}
+}
+
+// documentExpiry describes when a document expires relative to now
+func documentExpiry(doc Document, now time.Time) string {
+	switch days := doc.DaysLeft(now); {
+	case days < 0:
+		return fmt.Sprintf("expired %d day(s) ago", -days)
+	case days == 0:
+		return "expires today"
+	default:
+		return fmt.Sprintf("expires in %d day(s)", days)
+	}
+}
+
+// writeDocuments writes an employee's documents
+func writeDocuments(w io.Writer, list []Document, now time.Time) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "\nNo documents on file.")
+		return
+	}
+	fmt.Fprintf(w, "\n%-4s %-14s %-28s %-14s %-12s %s\n", "#", "Kind", "Title", "Number", "Expires", "Status")
+	fmt.Fprintln(w, strings.Repeat("-", 96))
+	for _, doc := range list {
+		fmt.Fprintf(w, "%-4d %-14s %-28s %-14s %-12s %s\n", doc.ID, DocumentKindToString(doc.Kind),
+			doc.Title, doc.Number, formatDate(doc.Expires), documentExpiry(doc, now))
+	}
+}
+
+// writeComplianceReport writes the documents expiring within days of now,
+// naming each holder from the manager
+func writeComplianceReport(w io.Writer, list []Document, manager EmployeeManager, now time.Time, days int) {
+	fmt.Fprintf(w, "\n=== Compliance: Documents Expiring Within %d Days ===\n", days)
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No documents expire in that time.")
+		return
+	}
+	fmt.Fprintf(w, "%-6s %-20s %-12s %-14s %-28s %-12s %s\n", "ID", "Employee", "Department", "Kind", "Title", "Expires", "Status")
+	fmt.Fprintln(w, strings.Repeat("-", 118))
+	expired := 0
+	for _, doc := range list {
+		var name, dept string
+		if e, err := manager.GetEmployee(doc.EmployeeID); err == nil {
+			name, dept = e.Name, e.Department.String()
+		}
+		if doc.DaysLeft(now) < 0 {
+			expired++
+		}
+		fmt.Fprintf(w, "%-6d %-20s %-12s %-14s %-28s %-12s %s\n", doc.EmployeeID, name, dept,
+			DocumentKindToString(doc.Kind), doc.Title, formatDate(doc.Expires), documentExpiry(doc, now))
+	}
+	fmt.Fprintf(w, "\nExpiring: %d  Already expired: %d\n", len(list)-expired, expired)
+}
+
+// writeComplianceReportCSV writes the documents expiring soon as CSV with a header row
+func writeComplianceReportCSV(w io.Writer, list []Document, manager EmployeeManager, now time.Time) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"employee_id", "employee", "department", "document_id", "kind", "title", "number", "expires", "days_left"})
+	for _, doc := range list {
+		var name, dept string
+		if e, err := manager.GetEmployee(doc.EmployeeID); err == nil {
+			name, dept = e.Name, e.Department.String()
+		}
+		out.Write([]string{
+			strconv.Itoa(doc.EmployeeID),
+			name,
+			dept,
+			strconv.Itoa(doc.ID),
+			DocumentKindToString(doc.Kind),
+			doc.Title,
+			doc.Number,
+			formatDate(doc.Expires),
+			strconv.Itoa(doc.DaysLeft(now)),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// documentsInteractive records documents and reports those expiring through
+// user interaction
+func documentsInteractive(documents *Documents, manager EmployeeManager, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Documents & Compliance ==="))
+
+	// Check access before asking for any input
+	if err := user.Require(PermViewPersonalData); err != nil {
+		return err
+	}
+
+	fmt.Println("1. Add document")
+	fmt.Println("2. View employee's documents")
+	fmt.Println("3. Renew document")
+	fmt.Println("4. Remove document")
+	fmt.Println("5. Compliance report")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+	now := clockOf(manager).Now()
+
+	switch option {
+	case 1:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		kind, err := readValue(reader, "Kind (work-permit/visa/passport/certification/other): ", StringToDocumentKind)
+		if err != nil {
+			return err
+		}
+		title, err := readString(reader, "Title: ")
+		if err != nil {
+			return err
+		}
+		number, err := readString(reader, "Number (optional): ")
+		if err != nil {
+			return err
+		}
+		expires, err := readDate(reader, "Expiry date")
+		if err != nil {
+			return err
+		}
+		doc, err := documents.Add(user, id, kind, title, number, expires)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Document #%d added; it %s", doc.ID, documentExpiry(*doc, now))))
+
+	case 2:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		list, err := documents.ForEmployee(user, id)
+		if err != nil {
+			return err
+		}
+		writeDocuments(os.Stdout, list, now)
+
+	case 3:
+		docID, err := readInt(reader, "Document number: ")
+		if err != nil {
+			return err
+		}
+		expires, err := readDate(reader, "New expiry date")
+		if err != nil {
+			return err
+		}
+		doc, err := documents.Renew(user, docID, expires)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Document #%d renewed; it %s", doc.ID, documentExpiry(*doc, now))))
+
+	case 4:
+		docID, err := readInt(reader, "Document number: ")
+		if err != nil {
+			return err
+		}
+		ok, err := confirm(reader, fmt.Sprintf("Remove document #%d?", docID), true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("\n" + warningText("Operation cancelled."))
+			return nil
+		}
+		if err := documents.Remove(user, docID); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText("Document removed successfully!"))
+
+	case 5:
+		days, err := readInt(reader, fmt.Sprintf("Number of days to look ahead [%d]: ", documentAlertDays))
+		if err != nil {
+			return err
+		}
+		if days <= 0 {
+			days = documentAlertDays
+		}
+		list, err := documents.Expiring(user, now, days)
+		if err != nil {
+			return err
+		}
+		writeComplianceReport(os.Stdout, list, manager, now, days)
+		if len(list) == 0 {
+			return nil
+		}
+		path, err := readString(reader, "\nSave as CSV (file path; blank to skip): ")
+		if err != nil || path == "" {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = writeComplianceReportCSV(f, list, manager, now)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Report saved to %s\n", path)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
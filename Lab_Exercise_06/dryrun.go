@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// capability looks for an optional interface on a manager, searching through
+// decorators that expose the manager they wrap with Unwrap
+func capability[T any](manager EmployeeManager) (T, bool) {
+	for manager != nil {
+		if c, ok := manager.(T); ok {
+			return c, true
+		}
+		u, ok := manager.(interface{ Unwrap() EmployeeManager })
+		if !ok {
+			break
+		}
+		manager = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// DryRunManager decorates an EmployeeManager so that writes are validated and
+// printed but never applied. Reads are passed through to the wrapped manager.
+type DryRunManager struct {
+	inner EmployeeManager
+	out   io.Writer
+}
+
+// NewDryRunManager creates a DryRunManager that reports would-be changes to out
+func NewDryRunManager(inner EmployeeManager, out io.Writer) *DryRunManager {
+	return &DryRunManager{inner: inner, out: out}
+}
+
+// Unwrap returns the wrapped manager
+func (d *DryRunManager) Unwrap() EmployeeManager {
+	return d.inner
+}
+
+// report prints a would-be change
+func (d *DryRunManager) report(format string, args ...interface{}) {
+	fmt.Fprintf(d.out, "[dry-run] "+format+"\n", args...)
+}
+
+// AddEmployee validates the employee and reports it without adding it
+func (d *DryRunManager) AddEmployee(e *Employee) error {
+	if e == nil {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, clockOf(d.inner).Now()); err != nil {
+		return err
+	}
+	if e.ID != 0 {
+		if _, err := d.inner.GetEmployee(e.ID); err == nil {
+			return ErrDuplicateID
+		}
+	}
+
+	d.report("would add %s to %s", e.Name, e.Department)
+	for _, change := range DiffEmployees(nil, e) {
+		d.report("  %s", change)
+	}
+	return nil
+}
+
+// RemoveEmployee reports the employee that would be removed
+func (d *DryRunManager) RemoveEmployee(id int) error {
+	employee, err := d.inner.GetEmployee(id)
+	if err != nil {
+		return err
+	}
+	d.report("would remove %s (ID %d) from %s", employee.Name, id, employee.Department)
+	return nil
+}
+
+// UpdateEmployee validates the employee and reports the fields that would change
+func (d *DryRunManager) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, clockOf(d.inner).Now()); err != nil {
+		return err
+	}
+	current, err := d.inner.GetEmployee(e.ID)
+	if err != nil {
+		return err
+	}
+
+	d.report("would update %s (ID %d)", current.Name, e.ID)
+	for _, change := range DiffEmployees(current, e) {
+		d.report("  %s", change)
+	}
+	return nil
+}
+
+// TransferEmployee validates and reports the transfer without applying it
+func (d *DryRunManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+	employee, err := d.inner.GetEmployee(id)
+	if err != nil {
+		return err
+	}
+	if employee.Department == newDept {
+		return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+	}
+
+	d.report("would transfer %s (ID %d) %s -> %s (effective %s)", employee.Name, id,
+		employee.Department, newDept, formatDate(effectiveDate))
+	return nil
+}
+
+// GetEmployee retrieves an employee from the wrapped manager
+func (d *DryRunManager) GetEmployee(id int) (*Employee, error) {
+	return d.inner.GetEmployee(id)
+}
+
+// ListEmployees lists employees from the wrapped manager
+func (d *DryRunManager) ListEmployees() ([]*Employee, error) {
+	return d.inner.ListEmployees()
+}
+
+// FilterEmployees filters employees in the wrapped manager
+func (d *DryRunManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	return d.inner.FilterEmployees(filter)
+}
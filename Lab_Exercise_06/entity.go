@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrEntityFull is returned when a legal entity has handed out every employee ID in its range
+var ErrEntityFull = errorsx.New(errorsx.ErrConflict, "id", "no employee IDs left in the legal entity")
+
+// LegalEntity is a company of the group that employs people. Its employees
+// are those whose IDs fall in its range, and they are paid under its
+// payroll rules.
+type LegalEntity struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	IDFrom int    `json:"id_from"` // the first employee ID the entity hands out
+	IDTo   int    `json:"id_to"`   // the last
+	// Payroll is the entity's payroll config file, relative to the entities
+	// file; the -payroll config applies if it is empty
+	Payroll string `json:"payroll,omitempty"`
+
+	payroll *PayrollConfig
+}
+
+// Contains reports whether an employee ID belongs to the entity
+func (l *LegalEntity) Contains(id int) bool {
+	return id >= l.IDFrom && id <= l.IDTo
+}
+
+// Config returns the payroll config of the entity. A nil entity, which
+// stands for the group, has the -payroll config.
+func (l *LegalEntity) Config() *PayrollConfig {
+	if l == nil || l.payroll == nil {
+		return payrollConfig
+	}
+	return l.payroll
+}
+
+// Employees returns the employees of the entity. A nil entity, which
+// stands for the group, has all of them.
+func (l *LegalEntity) Employees(employees []Employee) []Employee {
+	if l == nil {
+		return employees
+	}
+	var out []Employee
+	for _, e := range employees {
+		if l.Contains(e.ID) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// EntityMap is the legal entities of the group
+type EntityMap struct {
+	Entities []LegalEntity `json:"entities"`
+}
+
+// legalEntities is the group's legal entities, or nil if it is a single
+// company. main sets it from the -entities flag.
+var legalEntities *EntityMap
+
+// LoadEntities reads and checks a legal entities file and the payroll
+// configs it names
+func LoadEntities(path string) (*EntityMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseEntities(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range m.Entities {
+		l := &m.Entities[i]
+		if l.Payroll == "" {
+			continue
+		}
+		file := l.Payroll
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		if l.payroll, err = LoadPayrollConfig(file); err != nil {
+			return nil, fmt.Errorf("payroll of %s: %w", l.Code, err)
+		}
+	}
+	return m, nil
+}
+
+// ParseEntities reads and checks legal entities in JSON. Their ID ranges
+// must not overlap, so every employee belongs to at most one.
+func ParseEntities(data []byte) (*EntityMap, error) {
+	var m EntityMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if len(m.Entities) == 0 {
+		return nil, fmt.Errorf("%w: no legal entities are defined", ErrInvalidInput)
+	}
+	byRange := make([]*LegalEntity, len(m.Entities))
+	codes := make(map[string]bool, len(m.Entities))
+	for i := range m.Entities {
+		l := &m.Entities[i]
+		switch {
+		case l.Code == "":
+			return nil, fmt.Errorf("%w: every legal entity needs a code", ErrInvalidInput)
+		case codes[strings.ToUpper(l.Code)]:
+			return nil, fmt.Errorf("%w: legal entity %s is defined twice", ErrInvalidInput, l.Code)
+		case l.IDFrom < 1 || l.IDTo < l.IDFrom:
+			return nil, fmt.Errorf("%w: legal entity %s has an invalid ID range %d-%d", ErrInvalidInput, l.Code, l.IDFrom, l.IDTo)
+		}
+		codes[strings.ToUpper(l.Code)] = true
+		byRange[i] = l
+	}
+	sort.Slice(byRange, func(i, j int) bool { return byRange[i].IDFrom < byRange[j].IDFrom })
+	for i := 1; i < len(byRange); i++ {
+		if prev := byRange[i-1]; byRange[i].IDFrom <= prev.IDTo {
+			return nil, fmt.Errorf("%w: the ID ranges of %s and %s overlap", ErrInvalidInput, prev.Code, byRange[i].Code)
+		}
+	}
+	return &m, nil
+}
+
+// Find returns the legal entity with a code
+func (m *EntityMap) Find(code string) (*LegalEntity, error) {
+	for i := range m.Entities {
+		if strings.EqualFold(m.Entities[i].Code, code) {
+			return &m.Entities[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown legal entity %q", ErrInvalidInput, code)
+}
+
+// Of returns the legal entity an employee ID belongs to, or nil
+func (m *EntityMap) Of(id int) *LegalEntity {
+	for i := range m.Entities {
+		if m.Entities[i].Contains(id) {
+			return &m.Entities[i]
+		}
+	}
+	return nil
+}
+
+// NextID returns the lowest employee ID of an entity that no employee of
+// the manager has
+func (m *EntityMap) NextID(manager EmployeeManager, l *LegalEntity) (int, error) {
+	employees, err := employeeValues(manager)
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[int]bool)
+	for _, e := range employees {
+		if l.Contains(e.ID) {
+			used[e.ID] = true
+		}
+	}
+	for id := l.IDFrom; id <= l.IDTo; id++ {
+		if !used[id] {
+			return id, nil
+		}
+	}
+	return 0, ErrEntityFull.WithValue(l.Code)
+}
+
+// AssignIDs returns a hook for HookedManager.BeforeAdd that keeps new
+// employees in the ID ranges of the entities. An employee added without an
+// ID joins the first entity; one with an ID outside every range is refused.
+func (m *EntityMap) AssignIDs(manager EmployeeManager) func(e *Employee) error {
+	return func(e *Employee) error {
+		if e.ID != 0 {
+			if m.Of(e.ID) == nil {
+				return fmt.Errorf("%w: employee ID %d is outside the ID range of every legal entity", ErrInvalidInput, e.ID)
+			}
+			return nil
+		}
+		id, err := m.NextID(manager, &m.Entities[0])
+		if err != nil {
+			return err
+		}
+		e.ID = id
+		return nil
+	}
+}
+
+// payrollConfigOf returns the payroll config an employee is paid under:
+// that of their legal entity, else the -payroll config
+func payrollConfigOf(id int) *PayrollConfig {
+	if legalEntities == nil {
+		return payrollConfig
+	}
+	return legalEntities.Of(id).Config()
+}
+
+// entityCode returns the code of an employee's legal entity, or "" if
+// there are no legal entities or the employee is in none
+func entityCode(id int) string {
+	if legalEntities == nil {
+		return ""
+	}
+	if l := legalEntities.Of(id); l != nil {
+		return l.Code
+	}
+	return ""
+}
+
+// commandEntity loads a command's -entities file if it is given, and
+// returns the entity named by -entity. Without legal entities it returns
+// nil, meaning everyone; with them, an entity is needed if required.
+func commandEntity(path, code string, required bool) (*LegalEntity, error) {
+	if path != "" {
+		m, err := LoadEntities(path)
+		if err != nil {
+			return nil, err
+		}
+		legalEntities = m
+	}
+	switch {
+	case legalEntities == nil && code != "":
+		return nil, fmt.Errorf("%w: -entity needs the -entities file", ErrInvalidInput)
+	case legalEntities == nil, code == "" && !required:
+		return nil, nil
+	case code == "":
+		return nil, fmt.Errorf("%w: payroll is run per legal entity; choose one with -entity", ErrInvalidInput)
+	}
+	return legalEntities.Find(code)
+}
+
+// readEntity reads a legal entity code from the user. Without legal
+// entities it asks nothing and returns nil; with them, blank returns nil
+// for the whole group unless an entity is required.
+func readEntity(reader *bufio.Reader, required bool) (*LegalEntity, error) {
+	if legalEntities == nil {
+		return nil, nil
+	}
+	codes := make([]string, len(legalEntities.Entities))
+	for i, l := range legalEntities.Entities {
+		codes[i] = l.Code
+	}
+	prompt := fmt.Sprintf("Legal entity (%s; blank for the whole group): ", strings.Join(codes, ", "))
+	if required {
+		prompt = fmt.Sprintf("Legal entity (%s): ", strings.Join(codes, ", "))
+	}
+	return readValue(reader, prompt, func(input string) (*LegalEntity, error) {
+		if input == "" && !required {
+			return nil, nil
+		}
+		return legalEntities.Find(input)
+	})
+}
+
+// EntitySummary is the headcount and annual payroll of a legal entity
+type EntitySummary struct {
+	Entity    *LegalEntity // nil for employees outside every entity
+	Currency  string       // that salaries are paid in
+	Headcount int
+	Payroll   float64 // annual salaries
+}
+
+// Summary returns the headcount and payroll of each entity, in the order
+// they are defined, then of any employees outside every entity
+func (m *EntityMap) Summary(employees []Employee) []EntitySummary {
+	summaries := make([]EntitySummary, len(m.Entities)+1)
+	for i := range m.Entities {
+		summaries[i] = EntitySummary{Entity: &m.Entities[i], Currency: m.Entities[i].Config().Currency}
+	}
+	outside := &summaries[len(m.Entities)]
+	outside.Currency = payrollConfig.Currency
+	for _, e := range employees {
+		s := outside
+		for i := range m.Entities {
+			if m.Entities[i].Contains(e.ID) {
+				s = &summaries[i]
+				break
+			}
+		}
+		s.Headcount++
+		s.Payroll += e.Salary
+	}
+	if outside.Headcount == 0 {
+		summaries = summaries[:len(m.Entities)]
+	}
+	return summaries
+}
+
+// writeEntityReport writes the headcount and payroll of each legal entity
+// and of the group as a whole. Salaries are in the currency each entity
+// pays in, so the group's payroll is totalled per currency.
+func writeEntityReport(w io.Writer, summaries []EntitySummary) {
+	fmt.Fprintln(w, "\n=== Legal Entities ===")
+	fmt.Fprintf(w, "%-8s %-24s %-15s %-8s %9s %16s %14s\n", "Entity", "Name", "IDs", "Currency", "Headcount", "Annual Payroll", "Average")
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	headcount := 0
+	totals := make(map[string]float64)
+	var currencies []string
+	for _, s := range summaries {
+		code, name, ids := "-", "(outside every entity)", ""
+		if l := s.Entity; l != nil {
+			code, name, ids = l.Code, l.Name, fmt.Sprintf("%d-%d", l.IDFrom, l.IDTo)
+		}
+		average := 0.0
+		if s.Headcount > 0 {
+			average = s.Payroll / float64(s.Headcount)
+		}
+		fmt.Fprintf(w, "%-8s %-24s %-15s %-8s %9d %16.2f %14.2f\n", code, name, ids, s.Currency, s.Headcount, s.Payroll, average)
+
+		headcount += s.Headcount
+		if _, ok := totals[s.Currency]; !ok {
+			currencies = append(currencies, s.Currency)
+		}
+		totals[s.Currency] += s.Payroll
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	sort.Strings(currencies)
+	for i, c := range currencies {
+		label, count := "", ""
+		if i == 0 {
+			label, count = "Group", fmt.Sprint(headcount)
+		}
+		fmt.Fprintf(w, "%-8s %-24s %-15s %-8s %9s %16.2f\n", label, "", "", c, count, totals[c])
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment constants using iota
+const (
+	EnvProduction = iota
+	EnvStaging
+	EnvSandbox
+)
+
+// EnvironmentToString converts environment constant to string
+func EnvironmentToString(env int) string {
+	switch env {
+	case EnvProduction:
+		return "prod"
+	case EnvStaging:
+		return "staging"
+	case EnvSandbox:
+		return "sandbox"
+	default:
+		return "unknown"
+	}
+}
+
+// StringToEnvironment converts string to environment constant
+func StringToEnvironment(env string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "prod", "production":
+		return EnvProduction, nil
+	case "staging":
+		return EnvStaging, nil
+	case "sandbox":
+		return EnvSandbox, nil
+	default:
+		return -1, fmt.Errorf("%w: unknown environment %q (prod, staging, sandbox)", ErrInvalidInput, env)
+	}
+}
+
+// WorkspaceConfig describes how to open the workspace of each environment
+type WorkspaceConfig struct {
+	StorageName    string
+	DSN            string // production storage
+	StagingDSN     string // staging storage, required unless the backend is memory
+	SampleData     bool   // load sample data into production at startup
+	SampleSize     int    // employees in the sample data and the sandbox
+	CacheURL       string
+	ApprovalPolicy ApprovalPolicy
+	ApprovalsFile  string
+	User           User
+	DryRun         bool
+	ReadOnly       bool // follow production and staging without changing them
+	Tracer         Tracer
+	Watches        *Watchlist
+	Rules          *RuleSet // the deployment's rules, installed as hooks
+	BankFile       string   // file of encrypted bank accounts; memory only if empty
+	LoansFile      string   // file of loans and salary advances; memory only if empty
+	AssetsFile     string   // file of company assets issued to employees; memory only if empty
+	DocumentsFile  string   // file of employees' dated documents; memory only if empty
+	LeaveFile      string   // file of leave requests and balances; memory only if empty
+	TimeFile       string   // file of employees' clocked time; memory only if empty
+}
+
+// Workspace is everything that works on the data of one environment: its
+// store, the decorated manager used by the menu and API, and the budgets,
+// candidates, succession plan, exits and personal records kept alongside it
+type Workspace struct {
+	Env         int
+	Store       Storage
+	Manager     EmployeeManager
+	Hooks       *HookedManager // lifecycle hooks around changes made through Manager
+	Plan        *BudgetPlan
+	Recruitment *Recruitment
+	Succession  *SuccessionPlan
+	Attrition   *Attrition
+	Records     *PersonalRecords
+	Banks       *BankDetails
+	Loans       *Loans
+	Assets      *Assets
+	Documents   *Documents
+	Leave       *Leave
+	Time        *TimeClock
+	closers     []func() error
+}
+
+// Close releases the workspace's cache connection and store
+func (w *Workspace) Close() error {
+	var first error
+	for i := len(w.closers) - 1; i >= 0; i-- {
+		if err := w.closers[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// environmentPath adds the environment name to a file path, so that
+// approvals.json becomes approvals-staging.json. Production keeps the path.
+func environmentPath(path string, env int) string {
+	if path == "" || env == EnvProduction {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + EnvironmentToString(env) + ext
+}
+
+// openWorkspace opens the store of an environment and wraps it the same way
+// in every environment. The sandbox is always a fresh in-memory store seeded
+// with sample data, and staging never falls back to the production DSN, so
+// neither can touch real data.
+func openWorkspace(cfg WorkspaceConfig, env int) (_ *Workspace, err error) {
+	open := OpenStorage
+	if cfg.ReadOnly {
+		open = openStorageReadOnly
+	}
+
+	var store Storage
+	switch env {
+	case EnvProduction:
+		store, err = open(cfg.StorageName, cfg.DSN, appClock)
+	case EnvStaging:
+		if cfg.StorageName != "memory" && cfg.StagingDSN == "" {
+			return nil, fmt.Errorf("%w: the staging environment needs -staging-dsn with the %s backend", ErrInvalidInput, cfg.StorageName)
+		}
+		store, err = open(cfg.StorageName, cfg.StagingDSN, appClock)
+	case EnvSandbox:
+		store, err = OpenStorage("memory", "", appClock)
+	default:
+		return nil, fmt.Errorf("%w: unknown environment %d", ErrInvalidInput, env)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workspace{Env: env, Store: store, Plan: NewBudgetPlan(), closers: []func() error{store.Close}}
+	defer func() {
+		if err != nil {
+			w.Close()
+		}
+	}()
+
+	// Sample data is loaded before the dry-run wrapper so there is something to
+	// preview against, and before subscribing so it does not flood the console
+	if env == EnvSandbox || (env == EnvProduction && cfg.SampleData) {
+		addSampleData(store, cfg.SampleSize)
+	}
+
+	// A read-only workspace refuses changes from here on; the sandbox is
+	// throwaway data, so it stays writable
+	if cfg.ReadOnly && env != EnvSandbox {
+		store = NewReadOnlyStorage(store)
+		w.Store = store
+	}
+
+	// Print manager notifications to the console
+	store.Subscribe(func(ev Event) {
+		fmt.Println("\nNotification:", ev)
+	})
+
+	// Department budgets, checked whenever headcount changes
+	store.Subscribe(w.Plan.Watch(store))
+
+	// The user's watches, checked after every change
+	if cfg.Watches != nil {
+		store.Subscribe(cfg.Watches.Subscriber(EnvironmentToString(env), store))
+	}
+
+	// Users who asked to be told of changes
+	store.Subscribe(appNotifications.Subscriber(EnvironmentToString(env)))
+
+	// All other changes go through the manager, which only previews them in dry-run mode
+	var manager EmployeeManager = store
+	if cfg.CacheURL != "" {
+		cache, err := NewCachingManager(store, cfg.CacheURL)
+		if err != nil {
+			return nil, err
+		}
+		// Environments sharing a Redis server must not read each other's entries
+		if env != EnvProduction {
+			cache.prefix += EnvironmentToString(env) + ":"
+		}
+		w.closers = append(w.closers, cache.Close)
+		manager = cache
+	}
+	var approvals *ApprovalManager
+	if p := cfg.ApprovalPolicy; p.SalaryChangePercent > 0 || p.Transfers || p.LeaveDays > 0 {
+		if approvals, err = NewApprovalManager(manager, p, cfg.User, environmentPath(cfg.ApprovalsFile, env)); err != nil {
+			return nil, err
+		}
+		manager = approvals
+	}
+
+	// Department salary budgets are enforced when changes are made, with
+	// approvals if they are enabled
+	manager = NewBudgetEnforcer(manager, w.Plan)
+
+	// Hooks see every change made through the manager, and can stop one before
+	// budgets, approvals or the cache see it. A dry run applies no changes, so
+	// it bypasses them along with everything else.
+	w.Hooks = NewHookedManager(manager)
+	manager = w.Hooks
+	if cfg.Rules != nil {
+		cfg.Rules.Install(w.Hooks)
+	}
+	// New employees get IDs in the range of a legal entity
+	if legalEntities != nil {
+		w.Hooks.BeforeAdd(legalEntities.AssignIDs(w.Hooks))
+	}
+	if cfg.DryRun {
+		manager = NewDryRunManager(store, os.Stdout)
+	}
+
+	// Tracing wraps everything else, so its spans cover caches, approvals and storage
+	if cfg.Tracer != nil {
+		manager = NewTracingManager(manager, cfg.Tracer)
+	}
+	w.Manager = manager
+
+	// Candidates are hired into the same manager
+	w.Recruitment = NewRecruitment(manager)
+
+	// Key roles and their successors; employees who leave stop being successors
+	w.Succession = NewSuccessionPlan(manager)
+	store.Subscribe(w.Succession.Forget())
+
+	// Exit interviews, recorded once a removal has actually happened
+	w.Attrition = NewAttrition()
+	store.Subscribe(w.Attrition.Watch())
+
+	// Emergency contacts and dependents, visible to HR only
+	w.Records = NewPersonalRecords(manager)
+	store.Subscribe(w.Records.Forget())
+
+	// Bank accounts, encrypted and visible to HR only; the sandbox never
+	// reads or writes the real file
+	bankFile := environmentPath(cfg.BankFile, env)
+	if env == EnvSandbox {
+		bankFile = ""
+	}
+	key, err := bankKey(bankFile)
+	if err != nil {
+		return nil, err
+	}
+	if w.Banks, err = NewBankDetails(manager, key, bankFile); err != nil {
+		return nil, err
+	}
+	store.Subscribe(w.Banks.Forget())
+
+	// Loans and salary advances, repaid through payroll; outstanding loans
+	// are kept when an employee leaves
+	loansFile := environmentPath(cfg.LoansFile, env)
+	if env == EnvSandbox {
+		loansFile = ""
+	}
+	if w.Loans, err = NewLoans(manager, loansFile); err != nil {
+		return nil, err
+	}
+
+	// Company assets; an employee cannot be removed until theirs are returned
+	assetsFile := environmentPath(cfg.AssetsFile, env)
+	if env == EnvSandbox {
+		assetsFile = ""
+	}
+	if w.Assets, err = NewAssets(manager, assetsFile); err != nil {
+		return nil, err
+	}
+	w.Hooks.BeforeRemove(w.Assets.CheckReturned)
+
+	// Work permits, visas and certifications, visible to HR only
+	documentsFile := environmentPath(cfg.DocumentsFile, env)
+	if env == EnvSandbox {
+		documentsFile = ""
+	}
+	if w.Documents, err = NewDocuments(manager, documentsFile); err != nil {
+		return nil, err
+	}
+	store.Subscribe(w.Documents.Forget())
+
+	// Leave requests and the days each employee has left
+	leaveFile := environmentPath(cfg.LeaveFile, env)
+	if env == EnvSandbox {
+		leaveFile = ""
+	}
+	if w.Leave, err = NewLeave(manager, leaveFile); err != nil {
+		return nil, err
+	}
+	if approvals != nil {
+		approvals.QueueLeave(w.Leave)
+	}
+	store.Subscribe(w.Leave.Forget())
+
+	// Time clocked by employees, for attendance
+	timeFile := environmentPath(cfg.TimeFile, env)
+	if env == EnvSandbox {
+		timeFile = ""
+	}
+	if w.Time, err = NewTimeClock(manager, timeFile); err != nil {
+		return nil, err
+	}
+	store.Subscribe(w.Time.Forget())
+
+	return w, nil
+}
+
+// Workspaces opens environments on first use and keeps them open, so
+// switching back and forth is instant and keeps each environment's data
+type Workspaces struct {
+	config     WorkspaceConfig
+	workspaces map[int]*Workspace
+	current    *Workspace
+}
+
+// NewWorkspaces opens the workspace of the starting environment
+func NewWorkspaces(cfg WorkspaceConfig, env int) (*Workspaces, error) {
+	ws := &Workspaces{config: cfg, workspaces: make(map[int]*Workspace)}
+	if _, err := ws.Switch(env); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// Current returns the workspace of the current environment
+func (ws *Workspaces) Current() *Workspace {
+	return ws.current
+}
+
+// Switch makes env the current environment, opening it if needed
+func (ws *Workspaces) Switch(env int) (*Workspace, error) {
+	w, ok := ws.workspaces[env]
+	if !ok {
+		var err error
+		w, err = openWorkspace(ws.config, env)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s environment: %w", EnvironmentToString(env), err)
+		}
+		ws.workspaces[env] = w
+	}
+	ws.current = w
+	return w, nil
+}
+
+// Close closes every open workspace
+func (ws *Workspaces) Close() error {
+	var first error
+	for _, w := range ws.workspaces {
+		if err := w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// environmentInteractive switches the menu to another environment
+func environmentInteractive(ws *Workspaces, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Switch Environment ==="))
+	fmt.Printf("Current environment: %s\n", EnvironmentToString(ws.Current().Env))
+
+	env, err := readValue(reader, "Environment (prod, staging, sandbox): ", StringToEnvironment)
+	if err != nil {
+		return err
+	}
+
+	w, err := ws.Switch(env)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nSwitched to the %s environment.\n", EnvironmentToString(w.Env))
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// etagOf returns an entity tag derived from what a response shows: the
+// versions of its employees, which go up with every write, and whatever
+// else selects its content, such as the fields parameter. Values computed
+// from the clock, such as tenure, do not change it between writes.
+func etagOf(key ...interface{}) string {
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// employeeETag returns the entity tag of an employee's representation with
+// the given fields, or all of them if fields is nil
+func employeeETag(e *Employee, fields []string) string {
+	return etagOf(e.ID, e.Version, fields)
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists the
+// tag. Weak tags match their strong form only when weak is true, as for
+// If-None-Match.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCacheableJSON writes v as a JSON response with the given ETag, or only
+// 304 Not Modified when the client's If-None-Match already has that tag
+func writeCacheableJSON(w http.ResponseWriter, r *http.Request, v interface{}, etag string) {
+	w.Header().Set("ETag", etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// checkIfMatch enforces a request's If-Match header against the current
+// employee, writing 412 Precondition Failed with the current tag when the
+// client's copy is stale. It reports whether the request may go ahead.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, manager EmployeeManager, id int) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	current, err := employeeValue(manager, id)
+	if err != nil && !errors.Is(err, ErrEmployeeNotFound) {
+		writeError(w, r, err)
+		return false
+	}
+	if err != nil {
+		writeAPIError(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, "the employee no longer exists")
+		return false
+	}
+	etag := employeeETag(&current, nil)
+	if etagMatches(ifMatch, etag, false) {
+		return true
+	}
+	w.Header().Set("ETag", etag)
+	writeAPIError(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, "the employee has changed since it was read")
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETagFollowsVersion(t *testing.T) {
+	clock := NewFakeClock(dateOf(2024, 1, 1))
+	savedClock, savedFields := appClock, computedFields
+	t.Cleanup(func() { appClock, computedFields = savedClock, savedFields })
+	appClock = clock
+	fields, err := ParseComputedFields([]byte(`{"fields": [{"name": "tenure", "expression": "experience"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	computedFields = fields
+
+	manager := NewInMemoryEmployeeManagerWithClock(clock)
+	ann := &Employee{Name: "Ann", Position: "Engineer", Salary: 90000, Department: Engineering, JoinDate: dateOf(2020, 3, 1)}
+	if err := manager.AddEmployee(ann); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(manager)
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, as("ann", httptest.NewRequest(http.MethodGet, "/employees/1", nil)))
+		return w
+	}
+	put := func(ifMatch string) *httptest.ResponseRecorder {
+		body := `{"name":"Ann","position":"Lead","salary":95000,"department":"Engineering","join_date":"2020-03-01"}`
+		r := httptest.NewRequest(http.MethodPut, "/employees/1", strings.NewReader(body))
+		r.Header.Set("If-Match", ifMatch)
+		as("ann", r)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		return w
+	}
+
+	first := get()
+	clock.AdvanceDays(30)
+	later := get()
+	if first.Body.String() == later.Body.String() {
+		t.Fatal("the computed tenure did not change, so the test does not cover it")
+	}
+	etag := first.Header().Get("ETag")
+	if later.Header().Get("ETag") != etag {
+		t.Errorf("ETag changed from %s to %s without a write", etag, later.Header().Get("ETag"))
+	}
+
+	if w := put(etag); w.Code != http.StatusOK {
+		t.Fatalf("PUT with the current ETag returned %d: %s", w.Code, w.Body)
+	} else if w.Header().Get("ETag") == etag || w.Header().Get("ETag") != get().Header().Get("ETag") {
+		t.Errorf("PUT returned ETag %s, want a new one matching GET", w.Header().Get("ETag"))
+	}
+	if w := put(etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with a stale ETag returned %d, want 412", w.Code)
+	}
+
+	if err := manager.TransferEmployee(ann.ID, Finance, clock.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := manager.GetEmployee(ann.ID); e.Version != 3 {
+		t.Errorf("version after adding, updating and transferring is %d, want 3", e.Version)
+	}
+}
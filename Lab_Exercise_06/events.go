@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types published by the employee manager
+const (
+	EventEmployeeAdded       = "employee.added"
+	EventEmployeeUpdated     = "employee.updated"
+	EventEmployeeRemoved     = "employee.removed"
+	EventEmployeeTransferred = "employee.transferred"
+)
+
+// Event describes a change that subscribers are notified about
+type Event struct {
+	Type       string
+	EmployeeID int
+	Department Department
+	Message    string
+	Time       time.Time
+}
+
+// String returns a one-line description of the event
+func (ev Event) String() string {
+	return fmt.Sprintf("[%s] %s", formatDateTime(ev.Time), ev.Message)
+}
+
+// EventBus delivers events to registered subscribers in order of subscription
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []func(Event)
+	delivering  atomic.Int64 // events being delivered right now
+}
+
+// Subscribe registers a function that is called for every published event
+func (b *EventBus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Publish notifies all subscribers of the event
+func (b *EventBus) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	subscribers := make([]func(Event), len(b.subscribers))
+	copy(subscribers, b.subscribers)
+	b.mu.RUnlock()
+
+	b.delivering.Add(1)
+	defer b.delivering.Add(-1)
+	for _, fn := range subscribers {
+		fn(ev)
+	}
+}
+
+// Backlog returns the number of events whose delivery has not finished. Events
+// are delivered synchronously, so it only grows when subscribers are slow or stuck.
+func (b *EventBus) Backlog() int {
+	return int(b.delivering.Load())
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Change is a single stored event in the employee manager's log.
+// Every mutation is recorded as a Change; the current state is a projection
+// built by applying the changes in order.
+type Change struct {
+	Seq        int
+	Type       string // one of the Event* constants
+	EmployeeID int
+	Time       time.Time
+	Employee   Employee        // state after the change; for removals, the state before
+	Transfer   *TransferRecord // set for transfers
+}
+
+// Message returns a human-readable description of the change
+func (c Change) Message() string {
+	e := c.Employee
+	switch c.Type {
+	case EventEmployeeAdded:
+		return fmt.Sprintf("%s (ID %d) added to %s", e.Name, e.ID, e.Department)
+	case EventEmployeeRemoved:
+		return fmt.Sprintf("%s (ID %d) removed from %s", e.Name, e.ID, e.Department)
+	case EventEmployeeTransferred:
+		return fmt.Sprintf("%s (ID %d) transferred %s", e.Name, e.ID, c.Transfer)
+	default:
+		return fmt.Sprintf("%s (ID %d) updated", e.Name, e.ID)
+	}
+}
+
+// notification converts the change into the Event published to subscribers
+func (c Change) notification() Event {
+	return Event{
+		Type:       c.Type,
+		EmployeeID: c.EmployeeID,
+		Department: c.Employee.Department,
+		Message:    c.Message(),
+		Time:       c.Time,
+	}
+}
+
+// projection is the state derived from the change log
+type projection struct {
+	employees map[int]*Employee
+	deptIndex map[Department]map[int]struct{} // department -> set of employee IDs
+	transfers map[int][]TransferRecord
+}
+
+func newProjection() *projection {
+	return &projection{
+		employees: make(map[int]*Employee),
+		deptIndex: make(map[Department]map[int]struct{}),
+		transfers: make(map[int][]TransferRecord),
+	}
+}
+
+// apply folds a single change into the projection. Stored employee records
+// are never modified afterwards; each change stores a new record, so readers
+// may copy a record without it changing underneath them.
+func (p *projection) apply(c Change) {
+	switch c.Type {
+	case EventEmployeeRemoved:
+		if existing, ok := p.employees[c.EmployeeID]; ok {
+			p.unindexDepartment(c.EmployeeID, existing.Department)
+			delete(p.employees, c.EmployeeID)
+		}
+		return
+	case EventEmployeeTransferred:
+		p.transfers[c.EmployeeID] = append(p.transfers[c.EmployeeID], *c.Transfer)
+	}
+
+	if existing, ok := p.employees[c.EmployeeID]; ok {
+		p.unindexDepartment(c.EmployeeID, existing.Department)
+	}
+	employeeCopy := c.Employee
+	p.employees[c.EmployeeID] = &employeeCopy
+	p.indexDepartment(c.EmployeeID, employeeCopy.Department)
+}
+
+// indexDepartment adds an employee ID to the department index
+func (p *projection) indexDepartment(id int, dept Department) {
+	if p.deptIndex[dept] == nil {
+		p.deptIndex[dept] = make(map[int]struct{})
+	}
+	p.deptIndex[dept][id] = struct{}{}
+}
+
+// unindexDepartment removes an employee ID from the department index
+func (p *projection) unindexDepartment(id int, dept Department) {
+	delete(p.deptIndex[dept], id)
+	if len(p.deptIndex[dept]) == 0 {
+		delete(p.deptIndex, dept)
+	}
+}
+
+// record stores a change in the log and applies it to the current state.
+// The caller must hold the write lock.
+func (m *InMemoryEmployeeManager) record(c Change) Change {
+	c.Seq = len(m.log) + 1
+	if c.Time.IsZero() {
+		c.Time = m.clock.Now()
+	}
+	// Keep the log ordered by time even if the clock moves backwards
+	if n := len(m.log); n > 0 && c.Time.Before(m.log[n-1].Time) {
+		c.Time = m.log[n-1].Time
+	}
+	m.log = append(m.log, c)
+	m.state.apply(c)
+	return c
+}
+
+// projectAt rebuilds the state as it was at the given time.
+// The caller must hold the read lock.
+func (m *InMemoryEmployeeManager) projectAt(at time.Time) *projection {
+	p := newProjection()
+	for _, c := range m.log {
+		if c.Time.After(at) {
+			break
+		}
+		p.apply(c)
+	}
+	return p
+}
+
+// GetEmployeeAt reconstructs an employee's record as it was at the given time
+func (m *InMemoryEmployeeManager) GetEmployeeAt(id int, at time.Time) (*Employee, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var state *Employee
+	for _, c := range m.log {
+		if c.Time.After(at) {
+			break
+		}
+		if c.EmployeeID != id {
+			continue
+		}
+		if c.Type == EventEmployeeRemoved {
+			state = nil
+			continue
+		}
+		employeeCopy := c.Employee
+		state = &employeeCopy
+	}
+
+	if state == nil {
+		return nil, ErrEmployeeNotFound
+	}
+	return state, nil
+}
+
+// ListEmployeesAt returns all employees as they were at the given time, ordered by ID
+func (m *InMemoryEmployeeManager) ListEmployeesAt(at time.Time) []*Employee {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p := m.projectAt(at)
+	employees := make([]*Employee, 0, len(p.employees))
+	for _, emp := range p.employees {
+		employees = append(employees, emp)
+	}
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+	return employees
+}
+
+// ChangesSince returns the changes recorded after the given time, oldest first
+func (m *InMemoryEmployeeManager) ChangesSince(since time.Time) []Change {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i := sort.Search(len(m.log), func(i int) bool { return m.log[i].Time.After(since) })
+	changes := make([]Change, len(m.log)-i)
+	copy(changes, m.log[i:])
+	return changes
+}
+
+// AuditEntry is a change together with the fields it modified
+type AuditEntry struct {
+	Change
+	Fields []FieldChange
+}
+
+// AuditTrail returns the changes recorded after the given time, each with the
+// field-level differences from the employee's previous state
+func (m *InMemoryEmployeeManager) AuditTrail(since time.Time) []AuditEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p := m.projectAt(since)
+	entries := make([]AuditEntry, 0)
+	for _, c := range m.log {
+		if !c.Time.After(since) {
+			continue
+		}
+
+		previous := p.employees[c.EmployeeID]
+		entry := AuditEntry{Change: c}
+		if c.Type != EventEmployeeRemoved {
+			entry.Fields = DiffEmployees(previous, &c.Employee)
+		}
+		entries = append(entries, entry)
+		p.apply(c)
+	}
+	return entries
+}
+
+// History returns every change recorded for an employee, oldest first
+func (m *InMemoryEmployeeManager) History(id int) []Change {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]Change, 0)
+	for _, c := range m.log {
+		if c.EmployeeID == id {
+			history = append(history, c)
+		}
+	}
+	return history
+}
+
+// startOfPreviousQuarter returns the first day of the quarter before the one containing t
+func startOfPreviousQuarter(t time.Time) time.Time {
+	t = t.In(userLocation)
+	quarterStart := time.Month((int(t.Month())-1)/3*3 + 1)
+	return dateOf(t.Year(), quarterStart-3, 1)
+}
+
+// writeAuditTrail writes the changes since a date, with the fields each update changed
+func writeAuditTrail(w io.Writer, since time.Time, entries []AuditEntry) {
+	fmt.Fprintf(w, "\n%d change(s) since %s:\n", len(entries), formatDate(since))
+	for _, entry := range entries {
+		fmt.Fprintf(w, "#%-4d %s  %s\n", entry.Seq, formatDateTime(entry.Time), entry.Message())
+		if entry.Type == EventEmployeeUpdated {
+			for _, field := range entry.Fields {
+				fmt.Fprintf(w, "        %s\n", field)
+			}
+		}
+	}
+}
+
+// changeReportInteractive shows what changed since a date, and can look up an
+// employee's record as it was at that date
+func changeReportInteractive(manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Change History ==="))
+
+	m, ok := capability[interface {
+		AuditTrail(since time.Time) []AuditEntry
+		GetEmployeeAt(id int, at time.Time) (*Employee, error)
+	}](manager)
+	if !ok {
+		return fmt.Errorf("%w: change history is not available for this storage", ErrInvalidInput)
+	}
+
+	defaultSince := startOfPreviousQuarter(clockOf(manager).Now())
+	input, err := readString(reader, fmt.Sprintf("Show changes since (YYYY-MM-DD) [%s]: ", formatDate(defaultSince)))
+	if err != nil {
+		return err
+	}
+	since := defaultSince
+	if input != "" {
+		since, err = parseDate(input)
+		if err != nil {
+			return fmt.Errorf("%w: please enter a valid date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+	}
+
+	writeAuditTrail(os.Stdout, since, m.AuditTrail(since))
+
+	id, err := readInt(reader, "\nEmployee ID to view as of that date (blank to skip): ")
+	if err != nil || id == 0 {
+		return err
+	}
+
+	then, err := m.GetEmployeeAt(id, since)
+	if err != nil {
+		return fmt.Errorf("no record of employee %d on %s: %w", id, formatDate(since), err)
+	}
+
+	fmt.Printf("\nEmployee %d as of %s:\n", id, formatDate(since))
+	fmt.Println(then)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// writeEmployeesJSON writes employees as a JSON array in the Lab_Exercise_06
+// schema, one at a time so progress can be shown and Ctrl-C can stop it. It
+// returns how many employees were written; the array is always closed, so a
+// stopped export is still valid JSON.
+func writeEmployeesJSON(w io.Writer, employees []*Employee, progress *Progress, cancelled func() bool) (int, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, e := range employees {
+		if cancelled() {
+			break
+		}
+		data, err := json.MarshalIndent(toEmployeeJSON(e), "  ", "  ")
+		if err != nil {
+			return written, err
+		}
+		sep := ",\n  "
+		if written == 0 {
+			sep = "\n  "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s", sep, data); err != nil {
+			return written, err
+		}
+		written++
+		progress.Add(1)
+	}
+	if written > 0 {
+		_, err := io.WriteString(w, "\n]\n")
+		return written, err
+	}
+	_, err := io.WriteString(w, "]\n")
+	return written, err
+}
+
+// runExport implements the export command, which writes the employees of a
+// storage backend as JSON in the schema that migrate writes, or their
+// payroll by cost center as ledger CSV
+func runExport(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to export from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	out := fs.String("out", "", "file to write (default standard output)")
+	readOnly := fs.Bool("read-only", false, "open the store read-only, following the instance that writes to it")
+	fieldsPath := fs.String("fields", "", "JSON file of computed fields to include")
+	costCentersPath := fs.String("cost-centers", "", "JSON file of cost centers to split each salary between")
+	formatName := fs.String("format", "json", "output format (json, or ledger for payroll by cost center as CSV)")
+	entitiesPath := fs.String("entities", "", "JSON file of the group's legal entities")
+	entityName := fs.String("entity", "", "legal entity to export the employees of (default the whole group)")
+	location := fs.String("location", "", "export only the employees at this location")
+	workMode := fs.String("work-mode", "", "export only the employees with this work mode (on-site, hybrid or remote)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	if *formatName != "json" && *formatName != "ledger" {
+		return fmt.Errorf("%w: format must be json or ledger", ErrInvalidInput)
+	}
+	if *costCentersPath != "" {
+		m, err := LoadCostCenters(*costCentersPath)
+		if err != nil {
+			return err
+		}
+		costCenters = m
+	} else if *formatName == "ledger" {
+		return fmt.Errorf("%w: the ledger format needs -cost-centers", ErrInvalidInput)
+	}
+	if *fieldsPath != "" {
+		fields, err := LoadComputedFields(*fieldsPath)
+		if err != nil {
+			return err
+		}
+		computedFields = fields
+	}
+	entity, err := commandEntity(*entitiesPath, *entityName, false)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if *location != "" {
+		query.Set("location", *location)
+	}
+	if *workMode != "" {
+		query.Set("work_mode", *workMode)
+	}
+	matches, err := ParseEmployeeQuery(query, appClock.Now())
+	if err != nil {
+		return err
+	}
+
+	open := OpenStorage
+	if *readOnly {
+		open = OpenStorageReadOnly
+	}
+	store, err := open(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	employees, err := store.ListEmployees()
+	if err != nil {
+		return err
+	}
+	kept := employees[:0]
+	for _, e := range employees {
+		if (entity == nil || entity.Contains(e.ID)) && matches(e) {
+			kept = append(kept, e)
+		}
+	}
+	employees = kept
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout = f
+	}
+	if *formatName == "ledger" {
+		values := make([]Employee, len(employees))
+		for i, e := range employees {
+			values[i] = *e
+		}
+		return writeLedgerCSV(stdout, costCenters.Ledger(values))
+	}
+
+	// Ctrl-C stops exporting and keeps the employees written so far
+	ctx, stop := interruptContext()
+	defer stop()
+	progress := NewProgress(os.Stderr, "Exporting", len(employees))
+	written, err := writeEmployeesJSON(stdout, employees, progress, func() bool { return ctx.Err() != nil })
+	switch {
+	case err != nil:
+		progress.Done(err)
+		return err
+	case ctx.Err() != nil:
+		progress.Done(ErrCancelled)
+		return fmt.Errorf("%w: exported %d of %d employee(s)", ErrCancelled, written, len(employees))
+	}
+	progress.Done(nil)
+	return nil
+}
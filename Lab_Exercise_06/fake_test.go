@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Call is a call recorded by a FakeManager
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// String returns the call as it would be written in Go, such as GetEmployee(3)
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = fmt.Sprintf("%v", arg)
+	}
+	return c.Method + "(" + strings.Join(args, ", ") + ")"
+}
+
+// FakeManager is an EmployeeManager for testing code that uses one, such as
+// the HTTP handlers. It keeps employees in an in-memory manager on its own
+// clock, records every call, and returns scripted errors instead of calling
+// through when told to fail. FilterEmployees cannot fail, since it returns no
+// error. It is safe for concurrent use.
+type FakeManager struct {
+	store *InMemoryEmployeeManager
+
+	mu       sync.Mutex
+	calls    []Call
+	failures map[string][]error
+}
+
+// NewFakeManager creates a FakeManager holding the given employees. Its clock
+// starts at 2024-01-01 so join dates and experience are predictable.
+func NewFakeManager(employees ...*Employee) *FakeManager {
+	f := &FakeManager{
+		store:    NewInMemoryEmployeeManagerWithClock(NewFakeClock(dateOf(2024, 1, 1))),
+		failures: make(map[string][]error),
+	}
+	for _, e := range employees {
+		if err := f.store.AddEmployee(e); err != nil {
+			panic(fmt.Sprintf("fake manager: adding %s: %v", e.Name, err))
+		}
+	}
+	return f
+}
+
+// FailNext makes the next calls of a method return the given errors in turn,
+// without calling through. Later calls behave normally again.
+func (f *FakeManager) FailNext(method string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], errs...)
+}
+
+// Calls returns every call made so far, in order
+func (f *FakeManager) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls made so far to one method, in order
+func (f *FakeManager) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := make([]Call, 0)
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls and any scripted errors not yet returned
+func (f *FakeManager) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.failures = make(map[string][]error)
+}
+
+// Unwrap returns the in-memory manager holding the employees, so capabilities
+// such as Clock and ChangesSince are found through the fake
+func (f *FakeManager) Unwrap() EmployeeManager {
+	return f.store
+}
+
+// Subscribe registers a function that is called for every change
+func (f *FakeManager) Subscribe(fn func(Event)) {
+	f.store.Subscribe(fn)
+}
+
+// Close does nothing, so a FakeManager can stand in for a Storage backend
+func (f *FakeManager) Close() error {
+	return nil
+}
+
+// call records a call and returns the next scripted error for the method, if any
+func (f *FakeManager) call(method string, args ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+	if errs := f.failures[method]; len(errs) > 0 {
+		f.failures[method] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+// AddEmployee records the call and adds the employee unless scripted to fail
+func (f *FakeManager) AddEmployee(e *Employee) error {
+	if err := f.call("AddEmployee", e); err != nil {
+		return err
+	}
+	return f.store.AddEmployee(e)
+}
+
+// RemoveEmployee records the call and removes the employee unless scripted to fail
+func (f *FakeManager) RemoveEmployee(id int) error {
+	if err := f.call("RemoveEmployee", id); err != nil {
+		return err
+	}
+	return f.store.RemoveEmployee(id)
+}
+
+// UpdateEmployee records the call and updates the employee unless scripted to fail
+func (f *FakeManager) UpdateEmployee(e *Employee) error {
+	if err := f.call("UpdateEmployee", e); err != nil {
+		return err
+	}
+	return f.store.UpdateEmployee(e)
+}
+
+// GetEmployee records the call and retrieves the employee unless scripted to fail
+func (f *FakeManager) GetEmployee(id int) (*Employee, error) {
+	if err := f.call("GetEmployee", id); err != nil {
+		return nil, err
+	}
+	return f.store.GetEmployee(id)
+}
+
+// ListEmployees records the call and lists the employees unless scripted to fail
+func (f *FakeManager) ListEmployees() ([]*Employee, error) {
+	if err := f.call("ListEmployees"); err != nil {
+		return nil, err
+	}
+	return f.store.ListEmployees()
+}
+
+// FilterEmployees records the call and filters the employees
+func (f *FakeManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	f.call("FilterEmployees")
+	return f.store.FilterEmployees(filter)
+}
+
+// TransferEmployee records the call and transfers the employee unless scripted to fail
+func (f *FakeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if err := f.call("TransferEmployee", id, newDept, effectiveDate); err != nil {
+		return err
+	}
+	return f.store.TransferEmployee(id, newDept, effectiveDate)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// employeeFile is what the file backend stores: its schema version and the
+// change log of an InMemoryEmployeeManager
+type employeeFile struct {
+	Version int      `json:"version"`
+	Changes []Change `json:"changes"`
+}
+
+// fileMigrations upgrade an employee file one schema version at a time.
+// Files written before versioning have no version; migration 1 has nothing
+// to change in them.
+var fileMigrations = []Migration[*employeeFile]{
+	{Version: 1, Description: "change log of employees and transfers", Up: func(*employeeFile) error { return nil }},
+	{Version: 2, Description: "employee versions, for entity tags", Up: numberEmployeeVersions},
+}
+
+// numberEmployeeVersions gives the employees in a change log the versions
+// the manager would have given them: 1 when added, one more with each
+// update and transfer
+func numberEmployeeVersions(file *employeeFile) error {
+	versions := make(map[int]int)
+	for i := range file.Changes {
+		c := &file.Changes[i]
+		switch c.Type {
+		case EventEmployeeAdded:
+			versions[c.EmployeeID] = 1
+		case EventEmployeeUpdated, EventEmployeeTransferred:
+			versions[c.EmployeeID]++
+		}
+		c.Employee.Version = versions[c.EmployeeID]
+	}
+	return nil
+}
+
+// FileEmployeeManager is the file storage backend: an in-memory manager
+// whose change log is saved to a JSON file after every change and replayed
+// when the file is opened, so history, audits and time travel survive
+// restarts. The file is written under a temporary name and renamed, so it is
+// never left half written. It is read once, so only one process should
+// write to a file at a time.
+type FileEmployeeManager struct {
+	*InMemoryEmployeeManager
+	path   string
+	saveMu sync.Mutex // keeps saves in the order of the changes they save
+}
+
+// NewFileEmployeeManager opens the employee file at path, creating it on
+// the first change if it does not exist, and upgrades its schema
+func NewFileEmployeeManager(path string, clock Clock) (*FileEmployeeManager, error) {
+	return openEmployeeFile(path, clock, false)
+}
+
+// openEmployeeFile opens an employee file. Opened read-only, it is never
+// written, so its schema must already be the latest.
+func openEmployeeFile(path string, clock Clock, readOnly bool) (*FileEmployeeManager, error) {
+	m := &FileEmployeeManager{InMemoryEmployeeManager: NewInMemoryEmployeeManagerWithClock(clock), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var file employeeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading employees %s: %w", path, err)
+	}
+	versionRead := file.Version
+	if readOnly {
+		err = checkSchemaVersion("file", file.Version, latestVersion(fileMigrations))
+	} else {
+		err = applyMigrations("file", file.Version, fileMigrations, func(migration Migration[*employeeFile]) error {
+			if err := migration.Up(&file); err != nil {
+				return err
+			}
+			file.Version = migration.Version
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range file.Changes {
+		if c.Seq != i+1 || (c.Type == EventEmployeeTransferred && c.Transfer == nil) {
+			return nil, fmt.Errorf("reading employees %s: change %d of the log is damaged", path, i+1)
+		}
+		m.log = append(m.log, c)
+		m.state.apply(c)
+		// IDs of removed employees are not given out again, so they can be rehired
+		m.nextID = max(m.nextID, c.EmployeeID+1)
+	}
+	if !readOnly && len(data) > 0 && file.Version != versionRead {
+		if err := m.save(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// save writes the change log to the file
+func (m *FileEmployeeManager) save() error {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	// The log is only ever appended to, so the changes up to now stay as they are
+	m.mu.RLock()
+	changes := m.log
+	m.mu.RUnlock()
+
+	data, err := json.MarshalIndent(employeeFile{Version: latestVersion(fileMigrations), Changes: changes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := m.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, m.path)
+	})
+}
+
+// saved saves a change the in-memory manager has made. A change that
+// cannot be saved stays in memory and is saved with the next one.
+func (m *FileEmployeeManager) saved(err error) error {
+	if err != nil {
+		return err
+	}
+	if err := m.save(); err != nil {
+		return fmt.Errorf("the change was made but not saved to %s: %w", m.path, err)
+	}
+	return nil
+}
+
+// AddEmployee adds an employee and saves the file
+func (m *FileEmployeeManager) AddEmployee(e *Employee) error {
+	return m.saved(m.InMemoryEmployeeManager.AddEmployee(e))
+}
+
+// RemoveEmployee removes an employee and saves the file
+func (m *FileEmployeeManager) RemoveEmployee(id int) error {
+	return m.saved(m.InMemoryEmployeeManager.RemoveEmployee(id))
+}
+
+// UpdateEmployee updates an employee and saves the file
+func (m *FileEmployeeManager) UpdateEmployee(e *Employee) error {
+	return m.saved(m.InMemoryEmployeeManager.UpdateEmployee(e))
+}
+
+// TransferEmployee transfers an employee and saves the file
+func (m *FileEmployeeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	return m.saved(m.InMemoryEmployeeManager.TransferEmployee(id, newDept, effectiveDate))
+}
+
+func init() {
+	// The DSN is the path of the JSON file, e.g. -storage file -dsn employees.json
+	RegisterStorage("file", func(dsn string, clock Clock) (Storage, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("%w: the file backend needs a -dsn file path, such as employees.json", ErrInvalidInput)
+		}
+		return NewFileEmployeeManager(dsn, clock)
+	})
+
+	// Read-only instances read the file as it was when they started
+	RegisterReadOnlyStorage("file", func(dsn string, clock Clock) (Storage, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("%w: the file backend needs a -dsn file path, such as employees.json", ErrInvalidInput)
+		}
+		return openEmployeeFile(dsn, clock, true)
+	})
+}
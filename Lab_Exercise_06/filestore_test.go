@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStorageReopens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.json")
+	store, err := NewFileEmployeeManager(path, SystemClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ann := &Employee{Name: "Ann", Position: "Engineer", Salary: 90000, Department: Engineering, JoinDate: dateOf(2020, 3, 1)}
+	bob := &Employee{Name: "Bob", Position: "Analyst", Salary: 70000, Department: Finance, JoinDate: dateOf(2021, 6, 1)}
+	for _, e := range []*Employee{ann, bob} {
+		if err := store.AddEmployee(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.TransferEmployee(ann.ID, Operations, dateOf(2022, 1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveEmployee(bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileEmployeeManager(path, SystemClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.GetEmployee(ann.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Department != Operations || got.Salary != 90000 || !got.JoinDate.Equal(ann.JoinDate) {
+		t.Errorf("reopened %+v, want Ann in Operations", got)
+	}
+	if history := reopened.TransferHistory(ann.ID); len(history) != 1 {
+		t.Errorf("reopened transfer history has %d record(s), want 1", len(history))
+	}
+	if _, err := reopened.GetEmployee(bob.ID); err == nil {
+		t.Error("a removed employee is back after reopening")
+	}
+
+	// A removed employee's ID is not given to a new one
+	cy := &Employee{Name: "Cy", Position: "Clerk", Salary: 40000, Department: HR, JoinDate: dateOf(2023, 1, 1)}
+	if err := reopened.AddEmployee(cy); err != nil {
+		t.Fatal(err)
+	}
+	if cy.ID == bob.ID {
+		t.Errorf("the new employee was given ID %d, which the removed one had", cy.ID)
+	}
+}
+
+func TestFileStorageNumbersVersions(t *testing.T) {
+	// A version 1 file, written before employees had versions
+	path := filepath.Join(t.TempDir(), "employees.json")
+	data := `{"version": 1, "changes": [
+		{"Seq": 1, "Type": "employee.added", "EmployeeID": 1, "Employee": {"ID": 1, "Name": "Ann", "Salary": 90000, "Department": 1}},
+		{"Seq": 2, "Type": "employee.updated", "EmployeeID": 1, "Employee": {"ID": 1, "Name": "Ann", "Salary": 95000, "Department": 1}}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileEmployeeManager(path, SystemClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ann, err := store.GetEmployee(1); err != nil || ann.Version != 2 {
+		t.Errorf("Ann after an update has version %v (%v), want 2", ann, err)
+	}
+}
+
+func TestFileStorageRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "changes": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileEmployeeManager(path, SystemClock{}); err == nil {
+		t.Error("a file with a newer schema was opened")
+	}
+}
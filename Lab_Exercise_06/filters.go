@@ -0,0 +1,199 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Predicate reports whether a value matches a condition. Employee predicates
+// can be passed straight to FilterEmployees.
+type Predicate[T any] func(T) bool
+
+// And matches values that match every predicate. With no predicates it matches everything.
+func And[T any](predicates ...Predicate[T]) Predicate[T] {
+	return func(v T) bool {
+		for _, p := range predicates {
+			if !p(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches values that match any predicate. With no predicates it matches nothing.
+func Or[T any](predicates ...Predicate[T]) Predicate[T] {
+	return func(v T) bool {
+		for _, p := range predicates {
+			if p(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches values that do not match the predicate
+func Not[T any](p Predicate[T]) Predicate[T] {
+	return func(v T) bool {
+		return !p(v)
+	}
+}
+
+// Between matches values whose field lies within [min, max]
+func Between[V any, F cmp.Ordered](field func(V) F, min, max F) Predicate[V] {
+	return func(v V) bool {
+		f := field(v)
+		return f >= min && f <= max
+	}
+}
+
+// ByDepartment matches employees in a department
+func ByDepartment(dept Department) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.Department == dept
+	}
+}
+
+// NameContains matches employees whose name contains s, ignoring case
+func NameContains(s string) Predicate[*Employee] {
+	s = strings.ToLower(s)
+	return func(e *Employee) bool {
+		return strings.Contains(strings.ToLower(e.Name), s)
+	}
+}
+
+// AtLocation matches employees at a location, ignoring case
+func AtLocation(location string) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return strings.EqualFold(e.Location, location)
+	}
+}
+
+// ByWorkMode matches employees who work on-site, hybrid or remote
+func ByWorkMode(mode int) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.WorkMode == mode
+	}
+}
+
+// SalaryBetween matches employees whose salary lies within [min, max]
+func SalaryBetween(min, max float64) Predicate[*Employee] {
+	return Between(func(e *Employee) float64 { return e.Salary }, min, max)
+}
+
+// SalaryAtLeast matches employees earning at least min
+func SalaryAtLeast(min float64) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.Salary >= min
+	}
+}
+
+// SalaryAtMost matches employees earning at most max
+func SalaryAtMost(max float64) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.Salary <= max
+	}
+}
+
+// JoinedAfter matches employees who joined after t
+func JoinedAfter(t time.Time) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.JoinDate.After(t)
+	}
+}
+
+// JoinedBefore matches employees who joined before t
+func JoinedBefore(t time.Time) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.JoinDate.Before(t)
+	}
+}
+
+// MinExperience matches employees with at least the given years of experience at now
+func MinExperience(years float64, now time.Time) Predicate[*Employee] {
+	return func(e *Employee) bool {
+		return e.ExperienceAt(now) >= years
+	}
+}
+
+// ParseEmployeeQuery builds a predicate from URL query parameters, so API
+// clients can filter with the same predicates as the CLI. Recognised
+// parameters are q, a search in the syntax of ParseQuery, and name,
+// department, location, work_mode, min_salary, max_salary, joined_after,
+// joined_before, joined_from, joined_to and min_experience; all given
+// conditions must match. joined_from and joined_to include the date itself.
+func ParseEmployeeQuery(query url.Values, now time.Time) (Predicate[*Employee], error) {
+	predicates := make([]Predicate[*Employee], 0)
+
+	if q := query.Get("q"); q != "" {
+		p, err := ParseQuery(q, now)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+	}
+
+	if name := query.Get("name"); name != "" {
+		predicates = append(predicates, NameContains(name))
+	}
+	if dept := query.Get("department"); dept != "" {
+		d, err := ParseDepartment(dept)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown department %q", ErrInvalidInput, dept)
+		}
+		predicates = append(predicates, ByDepartment(d))
+	}
+	if location := query.Get("location"); location != "" {
+		predicates = append(predicates, AtLocation(location))
+	}
+	if mode := query.Get("work_mode"); mode != "" {
+		m, err := StringToWorkMode(mode)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, ByWorkMode(m))
+	}
+
+	for key, build := range map[string]func(float64) Predicate[*Employee]{
+		"min_salary": SalaryAtLeast,
+		"max_salary": SalaryAtMost,
+	} {
+		if v := query.Get(key); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidInput, key)
+			}
+			predicates = append(predicates, build(f))
+		}
+	}
+
+	for key, build := range map[string]func(time.Time) Predicate[*Employee]{
+		"joined_after":  JoinedAfter,
+		"joined_before": JoinedBefore,
+		"joined_from":   func(t time.Time) Predicate[*Employee] { return Not(JoinedBefore(t)) },
+		"joined_to":     func(t time.Time) Predicate[*Employee] { return Not(JoinedAfter(t)) },
+	} {
+		if v := query.Get(key); v != "" {
+			date, err := parseDate(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a date in YYYY-MM-DD format", ErrInvalidInput, key)
+			}
+			predicates = append(predicates, build(date))
+		}
+	}
+
+	if v := query.Get("min_experience"); v != "" {
+		years, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: min_experience must be a number of years", ErrInvalidInput)
+		}
+		predicates = append(predicates, MinExperience(years, now))
+	}
+
+	return And(predicates...), nil
+}
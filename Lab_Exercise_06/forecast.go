@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// monthLayout is how the months of a forecast are written and read
+const monthLayout = "2006-01"
+
+// maxForecastMonths is the longest forecast that can be made
+const maxForecastMonths = 60
+
+// PlannedHire is a hire a payroll forecast assumes, such as two Engineering
+// hires at 90000 joining in December
+type PlannedHire struct {
+	Month      time.Time // first day of the month they join
+	Department Department
+	Salary     float64
+	Count      int
+}
+
+// String returns the planned hire as parsePlannedHire reads it
+func (h PlannedHire) String() string {
+	return fmt.Sprintf("%s,%s,%.2f,%d", h.Month.In(userLocation).Format(monthLayout), h.Department, h.Salary, h.Count)
+}
+
+// parsePlannedHire parses a planned hire written as
+// YYYY-MM,department,salary[,count]; count defaults to 1
+func parsePlannedHire(s string) (PlannedHire, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 3 || len(parts) > 4 {
+		return PlannedHire{}, fmt.Errorf("%w: planned hire %q must be YYYY-MM,department,salary[,count]", ErrInvalidInput, s)
+	}
+	month, err := time.ParseInLocation(monthLayout, strings.TrimSpace(parts[0]), userLocation)
+	if err != nil {
+		return PlannedHire{}, fmt.Errorf("%w: planned hire month %q must be YYYY-MM", ErrInvalidInput, parts[0])
+	}
+	dept, err := ParseDepartment(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return PlannedHire{}, err
+	}
+	salary, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+	if err != nil || salary < 0 {
+		return PlannedHire{}, fmt.Errorf("%w: planned hire salary %q must be a number of at least 0", ErrInvalidInput, parts[2])
+	}
+	count := 1
+	if len(parts) == 4 {
+		if count, err = strconv.Atoi(strings.TrimSpace(parts[3])); err != nil || count < 1 {
+			return PlannedHire{}, fmt.Errorf("%w: planned hire count %q must be at least 1", ErrInvalidInput, parts[3])
+		}
+	}
+	return PlannedHire{Month: month.UTC(), Department: dept, Salary: salary, Count: count}, nil
+}
+
+// plannedHires are the planned hires of a forecast, set by repeating a flag
+type plannedHires []PlannedHire
+
+func (h *plannedHires) String() string {
+	if h == nil {
+		return ""
+	}
+	hires := make([]string, len(*h))
+	for i, hire := range *h {
+		hires[i] = hire.String()
+	}
+	return strings.Join(hires, " ")
+}
+
+// Set adds a planned hire. With String it makes *plannedHires a flag.Value.
+func (h *plannedHires) Set(s string) error {
+	hire, err := parsePlannedHire(s)
+	if err != nil {
+		return err
+	}
+	*h = append(*h, hire)
+	return nil
+}
+
+// contractEnds are the last days of employees' fixed-term contracts, by
+// employee ID, set by repeating a flag as id=YYYY-MM-DD
+type contractEnds map[int]time.Time
+
+func (c contractEnds) String() string {
+	ends := make([]string, 0, len(c))
+	for id, end := range c {
+		ends = append(ends, fmt.Sprintf("%d=%s", id, formatDate(end)))
+	}
+	sort.Strings(ends)
+	return strings.Join(ends, " ")
+}
+
+// Set adds a contract end. With String it makes contractEnds a flag.Value.
+func (c contractEnds) Set(s string) error {
+	idText, dateText, found := strings.Cut(s, "=")
+	id, err := strconv.Atoi(strings.TrimSpace(idText))
+	if !found || err != nil || id <= 0 {
+		return fmt.Errorf("%w: contract end %q must be id=YYYY-MM-DD", ErrInvalidInput, s)
+	}
+	end, err := parseDate(strings.TrimSpace(dateText))
+	if err != nil {
+		return fmt.Errorf("%w: contract end date %q must be YYYY-MM-DD", ErrInvalidInput, dateText)
+	}
+	c[id] = end
+	return nil
+}
+
+// ForecastAssumptions are what a payroll forecast projects from
+type ForecastAssumptions struct {
+	Months           int
+	AttritionPercent float64 // share of employees expected to leave in a year
+	IncrementPercent float64 // annual raise, given on each work anniversary
+	Hires            []PlannedHire
+	ContractEnds     map[int]time.Time // employees are paid through the month their contract ends
+}
+
+// ForecastMonth is the projected headcount and payroll of one month.
+// Attrition makes headcounts and leavers expected values, not whole people.
+type ForecastMonth struct {
+	Month      time.Time // first day of the month
+	Headcount  float64
+	Hires      int
+	Leavers    float64 // expected to leave through attrition by the end of the month
+	Ended      int     // contracts ending in the month
+	Payroll    float64
+	Cumulative float64 // payroll of this and the earlier months of the forecast
+	// ByCostCenter splits Payroll by cost center code, with "" for payroll
+	// not charged to any. It is nil if no cost centers are defined.
+	ByCostCenter map[string]float64
+}
+
+// ForecastPayroll projects the monthly payroll of the employees, starting
+// the month after now. Salaries are annual, so a month costs a twelfth.
+// Attrition is spread evenly over the months and applied to planned hires
+// from the month they join.
+func ForecastPayroll(employees []Employee, now time.Time, a ForecastAssumptions) ([]ForecastMonth, error) {
+	if a.Months < 1 || a.Months > maxForecastMonths {
+		return nil, fmt.Errorf("%w: a forecast covers 1 to %d months", ErrInvalidInput, maxForecastMonths)
+	}
+	if a.AttritionPercent < 0 || a.AttritionPercent >= 100 {
+		return nil, fmt.Errorf("%w: attrition must be at least 0%% and under 100%%", ErrInvalidInput)
+	}
+	if a.IncrementPercent <= -100 {
+		return nil, fmt.Errorf("%w: the annual increment must be more than -100%%", ErrInvalidInput)
+	}
+
+	local := now.In(userLocation)
+	monthStart := func(i int) time.Time { return dateOf(local.Year(), local.Month()+1+time.Month(i), 1) }
+	first, end := monthStart(0), monthStart(a.Months)
+	hireMonth := make([]int, len(a.Hires))
+	for i, h := range a.Hires {
+		if h.Month.Before(first) || !h.Month.Before(end) {
+			return nil, fmt.Errorf("%w: planned hire in %s is outside the forecast (%s to %s)", ErrInvalidInput,
+				h.Month.In(userLocation).Format(monthLayout), first.In(userLocation).Format(monthLayout),
+				monthStart(a.Months-1).In(userLocation).Format(monthLayout))
+		}
+		hm := h.Month.In(userLocation)
+		hireMonth[i] = (hm.Year()-first.In(userLocation).Year())*12 + int(hm.Month()-first.In(userLocation).Month())
+	}
+
+	stay := math.Pow(1-a.AttritionPercent/100, 1.0/12)
+	increment := 1 + a.IncrementPercent/100
+	months := make([]ForecastMonth, a.Months)
+	cumulative := 0.0
+	for i := range months {
+		start, next := monthStart(i), monthStart(i+1)
+		m := &months[i]
+		m.Month = start
+		if costCenters != nil {
+			m.ByCostCenter = make(map[string]float64)
+		}
+		for j := range employees {
+			e := &employees[j]
+			if ends, ok := a.ContractEnds[e.ID]; ok {
+				if ends.Before(start) {
+					continue
+				}
+				if ends.Before(next) {
+					m.Ended++
+				}
+			}
+			present := math.Pow(stay, float64(i))
+			m.Headcount += present
+			m.Leavers += present * (1 - stay)
+			pay := e.Salary * math.Pow(increment, float64(raisesBy(e.JoinDate, now, start))) / 12 * present
+			m.Payroll += pay
+			m.chargeCostCenters(e, pay)
+		}
+		for k, h := range a.Hires {
+			if hireMonth[k] > i {
+				continue
+			}
+			if hireMonth[k] == i {
+				m.Hires += h.Count
+			}
+			present := float64(h.Count) * math.Pow(stay, float64(i-hireMonth[k]))
+			m.Headcount += present
+			m.Leavers += present * (1 - stay)
+			m.Payroll += h.Salary / 12 * present
+			m.chargeCostCenters(&Employee{Department: h.Department}, h.Salary/12*present)
+		}
+		cumulative += m.Payroll
+		m.Cumulative = cumulative
+	}
+	return months, nil
+}
+
+// chargeCostCenters adds an employee's pay for the month to the cost
+// centers it is charged to
+func (m *ForecastMonth) chargeCostCenters(e *Employee, pay float64) {
+	if m.ByCostCenter == nil {
+		return
+	}
+	allocations := costCenters.Split(e)
+	if allocations == nil {
+		m.ByCostCenter[""] += pay
+	}
+	for _, a := range allocations {
+		m.ByCostCenter[a.CostCenter] += pay * a.Percent / 100
+	}
+}
+
+// raisesBy counts the work anniversaries of someone who joined on a date
+// that fall after now and on or before a later date
+func raisesBy(joined, now, at time.Time) int {
+	anniversary, _ := nextAnniversary(joined, now.In(userLocation).AddDate(0, 0, 1))
+	raises := 0
+	for !anniversary.After(at) {
+		raises++
+		anniversary = anniversaryIn(joined, anniversary.Year()+1, userLocation)
+	}
+	return raises
+}
+
+// writeForecast writes a forecast as a table, with the total payroll
+func writeForecast(w io.Writer, months []ForecastMonth) {
+	fmt.Fprintln(w, "\n=== Payroll Forecast ===")
+	fmt.Fprintf(w, "%-8s %10s %6s %8s %9s %14s %15s\n",
+		"Month", "Headcount", "Hires", "Leavers", "Contracts", "Payroll", "Cumulative")
+	fmt.Fprintln(w, strings.Repeat("-", 76))
+	for _, m := range months {
+		fmt.Fprintf(w, "%-8s %10.1f %6d %8.1f %9d %14.2f %15.2f\n",
+			m.Month.In(userLocation).Format(monthLayout), m.Headcount, m.Hires, m.Leavers, m.Ended, m.Payroll, m.Cumulative)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 76))
+	if len(months) > 0 {
+		fmt.Fprintf(w, "%-8s %67.2f\n", "Total", months[len(months)-1].Cumulative)
+	}
+}
+
+// writeForecastCSV writes a forecast as CSV with a header row. When cost
+// centers are defined, the monthly payroll of each follows in a column named
+// payroll_ and its code, with payroll_unallocated for payroll not charged to any.
+func writeForecastCSV(w io.Writer, months []ForecastMonth) error {
+	var codes []string
+	for _, m := range months {
+		for code := range m.ByCostCenter {
+			if !slices.Contains(codes, code) {
+				codes = append(codes, code)
+			}
+		}
+	}
+	sort.Strings(codes)
+	header := []string{"month", "headcount", "hires", "expected_leavers", "contracts_ending", "payroll", "cumulative_payroll"}
+	for _, code := range codes {
+		if code == "" {
+			code = "unallocated"
+		}
+		header = append(header, "payroll_"+code)
+	}
+
+	out := csv.NewWriter(w)
+	out.Write(header)
+	for _, m := range months {
+		record := []string{
+			m.Month.In(userLocation).Format(monthLayout),
+			strconv.FormatFloat(m.Headcount, 'f', 2, 64),
+			strconv.Itoa(m.Hires),
+			strconv.FormatFloat(m.Leavers, 'f', 2, 64),
+			strconv.Itoa(m.Ended),
+			strconv.FormatFloat(m.Payroll, 'f', 2, 64),
+			strconv.FormatFloat(m.Cumulative, 'f', 2, 64),
+		}
+		for _, code := range codes {
+			record = append(record, strconv.FormatFloat(m.ByCostCenter[code], 'f', 2, 64))
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// forecastInteractive asks for the assumptions of a payroll forecast, shows
+// it, and can save it as CSV
+func forecastInteractive(employees []Employee, now time.Time, reader *bufio.Reader) error {
+	a := ForecastAssumptions{Months: 12, ContractEnds: make(contractEnds)}
+	var err error
+	if a.AttritionPercent, err = readFloat(reader, "Annual attrition (%) [0]: "); err != nil {
+		return err
+	}
+	if a.IncrementPercent, err = readFloat(reader, "Annual increment on work anniversaries (%) [0]: "); err != nil {
+		return err
+	}
+	for {
+		hire, err := readValue(reader, "Planned hire (YYYY-MM,department,salary[,count]; blank when done): ", func(input string) (*PlannedHire, error) {
+			if input == "" {
+				return nil, nil
+			}
+			hire, err := parsePlannedHire(input)
+			return &hire, err
+		})
+		if err != nil {
+			return err
+		}
+		if hire == nil {
+			break
+		}
+		a.Hires = append(a.Hires, *hire)
+	}
+	for {
+		input, err := readValue(reader, "Contract end (id=YYYY-MM-DD; blank when done): ", func(input string) (string, error) {
+			if input == "" {
+				return "", nil
+			}
+			return input, contractEnds(a.ContractEnds).Set(input)
+		})
+		if err != nil {
+			return err
+		}
+		if input == "" {
+			break
+		}
+	}
+
+	months, err := ForecastPayroll(employees, now, a)
+	if err != nil {
+		return err
+	}
+	writeForecast(os.Stdout, months)
+
+	path, err := readString(reader, "\nSave as CSV (file path; blank to skip): ")
+	if err != nil || path == "" {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeForecastCSV(f, months)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Forecast saved to %s\n", path)
+	return nil
+}
+
+// runForecast implements the forecast command, which projects the payroll
+// of a storage backend's employees as a table or CSV
+func runForecast(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to read employees from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	months := fs.Int("months", 12, fmt.Sprintf("months to forecast (1 to %d)", maxForecastMonths))
+	attrition := fs.Float64("attrition", 0, "share of employees expected to leave in a year, in percent")
+	increment := fs.Float64("increment", 0, "annual raise given on each work anniversary, in percent")
+	var hires plannedHires
+	fs.Var(&hires, "hire", "planned hire as `YYYY-MM,department,salary[,count]`; repeat for more")
+	ends := make(contractEnds)
+	fs.Var(ends, "contract-end", "last day of an employee's contract as `id=YYYY-MM-DD`; repeat for more")
+	costCentersPath := fs.String("cost-centers", "", "JSON file of cost centers to break the CSV payroll down by")
+	formatName := fs.String("format", "table", "output format (table, csv)")
+	out := fs.String("out", "", "file to write (default standard output)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	if *formatName != "table" && *formatName != "csv" {
+		return fmt.Errorf("%w: format must be table or csv", ErrInvalidInput)
+	}
+	if *costCentersPath != "" {
+		var err error
+		if costCenters, err = LoadCostCenters(*costCentersPath); err != nil {
+			return err
+		}
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	employees, err := employeeValues(store)
+	if err != nil {
+		return err
+	}
+
+	forecast, err := ForecastPayroll(employees, appClock.Now(), ForecastAssumptions{
+		Months:           *months,
+		AttritionPercent: *attrition,
+		IncrementPercent: *increment,
+		Hires:            hires,
+		ContractEnds:     ends,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout = f
+	}
+	if *formatName == "csv" {
+		return writeForecastCSV(stdout, forecast)
+	}
+	writeForecast(stdout, forecast)
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The seed corpus of each fuzz test is in testdata/fuzz/<FuzzName>. go test
+// replays it, and go test -fuzz=FuzzName adds the inputs that fail there too.
+
+// FuzzParseDepartment checks that ParseDepartment only accepts the names of
+// departments, and that they convert back to the same department both by
+// name and through JSON
+func FuzzParseDepartment(f *testing.F) {
+	f.Fuzz(func(t *testing.T, name string) {
+		dept, err := ParseDepartment(name)
+		if err != nil {
+			if dept != -1 {
+				t.Fatalf("rejected %q but returned department %d", name, dept)
+			}
+			return
+		}
+
+		if !dept.Valid() {
+			t.Fatalf("accepted %q as unknown department %d", name, dept)
+		}
+		if again, err := ParseDepartment(dept.String()); err != nil || again != dept {
+			t.Fatalf("%q does not convert back to department %d", dept, dept)
+		}
+		var decoded Department
+		encoded, err := json.Marshal(dept)
+		if err != nil || json.Unmarshal(encoded, &decoded) != nil || decoded != dept {
+			t.Fatalf("department %d does not survive JSON encoding", dept)
+		}
+	})
+}
+
+// FuzzValidate checks that invalid employees are rejected without changing
+// the store, and that valid ones are stored as given
+func FuzzValidate(f *testing.F) {
+	f.Fuzz(func(t *testing.T, name string, salary float64) {
+		manager := NewInMemoryEmployeeManagerWithClock(SystemClock{})
+		e := &Employee{Name: name, Position: "Engineer", Salary: salary, Department: Engineering, JoinDate: dateOf(2020, 1, 1)}
+		err := manager.AddEmployee(e)
+
+		invalid := strings.TrimSpace(name) == "" || salary < 0 || math.IsNaN(salary) || math.IsInf(salary, 0)
+		employees, _ := manager.ListEmployees()
+		switch {
+		case invalid && err == nil:
+			t.Fatalf("accepted invalid employee %q with salary %v", name, salary)
+		case err != nil && len(employees) != 0:
+			t.Fatalf("rejected employee %q but stored %d employee(s)", name, len(employees))
+		case err != nil:
+			return
+		}
+
+		stored, err := manager.GetEmployee(e.ID)
+		if err != nil {
+			t.Fatalf("added employee %d cannot be retrieved: %v", e.ID, err)
+		}
+		if stored.Name != name || stored.Salary != salary || len(employees) != 1 {
+			t.Fatalf("stored %q with salary %v, want %q with salary %v", stored.Name, stored.Salary, name, salary)
+		}
+	})
+}
+
+// FuzzImportCSV checks that every row of a CSV file, mapped by the suggested
+// mapping, either gives a valid employee or is reported as invalid
+func FuzzImportCSV(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		records, err := parseCSV(data)
+		if err != nil {
+			return
+		}
+		header, rows, err := importTable("fuzz.csv", records)
+		if err != nil {
+			return
+		}
+
+		mapped := mapImportRows(SuggestImportMapping(header), header, rows)
+		if len(mapped) != len(rows) {
+			t.Fatalf("%d row(s) were mapped to %d", len(rows), len(mapped))
+		}
+		for _, r := range mapped {
+			if r.Err != nil {
+				if !errors.Is(r.Err, ErrInvalidInput) {
+					t.Fatalf("line %d failed with %v, want ErrInvalidInput", r.Line, r.Err)
+				}
+				continue
+			}
+			if err := validateEmployee(r.Employee, appClock.Now()); err != nil {
+				t.Fatalf("line %d gave invalid employee: %v", r.Line, err)
+			}
+		}
+	})
+}
+
+// FuzzMigrate checks that every decoded legacy record is either migrated
+// into a valid employee with a unique ID or reported as skipped
+func FuzzMigrate(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		records, err := decodeLegacyEmployees(data)
+		if err != nil {
+			return
+		}
+
+		employees, errs := MigrateEmployees(context.Background(), records, -1, dateOf(2020, 1, 1), NewDepartmentMapping(), nil)
+		if len(employees)+len(errs) != len(records) {
+			t.Fatalf("%d record(s) gave %d employee(s) and %d error(s)", len(records), len(employees), len(errs))
+		}
+
+		seen := make(map[int]bool)
+		for _, e := range employees {
+			if err := validateEmployee(e, appClock.Now()); err != nil {
+				t.Fatalf("migrated invalid employee %d: %v", e.ID, err)
+			}
+			if e.ID <= 0 || seen[e.ID] {
+				t.Fatalf("migrated employee with invalid or duplicate ID %d", e.ID)
+			}
+			seen[e.ID] = true
+		}
+	})
+}
+
+// FuzzQuery checks that any query string either fails to parse with
+// ErrInvalidInput or gives a predicate that can be applied to employees
+func FuzzQuery(f *testing.F) {
+	f.Fuzz(func(t *testing.T, raw string) {
+		query, err := url.ParseQuery(raw)
+		if err != nil {
+			return
+		}
+
+		predicate, err := ParseEmployeeQuery(query, appClock.Now())
+		if err != nil {
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("query %q failed with %v, want ErrInvalidInput", raw, err)
+			}
+			return
+		}
+
+		for i := 0; i < 10; i++ {
+			matched := predicate(benchEmployee(i))
+			if len(query) == 0 && !matched {
+				t.Fatalf("empty query does not match employee %d", i)
+			}
+		}
+	})
+}
+
+// FuzzAPI sends a request, written as a request line followed by a body, to
+// the HTTP API of a fake manager as an HR user, and checks that it never fails with a
+// server error. The fake never fails, so any 5xx is a handler bug.
+func FuzzAPI(f *testing.F) {
+	f.Fuzz(func(t *testing.T, request string) {
+		line, body, _ := strings.Cut(request, "\n")
+		method, target, _ := strings.Cut(line, " ")
+		if method == "" || !strings.HasPrefix(target, "/") {
+			return
+		}
+		r, err := http.NewRequest(method, "http://localhost"+target, strings.NewReader(body))
+		if err != nil {
+			return
+		}
+
+		manager := NewFakeManager(benchEmployee(1), benchEmployee(2), benchEmployee(3))
+		w := httptest.NewRecorder()
+		newTestServer(manager).ServeHTTP(w, as("ann", r))
+		if w.Code >= http.StatusInternalServerError {
+			t.Fatalf("%s %s returned %d: %s (calls: %v)", method, target, w.Code, strings.TrimSpace(w.Body.String()), manager.Calls())
+		}
+	})
+}
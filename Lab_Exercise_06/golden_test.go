@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goldenView renders one view of the CLI output from the golden fixture
+type goldenView struct {
+	name   string
+	render func(w io.Writer, f *goldenFixture) error
+}
+
+// goldenFixture is the fixed data the golden views are rendered from
+type goldenFixture struct {
+	manager   *InMemoryEmployeeManager
+	plan      *BudgetPlan
+	employees []Employee
+	start     time.Time // before the fixture's changes were made
+	now       time.Time
+}
+
+// newGoldenFixture builds the fixture on a fake clock in UTC, so the output
+// does not depend on when or where it is rendered
+func newGoldenFixture() (*goldenFixture, error) {
+	clock := NewFakeClock(dateOf(2024, 1, 1))
+	appClock = clock
+	if err := setUserLocation("UTC"); err != nil {
+		return nil, err
+	}
+
+	f := &goldenFixture{manager: NewInMemoryEmployeeManagerWithClock(clock), plan: NewBudgetPlan(), start: clock.Now()}
+	employees := []*Employee{
+		{Name: "John Doe", Position: "Software Engineer", Salary: 85000, Department: Engineering,
+			JoinDate: dateOf(2020, 5, 15), BirthDate: dateOf(1990, 7, 2)},
+		{Name: "Jane Smith", Position: "HR Manager", Salary: 75000, Department: HR, JoinDate: dateOf(2019, 3, 10)},
+		{Name: "Michael Johnson", Position: "Finance Director", Salary: 110000, Department: Finance, JoinDate: dateOf(2018, 1, 5)},
+		{Name: "Emily Williams", Position: "Marketing Specialist", Salary: 65000, Department: Marketing,
+			JoinDate: dateOf(2021, 8, 22), BirthDate: dateOf(1995, 12, 9)},
+		{Name: "Robert Brown", Position: "Operations Manager", Salary: 90000, Department: Operations, JoinDate: dateOf(2019, 11, 7)},
+		{Name: "Priya Sharma", Position: "Software Engineer", Salary: 92000, Department: Engineering,
+			JoinDate: dateOf(2023, 11, 1), ProbationEnd: dateOf(2024, 1, 30)},
+	}
+	if errs := AddMultipleEmployees(f.manager, employees...); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	// A few changes for the change history
+	clock.AdvanceDays(10)
+	raise := *employees[0]
+	raise.Salary, raise.Position = 95000, "Senior Software Engineer"
+	if err := f.manager.UpdateEmployee(&raise); err != nil {
+		return nil, err
+	}
+	clock.AdvanceDays(5)
+	if err := f.manager.TransferEmployee(employees[3].ID, Operations, clock.Now()); err != nil {
+		return nil, err
+	}
+
+	for _, b := range []DepartmentBudget{
+		{Department: Engineering, Headcount: 3, SalaryBudget: 250000},
+		{Department: Operations, Headcount: 1, SalaryBudget: 100000, Enforcement: EnforceReject},
+	} {
+		if err := f.plan.SetBudget(b); err != nil {
+			return nil, err
+		}
+	}
+
+	values, err := employeeValues(f.manager)
+	if err != nil {
+		return nil, err
+	}
+	sortEmployeeValues(values, OrderByID)
+	f.employees = values
+	f.now = clock.Now()
+	return f, nil
+}
+
+// goldenViews lists the views checked by TestGolden
+var goldenViews = []goldenView{
+	{"employee-list", func(w io.Writer, f *goldenFixture) error {
+		employees, err := f.manager.ListEmployees()
+		if err != nil {
+			return err
+		}
+		sortEmployees(employees, OrderByID)
+		writeEmployeeList(w, fmt.Sprintf("=== All Employees (%d) ===", len(employees)), employees)
+		return nil
+	}},
+	{"employee-table", func(w io.Writer, f *goldenFixture) error {
+		employees, err := f.manager.ListEmployees()
+		if err != nil {
+			return err
+		}
+		sortEmployees(employees, OrderByID)
+		columns, err := ParseColumns("id,name,position,department,salary,joined,probation_end")
+		if err != nil {
+			return err
+		}
+		writeEmployeeTable(w, columns, employees, false)
+		return nil
+	}},
+	{"employee-markdown", func(w io.Writer, f *goldenFixture) error {
+		employees, err := f.manager.ListEmployees()
+		if err != nil {
+			return err
+		}
+		sortEmployees(employees, OrderBySalary)
+		columns, err := ParseColumns("name,department,salary")
+		if err != nil {
+			return err
+		}
+		writeEmployeeTable(w, columns, employees, true)
+		return nil
+	}},
+	{"employee-detail", func(w io.Writer, f *goldenFixture) error {
+		e, err := f.manager.GetEmployee(6)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, e)
+		return nil
+	}},
+	{"salary-by-department", func(w io.Writer, f *goldenFixture) error {
+		writeSalaryStats(w, "Salary by Department", StatsByDepartment(f.employees, f.now))
+		return nil
+	}},
+	{"salary-by-position", func(w io.Writer, f *goldenFixture) error {
+		writeSalaryStats(w, "Salary by Position", StatsByPosition(f.employees, f.now))
+		return nil
+	}},
+	{"experience-by-department", func(w io.Writer, f *goldenFixture) error {
+		writeExperienceStats(w, "Experience by Department", StatsByDepartment(f.employees, f.now))
+		return nil
+	}},
+	{"headcount-chart", func(w io.Writer, f *goldenFixture) error {
+		writeHeadcountChart(w, "Headcount by Department", StatsByDepartment(f.employees, f.now))
+		return nil
+	}},
+	{"salary-histogram", func(w io.Writer, f *goldenFixture) error {
+		writeSalaryHistogram(w, "Salary Distribution", f.employees)
+		return nil
+	}},
+	{"budget-report", func(w io.Writer, f *goldenFixture) error {
+		writeBudgetReport(w, f.plan.Report(f.manager))
+		return nil
+	}},
+	{"change-history", func(w io.Writer, f *goldenFixture) error {
+		writeAuditTrail(w, f.start, f.manager.AuditTrail(f.start))
+		return nil
+	}},
+}
+
+// firstDifference describes the first line where got differs from want
+func firstDifference(got, want []byte) string {
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return fmt.Sprintf("line %d:\n  got:  %q\n  want: %q", i+1, g, w)
+		}
+	}
+	return "no difference"
+}
+
+// updateGolden rewrites the golden files, after an intended formatting
+// change: go test -run TestGolden -update, then review the changed files in
+// the diff
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/golden with the current output")
+
+// TestGolden renders the CLI's tables, reports and detail views from fixed
+// data and compares them with the files in testdata/golden
+func TestGolden(t *testing.T) {
+	clock, location := appClock, userLocation
+	t.Cleanup(func() { appClock, userLocation = clock, location })
+
+	fixture, err := newGoldenFixture()
+	if err != nil {
+		t.Fatalf("building golden fixture: %v", err)
+	}
+	dir := filepath.Join("testdata", "golden")
+
+	for _, view := range goldenViews {
+		t.Run(view.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := view.render(&got, fixture); err != nil {
+				t.Fatalf("rendering: %v", err)
+			}
+			path := filepath.Join(dir, view.name+".txt")
+
+			if *updateGolden {
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("output differs from %s at %s\nrerun with -update if the change is intended",
+					path, firstDifference(got.Bytes(), want))
+			}
+		})
+	}
+}
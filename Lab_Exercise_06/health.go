@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Readiness thresholds
+const (
+	healthCheckTimeout = 2 * time.Second
+	maxEventBacklog    = 100 // more events than this stuck in delivery means subscribers are hung
+)
+
+// checkJSON is the result of one readiness check
+type checkJSON struct {
+	Status string `json:"status"` // ok, degraded or failing
+	Error  string `json:"error,omitempty"`
+}
+
+// eventsCheckJSON is the result of the event-bus readiness check
+type eventsCheckJSON struct {
+	checkJSON
+	Backlog int `json:"backlog"`
+}
+
+// schedulerCheckJSON is the result of the scheduler readiness check
+type schedulerCheckJSON struct {
+	checkJSON
+	LastTick    string   `json:"last_tick,omitempty"`
+	FailingJobs []string `json:"failing_jobs,omitempty"`
+}
+
+// readinessJSON is the JSON representation of the readiness report
+type readinessJSON struct {
+	Status    string              `json:"status"` // ready or unready
+	Storage   checkJSON           `json:"storage"`
+	Cache     *checkJSON          `json:"cache,omitempty"`
+	Events    *eventsCheckJSON    `json:"events,omitempty"`
+	Scheduler *schedulerCheckJSON `json:"scheduler,omitempty"`
+}
+
+// checkResult converts an error into a check result, reporting a failure with
+// the given status
+func checkResult(err error, failure string) checkJSON {
+	if err != nil {
+		return checkJSON{Status: failure, Error: err.Error()}
+	}
+	return checkJSON{Status: "ok"}
+}
+
+// handleHealthz reports that the process is up and serving requests
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the server can handle traffic. Storage that
+// cannot be reached, a stuck event bus or a stalled scheduler make it unready;
+// an unreachable cache or failing jobs are reported as degraded, since the
+// API still works without them.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	report := readinessJSON{Status: "ready", Storage: checkJSON{Status: "ok"}}
+	fail := func(check *checkJSON) {
+		if check.Status == "failing" {
+			report.Status = "unready"
+		}
+	}
+
+	if db, ok := capability[interface{ Ping(context.Context) error }](s.manager); ok {
+		report.Storage = checkResult(db.Ping(ctx), "failing")
+		fail(&report.Storage)
+	}
+
+	if cache, ok := capability[interface{ PingCache() error }](s.manager); ok {
+		check := checkResult(cache.PingCache(), "degraded")
+		report.Cache = &check
+	}
+
+	if events, ok := capability[interface{ EventBacklog() int }](s.manager); ok {
+		backlog := events.EventBacklog()
+		check := &eventsCheckJSON{checkJSON: checkJSON{Status: "ok"}, Backlog: backlog}
+		if backlog > maxEventBacklog {
+			check.Status = "failing"
+			check.Error = "event subscribers are not keeping up"
+		}
+		report.Events = check
+		fail(&check.checkJSON)
+	}
+
+	if s.scheduler != nil {
+		check := &schedulerCheckJSON{checkJSON: checkJSON{Status: "ok"}}
+		lastTick := s.scheduler.LastTick()
+		if lastTick.IsZero() || time.Since(lastTick) > 2*schedulerTick {
+			check.Status = "failing"
+			check.Error = "scheduler has not checked for due jobs recently"
+		}
+		if !lastTick.IsZero() {
+			check.LastTick = lastTick.UTC().Format(time.RFC3339)
+		}
+		for _, job := range s.scheduler.Status() {
+			if job.LastError != "" {
+				check.FailingJobs = append(check.FailingJobs, job.Name)
+			}
+		}
+		if check.Status == "ok" && len(check.FailingJobs) > 0 {
+			check.Status = "degraded"
+		}
+		report.Scheduler = check
+		fail(&check.checkJSON)
+	}
+
+	status := http.StatusOK
+	if report.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
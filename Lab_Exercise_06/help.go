@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Command is a command run instead of the interactive menu, with what the
+// help shows about it. Its flags are found by asking it for its usage.
+type Command struct {
+	Summary     string
+	Args        string   // what follows the flags in its usage, if anything
+	Subcommands []string // subcommands with flags of their own and what follows them, such as "import BUNDLE"
+	Run         func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// withoutInput adapts a command that reads no standard input
+func withoutInput(run func(args []string, stdout, stderr io.Writer) error) func([]string, io.Reader, io.Writer, io.Writer) error {
+	return func(args []string, _ io.Reader, stdout, stderr io.Writer) error { return run(args, stdout, stderr) }
+}
+
+// menuOption is an option of the main menu, with its help topic
+type menuOption struct {
+	Name  string
+	Topic string
+	Help  string
+}
+
+// menuOptions are the options of the main menu, numbered from 1 in order
+var menuOptions = []menuOption{
+	{"Add Employee", "add-employee", "Add an employee, optionally starting from a position template that fills in the department, a salary in the band and the end of probation."},
+	{"View All Employees", "view-employees", "List every employee in the order chosen with -sort, laid out as -format and -columns choose."},
+	{"Update Employee", "update-employee", "Change an employee's name, position, department, salary or other fields. Rules and the approval policy may reject or queue the change."},
+	{"Remove Employee", "remove-employee", "Remove an employee, recording why they left for the attrition report. Assets they still hold are listed first."},
+	{"Search Employees", "search", "Search by field, such as department=Engineering salary>50000, or with a query. The last search is remembered in your preferences."},
+	{"Add Sample Data", "sample-data", "Add generated employees, for trying the system out."},
+	{"Transfer Employee", "transfer", "Move an employee to another department, with a new position and salary."},
+	{"Headcount Planning", "headcount", "Set each department's budget and compare it with actual payroll."},
+	{"Recruitment", "recruitment", "Track candidates from application through interviews and offers to hiring or rejection."},
+	{"Emergency Contacts & Dependents", "contacts", "Keep each employee's emergency contacts and dependents."},
+	{"Upcoming Anniversaries & Birthdays", "anniversaries", "List the work anniversaries and birthdays coming up."},
+	{"Advance Simulated Clock", "clock", "Move the clock started with -now forward, to see how dates play out."},
+	{"Change History", "history", "Show what changed between two dates, where the storage keeps a history."},
+	{"Reports", "reports", "Salary statistics, experience and headcount charts, computed fields, payroll forecasts, payroll by cost center, location and legal entity, and payroll runs."},
+	{"Approval Queue", "approvals", "Approve or reject the changes queued by -approve-salary-change and -approve-transfers."},
+	{"Switch Environment", "environments", "Switch between production, staging and a sandbox seeded from production."},
+	{"Watches", "watches", "Add, list and remove watches, which notify you when a search's count or salary crosses a threshold."},
+	{"Succession Planning", "succession", "Mark key roles and nominate their successors."},
+	{"Attrition", "attrition", "Report who left and why, by department and reason."},
+	{"Import Employees", "import-employees", "Add employees from a CSV or .xlsx file, mapping its columns to fields."},
+	{"Data Quality", "data-quality", "Find missing, inconsistent and suspicious data, as the lint command does."},
+	{"Compare Employees", "compare-employees", "Lay out employees side by side, highlighting the differences."},
+	{"What-If Simulation", "what-if", "Try raises, transfers, hires and removals on a copy, and see their effect on payroll and budgets."},
+	{"Bank Details & Payments", "bank", "Keep employees' bank accounts and write the payment file of a month's payroll."},
+	{"Loans & Advances", "loans", "Issue loans and salary advances, repaid by deductions from payroll."},
+	{"Company Assets", "assets", "Issue, return and write off the assets employees hold, and report those outstanding."},
+	{"Documents & Compliance", "documents", "Keep work permits, visas and certifications with their expiry dates, and report those expiring."},
+	{"Leave", "leave", "Request, approve and reject leave, set balances, and view public holidays and leave policies."},
+	{"Time & Attendance", "attendance", "Clock in and out, record or import hours, and report absences, lateness and overtime."},
+	{"Notifications", "notifications", "Choose which events notify you, on which channels and how often, and preview or send the weekly digest."},
+	{"Help", "help", "Read these help topics, or search them."},
+}
+
+// helpTopic is a page of the built-in help
+type helpTopic struct {
+	name, summary string
+	write         func(w io.Writer)
+}
+
+// helpProgram is the program's name in usage lines
+func helpProgram() string {
+	return filepath.Base(os.Args[0])
+}
+
+// helpTopics returns every help topic: an overview, the commands, the
+// menu options and the flags of the program. flags are the program's
+// flags, or nil where they are not defined.
+func helpTopics(flags *flag.FlagSet) []helpTopic {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	topics := []helpTopic{{"commands", "the commands run instead of the menu", func(w io.Writer) {
+		fmt.Fprintf(w, "Usage: %s COMMAND [flags]\n\n", helpProgram())
+		for _, name := range names {
+			fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].Summary)
+		}
+		fmt.Fprintf(w, "\nRun %s help COMMAND for the flags of one.\n", helpProgram())
+	}}, {"menu", "the options of the interactive menu", func(w io.Writer) {
+		for i, o := range menuOptions {
+			fmt.Fprintf(w, "%2d. %-36s help %s\n", i+1, o.Name, o.Topic)
+		}
+	}}}
+	if flags != nil {
+		topics = append(topics, helpTopic{"options", "the flags of the interactive menu and the API server", func(w io.Writer) {
+			fmt.Fprintf(w, "Usage: %s [flags]\n\n", helpProgram())
+			flags.SetOutput(w)
+			flags.PrintDefaults()
+			flags.SetOutput(nil)
+		}})
+	}
+
+	for _, name := range names {
+		name, c := name, commands[name]
+		topics = append(topics, helpTopic{name, c.Summary, func(w io.Writer) { writeCommandHelp(w, name, c) }})
+	}
+	for i, o := range menuOptions {
+		i, o := i, o
+		topics = append(topics, helpTopic{o.Topic, fmt.Sprintf("menu option %d, %s", i+1, o.Name), func(w io.Writer) {
+			fmt.Fprintf(w, "Menu option %d: %s\n\n%s\n", i+1, o.Name, o.Help)
+		}})
+	}
+	return topics
+}
+
+// writeCommandHelp writes a command's usage, asking the command for its flags
+func writeCommandHelp(w io.Writer, name string, c Command) {
+	fmt.Fprintf(w, "%s: %s\n", name, c.Summary)
+	usage := func(args []string, line string) {
+		var flags bytes.Buffer
+		c.Run(append(args, "-h"), strings.NewReader(""), io.Discard, &flags)
+		fmt.Fprintf(w, "\nUsage: %s %s\n", helpProgram(), line)
+		// Drop the usage line the flag package or the command starts with
+		text := flags.String()
+		if i := strings.IndexByte(text, '\n'); i >= 0 && strings.HasPrefix(strings.ToLower(text), "usage") {
+			text = text[i+1:]
+		}
+		fmt.Fprint(w, text)
+	}
+	if len(c.Subcommands) == 0 {
+		usage(nil, strings.TrimSpace(fmt.Sprintf("%s [flags] %s", name, c.Args)))
+		return
+	}
+	for _, sub := range c.Subcommands {
+		sub, args, _ := strings.Cut(sub, " ")
+		usage([]string{sub}, strings.TrimSpace(fmt.Sprintf("%s %s [flags] %s", name, sub, args)))
+	}
+}
+
+// writeHelp writes the help topic named by query, or when there is none,
+// the topics that mention every word of it. An empty query gives the index.
+func writeHelp(w io.Writer, topics []helpTopic, query string) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		fmt.Fprintf(w, "Usage: %s help TOPIC, or %s help WORDS to search\n\nTopics:\n", helpProgram(), helpProgram())
+		for _, t := range topics {
+			fmt.Fprintf(w, "  %-18s %s\n", t.name, t.summary)
+		}
+		return
+	}
+	for _, t := range topics {
+		if t.name == query {
+			t.write(w)
+			return
+		}
+	}
+
+	// Search the text of every topic, showing the lines that match
+	words := strings.Fields(query)
+	found := 0
+	for _, t := range topics {
+		var page strings.Builder
+		t.write(&page)
+		text := strings.ToLower(t.name + " " + t.summary + "\n" + page.String())
+		if !containsAll(text, words) {
+			continue
+		}
+		found++
+		fmt.Fprintf(w, "%s: %s\n", t.name, t.summary)
+		lines := strings.Split(page.String(), "\n")
+		for i, line := range lines {
+			// A flag's description is on the line after its name
+			if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") && i > 0 {
+				line = strings.TrimSpace(lines[i-1]) + "  " + strings.TrimSpace(line)
+			} else if i+1 < len(lines) && strings.HasPrefix(strings.TrimLeft(lines[i+1], " "), "\t") {
+				continue
+			}
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && trimmed != t.name+": "+t.summary && containsAny(strings.ToLower(line), words) {
+				fmt.Fprintf(w, "    %s\n", trimmed)
+			}
+		}
+	}
+	if found == 0 {
+		fmt.Fprintf(w, "No help topic mentions %q. Run %s help for the list of topics.\n", query, helpProgram())
+	}
+}
+
+// containsAll reports whether text contains every word
+func containsAll(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAny reports whether text contains any of the words
+func containsAny(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// runHelp runs the help command. main runs it once its flags are defined,
+// so the options topic can describe them.
+func runHelp(args []string, stdout io.Writer) {
+	writeHelp(stdout, helpTopics(flag.CommandLine), strings.Join(args, " "))
+}
+
+// helpInteractive asks for a help topic or words to search for and shows it
+func helpInteractive(reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Help ==="))
+	query, err := readString(reader, "Topic or words to search for (blank for the list of topics): ")
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	writeHelp(os.Stdout, helpTopics(flag.CommandLine), query)
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Holiday is a public holiday on which nobody works
+type Holiday struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+// Default working day of a holiday calendar
+const (
+	defaultWorkStart = "09:00"
+	defaultWorkHours = 8
+)
+
+// HolidayCalendar is the working week and the public holidays that leave is
+// counted against. A day of leave is only deducted for a working day.
+type HolidayCalendar struct {
+	Weekend   []string  `json:"weekend,omitempty"`    // days off every week; Saturday and Sunday if empty
+	WorkStart string    `json:"work_start,omitempty"` // when the working day starts, HH:MM; 09:00 if empty
+	WorkHours float64   `json:"work_hours,omitempty"` // hours in a working day; 8 if 0
+	Holidays  []Holiday `json:"holidays"`
+
+	weekend  map[time.Weekday]bool
+	start    time.Duration     // WorkStart after midnight
+	holidays map[string]string // holiday names by date
+}
+
+// holidayCalendar is the calendar leave is counted against. main sets it
+// from the -holidays flag; without one, only weekends are days off.
+var holidayCalendar = &HolidayCalendar{
+	WorkStart: defaultWorkStart,
+	WorkHours: defaultWorkHours,
+	weekend:   map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+	start:     9 * time.Hour,
+}
+
+// LoadHolidayCalendar reads and checks a holiday calendar file
+func LoadHolidayCalendar(path string) (*HolidayCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseHolidayCalendar(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParseHolidayCalendar reads and checks a holiday calendar in JSON
+func ParseHolidayCalendar(data []byte) (*HolidayCalendar, error) {
+	var c HolidayCalendar
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if len(c.Weekend) == 0 {
+		c.Weekend = []string{"saturday", "sunday"}
+	}
+	c.weekend = make(map[time.Weekday]bool, len(c.Weekend))
+	for _, name := range c.Weekend {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		c.weekend[day] = true
+	}
+	if len(c.weekend) == 7 {
+		return nil, fmt.Errorf("%w: the weekend cannot be every day of the week", ErrInvalidInput)
+	}
+
+	if c.WorkStart == "" {
+		c.WorkStart = defaultWorkStart
+	}
+	start, err := time.Parse("15:04", c.WorkStart)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the working day must start at a time such as 09:00, not %q", ErrInvalidInput, c.WorkStart)
+	}
+	c.start = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	if c.WorkHours == 0 {
+		c.WorkHours = defaultWorkHours
+	}
+	if c.WorkHours < 0 || c.WorkHours > 24 {
+		return nil, fmt.Errorf("%w: a working day cannot have %.1f hours", ErrInvalidInput, c.WorkHours)
+	}
+
+	c.holidays = make(map[string]string, len(c.Holidays))
+	for i, h := range c.Holidays {
+		date, err := parseDate(h.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: holiday %q has an invalid date %q", ErrInvalidInput, h.Name, h.Date)
+		}
+		key := formatDate(date)
+		if _, ok := c.holidays[key]; ok {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidInput, key)
+		}
+		c.holidays[key] = h.Name
+		c.Holidays[i].Date = key
+	}
+	sort.Slice(c.Holidays, func(i, j int) bool { return c.Holidays[i].Date < c.Holidays[j].Date })
+	return &c, nil
+}
+
+// parseWeekday converts the English name of a day of the week, or its first
+// three letters, to a time.Weekday
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown day of the week %q", ErrInvalidInput, name)
+}
+
+// HolidayOn returns the name of the public holiday on a day, if it is one
+func (c *HolidayCalendar) HolidayOn(day time.Time) (string, bool) {
+	name, ok := c.holidays[formatDate(day)]
+	return name, ok
+}
+
+// IsWorkingDay reports whether a day is neither a weekend day nor a public holiday
+func (c *HolidayCalendar) IsWorkingDay(day time.Time) bool {
+	if c.weekend[day.In(userLocation).Weekday()] {
+		return false
+	}
+	_, holiday := c.HolidayOn(day)
+	return !holiday
+}
+
+// Between returns the public holidays from one day to another, both included
+func (c *HolidayCalendar) Between(from, to time.Time) []Holiday {
+	first, last := formatDate(from), formatDate(to)
+	var list []Holiday
+	for _, h := range c.Holidays {
+		if h.Date >= first && h.Date <= last {
+			list = append(list, h)
+		}
+	}
+	return list
+}
+
+// writeHolidays writes the working week and the public holidays of a year
+func writeHolidays(w io.Writer, c *HolidayCalendar, year int) {
+	var weekend []string
+	for i := 1; i <= 7; i++ {
+		if day := time.Weekday(i % 7); c.weekend[day] { // Monday first
+			weekend = append(weekend, day.String())
+		}
+	}
+	fmt.Fprintf(w, "\n=== Public Holidays %d ===\n", year)
+	fmt.Fprintf(w, "Weekend: %s\n", strings.Join(weekend, ", "))
+	fmt.Fprintf(w, "Working day: %.1f hours from %s\n\n", c.WorkHours, c.WorkStart)
+
+	list := c.Between(dateOf(year, time.January, 1), dateOf(year, time.December, 31))
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No public holidays in the calendar.")
+		return
+	}
+	for _, h := range list {
+		date, _ := parseDate(h.Date)
+		fmt.Fprintf(w, "%s  %-9s  %s\n", h.Date, date.In(userLocation).Weekday(), h.Name)
+	}
+}
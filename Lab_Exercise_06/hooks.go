@@ -0,0 +1,184 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// HookedManager decorates an EmployeeManager with lifecycle hooks, so
+// cross-cutting concerns such as validation, auditing, notifications and
+// cache invalidation can be layered onto any manager without changing it.
+//
+// Before hooks run in the order they were registered, before the change is
+// passed on; the first to return an error stops the change and its error is
+// returned. After hooks run in order once the change has succeeded. Transfers
+// are updates that change the department. Hooks must not make changes
+// through the same HookedManager.
+type HookedManager struct {
+	EmployeeManager
+	mu           sync.RWMutex
+	beforeAdd    []func(e *Employee) error
+	afterAdd     []func(e *Employee)
+	beforeUpdate []func(old, new *Employee) error
+	afterUpdate  []func(old, new *Employee)
+	beforeRemove []func(e *Employee) error
+	afterRemove  []func(e *Employee)
+}
+
+// NewHookedManager wraps a manager with no hooks
+func NewHookedManager(inner EmployeeManager) *HookedManager {
+	return &HookedManager{EmployeeManager: inner}
+}
+
+// Unwrap returns the wrapped manager
+func (h *HookedManager) Unwrap() EmployeeManager {
+	return h.EmployeeManager
+}
+
+// BeforeAdd registers a hook run before an employee is added. Changes it
+// makes to the employee are kept.
+func (h *HookedManager) BeforeAdd(fn func(e *Employee) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeAdd = append(h.beforeAdd, fn)
+}
+
+// AfterAdd registers a hook run after an employee is added, with the ID it was given
+func (h *HookedManager) AfterAdd(fn func(e *Employee)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterAdd = append(h.afterAdd, fn)
+}
+
+// BeforeUpdate registers a hook run before an employee is updated or
+// transferred, with the employee as stored and as it is about to be. Changes
+// it makes to an update are kept; a transfer only changes the department.
+func (h *HookedManager) BeforeUpdate(fn func(old, new *Employee) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeUpdate = append(h.beforeUpdate, fn)
+}
+
+// AfterUpdate registers a hook run after an employee is updated or
+// transferred, with the employee as it was and as it is now
+func (h *HookedManager) AfterUpdate(fn func(old, new *Employee)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterUpdate = append(h.afterUpdate, fn)
+}
+
+// BeforeRemove registers a hook run before an employee is removed
+func (h *HookedManager) BeforeRemove(fn func(e *Employee) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeRemove = append(h.beforeRemove, fn)
+}
+
+// AfterRemove registers a hook run after an employee is removed, with the
+// employee as it was
+func (h *HookedManager) AfterRemove(fn func(e *Employee)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterRemove = append(h.afterRemove, fn)
+}
+
+// registered returns a copy of a list of hooks, so hooks can be registered
+// while others run
+func registered[T any](h *HookedManager, hooks *[]T) []T {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]T(nil), *hooks...)
+}
+
+// AddEmployee adds an employee between the add hooks
+func (h *HookedManager) AddEmployee(e *Employee) error {
+	if e == nil {
+		return h.EmployeeManager.AddEmployee(e)
+	}
+	for _, fn := range registered(h, &h.beforeAdd) {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := h.EmployeeManager.AddEmployee(e); err != nil {
+		return err
+	}
+	for _, fn := range registered(h, &h.afterAdd) {
+		fn(e)
+	}
+	return nil
+}
+
+// UpdateEmployee updates an employee between the update hooks
+func (h *HookedManager) UpdateEmployee(e *Employee) error {
+	before, after := registered(h, &h.beforeUpdate), registered(h, &h.afterUpdate)
+	if e == nil || (len(before) == 0 && len(after) == 0) {
+		return h.EmployeeManager.UpdateEmployee(e)
+	}
+	old, err := h.EmployeeManager.GetEmployee(e.ID)
+	if err != nil {
+		return err
+	}
+	for _, fn := range before {
+		if err := fn(old, e); err != nil {
+			return err
+		}
+	}
+	if err := h.EmployeeManager.UpdateEmployee(e); err != nil {
+		return err
+	}
+	for _, fn := range after {
+		fn(old, e)
+	}
+	return nil
+}
+
+// TransferEmployee transfers an employee between the update hooks
+func (h *HookedManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	before, after := registered(h, &h.beforeUpdate), registered(h, &h.afterUpdate)
+	if len(before) == 0 && len(after) == 0 {
+		return h.EmployeeManager.TransferEmployee(id, newDept, effectiveDate)
+	}
+	old, err := h.EmployeeManager.GetEmployee(id)
+	if err != nil {
+		return err
+	}
+	proposed := *old
+	proposed.Department = newDept
+	for _, fn := range before {
+		if err := fn(old, &proposed); err != nil {
+			return err
+		}
+	}
+	if err := h.EmployeeManager.TransferEmployee(id, newDept, effectiveDate); err != nil {
+		return err
+	}
+	for _, fn := range after {
+		fn(old, &proposed)
+	}
+	return nil
+}
+
+// RemoveEmployee removes an employee between the remove hooks
+func (h *HookedManager) RemoveEmployee(id int) error {
+	before, after := registered(h, &h.beforeRemove), registered(h, &h.afterRemove)
+	if len(before) == 0 && len(after) == 0 {
+		return h.EmployeeManager.RemoveEmployee(id)
+	}
+	old, err := h.EmployeeManager.GetEmployee(id)
+	if err != nil {
+		return err
+	}
+	for _, fn := range before {
+		if err := fn(old); err != nil {
+			return err
+		}
+	}
+	if err := h.EmployeeManager.RemoveEmployee(id); err != nil {
+		return err
+	}
+	for _, fn := range after {
+		fn(old)
+	}
+	return nil
+}
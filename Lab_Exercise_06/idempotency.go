@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotencyHeader carries the key a client sends with a mutation so that
+// retrying it does not apply it twice
+const idempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKey is the longest idempotency key accepted
+const maxIdempotencyKey = 255
+
+// idempotencyTTL is how long the response to a key is kept for replay
+const idempotencyTTL = 24 * time.Hour
+
+// idempotentResponse is the outcome of the request that first used a key
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte // of the method, path and body
+	done        bool              // false while the first request is in progress
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// IdempotencyKeys remembers the responses to mutations by their idempotency
+// key. A retry with the same key and request gets the first response again
+// instead of being applied twice. Keys are kept in memory, so a retry must
+// reach the same instance.
+type IdempotencyKeys struct {
+	mu        sync.Mutex
+	clock     Clock
+	ttl       time.Duration
+	responses map[string]*idempotentResponse
+}
+
+// NewIdempotencyKeys creates an empty set of keys, each kept for ttl
+func NewIdempotencyKeys(clock Clock, ttl time.Duration) *IdempotencyKeys {
+	return &IdempotencyKeys{clock: clock, ttl: ttl, responses: make(map[string]*idempotentResponse)}
+}
+
+// begin claims a key for a request. It returns true if the request should
+// run, or false with a copy of the key's response when it has been used.
+func (k *IdempotencyKeys) begin(key string, fingerprint [sha256.Size]byte) (idempotentResponse, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.clock.Now()
+	for used, resp := range k.responses {
+		if now.After(resp.expires) {
+			delete(k.responses, used)
+		}
+	}
+	if resp, ok := k.responses[key]; ok {
+		return *resp, false
+	}
+	k.responses[key] = &idempotentResponse{fingerprint: fingerprint, expires: now.Add(k.ttl)}
+	return idempotentResponse{}, true
+}
+
+// finish records the response to a claimed key. Server errors are not
+// recorded, so a retry runs the request again.
+func (k *IdempotencyKeys) finish(key string, rec *responseRecorder) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if rec.status >= http.StatusInternalServerError {
+		delete(k.responses, key)
+		return
+	}
+	resp := k.responses[key]
+	resp.done = true
+	resp.status = rec.status
+	resp.header = rec.Header().Clone()
+	resp.body = rec.body.Bytes()
+	resp.expires = k.clock.Now().Add(k.ttl)
+}
+
+// responseRecorder captures the response written by a handler while passing
+// it through
+type responseRecorder struct {
+	statusRecorder
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Wrap makes a mutation idempotent for requests that carry an idempotency
+// key. A retry replays the first response; reusing a key for a different
+// request fails with 422, and retrying while the first request is still in
+// progress fails with 409.
+func (k *IdempotencyKeys) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeError(w, r, fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidInput, idempotencyHeader, maxIdempotencyKey))
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: reading the request: %v", ErrInvalidInput, err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.Sum256([]byte(r.Method + " " + r.URL.Path + "\n" + string(body)))
+		resp, fresh := k.begin(key, fingerprint)
+		switch {
+		case fresh:
+			rec := &responseRecorder{statusRecorder: statusRecorder{ResponseWriter: w, status: http.StatusOK}}
+			next(rec, r)
+			k.finish(key, rec)
+		case resp.fingerprint != fingerprint:
+			writeAPIError(w, r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, idempotencyHeader+" was already used for a different request")
+		case !resp.done:
+			writeAPIError(w, r, http.StatusConflict, CodeRequestInProgress, "a request with this "+idempotencyHeader+" is in progress")
+		default:
+			for name, values := range resp.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(resp.status)
+			w.Write(resp.body)
+		}
+	}
+}
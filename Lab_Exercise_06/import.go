@@ -0,0 +1,868 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importField is an employee field that can be read from a column
+type importField struct {
+	Name     string   // key in the mapping file
+	Label    string   // shown by the wizard
+	Synonyms []string // column headers suggested for the field, normalized
+}
+
+// importFields lists the fields in the order the wizard asks for them
+var importFields = []importField{
+	{"id", "Employee ID", []string{"id", "employeeid", "empid", "employeenumber", "staffid"}},
+	{"name", "Name", []string{"name", "fullname", "employeename", "employee"}},
+	{"position", "Position", []string{"position", "title", "jobtitle", "role", "job"}},
+	{"salary", "Salary", []string{"salary", "annualsalary", "annualpay", "pay", "basesalary", "basepay", "compensation"}},
+	{"department", "Department", []string{"department", "dept", "team", "division"}},
+	{"join_date", "Join date", []string{"joindate", "joined", "startdate", "hiredate", "dateofjoining"}},
+	{"birth_date", "Birth date", []string{"birthdate", "dob", "dateofbirth", "birthday"}},
+	{"location", "Location", []string{"location", "office", "site", "city", "worklocation"}},
+	{"work_mode", "Work mode", []string{"workmode", "remote", "worktype", "workarrangement"}},
+	{"time_zone", "Time zone", []string{"timezone", "tz", "zone"}},
+	{"last_updated", "Last updated", []string{"lastupdated", "updated", "updatedat", "lastmodified", "modified"}},
+}
+
+// ImportMapping says which column of a file holds each employee field, the
+// value of fields without a column, and how dates are written. It is saved as
+// JSON so the same layout can be imported again without the wizard:
+//
+//	{"columns": {"name": "Full Name", "salary": "Annual Pay"},
+//	 "defaults": {"department": "Engineering", "join_date": "today"},
+//	 "date_format": "DD/MM/YYYY"}
+type ImportMapping struct {
+	Columns    map[string]string `json:"columns"`
+	Defaults   map[string]string `json:"defaults,omitempty"`
+	DateFormat string            `json:"date_format,omitempty"` // YYYY, MM and DD, as in DD/MM/YYYY
+}
+
+// LoadImportMapping reads a mapping file and checks that it only names known fields
+func LoadImportMapping(path string) (*ImportMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &ImportMapping{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%w: reading mapping %s: %v", ErrInvalidInput, path, err)
+	}
+	for _, fields := range []map[string]string{m.Columns, m.Defaults} {
+		for name := range fields {
+			if !isImportField(name) {
+				return nil, fmt.Errorf("%w: unknown field %q in mapping %s", ErrInvalidInput, name, path)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Save writes the mapping to a file
+func (m *ImportMapping) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func isImportField(name string) bool {
+	for _, f := range importFields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHeader lowercases a column header and drops everything but letters and digits
+func normalizeHeader(header string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(header) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// SuggestImportMapping maps each field to the first column whose header is one
+// of the field's synonyms
+func SuggestImportMapping(header []string) *ImportMapping {
+	m := &ImportMapping{Columns: make(map[string]string)}
+	used := make(map[int]bool)
+	for _, f := range importFields {
+		for i, h := range header {
+			if used[i] {
+				continue
+			}
+			for _, synonym := range f.Synonyms {
+				if normalizeHeader(h) == synonym {
+					m.Columns[f.Name] = h
+					used[i] = true
+					break
+				}
+			}
+			if _, ok := m.Columns[f.Name]; ok {
+				break
+			}
+		}
+	}
+	return m
+}
+
+// dateLayoutOf converts a date format such as DD/MM/YYYY to a Go time layout
+func dateLayoutOf(format string) string {
+	return strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(strings.ToUpper(format))
+}
+
+// importDateFormats are the date formats the wizard tries on the data
+var importDateFormats = []string{"YYYY-MM-DD", "DD/MM/YYYY", "MM/DD/YYYY", "DD.MM.YYYY", "DD-MM-YYYY", "YYYY/MM/DD"}
+
+// excelEpoch is day 0 of spreadsheet date serial numbers
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// coerceDate parses a date in the mapping's format, as YYYY-MM-DD or RFC 3339,
+// or as a spreadsheet serial number. "today" is the current date.
+func (m *ImportMapping) coerceDate(value string) (time.Time, error) {
+	if strings.EqualFold(value, "today") {
+		return today(), nil
+	}
+	if m.DateFormat != "" {
+		if date, err := time.ParseInLocation(dateLayoutOf(m.DateFormat), value, userLocation); err == nil {
+			return date.UTC(), nil
+		}
+	}
+	if date, err := parseLegacyDate(value); err == nil {
+		return date, nil
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 1 && serial < 100000 {
+		y, mo, d := excelEpoch.AddDate(0, 0, int(serial)).Date()
+		return dateOf(y, mo, d), nil
+	}
+	format := m.DateFormat
+	if format == "" {
+		format = "YYYY-MM-DD"
+	}
+	return time.Time{}, fmt.Errorf("%w: %q is not a date in %s format", ErrInvalidInput, value, format)
+}
+
+// coerceSalary parses an amount such as $85,000, 85 000 or 85k
+func coerceSalary(value string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case '$', '€', '£', '¥', '₹', ',', ' ', ' ', '\'':
+			return -1
+		}
+		return r
+	}, value)
+	multiplier := 1.0
+	if strings.HasSuffix(strings.ToLower(cleaned), "k") {
+		cleaned, multiplier = cleaned[:len(cleaned)-1], 1000
+	}
+	salary, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil || math.IsNaN(salary) || math.IsInf(salary, 0) {
+		return 0, fmt.Errorf("%w: %q is not an amount", ErrInvalidInput, value)
+	}
+	return salary * multiplier, nil
+}
+
+// departmentAliases are other names departments are often exported under
+var departmentAliases = map[string]Department{
+	"humanresources": HR,
+	"people":         HR,
+	"eng":            Engineering,
+	"tech":           Engineering,
+	"fin":            Finance,
+	"accounting":     Finance,
+	"mkt":            Marketing,
+	"mktg":           Marketing,
+	"ops":            Operations,
+}
+
+// coerceDepartment converts a department name, alias or constant to the constant
+func coerceDepartment(value string) (Department, error) {
+	if dept, err := ParseDepartment(value); err == nil {
+		return dept, nil
+	}
+	if dept, ok := departmentAliases[normalizeHeader(value)]; ok {
+		return dept, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && Department(n).Valid() {
+		return Department(n), nil
+	}
+	return -1, fmt.Errorf("%w: unknown department %q", ErrInvalidInput, value)
+}
+
+// cell returns a field's value in a row, or its default value when the
+// field has no column or the cell is empty
+func (m *ImportMapping) cell(header, row []string, f importField) (string, error) {
+	value := ""
+	if column, ok := m.Columns[f.Name]; ok {
+		i := slices.Index(header, column)
+		if i < 0 {
+			return "", fmt.Errorf("%w: the file has no column %q for %s", ErrInvalidInput, column, f.Label)
+		}
+		if i < len(row) {
+			value = strings.TrimSpace(row[i])
+		}
+	}
+	if value == "" {
+		value = strings.TrimSpace(m.Defaults[f.Name])
+	}
+	return value, nil
+}
+
+// Employee converts a row into an employee, coercing each value to the
+// field's type. Empty cells take the field's default value, except the
+// department and work mode, which are -1 so a sync can tell them from a
+// value in the file.
+func (m *ImportMapping) Employee(header, row []string) (*Employee, error) {
+	e := &Employee{Department: -1, WorkMode: -1}
+	for _, f := range importFields {
+		value, err := m.cell(header, row, f)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			continue
+		}
+
+		switch f.Name {
+		case "id":
+			e.ID, err = strconv.Atoi(value)
+			if err != nil {
+				err = fmt.Errorf("%w: %q is not an employee ID", ErrInvalidInput, value)
+			}
+		case "name":
+			e.Name = value
+		case "position":
+			e.Position = value
+		case "salary":
+			e.Salary, err = coerceSalary(value)
+		case "department":
+			e.Department, err = coerceDepartment(value)
+		case "join_date":
+			e.JoinDate, err = m.coerceDate(value)
+		case "birth_date":
+			e.BirthDate, err = m.coerceDate(value)
+		case "location":
+			e.Location = value
+		case "work_mode":
+			e.WorkMode, err = StringToWorkMode(value)
+		case "time_zone":
+			e.TimeZone, err = parseTimeZone(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Label, err)
+		}
+	}
+	return e, nil
+}
+
+// LastUpdated returns when the source system last changed a row, or the zero
+// time when the file does not say. It may include a time of day.
+func (m *ImportMapping) LastUpdated(header, row []string) (time.Time, error) {
+	for _, f := range importFields {
+		if f.Name != "last_updated" {
+			continue
+		}
+		value, err := m.cell(header, row, f)
+		if err != nil || value == "" {
+			return time.Time{}, err
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+			if t, err := time.ParseInLocation(layout, value, userLocation); err == nil {
+				return t, nil
+			}
+		}
+		t, err := m.coerceDate(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", f.Label, err)
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+// readImportTable reads the header and rows of a CSV or .xlsx file. The first
+// row is the header; blank rows are skipped.
+func readImportTable(path string) ([]string, [][]string, error) {
+	var records [][]string
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		records, err = readXLSX(path)
+	} else {
+		records, err = readCSV(path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return importTable(path, records)
+}
+
+// importTable splits the records read from a file into its header and rows,
+// skipping blank rows
+func importTable(path string, records [][]string) ([]string, [][]string, error) {
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		if strings.TrimSpace(strings.Join(record, "")) != "" {
+			rows = append(rows, record)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s has no header row", ErrInvalidInput, path)
+	}
+	header := rows[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	return header, rows[1:], nil
+}
+
+// readCSV reads a CSV file with parseCSV
+func readCSV(path string) ([][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseCSV(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", ErrInvalidInput, path, err)
+	}
+	return records, nil
+}
+
+// parseCSV parses CSV data, separated by commas, semicolons or tabs,
+// whichever the header line uses most
+func parseCSV(data []byte) ([][]string, error) {
+	first, _, _ := strings.Cut(string(data), "\n")
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.Comma = ','
+	for _, sep := range []rune{';', '\t'} {
+		if strings.Count(first, string(sep)) > strings.Count(first, string(r.Comma)) {
+			r.Comma = sep
+		}
+	}
+	r.FieldsPerRecord = -1
+	return r.ReadAll()
+}
+
+// readXLSX reads the cells of the first worksheet of an .xlsx workbook
+func readXLSX(path string) ([][]string, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not an .xlsx workbook: %v", ErrInvalidInput, path, err)
+	}
+	defer z.Close()
+
+	decode := func(name string, v any) (bool, error) {
+		for _, f := range z.File {
+			if f.Name != name {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return false, err
+			}
+			defer r.Close()
+			return true, xml.NewDecoder(r).Decode(v)
+		}
+		return false, nil
+	}
+
+	var shared struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if _, err := decode("xl/sharedStrings.xml", &shared); err != nil {
+		return nil, fmt.Errorf("%w: reading shared strings of %s: %v", ErrInvalidInput, path, err)
+	}
+	strs := make([]string, len(shared.Items))
+	for i, item := range shared.Items {
+		strs[i] = item.Text
+		for _, run := range item.Runs {
+			strs[i] += run.Text
+		}
+	}
+
+	// The first worksheet, by number
+	var sheets []string
+	for _, f := range z.File {
+		if strings.HasPrefix(f.Name, "xl/worksheets/sheet") && strings.HasSuffix(f.Name, ".xml") {
+			sheets = append(sheets, f.Name)
+		}
+	}
+	sheetNumber := func(name string) int {
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "xl/worksheets/sheet"), ".xml"))
+		return n
+	}
+	sort.Slice(sheets, func(i, j int) bool { return sheetNumber(sheets[i]) < sheetNumber(sheets[j]) })
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: %s has no worksheets", ErrInvalidInput, path)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if _, err := decode(sheets[0], &sheet); err != nil {
+		return nil, fmt.Errorf("%w: reading %s of %s: %v", ErrInvalidInput, sheets[0], path, err)
+	}
+
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var record []string
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				col = 0
+				for _, r := range cell.Ref {
+					if r < 'A' || r > 'Z' {
+						break
+					}
+					col = col*26 + int(r-'A') + 1
+				}
+				col--
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			switch cell.Type {
+			case "s":
+				if n, err := strconv.Atoi(cell.Value); err == nil && n >= 0 && n < len(strs) {
+					record[col] = strs[n]
+				}
+			case "inlineStr":
+				record[col] = cell.Inline
+			default:
+				record[col] = cell.Value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// importRow is a row of the file mapped to an employee, or the reason it cannot be imported
+type importRow struct {
+	Line     int // row number in the file, counting the header as 1
+	Employee *Employee
+	Err      error
+}
+
+// mapImportRows maps every row and validates the employees it gives
+func mapImportRows(m *ImportMapping, header []string, rows [][]string) []importRow {
+	now := appClock.Now()
+	mapped := make([]importRow, len(rows))
+	for i, row := range rows {
+		e, err := m.Employee(header, row)
+		if err == nil {
+			newImportedEmployee(e)
+			err = validateEmployee(e, now)
+		}
+		mapped[i] = importRow{Line: i + 2, Employee: e, Err: err}
+	}
+	return mapped
+}
+
+// newImportedEmployee fills in the defaults of a row that adds an employee.
+// Employees work on-site unless the file says otherwise.
+func newImportedEmployee(e *Employee) {
+	if e.WorkMode == -1 {
+		e.WorkMode = WorkOnSite
+	}
+}
+
+// writeImportPreview shows the first n mapped rows, then how many rows are
+// valid and the problems of the rest
+func writeImportPreview(w io.Writer, mapped []importRow, n int) {
+	var preview []*Employee
+	for _, r := range mapped[:min(n, len(mapped))] {
+		if r.Err == nil {
+			preview = append(preview, r.Employee)
+		}
+	}
+	fmt.Fprintf(w, "\nFirst %d mapped row(s):\n", min(n, len(mapped)))
+	writeEmployeeTable(w, listColumns, preview, false)
+
+	valid := 0
+	for _, r := range mapped {
+		if r.Err == nil {
+			valid++
+		} else {
+			fmt.Fprintf(w, "%s row %d: %v\n", warningText("Skipping"), r.Line, r.Err)
+		}
+	}
+	fmt.Fprintf(w, "\n%d of %d row(s) can be imported.\n", valid, len(mapped))
+}
+
+// mappingWizard asks which column holds each field, suggesting one where a
+// header matches, then asks for defaults of unmapped fields and the date format
+func mappingWizard(reader *bufio.Reader, header []string, rows [][]string) (*ImportMapping, error) {
+	suggested := SuggestImportMapping(header)
+	m := &ImportMapping{Columns: make(map[string]string), Defaults: make(map[string]string)}
+
+	fmt.Println("\nColumns in the file:")
+	for i, h := range header {
+		sample := ""
+		if len(rows) > 0 && i < len(rows[0]) {
+			sample = rows[0][i]
+		}
+		fmt.Printf("%2d. %-24s e.g. %q\n", i+1, h, sample)
+	}
+
+	for _, f := range importFields {
+		current := "none"
+		choice := 0
+		if column, ok := suggested.Columns[f.Name]; ok {
+			for i, h := range header {
+				if h == column {
+					choice = i + 1
+					current = fmt.Sprintf("%d. %s", choice, h)
+				}
+			}
+		}
+
+		prompt := fmt.Sprintf("Column for %s [%s] (number, 0 for none): ", f.Label, current)
+		column, err := readValue(reader, prompt, func(input string) (int, error) {
+			if input == "" {
+				return choice, nil
+			}
+			n, err := parseInt(input)
+			if err != nil {
+				return 0, err
+			}
+			if n < 0 || n > len(header) {
+				return 0, fmt.Errorf("%w: please enter a column number from 1 to %d", ErrInvalidInput, len(header))
+			}
+			return n, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if column > 0 {
+			m.Columns[f.Name] = header[column-1]
+			continue
+		}
+
+		if f.Name == "id" {
+			continue // the store assigns IDs
+		}
+		value, err := readString(reader, fmt.Sprintf("Default %s for every row (blank for none): ", f.Label))
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			m.Defaults[f.Name] = value
+		}
+	}
+
+	// Suggest the date format most sampled dates match, the earliest listed on a tie
+	var dates []string
+	for _, field := range []string{"join_date", "birth_date"} {
+		if column, ok := m.Columns[field]; ok {
+			for i, h := range header {
+				if h != column {
+					continue
+				}
+				for _, row := range rows[:min(len(rows), 50)] {
+					if i < len(row) && strings.TrimSpace(row[i]) != "" {
+						dates = append(dates, strings.TrimSpace(row[i]))
+					}
+				}
+			}
+		}
+	}
+	if len(dates) > 0 {
+		format, best := "", 0
+		for _, candidate := range importDateFormats {
+			matches := 0
+			for _, d := range dates {
+				if _, err := time.Parse(dateLayoutOf(candidate), d); err == nil {
+					matches++
+				}
+			}
+			if matches > best {
+				format, best = candidate, matches
+			}
+		}
+		input, err := readString(reader, fmt.Sprintf("Date format, using YYYY, MM and DD [%s]: ", format))
+		if err != nil {
+			return nil, err
+		}
+		if input == "" {
+			input = format
+		}
+		m.DateFormat = strings.ToUpper(input)
+	}
+	return m, nil
+}
+
+// loadImport reads a file and its mapping, from mappingPath or the wizard
+func loadImport(reader *bufio.Reader, path, mappingPath string) ([]string, [][]string, *ImportMapping, error) {
+	header, rows, err := readImportTable(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var mapping *ImportMapping
+	if mappingPath != "" {
+		mapping, err = LoadImportMapping(mappingPath)
+	} else {
+		mapping, err = mappingWizard(reader, header, rows)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return header, rows, mapping, nil
+}
+
+// importRows adds the valid mapped employees to the manager. Ctrl-C stops
+// adding and keeps the employees added so far.
+func importRows(manager EmployeeManager, mapped []importRow) (int, []error) {
+	employees := make([]*Employee, 0, len(mapped))
+	for _, r := range mapped {
+		if r.Err == nil {
+			employees = append(employees, r.Employee)
+		}
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	return addEmployeesWithProgress(ctx, manager, employees, "Importing")
+}
+
+// importInteractive imports employees from a CSV or .xlsx file through user interaction
+func importInteractive(manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Import Employees ==="))
+
+	path, err := readString(reader, "CSV or .xlsx file: ")
+	if err != nil {
+		return err
+	}
+	mappingPath, err := readString(reader, "Mapping file (blank to map the columns now): ")
+	if err != nil {
+		return err
+	}
+
+	header, rows, mapping, err := loadImport(reader, path, mappingPath)
+	if err != nil {
+		return err
+	}
+	mapped := mapImportRows(mapping, header, rows)
+	writeImportPreview(os.Stdout, mapped, 5)
+
+	if mappingPath == "" {
+		save, err := readString(reader, "Save this mapping to a file for next time (blank to skip): ")
+		if err != nil {
+			return err
+		}
+		if save != "" {
+			if err := mapping.Save(save); err != nil {
+				return err
+			}
+			fmt.Printf("Mapping saved to %s\n", save)
+		}
+	}
+
+	ok, err := confirm(reader, "\nImport the valid rows?", false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("\n" + warningText("Operation cancelled."))
+		return nil
+	}
+
+	release, err := lockBatch(manager, "import")
+	if err != nil {
+		return err
+	}
+	defer release()
+	added, errs := importRows(manager, mapped)
+	for _, err := range errs {
+		if errors.Is(err, ErrCancelled) {
+			return err
+		}
+		fmt.Println(err)
+	}
+	fmt.Println("\n" + successText(fmt.Sprintf("Imported %d employee(s).", added)))
+	return nil
+}
+
+// runImport implements the import command, which adds the employees of a CSV
+// or .xlsx file to a storage backend. Without -mapping it asks which column
+// holds each field. With -upsert it syncs the backend with the file instead,
+// updating the employees it matches and adding the rest; fields edited both
+// locally and in the file since the -baseline sync are settled by -conflict
+// or queued, and -resolve walks through the queue.
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	in := fs.String("in", "", "CSV or .xlsx file to import")
+	mappingPath := fs.String("mapping", "", "JSON file mapping the file's columns to employee fields (default ask)")
+	saveMapping := fs.String("save-mapping", "", "save the mapping used to this file")
+	preview := fs.Int("preview", 5, "how many mapped rows to show before importing")
+	storageName := fs.String("storage", "", "storage backend to import into (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	assumeYes := fs.Bool("yes", false, "import without asking for confirmation")
+	upsert := fs.Bool("upsert", false, "update employees matched by ID, or by name and birth date, and add the rest")
+	deleteMissing := fs.Bool("delete-missing", false, "with -upsert, remove employees the file does not list")
+	conflict := fs.String("conflict", "manual", "with -upsert, settle fields edited on both sides by prefer-local, prefer-import, newest-wins or manual")
+	baselinePath := fs.String("baseline", "", "with -upsert, file recording the last sync, to tell local edits from the file's")
+	queuePath := fs.String("conflicts", "import-conflicts.json", "file of conflicts queued for manual resolution")
+	resolve := fs.Bool("resolve", false, "resolve the queued conflicts instead of importing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	strategy, err := StringToConflictStrategy(*conflict)
+	if err != nil {
+		return err
+	}
+	if *in == "" && !*resolve {
+		return fmt.Errorf("%w: -in is required", ErrInvalidInput)
+	}
+	if *deleteMissing && !*upsert {
+		return fmt.Errorf("%w: -delete-missing requires -upsert", ErrInvalidInput)
+	}
+	if *assumeYes {
+		confirmPolicy = ConfirmNever
+	}
+
+	reader := bufio.NewReader(stdin)
+	if *resolve {
+		store, err := OpenStorage(backend, connection, appClock)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		release, err := lockBatch(store, "import")
+		if err != nil {
+			return err
+		}
+		defer release()
+		return resolveConflicts(reader, store, *queuePath, stdout)
+	}
+
+	header, rows, mapping, err := loadImport(reader, *in, *mappingPath)
+	if err != nil {
+		return err
+	}
+	if *saveMapping != "" {
+		if err := mapping.Save(*saveMapping); err != nil {
+			return err
+		}
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	release, err := lockBatch(store, "import")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if *upsert {
+		opts := SyncOptions{RemoveMissing: *deleteMissing, Strategy: strategy}
+		if *baselinePath != "" {
+			if opts.Baseline, err = LoadSyncBaseline(*baselinePath); err != nil {
+				return err
+			}
+		}
+		plan, syncErr := syncImport(reader, store, mapping, header, rows, opts, stdout)
+		if plan == nil {
+			return syncErr
+		}
+		if len(plan.Conflicts) > 0 {
+			if err := queueConflicts(*queuePath, plan.Conflicts); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "%d conflict(s) queued in %s; resolve them with import -resolve -conflicts %s\n",
+				len(plan.Conflicts), *queuePath, *queuePath)
+		}
+		if opts.Baseline != nil {
+			plan.RecordBaseline(opts.Baseline, appClock.Now())
+			if err := opts.Baseline.Save(*baselinePath); err != nil {
+				return err
+			}
+		}
+		return syncErr
+	}
+
+	mapped := mapImportRows(mapping, header, rows)
+	writeImportPreview(stdout, mapped, *preview)
+	ok, err := confirm(reader, "\nImport the valid rows?", false)
+	if err != nil || !ok {
+		return err
+	}
+
+	added, errs := importRows(store, mapped)
+	fmt.Fprintf(stdout, "Imported %d employee(s) into %s\n", added, backend)
+	if len(errs) > 0 {
+		return fmt.Errorf("%d employee(s) were not imported, first: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
+// syncImport shows what syncing the store with the rows would change and,
+// once confirmed, makes the changes and prints how many were made. It
+// returns the plan, or nil if the sync was cancelled.
+func syncImport(reader *bufio.Reader, store EmployeeManager, mapping *ImportMapping, header []string, rows [][]string, opts SyncOptions, stdout io.Writer) (*SyncPlan, error) {
+	plan, err := PlanSync(store, mapping, header, rows, opts)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(stdout)
+	writeSyncPlan(stdout, plan)
+	if len(plan.Adds)+len(plan.Updates)+len(plan.Removes) == 0 {
+		return plan, nil
+	}
+
+	ok, err := confirm(reader, "\nApply these changes?", len(plan.Removes) > 0)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	result, errs := plan.Apply(store)
+	fmt.Fprintf(stdout, "Synced: %s\n", result)
+	if len(errs) > 0 {
+		return plan, fmt.Errorf("%d change(s) were not made, first: %w", len(errs), errs[0])
+	}
+	return plan, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// probationAlertDays is how many days ahead the probation job warns about
+// probation periods that are ending
+const probationAlertDays = 14
+
+// documentAlertDays is how many days ahead the document job warns about
+// work permits, visas and certifications that are expiring, and the
+// default window of the compliance report
+const documentAlertDays = 30
+
+// reminderJob logs the anniversaries and birthdays falling on the day it runs
+func reminderJob(manager EmployeeManager, logger *log.Logger) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		reminders, err := UpcomingReminders(manager, now.In(userLocation), 0)
+		if err != nil {
+			return err
+		}
+		for _, r := range reminders {
+			logger.Println("Reminder:", r)
+		}
+		return nil
+	}
+}
+
+// probationJob logs the employees whose probation ends within probationAlertDays
+func probationJob(manager EmployeeManager, logger *log.Logger) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		employees, err := employeeValues(manager)
+		if err != nil {
+			return err
+		}
+		sortEmployeeValues(employees, OrderByID)
+
+		today := dateOf(now.In(userLocation).Date())
+		until := today.AddDate(0, 0, probationAlertDays)
+		for _, e := range employees {
+			if e.ProbationEnd.IsZero() || e.ProbationEnd.Before(today) || e.ProbationEnd.After(until) {
+				continue
+			}
+			logger.Printf("Probation: %s (ID %d) ends probation on %s", e.Name, e.ID, formatDate(e.ProbationEnd))
+		}
+		return nil
+	}
+}
+
+// documentJob logs the documents that have expired or expire within
+// documentAlertDays, and notifies the users who asked to be told
+func documentJob(documents *Documents, manager EmployeeManager, logger *log.Logger) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		for _, doc := range documents.expiring(now, documentAlertDays) {
+			name := "(unknown)"
+			if e, err := manager.GetEmployee(doc.EmployeeID); err == nil {
+				name = e.Name
+			}
+			text := fmt.Sprintf("%s (ID %d) %s %q %s, on %s", name, doc.EmployeeID,
+				strings.ToLower(DocumentKindToString(doc.Kind)), doc.Title, documentExpiry(doc, now), formatDate(doc.Expires))
+			logger.Printf("Document: %s", text)
+			appNotifications.Publish(Notification{Event: NotifyDocumentExpiring, Subject: "Document expiring", Text: text, Time: now})
+		}
+		return nil
+	}
+}
+
+// digestJob sends the users who want a digest the notifications queued for it
+func digestJob(notifications *Notifications, logger *log.Logger) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		sent, errs := notifications.SendDigests(now)
+		if sent > 0 {
+			logger.Printf("Notifications: sent %d digest(s)", sent)
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// weeklyDigestJob sends the weekly digest to the users who are notified of it
+func weeklyDigestJob(manager EmployeeManager, documents *Documents, leave *Leave, clock *TimeClock, logger *log.Logger) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		digest, err := BuildWeeklyDigest(manager, documents, leave, clock, now)
+		if err != nil {
+			return err
+		}
+		note, err := digest.Notification(now)
+		if err != nil {
+			return err
+		}
+		appNotifications.Publish(note)
+		logger.Printf("Weekly digest: %s", note.Text)
+		return nil
+	}
+}
+
+// leaveAccrualJob accrues leave under the leave policies and logs the
+// leave employees forfeit or lose to expiry
+func leaveAccrualJob(leave *Leave, policies *LeavePolicies, manager EmployeeManager, logger *log.Logger) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		changes, err := leave.accrue(policies, now)
+		if err != nil {
+			return err
+		}
+		accrued := make(map[int]bool)
+		for _, c := range changes {
+			switch c.Kind {
+			case LeaveAccrued:
+				accrued[c.EmployeeID] = true
+			case LeaveForfeited, LeaveExpired:
+				name := "(unknown)"
+				if e, err := manager.GetEmployee(c.EmployeeID); err == nil {
+					name = e.Name
+				}
+				logger.Printf("Leave: %s (ID %d) %s %.2f day(s)", name, c.EmployeeID, strings.ToLower(LeaveAccrualToString(c.Kind)), c.Days)
+			}
+		}
+		if len(accrued) > 0 {
+			logger.Printf("Leave: accrued for %d employee(s) through %s", len(accrued), now.In(userLocation).Format(monthLayout))
+		}
+		return nil
+	}
+}
+
+// snapshotJob writes every employee to a dated JSON file in dir. The file is
+// written under a temporary name and renamed, so a snapshot is never half written.
+func snapshotJob(manager EmployeeManager, dir string) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		employees, err := employeeValues(manager)
+		if err != nil {
+			return err
+		}
+		sortEmployeeValues(employees, OrderByID)
+
+		out := make([]employeeJSON, len(employees))
+		for i := range employees {
+			out[i] = toEmployeeJSON(&employees[i])
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, "employees-"+formatDate(now)+".json")
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	}
+}
+
+// statsJob recomputes the cached summary report
+func statsJob(summary *SummaryCache) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		_, err := summary.Refresh()
+		return err
+	}
+}
+
+// registerStandardJobs registers the recurring jobs of server mode. Leave
+// accrual is only registered when there are leave policies, and the nightly
+// snapshot when a snapshot directory is given.
+func registerStandardJobs(s *Scheduler, manager EmployeeManager, documents *Documents, leave *Leave, clock *TimeClock, summary *SummaryCache, snapshotDir string, logger *log.Logger) error {
+	if err := s.Register("reminders", DailyAt{Hour: 8}, reminderJob(manager, logger)); err != nil {
+		return err
+	}
+	if err := s.Register("probation-alerts", DailyAt{Hour: 8}, probationJob(manager, logger)); err != nil {
+		return err
+	}
+	if err := s.Register("document-alerts", DailyAt{Hour: 8}, documentJob(documents, manager, logger)); err != nil {
+		return err
+	}
+	if err := s.Register("stats", Every(15*time.Minute), statsJob(summary)); err != nil {
+		return err
+	}
+	if err := s.Register("notification-digest", DailyAt{Hour: 7}, digestJob(appNotifications, logger)); err != nil {
+		return err
+	}
+	if err := s.Register("weekly-digest", WeeklyAt{Weekday: time.Monday, Hour: 7}, weeklyDigestJob(manager, documents, leave, clock, logger)); err != nil {
+		return err
+	}
+	if leavePolicies != nil {
+		if err := s.Register("leave-accrual", DailyAt{Hour: 1}, leaveAccrualJob(leave, leavePolicies, manager, logger)); err != nil {
+			return err
+		}
+	}
+	if snapshotDir != "" {
+		return s.Register("snapshot", DailyAt{Hour: 2}, snapshotJob(manager, snapshotDir))
+	}
+	return nil
+}
@@ -0,0 +1,763 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+var (
+	ErrLeaveNotFound     = errorsx.New(errorsx.ErrNotFound, "leave_id", "leave request not found")
+	ErrInsufficientLeave = errorsx.New(errorsx.ErrConflict, "days", "not enough leave left")
+)
+
+// LeaveRequest is a request for days off, from the first day to the last.
+// Either end can be a half day: leave that starts at midday takes only the
+// afternoon of the first day, and leave that ends at midday only the
+// morning of the last.
+type LeaveRequest struct {
+	ID          int       `json:"id"`
+	EmployeeID  int       `json:"employee_id"`
+	Start       time.Time `json:"start"` // midnight of the first day in the user's time zone
+	End         time.Time `json:"end"`   // midnight of the last day
+	HalfStart   bool      `json:"half_start,omitempty"`
+	HalfEnd     bool      `json:"half_end,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	Status      int       `json:"status"` // StatusPending, StatusApproved or StatusRejected
+	RequestedBy string    `json:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	DecidedAt   time.Time `json:"decided_at"`
+	Reason      string    `json:"reason,omitempty"` // given when rejected
+	// Queued marks a request longer than the approval policy grants at
+	// once, decided in the approval queue rather than by a leave approver
+	Queued bool `json:"queued,omitempty"`
+	// Days is the leave the request takes, worked out again against the
+	// holiday calendar when it is approved and deducted from the balance
+	Days float64 `json:"days"`
+}
+
+// Span describes the days of a request, such as "2026-10-19 pm to 2026-10-23"
+func (r *LeaveRequest) Span() string {
+	start, end := formatDate(r.Start), formatDate(r.End)
+	switch {
+	case start == end && r.HalfStart:
+		return start + " pm"
+	case start == end && r.HalfEnd:
+		return start + " am"
+	case start == end:
+		return start
+	}
+	if r.HalfStart {
+		start += " pm"
+	}
+	if r.HalfEnd {
+		end += " am"
+	}
+	return start + " to " + end
+}
+
+// requested describes a request just made, granted at once or waiting for approval
+func (r *LeaveRequest) requested() string {
+	state := "is waiting for approval"
+	if r.Status == StatusApproved {
+		state = "is granted"
+	}
+	return fmt.Sprintf("Leave request #%d for %s, %.1f working day(s), %s", r.ID, r.Span(), r.Days, state)
+}
+
+// Covers reports whether the request takes the morning and the afternoon
+// of a day off
+func (r *LeaveRequest) Covers(day time.Time) (morning, afternoon bool) {
+	date := formatDate(day)
+	first, last := formatDate(r.Start), formatDate(r.End)
+	if date < first || date > last {
+		return false, false
+	}
+	return date != first || !r.HalfStart, date != last || !r.HalfEnd
+}
+
+// LeaveDays returns the days of leave taken from the first day to the last
+// under a calendar: the working days between them, less half a day for each
+// end that is a half day
+func LeaveDays(c *HolidayCalendar, start, end time.Time, halfStart, halfEnd bool) float64 {
+	first, last := start.In(userLocation), formatDate(end)
+	days := 0.0
+	for i := 0; ; i++ {
+		day := dateOf(first.Year(), first.Month(), first.Day()+i)
+		if day.After(end) {
+			break
+		}
+		if !c.IsWorkingDay(day) {
+			continue
+		}
+		days++
+		if i == 0 && halfStart {
+			days -= 0.5
+		}
+		if formatDate(day) == last && halfEnd {
+			days -= 0.5
+		}
+	}
+	return days
+}
+
+// LeaveAccount is the leave an employee has left
+type LeaveAccount struct {
+	Balance float64 `json:"balance"` // days left, including those carried over
+	// Carried is the part of the balance carried over from last year. It is
+	// taken first, and what is left of it may expire under the leave policy.
+	Carried float64 `json:"carried,omitempty"`
+	Year    int     `json:"year,omitempty"` // the leave year the policy last accrued for
+}
+
+// Leave records employees' leave requests and the days of leave each has
+// left. Approvers decide requests; approving one deducts its working days,
+// counted against the holiday calendar, from the employee's balance. Leave
+// policies top the balances up as the months pass.
+type Leave struct {
+	mu       sync.Mutex
+	manager  EmployeeManager
+	requests []*LeaveRequest
+	accounts map[int]*LeaveAccount // by employee ID
+	accrued  time.Time             // the first day of the last month the policies accrued for
+	nextID   int
+	path     string // JSON file the leave is kept in, or "" for memory only
+
+	// approvals, set by ApprovalManager.QueueLeave, grant short leave as it
+	// is asked for and queue long leave for approval
+	approvals *ApprovalManager
+}
+
+// leaveFile is the JSON layout of the leave file
+type leaveFile struct {
+	Requests []*LeaveRequest       `json:"requests"`
+	Accounts map[int]*LeaveAccount `json:"accounts"`
+	Accrued  time.Time             `json:"accrued_through"`
+}
+
+// NewLeave creates the leave of employees of the given manager, reading it
+// from the file at path if there is one
+func NewLeave(manager EmployeeManager, path string) (*Leave, error) {
+	l := &Leave{manager: manager, accounts: make(map[int]*LeaveAccount), nextID: 1, path: path}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var file leaveFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading leave %s: %w", path, err)
+	}
+	l.requests, l.accrued = file.Requests, file.Accrued
+	if file.Accounts != nil {
+		l.accounts = file.Accounts
+	}
+	for _, r := range l.requests {
+		l.nextID = max(l.nextID, r.ID+1)
+	}
+	return l, nil
+}
+
+// save writes the leave to the file. The caller must hold the lock. The
+// file is written under a temporary name and renamed, and an interrupt
+// waits for the save, so it is never left half written.
+func (l *Leave) save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(leaveFile{Requests: l.requests, Accounts: l.accounts, Accrued: l.accrued}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := l.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, l.path)
+	})
+}
+
+// account returns the leave account of an employee, opening an empty one.
+// The caller must hold the lock.
+func (l *Leave) account(employeeID int) *LeaveAccount {
+	a := l.accounts[employeeID]
+	if a == nil {
+		a = &LeaveAccount{}
+		l.accounts[employeeID] = a
+	}
+	return a
+}
+
+// balance returns the days of leave an employee has left. The caller must
+// hold the lock.
+func (l *Leave) balance(employeeID int) float64 {
+	if a := l.accounts[employeeID]; a != nil {
+		return a.Balance
+	}
+	return 0
+}
+
+// request returns a copy of the request with an ID, if there is one
+func (l *Leave) request(id int) (LeaveRequest, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r := l.find(id); r != nil {
+		return *r, true
+	}
+	return LeaveRequest{}, false
+}
+
+// find returns the request with an ID, or nil. The caller must hold the lock.
+func (l *Leave) find(id int) *LeaveRequest {
+	for _, r := range l.requests {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Request asks for leave for an employee. Employees can ask for their own
+// leave and HR for anyone's. The request waits for an approver, unless an
+// approval policy limits the leave that needs one: shorter leave is then
+// granted at once, and longer leave queued for approval.
+func (l *Leave) Request(user User, employeeID int, start, end time.Time, halfStart, halfEnd bool, note string) (*LeaveRequest, error) {
+	if err := user.RequireOwnOr(PermEditPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	employee, err := l.manager.GetEmployee(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case start.IsZero() || end.IsZero():
+		return nil, fmt.Errorf("%w: leave needs a first and a last day", ErrInvalidInput)
+	case end.Before(start):
+		return nil, fmt.Errorf("%w: the last day of leave is before the first", ErrInvalidInput)
+	case formatDate(start) == formatDate(end) && halfStart && halfEnd:
+		return nil, fmt.Errorf("%w: a single day of leave is the morning, the afternoon or the whole day", ErrInvalidInput)
+	}
+	days := LeaveDays(holidayCalendar, start, end, halfStart, halfEnd)
+	if days <= 0 {
+		return nil, fmt.Errorf("%w: %s to %s has no working days", ErrInvalidInput, formatDate(start), formatDate(end))
+	}
+
+	l.mu.Lock()
+	for _, r := range l.requests {
+		if r.EmployeeID == employeeID && r.Status != StatusRejected && !r.Start.After(end) && !start.After(r.End) {
+			l.mu.Unlock()
+			return nil, fmt.Errorf("%w: leave request #%d already covers %s", ErrInvalidInput, r.ID, r.Span())
+		}
+	}
+	r := &LeaveRequest{
+		ID:          l.nextID,
+		EmployeeID:  employeeID,
+		Start:       start,
+		End:         end,
+		HalfStart:   halfStart,
+		HalfEnd:     halfEnd,
+		Note:        strings.TrimSpace(note),
+		Status:      StatusPending,
+		RequestedBy: user.Name,
+		RequestedAt: clockOf(l.manager).Now(),
+		Days:        days,
+	}
+	var limit float64
+	if l.approvals != nil {
+		limit = l.approvals.policy.LeaveDays
+	}
+	oldAccount, hadAccount := l.accounts[employeeID]
+	if hadAccount {
+		saved := *oldAccount
+		oldAccount = &saved
+	}
+	switch {
+	case limit > 0 && days > limit:
+		r.Queued = true
+	case limit > 0:
+		if err := l.take(employeeID, days); err != nil {
+			l.restoreAccount(employeeID, oldAccount)
+			l.mu.Unlock()
+			return nil, err
+		}
+		r.Status, r.DecidedBy, r.DecidedAt = StatusApproved, "approval policy", r.RequestedAt
+	}
+	l.requests = append(l.requests, r)
+	l.nextID++
+	if err := l.save(); err != nil {
+		l.requests = l.requests[:len(l.requests)-1]
+		l.nextID--
+		l.restoreAccount(employeeID, oldAccount)
+		l.mu.Unlock()
+		return nil, err
+	}
+	copied := *r
+	l.mu.Unlock()
+
+	// The approval queue is not locked with the leave, so the leave is not
+	// locked while the request joins it
+	if r.Queued {
+		queued := copied
+		if err := l.approvals.submit(&ApprovalRequest{Kind: RequestLeave, Before: *employee, Leave: &queued}); !errors.Is(err, ErrPendingApproval) {
+			l.withdraw(copied.ID)
+			return nil, err
+		}
+	}
+	return &copied, nil
+}
+
+// withdraw drops a request that could not be queued for approval
+func (l *Leave) withdraw(id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, r := range l.requests {
+		if r.ID == id {
+			l.requests = append(l.requests[:i], l.requests[i+1:]...)
+			break
+		}
+	}
+	if err := l.save(); err != nil {
+		fmt.Printf("%s leave request #%d could not be withdrawn: %v\n", errorText("Error:"), id, err)
+	}
+}
+
+// restoreAccount puts back an employee's account as it was, or drops it if
+// there was none. The caller must hold the lock.
+func (l *Leave) restoreAccount(employeeID int, old *LeaveAccount) {
+	if old == nil {
+		delete(l.accounts, employeeID)
+	} else {
+		*l.account(employeeID) = *old
+	}
+}
+
+// take deducts days from an employee's balance, carried-over days first.
+// The caller must hold the lock.
+func (l *Leave) take(employeeID int, days float64) error {
+	account := l.account(employeeID)
+	if days > account.Balance {
+		return ErrInsufficientLeave.WithValue(fmt.Sprintf("%.1f day(s) requested, %.1f left", days, account.Balance))
+	}
+	account.Balance = roundDays(account.Balance - days)
+	account.Carried = roundDays(account.Carried - min(days, account.Carried))
+	return nil
+}
+
+// decide finds a pending request for an approver, in the approval queue or
+// not as queued says. The caller must hold the lock.
+func (l *Leave) decide(approver User, id int, queued bool) (*LeaveRequest, error) {
+	if err := approver.Require(PermApproveChanges); err != nil {
+		return nil, err
+	}
+	r := l.find(id)
+	switch {
+	case r == nil:
+		return nil, ErrLeaveNotFound.WithValue(id)
+	case r.Status != StatusPending:
+		return nil, fmt.Errorf("%w: leave request #%d is already %s", ErrInvalidInput, id,
+			strings.ToLower(ApprovalStatusToString(r.Status)))
+	case r.Queued && !queued:
+		return nil, fmt.Errorf("%w: leave request #%d is longer than the approval policy grants, so it is decided in the approval queue", ErrInvalidInput, id)
+	case !r.Queued && queued:
+		return nil, fmt.Errorf("%w: leave request #%d is not in the approval queue", ErrInvalidInput, id)
+	case r.RequestedBy == approver.Name, approver.EmployeeID != 0 && r.EmployeeID == approver.EmployeeID:
+		return nil, fmt.Errorf("%w: leave cannot be approved or rejected by the person who asked for it", ErrPermissionDenied)
+	}
+	return r, nil
+}
+
+// Approve grants a pending request and deducts its days from the employee's
+// balance. The days are counted against the holiday calendar as it is now,
+// which may have gained holidays since the request was made.
+func (l *Leave) Approve(approver User, id int) (*LeaveRequest, error) {
+	return l.approve(approver, id, false)
+}
+
+// approve is Approve for requests in the approval queue or not, as queued says
+func (l *Leave) approve(approver User, id int, queued bool) (*LeaveRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, err := l.decide(approver, id, queued)
+	if err != nil {
+		return nil, err
+	}
+	days := LeaveDays(holidayCalendar, r.Start, r.End, r.HalfStart, r.HalfEnd)
+	account := l.account(r.EmployeeID)
+	old, oldAccount := *r, *account
+	if err := l.take(r.EmployeeID, days); err != nil {
+		return nil, err
+	}
+	r.Status, r.DecidedBy, r.DecidedAt, r.Days = StatusApproved, approver.Name, clockOf(l.manager).Now(), days
+	if err := l.save(); err != nil {
+		*r, *account = old, oldAccount
+		return nil, err
+	}
+	copied := *r
+	return &copied, nil
+}
+
+// Reject turns down a pending request, giving a reason
+func (l *Leave) Reject(approver User, id int, reason string) (*LeaveRequest, error) {
+	return l.reject(approver, id, reason, false)
+}
+
+// reject is Reject for requests in the approval queue or not, as queued says
+func (l *Leave) reject(approver User, id int, reason string, queued bool) (*LeaveRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, err := l.decide(approver, id, queued)
+	if err != nil {
+		return nil, err
+	}
+	old := *r
+	r.Status, r.DecidedBy, r.DecidedAt, r.Reason = StatusRejected, approver.Name, clockOf(l.manager).Now(), strings.TrimSpace(reason)
+	if err := l.save(); err != nil {
+		*r = old
+		return nil, err
+	}
+	copied := *r
+	return &copied, nil
+}
+
+// Pending returns the requests waiting for a leave approver, oldest first,
+// with the balance each employee has left. Those in the approval queue are
+// left out.
+func (l *Leave) Pending(approver User) ([]LeaveRequest, map[int]float64, error) {
+	if err := approver.Require(PermApproveChanges); err != nil {
+		return nil, nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var list []LeaveRequest
+	balances := make(map[int]float64)
+	for _, r := range l.requests {
+		if r.Status == StatusPending && !r.Queued {
+			list = append(list, *r)
+			balances[r.EmployeeID] = l.balance(r.EmployeeID)
+		}
+	}
+	return list, balances, nil
+}
+
+// pending returns the requests waiting for an approver, oldest first, for reports
+func (l *Leave) pending() []LeaveRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var list []LeaveRequest
+	for _, r := range l.requests {
+		if r.Status == StatusPending {
+			list = append(list, *r)
+		}
+	}
+	return list
+}
+
+// approved returns the approved requests that overlap the days from one to
+// another, for reports
+func (l *Leave) approved(from, to time.Time) []LeaveRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var list []LeaveRequest
+	for _, r := range l.requests {
+		if r.Status == StatusApproved && !r.Start.After(to) && !from.After(r.End) {
+			list = append(list, *r)
+		}
+	}
+	return list
+}
+
+// ForEmployee returns an employee's requests, latest first, and the leave
+// they have left
+func (l *Leave) ForEmployee(user User, employeeID int) ([]LeaveRequest, LeaveAccount, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return nil, LeaveAccount{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var list []LeaveRequest
+	for _, r := range l.requests {
+		if r.EmployeeID == employeeID {
+			list = append(list, *r)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Start.After(list[j].Start) })
+	var account LeaveAccount
+	if a := l.accounts[employeeID]; a != nil {
+		account = *a
+	}
+	return list, account, nil
+}
+
+// SetBalance sets the days of leave an employee has left, in half days, as
+// days of this year's leave
+func (l *Leave) SetBalance(user User, employeeID int, days float64) error {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return err
+	}
+	if days < 0 || days != math.Round(days*2)/2 {
+		return fmt.Errorf("%w: leave is counted in half days and cannot be negative", ErrInvalidInput)
+	}
+	if _, err := l.manager.GetEmployee(employeeID); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	account := l.account(employeeID)
+	old := *account
+	account.Balance, account.Carried = days, 0
+	if err := l.save(); err != nil {
+		*account = old
+		return err
+	}
+	return nil
+}
+
+// Forget returns an event subscriber that drops the leave of removed employees
+func (l *Leave) Forget() func(Event) {
+	return func(ev Event) {
+		if ev.Type != EventEmployeeRemoved {
+			return
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		kept := l.requests[:0]
+		for _, r := range l.requests {
+			if r.EmployeeID != ev.EmployeeID {
+				kept = append(kept, r)
+			}
+		}
+		_, had := l.accounts[ev.EmployeeID]
+		if len(kept) != len(l.requests) || had {
+			l.requests = kept
+			delete(l.accounts, ev.EmployeeID)
+			l.save()
+		}
+	}
+}
+
+// writeLeaveRequests writes leave requests, naming each employee from the
+// manager, with the balance they have left if balances is given
+func writeLeaveRequests(w io.Writer, list []LeaveRequest, manager EmployeeManager, balances map[int]float64) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "\nNo leave requests.")
+		return
+	}
+	fmt.Fprintf(w, "\n%-4s %-20s %-30s %6s %-9s %s\n", "#", "Employee", "Dates", "Days", "Status", "Note")
+	fmt.Fprintln(w, strings.Repeat("-", 96))
+	for _, r := range list {
+		name := fmt.Sprintf("ID %d", r.EmployeeID)
+		if e, err := manager.GetEmployee(r.EmployeeID); err == nil {
+			name = e.Name
+		}
+		note := r.Note
+		switch {
+		case r.Status == StatusRejected && r.Reason != "":
+			note = "rejected: " + r.Reason
+		case balances != nil:
+			note = strings.TrimSuffix(fmt.Sprintf("%.1f day(s) left; %s", balances[r.EmployeeID], note), "; ")
+		}
+		fmt.Fprintf(w, "%-4d %-20s %-30s %6.1f %-9s %s\n", r.ID, name, r.Span(), r.Days,
+			ApprovalStatusToString(r.Status), note)
+	}
+}
+
+// writeLeaveAccount writes the leave an employee has left
+func writeLeaveAccount(w io.Writer, account LeaveAccount) {
+	fmt.Fprintf(w, "\nLeave left: %.1f day(s)", account.Balance)
+	if account.Carried > 0 {
+		fmt.Fprintf(w, ", of which %.1f carried over from last year", account.Carried)
+	}
+	fmt.Fprintln(w)
+}
+
+// readLeaveDays reads the first and last day of leave and whether either
+// is a half day
+func readLeaveDays(reader *bufio.Reader) (start, end time.Time, halfStart, halfEnd bool, err error) {
+	if start, err = readDate(reader, "First day"); err != nil {
+		return
+	}
+	if end, err = readOptionalDate(reader, "Last day (blank for one day)"); err != nil {
+		return
+	}
+	if end.IsZero() {
+		end = start
+	}
+
+	if formatDate(start) == formatDate(end) {
+		var half string
+		half, err = readValue(reader, "Half day? (blank for the whole day, am, pm): ", func(input string) (string, error) {
+			switch input = strings.ToLower(input); input {
+			case "", "am", "pm":
+				return input, nil
+			}
+			return "", fmt.Errorf("%w: enter am, pm or leave blank", ErrInvalidInput)
+		})
+		return start, end, half == "pm", half == "am", err
+	}
+
+	var answer string
+	if answer, err = readString(reader, "Start at midday? (y/n): "); err != nil {
+		return
+	}
+	halfStart = strings.ToLower(answer) == "y"
+	if answer, err = readString(reader, "End at midday? (y/n): "); err != nil {
+		return
+	}
+	halfEnd = strings.ToLower(answer) == "y"
+	return start, end, halfStart, halfEnd, nil
+}
+
+// leaveInteractive requests, decides and reports leave through user interaction
+func leaveInteractive(leave *Leave, manager EmployeeManager, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Leave ==="))
+	fmt.Println("1. Request leave")
+	fmt.Println("2. Pending requests")
+	fmt.Println("3. Approve request")
+	fmt.Println("4. Reject request")
+	fmt.Println("5. View employee's leave")
+	fmt.Println("6. Set leave balance")
+	fmt.Println("7. Public holidays")
+	if leavePolicies != nil {
+		fmt.Println("8. Leave policies")
+		fmt.Println("9. Accrue leave now")
+	}
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		start, end, halfStart, halfEnd, err := readLeaveDays(reader)
+		if err != nil {
+			return err
+		}
+		note, err := readString(reader, "Note (optional): ")
+		if err != nil {
+			return err
+		}
+		r, err := leave.Request(user, id, start, end, halfStart, halfEnd, note)
+		if err != nil {
+			return err
+		}
+		appNotifications.Publish(leaveNotification(r, manager))
+		fmt.Println("\n" + successText(r.requested()))
+
+	case 2:
+		list, balances, err := leave.Pending(user)
+		if err != nil {
+			return err
+		}
+		writeLeaveRequests(os.Stdout, list, manager, balances)
+
+	case 3:
+		id, err := readInt(reader, "Enter leave request #: ")
+		if err != nil {
+			return err
+		}
+		r, err := leave.Approve(user, id)
+		if err != nil {
+			return err
+		}
+		appNotifications.Publish(leaveNotification(r, manager))
+		fmt.Println("\n" + successText(fmt.Sprintf("Leave request #%d approved; %.1f day(s) deducted", r.ID, r.Days)))
+
+	case 4:
+		id, err := readInt(reader, "Enter leave request #: ")
+		if err != nil {
+			return err
+		}
+		reason, err := readString(reader, "Reason: ")
+		if err != nil {
+			return err
+		}
+		r, err := leave.Reject(user, id, reason)
+		if err != nil {
+			return err
+		}
+		appNotifications.Publish(leaveNotification(r, manager))
+		fmt.Println("\n" + successText(fmt.Sprintf("Leave request #%d rejected", id)))
+
+	case 5:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		list, account, err := leave.ForEmployee(user, id)
+		if err != nil {
+			return err
+		}
+		writeLeaveRequests(os.Stdout, list, manager, nil)
+		writeLeaveAccount(os.Stdout, account)
+
+	case 6:
+		id, err := readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		days, err := readFloat(reader, "Days of leave left: ")
+		if err != nil {
+			return err
+		}
+		if err := leave.SetBalance(user, id, days); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Leave balance set to %.1f day(s)", days)))
+
+	case 7:
+		year, err := readValue(reader, "Year (blank for this year): ", func(input string) (int, error) {
+			if input == "" {
+				return clockOf(manager).Now().In(userLocation).Year(), nil
+			}
+			year, err := strconv.Atoi(input)
+			if err != nil || year < 1900 || year > 9999 {
+				return 0, fmt.Errorf("%w: please enter a year such as 2026", ErrInvalidInput)
+			}
+			return year, nil
+		})
+		if err != nil {
+			return err
+		}
+		writeHolidays(os.Stdout, holidayCalendar, year)
+
+	case 8, 9:
+		if leavePolicies == nil {
+			return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+		}
+		if option == 8 {
+			writeLeavePolicies(os.Stdout, leavePolicies)
+			return nil
+		}
+		changes, err := leave.Accrue(user, leavePolicies, clockOf(manager).Now())
+		if err != nil {
+			return err
+		}
+		writeLeaveAccruals(os.Stdout, changes, manager)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
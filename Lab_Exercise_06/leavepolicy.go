@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Accrual kind constants using iota
+const (
+	AccrueMonthly  = iota // a twelfth of the year's leave at the start of each month
+	AccrueAnnually        // the year's leave at the start of the year
+)
+
+// AccrualToString converts an accrual kind constant to string
+func AccrualToString(kind int) string {
+	switch kind {
+	case AccrueMonthly:
+		return "monthly"
+	case AccrueAnnually:
+		return "annual"
+	default:
+		return "unknown"
+	}
+}
+
+// StringToAccrual converts string to accrual kind constant
+func StringToAccrual(kind string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "monthly", "month":
+		return AccrueMonthly, nil
+	case "annual", "annually", "yearly":
+		return AccrueAnnually, nil
+	default:
+		return -1, fmt.Errorf("%w: accrual must be monthly or annual", ErrInvalidInput)
+	}
+}
+
+// LeavePolicy is how employees earn leave and how much of it they keep
+// from one year to the next
+type LeavePolicy struct {
+	Name        string   `json:"name"`
+	Departments []string `json:"departments,omitempty"` // departments the policy covers; the default policy lists none
+	Accrual     string   `json:"accrual"`               // monthly or annual
+	Days        float64  `json:"days"`                  // days of leave a year
+	CarryOver   float64  `json:"carry_over"`            // most unused days kept into the next year
+	// CarryOverMonths is how many months into the new year the days carried
+	// over can be taken before they expire; 0 keeps them until taken
+	CarryOverMonths int `json:"carry_over_months,omitempty"`
+
+	accrual int
+}
+
+// LeavePolicies are the leave policies of the company. Each employee is
+// under the policy of their department, else the default one.
+type LeavePolicies struct {
+	Policies []LeavePolicy `json:"policies"`
+
+	byDepartment map[Department]*LeavePolicy
+	fallback     *LeavePolicy
+}
+
+// leavePolicies are the leave policies, or nil if leave balances are only
+// set by hand. main sets them from the -leave-policies flag.
+var leavePolicies *LeavePolicies
+
+// LoadLeavePolicies reads and checks a leave policy file
+func LoadLeavePolicies(path string) (*LeavePolicies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseLeavePolicies(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseLeavePolicies reads and checks leave policies in JSON. A department
+// can be under one policy only, and only one policy can be the default.
+func ParseLeavePolicies(data []byte) (*LeavePolicies, error) {
+	var p LeavePolicies
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if len(p.Policies) == 0 {
+		return nil, fmt.Errorf("%w: no leave policies are defined", ErrInvalidInput)
+	}
+
+	p.byDepartment = make(map[Department]*LeavePolicy)
+	for i := range p.Policies {
+		policy := &p.Policies[i]
+		var err error
+		if policy.accrual, err = StringToAccrual(policy.Accrual); err != nil {
+			return nil, fmt.Errorf("leave policy %q: %w", policy.Name, err)
+		}
+		switch {
+		case policy.Name == "":
+			return nil, fmt.Errorf("%w: every leave policy needs a name", ErrInvalidInput)
+		case policy.Days < 0 || policy.Days > 366:
+			return nil, fmt.Errorf("%w: leave policy %q grants %.1f days a year", ErrInvalidInput, policy.Name, policy.Days)
+		case policy.CarryOver < 0:
+			return nil, fmt.Errorf("%w: leave policy %q has a negative carry-over", ErrInvalidInput, policy.Name)
+		case policy.CarryOverMonths < 0 || policy.CarryOverMonths > 11:
+			return nil, fmt.Errorf("%w: carried-over leave of policy %q must expire within 0 to 11 months", ErrInvalidInput, policy.Name)
+		}
+
+		if len(policy.Departments) == 0 {
+			if p.fallback != nil {
+				return nil, fmt.Errorf("%w: leave policies %q and %q are both the default", ErrInvalidInput, p.fallback.Name, policy.Name)
+			}
+			p.fallback = policy
+		}
+		for _, name := range policy.Departments {
+			d, err := ParseDepartment(name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: leave policy %q names an unknown department %q", ErrInvalidInput, policy.Name, name)
+			}
+			if other, ok := p.byDepartment[d]; ok {
+				return nil, fmt.Errorf("%w: %s is under both leave policies %q and %q", ErrInvalidInput, d, other.Name, policy.Name)
+			}
+			p.byDepartment[d] = policy
+		}
+	}
+	return &p, nil
+}
+
+// For returns the policy an employee is under, or nil if there is none
+func (p *LeavePolicies) For(e *Employee) *LeavePolicy {
+	if policy, ok := p.byDepartment[e.Department]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+// roundDays rounds days of leave to hundredths, so monthly accruals add up
+// without floating-point dust
+func roundDays(days float64) float64 {
+	return math.Round(days*100) / 100
+}
+
+// Leave accrual kind constants using iota
+const (
+	LeaveAccrued   = iota // leave earned under the policy
+	LeaveCarried          // unused leave kept into the new year
+	LeaveForfeited        // unused leave over the carry-over cap, lost at the year end
+	LeaveExpired          // carried-over leave not taken in time
+)
+
+// LeaveAccrualToString converts a leave accrual kind constant to string
+func LeaveAccrualToString(kind int) string {
+	switch kind {
+	case LeaveAccrued:
+		return "Accrued"
+	case LeaveCarried:
+		return "Carried over"
+	case LeaveForfeited:
+		return "Forfeited"
+	case LeaveExpired:
+		return "Expired"
+	default:
+		return "Unknown"
+	}
+}
+
+// LeaveAccrual is a change the leave policies made to an employee's balance
+type LeaveAccrual struct {
+	EmployeeID int
+	Month      time.Time // the first day of the month it was made for
+	Kind       int
+	Days       float64
+}
+
+// Accrue brings every employee's leave up to date with the policies, month
+// by month through the month of now, and returns the changes it made.
+// Months already accrued are skipped, so it can run any number of times;
+// the first run starts from the month of now.
+func (l *Leave) Accrue(user User, policies *LeavePolicies, now time.Time) ([]LeaveAccrual, error) {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return nil, err
+	}
+	return l.accrue(policies, now)
+}
+
+// accrue is Accrue for the scheduler, which acts for no user.
+//
+// At the start of each leave year, which is the calendar year, the balance
+// left over is cut to the policy's carry-over cap and the rest forfeited;
+// the days kept are taken first and expire CarryOverMonths later. Monthly
+// policies then add a twelfth of the year's days each month, and annual
+// ones the year's days at once, prorated for employees who join during the
+// year. Employees accrue from the month they join.
+func (l *Leave) accrue(policies *LeavePolicies, now time.Time) ([]LeaveAccrual, error) {
+	employees, err := employeeValues(l.manager)
+	if err != nil {
+		return nil, err
+	}
+	sortEmployeeValues(employees, OrderByID)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	local := now.In(userLocation)
+	current := dateOf(local.Year(), local.Month(), 1)
+	month := current
+	if !l.accrued.IsZero() {
+		month = l.accrued.In(userLocation).AddDate(0, 1, 0)
+		month = dateOf(month.Year(), month.Month(), 1)
+	}
+	if month.After(current) {
+		return nil, nil
+	}
+
+	accounts := make(map[int]LeaveAccount, len(l.accounts))
+	for id, a := range l.accounts {
+		accounts[id] = *a
+	}
+	var changes []LeaveAccrual
+	for ; !month.After(current); month = month.In(userLocation).AddDate(0, 1, 0) {
+		m := month.In(userLocation)
+		next := dateOf(m.Year(), m.Month()+1, 1)
+		for i := range employees {
+			e := &employees[i]
+			policy := policies.For(e)
+			if policy == nil || !e.JoinDate.Before(next) {
+				continue
+			}
+			change := func(kind int, days float64) {
+				if days > 0 {
+					changes = append(changes, LeaveAccrual{EmployeeID: e.ID, Month: month, Kind: kind, Days: roundDays(days)})
+				}
+			}
+
+			a := l.account(e.ID)
+			if a.Year != 0 && a.Year < m.Year() {
+				kept := min(a.Balance, policy.CarryOver)
+				change(LeaveForfeited, a.Balance-kept)
+				change(LeaveCarried, kept)
+				a.Balance, a.Carried = kept, kept
+			}
+			if policy.CarryOverMonths > 0 && int(m.Month()) == 1+policy.CarryOverMonths && a.Carried > 0 {
+				change(LeaveExpired, a.Carried)
+				a.Balance, a.Carried = roundDays(a.Balance-a.Carried), 0
+			}
+
+			var earned float64
+			switch policy.accrual {
+			case AccrueMonthly:
+				earned = policy.Days / 12
+			case AccrueAnnually:
+				if a.Year < m.Year() {
+					// Joiners get the months of the year left from the month
+					// they joined, in half days; everyone else the whole year
+					from := time.January
+					if joined := e.JoinDate.In(userLocation); joined.Year() == m.Year() {
+						from = joined.Month()
+					}
+					earned = math.Round(policy.Days*float64(13-from)/12*2) / 2
+				}
+			}
+			change(LeaveAccrued, earned)
+			a.Balance = roundDays(a.Balance + earned)
+			a.Year = m.Year()
+		}
+	}
+
+	old := l.accrued
+	l.accrued = current
+	if err := l.save(); err != nil {
+		l.accrued = old
+		for id := range l.accounts {
+			if a, ok := accounts[id]; ok {
+				*l.accounts[id] = a
+			} else {
+				delete(l.accounts, id)
+			}
+		}
+		return nil, err
+	}
+	return changes, nil
+}
+
+// writeLeavePolicies writes the leave policies and who they cover
+func writeLeavePolicies(w io.Writer, policies *LeavePolicies) {
+	fmt.Fprintln(w, "\n=== Leave Policies ===")
+	fmt.Fprintf(w, "%-16s %-8s %6s %10s %-16s %s\n", "Policy", "Accrual", "Days", "Carry-over", "Carried expires", "Departments")
+	fmt.Fprintln(w, strings.Repeat("-", 90))
+	for _, p := range policies.Policies {
+		expires := "never"
+		if p.CarryOverMonths > 0 {
+			expires = time.Month(p.CarryOverMonths).String() + " end"
+		}
+		departments := strings.Join(p.Departments, ", ")
+		if departments == "" {
+			departments = "(default)"
+		}
+		fmt.Fprintf(w, "%-16s %-8s %6.1f %10.1f %-16s %s\n", p.Name, AccrualToString(p.accrual), p.Days, p.CarryOver, expires, departments)
+	}
+}
+
+// writeLeaveAccruals writes the changes an accrual run made, naming each
+// employee from the manager
+func writeLeaveAccruals(w io.Writer, changes []LeaveAccrual, manager EmployeeManager) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "\nLeave is already up to date.")
+		return
+	}
+	fmt.Fprintf(w, "\n%-8s %-6s %-20s %-13s %8s\n", "Month", "ID", "Employee", "Change", "Days")
+	fmt.Fprintln(w, strings.Repeat("-", 60))
+	for _, c := range changes {
+		var name string
+		if e, err := manager.GetEmployee(c.EmployeeID); err == nil {
+			name = e.Name
+		}
+		fmt.Fprintf(w, "%-8s %-6d %-20s %-13s %8.2f\n", c.Month.In(userLocation).Format(monthLayout), c.EmployeeID, name,
+			LeaveAccrualToString(c.Kind), c.Days)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestAnnualAccrualProratesFromJoining(t *testing.T) {
+	policies, err := ParseLeavePolicies([]byte(`{"policies": [{"name": "standard", "accrual": "annual", "days": 24}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewInMemoryEmployeeManagerWithClock(SystemClock{})
+	veteran := &Employee{Name: "Ann", Position: "Engineer", Salary: 90000, Department: Engineering, JoinDate: dateOf(2020, 3, 1)}
+	joiner := &Employee{Name: "Bob", Position: "Engineer", Salary: 80000, Department: Engineering, JoinDate: dateOf(2024, 3, 15)}
+	for _, e := range []*Employee{veteran, joiner} {
+		if err := manager.AddEmployee(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leave, err := NewLeave(manager, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Accrual starts in June, after both have joined
+	if _, err := leave.accrue(policies, dateOf(2024, 6, 10)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		e    *Employee
+		days float64
+	}{{veteran, 24}, {joiner, 20}} {
+		if got := leave.balance(want.e.ID); got != want.days {
+			t.Errorf("%s, who joined %s, accrued %.1f days, want %.1f", want.e.Name, want.e.JoinDate.Format("2006-01-02"), got, want.days)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity constants using iota, from least to most severe
+const (
+	SeverityInfo = iota
+	SeverityWarning
+	SeverityError
+)
+
+// SeverityToString converts a severity constant to string
+func SeverityToString(severity int) string {
+	switch severity {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToSeverity converts a string to a severity constant
+func StringToSeverity(s string) (int, error) {
+	for severity := SeverityInfo; severity <= SeverityError; severity++ {
+		if strings.EqualFold(s, SeverityToString(severity)) {
+			return severity, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: unknown severity %q (info, warning or error)", ErrInvalidInput, s)
+}
+
+// Issue is a data quality problem found in an employee record
+type Issue struct {
+	Severity   int    `json:"-"`
+	Check      string `json:"check"`
+	EmployeeID int    `json:"employee_id"`
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	Fix        string `json:"fix"`
+}
+
+// MarshalJSON writes the severity by name
+func (i Issue) MarshalJSON() ([]byte, error) {
+	type issue Issue
+	return json.Marshal(struct {
+		Severity string `json:"severity"`
+		issue
+	}{SeverityToString(i.Severity), issue(i)})
+}
+
+// lintCheck looks for one kind of problem in the dataset
+type lintCheck struct {
+	Name string
+	Run  func(employees []*Employee, now time.Time) []Issue
+}
+
+// lintChecks are the checks Lint runs, in the order their issues are listed
+// within a severity
+var lintChecks = []lintCheck{
+	{"missing-position", lintMissingPosition},
+	{"salary-band", lintSalaryBand},
+	{"future-join-date", lintFutureJoinDate},
+	{"duplicate-name", lintDuplicateNames},
+}
+
+func lintMissingPosition(employees []*Employee, now time.Time) []Issue {
+	var issues []Issue
+	for _, e := range employees {
+		if strings.TrimSpace(e.Position) == "" {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning, EmployeeID: e.ID, Name: e.Name,
+				Message: "has no position",
+				Fix:     "set the position, starting from a position template where one fits",
+			})
+		}
+	}
+	return issues
+}
+
+func lintSalaryBand(employees []*Employee, now time.Time) []Issue {
+	var issues []Issue
+	for _, e := range employees {
+		t, ok := FindTemplate(e.Position)
+		if !ok || t.InBand(e.Salary) {
+			continue
+		}
+		fix := fmt.Sprintf("raise the salary to at least $%.2f or review the position", t.MinSalary)
+		if e.Salary > t.MaxSalary {
+			fix = fmt.Sprintf("move to a more senior position or record an exception to the $%.2f maximum", t.MaxSalary)
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityWarning, EmployeeID: e.ID, Name: e.Name,
+			Message: fmt.Sprintf("salary $%.2f is outside the %s band of $%.2f-$%.2f", e.Salary, t.Position, t.MinSalary, t.MaxSalary),
+			Fix:     fix,
+		})
+	}
+	return issues
+}
+
+func lintFutureJoinDate(employees []*Employee, now time.Time) []Issue {
+	var issues []Issue
+	for _, e := range employees {
+		if e.JoinDate.After(now) {
+			issues = append(issues, Issue{
+				Severity: SeverityError, EmployeeID: e.ID, Name: e.Name,
+				Message: fmt.Sprintf("join date %s is in the future", formatDate(e.JoinDate)),
+				Fix:     "correct the join date, or remove the record and hire them through recruitment",
+			})
+		}
+	}
+	return issues
+}
+
+func lintDuplicateNames(employees []*Employee, now time.Time) []Issue {
+	byName := make(map[string][]*Employee)
+	for _, e := range employees {
+		byName[normalizeName(e.Name)] = append(byName[normalizeName(e.Name)], e)
+	}
+
+	var issues []Issue
+	for _, group := range byName {
+		if len(group) < 2 {
+			continue
+		}
+		// Records that also agree on everything else are likely the same person
+		severity := SeverityWarning
+		for _, e := range group[1:] {
+			if !sameDay(e.BirthDate, group[0].BirthDate) || e.Department != group[0].Department {
+				severity = SeverityInfo
+			}
+		}
+		for _, e := range group {
+			others := make([]string, 0, len(group)-1)
+			for _, other := range group {
+				if other.ID != e.ID {
+					others = append(others, fmt.Sprintf("%d", other.ID))
+				}
+			}
+			issues = append(issues, Issue{
+				Severity: severity, EmployeeID: e.ID, Name: e.Name,
+				Message: fmt.Sprintf("shares a name with ID %s", strings.Join(others, ", ")),
+				Fix:     "remove one record if they are the same person; otherwise add a birth date or middle initial to tell them apart",
+			})
+		}
+	}
+	return issues
+}
+
+// lintSuccession reports successors the succession plan names who are not employees
+func lintSuccession(plan *SuccessionPlan, employees []*Employee) []Issue {
+	ids := make(map[int]bool, len(employees))
+	for _, e := range employees {
+		ids[e.ID] = true
+	}
+
+	var issues []Issue
+	for _, role := range plan.KeyRoles() {
+		for _, s := range role.Successors {
+			if ids[s.EmployeeID] {
+				continue
+			}
+			issues = append(issues, Issue{
+				Severity: SeverityError, Check: "orphaned-reference", EmployeeID: s.EmployeeID,
+				Message: fmt.Sprintf("is a successor for %s in %s but is not an employee", role.Position, role.Department),
+				Fix:     "withdraw the nomination in Succession Planning",
+			})
+		}
+	}
+	return issues
+}
+
+// Lint runs every check over the employees, and checks the references of a
+// succession plan when one is given. Issues are ordered from most to least
+// severe, then by employee ID.
+func Lint(employees []*Employee, now time.Time, plan *SuccessionPlan) []Issue {
+	issues := make([]Issue, 0)
+	for _, check := range lintChecks {
+		for _, issue := range check.Run(employees, now) {
+			issue.Check = check.Name
+			issues = append(issues, issue)
+		}
+	}
+	if plan != nil {
+		issues = append(issues, lintSuccession(plan, employees)...)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Severity != issues[j].Severity {
+			return issues[i].Severity > issues[j].Severity
+		}
+		return issues[i].EmployeeID < issues[j].EmployeeID
+	})
+	return issues
+}
+
+// writeIssues lists the issues with their fixes, then counts them by severity
+func writeIssues(w io.Writer, issues []Issue) {
+	if len(issues) == 0 {
+		fmt.Fprintln(w, successText("No data quality issues found."))
+		return
+	}
+
+	counts := make([]int, SeverityError+1)
+	for _, issue := range issues {
+		counts[issue.Severity]++
+		label := fmt.Sprintf("%-7s", SeverityToString(issue.Severity))
+		switch issue.Severity {
+		case SeverityError:
+			label = errorText(label)
+		case SeverityWarning:
+			label = warningText(label)
+		}
+		subject := fmt.Sprintf("ID %d", issue.EmployeeID)
+		if issue.Name != "" {
+			subject = fmt.Sprintf("%s (ID %d)", issue.Name, issue.EmployeeID)
+		}
+		fmt.Fprintf(w, "%s %-18s %s %s\n", label, issue.Check, subject, issue.Message)
+		fmt.Fprintf(w, "%-26s fix: %s\n", "", issue.Fix)
+	}
+	fmt.Fprintf(w, "\n%d error(s), %d warning(s), %d info\n", counts[SeverityError], counts[SeverityWarning], counts[SeverityInfo])
+}
+
+// dataQualityInteractive scans the employees and the workspace's succession plan for issues
+func dataQualityInteractive(manager EmployeeManager, plan *SuccessionPlan) error {
+	fmt.Println("\n" + headerText("=== Data Quality ==="))
+	employees, err := manager.ListEmployees()
+	if err != nil {
+		return err
+	}
+	writeIssues(os.Stdout, Lint(employees, appClock.Now(), plan))
+	return nil
+}
+
+// runLint implements the lint command, which scans a storage backend for data
+// quality issues and fails when any is at least as severe as -fail-on
+func runLint(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to scan (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	format := fs.String("format", "text", "output format: text or json")
+	minSeverity := fs.String("min-severity", "info", "list issues at least this severe: info, warning or error")
+	failOn := fs.String("fail-on", "error", "exit with an error when an issue is at least this severe")
+	readOnly := fs.Bool("read-only", false, "open the store read-only, following the instance that writes to it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("%w: unknown format %q (text or json)", ErrInvalidInput, *format)
+	}
+	least, err := StringToSeverity(*minSeverity)
+	if err != nil {
+		return err
+	}
+	failAt, err := StringToSeverity(*failOn)
+	if err != nil {
+		return err
+	}
+
+	open := OpenStorage
+	if *readOnly {
+		open = OpenStorageReadOnly
+	}
+	store, err := open(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	employees, err := store.ListEmployees()
+	if err != nil {
+		return err
+	}
+
+	issues := make([]Issue, 0)
+	failing := 0
+	for _, issue := range Lint(employees, appClock.Now(), nil) {
+		if issue.Severity >= failAt {
+			failing++
+		}
+		if issue.Severity >= least {
+			issues = append(issues, issue)
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		writeIssues(stdout, issues)
+	}
+	if failing > 0 {
+		return fmt.Errorf("%d issue(s) at %s severity or above", failing, SeverityToString(failAt))
+	}
+	return nil
+}
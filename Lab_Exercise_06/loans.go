@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrLoanNotFound is returned when no loan has the given ID
+var ErrLoanNotFound = errorsx.New(errorsx.ErrNotFound, "loan_id", "loan not found")
+
+// maxInstallments is the most monthly installments a loan can be repaid in
+const maxInstallments = 120
+
+// Loan kind constants using iota
+const (
+	LoanKindLoan = iota
+	LoanKindAdvance
+)
+
+// LoanKindToString converts a loan kind constant to string
+func LoanKindToString(kind int) string {
+	switch kind {
+	case LoanKindLoan:
+		return "Loan"
+	case LoanKindAdvance:
+		return "Salary advance"
+	default:
+		return "Unknown"
+	}
+}
+
+// Loan is money lent to an employee, or pay advanced to them, repaid in
+// equal monthly installments deducted from their pay. Loans are interest free.
+type Loan struct {
+	ID           int             `json:"id"`
+	EmployeeID   int             `json:"employee_id"`
+	Kind         int             `json:"kind"`
+	Currency     string          `json:"currency"`
+	Principal    int64           `json:"principal"` // in minor units of the currency
+	Installments int             `json:"installments"`
+	FirstMonth   time.Time       `json:"first_month"` // first day of the month of the first installment
+	Issued       time.Time       `json:"issued"`
+	Settlement   *LoanSettlement `json:"settlement,omitempty"`
+}
+
+// LoanSettlement is the early repayment of what was left of a loan
+type LoanSettlement struct {
+	Month  time.Time `json:"month"`  // first day of the first month without an installment
+	Amount int64     `json:"amount"` // the balance repaid
+	// ThroughPayroll deducts the balance from the month's pay instead of it
+	// being repaid separately
+	ThroughPayroll bool `json:"through_payroll"`
+}
+
+// LoanInstallment is a scheduled repayment of a loan
+type LoanInstallment struct {
+	Month   time.Time // first day of the month it is deducted in
+	Amount  int64
+	Balance int64 // left to repay after it
+}
+
+// Schedule returns the loan's installments. The principal is split evenly,
+// the first installments taking a minor unit more when it does not divide.
+// A settlement replaces the installments from its month on.
+func (l *Loan) Schedule() []LoanInstallment {
+	schedule := make([]LoanInstallment, 0, l.Installments)
+	balance := l.Principal
+	for i := 0; i < l.Installments; i++ {
+		month := addMonths(l.FirstMonth, i)
+		if l.Settlement != nil && !month.Before(l.Settlement.Month) {
+			break
+		}
+		amount := l.Principal / int64(l.Installments)
+		if int64(i) < l.Principal%int64(l.Installments) {
+			amount++
+		}
+		balance -= amount
+		schedule = append(schedule, LoanInstallment{Month: month, Amount: amount, Balance: balance})
+	}
+	return schedule
+}
+
+// BalanceAfter returns what is left to repay after the installments up to
+// and including those of a month
+func (l *Loan) BalanceAfter(month time.Time) int64 {
+	if l.Settlement != nil && !month.Before(l.Settlement.Month) {
+		return 0
+	}
+	balance := l.Principal
+	for _, i := range l.Schedule() {
+		if i.Month.After(month) {
+			break
+		}
+		balance = i.Balance
+	}
+	return balance
+}
+
+// deduction returns the pay item the loan deducts in a month, if any
+func (l *Loan) deduction(month time.Time) (PayItem, bool) {
+	if s := l.Settlement; s != nil && s.ThroughPayroll && s.Month.Equal(month) {
+		name := fmt.Sprintf("%s #%d settlement", LoanKindToString(l.Kind), l.ID)
+		return PayItem{Name: name, Kind: Deduction, exact: -float64(s.Amount)}, true
+	}
+	for n, i := range l.Schedule() {
+		if i.Month.Equal(month) {
+			name := fmt.Sprintf("%s #%d installment %d of %d", LoanKindToString(l.Kind), l.ID, n+1, l.Installments)
+			return PayItem{Name: name, Kind: Deduction, exact: -float64(i.Amount)}, true
+		}
+	}
+	return PayItem{}, false
+}
+
+// addMonths returns the first day of the month some months after the month
+// starting on a day
+func addMonths(month time.Time, months int) time.Time {
+	local := month.In(userLocation)
+	return dateOf(local.Year(), local.Month()+time.Month(months), 1)
+}
+
+// Loans records employees' loans and salary advances. Issuing and settling
+// them is restricted to users allowed to edit personal data; payroll runs
+// deduct their installments.
+type Loans struct {
+	mu      sync.Mutex
+	manager EmployeeManager
+	loans   []*Loan
+	nextID  int
+	path    string // JSON file the loans are kept in, or "" for memory only
+}
+
+// NewLoans creates loans for employees of the given manager, reading them
+// from the file at path if there is one
+func NewLoans(manager EmployeeManager, path string) (*Loans, error) {
+	l := &Loans{manager: manager, nextID: 1, path: path}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.loans); err != nil {
+		return nil, fmt.Errorf("reading loans %s: %w", path, err)
+	}
+	for _, loan := range l.loans {
+		l.nextID = max(l.nextID, loan.ID+1)
+	}
+	return l, nil
+}
+
+// save writes the loans to the file. The caller must hold the lock. The
+// file is written under a temporary name and renamed, and an interrupt
+// waits for the save, so it is never left half written.
+func (l *Loans) save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.loans, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := l.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, l.path)
+	})
+}
+
+// Issue lends an employee an amount in the currency they are paid in,
+// repaid in monthly installments from the month starting on firstMonth
+func (l *Loans) Issue(user User, employeeID, kind int, amount float64, installments int, firstMonth time.Time) (*Loan, error) {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return nil, err
+	}
+	if _, err := l.manager.GetEmployee(employeeID); err != nil {
+		return nil, err
+	}
+	currency := payrollConfigOf(employeeID).currency
+	principal := RoundHalfUp.round(currency.minorUnits(amount))
+	switch {
+	case LoanKindToString(kind) == "Unknown":
+		return nil, fmt.Errorf("%w: please select a valid loan kind", ErrInvalidInput)
+	case principal <= 0:
+		return nil, fmt.Errorf("%w: the amount lent must be more than 0", ErrInvalidInput)
+	case installments < 1 || installments > maxInstallments:
+		return nil, fmt.Errorf("%w: a loan is repaid in 1 to %d installments", ErrInvalidInput, maxInstallments)
+	case int64(installments) > principal:
+		return nil, fmt.Errorf("%w: %s cannot be split into %d installments", ErrInvalidInput, currency.Format(principal), installments)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	loan := &Loan{
+		ID:           l.nextID,
+		EmployeeID:   employeeID,
+		Kind:         kind,
+		Currency:     currency.Code,
+		Principal:    principal,
+		Installments: installments,
+		FirstMonth:   addMonths(firstMonth, 0),
+		Issued:       clockOf(l.manager).Now(),
+	}
+	l.loans = append(l.loans, loan)
+	l.nextID++
+	if err := l.save(); err != nil {
+		l.loans = l.loans[:len(l.loans)-1]
+		l.nextID--
+		return nil, err
+	}
+	copied := *loan
+	return &copied, nil
+}
+
+// Settle repays what is left of a loan in the month starting on a day,
+// ending its installments, and returns the settled loan. Through payroll,
+// the balance is deducted from that month's pay.
+func (l *Loans) Settle(user User, loanID int, month time.Time, throughPayroll bool) (*Loan, error) {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var loan *Loan
+	for _, candidate := range l.loans {
+		if candidate.ID == loanID {
+			loan = candidate
+		}
+	}
+	if loan == nil {
+		return nil, ErrLoanNotFound.WithValue(loanID)
+	}
+	if loan.Settlement != nil {
+		return nil, fmt.Errorf("%w: loan #%d was already settled in %s", ErrInvalidInput, loanID, loan.Settlement.Month.In(userLocation).Format(monthLayout))
+	}
+	month = addMonths(month, 0)
+	balance := loan.BalanceAfter(addMonths(month, -1))
+	if balance == 0 {
+		return nil, fmt.Errorf("%w: loan #%d is repaid by %s", ErrInvalidInput, loanID, month.In(userLocation).Format(monthLayout))
+	}
+
+	loan.Settlement = &LoanSettlement{Month: month, Amount: balance, ThroughPayroll: throughPayroll}
+	if err := l.save(); err != nil {
+		loan.Settlement = nil
+		return nil, err
+	}
+	copied := *loan
+	return &copied, nil
+}
+
+// ForEmployee returns the loans of an employee, oldest first
+func (l *Loans) ForEmployee(user User, employeeID int) ([]Loan, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var loans []Loan
+	for _, loan := range l.loans {
+		if loan.EmployeeID == employeeID {
+			loans = append(loans, *loan)
+		}
+	}
+	return loans, nil
+}
+
+// Deductions returns the loan repayments to deduct from an employee's pay
+// in the month starting on a day, in the currency given
+func (l *Loans) Deductions(employeeID int, month time.Time, currency Currency) ([]PayItem, error) {
+	if l == nil {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var items []PayItem
+	for _, loan := range l.loans {
+		if loan.EmployeeID != employeeID {
+			continue
+		}
+		item, ok := loan.deduction(month)
+		if !ok {
+			continue
+		}
+		if loan.Currency != currency.Code {
+			return nil, fmt.Errorf("%w: loan #%d is in %s but payroll is paid in %s", ErrInvalidInput, loan.ID, loan.Currency, currency.Code)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// writeLoans writes an employee's loans with their installments and what
+// is left to repay after the month of now
+func writeLoans(w io.Writer, loans []Loan, now time.Time) {
+	if len(loans) == 0 {
+		fmt.Fprintln(w, "\nNo loans or advances.")
+		return
+	}
+	month := addMonths(now, 0)
+	for _, loan := range loans {
+		c, _ := ParseCurrency(loan.Currency)
+		fmt.Fprintf(w, "\n%s #%d: %s %s in %d installment(s), issued %s\n", LoanKindToString(loan.Kind), loan.ID,
+			c.Format(loan.Principal), c.Code, loan.Installments, formatDate(loan.Issued))
+		fmt.Fprintf(w, "  %-8s %14s %14s\n", "Month", "Installment", "Balance")
+		for _, i := range loan.Schedule() {
+			fmt.Fprintf(w, "  %-8s %14s %14s\n", i.Month.In(userLocation).Format(monthLayout), c.Format(i.Amount), c.Format(i.Balance))
+		}
+		if s := loan.Settlement; s != nil {
+			how := "repaid separately"
+			if s.ThroughPayroll {
+				how = "deducted from pay"
+			}
+			fmt.Fprintf(w, "  %-8s %14s %14s  settled early, %s\n", s.Month.In(userLocation).Format(monthLayout), c.Format(s.Amount), c.Format(0), how)
+		}
+		fmt.Fprintf(w, "  Outstanding after %s: %s\n", month.In(userLocation).Format(monthLayout), c.Format(loan.BalanceAfter(month)))
+	}
+}
+
+// loansInteractive issues, shows and settles loans through user interaction
+func loansInteractive(loans *Loans, manager EmployeeManager, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Loans & Advances ==="))
+
+	// Check access before asking for any input
+	if err := user.Require(PermViewPersonalData); err != nil {
+		return err
+	}
+
+	id, err := readInt(reader, "Enter employee ID: ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n1. Issue loan")
+	fmt.Println("2. Issue salary advance")
+	fmt.Println("3. View loans and balances")
+	fmt.Println("4. Settle loan early")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+	now := clockOf(manager).Now()
+	readMonth := func(prompt string) (time.Time, error) {
+		return readValue(reader, prompt, func(input string) (time.Time, error) {
+			return parsePayrollMonth(input, now)
+		})
+	}
+
+	switch option {
+	case 1, 2:
+		kind := LoanKindLoan
+		if option == 2 {
+			kind = LoanKindAdvance
+		}
+		amount, err := readFloat(reader, fmt.Sprintf("Amount (%s): ", payrollConfigOf(id).currency.Code))
+		if err != nil {
+			return err
+		}
+		installments := 1
+		if kind == LoanKindLoan {
+			if installments, err = readInt(reader, "Monthly installments: "); err != nil {
+				return err
+			}
+		}
+		first, err := readMonth("First installment month (YYYY-MM; blank for this month): ")
+		if err != nil {
+			return err
+		}
+		loan, err := loans.Issue(user, id, kind, amount, installments, first)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("%s #%d issued successfully!", LoanKindToString(kind), loan.ID)))
+		writeLoans(os.Stdout, []Loan{*loan}, now)
+
+	case 3:
+		list, err := loans.ForEmployee(user, id)
+		if err != nil {
+			return err
+		}
+		writeLoans(os.Stdout, list, now)
+
+	case 4:
+		loanID, err := readInt(reader, "Loan number: ")
+		if err != nil {
+			return err
+		}
+		month, err := readMonth("Settle in month (YYYY-MM; blank for this month): ")
+		if err != nil {
+			return err
+		}
+		throughPayroll, err := readValue(reader, "Deduct the balance from that month's pay? (y/n): ", func(input string) (bool, error) {
+			switch strings.ToLower(input) {
+			case "y", "yes":
+				return true, nil
+			case "n", "no":
+				return false, nil
+			}
+			return false, fmt.Errorf("%w: please answer y or n", ErrInvalidInput)
+		})
+		if err != nil {
+			return err
+		}
+		list, err := loans.ForEmployee(user, id)
+		if err != nil {
+			return err
+		}
+		owned := false
+		for _, loan := range list {
+			owned = owned || loan.ID == loanID
+		}
+		if !owned {
+			return ErrLoanNotFound.WithValue(loanID)
+		}
+		loan, err := loans.Settle(user, loanID, month, throughPayroll)
+		if err != nil {
+			return err
+		}
+		c, _ := ParseCurrency(loan.Currency)
+		fmt.Println("\n" + successText(fmt.Sprintf("Loan #%d settled with %s %s", loan.ID, c.Format(loan.Settlement.Amount), c.Code)))
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Work mode constants using iota
+const (
+	WorkOnSite = iota
+	WorkHybrid
+	WorkRemote
+)
+
+// WorkModeToString converts a work mode constant to string
+func WorkModeToString(mode int) string {
+	switch mode {
+	case WorkOnSite:
+		return "On-site"
+	case WorkHybrid:
+		return "Hybrid"
+	case WorkRemote:
+		return "Remote"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToWorkMode converts string to work mode constant
+func StringToWorkMode(mode string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "on-site", "onsite", "office":
+		return WorkOnSite, nil
+	case "hybrid":
+		return WorkHybrid, nil
+	case "remote":
+		return WorkRemote, nil
+	default:
+		return -1, fmt.Errorf("%w: work mode must be on-site, hybrid or remote", ErrInvalidInput)
+	}
+}
+
+// locationLabel describes where an employee works, such as "London (Hybrid)"
+func locationLabel(e *Employee) string {
+	switch {
+	case e.Location == "" && e.WorkMode == WorkOnSite:
+		return ""
+	case e.Location == "":
+		return WorkModeToString(e.WorkMode)
+	case e.WorkMode == WorkOnSite:
+		return e.Location
+	default:
+		return fmt.Sprintf("%s (%s)", e.Location, WorkModeToString(e.WorkMode))
+	}
+}
+
+// localTimeLabel shows an employee's time zone with the time there at now,
+// such as "Europe/London 14:05", or "" if they work in the user's
+func localTimeLabel(e *Employee, now time.Time) string {
+	if e.TimeZone == "" {
+		return ""
+	}
+	loc, err := loadTimeZone(e.TimeZone)
+	if err != nil {
+		return e.TimeZone
+	}
+	return fmt.Sprintf("%s %s", e.TimeZone, now.In(loc).Format("15:04"))
+}
+
+// parseTimeZone parses an IANA time zone name, blank for the user's
+func parseTimeZone(input string) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+	loc, err := loadTimeZone(input)
+	if err != nil {
+		return "", fmt.Errorf("%w: unknown time zone %q; use an IANA name such as Europe/London", ErrInvalidInput, input)
+	}
+	return loc.String(), nil
+}
+
+// readWorkplace reads where an employee works into e. With keep, blank
+// answers keep the current values; otherwise they mean on-site in the
+// user's time zone.
+func readWorkplace(reader *bufio.Reader, e *Employee, keep bool) error {
+	prompt := func(label, current string) string {
+		if keep {
+			return fmt.Sprintf("%s [%s]: ", label, current)
+		}
+		return label + ": "
+	}
+
+	location, err := readString(reader, prompt("Location (office, or city if remote; optional)", e.Location))
+	if err != nil {
+		return err
+	}
+	if location != "" || !keep {
+		e.Location = location
+	}
+
+	mode, err := readValue(reader, prompt("Work mode (on-site, hybrid, remote)", WorkModeToString(e.WorkMode)), func(input string) (int, error) {
+		if input == "" {
+			if keep {
+				return e.WorkMode, nil
+			}
+			return WorkOnSite, nil
+		}
+		return StringToWorkMode(input)
+	})
+	if err != nil {
+		return err
+	}
+	e.WorkMode = mode
+
+	zone, err := readValue(reader, prompt("Time zone (IANA name, e.g. Europe/London; blank for yours)", e.TimeZone), func(input string) (string, error) {
+		if input == "" && keep {
+			return e.TimeZone, nil
+		}
+		return parseTimeZone(input)
+	})
+	if err != nil {
+		return err
+	}
+	e.TimeZone = zone
+	return nil
+}
+
+// LocationHeadcount is the headcount at a location by work mode, with the
+// time zones its employees work in
+type LocationHeadcount struct {
+	Location  string // "" for employees with no location
+	Modes     [3]int // headcount by work mode
+	TimeZones []string
+}
+
+// Total returns the headcount at the location
+func (l LocationHeadcount) Total() int {
+	return l.Modes[WorkOnSite] + l.Modes[WorkHybrid] + l.Modes[WorkRemote]
+}
+
+// HeadcountByLocation counts employees by location, largest first. Locations
+// differing only in case are counted together.
+func HeadcountByLocation(employees []Employee) []LocationHeadcount {
+	byKey := make(map[string]*LocationHeadcount)
+	for _, e := range employees {
+		key := strings.ToLower(strings.TrimSpace(e.Location))
+		l := byKey[key]
+		if l == nil {
+			l = &LocationHeadcount{Location: strings.TrimSpace(e.Location)}
+			byKey[key] = l
+		}
+		if e.WorkMode >= 0 && e.WorkMode < len(l.Modes) {
+			l.Modes[e.WorkMode]++
+		}
+		zone := e.TimeZone
+		if zone == "" {
+			zone = userLocation.String()
+		}
+		if !slices.Contains(l.TimeZones, zone) {
+			l.TimeZones = append(l.TimeZones, zone)
+		}
+	}
+
+	out := make([]LocationHeadcount, 0, len(byKey))
+	for _, l := range byKey {
+		sort.Strings(l.TimeZones)
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total() != out[j].Total() {
+			return out[i].Total() > out[j].Total()
+		}
+		return strings.ToLower(out[i].Location) < strings.ToLower(out[j].Location)
+	})
+	return out
+}
+
+// writeLocationReport writes the headcount at each location by work mode
+func writeLocationReport(w io.Writer, locations []LocationHeadcount) {
+	fmt.Fprintln(w, "\n=== Headcount by Location ===")
+	fmt.Fprintf(w, "%-20s %8s %8s %8s %8s  %s\n", "Location", "On-site", "Hybrid", "Remote", "Total", "Time Zones")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	var total LocationHeadcount
+	for _, l := range locations {
+		name := l.Location
+		if name == "" {
+			name = "(no location)"
+		}
+		fmt.Fprintf(w, "%-20s %8d %8d %8d %8d  %s\n", name,
+			l.Modes[WorkOnSite], l.Modes[WorkHybrid], l.Modes[WorkRemote], l.Total(), strings.Join(l.TimeZones, ", "))
+		for i, n := range l.Modes {
+			total.Modes[i] += n
+		}
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	fmt.Fprintf(w, "%-20s %8d %8d %8d %8d\n", "Total",
+		total.Modes[WorkOnSite], total.Modes[WorkHybrid], total.Modes[WorkRemote], total.Total())
+}
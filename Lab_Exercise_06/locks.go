@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrLockHeld is returned when a batch operation is already running, in this
+// process or in another instance sharing the same backend
+var ErrLockHeld = errorsx.New(errorsx.ErrConflict, "", "already running")
+
+// Locker takes named locks shared by every instance that uses the same
+// backend, so batch operations such as imports run one at a time
+type Locker interface {
+	// TryLock takes the lock without waiting, returning ErrLockHeld if it is
+	// taken. The release function gives it back.
+	TryLock(ctx context.Context, name string) (release func() error, err error)
+}
+
+// localLocker holds locks within this process, for backends that are not
+// shared between instances
+type localLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// processLocker is the locker of managers without a shared one
+var processLocker = &localLocker{held: make(map[string]bool)}
+
+// TryLock takes a lock held by this process
+func (l *localLocker) TryLock(ctx context.Context, name string) (func() error, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[name] {
+		return nil, fmt.Errorf("%w in this process", ErrLockHeld)
+	}
+	l.held[name] = true
+	return func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+		return nil
+	}, nil
+}
+
+// lockerOf returns the manager's shared locker, or the process's own
+func lockerOf(manager EmployeeManager) Locker {
+	if l, ok := capability[Locker](manager); ok {
+		return l
+	}
+	return processLocker
+}
+
+// lockBatch takes the named lock before a batch operation, failing with
+// ErrLockHeld rather than running it twice at once. The returned function
+// releases the lock.
+func lockBatch(manager EmployeeManager, name string) (func(), error) {
+	release, err := lockerOf(manager).TryLock(context.Background(), name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start %s: %w", name, err)
+	}
+	return func() {
+		if err := release(); err != nil {
+			log.Printf("Releasing the %s lock: %v", name, err)
+		}
+	}, nil
+}
+
+// TryLock takes a session-level advisory lock on a connection held until the
+// lock is released. If the process dies, the session ends and the lock is
+// released by the server.
+func (m *PostgresEmployeeManager) TryLock(ctx context.Context, name string) (func() error, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := "employees:" + name
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, fmt.Errorf("%w on another instance (postgres advisory lock)", ErrLockHeld)
+	}
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, key)
+		return err
+	}, nil
+}
+
+// redisLease is how long a Redis lock lasts without being renewed. The holder
+// renews it at a third of that, so a process that dies frees it within a lease.
+const redisLease = 30 * time.Second
+
+// redisRenewScript extends a lease only if it still belongs to the holder
+const redisRenewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+
+// redisReleaseScript deletes a lease only if it still belongs to the holder
+const redisReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+// TryLock takes a lease in Redis, renewed in the background until released.
+// Unlike reads, locking does not fall back when Redis is down: running
+// unlocked could apply a batch twice.
+func (c *CachingManager) TryLock(ctx context.Context, name string) (func() error, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	key, value := c.prefix+"lock:"+name, hex.EncodeToString(token)
+	lease := strconv.FormatInt(redisLease.Milliseconds(), 10)
+
+	if _, err := c.client.Do("SET", key, value, "NX", "PX", lease); err != nil {
+		if errors.Is(err, errRedisNil) {
+			return nil, fmt.Errorf("%w on another instance (redis lease)", ErrLockHeld)
+		}
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(redisLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reply, err := c.client.Do("EVAL", redisRenewScript, "1", key, value, lease)
+				if err != nil || reply == int64(0) {
+					log.Printf("Lost the %s lease in Redis (%v); another instance may start it", name, err)
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(stop)
+		<-done
+		_, err := c.client.Do("EVAL", redisReleaseScript, "1", key, value)
+		return err
+	}, nil
+}
@@ -1,67 +1,72 @@
+// The API's routes use method and wildcard patterns, which the server
+// matches only with the Go 1.22 mux. go.mod asks for it; this keeps it on
+// when the files are built outside the module.
+//go:debug httpmuxgo121=0
+
 package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// Custom error types
+var (
+	ErrEmployeeNotFound = errorsx.New(errorsx.ErrNotFound, "id", "employee not found")
+	ErrInvalidID        = errorsx.New(errorsx.ErrValidation, "id", "invalid employee ID")
+	ErrDuplicateID      = errorsx.New(errorsx.ErrConflict, "id", "employee ID already exists")
+	ErrInvalidInput     = errorsx.New(errorsx.ErrValidation, "", "invalid input")
+	ErrCancelled        = errors.New("operation cancelled")
 )
 
-// Department constants using iota
+// Reasons a field can be invalid, reported by FieldError
 const (
-	HR = iota
-	Engineering
-	Finance
-	Marketing
-	Operations
+	ReasonRequired          = "REQUIRED"
+	ReasonInvalidNumber     = "INVALID_NUMBER"
+	ReasonNegative          = "NEGATIVE"
+	ReasonUnknownDepartment = "UNKNOWN_DEPARTMENT"
+	ReasonInvalidDate       = "INVALID_DATE"
+	ReasonFutureDate        = "FUTURE_DATE"
+	ReasonImmutable         = "IMMUTABLE"
+	ReasonInvalidType       = "INVALID_TYPE"
+	ReasonUnknownWorkMode   = "UNKNOWN_WORK_MODE"
+	ReasonUnknownTimeZone   = "UNKNOWN_TIME_ZONE"
 )
 
-// DepartmentToString converts department constant to string
-func DepartmentToString(dept int) string {
-	switch dept {
-	case HR:
-		return "HR"
-	case Engineering:
-		return "Engineering"
-	case Finance:
-		return "Finance"
-	case Marketing:
-		return "Marketing"
-	case Operations:
-		return "Operations"
-	default:
-		return "Unknown"
-	}
-}
-
-// StringToDepartment converts string to department constant
-func StringToDepartment(dept string) (int, error) {
-	switch strings.ToLower(dept) {
-	case "hr":
-		return HR, nil
-	case "engineering":
-		return Engineering, nil
-	case "finance":
-		return Finance, nil
-	case "marketing":
-		return Marketing, nil
-	case "operations":
-		return Operations, nil
-	default:
-		return -1, errors.New("invalid department")
-	}
+// FieldError is invalid input in one field of an employee. It matches
+// ErrInvalidInput, and reads the same as the plain errors made from it.
+type FieldError struct {
+	Field   string // the field's name in the API, such as join_date
+	Reason  string // one of the Reason constants
+	Message string
 }
 
-// Custom error types
-var (
-	ErrEmployeeNotFound = errors.New("employee not found")
-	ErrInvalidID        = errors.New("invalid employee ID")
-	ErrDuplicateID      = errors.New("employee ID already exists")
-	ErrInvalidInput     = errors.New("invalid input")
-)
+// fieldError makes a FieldError with a formatted message
+func fieldError(field, reason, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *FieldError) Error() string {
+	return ErrInvalidInput.Error() + ": " + e.Message
+}
+
+// Unwrap returns ErrInvalidInput
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidInput
+}
 
 // Employee struct to store employee information
 type Employee struct {
@@ -69,23 +74,94 @@ type Employee struct {
 	Name       string
 	Position   string
 	Salary     float64
-	Department int
+	Department Department
 	JoinDate   time.Time
+	BirthDate  time.Time // optional, zero if unknown
+	// ProbationEnd is the last day of probation, zero if not on probation
+	ProbationEnd time.Time
+	Location     string // the office worked from, or the city if remote; optional
+	WorkMode     int    // WorkOnSite, WorkHybrid or WorkRemote
+	// TimeZone is the IANA time zone the employee works in, empty for the user's
+	TimeZone string
+	// Version is 1 when the employee is added and goes up by one with every
+	// update and transfer. Managers set it; the version passed to them is ignored.
+	Version int
 }
 
-// CalculateExperience calculates years of experience
+// CalculateExperience calculates years of experience as of today on the
+// application clock. Future join dates count as no experience.
 func (e *Employee) CalculateExperience() float64 {
-	duration := time.Since(e.JoinDate)
-	return duration.Hours() / 24 / 365
+	return e.ExperienceAt(appClock.Now())
 }
 
 // String returns a formatted string representation of the employee
 func (e *Employee) String() string {
-	return fmt.Sprintf(
-		"ID: %d\nName: %s\nPosition: %s\nSalary: $%.2f\nDepartment: %s\nJoin Date: %s\nExperience: %.1f years",
-		e.ID, e.Name, e.Position, e.Salary, DepartmentToString(e.Department),
-		e.JoinDate.Format("2006-01-02"), e.CalculateExperience(),
+	s := fmt.Sprintf(
+		"ID: %d\nName: %s\nPosition: %s\nSalary: $%.2f\nDepartment: %s\nJoin Date: %s\nExperience: %s",
+		e.ID, e.Name, e.Position, e.Salary, e.Department,
+		formatDate(e.JoinDate), e.Tenure(),
 	)
+	if !e.BirthDate.IsZero() {
+		s += fmt.Sprintf("\nBirth Date: %s", formatDate(e.BirthDate))
+	}
+	if !e.ProbationEnd.IsZero() {
+		s += fmt.Sprintf("\nProbation Ends: %s", formatDate(e.ProbationEnd))
+	}
+	if e.Location != "" || e.WorkMode != WorkOnSite {
+		s += fmt.Sprintf("\nLocation: %s", locationLabel(e))
+	}
+	if e.TimeZone != "" {
+		s += fmt.Sprintf("\nTime Zone: %s", localTimeLabel(e, appClock.Now()))
+	}
+	return s
+}
+
+// normalizeTimes converts the employee's dates to UTC for storage
+func (e *Employee) normalizeTimes() {
+	e.JoinDate = e.JoinDate.UTC()
+	if !e.BirthDate.IsZero() {
+		e.BirthDate = e.BirthDate.UTC()
+	}
+	if !e.ProbationEnd.IsZero() {
+		e.ProbationEnd = e.ProbationEnd.UTC()
+	}
+}
+
+// validateEmployee checks the fields of an employee before it is stored
+func validateEmployee(e *Employee, now time.Time) error {
+	if e.ID < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidID, e.ID)
+	}
+	if strings.TrimSpace(e.Name) == "" {
+		return fieldError("name", ReasonRequired, "name cannot be empty")
+	}
+	if err := validateSalary(e.Salary); err != nil {
+		return err
+	}
+	if !e.Department.Valid() {
+		return fieldError("department", ReasonUnknownDepartment, "please select a valid department")
+	}
+	if e.JoinDate.After(now) {
+		return fieldError("join_date", ReasonFutureDate, "join date cannot be in the future")
+	}
+	if WorkModeToString(e.WorkMode) == "Unknown" {
+		return fieldError("work_mode", ReasonUnknownWorkMode, "please select a valid work mode")
+	}
+	if _, err := loadTimeZone(e.TimeZone); e.TimeZone != "" && err != nil {
+		return fieldError("time_zone", ReasonUnknownTimeZone, "unknown time zone %q", e.TimeZone)
+	}
+	return nil
+}
+
+// validateSalary checks that a salary is a number of zero or more
+func validateSalary(salary float64) error {
+	if math.IsNaN(salary) || math.IsInf(salary, 0) {
+		return fieldError("salary", ReasonInvalidNumber, "salary must be a number")
+	}
+	if salary < 0 {
+		return fieldError("salary", ReasonNegative, "salary cannot be negative")
+	}
+	return nil
 }
 
 // EmployeeManager interface defines operations for managing employees
@@ -96,48 +172,91 @@ type EmployeeManager interface {
 	GetEmployee(id int) (*Employee, error)
 	ListEmployees() ([]*Employee, error)
 	FilterEmployees(filter func(*Employee) bool) []*Employee
+	TransferEmployee(id int, newDept Department, effectiveDate time.Time) error
 }
 
-// InMemoryEmployeeManager implements EmployeeManager interface using in-memory storage
+// InMemoryEmployeeManager implements EmployeeManager interface using in-memory storage.
+// It is event sourced: every change is appended to a log and the current state is
+// a projection of that log, so past states can be reconstructed for audits.
+// It is safe for concurrent use; events are published after the lock is released
+// so subscribers can call back into the manager.
 type InMemoryEmployeeManager struct {
-	employees map[int]*Employee
-	nextID    int
+	mu     sync.RWMutex
+	log    []Change
+	state  *projection
+	nextID int
+	events EventBus
+	clock  Clock
 }
 
-// NewInMemoryEmployeeManager creates a new InMemoryEmployeeManager
+// NewInMemoryEmployeeManager creates a new InMemoryEmployeeManager using the system clock
 func NewInMemoryEmployeeManager() *InMemoryEmployeeManager {
+	return NewInMemoryEmployeeManagerWithClock(SystemClock{})
+}
+
+// NewInMemoryEmployeeManagerWithClock creates a new InMemoryEmployeeManager that
+// reads the current time from clock
+func NewInMemoryEmployeeManagerWithClock(clock Clock) *InMemoryEmployeeManager {
 	return &InMemoryEmployeeManager{
-		employees: make(map[int]*Employee),
-		nextID:    1,
+		state:  newProjection(),
+		nextID: 1,
+		clock:  clock,
 	}
 }
 
+// Clock returns the clock the manager reads the current time from
+func (m *InMemoryEmployeeManager) Clock() Clock {
+	return m.clock
+}
+
 // AddEmployee adds a new employee to the manager
 func (m *InMemoryEmployeeManager) AddEmployee(e *Employee) error {
 	if e == nil {
 		return ErrInvalidInput
 	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
 
+	m.mu.Lock()
 	if e.ID == 0 {
-		// Auto-assign ID if not provided
+		// Auto-assign ID if not provided, skipping IDs that were set explicitly
+		for {
+			if _, exists := m.state.employees[m.nextID]; !exists {
+				break
+			}
+			m.nextID++
+		}
 		e.ID = m.nextID
 		m.nextID++
-	} else if _, exists := m.employees[e.ID]; exists {
+	} else if _, exists := m.state.employees[e.ID]; exists {
+		m.mu.Unlock()
 		return ErrDuplicateID
 	}
 
-	// Store a copy of the employee
+	// Store a copy of the employee, with all times in UTC
+	e.Version = 1
 	employeeCopy := *e
-	m.employees[e.ID] = &employeeCopy
+	employeeCopy.normalizeTimes()
+	c := m.record(Change{Type: EventEmployeeAdded, EmployeeID: e.ID, Employee: employeeCopy})
+	m.mu.Unlock()
+
+	m.events.Publish(c.notification())
 	return nil
 }
 
 // RemoveEmployee removes an employee by ID
 func (m *InMemoryEmployeeManager) RemoveEmployee(id int) error {
-	if _, exists := m.employees[id]; !exists {
+	m.mu.Lock()
+	employee, exists := m.state.employees[id]
+	if !exists {
+		m.mu.Unlock()
 		return ErrEmployeeNotFound
 	}
-	delete(m.employees, id)
+	c := m.record(Change{Type: EventEmployeeRemoved, EmployeeID: id, Employee: *employee})
+	m.mu.Unlock()
+
+	m.events.Publish(c.notification())
 	return nil
 }
 
@@ -146,20 +265,34 @@ func (m *InMemoryEmployeeManager) UpdateEmployee(e *Employee) error {
 	if e == nil || e.ID == 0 {
 		return ErrInvalidInput
 	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
 
-	if _, exists := m.employees[e.ID]; !exists {
+	m.mu.Lock()
+	existing, exists := m.state.employees[e.ID]
+	if !exists {
+		m.mu.Unlock()
 		return ErrEmployeeNotFound
 	}
 
-	// Store a copy of the updated employee
+	// Store a copy of the updated employee, with all times in UTC
+	e.Version = existing.Version + 1
 	employeeCopy := *e
-	m.employees[e.ID] = &employeeCopy
+	employeeCopy.normalizeTimes()
+	c := m.record(Change{Type: EventEmployeeUpdated, EmployeeID: e.ID, Employee: employeeCopy})
+	m.mu.Unlock()
+
+	m.events.Publish(c.notification())
 	return nil
 }
 
 // GetEmployee retrieves an employee by ID
 func (m *InMemoryEmployeeManager) GetEmployee(id int) (*Employee, error) {
-	employee, exists := m.employees[id]
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	employee, exists := m.state.employees[id]
 	if !exists {
 		return nil, ErrEmployeeNotFound
 	}
@@ -171,25 +304,36 @@ func (m *InMemoryEmployeeManager) GetEmployee(id int) (*Employee, error) {
 
 // ListEmployees returns a list of all employees
 func (m *InMemoryEmployeeManager) ListEmployees() ([]*Employee, error) {
-	employees := make([]*Employee, 0, len(m.employees))
-	for _, emp := range m.employees {
-		// Create a copy to prevent modification of the original
-		employeeCopy := *emp
-		employees = append(employees, &employeeCopy)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Copy the records into one backing array to prevent modification of the originals
+	values := make([]Employee, 0, len(m.state.employees))
+	employees := make([]*Employee, 0, len(m.state.employees))
+	for _, emp := range m.state.employees {
+		values = append(values, *emp)
+		employees = append(employees, &values[len(values)-1])
 	}
 	return employees, nil
 }
 
 // FilterEmployees returns employees that match the filter criteria
 func (m *InMemoryEmployeeManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
-	result := make([]*Employee, 0)
-	for _, emp := range m.employees {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := make([]Employee, 0)
+	for _, emp := range m.state.employees {
 		if filter(emp) {
-			// Create a copy to prevent modification of the original
-			employeeCopy := *emp
-			result = append(result, &employeeCopy)
+			values = append(values, *emp)
 		}
 	}
+
+	// Point into the copies only once they are all made, since append may move them
+	result := make([]*Employee, len(values))
+	for i := range values {
+		result[i] = &values[i]
+	}
 	return result
 }
 
@@ -206,6 +350,13 @@ func AddMultipleEmployees(manager EmployeeManager, employees ...*Employee) []err
 
 // Helper functions for user interaction
 
+// cancelToken can be entered at any prompt to abort the current operation
+const cancelToken = ":cancel"
+
+// maxInputAttempts is how many times a prompt is repeated after invalid input
+// before the operation is abandoned. main sets it from the -attempts flag.
+var maxInputAttempts = 3
+
 // readString reads a string from the user
 func readString(reader *bufio.Reader, prompt string) (string, error) {
 	fmt.Print(prompt)
@@ -213,18 +364,35 @@ func readString(reader *bufio.Reader, prompt string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(input), nil
+	input = strings.TrimSpace(input)
+	if input == cancelToken {
+		return "", ErrCancelled
+	}
+	return input, nil
 }
 
-// readInt reads an integer from the user
-func readInt(reader *bufio.Reader, prompt string) (int, error) {
-	input, err := readString(reader, prompt)
-	if err != nil {
-		return 0, err
+// readValue reads and parses a value from the user, asking again while the
+// input is invalid, up to maxInputAttempts times
+func readValue[T any](reader *bufio.Reader, prompt string, parse func(string) (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		input, err := readString(reader, prompt)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+
+		value, err := parse(input)
+		if err == nil || !errors.Is(err, ErrInvalidInput) || attempt >= maxInputAttempts {
+			return value, err
+		}
+		fmt.Printf("%s %v (%d attempt(s) left, %s to abort)\n", errorText("Error:"), err, maxInputAttempts-attempt, cancelToken)
 	}
+}
 
+// parseInt parses an integer, allowing empty input for optional fields
+func parseInt(input string) (int, error) {
 	if input == "" {
-		return 0, nil // Allow empty input for optional fields
+		return 0, nil
 	}
 
 	value, err := strconv.Atoi(input)
@@ -234,15 +402,10 @@ func readInt(reader *bufio.Reader, prompt string) (int, error) {
 	return value, nil
 }
 
-// readFloat reads a float from the user
-func readFloat(reader *bufio.Reader, prompt string) (float64, error) {
-	input, err := readString(reader, prompt)
-	if err != nil {
-		return 0, err
-	}
-
+// parseFloat parses a float, allowing empty input for optional fields
+func parseFloat(input string) (float64, error) {
 	if input == "" {
-		return 0, nil // Allow empty input for optional fields
+		return 0, nil
 	}
 
 	value, err := strconv.ParseFloat(input, 64)
@@ -252,91 +415,148 @@ func readFloat(reader *bufio.Reader, prompt string) (float64, error) {
 	return value, nil
 }
 
-// readDate reads a date from the user
-func readDate(reader *bufio.Reader, prompt string) (time.Time, error) {
-	input, err := readString(reader, prompt+" (YYYY-MM-DD): ")
+// parseSalary parses a salary and checks it as validateEmployee will, so a
+// bad one is asked for again instead of failing the whole form at the end
+func parseSalary(input string) (float64, error) {
+	salary, err := parseFloat(input)
 	if err != nil {
-		return time.Time{}, err
+		return 0, err
 	}
+	return salary, validateSalary(salary)
+}
 
-	if input == "" {
-		return time.Now(), nil // Default to current date if empty
-	}
+// readInt reads an integer from the user
+func readInt(reader *bufio.Reader, prompt string) (int, error) {
+	return readValue(reader, prompt, parseInt)
+}
 
-	date, err := time.Parse("2006-01-02", input)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("%w: please enter a valid date in YYYY-MM-DD format", ErrInvalidInput)
-	}
-	return date, nil
+// readFloat reads a float from the user
+func readFloat(reader *bufio.Reader, prompt string) (float64, error) {
+	return readValue(reader, prompt, parseFloat)
+}
+
+// readDate reads a date from the user
+func readDate(reader *bufio.Reader, prompt string) (time.Time, error) {
+	return readValue(reader, prompt+" (YYYY-MM-DD): ", func(input string) (time.Time, error) {
+		if input == "" {
+			return today(), nil // Default to current date if empty
+		}
+
+		date, err := parseDate(input)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: please enter a valid date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+		return date, nil
+	})
+}
+
+// readOptionalDate reads a date from the user, returning the zero time if left empty
+func readOptionalDate(reader *bufio.Reader, prompt string) (time.Time, error) {
+	return readValue(reader, prompt+" (YYYY-MM-DD, optional): ", func(input string) (time.Time, error) {
+		if input == "" {
+			return time.Time{}, nil
+		}
+
+		date, err := parseDate(input)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: please enter a valid date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+		return date, nil
+	})
 }
 
 // readDepartment reads a department from the user
-func readDepartment(reader *bufio.Reader) (int, error) {
+func readDepartment(reader *bufio.Reader) (Department, error) {
 	fmt.Println("\nAvailable departments:")
-	fmt.Println("1. HR")
-	fmt.Println("2. Engineering")
-	fmt.Println("3. Finance")
-	fmt.Println("4. Marketing")
-	fmt.Println("5. Operations")
-
-	choice, err := readInt(reader, "Select department (1-5): ")
-	if err != nil {
-		return -1, err
+	departments := AllDepartments()
+	for i, dept := range departments {
+		fmt.Printf("%d. %s\n", i+1, dept)
 	}
 
-	switch choice {
-	case 1:
-		return HR, nil
-	case 2:
-		return Engineering, nil
-	case 3:
-		return Finance, nil
-	case 4:
-		return Marketing, nil
-	case 5:
-		return Operations, nil
-	default:
-		return -1, fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
-	}
+	prompt := fmt.Sprintf("Select department (1-%d): ", len(departments))
+	return readValue(reader, prompt, func(input string) (Department, error) {
+		choice, err := parseInt(input)
+		if err != nil {
+			return -1, err
+		}
+		if choice < 1 || choice > len(departments) {
+			return -1, fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+		}
+		return departments[choice-1], nil
+	})
 }
 
 // Interactive console functions
 
 // addEmployeeInteractive adds an employee through user interaction
 func addEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) error {
-	fmt.Println("\n=== Add New Employee ===")
+	fmt.Println("\n" + headerText("=== Add New Employee ==="))
 
-	name, err := readString(reader, "Name: ")
+	template, err := readTemplate(reader)
 	if err != nil {
 		return err
 	}
 
-	position, err := readString(reader, "Position: ")
+	name, err := readString(reader, "Name: ")
 	if err != nil {
 		return err
 	}
+	employee := &Employee{Name: name}
+
+	if template != nil {
+		if err := readFromTemplate(reader, *template, employee); err != nil {
+			return err
+		}
+	} else {
+		employee.Position, err = readString(reader, "Position: ")
+		if err != nil {
+			return err
+		}
 
-	salary, err := readFloat(reader, "Salary: ")
+		employee.Salary, err = readValue(reader, "Salary: ", parseSalary)
+		if err != nil {
+			return err
+		}
+
+		employee.Department, err = readDepartment(reader)
+		if err != nil {
+			return err
+		}
+
+		employee.JoinDate, err = readDate(reader, "Join Date")
+		if err != nil {
+			return err
+		}
+	}
+
+	employee.BirthDate, err = readOptionalDate(reader, "Birth Date")
 	if err != nil {
 		return err
 	}
 
-	department, err := readDepartment(reader)
-	if err != nil {
+	if err := readWorkplace(reader, employee, false); err != nil {
 		return err
 	}
 
-	joinDate, err := readDate(reader, "Join Date")
+	// Former employees are rehired under their old record
+	rehired, err := offerRehire(manager, reader, employee)
 	if err != nil {
 		return err
 	}
+	if rehired {
+		fmt.Println("\n" + successText(fmt.Sprintf("Employee rehired with ID: %d, tenure %s", employee.ID, employee.Tenure())))
+		return nil
+	}
 
-	employee := &Employee{
-		Name:       name,
-		Position:   position,
-		Salary:     salary,
-		Department: department,
-		JoinDate:   joinDate,
+	// With legal entities, the employee takes the next free ID of theirs
+	entity, err := readEntity(reader, true)
+	if err != nil {
+		return err
+	}
+	if entity != nil {
+		if employee.ID, err = legalEntities.NextID(manager, entity); err != nil {
+			return err
+		}
 	}
 
 	err = manager.AddEmployee(employee)
@@ -344,13 +564,13 @@ func addEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) error
 		return err
 	}
 
-	fmt.Printf("\nEmployee added successfully with ID: %d\n", employee.ID)
+	fmt.Println("\n" + successText(fmt.Sprintf("Employee added successfully with ID: %d", employee.ID)))
 	return nil
 }
 
 // updateEmployeeInteractive updates an employee through user interaction
 func updateEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) error {
-	fmt.Println("\n=== Update Employee ===")
+	fmt.Println("\n" + headerText("=== Update Employee ==="))
 
 	id, err := readInt(reader, "Enter employee ID to update: ")
 	if err != nil {
@@ -361,6 +581,7 @@ func updateEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) er
 	if err != nil {
 		return err
 	}
+	original := *employee
 
 	fmt.Println("\nCurrent employee information:")
 	fmt.Println(employee)
@@ -382,17 +603,15 @@ func updateEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) er
 		employee.Position = position
 	}
 
-	salaryStr, err := readString(reader, fmt.Sprintf("Salary [%.2f]: ", employee.Salary))
+	employee.Salary, err = readValue(reader, fmt.Sprintf("Salary [%.2f]: ", employee.Salary), func(input string) (float64, error) {
+		if input == "" {
+			return employee.Salary, nil
+		}
+		return parseSalary(input)
+	})
 	if err != nil {
 		return err
 	}
-	if salaryStr != "" {
-		salary, err := strconv.ParseFloat(salaryStr, 64)
-		if err != nil {
-			return fmt.Errorf("%w: please enter a valid number", ErrInvalidInput)
-		}
-		employee.Salary = salary
-	}
 
 	fmt.Println("\nUpdate department? (y/n)")
 	updateDept, err := readString(reader, "Choice: ")
@@ -422,18 +641,66 @@ func updateEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) er
 		employee.JoinDate = joinDate
 	}
 
+	fmt.Println("\nUpdate birth date? (y/n)")
+	updateBirthDate, err := readString(reader, "Choice: ")
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(updateBirthDate) == "y" {
+		birthDate, err := readOptionalDate(reader, "Birth Date")
+		if err != nil {
+			return err
+		}
+		employee.BirthDate = birthDate
+	}
+
+	fmt.Println("\nUpdate location or work mode? (y/n)")
+	updateWorkplace, err := readString(reader, "Choice: ")
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(updateWorkplace) == "y" {
+		if err := readWorkplace(reader, employee, true); err != nil {
+			return err
+		}
+	}
+
+	changes := DiffEmployees(&original, employee)
+	if len(changes) == 0 {
+		fmt.Println("\nNo changes to apply.")
+		return nil
+	}
+
+	fmt.Println("\nThe following changes will be applied:")
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+
+	ok, err := confirm(reader, "\nApply these changes?", false)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		fmt.Println("\n" + warningText("Operation cancelled."))
+		return nil
+	}
+
 	err = manager.UpdateEmployee(employee)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("\nEmployee updated successfully!")
+	fmt.Println("\n" + successText("Employee updated successfully!"))
 	return nil
 }
 
-// removeEmployeeInteractive removes an employee through user interaction
-func removeEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) error {
-	fmt.Println("\n=== Remove Employee ===")
+// removeEmployeeInteractive removes an employee through user interaction,
+// recording why they left
+func removeEmployeeInteractive(manager EmployeeManager, attrition *Attrition, assets *Assets, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Remove Employee ==="))
 
 	id, err := readInt(reader, "Enter employee ID to remove: ")
 	if err != nil {
@@ -448,39 +715,61 @@ func removeEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) er
 	fmt.Println("\nEmployee to remove:")
 	fmt.Println(employee)
 
-	confirm, err := readString(reader, "\nAre you sure you want to remove this employee? (y/n): ")
+	// Company assets must come back before anything else is asked
+	if err := assets.CheckReturned(employee); err != nil {
+		writeEmployeeAssets(os.Stdout, assets.OutstandingFor(id))
+		return err
+	}
+
+	ok, err := confirm(reader, "\nAre you sure you want to remove this employee?", true)
 	if err != nil {
 		return err
 	}
 
-	if strings.ToLower(confirm) != "y" {
-		fmt.Println("\nOperation cancelled.")
+	if !ok {
+		fmt.Println("\n" + warningText("Operation cancelled."))
 		return nil
 	}
 
+	interview, err := readExitInterview(reader)
+	if err != nil {
+		return err
+	}
+	if err := attrition.Expect(*employee, interview); err != nil {
+		return err
+	}
+
 	err = manager.RemoveEmployee(id)
 	if err != nil {
+		attrition.Cancel(id)
 		return err
 	}
 
-	fmt.Println("\nEmployee removed successfully!")
+	fmt.Println("\n" + successText("Employee removed successfully!"))
 	return nil
 }
 
 // searchEmployeesInteractive searches for employees through user interaction
 func searchEmployeesInteractive(manager EmployeeManager, reader *bufio.Reader) error {
-	fmt.Println("\n=== Search Employees ===")
+	fmt.Println("\n" + headerText("=== Search Employees ==="))
 	fmt.Println("1. Search by name")
 	fmt.Println("2. Search by department")
 	fmt.Println("3. Search by salary range")
 	fmt.Println("4. Search by experience")
+	fmt.Println("5. Search by join date")
+	fmt.Println("6. Search by location or work mode")
+	if appPreferences.LastSearch != "" {
+		fmt.Printf("7. Repeat last search (%s)\n", appPreferences.LastSearch)
+	}
 
 	option, err := readInt(reader, "\nSelect search option: ")
 	if err != nil {
 		return err
 	}
 
+	// The search is also written as a query, to be remembered for next time
 	var employees []*Employee
+	query := url.Values{}
 
 	switch option {
 	case 1:
@@ -489,9 +778,8 @@ func searchEmployeesInteractive(manager EmployeeManager, reader *bufio.Reader) e
 			return err
 		}
 
-		employees = manager.FilterEmployees(func(e *Employee) bool {
-			return strings.Contains(strings.ToLower(e.Name), strings.ToLower(name))
-		})
+		employees = manager.FilterEmployees(NameContains(name))
+		query.Set("name", name)
 
 	case 2:
 		department, err := readDepartment(reader)
@@ -499,9 +787,12 @@ func searchEmployeesInteractive(manager EmployeeManager, reader *bufio.Reader) e
 			return err
 		}
 
-		employees = manager.FilterEmployees(func(e *Employee) bool {
-			return e.Department == department
-		})
+		if lister, ok := capability[interface{ ListByDepartment(Department) []*Employee }](manager); ok {
+			employees = lister.ListByDepartment(department)
+		} else {
+			employees = manager.FilterEmployees(ByDepartment(department))
+		}
+		query.Set("department", department.String())
 
 	case 3:
 		minSalary, err := readFloat(reader, "Enter minimum salary: ")
@@ -514,9 +805,9 @@ func searchEmployeesInteractive(manager EmployeeManager, reader *bufio.Reader) e
 			return err
 		}
 
-		employees = manager.FilterEmployees(func(e *Employee) bool {
-			return e.Salary >= minSalary && e.Salary <= maxSalary
-		})
+		employees = manager.FilterEmployees(SalaryBetween(minSalary, maxSalary))
+		query.Set("min_salary", strconv.FormatFloat(minSalary, 'f', -1, 64))
+		query.Set("max_salary", strconv.FormatFloat(maxSalary, 'f', -1, 64))
 
 	case 4:
 		minExp, err := readFloat(reader, "Enter minimum years of experience: ")
@@ -524,26 +815,83 @@ func searchEmployeesInteractive(manager EmployeeManager, reader *bufio.Reader) e
 			return err
 		}
 
-		employees = manager.FilterEmployees(func(e *Employee) bool {
-			return e.CalculateExperience() >= minExp
+		employees = manager.FilterEmployees(MinExperience(minExp, clockOf(manager).Now()))
+		query.Set("min_experience", strconv.FormatFloat(minExp, 'f', -1, 64))
+
+	case 5:
+		from, err := readDate(reader, "Joined on or after")
+		if err != nil {
+			return err
+		}
+
+		to, err := readOptionalDate(reader, "Joined on or before")
+		if err != nil {
+			return err
+		}
+
+		joined := Not(JoinedBefore(from))
+		if !to.IsZero() {
+			joined = And(joined, Not(JoinedAfter(to)))
+		}
+		employees = manager.FilterEmployees(joined)
+		query.Set("joined_from", formatDate(from))
+		if !to.IsZero() {
+			query.Set("joined_to", formatDate(to))
+		}
+
+	case 6:
+		location, err := readString(reader, "Enter location (blank for any): ")
+		if err != nil {
+			return err
+		}
+
+		mode, err := readValue(reader, "Work mode (on-site, hybrid, remote; blank for any): ", func(input string) (int, error) {
+			if input == "" {
+				return -1, nil
+			}
+			return StringToWorkMode(input)
 		})
+		if err != nil {
+			return err
+		}
+
+		var matches []Predicate[*Employee]
+		if location != "" {
+			matches = append(matches, AtLocation(location))
+			query.Set("location", location)
+		}
+		if mode != -1 {
+			matches = append(matches, ByWorkMode(mode))
+			query.Set("work_mode", strings.ToLower(WorkModeToString(mode)))
+		}
+		employees = manager.FilterEmployees(And(matches...))
+
+	case 7:
+		if appPreferences.LastSearch == "" {
+			return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+		}
+		query, err = url.ParseQuery(appPreferences.LastSearch)
+		if err != nil {
+			return fmt.Errorf("%w: the last search cannot be read: %v", ErrInvalidInput, err)
+		}
+		matches, err := ParseEmployeeQuery(query, clockOf(manager).Now())
+		if err != nil {
+			return err
+		}
+		employees = manager.FilterEmployees(matches)
 
 	default:
 		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
 	}
+	appPreferences.RememberSearch(query)
 
 	if len(employees) == 0 {
 		fmt.Println("\nNo employees found matching the criteria.")
 		return nil
 	}
+	sortEmployees(employees, listOrder)
 
-	fmt.Printf("\nFound %d employee(s):\n\n", len(employees))
-	for i, emp := range employees {
-		fmt.Printf("=== Employee %d ===\n", i+1)
-		fmt.Println(emp)
-		fmt.Println()
-	}
-
+	writeEmployeeList(os.Stdout, fmt.Sprintf("Found %d employee(s):", len(employees)), employees)
 	return nil
 }
 
@@ -558,100 +906,491 @@ func displayAllEmployees(manager EmployeeManager) error {
 		fmt.Println("\nNo employees found.")
 		return nil
 	}
+	sortEmployees(employees, listOrder)
+
+	writeEmployeeList(os.Stdout, fmt.Sprintf("=== All Employees (%d) ===", len(employees)), employees)
+	return nil
+}
 
-	fmt.Printf("\n=== All Employees (%d) ===\n\n", len(employees))
+// writeEmployeeList writes a heading followed by the employees in the list format
+func writeEmployeeList(w io.Writer, heading string, employees []*Employee) {
+	fmt.Fprintf(w, "\n%s\n\n", headerText(heading))
+	if listFormat != FormatDetail {
+		writeEmployeeTable(w, listColumns, employees, listFormat == FormatMarkdown)
+		return
+	}
 	for i, emp := range employees {
-		fmt.Printf("=== Employee %d ===\n", i+1)
-		fmt.Println(emp)
-		fmt.Println()
+		fmt.Fprintf(w, "=== Employee %d ===\n", i+1)
+		fmt.Fprintln(w, emp)
+		fmt.Fprintln(w)
 	}
-
-	return nil
 }
 
-// addSampleData adds sample data to the manager
-func addSampleData(manager EmployeeManager) {
-	// Create sample employees
-	employees := []*Employee{
-		{
-			Name:       "John Doe",
-			Position:   "Software Engineer",
-			Salary:     85000,
-			Department: Engineering,
-			JoinDate:   time.Date(2020, 5, 15, 0, 0, 0, 0, time.Local),
-		},
-		{
-			Name:       "Jane Smith",
-			Position:   "HR Manager",
-			Salary:     75000,
-			Department: HR,
-			JoinDate:   time.Date(2019, 3, 10, 0, 0, 0, 0, time.Local),
-		},
-		{
-			Name:       "Michael Johnson",
-			Position:   "Finance Director",
-			Salary:     110000,
-			Department: Finance,
-			JoinDate:   time.Date(2018, 1, 5, 0, 0, 0, 0, time.Local),
-		},
-		{
-			Name:       "Emily Williams",
-			Position:   "Marketing Specialist",
-			Salary:     65000,
-			Department: Marketing,
-			JoinDate:   time.Date(2021, 8, 22, 0, 0, 0, 0, time.Local),
-		},
-		{
-			Name:       "Robert Brown",
-			Position:   "Operations Manager",
-			Salary:     90000,
-			Department: Operations,
-			JoinDate:   time.Date(2019, 11, 7, 0, 0, 0, 0, time.Local),
-		},
-	}
-
-	// Add employees using variadic function
-	errors := AddMultipleEmployees(manager, employees...)
-	if len(errors) > 0 {
-		fmt.Println("Errors adding sample data:")
-		for _, err := range errors {
-			fmt.Println(err)
-		}
-	}
-}
-
-// displayMenu displays the main menu
-func displayMenu() {
-	fmt.Println("\n======= Employee Management System =======")
-	fmt.Println("1. Add Employee")
-	fmt.Println("2. View All Employees")
-	fmt.Println("3. Update Employee")
-	fmt.Println("4. Remove Employee")
-	fmt.Println("5. Search Employees")
-	fmt.Println("6. Add Sample Data")
+// displayMenu displays the main menu, with a warning when the data is not production data
+func displayMenu(env int) {
+	fmt.Println("\n" + headerText("======= Employee Management System ======="))
+	if env != EnvProduction {
+		fmt.Println(warningText(strings.ToUpper(EnvironmentToString(env)) + " ENVIRONMENT: changes do not affect production data"))
+	}
+	for i, option := range menuOptions {
+		fmt.Printf("%d. %s\n", i+1, option.Name)
+	}
 	fmt.Println("0. Exit")
 	fmt.Println("=========================================")
 }
 
+// commands are run instead of the interactive menu when named as the first argument
+var commands = map[string]Command{
+	"migrate":   {Summary: "convert data saved by any of the lab programs into this program's JSON schema", Run: runMigrate},
+	"seed":      {Summary: "generate fake employees for load tests and demos", Run: withoutInput(runSeed)},
+	"export":    {Summary: "write a storage backend's employees as JSON, or their payroll as ledger CSV", Run: withoutInput(runExport)},
+	"badge":     {Summary: "write printable badges for one employee or all of them", Run: withoutInput(runBadge)},
+	"import":    {Summary: "add or sync employees from a CSV or .xlsx file", Run: runImport},
+	"lint":      {Summary: "scan a storage backend for data quality issues", Run: withoutInput(runLint)},
+	"compare":   {Summary: "lay out employees side by side", Args: "ID ID...", Run: withoutInput(runCompare)},
+	"forecast":  {Summary: "project the payroll of a storage backend's employees", Run: withoutInput(runForecast)},
+	"payroll":   {Summary: "run payroll for a month as a table or CSV of line items", Run: withoutInput(runPayroll)},
+	"payslips":  {Summary: "write the payslips of a month", Run: withoutInput(runPayslips)},
+	"payments":  {Summary: "write the payment file of a month's payroll for the bank", Run: withoutInput(runPayments)},
+	"config":    {Summary: "export the configuration as a YAML bundle, or import one", Subcommands: []string{"export", "import BUNDLE"}, Run: withoutInput(runConfig)},
+	"telemetry": {Summary: "show the usage reports telemetry recorded and sent", Run: withoutInput(runTelemetry)},
+	"setup":     {Summary: "set the deployment up: storage, currency, departments, salary bands and admin", Run: runSetup},
+	"doctor":    {Summary: "check the configuration, storage, indexes, clock and unfinished saves, with how to fix what fails", Run: withoutInput(runDoctor)},
+	"repair":    {Summary: "rebuild the store's indexes and fix leave that disagrees with the employee records", Run: withoutInput(runRepair)},
+}
+
 // main function - entry point of the application
 func main() {
-	// Create employee manager
-	manager := NewInMemoryEmployeeManager()
+	// A panic anywhere below leaves a crash report for the bug report
+	defer appCrash.Recover()
+
+	// Commands such as migrate and seed run on their own and exit
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command.Run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	userName := flag.String("user", os.Getenv("USER"), "name of the user operating the system")
+	roleName := flag.String("role", "admin", "role of the user (employee, manager, hr, admin)")
+	employeeID := flag.Int("employee-id", 0, "the user's own employee ID; the employee role gets a read-only self-service menu for it")
+	serveAddr := flag.String("serve", "", "serve the HTTP API on this address (e.g. :8080) instead of the menu")
+	snapshotDir := flag.String("snapshot-dir", "", "write a JSON snapshot of all employees to this directory when interrupted, and nightly in server mode")
+	traceName := flag.String("trace", "", fmt.Sprintf("trace manager operations and HTTP requests (%s)", strings.Join(TracerNames(), ", ")))
+	traceDSN := flag.String("trace-dsn", "", "where the tracer sends spans, such as a file or collector endpoint")
+	profiling := flag.Bool("pprof", false, "serve runtime profiles under /debug/pprof/ in server mode")
+	sampleData := flag.Bool("sample", false, "load sample data at startup")
+	sampleSize := flag.Int("sample-size", defaultSampleSize, "how many employees the sample data and the sandbox start with")
+	timeZone := flag.String("tz", os.Getenv("EMS_TIMEZONE"), "time zone for entering and displaying dates (e.g. Asia/Kolkata)")
+	simulatedDate := flag.String("now", "", "simulate the clock starting at this date (YYYY-MM-DD)")
+	dryRun := flag.Bool("dry-run", false, "print the changes that would be made without applying them")
+	readOnly := flag.Bool("read-only", false, "open the store as a read-only follower of another instance, for reports and dashboards")
+	confirmName := flag.String("confirm", "always", "which changes ask for confirmation (always, destructive, never)")
+	assumeYes := flag.Bool("yes", false, "answer yes to every confirmation, for scripted use")
+	storageName := flag.String("storage", "memory", fmt.Sprintf("storage backend (%s)", strings.Join(StorageBackends(), ", ")))
+	storageDSN := flag.String("dsn", "", "storage connection string, such as a file path or database URL")
+	envName := flag.String("env", "prod", "environment to start in (prod, staging, sandbox); the menu can switch between them")
+	stagingDSN := flag.String("staging-dsn", "", "storage connection string of the staging environment")
+	approveSalary := flag.Float64("approve-salary-change", 0, "queue salary changes larger than this percentage for approval (0 disables)")
+	approveTransfers := flag.Bool("approve-transfers", false, "queue transfers for approval")
+	approveLeave := flag.Float64("approve-leave-days", 0, "grant leave of up to this many working days as it is asked for, and queue longer leave for approval (0 leaves all leave to the leave approvers)")
+	bankFile := flag.String("bank-file", "", "keep employees' bank accounts in this file, encrypted with the key in EMS_BANK_KEY (default memory only)")
+	loansFile := flag.String("loans-file", "", "keep employees' loans and salary advances, deducted in payroll runs, in this JSON file (default memory only)")
+	assetsFile := flag.String("assets-file", "", "keep the company assets issued to employees in this JSON file (default memory only)")
+	documentsFile := flag.String("documents-file", "", "keep employees' work permits, visas and certifications, with their expiry dates, in this JSON file (default memory only)")
+	leaveFile := flag.String("leave-file", "", "keep leave requests and the days of leave employees have left in this JSON file (default memory only)")
+	timeFile := flag.String("time-file", "", "keep the times employees clock in and out in this JSON file (default memory only)")
+	notificationsFile := flag.String("notifications-file", "", "keep users' notification settings, and the notifications waiting for their digests, in this JSON file (default memory only)")
+	digestTemplatePath := flag.String("digest-template", "", "text/template file laying out the weekly digest sent to users notified of digest.weekly")
+	approvalsFile := flag.String("approvals", "", "keep the approval queue in this JSON file, shared by requesters and approvers")
+	cacheURL := flag.String("cache", "", "cache reads in Redis (e.g. redis://localhost:6379?ttl=30s)")
+	themeName := flag.String("theme", os.Getenv("EMS_THEME"), fmt.Sprintf("color theme of console output (%s)", strings.Join(ThemeNames(), ", ")))
+	noColor := flag.Bool("no-color", false, "print plain text without colors; also set by the NO_COLOR environment variable")
+	ascii := flag.Bool("ascii", false, "draw report charts with ASCII characters instead of Unicode blocks")
+	formatName := flag.String("format", "detail", "how employee lists and search results are shown (detail, table, markdown)")
+	columnList := flag.String("columns", defaultColumns, fmt.Sprintf("columns of the table and markdown formats (%s)", strings.Join(columnNames(), ", ")))
+	sortName := flag.String("sort", "id", "order of employee lists and search results (id, name, salary, department, joined)")
+	fieldsPath := flag.String("fields", "", "JSON file of computed fields, such as bonus = salary * 0.1, which can be searched, shown and exported like stored ones")
+	costCentersPath := flag.String("cost-centers", "", "JSON file mapping departments and split employees to cost centers and GL accounts, for payroll reports and the API")
+	payrollPath := flag.String("payroll", "", "JSON file of the payroll currency, its rounding policies, deductions and income tax brackets")
+	holidaysPath := flag.String("holidays", "", "JSON file of the weekend days and public holidays that leave is counted against")
+	leavePoliciesPath := flag.String("leave-policies", "", "JSON file of the leave policies that accrue leave, with their carry-over caps and expiry, run by the scheduler in server mode")
+	entitiesPath := flag.String("entities", "", "JSON file of the group's legal entities, with the employee ID range and payroll rules of each")
+	positionsPath := flag.String("positions", "", "JSON file of the position templates new hires start from, with their department, salary band and probation")
+	rolesPath := flag.String("roles", "", "JSON file of the permissions granted to each role")
+	apiKeysPath := flag.String("api-keys", "", "JSON file of the API keys HTTP API clients authenticate with, and the user and role of each; needed to serve the API")
+	rulesPath := flag.String("rules", "", "JSON file of this deployment's rules, which reject or adjust additions and updates")
+	attempts := flag.Int("attempts", maxInputAttempts, "how many times to ask for a value after invalid input")
+	systemConfigPath := flag.String("system-config", "", "configuration bundle of the deployment, written by the setup command or config export (default system.yaml in the user's config directory; off to disable)")
+	telemetrySetting := flag.String("telemetry", os.Getenv("EMS_TELEMETRY"), "anonymous usage statistics: off (the default), local to only record them, or the URL to send them to; the telemetry command shows what was recorded and sent")
+	configPath := flag.String("config", "", "file remembering the user's sort, format, columns, theme, time zone and last search (default in the user's config directory; off to disable)")
+
+	// Help runs once the flags are defined, so it can describe them
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		runHelp(os.Args[2:], os.Stdout)
+		return
+	}
+	flag.Parse()
+
+	// Remembered preferences fill in the flags that were not given this time
+	if *configPath == "" {
+		*configPath, _ = defaultPreferencesPath(*userName)
+	} else if *configPath == preferencesOff {
+		*configPath = ""
+	}
+	preferences, err := LoadPreferences(*configPath)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	preferencesChanged, err := preferences.Apply(flag.CommandLine)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	appPreferences = preferences
+
+	if err := setUserLocation(*timeZone); err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+
+	theme, err := StringToTheme(*themeName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(2)
+	}
+	if !*noColor && colorsSupported(os.Stdout) {
+		colorTheme = &theme
+	}
+
+	policy, err := StringToConfirmPolicy(*confirmName)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	if *assumeYes {
+		policy = ConfirmNever
+	}
+	confirmPolicy = policy
+
+	order, err := StringToOrder(*sortName)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	listOrder = order
+
+	format, err := StringToFormat(*formatName)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	listFormat = format
 
 	// Create reader for user input
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Println("Welcome to the Employee Management System!")
+	// The system configuration comes first, so the flags naming single
+	// files replace its sections. The first interactive run offers to set
+	// it up.
+	systemConfig, systemRules, err := loadSystemConfig(*systemConfigPath)
+	if err == nil && systemConfig == nil && *systemConfigPath == "" && *serveAddr == "" && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		systemConfig, systemRules, err = offerSetup(reader, *userName)
+	}
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	if systemConfig != nil {
+		given := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		if s := systemConfig.Storage; s != nil && !given["storage"] && !given["dsn"] {
+			*storageName, *storageDSN = s.Backend, s.DSN
+		}
+		// Only the admin the deployment was set up with is an admin by default
+		if systemConfig.Admin != "" && !given["role"] && *userName != systemConfig.Admin {
+			*roleName = "manager"
+			fmt.Println(warningText(fmt.Sprintf("Running as a manager: %s is the admin. Give -role to choose a role.", systemConfig.Admin)))
+		}
+	}
+
+	// Computed fields can be selected as columns and used in rules, so they are loaded first
+	if *fieldsPath != "" {
+		if computedFields, err = LoadComputedFields(*fieldsPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *costCentersPath != "" {
+		if costCenters, err = LoadCostCenters(*costCentersPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *payrollPath != "" {
+		if payrollConfig, err = LoadPayrollConfig(*payrollPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *entitiesPath != "" {
+		if legalEntities, err = LoadEntities(*entitiesPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *holidaysPath != "" {
+		if holidayCalendar, err = LoadHolidayCalendar(*holidaysPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *leavePoliciesPath != "" {
+		if leavePolicies, err = LoadLeavePolicies(*leavePoliciesPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *positionsPath != "" {
+		if positionTemplates, err = LoadPositionTemplates(*positionsPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *rolesPath != "" {
+		if rolePermissions, err = LoadRolePermissions(*rolesPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *apiKeysPath != "" {
+		if appAPIKeys, err = LoadAPIKeys(*apiKeysPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *notificationsFile != "" {
+		if appNotifications, err = NewNotifications(*notificationsFile); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	if *digestTemplatePath != "" {
+		if digestTemplate, err = LoadDigestTemplate(*digestTemplatePath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	columns, err := ParseColumns(*columnList)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	listColumns = columns
+	asciiCharts = *ascii
+
+	if *sampleSize < 1 {
+		fmt.Println("Error: -sample-size must be at least 1")
+		os.Exit(2)
+	}
+
+	if *attempts < 1 {
+		fmt.Println("Error: -attempts must be at least 1")
+		os.Exit(2)
+	}
+	maxInputAttempts = *attempts
+
+	// Flags are only remembered once they are known to be valid
+	if preferencesChanged {
+		if err := appPreferences.Save(); err != nil {
+			fmt.Println(warningText("Warning: preferences could not be saved: " + err.Error()))
+		}
+	}
+
+	// A simulated clock lets demos move time forward from the menu
+	var simulatedClock *FakeClock
+	if *simulatedDate != "" {
+		start, err := parseDate(*simulatedDate)
+		if err != nil {
+			fmt.Println("Error: -now must be a date in YYYY-MM-DD format")
+			os.Exit(2)
+		}
+		simulatedClock = NewFakeClock(start)
+		appClock = simulatedClock
+	}
+
+	role, err := StringToRole(*roleName)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	user := User{Name: *userName, Role: role, EmployeeID: *employeeID}
+	if role == RoleEmployee && user.EmployeeID == 0 {
+		fmt.Println("Error: the employee role requires -employee-id")
+		os.Exit(2)
+	}
+
+	// Tracing wraps every environment's manager, so its spans cover caches, approvals and storage
+	var tracer Tracer
+	if *traceName != "" {
+		t, shutdown, err := OpenTracer(*traceName, *traceDSN)
+		if err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+		defer shutdown()
+		appShutdown.OnExit(shutdown)
+		tracer = t
+	}
+
+	env, err := StringToEnvironment(*envName)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+
+	// The user's saved watches are checked in every environment
+	watchlist, err := NewWatchlist(appPreferences.Watches)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+
+	// The deployment's rules check changes in every environment
+	rules := systemRules
+	if *rulesPath != "" {
+		if rules, err = LoadRules(*rulesPath); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(2)
+		}
+	}
+
+	// Open the employee store of the starting environment; others open when switched to
+	workspaces, err := NewWorkspaces(WorkspaceConfig{
+		StorageName:    *storageName,
+		DSN:            *storageDSN,
+		StagingDSN:     *stagingDSN,
+		SampleData:     *sampleData,
+		SampleSize:     *sampleSize,
+		CacheURL:       *cacheURL,
+		ApprovalPolicy: ApprovalPolicy{SalaryChangePercent: *approveSalary, Transfers: *approveTransfers, LeaveDays: *approveLeave},
+		ApprovalsFile:  *approvalsFile,
+		User:           user,
+		DryRun:         *dryRun,
+		ReadOnly:       *readOnly,
+		Tracer:         tracer,
+		Watches:        watchlist,
+		Rules:          rules,
+		BankFile:       *bankFile,
+		LoansFile:      *loansFile,
+		AssetsFile:     *assetsFile,
+		DocumentsFile:  *documentsFile,
+		LeaveFile:      *leaveFile,
+		TimeFile:       *timeFile,
+	}, env)
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	defer workspaces.Close()
+	workspace := workspaces.Current()
+
+	// Ctrl-C and SIGTERM save a snapshot and close the stores before exiting
+	appShutdown.OnExit(workspaces.Close)
+	appCrash.Watch(systemConfig, func() EmployeeManager { return workspaces.Current().Manager })
+
+	// Usage is only counted, and reported on exit, when the user opts in
+	mode := "menu"
+	if *serveAddr != "" {
+		mode = "server"
+	}
+	telemetryLog, _ := defaultTelemetryLogPath()
+	appTelemetry, err = NewTelemetry(*telemetrySetting, telemetryLog, mode, *storageName, func() int {
+		employees, err := employeeValues(workspaces.Current().Manager)
+		if err != nil {
+			return -1
+		}
+		return len(employees)
+	})
+	if err != nil {
+		fmt.Println(errorText("Error:"), err)
+		os.Exit(2)
+	}
+	if appTelemetry != nil {
+		flush := func() error { return appTelemetry.Flush(appClock.Now()) }
+		defer flush()
+		appShutdown.OnExit(flush)
+		fmt.Println(warningText(appTelemetry.Notice()))
+	}
+	if *snapshotDir != "" {
+		appShutdown.OnExit(func() error {
+			return snapshotJob(workspaces.Current().Store, *snapshotDir)(context.Background(), appClock.Now())
+		})
+	}
+	appShutdown.Listen()
+
+	if *serveAddr != "" {
+		if role == RoleEmployee {
+			fmt.Println("Error: the employee role cannot serve the API")
+			os.Exit(2)
+		}
+		if len(appAPIKeys) == 0 {
+			fmt.Println("Error: serving the API needs -api-keys, since every route but the health checks is authenticated")
+			os.Exit(2)
+		}
+		if err := runServer(*serveAddr, workspace.Manager, workspace.Documents, workspace.Leave, workspace.Time, *profiling, *snapshotDir, tracer); err != nil {
+			fmt.Println(errorText("Error:"), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Employees only get read-only access to their own record
+	if user.SelfServiceOnly() {
+		runSelfService(workspace.Manager, workspace.Records, workspace.Leave, workspace.Time, user, reader)
+		return
+	}
+
+	fmt.Printf("Welcome to the Employee Management System, %s (%s)!\n", user.Name, RoleToString(user.Role))
+	fmt.Printf("Dates are shown in the %s time zone.\n", userLocation)
+	fmt.Printf("Enter %s at any prompt to abort the current operation.\n", cancelToken)
+	if *dryRun {
+		fmt.Println(warningText("DRY-RUN MODE: changes are printed but not saved."))
+	}
+	if *readOnly {
+		fmt.Println(warningText("READ-ONLY MODE: following the store; changes are refused."))
+	}
 
 	for {
-		displayMenu()
+		workspace = workspaces.Current()
+		manager := workspace.Manager
+		displayMenu(workspace.Env)
 
 		choice, err := readInt(reader, "Enter your choice: ")
 		if err != nil {
-			fmt.Println("Error:", err)
+			fmt.Println(errorText("Error:"), err)
 			continue
 		}
 
+		if choice >= 1 && choice <= len(menuOptions) {
+			appTelemetry.Feature(menuOptions[choice-1].Topic)
+		}
 		switch choice {
 		case 1:
 			err = addEmployeeInteractive(manager, reader)
@@ -660,13 +1399,61 @@ func main() {
 		case 3:
 			err = updateEmployeeInteractive(manager, reader)
 		case 4:
-			err = removeEmployeeInteractive(manager, reader)
+			err = removeEmployeeInteractive(manager, workspace.Attrition, workspace.Assets, reader)
 		case 5:
 			err = searchEmployeesInteractive(manager, reader)
 		case 6:
-			addSampleData(manager)
-			fmt.Println("\nSample data added successfully!")
-			err = nil
+			err = sampleDataInteractive(manager, reader)
+		case 7:
+			err = transferEmployeeInteractive(manager, reader)
+		case 8:
+			err = budgetInteractive(manager, workspace.Plan, reader)
+		case 9:
+			err = recruitmentInteractive(workspace.Recruitment, reader)
+		case 10:
+			err = personalRecordsInteractive(workspace.Records, user, reader)
+		case 11:
+			err = remindersInteractive(manager, reader)
+		case 12:
+			err = advanceClockInteractive(simulatedClock, reader)
+		case 13:
+			err = changeReportInteractive(manager, reader)
+		case 14:
+			err = reportsInteractive(manager, workspace.Loans, reader)
+		case 15:
+			err = approvalsInteractive(manager, user, reader)
+		case 16:
+			err = environmentInteractive(workspaces, reader)
+		case 17:
+			err = watchesInteractive(watchlist, reader)
+		case 18:
+			err = successionInteractive(workspace.Succession, manager, reader)
+		case 19:
+			err = attritionReportInteractive(workspace.Attrition)
+		case 20:
+			err = importInteractive(manager, reader)
+		case 21:
+			err = dataQualityInteractive(manager, workspace.Succession)
+		case 22:
+			err = compareInteractive(manager, reader)
+		case 23:
+			err = simulationInteractive(manager, workspace.Plan, reader)
+		case 24:
+			err = bankDetailsInteractive(workspace.Banks, workspace.Loans, manager, user, reader)
+		case 25:
+			err = loansInteractive(workspace.Loans, manager, user, reader)
+		case 26:
+			err = assetsInteractive(workspace.Assets, manager, reader)
+		case 27:
+			err = documentsInteractive(workspace.Documents, manager, user, reader)
+		case 28:
+			err = leaveInteractive(workspace.Leave, manager, user, reader)
+		case 29:
+			err = attendanceInteractive(workspace.Time, workspace.Leave, manager, user, reader)
+		case 30:
+			err = notificationsInteractive(appNotifications, manager, workspace.Documents, workspace.Leave, workspace.Time, user, reader)
+		case 31:
+			err = helpInteractive(reader)
 		case 0:
 			fmt.Println("\nThank you for using the Employee Management System. Goodbye!")
 			return
@@ -674,8 +1461,13 @@ func main() {
 			err = fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
 		}
 
-		if err != nil {
-			fmt.Println("Error:", err)
+		if errors.Is(err, ErrCancelled) {
+			fmt.Println("\n" + warningText("Operation cancelled."))
+		} else if errors.Is(err, ErrPendingApproval) {
+			fmt.Println("\n" + warningText(fmt.Sprintf("The change was %s. It will be applied once approved.", err)))
+		} else if err != nil {
+			appTelemetry.Error(err)
+			fmt.Println(errorText("Error:"), err)
 		}
 	}
 }
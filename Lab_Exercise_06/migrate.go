@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyEmployee accepts the employee shapes of every lab program:
+//   - Lab_Exercise_03_04: ID, Name, Department (string), Salary, Position
+//   - Lab_Exercise_05: ID, Name, Position, Salary, Performance, LastUpdated
+//   - Lab_Exercise_06: the fields of Employee, or the HTTP API representation
+//
+// Field names are matched case-insensitively by encoding/json.
+type legacyEmployee struct {
+	ID          int
+	Name        string
+	Position    string
+	Salary      float64
+	Department  json.RawMessage // a name in Lab_Exercise_03_04, a constant in Lab_Exercise_06
+	JoinDate    string
+	JoinDateAPI string `json:"join_date"`
+	BirthDate   string
+	BirthAPI    string `json:"birth_date"`
+}
+
+// legacyDepartmentAliases are the departments of Lab_Exercise_03_04 that
+// are named differently here. Its HR, Finance and Marketing keep their names.
+var legacyDepartmentAliases = map[string]string{"IT": "Engineering"}
+
+// DepartmentMapping maps the department names of older programs onto the
+// departments, and counts the records it mapped and the names it could not.
+// With String and Set, it makes a -map flag of old=new pairs.
+type DepartmentMapping struct {
+	aliases  map[string][2]string // old and department names, by lower-case old name
+	Mapped   map[string]int       // records mapped, by "old -> new"
+	Unmapped map[string]int       // records with a department that maps to none, by name
+}
+
+// NewDepartmentMapping returns a mapping with the aliases of Lab_Exercise_03_04
+func NewDepartmentMapping() *DepartmentMapping {
+	m := &DepartmentMapping{aliases: make(map[string][2]string), Mapped: make(map[string]int), Unmapped: make(map[string]int)}
+	for old, name := range legacyDepartmentAliases {
+		m.aliases[strings.ToLower(old)] = [2]string{old, name}
+	}
+	return m
+}
+
+// String lists the aliases as old=new pairs
+func (m *DepartmentMapping) String() string {
+	if m == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(m.aliases))
+	for _, alias := range m.aliases {
+		pairs = append(pairs, alias[0]+"="+alias[1])
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set adds an old=new alias, replacing any for the same old name
+func (m *DepartmentMapping) Set(value string) error {
+	old, name, ok := strings.Cut(value, "=")
+	old, name = strings.TrimSpace(old), strings.TrimSpace(name)
+	if !ok || old == "" {
+		return fmt.Errorf("%w: expected old=department, not %q", ErrInvalidInput, value)
+	}
+	dept, err := ParseDepartment(name)
+	if err != nil {
+		return err
+	}
+	m.aliases[strings.ToLower(old)] = [2]string{old, dept.String()}
+	return nil
+}
+
+// Department returns the department a name stands for: the department of
+// that name if there is one, or else the one it is mapped onto
+func (m *DepartmentMapping) Department(name string) (Department, error) {
+	if dept, err := ParseDepartment(name); err == nil {
+		return dept, nil
+	}
+	if m != nil {
+		if alias, ok := m.aliases[strings.ToLower(name)]; ok {
+			if dept, err := ParseDepartment(alias[1]); err == nil {
+				m.Mapped[alias[0]+" -> "+dept.String()]++
+				return dept, nil
+			}
+		}
+		m.Unmapped[name]++
+	}
+	return -1, fmt.Errorf("%w: unknown department %q; map it onto one with -map %s=<department>", ErrInvalidInput, name, name)
+}
+
+// parseLegacyDepartment converts a department name or constant to the constant,
+// mapping old names with mapping and using dflt when the record has no department
+func parseLegacyDepartment(raw json.RawMessage, dflt Department, mapping *DepartmentMapping) (Department, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return dflt, nil
+	}
+
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return mapping.Department(strings.TrimSpace(name))
+	}
+
+	n, err := strconv.Atoi(string(raw))
+	if err != nil || !Department(n).Valid() {
+		return -1, fmt.Errorf("%w: unknown department %s", ErrInvalidInput, raw)
+	}
+	return Department(n), nil
+}
+
+// parseLegacyDate parses a date written as YYYY-MM-DD or RFC 3339
+func parseLegacyDate(values ...string) (time.Time, error) {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if date, err := parseDate(value); err == nil {
+			return date, nil
+		}
+		if date, err := time.Parse(time.RFC3339, value); err == nil {
+			return date.UTC(), nil
+		}
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidInput, value)
+	}
+	return time.Time{}, nil
+}
+
+// toEmployee converts a legacy record into the unified Employee schema
+func (l legacyEmployee) toEmployee(defaultDept Department, defaultJoin time.Time, mapping *DepartmentMapping) (*Employee, error) {
+	dept, err := parseLegacyDepartment(l.Department, defaultDept, mapping)
+	if err != nil {
+		return nil, err
+	}
+	joinDate, err := parseLegacyDate(l.JoinDate, l.JoinDateAPI)
+	if err != nil {
+		return nil, err
+	}
+	if joinDate.IsZero() {
+		joinDate = defaultJoin
+	}
+	birthDate, err := parseLegacyDate(l.BirthDate, l.BirthAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Employee{
+		ID:         l.ID,
+		Name:       l.Name,
+		Position:   l.Position,
+		Salary:     l.Salary,
+		Department: dept,
+		JoinDate:   joinDate,
+		BirthDate:  birthDate,
+	}, nil
+}
+
+// decodeLegacyEmployees reads either a JSON array of employees or an object
+// keyed by employee ID, as the lab programs' maps marshal to
+func decodeLegacyEmployees(data []byte) ([]legacyEmployee, error) {
+	var list []legacyEmployee
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+
+	var byID map[string]legacyEmployee
+	if err := json.Unmarshal(data, &byID); err != nil {
+		return nil, fmt.Errorf("%w: expected a JSON array or object of employees", ErrInvalidInput)
+	}
+	for key, l := range byID {
+		if l.ID == 0 {
+			l.ID, _ = strconv.Atoi(key)
+		}
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// MigrateEmployees converts legacy records into employees, validating them in a
+// fresh manager so IDs are assigned and duplicates rejected. Department
+// names are mapped with mapping, which counts what it mapped. It returns the
+// migrated employees and an error for each record that could not be migrated.
+// If ctx is cancelled, the remaining records are not migrated and the last
+// error is ErrCancelled. progress, if not nil, is told about each record.
+func MigrateEmployees(ctx context.Context, records []legacyEmployee, defaultDept Department, defaultJoin time.Time, mapping *DepartmentMapping, progress *Progress) ([]*Employee, []error) {
+	manager := NewInMemoryEmployeeManager()
+	errs := make([]error, 0)
+
+	for i, record := range records {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("%w: %d of %d record(s) not migrated", ErrCancelled, len(records)-i, len(records)))
+			break
+		}
+		if progress != nil {
+			progress.Add(1)
+		}
+		employee, err := record.toEmployee(defaultDept, defaultJoin, mapping)
+		if err == nil {
+			err = manager.AddEmployee(employee)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("record %d (%s): %w", i+1, record.Name, err))
+		}
+	}
+
+	employees, _ := manager.ListEmployees()
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+	return employees, errs
+}
+
+// runMigrate implements the migrate command, which converts data saved by any
+// of the lab programs into the Lab_Exercise_06 JSON schema
+func runMigrate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	in := fs.String("in", "", "file to read (default standard input)")
+	out := fs.String("out", "", "file to write (default standard output)")
+	defaultDept := Department(-1)
+	fs.Var(&defaultDept, "department", "department `name` for records without one (e.g. Lab_Exercise_05 data)")
+	joined := fs.String("joined", "", "join date (YYYY-MM-DD) for records without one (default today)")
+	mapping := NewDepartmentMapping()
+	fs.Var(mapping, "map", "map an old department onto one of these as `old=department`; repeatable, and IT=Engineering by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	defaultJoin := today()
+	if *joined != "" {
+		date, err := parseDate(*joined)
+		if err != nil {
+			return fmt.Errorf("%w: -joined must be a date in YYYY-MM-DD format", ErrInvalidInput)
+		}
+		defaultJoin = date
+	}
+
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdin = f
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+
+	records, err := decodeLegacyEmployees(data)
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C stops migrating and writes the records migrated so far
+	ctx, stop := interruptContext()
+	defer stop()
+	progress := NewProgress(os.Stderr, "Migrating", len(records))
+	employees, errs := MigrateEmployees(ctx, records, defaultDept, defaultJoin, mapping, progress)
+	if ctx.Err() != nil {
+		progress.Done(ErrCancelled)
+	} else {
+		progress.Done(nil)
+	}
+	for _, err := range errs {
+		fmt.Fprintln(stderr, "Skipped", err)
+	}
+
+	result := make([]employeeJSON, 0, len(employees))
+	for _, e := range employees {
+		result = append(result, toEmployeeJSON(e))
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout = f
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "Migrated %d of %d record(s)\n", len(employees), len(records))
+	writeMappingCounts(stderr, "Mapped departments", mapping.Mapped)
+	writeMappingCounts(stderr, "Unmapped departments", mapping.Unmapped)
+	return nil
+}
+
+// writeMappingCounts prints the record counts of a department mapping, by name
+func writeMappingCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(counts))
+	total := 0
+	for name, n := range counts {
+		names = append(names, name)
+		total += n
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "%s: %d record(s)\n", title, total)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %d\n", name, counts[name])
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrateMapsLegacyDepartments(t *testing.T) {
+	records, err := decodeLegacyEmployees([]byte(`[
+		{"ID": 1, "Name": "Ann", "Department": "IT", "Salary": 60000, "Position": "Lead"},
+		{"ID": 2, "Name": "Bob", "Department": "HR", "Salary": 50000, "Position": "Senior"},
+		{"ID": 3, "Name": "Cy", "Department": "Legal", "Salary": 50000, "Position": "Junior"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := NewDepartmentMapping()
+	employees, errs := MigrateEmployees(context.Background(), records, -1, dateOf(2020, 1, 1), mapping, nil)
+	if len(employees) != 2 || len(errs) != 1 {
+		t.Fatalf("migrated %d and skipped %d record(s), want 2 and 1: %v", len(employees), len(errs), errs)
+	}
+	if employees[0].Department != Engineering {
+		t.Errorf("IT was migrated to %s, want Engineering", employees[0].Department)
+	}
+	if mapping.Mapped["IT -> Engineering"] != 1 || mapping.Unmapped["Legal"] != 1 || len(mapping.Mapped) != 1 {
+		t.Errorf("mapped %v and could not map %v", mapping.Mapped, mapping.Unmapped)
+	}
+
+	if err := mapping.Set("legal=Finance"); err != nil {
+		t.Fatal(err)
+	}
+	if dept, err := mapping.Department("Legal"); err != nil || dept != Finance {
+		t.Errorf("Legal mapped with -map legal=Finance is %v (%v), want Finance", dept, err)
+	}
+}
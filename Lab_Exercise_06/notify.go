@@ -0,0 +1,629 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrNoNotificationSettings is returned for a user who has not chosen how to be notified
+var ErrNoNotificationSettings = errorsx.New(errorsx.ErrNotFound, "user", "no notification settings")
+
+// Notification event types, besides the manager's employee events
+const (
+	NotifyWatchAlert       = "watch.alert"
+	NotifyDocumentExpiring = "document.expiring"
+	NotifyLeaveRequested   = "leave.requested"
+	NotifyLeaveDecided     = "leave.decided"
+	NotifyWeeklyDigest     = "digest.weekly"
+	NotifyDigest           = "digest" // a user's queued notifications, together
+	NotifyTest             = "test"   // sent on request to check a user's channels
+)
+
+// notificationEvents are the event types users can choose to be notified of
+var notificationEvents = []string{
+	EventEmployeeAdded, EventEmployeeUpdated, EventEmployeeRemoved, EventEmployeeTransferred,
+	NotifyWatchAlert, NotifyDocumentExpiring, NotifyLeaveRequested, NotifyLeaveDecided, NotifyWeeklyDigest,
+}
+
+// Delivery constants using iota
+const (
+	DeliverImmediately = iota // each notification as it happens
+	DeliverDigest             // the day's notifications together, sent by the scheduler
+)
+
+// DeliveryToString converts a delivery constant to string
+func DeliveryToString(delivery int) string {
+	switch delivery {
+	case DeliverImmediately:
+		return "immediate"
+	case DeliverDigest:
+		return "digest"
+	default:
+		return "unknown"
+	}
+}
+
+// StringToDelivery converts string to delivery constant
+func StringToDelivery(delivery string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(delivery)) {
+	case "", "immediate", "immediately":
+		return DeliverImmediately, nil
+	case "digest", "daily":
+		return DeliverDigest, nil
+	default:
+		return -1, fmt.Errorf("%w: delivery must be immediate or digest", ErrInvalidInput)
+	}
+}
+
+// Notification is something users can be told about, on any channel
+type Notification struct {
+	Event   string    `json:"event"` // one of notificationEvents
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`             // one line
+	Detail  string    `json:"detail,omitempty"` // more, for email
+	Time    time.Time `json:"time"`
+
+	label   string      // what the console prints before the text; "Notification" if empty
+	payload interface{} // what webhooks are sent; the notification itself if nil
+}
+
+// NotificationSettings are how one user wants to be notified
+type NotificationSettings struct {
+	User     string   `json:"user"`
+	Channels []string `json:"channels"`         // notification targets, in the form OpenNotifier reads
+	Events   []string `json:"events,omitempty"` // event types to be notified of; all if empty
+	Delivery string   `json:"delivery"`         // immediate or digest
+}
+
+// Validate checks the channels, event types and delivery of the settings
+func (s *NotificationSettings) Validate() error {
+	if strings.TrimSpace(s.User) == "" {
+		return fmt.Errorf("%w: notification settings need a user", ErrInvalidInput)
+	}
+	if len(s.Channels) == 0 {
+		return fmt.Errorf("%w: notification settings need at least one channel", ErrInvalidInput)
+	}
+	for _, target := range s.Channels {
+		if _, err := OpenNotifier(target); err != nil {
+			return err
+		}
+	}
+	for _, event := range s.Events {
+		if !knownNotificationEvent(event) {
+			return fmt.Errorf("%w: unknown event type %q (available: %s)", ErrInvalidInput, event, strings.Join(notificationEvents, ", "))
+		}
+	}
+	_, err := StringToDelivery(s.Delivery)
+	return err
+}
+
+// knownNotificationEvent reports whether users can be notified of an event type
+func knownNotificationEvent(event string) bool {
+	for _, e := range notificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Wants reports whether the user is notified of an event type. Test
+// notifications go to everyone who asks for one.
+func (s *NotificationSettings) Wants(event string) bool {
+	if len(s.Events) == 0 || event == NotifyTest {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// digest returns whether the user's notifications wait for the digest
+func (s *NotificationSettings) digest() bool {
+	delivery, _ := StringToDelivery(s.Delivery)
+	return delivery == DeliverDigest
+}
+
+// queued reports whether a notification waits for the digest of users who
+// want one. Tests and the weekly digest are always sent straight away.
+func (n *Notification) queued() bool {
+	return n.Event != NotifyTest && n.Event != NotifyWeeklyDigest
+}
+
+// notificationsFile is the JSON file users' notification settings are kept in
+type notificationsFile struct {
+	Settings []NotificationSettings    `json:"settings"`
+	Queued   map[string][]Notification `json:"queued,omitempty"` // waiting for the digest, by user
+}
+
+// Notifications sends what happens to the users who asked to be told, on
+// their channels, as it happens or in a daily digest
+type Notifications struct {
+	mu       sync.Mutex
+	settings map[string]*NotificationSettings // by user name
+	queued   map[string][]Notification        // waiting for the digest, by user name
+	path     string                           // JSON file they are kept in, or "" for memory only
+}
+
+// appNotifications are the users' notification settings. main loads them
+// from the -notifications-file flag; without it they are kept in memory.
+var appNotifications = NewNotificationsInMemory()
+
+// NewNotificationsInMemory creates notification settings that are never saved
+func NewNotificationsInMemory() *Notifications {
+	return &Notifications{settings: make(map[string]*NotificationSettings), queued: make(map[string][]Notification)}
+}
+
+// NewNotifications creates notification settings kept in the file at path,
+// reading them from it if there is one
+func NewNotifications(path string) (*Notifications, error) {
+	n := NewNotificationsInMemory()
+	n.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return n, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var file notificationsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading notification settings %s: %w", path, err)
+	}
+	for i := range file.Settings {
+		s := file.Settings[i]
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: notification settings of %q: %w", path, s.User, err)
+		}
+		n.settings[s.User] = &s
+	}
+	for user, list := range file.Queued {
+		n.queued[user] = list
+	}
+	return n, nil
+}
+
+// save writes the settings and queued notifications to the file. The caller
+// must hold the lock. The file is written under a temporary name and
+// renamed, and an interrupt waits for the save, so it is never left half
+// written.
+func (n *Notifications) save() error {
+	if n.path == "" {
+		return nil
+	}
+	file := notificationsFile{Settings: n.all(), Queued: n.queued}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		tmp := n.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, n.path)
+	})
+}
+
+// all returns every user's settings, by user name. The caller must hold the lock.
+func (n *Notifications) all() []NotificationSettings {
+	list := make([]NotificationSettings, 0, len(n.settings))
+	for _, s := range n.settings {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].User < list[j].User })
+	return list
+}
+
+// Settings returns a user's settings, if they have any
+func (n *Notifications) Settings(user string) (NotificationSettings, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, ok := n.settings[user]
+	if !ok {
+		return NotificationSettings{}, false
+	}
+	return *s, true
+}
+
+// Set replaces a user's settings. Notifications already waiting for the
+// digest are sent with the next one.
+func (n *Notifications) Set(s NotificationSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	delivery, _ := StringToDelivery(s.Delivery)
+	s.Delivery = DeliveryToString(delivery)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	old, had := n.settings[s.User]
+	n.settings[s.User] = &s
+	if err := n.save(); err != nil {
+		if had {
+			n.settings[s.User] = old
+		} else {
+			delete(n.settings, s.User)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove deletes a user's settings, turning their notifications off and
+// dropping the ones waiting for their digest
+func (n *Notifications) Remove(user string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	old, ok := n.settings[user]
+	if !ok {
+		return ErrNoNotificationSettings.WithValue(user)
+	}
+	queued := n.queued[user]
+	delete(n.settings, user)
+	delete(n.queued, user)
+	if err := n.save(); err != nil {
+		n.settings[user] = old
+		if queued != nil {
+			n.queued[user] = queued
+		}
+		return err
+	}
+	return nil
+}
+
+// Publish sends a notification to the channels of every user who wants its
+// event type, or queues it for the digest of those who want one. Delivery
+// failures are reported on the console so one broken channel does not hide
+// the others.
+func (n *Notifications) Publish(note Notification) {
+	n.mu.Lock()
+	var immediate []NotificationSettings
+	queued := false
+	for _, s := range n.settings {
+		if !s.Wants(note.Event) {
+			continue
+		}
+		if s.digest() && note.queued() {
+			n.queued[s.User] = append(n.queued[s.User], note)
+			queued = true
+		} else {
+			immediate = append(immediate, *s)
+		}
+	}
+	if queued {
+		if err := n.save(); err != nil {
+			fmt.Printf("%s notifications for the digest could not be saved: %v\n", errorText("Error:"), err)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, s := range immediate {
+		for _, err := range deliverNotification(s.Channels, note) {
+			fmt.Printf("%s could not notify %s: %v\n", errorText("Error:"), s.User, err)
+		}
+	}
+}
+
+// Test sends a test notification to one user's channels, returning the
+// errors of the channels it could not be sent on
+func (n *Notifications) Test(user string, now time.Time) []error {
+	s, ok := n.Settings(user)
+	if !ok {
+		return []error{ErrNoNotificationSettings.WithValue(user)}
+	}
+	return deliverNotification(s.Channels, Notification{
+		Event:   NotifyTest,
+		Subject: "Test notification",
+		Text:    fmt.Sprintf("Notifications for %s are working", user),
+		Time:    now,
+	})
+}
+
+// SendDigests sends every user with queued notifications a digest of them,
+// and returns how many digests were sent
+func (n *Notifications) SendDigests(now time.Time) (int, []error) {
+	n.mu.Lock()
+	users := make([]string, 0, len(n.queued))
+	for user := range n.queued {
+		users = append(users, user)
+	}
+	n.mu.Unlock()
+	sort.Strings(users)
+
+	sent := 0
+	errs := make([]error, 0)
+	for _, user := range users {
+		ok, failed := n.SendDigest(user, now)
+		for _, err := range failed {
+			errs = append(errs, fmt.Errorf("digest for %s: %w", user, err))
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errs
+}
+
+// SendDigest sends a user the notifications queued for their digest, and
+// reports whether it was sent. A digest that cannot be sent on any channel
+// stays queued for the next run.
+func (n *Notifications) SendDigest(user string, now time.Time) (bool, []error) {
+	n.mu.Lock()
+	pending := n.queued[user]
+	s, ok := n.settings[user]
+	n.mu.Unlock()
+	if !ok || len(pending) == 0 {
+		return false, nil
+	}
+
+	errs := deliverNotification(s.Channels, digestNotification(pending, now))
+	if len(errs) == len(s.Channels) {
+		return false, errs
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	// Notifications queued while the digest was being sent wait for the next
+	n.queued[user] = n.queued[user][min(len(pending), len(n.queued[user])):]
+	if len(n.queued[user]) == 0 {
+		delete(n.queued, user)
+	}
+	if err := n.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return true, errs
+}
+
+// Queued returns how many notifications are waiting for a user's digest
+func (n *Notifications) Queued(user string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.queued[user])
+}
+
+// Subscriber returns an event subscriber that notifies users of the
+// employee changes made in the named environment
+func (n *Notifications) Subscriber(env string) func(Event) {
+	return func(ev Event) {
+		n.Publish(Notification{
+			Event:   ev.Type,
+			Subject: fmt.Sprintf("Employee change in %s", env),
+			Text:    ev.Message,
+			Time:    ev.Time,
+		})
+	}
+}
+
+// digestNotification combines notifications into one, oldest first
+func digestNotification(list []Notification, now time.Time) Notification {
+	sorted := append([]Notification(nil), list...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	var detail strings.Builder
+	for _, note := range sorted {
+		fmt.Fprintf(&detail, "[%s] %s: %s\n", formatDateTime(note.Time), note.Subject, note.Text)
+	}
+	return Notification{
+		Event:   NotifyDigest,
+		Subject: fmt.Sprintf("Digest of %d notification(s)", len(sorted)),
+		Text:    fmt.Sprintf("%d notification(s) since the last digest", len(sorted)),
+		Detail:  strings.TrimSuffix(detail.String(), "\n"),
+		Time:    now,
+		label:   "Notification digest",
+		payload: struct {
+			Event         string         `json:"event"`
+			Time          time.Time      `json:"time"`
+			Notifications []Notification `json:"notifications"`
+		}{NotifyDigest, now, sorted},
+	}
+}
+
+// deliverNotification sends a notification to each target, returning the
+// errors of those it could not be sent to
+func deliverNotification(targets []string, note Notification) []error {
+	var errs []error
+	for _, target := range targets {
+		notifier, err := OpenNotifier(target)
+		if err == nil {
+			err = notifier.Notify(note)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifierKind(target), err))
+		}
+	}
+	return errs
+}
+
+// leaveNotification describes a leave request that was made or decided
+func leaveNotification(r *LeaveRequest, manager EmployeeManager) Notification {
+	name := fmt.Sprintf("employee ID %d", r.EmployeeID)
+	if e, err := manager.GetEmployee(r.EmployeeID); err == nil {
+		name = e.Name
+	}
+	if r.Status == StatusPending {
+		return Notification{
+			Event:   NotifyLeaveRequested,
+			Subject: "Leave requested",
+			Text:    fmt.Sprintf("%s asked for leave #%d, %s (%.1f day(s))", name, r.ID, r.Span(), r.Days),
+			Detail:  r.Note,
+			Time:    r.RequestedAt,
+		}
+	}
+	return Notification{
+		Event:   NotifyLeaveDecided,
+		Subject: "Leave " + strings.ToLower(ApprovalStatusToString(r.Status)),
+		Text: fmt.Sprintf("Leave #%d of %s, %s, was %s by %s", r.ID, name, r.Span(),
+			strings.ToLower(ApprovalStatusToString(r.Status)), r.DecidedBy),
+		Detail: r.Reason,
+		Time:   r.DecidedAt,
+	}
+}
+
+// writeNotificationSettings writes a user's notification settings
+func writeNotificationSettings(w io.Writer, s NotificationSettings, queued int) {
+	fmt.Fprintf(w, "\nNotification settings of %s:\n", s.User)
+	for _, target := range s.Channels {
+		fmt.Fprintf(w, "  %-8s %s\n", notifierKind(target), target)
+	}
+	events := "all"
+	if len(s.Events) > 0 {
+		events = strings.Join(s.Events, ", ")
+	}
+	fmt.Fprintf(w, "Events:   %s\n", events)
+	fmt.Fprintf(w, "Delivery: %s\n", s.Delivery)
+	if queued > 0 {
+		fmt.Fprintf(w, "%d notification(s) waiting for the next digest\n", queued)
+	}
+}
+
+// readNotificationSettings asks for a user's notification settings, offering
+// their current ones as the defaults
+func readNotificationSettings(reader *bufio.Reader, current NotificationSettings) (NotificationSettings, error) {
+	s := NotificationSettings{User: current.User}
+	prompt := "Channels (console, mailto URL, Slack webhook URL or webhook URL, comma-separated): "
+	if len(current.Channels) > 0 {
+		prompt = fmt.Sprintf("Channels, comma-separated (blank to keep %s): ", strings.Join(current.Channels, ", "))
+	}
+	channels, err := readString(reader, prompt)
+	if err != nil {
+		return s, err
+	}
+	s.Channels = splitList(channels)
+	if len(s.Channels) == 0 {
+		s.Channels = current.Channels
+	}
+
+	fmt.Printf("Event types: %s\n", strings.Join(notificationEvents, ", "))
+	events, err := readString(reader, "Events to be notified of, comma-separated (blank for all): ")
+	if err != nil {
+		return s, err
+	}
+	s.Events = splitList(events)
+
+	delivery, err := readValue(reader, "Delivery (immediate or digest): ", StringToDelivery)
+	if err != nil {
+		return s, err
+	}
+	s.Delivery = DeliveryToString(delivery)
+	return s, nil
+}
+
+// splitList splits a comma-separated list, dropping blank items
+func splitList(text string) []string {
+	var list []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// notificationsInteractive lets the user choose how they are notified, and
+// preview or send the weekly digest
+func notificationsInteractive(notifications *Notifications, manager EmployeeManager, documents *Documents, leave *Leave, clock *TimeClock, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Notifications ==="))
+	fmt.Println("1. View my notification settings")
+	fmt.Println("2. Change my notification settings")
+	fmt.Println("3. Turn my notifications off")
+	fmt.Println("4. Send me a test notification")
+	fmt.Println("5. Send my digest now")
+	fmt.Println("6. Preview the weekly digest")
+	fmt.Println("7. Send the weekly digest now")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+	if option >= 1 && option <= 5 {
+		if err := user.Require(PermManageNotifications); err != nil {
+			return err
+		}
+	}
+	settings, ok := notifications.Settings(user.Name)
+
+	switch option {
+	case 1:
+		if !ok {
+			fmt.Println("\nYou have no notification settings, so you are not notified of anything.")
+			return nil
+		}
+		writeNotificationSettings(os.Stdout, settings, notifications.Queued(user.Name))
+
+	case 2:
+		if !ok {
+			settings.User = user.Name
+		}
+		settings, err = readNotificationSettings(reader, settings)
+		if err != nil {
+			return err
+		}
+		if err := notifications.Set(settings); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText("Notification settings saved."))
+
+	case 3:
+		if err := notifications.Remove(user.Name); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText("Notifications turned off."))
+
+	case 4:
+		errs := notifications.Test(user.Name, clockOf(manager).Now())
+		for _, err := range errs {
+			fmt.Println(errorText("Error:"), err)
+		}
+		if len(errs) == 0 {
+			fmt.Println("\n" + successText("Test notification sent."))
+		}
+
+	case 5:
+		sent, errs := notifications.SendDigest(user.Name, clockOf(manager).Now())
+		for _, err := range errs {
+			fmt.Println(errorText("Error:"), err)
+		}
+		if !sent {
+			if len(errs) == 0 {
+				fmt.Println("\nNo notifications are waiting for your digest.")
+			}
+			return nil
+		}
+		fmt.Println("\n" + successText("Digest sent."))
+
+	case 6, 7:
+		if err := user.Require(PermViewPersonalData); err != nil {
+			return err
+		}
+		now := clockOf(manager).Now()
+		digest, err := BuildWeeklyDigest(manager, documents, leave, clock, now)
+		if err != nil {
+			return err
+		}
+		if option == 6 {
+			fmt.Println()
+			return WriteWeeklyDigest(os.Stdout, digest, now)
+		}
+		note, err := digest.Notification(now)
+		if err != nil {
+			return err
+		}
+		notifications.Publish(note)
+		fmt.Println("\n" + successText("Weekly digest sent: "+note.Text))
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// List order constants using iota
+const (
+	OrderByID = iota
+	OrderByName
+	OrderBySalary
+	OrderByDepartment
+	OrderByJoinDate
+)
+
+// listOrder decides how employee lists and search results are ordered.
+// main sets it from the -sort flag.
+var listOrder = OrderByID
+
+// OrderToString converts a list order constant to string
+func OrderToString(order int) string {
+	switch order {
+	case OrderByID:
+		return "id"
+	case OrderByName:
+		return "name"
+	case OrderBySalary:
+		return "salary"
+	case OrderByDepartment:
+		return "department"
+	case OrderByJoinDate:
+		return "joined"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToOrder converts string to list order constant
+func StringToOrder(order string) (int, error) {
+	switch strings.ToLower(order) {
+	case "id", "":
+		return OrderByID, nil
+	case "name":
+		return OrderByName, nil
+	case "salary":
+		return OrderBySalary, nil
+	case "department", "dept":
+		return OrderByDepartment, nil
+	case "joined", "join_date":
+		return OrderByJoinDate, nil
+	default:
+		return -1, fmt.Errorf("%w: sort order must be id, name, salary, department or joined", ErrInvalidInput)
+	}
+}
+
+// employeeLess reports whether a sorts before b in the given order. Employees
+// that compare equal are ordered by ID, so every order is deterministic.
+func employeeLess(order int, a, b *Employee) bool {
+	switch order {
+	case OrderByName:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	case OrderBySalary:
+		if a.Salary != b.Salary {
+			return a.Salary < b.Salary
+		}
+	case OrderByDepartment:
+		if a.Department != b.Department {
+			return a.Department < b.Department
+		}
+	case OrderByJoinDate:
+		if !a.JoinDate.Equal(b.JoinDate) {
+			return a.JoinDate.Before(b.JoinDate)
+		}
+	}
+	return a.ID < b.ID
+}
+
+// sortEmployees orders employees in place. Managers return employees in
+// whatever order their storage happens to hold them, so every list shown to
+// the user goes through here.
+func sortEmployees(employees []*Employee, order int) {
+	sort.Slice(employees, func(i, j int) bool { return employeeLess(order, employees[i], employees[j]) })
+}
+
+// sortEmployeeValues orders employee values in place
+func sortEmployeeValues(employees []Employee, order int) {
+	sort.Slice(employees, func(i, j int) bool { return employeeLess(order, &employees[i], &employees[j]) })
+}
+
+// ListSort is a list order and its direction, as the API's sort parameter
+// gives it: "salary" for ascending, "-salary" for descending
+type ListSort struct {
+	Order      int
+	Descending bool
+}
+
+// ParseListSort converts a sort parameter to a ListSort
+func ParseListSort(s string) (ListSort, error) {
+	name, descending := strings.CutPrefix(s, "-")
+	order, err := StringToOrder(name)
+	if err != nil {
+		names := []string{"id", "name", "salary", "department", "joined"}
+		if suggestion := closestWord(strings.ToLower(name), names); suggestion != "" {
+			return ListSort{}, fmt.Errorf("%w: unknown sort %q; did you mean %s?", ErrInvalidInput, name, suggestion)
+		}
+		return ListSort{}, fmt.Errorf("%w: sort must be %s, with a leading - for descending order", ErrInvalidInput, strings.Join(names, ", "))
+	}
+	return ListSort{Order: order, Descending: descending}, nil
+}
+
+// String returns the sort parameter for the sort
+func (s ListSort) String() string {
+	if s.Descending {
+		return "-" + OrderToString(s.Order)
+	}
+	return OrderToString(s.Order)
+}
+
+// Less reports whether a is listed before b
+func (s ListSort) Less(a, b *Employee) bool {
+	if s.Descending {
+		return employeeLess(s.Order, b, a)
+	}
+	return employeeLess(s.Order, a, b)
+}
+
+// SortValues orders employee values in place
+func (s ListSort) SortValues(employees []Employee) {
+	sort.Slice(employees, func(i, j int) bool { return s.Less(&employees[i], &employees[j]) })
+}
@@ -0,0 +1,99 @@
+//go:build otel
+
+// The otel tracer needs the OpenTelemetry SDK, so it is kept out of the default
+// build. To use it, copy this file next to main.go in a module that requires
+// go.opentelemetry.io/otel, go.opentelemetry.io/otel/sdk and
+// go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp, build with
+// -tags otel, and select it with -trace otel -trace-dsn localhost:4318.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelServiceName identifies this program's spans in the tracing backend
+const otelServiceName = "employee-management"
+
+// otelTracer adapts an OpenTelemetry tracer to the Tracer interface
+type otelTracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// Start starts a span, as a child of the span in ctx if there is one
+func (t otelTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, name)
+	return ctx, otelSpan{span}
+}
+
+// Extract continues the trace of the request's traceparent header
+func (t otelTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// otelSpan adapts an OpenTelemetry span to the Span interface
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case float64:
+		s.span.SetAttributes(attribute.Float64(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+func init() {
+	// The DSN is the host:port of an OTLP/HTTP collector without TLS, such as a
+	// local agent. Without one, the exporter reads the standard
+	// OTEL_EXPORTER_OTLP_* environment variables.
+	RegisterTracer("otel", func(dsn string) (Tracer, func() error, error) {
+		options := make([]otlptracehttp.Option, 0)
+		if dsn != "" {
+			options = append(options, otlptracehttp.WithEndpoint(dsn), otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), options...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		provider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", otelServiceName))),
+		)
+		tracer := otelTracer{
+			tracer:     provider.Tracer(otelServiceName),
+			propagator: propagation.TraceContext{},
+		}
+		shutdown := func() error { return provider.Shutdown(context.Background()) }
+		return tracer, shutdown, nil
+	})
+}
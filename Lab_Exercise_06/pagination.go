@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultPageSize is how many employees a page holds when a cursor is given
+// without a limit
+const defaultPageSize = 100
+
+// maxPageSize is the largest page the API returns
+const maxPageSize = 1000
+
+// pageCursor marks where a page ended: the sort key and ID of its last
+// employee. The next page starts after that position in the order rather
+// than at an offset, so adding or removing employees while paging does not
+// shift later pages. An employee whose sort key changes between pages may
+// still be listed twice or missed.
+type pageCursor struct {
+	Order string `json:"o"`
+	ID    int    `json:"id"`
+	Key   string `json:"k,omitempty"`
+}
+
+// encodeCursor returns the opaque token for the page ending at e
+func encodeCursor(sort ListSort, e *Employee) string {
+	c := pageCursor{Order: sort.String(), ID: e.ID}
+	switch sort.Order {
+	case OrderByName:
+		c.Key = e.Name
+	case OrderBySalary:
+		c.Key = strconv.FormatFloat(e.Salary, 'g', -1, 64)
+	case OrderByDepartment:
+		c.Key = strconv.Itoa(int(e.Department))
+	case OrderByJoinDate:
+		c.Key = e.JoinDate.UTC().Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the position a token marks, as an employee holding
+// the sort key and ID. The token must have been issued for the same sort.
+func decodeCursor(token string, sort ListSort) (*Employee, error) {
+	invalid := fmt.Errorf("%w: cursor is not valid", ErrInvalidInput)
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalid
+	}
+	if c.Order != sort.String() {
+		return nil, fmt.Errorf("%w: cursor was issued for sort=%s, not sort=%s", ErrInvalidInput, c.Order, sort)
+	}
+
+	e := &Employee{ID: c.ID}
+	switch sort.Order {
+	case OrderByName:
+		e.Name = c.Key
+	case OrderBySalary:
+		e.Salary, err = strconv.ParseFloat(c.Key, 64)
+	case OrderByDepartment:
+		var n int
+		n, err = strconv.Atoi(c.Key)
+		e.Department = Department(n)
+	case OrderByJoinDate:
+		e.JoinDate, err = time.Parse(time.RFC3339Nano, c.Key)
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	return e, nil
+}
+
+// pageRequest is the page a list request asks for. A request with neither a
+// limit nor a cursor asks for every employee.
+type pageRequest struct {
+	Paged bool
+	Limit int
+	After *Employee // the position the page starts after, nil for the first page
+}
+
+// parsePageRequest reads the limit and cursor parameters of a list request
+func parsePageRequest(query url.Values, sort ListSort) (pageRequest, error) {
+	var p pageRequest
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return p, fmt.Errorf("%w: limit must be a number from 1 to %d", ErrInvalidInput, maxPageSize)
+		}
+		p.Paged, p.Limit = true, n
+	}
+	if token := query.Get("cursor"); token != "" {
+		after, err := decodeCursor(token, sort)
+		if err != nil {
+			return p, err
+		}
+		p.Paged, p.After = true, after
+	}
+	if p.Paged && p.Limit == 0 {
+		p.Limit = defaultPageSize
+	}
+	return p, nil
+}
+
+// setNextPageLink points the response at the page after the one ending at
+// last, keeping the request's other parameters
+func setNextPageLink(w http.ResponseWriter, r *http.Request, sort ListSort, last *Employee) {
+	cursor := encodeCursor(sort, last)
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	w.Header().Set("Next-Cursor", cursor)
+}
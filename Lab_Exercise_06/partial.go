@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// employeeJSONFields are the names of the fields of an employee in the API,
+// in the order they are written
+var employeeJSONFields = func() []string {
+	t := reflect.TypeOf(employeeJSON{})
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(t.Field(i).Tag.Get("json"), ",")
+	}
+	return names
+}()
+
+// requiredEmployeeFields cannot be removed by a merge patch
+var requiredEmployeeFields = []string{"name", "salary", "department", "join_date"}
+
+// parseFieldSelection reads the fields parameter, a comma-separated list of
+// the employee fields to return. It returns nil when every field is wanted.
+func parseFieldSelection(query url.Values) ([]string, error) {
+	v := query.Get("fields")
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(employeeJSONFields, name) {
+			return nil, fmt.Errorf("%w: unknown field %q in fields (%s)", ErrInvalidInput, name, strings.Join(employeeJSONFields, ", "))
+		}
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// selectFields returns the employee with only the selected fields, or whole
+// when fields is nil. Optional fields the employee does not have are left out.
+func selectFields(e employeeJSON, fields []string) interface{} {
+	if fields == nil {
+		return e
+	}
+	var all map[string]json.RawMessage
+	data, _ := json.Marshal(e)
+	json.Unmarshal(data, &all)
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if v, ok := all[name]; ok {
+			selected[name] = v
+		}
+	}
+	return selected
+}
+
+// mergePatch applies a JSON Merge Patch (RFC 7396) to a decoded JSON
+// document: members of a patch object replace those of the target, null
+// removes a member, and nested objects are merged the same way
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+		} else {
+			t[name] = mergePatch(t[name], value)
+		}
+	}
+	return t
+}
+
+// patchEmployee applies a merge patch to an employee's API representation
+// and returns the patched employee
+func patchEmployee(current *Employee, patch []byte) (*Employee, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%w: the patch must be a JSON object", ErrInvalidInput)
+	}
+
+	var doc interface{}
+	data, _ := json.Marshal(toEmployeeJSON(current))
+	json.Unmarshal(data, &doc)
+	patched := mergePatch(doc, p).(map[string]interface{})
+	for _, name := range requiredEmployeeFields {
+		if _, ok := patched[name]; !ok {
+			return nil, fieldError(name, ReasonRequired, "%s cannot be removed", name)
+		}
+	}
+
+	data, _ = json.Marshal(patched)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var in employeeJSON
+	if err := dec.Decode(&in); err != nil {
+		return nil, decodeError(err)
+	}
+	if in.ID != current.ID {
+		return nil, fieldError("id", ReasonImmutable, "id cannot be changed")
+	}
+	return fromEmployeeJSON(in)
+}
+
+// handlePatchEmployee changes the fields of an employee named in a JSON
+// Merge Patch, leaving the rest as they are
+func (s *Server) handlePatchEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, ErrInvalidID)
+		return
+	}
+	if ct, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); ct != "" && ct != "application/merge-patch+json" && ct != "application/json" {
+		writeAPIError(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMedia, "patches must be application/merge-patch+json")
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		writeError(w, r, fmt.Errorf("%w: reading the request: %v", ErrInvalidInput, err))
+		return
+	}
+	s.updateEmployee(w, r, id, func(current *Employee) (*Employee, error) {
+		return patchEmployee(current, patch)
+	})
+}
@@ -0,0 +1,634 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrPayrollUnreconciled means a payroll run's totals are not the sums of its
+// line items, so it must not be paid
+var ErrPayrollUnreconciled = errorsx.New(errorsx.ErrConflict, "", "payroll does not reconcile")
+
+// Currency is a currency payroll can be paid in
+type Currency struct {
+	Code     string
+	Decimals int // digits of the minor unit, such as 2 for cents
+}
+
+// currencies are the currencies payroll can be paid in, by ISO 4217 code
+var currencies = map[string]Currency{
+	"USD": {"USD", 2},
+	"EUR": {"EUR", 2},
+	"GBP": {"GBP", 2},
+	"INR": {"INR", 2},
+	"CHF": {"CHF", 2},
+	"JPY": {"JPY", 0},
+	"KRW": {"KRW", 0},
+	"BHD": {"BHD", 3},
+	"KWD": {"KWD", 3},
+}
+
+// ParseCurrency returns the currency with an ISO 4217 code
+func ParseCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		codes := make([]string, 0, len(currencies))
+		for code := range currencies {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		return Currency{}, fmt.Errorf("%w: unknown currency %q (available: %s)", ErrInvalidInput, code, strings.Join(codes, ", "))
+	}
+	return c, nil
+}
+
+// minorUnits converts an amount in the currency to its minor units, such as
+// 12.345 dollars to 1234.5 cents. Digits well below the minor unit are
+// dropped, so float error cannot tip an amount over a rounding boundary.
+func (c Currency) minorUnits(amount float64) float64 {
+	return math.Round(amount*math.Pow10(c.Decimals)*1e4) / 1e4
+}
+
+// Format writes an amount in minor units in the currency's major units, such
+// as 123456 cents as 1234.56
+func (c Currency) Format(minor int64) string {
+	if c.Decimals == 0 {
+		return strconv.FormatInt(minor, 10)
+	}
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	unit := int64(math.Pow10(c.Decimals))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/unit, c.Decimals, minor%unit)
+}
+
+// RoundingMode is how an amount is rounded to the minor unit
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half-up"   // halves away from zero
+	RoundHalfEven RoundingMode = "half-even" // halves to the even unit, as banks do
+	RoundDown     RoundingMode = "down"      // toward zero
+	RoundUp       RoundingMode = "up"        // away from zero
+)
+
+// round rounds an amount in minor units to a whole unit
+func (m RoundingMode) round(units float64) int64 {
+	switch m {
+	case RoundHalfEven:
+		return int64(math.RoundToEven(units))
+	case RoundDown:
+		return int64(math.Trunc(units))
+	case RoundUp:
+		if units < 0 {
+			return int64(math.Floor(units))
+		}
+		return int64(math.Ceil(units))
+	}
+	return int64(math.Round(units))
+}
+
+// RoundingScope is what a rounding policy rounds
+type RoundingScope string
+
+const (
+	// RoundPerLine rounds each line item; the total is their sum
+	RoundPerLine RoundingScope = "line"
+	// RoundPerTotal rounds the run's total once and spreads it over the line
+	// items, the units rounding down left over going to the items that
+	// lost the most, so the total is what rounding it alone would give
+	RoundPerTotal RoundingScope = "total"
+)
+
+// RoundingPolicy is how a payroll run rounds its amounts to the minor unit
+type RoundingPolicy struct {
+	Mode  RoundingMode  `json:"mode"`
+	Scope RoundingScope `json:"scope"`
+}
+
+// String describes the policy, such as "half-up per line item"
+func (p RoundingPolicy) String() string {
+	if p.Scope == RoundPerTotal {
+		return string(p.Mode) + " on the total"
+	}
+	return string(p.Mode) + " per line item"
+}
+
+// DeductionRule is a deduction taken from every payslip, such as a pension
+// contribution of 5% or a health plan of 120 a month
+type DeductionRule struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"` // of gross pay
+	Amount  float64 `json:"amount"`  // a fixed monthly amount, prorated like base pay
+	PreTax  bool    `json:"pre_tax"` // taken from pay before income tax is worked out
+}
+
+// TaxBracket taxes the part of annual taxable pay from an amount up to the
+// next bracket at a rate
+type TaxBracket struct {
+	From float64 `json:"from"`
+	Rate float64 `json:"rate"` // in percent
+}
+
+// PayrollConfig is how payroll is computed
+type PayrollConfig struct {
+	Currency string `json:"currency"`
+	// Rounding is the rounding policy of each currency, by code. Currencies
+	// without one round half-up per line item.
+	Rounding   map[string]RoundingPolicy `json:"rounding"`
+	Deductions []DeductionRule           `json:"deductions"`
+	Tax        []TaxBracket              `json:"tax"` // income tax on annualized pay; none if empty
+	Payment    PaymentConfig             `json:"payment"`
+
+	currency Currency
+}
+
+// payrollConfig is how payroll is computed. main sets it from the -payroll flag.
+var payrollConfig = &PayrollConfig{Currency: "USD", currency: currencies["USD"]}
+
+// LoadPayrollConfig reads and checks a payroll configuration file
+func LoadPayrollConfig(path string) (*PayrollConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParsePayrollConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParsePayrollConfig reads and checks a payroll configuration in JSON
+func ParsePayrollConfig(data []byte) (*PayrollConfig, error) {
+	c := PayrollConfig{Currency: "USD"}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	var err error
+	if c.currency, err = ParseCurrency(c.Currency); err != nil {
+		return nil, err
+	}
+	policies := make(map[string]RoundingPolicy, len(c.Rounding))
+	for code, p := range c.Rounding {
+		currency, err := ParseCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		switch p.Mode {
+		case "":
+			p.Mode = RoundHalfUp
+		case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+		default:
+			return nil, fmt.Errorf("%w: %s rounding mode %q must be half-up, half-even, down or up", ErrInvalidInput, code, p.Mode)
+		}
+		switch p.Scope {
+		case "":
+			p.Scope = RoundPerLine
+		case RoundPerLine, RoundPerTotal:
+		default:
+			return nil, fmt.Errorf("%w: %s rounding scope %q must be line or total", ErrInvalidInput, code, p.Scope)
+		}
+		policies[currency.Code] = p
+	}
+	c.Rounding = policies
+
+	for _, d := range c.Deductions {
+		if d.Name == "" {
+			return nil, fmt.Errorf("%w: every deduction needs a name", ErrInvalidInput)
+		}
+		if (d.Percent == 0) == (d.Amount == 0) || d.Percent < 0 || d.Percent > 100 || d.Amount < 0 {
+			return nil, fmt.Errorf("%w: deduction %q needs either a percent from 0 to 100 or a positive amount", ErrInvalidInput, d.Name)
+		}
+	}
+	if err := c.Payment.validate(); err != nil {
+		return nil, err
+	}
+	sort.Slice(c.Tax, func(i, j int) bool { return c.Tax[i].From < c.Tax[j].From })
+	for i, b := range c.Tax {
+		if b.From < 0 || b.Rate < 0 || b.Rate > 100 || (i > 0 && b.From == c.Tax[i-1].From) {
+			return nil, fmt.Errorf("%w: tax brackets need distinct starts of at least 0 and rates from 0 to 100", ErrInvalidInput)
+		}
+	}
+	return &c, nil
+}
+
+// annualTax returns the income tax on an annual taxable pay
+func (c *PayrollConfig) annualTax(pay float64) float64 {
+	tax := 0.0
+	for i, b := range c.Tax {
+		upTo := pay
+		if i+1 < len(c.Tax) {
+			upTo = math.Min(pay, c.Tax[i+1].From)
+		}
+		if upTo > b.From {
+			tax += (upTo - b.From) * b.Rate / 100
+		}
+	}
+	return tax
+}
+
+// Policy returns the rounding policy of the currency payroll is paid in
+func (c *PayrollConfig) Policy() RoundingPolicy {
+	if p, ok := c.Rounding[c.currency.Code]; ok {
+		return p
+	}
+	return RoundingPolicy{Mode: RoundHalfUp, Scope: RoundPerLine}
+}
+
+// PayItemKind is whether a pay item adds to or takes from pay
+type PayItemKind int
+
+const (
+	Earning PayItemKind = iota
+	Deduction
+	Tax
+)
+
+// String returns the kind as payroll CSV writes it
+func (k PayItemKind) String() string {
+	switch k {
+	case Deduction:
+		return "deduction"
+	case Tax:
+		return "tax"
+	default:
+		return "earning"
+	}
+}
+
+// PayItem is a line item of an employee's pay for a month
+type PayItem struct {
+	Name   string
+	Kind   PayItemKind
+	Amount int64 // in minor units of the run's currency; deductions and tax are negative
+
+	exact float64 // the amount in minor units before rounding
+}
+
+// Payslip is an employee's pay for a month
+type Payslip struct {
+	Employee   *Employee
+	Items      []PayItem
+	Gross      int64
+	Deductions int64 // the deductions' total, as a positive amount
+	Tax        int64 // the tax's total, as a positive amount
+	Net        int64
+}
+
+// PayrollRun is the pay of every employee for a month, in minor units of
+// its currency
+type PayrollRun struct {
+	Month      time.Time // first day of the month
+	Currency   Currency
+	Policy     RoundingPolicy
+	Payslips   []Payslip // by employee ID
+	Gross      int64
+	Deductions int64
+	Tax        int64
+	Net        int64
+	// RoundingDifference is how far Net is from the exact net pay rounded
+	// once; rounding per line item can leave a few minor units either way
+	RoundingDifference int64
+}
+
+// RunPayroll computes the pay of the employees for the month starting on
+// a day. Salaries are annual, so a month pays a twelfth, and employees who
+// join during the month are paid for the days from their join date. The
+// configured deductions are taken, then income tax on the pay left after
+// pre-tax deductions, worked out on what a full year of it would be, and
+// the installments of any loans, which may be nil.
+func RunPayroll(employees []Employee, month time.Time, config *PayrollConfig, loans *Loans) (*PayrollRun, error) {
+	local := month.In(userLocation)
+	start := dateOf(local.Year(), local.Month(), 1)
+	next := dateOf(local.Year(), local.Month()+1, 1)
+	days := math.Round(next.Sub(start).Hours() / 24)
+
+	r := &PayrollRun{Month: start, Currency: config.currency, Policy: config.Policy()}
+	sorted := make([]*Employee, 0, len(employees))
+	for i := range employees {
+		if employees[i].JoinDate.Before(next) {
+			sorted = append(sorted, &employees[i])
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	for _, e := range sorted {
+		share := 1.0
+		name := "Base pay"
+		if e.JoinDate.After(start) {
+			worked := math.Round(next.Sub(e.JoinDate).Hours() / 24)
+			share = worked / days
+			name = fmt.Sprintf("Base pay (%.0f of %.0f days)", worked, days)
+		}
+		gross := e.Salary / 12 * share
+		items := []PayItem{{Name: name, Kind: Earning, exact: r.Currency.minorUnits(gross)}}
+
+		taxable := gross
+		for _, d := range config.Deductions {
+			amount := d.Amount * share
+			if d.Percent != 0 {
+				amount = gross * d.Percent / 100
+			}
+			if d.PreTax {
+				taxable -= amount
+			}
+			items = append(items, PayItem{Name: d.Name, Kind: Deduction, exact: -r.Currency.minorUnits(amount)})
+		}
+		repayments, err := loans.Deductions(e.ID, start, r.Currency)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, repayments...)
+		if len(config.Tax) > 0 {
+			tax := config.annualTax(math.Max(taxable, 0)/share*12) / 12 * share
+			items = append(items, PayItem{Name: "Income tax", Kind: Tax, exact: -r.Currency.minorUnits(tax)})
+		}
+		r.Payslips = append(r.Payslips, Payslip{Employee: e, Items: items})
+	}
+
+	r.round()
+	r.total()
+	if err := r.Reconcile(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// round rounds the line items by the run's policy
+func (r *PayrollRun) round() {
+	var items []*PayItem
+	for i := range r.Payslips {
+		for j := range r.Payslips[i].Items {
+			items = append(items, &r.Payslips[i].Items[j])
+		}
+	}
+	if r.Policy.Scope != RoundPerTotal {
+		for _, item := range items {
+			item.Amount = r.Policy.Mode.round(item.exact)
+		}
+		return
+	}
+
+	left := r.Policy.Mode.round(r.exactNet())
+	for _, item := range items {
+		item.Amount = int64(math.Floor(item.exact))
+		left -= item.Amount
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].exact-math.Floor(items[i].exact) > items[j].exact-math.Floor(items[j].exact)
+	})
+	for i := 0; i < len(items) && int64(i) < left; i++ {
+		items[i].Amount++
+	}
+}
+
+// exactNet returns the net pay of the run in minor units before rounding
+func (r *PayrollRun) exactNet() float64 {
+	net := 0.0
+	for _, p := range r.Payslips {
+		for _, item := range p.Items {
+			net += item.exact
+		}
+	}
+	return math.Round(net*1e4) / 1e4
+}
+
+// total adds up the payslips and the run from the rounded line items
+func (r *PayrollRun) total() {
+	r.Gross, r.Deductions, r.Tax, r.Net = 0, 0, 0, 0
+	for i := range r.Payslips {
+		p := &r.Payslips[i]
+		p.Gross, p.Deductions, p.Tax = 0, 0, 0
+		for _, item := range p.Items {
+			switch item.Kind {
+			case Earning:
+				p.Gross += item.Amount
+			case Deduction:
+				p.Deductions -= item.Amount
+			case Tax:
+				p.Tax -= item.Amount
+			}
+		}
+		p.Net = p.Gross - p.Deductions - p.Tax
+		r.Gross += p.Gross
+		r.Deductions += p.Deductions
+		r.Tax += p.Tax
+		r.Net += p.Net
+	}
+	r.RoundingDifference = r.Net - r.Policy.Mode.round(r.exactNet())
+}
+
+// Reconcile checks that every payslip's totals are the sums of its line
+// items, that the run's totals are the sums of the payslips' to the minor
+// unit, and that a run rounded on the total matches its exact total rounded
+func (r *PayrollRun) Reconcile() error {
+	var gross, deductions, tax, net int64
+	for _, p := range r.Payslips {
+		var items int64
+		for _, item := range p.Items {
+			items += item.Amount
+		}
+		if p.Net != items || p.Net != p.Gross-p.Deductions-p.Tax {
+			return fmt.Errorf("%w: employee %d's net pay %s is not the sum of their line items %s",
+				ErrPayrollUnreconciled, p.Employee.ID, r.Currency.Format(p.Net), r.Currency.Format(items))
+		}
+		gross += p.Gross
+		deductions += p.Deductions
+		tax += p.Tax
+		net += p.Net
+	}
+	if r.Gross != gross || r.Deductions != deductions || r.Tax != tax || r.Net != net {
+		return fmt.Errorf("%w: the total net pay %s is not the sum of the payslips %s",
+			ErrPayrollUnreconciled, r.Currency.Format(r.Net), r.Currency.Format(net))
+	}
+	if r.Policy.Scope == RoundPerTotal && r.RoundingDifference != 0 {
+		return fmt.Errorf("%w: the total net pay %s is %s off the exact total rounded",
+			ErrPayrollUnreconciled, r.Currency.Format(r.Net), r.Currency.Format(r.RoundingDifference))
+	}
+	return nil
+}
+
+// writePayrollRun writes a payroll run as a table of payslips, with the totals
+func writePayrollRun(w io.Writer, r *PayrollRun) {
+	c := r.Currency
+	fmt.Fprintf(w, "\n=== Payroll for %s (%s, rounded %s) ===\n", r.Month.In(userLocation).Format(monthLayout), c.Code, r.Policy)
+	fmt.Fprintf(w, "%-5s %-22s %-12s %14s %14s %14s %14s\n", "ID", "Name", "Department", "Gross", "Deductions", "Tax", "Net")
+	fmt.Fprintln(w, strings.Repeat("-", 101))
+	for _, p := range r.Payslips {
+		fmt.Fprintf(w, "%-5d %-22s %-12s %14s %14s %14s %14s\n", p.Employee.ID, p.Employee.Name, p.Employee.Department,
+			c.Format(p.Gross), c.Format(p.Deductions), c.Format(p.Tax), c.Format(p.Net))
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 101))
+	fmt.Fprintf(w, "%-41s %14s %14s %14s %14s\n", fmt.Sprintf("Total (%d employees)", len(r.Payslips)),
+		c.Format(r.Gross), c.Format(r.Deductions), c.Format(r.Tax), c.Format(r.Net))
+	if r.RoundingDifference != 0 {
+		fmt.Fprintf(w, "Rounding per line item paid %s more than rounding the total would.\n", c.Format(r.RoundingDifference))
+	}
+	fmt.Fprintln(w, "Reconciled: the totals are the sums of the line items.")
+}
+
+// writePayrollRunCSV writes a payroll run as CSV with a header row and a
+// row for each line item, amounts in the currency's major units
+func writePayrollRunCSV(w io.Writer, r *PayrollRun) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"month", "employee_id", "name", "department", "item", "kind", "amount", "currency"})
+	month := r.Month.In(userLocation).Format(monthLayout)
+	for _, p := range r.Payslips {
+		for _, item := range p.Items {
+			out.Write([]string{
+				month,
+				strconv.Itoa(p.Employee.ID),
+				p.Employee.Name,
+				p.Employee.Department.String(),
+				item.Name,
+				item.Kind.String(),
+				r.Currency.Format(item.Amount),
+				r.Currency.Code,
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// parsePayrollMonth parses the month of a payroll run, written as YYYY-MM;
+// blank means the month of now
+func parsePayrollMonth(input string, now time.Time) (time.Time, error) {
+	if input == "" {
+		local := now.In(userLocation)
+		return dateOf(local.Year(), local.Month(), 1), nil
+	}
+	month, err := time.ParseInLocation(monthLayout, input, userLocation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: month %q must be YYYY-MM", ErrInvalidInput, input)
+	}
+	return month.UTC(), nil
+}
+
+// payrollInteractive runs payroll for a month, shows it, and can save its
+// line items as CSV. With legal entities, payroll is run for one at a time.
+func payrollInteractive(manager EmployeeManager, loans *Loans, now time.Time, reader *bufio.Reader) error {
+	entity, err := readEntity(reader, true)
+	if err != nil {
+		return err
+	}
+	month, err := readValue(reader, "Month (YYYY-MM; blank for this month): ", func(input string) (time.Time, error) {
+		return parsePayrollMonth(input, now)
+	})
+	if err != nil {
+		return err
+	}
+	employees, _, err := payrollEmployees(manager, month)
+	if err != nil {
+		return err
+	}
+	run, err := RunPayroll(entity.Employees(employees), month, entity.Config(), loans)
+	if err != nil {
+		return err
+	}
+	writePayrollRun(os.Stdout, run)
+
+	path, err := readString(reader, "\nSave line items as CSV (file path; blank to skip): ")
+	if err != nil || path == "" {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writePayrollRunCSV(f, run)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Payroll saved to %s\n", path)
+	return nil
+}
+
+// runPayroll implements the payroll command, which runs payroll for a month
+// over a storage backend's employees as a table or CSV of line items
+func runPayroll(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("payroll", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to read employees from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	monthText := fs.String("month", "", "month to run payroll for, as YYYY-MM (default this month)")
+	configPath := fs.String("payroll", "", "JSON file of the payroll currency, rounding, deductions and tax")
+	loansFile := fs.String("loans-file", "", "file of loans whose installments are deducted")
+	entitiesPath := fs.String("entities", "", "JSON file of the group's legal entities, each with its own payroll")
+	entityName := fs.String("entity", "", "legal entity to run payroll for, required with -entities")
+	formatName := fs.String("format", "table", "output format (table, csv)")
+	out := fs.String("out", "", "file to write (default standard output)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	if *formatName != "table" && *formatName != "csv" {
+		return fmt.Errorf("%w: format must be table or csv", ErrInvalidInput)
+	}
+	month, err := parsePayrollMonth(*monthText, appClock.Now())
+	if err != nil {
+		return err
+	}
+	if *configPath != "" {
+		if payrollConfig, err = LoadPayrollConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	entity, err := commandEntity(*entitiesPath, *entityName, true)
+	if err != nil {
+		return err
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	loans, err := NewLoans(store, *loansFile)
+	if err != nil {
+		return err
+	}
+	employees, _, err := payrollEmployees(store, month)
+	if err != nil {
+		return err
+	}
+	run, err := RunPayroll(entity.Employees(employees), month, entity.Config(), loans)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout = f
+	}
+	if *formatName == "csv" {
+		return writePayrollRunCSV(stdout, run)
+	}
+	writePayrollRun(stdout, run)
+	return nil
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Payslip formats using iota
+const (
+	PayslipText = iota
+	PayslipHTML
+	PayslipPDF
+)
+
+// PayslipFormatToString converts a payslip format constant to its file extension
+func PayslipFormatToString(format int) string {
+	switch format {
+	case PayslipText:
+		return "txt"
+	case PayslipHTML:
+		return "html"
+	case PayslipPDF:
+		return "pdf"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToPayslipFormat converts string to payslip format constant
+func StringToPayslipFormat(format string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "text", "txt", "":
+		return PayslipText, nil
+	case "html":
+		return PayslipHTML, nil
+	case "pdf":
+		return PayslipPDF, nil
+	default:
+		return -1, fmt.Errorf("%w: payslip format %q must be text, html or pdf", ErrInvalidInput, format)
+	}
+}
+
+// SalaryChange is an employee's salary from a day on
+type SalaryChange struct {
+	Date   time.Time
+	Salary float64
+}
+
+// payrollEmployees returns the employees as they were at the end of a
+// month, from their change history when the store keeps one, with the
+// changes to their salary up to then. Employees who have since been
+// removed are not in it.
+func payrollEmployees(manager EmployeeManager, month time.Time) ([]Employee, map[int][]SalaryChange, error) {
+	employees, err := employeeValues(manager)
+	if err != nil {
+		return nil, nil, err
+	}
+	history, ok := capability[interface{ History(int) []Change }](manager)
+	if !ok {
+		return employees, nil, nil
+	}
+
+	local := month.In(userLocation)
+	next := dateOf(local.Year(), local.Month()+1, 1)
+	salaries := make(map[int][]SalaryChange, len(employees))
+	for i := range employees {
+		var changes []SalaryChange
+		for _, c := range history.History(employees[i].ID) {
+			if !c.Time.Before(next) || c.Type == EventEmployeeRemoved {
+				continue
+			}
+			employees[i] = c.Employee
+			if len(changes) == 0 || changes[len(changes)-1].Salary != c.Employee.Salary {
+				changes = append(changes, SalaryChange{Date: c.Time, Salary: c.Employee.Salary})
+			}
+		}
+		salaries[employees[i].ID] = changes
+	}
+	return employees, salaries, nil
+}
+
+// PayTotals are the totals of one or more payslips
+type PayTotals struct {
+	Gross, Deductions, Tax, Net int64
+}
+
+// add adds a payslip to the totals
+func (t *PayTotals) add(p Payslip) {
+	t.Gross += p.Gross
+	t.Deductions += p.Deductions
+	t.Tax += p.Tax
+	t.Net += p.Net
+}
+
+// PayslipBatch is the payslips of a month's payroll run, with what they show
+// besides the month's pay
+type PayslipBatch struct {
+	Run *PayrollRun
+	// YearToDate totals each employee's pay from January to the month, by ID
+	YearToDate map[int]PayTotals
+	// SalaryHistory is each employee's salary changes up to the end of the
+	// month, by ID, or nil if the store keeps no change history
+	SalaryHistory map[int][]SalaryChange
+}
+
+// NewPayslipBatch runs the payroll of a legal entity, or of everyone if it
+// is nil, for a month and for the months before it in the year, for
+// year-to-date totals
+func NewPayslipBatch(manager EmployeeManager, month time.Time, entity *LegalEntity, loans *Loans) (*PayslipBatch, error) {
+	local := month.In(userLocation)
+	b := &PayslipBatch{YearToDate: make(map[int]PayTotals)}
+	for m := time.January; m <= local.Month(); m++ {
+		start := dateOf(local.Year(), m, 1)
+		employees, salaries, err := payrollEmployees(manager, start)
+		if err != nil {
+			return nil, err
+		}
+		run, err := RunPayroll(entity.Employees(employees), start, entity.Config(), loans)
+		if err != nil {
+			return nil, fmt.Errorf("payroll for %s: %w", start.In(userLocation).Format(monthLayout), err)
+		}
+		for _, p := range run.Payslips {
+			totals := b.YearToDate[p.Employee.ID]
+			totals.add(p)
+			b.YearToDate[p.Employee.ID] = totals
+		}
+		b.Run, b.SalaryHistory = run, salaries
+	}
+	return b, nil
+}
+
+// payslipLines lays out a payslip as lines of monospaced text
+func (b *PayslipBatch) payslipLines(p Payslip) []string {
+	c := b.Run.Currency
+	e := p.Employee
+	var lines []string
+	line := func(format string, args ...interface{}) { lines = append(lines, fmt.Sprintf(format, args...)) }
+	amount := func(label string, minor int64) { line("  %-36s %14s", label, c.Format(minor)) }
+
+	line("PAYSLIP - %s", b.Run.Month.In(userLocation).Format("January 2006"))
+	line("")
+	line("Employee       %s (#%d)", e.Name, e.ID)
+	line("Position       %s, %s", e.Position, e.Department)
+	line("Annual salary  %.2f %s", e.Salary, c.Code)
+	sections := []struct {
+		kind    PayItemKind
+		heading string
+	}{{Earning, "Earnings"}, {Deduction, "Deductions"}, {Tax, "Tax"}}
+	for _, s := range sections {
+		first := true
+		for _, item := range p.Items {
+			if item.Kind != s.kind {
+				continue
+			}
+			if first {
+				line("")
+				line("%s", s.heading)
+				first = false
+			}
+			amount(item.Name, item.Amount)
+		}
+	}
+	line("  %s", strings.Repeat("-", 51))
+	amount("Gross pay", p.Gross)
+	amount("Deductions", p.Deductions)
+	amount("Tax", p.Tax)
+	amount("Net pay", p.Net)
+
+	ytd := b.YearToDate[e.ID]
+	line("")
+	line("Year to date (January to %s)", b.Run.Month.In(userLocation).Format("January"))
+	amount("Gross pay", ytd.Gross)
+	amount("Deductions", ytd.Deductions)
+	amount("Tax", ytd.Tax)
+	amount("Net pay", ytd.Net)
+
+	if history := b.SalaryHistory[e.ID]; len(history) > 0 {
+		line("")
+		line("Salary history")
+		for _, s := range history {
+			line("  %-36s %14.2f", formatDate(s.Date), s.Salary)
+		}
+	}
+	line("")
+	line("All amounts in %s.", c.Code)
+	return lines
+}
+
+// payslipTemplate lays out a payslip as a standalone HTML page
+var payslipTemplate = template.Must(template.New("payslip").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Payslip {{.Month}} - {{.Employee.Name}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; color: #212121; }
+table { width: 100%; border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { padding: 0.3em 0.5em; text-align: left; }
+td.amount { text-align: right; font-variant-numeric: tabular-nums; }
+tr.total td { border-top: 1px solid #9e9e9e; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Payslip &ndash; {{.Month}}</h1>
+<p>{{.Employee.Name}} (#{{.Employee.ID}})<br>{{.Employee.Position}}, {{.Employee.Department}}<br>Annual salary {{printf "%.2f" .Employee.Salary}} {{.Currency}}</p>
+<table>
+<tr><th>Item</th><th></th><th>Amount</th></tr>
+{{range .Items}}<tr><td>{{.Name}}</td><td>{{.Kind}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}<tr class="total"><td>Gross pay</td><td></td><td class="amount">{{.Gross}}</td></tr>
+<tr><td>Deductions</td><td></td><td class="amount">{{.Deductions}}</td></tr>
+<tr><td>Tax</td><td></td><td class="amount">{{.Tax}}</td></tr>
+<tr class="total"><td>Net pay</td><td></td><td class="amount">{{.Net}}</td></tr>
+</table>
+<h2>Year to date</h2>
+<table>
+<tr><td>Gross pay</td><td class="amount">{{.YearToDate.Gross}}</td></tr>
+<tr><td>Deductions</td><td class="amount">{{.YearToDate.Deductions}}</td></tr>
+<tr><td>Tax</td><td class="amount">{{.YearToDate.Tax}}</td></tr>
+<tr class="total"><td>Net pay</td><td class="amount">{{.YearToDate.Net}}</td></tr>
+</table>
+{{if .SalaryHistory}}<h2>Salary history</h2>
+<table>
+{{range .SalaryHistory}}<tr><td>{{.Date}}</td><td class="amount">{{.Salary}}</td></tr>
+{{end}}</table>
+{{end}}<p>All amounts in {{.Currency}}.</p>
+</body>
+</html>
+`))
+
+// payslipHTML is what payslipTemplate shows, with amounts already formatted
+type payslipHTML struct {
+	Month, Currency             string
+	Employee                    *Employee
+	Items                       []struct{ Name, Kind, Amount string }
+	Gross, Deductions, Tax, Net string
+	YearToDate                  struct{ Gross, Deductions, Tax, Net string }
+	SalaryHistory               []struct{ Date, Salary string }
+}
+
+// WritePayslip writes an employee's payslip from the batch in a format
+func (b *PayslipBatch) WritePayslip(w io.Writer, format int, p Payslip) error {
+	switch format {
+	case PayslipHTML:
+		c := b.Run.Currency
+		view := payslipHTML{
+			Month:      b.Run.Month.In(userLocation).Format("January 2006"),
+			Currency:   c.Code,
+			Employee:   p.Employee,
+			Gross:      c.Format(p.Gross),
+			Deductions: c.Format(p.Deductions),
+			Tax:        c.Format(p.Tax),
+			Net:        c.Format(p.Net),
+		}
+		for _, item := range p.Items {
+			view.Items = append(view.Items, struct{ Name, Kind, Amount string }{item.Name, item.Kind.String(), c.Format(item.Amount)})
+		}
+		ytd := b.YearToDate[p.Employee.ID]
+		view.YearToDate.Gross, view.YearToDate.Deductions = c.Format(ytd.Gross), c.Format(ytd.Deductions)
+		view.YearToDate.Tax, view.YearToDate.Net = c.Format(ytd.Tax), c.Format(ytd.Net)
+		for _, s := range b.SalaryHistory[p.Employee.ID] {
+			view.SalaryHistory = append(view.SalaryHistory, struct{ Date, Salary string }{formatDate(s.Date), fmt.Sprintf("%.2f", s.Salary)})
+		}
+		return payslipTemplate.Execute(w, view)
+	case PayslipPDF:
+		return writeTextPDF(w, b.payslipLines(p))
+	default:
+		_, err := io.WriteString(w, strings.Join(b.payslipLines(p), "\n")+"\n")
+		return err
+	}
+}
+
+// writeTextPDF writes lines of text as a one-page A4 PDF in Courier, which
+// every PDF reader has, so columns line up as they do in text
+func writeTextPDF(w io.Writer, lines []string) error {
+	const width, height, margin, size, leading = 595.0, 842.0, 56.0, 10.0, 13.0
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT /F1 %.0f Tf %.0f TL %.1f %.1f Td\n", size, leading, margin, height-margin)
+	for i, l := range lines {
+		font := "F1"
+		if i == 0 {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "/%s %.0f Tf (%s) Tj T*\n", font, size, pdfText(l))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> /Contents 4 0 R >>", width, height),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(pdf.Bytes())
+	return err
+}
+
+// runPayslips implements the payslips command, which writes the payslips of
+// every employee, or of one, for a month
+func runPayslips(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("payslips", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to read employees from (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	monthText := fs.String("month", "", "month of the payslips, as YYYY-MM (default this month)")
+	configPath := fs.String("payroll", "", "JSON file of the payroll currency, rounding, deductions and tax")
+	loansFile := fs.String("loans-file", "", "file of loans whose installments are deducted")
+	entitiesPath := fs.String("entities", "", "JSON file of the group's legal entities, each with its own payroll")
+	entityName := fs.String("entity", "", "legal entity to write payslips for, required with -entities")
+	id := fs.Int("id", 0, "employee to write a payslip for (default all employees)")
+	formatName := fs.String("format", "text", "payslip format (text, html, pdf)")
+	out := fs.String("out", "", "directory to write payslip-<id>-<YYYY-MM>.<format> files to (default the current directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	backend, connection, err := commandStorage(*systemPath, *storageName, *dsn)
+	if err != nil {
+		return err
+	}
+	format, err := StringToPayslipFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	month, err := parsePayrollMonth(*monthText, appClock.Now())
+	if err != nil {
+		return err
+	}
+	if *configPath != "" {
+		if payrollConfig, err = LoadPayrollConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	entity, err := commandEntity(*entitiesPath, *entityName, true)
+	if err != nil {
+		return err
+	}
+
+	store, err := OpenStorage(backend, connection, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	loans, err := NewLoans(store, *loansFile)
+	if err != nil {
+		return err
+	}
+	batch, err := NewPayslipBatch(store, month, entity, loans)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			return err
+		}
+	}
+
+	written := 0
+	for _, p := range batch.Run.Payslips {
+		if *id != 0 && p.Employee.ID != *id {
+			continue
+		}
+		name := fmt.Sprintf("payslip-%d-%s.%s", p.Employee.ID, month.In(userLocation).Format(monthLayout), PayslipFormatToString(format))
+		f, err := os.Create(filepath.Join(*out, name))
+		if err != nil {
+			return err
+		}
+		err = batch.WritePayslip(f, format, p)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("payslip for employee %d: %w", p.Employee.ID, err)
+		}
+		written++
+	}
+	if *id != 0 && written == 0 {
+		return fmt.Errorf("%w: employee %d was not paid in %s", ErrEmployeeNotFound, *id, month.In(userLocation).Format(monthLayout))
+	}
+	fmt.Fprintf(stdout, "Wrote %d payslip(s) for %s\n", written, month.In(userLocation).Format("January 2006"))
+	return nil
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Relationship constants using iota
+const (
+	RelationshipSpouse = iota
+	RelationshipPartner
+	RelationshipChild
+	RelationshipParent
+	RelationshipSibling
+	RelationshipFriend
+	RelationshipOther
+)
+
+// RelationshipToString converts a relationship constant to string
+func RelationshipToString(rel int) string {
+	switch rel {
+	case RelationshipSpouse:
+		return "Spouse"
+	case RelationshipPartner:
+		return "Partner"
+	case RelationshipChild:
+		return "Child"
+	case RelationshipParent:
+		return "Parent"
+	case RelationshipSibling:
+		return "Sibling"
+	case RelationshipFriend:
+		return "Friend"
+	case RelationshipOther:
+		return "Other"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToRelationship converts string to relationship constant
+func StringToRelationship(rel string) (int, error) {
+	for r := RelationshipSpouse; r <= RelationshipOther; r++ {
+		if strings.EqualFold(rel, RelationshipToString(r)) {
+			return r, nil
+		}
+	}
+	return -1, errors.New("invalid relationship")
+}
+
+// EmergencyContact is a person to contact when something happens to an employee
+type EmergencyContact struct {
+	Name         string
+	Relationship int
+	Phone        string
+	Email        string
+}
+
+// Dependent is a person financially dependent on an employee
+type Dependent struct {
+	Name         string
+	Relationship int
+	DateOfBirth  time.Time
+}
+
+// PersonalRecords stores emergency contacts and dependents per employee.
+// Every operation is restricted to users allowed to handle personal data.
+type PersonalRecords struct {
+	manager    EmployeeManager
+	contacts   map[int][]EmergencyContact
+	dependents map[int][]Dependent
+}
+
+// NewPersonalRecords creates personal records for employees of the given manager
+func NewPersonalRecords(manager EmployeeManager) *PersonalRecords {
+	return &PersonalRecords{
+		manager:    manager,
+		contacts:   make(map[int][]EmergencyContact),
+		dependents: make(map[int][]Dependent),
+	}
+}
+
+// AddEmergencyContact adds an emergency contact to an employee
+func (p *PersonalRecords) AddEmergencyContact(user User, employeeID int, c EmergencyContact) error {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return err
+	}
+	if _, err := p.manager.GetEmployee(employeeID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidInput)
+	}
+	if c.Phone == "" && c.Email == "" {
+		return fmt.Errorf("%w: a phone number or email is required", ErrInvalidInput)
+	}
+	if RelationshipToString(c.Relationship) == "Unknown" {
+		return fmt.Errorf("%w: please select a valid relationship", ErrInvalidInput)
+	}
+	p.contacts[employeeID] = append(p.contacts[employeeID], c)
+	return nil
+}
+
+// AddDependent adds a dependent to an employee
+func (p *PersonalRecords) AddDependent(user User, employeeID int, d Dependent) error {
+	if err := user.Require(PermEditPersonalData); err != nil {
+		return err
+	}
+	if _, err := p.manager.GetEmployee(employeeID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(d.Name) == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidInput)
+	}
+	if RelationshipToString(d.Relationship) == "Unknown" {
+		return fmt.Errorf("%w: please select a valid relationship", ErrInvalidInput)
+	}
+	p.dependents[employeeID] = append(p.dependents[employeeID], d)
+	return nil
+}
+
+// EmergencyContacts returns the emergency contacts of an employee
+func (p *PersonalRecords) EmergencyContacts(user User, employeeID int) ([]EmergencyContact, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	contacts := make([]EmergencyContact, len(p.contacts[employeeID]))
+	copy(contacts, p.contacts[employeeID])
+	return contacts, nil
+}
+
+// Dependents returns the dependents of an employee
+func (p *PersonalRecords) Dependents(user User, employeeID int) ([]Dependent, error) {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return nil, err
+	}
+	dependents := make([]Dependent, len(p.dependents[employeeID]))
+	copy(dependents, p.dependents[employeeID])
+	return dependents, nil
+}
+
+// Forget returns an event subscriber that drops the records of removed employees
+func (p *PersonalRecords) Forget() func(Event) {
+	return func(ev Event) {
+		if ev.Type == EventEmployeeRemoved {
+			delete(p.contacts, ev.EmployeeID)
+			delete(p.dependents, ev.EmployeeID)
+		}
+	}
+}
+
+// personalDataExport is the JSON layout of an employee's personal-data export
+type personalDataExport struct {
+	ID                int                    `json:"id"`
+	Name              string                 `json:"name"`
+	Position          string                 `json:"position"`
+	Salary            float64                `json:"salary"`
+	Department        string                 `json:"department"`
+	JoinDate          string                 `json:"join_date"`
+	BirthDate         string                 `json:"birth_date,omitempty"`
+	Location          string                 `json:"location,omitempty"`
+	WorkMode          string                 `json:"work_mode"`
+	TimeZone          string                 `json:"time_zone,omitempty"`
+	EmergencyContacts []emergencyContactJSON `json:"emergency_contacts"`
+	Dependents        []dependentJSON        `json:"dependents"`
+}
+
+type emergencyContactJSON struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship"`
+	Phone        string `json:"phone,omitempty"`
+	Email        string `json:"email,omitempty"`
+}
+
+type dependentJSON struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship"`
+	DateOfBirth  string `json:"date_of_birth,omitempty"`
+}
+
+// ExportPersonalData writes all personal data held about an employee as JSON
+func (p *PersonalRecords) ExportPersonalData(user User, employeeID int, w io.Writer) error {
+	if err := user.RequireFor(PermViewPersonalData, employeeID); err != nil {
+		return err
+	}
+
+	employee, err := p.manager.GetEmployee(employeeID)
+	if err != nil {
+		return err
+	}
+
+	export := personalDataExport{
+		ID:                employee.ID,
+		Name:              employee.Name,
+		Position:          employee.Position,
+		Salary:            employee.Salary,
+		Department:        employee.Department.String(),
+		JoinDate:          formatDate(employee.JoinDate),
+		Location:          employee.Location,
+		WorkMode:          strings.ToLower(WorkModeToString(employee.WorkMode)),
+		TimeZone:          employee.TimeZone,
+		EmergencyContacts: []emergencyContactJSON{},
+		Dependents:        []dependentJSON{},
+	}
+	if !employee.BirthDate.IsZero() {
+		export.BirthDate = formatDate(employee.BirthDate)
+	}
+	for _, c := range p.contacts[employeeID] {
+		export.EmergencyContacts = append(export.EmergencyContacts, emergencyContactJSON{
+			Name:         c.Name,
+			Relationship: RelationshipToString(c.Relationship),
+			Phone:        c.Phone,
+			Email:        c.Email,
+		})
+	}
+	for _, d := range p.dependents[employeeID] {
+		dep := dependentJSON{Name: d.Name, Relationship: RelationshipToString(d.Relationship)}
+		if !d.DateOfBirth.IsZero() {
+			dep.DateOfBirth = formatDate(d.DateOfBirth)
+		}
+		export.Dependents = append(export.Dependents, dep)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// readRelationship reads a relationship from the user
+func readRelationship(reader *bufio.Reader) (int, error) {
+	fmt.Println("\nRelationships:")
+	for r := RelationshipSpouse; r <= RelationshipOther; r++ {
+		fmt.Printf("%d. %s\n", r+1, RelationshipToString(r))
+	}
+
+	choice, err := readInt(reader, fmt.Sprintf("Select relationship (1-%d): ", RelationshipOther+1))
+	if err != nil {
+		return -1, err
+	}
+	if RelationshipToString(choice-1) == "Unknown" {
+		return -1, fmt.Errorf("%w: please select a valid relationship", ErrInvalidInput)
+	}
+	return choice - 1, nil
+}
+
+// personalRecordsInteractive manages contacts and dependents through user interaction
+func personalRecordsInteractive(records *PersonalRecords, user User, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Emergency Contacts & Dependents ==="))
+
+	// Check access before asking for any input
+	if err := user.Require(PermViewPersonalData); err != nil {
+		return err
+	}
+
+	id, err := readInt(reader, "Enter employee ID: ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n1. Add emergency contact")
+	fmt.Println("2. Add dependent")
+	fmt.Println("3. View contacts and dependents")
+	fmt.Println("4. Export personal data")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		name, err := readString(reader, "Contact name: ")
+		if err != nil {
+			return err
+		}
+		relationship, err := readRelationship(reader)
+		if err != nil {
+			return err
+		}
+		phone, err := readString(reader, "Phone: ")
+		if err != nil {
+			return err
+		}
+		email, err := readString(reader, "Email: ")
+		if err != nil {
+			return err
+		}
+
+		contact := EmergencyContact{Name: name, Relationship: relationship, Phone: phone, Email: email}
+		if err := records.AddEmergencyContact(user, id, contact); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText("Emergency contact added successfully!"))
+
+	case 2:
+		name, err := readString(reader, "Dependent name: ")
+		if err != nil {
+			return err
+		}
+		relationship, err := readRelationship(reader)
+		if err != nil {
+			return err
+		}
+		dateOfBirth, err := readDate(reader, "Date of Birth")
+		if err != nil {
+			return err
+		}
+
+		dependent := Dependent{Name: name, Relationship: relationship, DateOfBirth: dateOfBirth}
+		if err := records.AddDependent(user, id, dependent); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText("Dependent added successfully!"))
+
+	case 3:
+		contacts, err := records.EmergencyContacts(user, id)
+		if err != nil {
+			return err
+		}
+		dependents, err := records.Dependents(user, id)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nEmergency contacts (%d):\n", len(contacts))
+		for _, c := range contacts {
+			fmt.Printf("- %s (%s) %s %s\n", c.Name, RelationshipToString(c.Relationship), c.Phone, c.Email)
+		}
+		fmt.Printf("\nDependents (%d):\n", len(dependents))
+		for _, d := range dependents {
+			fmt.Printf("- %s (%s) born %s\n", d.Name, RelationshipToString(d.Relationship), formatDate(d.DateOfBirth))
+		}
+
+	case 4:
+		path, err := readString(reader, "Export file (leave blank to print): ")
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return records.ExportPersonalData(user, id, os.Stdout)
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := records.ExportPersonalData(user, id, file); err != nil {
+			return err
+		}
+		fmt.Printf("\nPersonal data exported to %s\n", path)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	return nil
+}
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The postgres backend uses database/sql. It is only registered when the
+// program is built with a driver that registers itself as "postgres" (for
+// example github.com/lib/pq, or github.com/jackc/pgx/v5/stdlib registered
+// under that name), so -storage does not offer it otherwise.
+const postgresDriver = "postgres"
+
+// postgresSchema creates the tables used by PostgresEmployeeManager. It is
+// the first schema migration; later changes to the tables are new migrations
+// in postgresMigrations rather than edits to it.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS employees (
+	id            SERIAL PRIMARY KEY,
+	name          TEXT NOT NULL,
+	position      TEXT NOT NULL,
+	salary        DOUBLE PRECISION NOT NULL,
+	department    INTEGER NOT NULL,
+	join_date     TIMESTAMPTZ NOT NULL,
+	birth_date    TIMESTAMPTZ,
+	probation_end TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS employees_department ON employees (department);
+CREATE TABLE IF NOT EXISTS transfers (
+	employee_id     INTEGER NOT NULL REFERENCES employees (id) ON DELETE CASCADE,
+	from_department INTEGER NOT NULL,
+	to_department   INTEGER NOT NULL,
+	effective_date  TIMESTAMPTZ NOT NULL,
+	recorded_at     TIMESTAMPTZ NOT NULL
+);`
+
+// postgresMigrations upgrade the database one schema version at a time.
+// Databases created before versioning have the version 1 tables and no
+// recorded version; migration 1 only creates what is missing, so it brings
+// them up to date as well.
+var postgresMigrations = []Migration[*sql.Tx]{
+	{Version: 1, Description: "employees and transfers tables", Up: execMigration(postgresSchema)},
+	{Version: 2, Description: "employee location, work mode and time zone", Up: execMigration(`
+ALTER TABLE employees
+	ADD COLUMN IF NOT EXISTS location  TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS work_mode INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS employees_location ON employees (location);`)},
+	{Version: 3, Description: "employee version, for entity tags", Up: execMigration(`
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`)},
+}
+
+// execMigration returns a migration step that runs SQL statements
+func execMigration(query string) func(*sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migratePostgres applies the schema migrations the database has not had.
+// Each runs in a transaction holding a lock on schema_version, so
+// concurrent processes starting against the same database migrate it once.
+func migratePostgres(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+		return err
+	}
+
+	return applyMigrations("postgres", current, postgresMigrations, func(m Migration[*sql.Tx]) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec(`LOCK TABLE schema_version IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		var applied int
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&applied); err != nil {
+			return err
+		}
+		if applied >= m.Version {
+			return nil // another process migrated first
+		}
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_version`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES ($1)`, m.Version); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// checkPostgresSchema checks, without changing anything, that the database
+// is at the schema version this build uses
+func checkPostgresSchema(db *sql.DB) error {
+	var versioned bool
+	if err := db.QueryRow(`SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&versioned); err != nil {
+		return err
+	}
+	current := 0
+	if versioned {
+		if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+			return err
+		}
+	}
+	return checkSchemaVersion("postgres", current, latestVersion(postgresMigrations))
+}
+
+// employeeColumns lists the employee columns in the order scanEmployee reads them
+const employeeColumns = "id, name, position, salary, department, join_date, birth_date, probation_end, location, work_mode, time_zone, version"
+
+// PostgresConfig holds the connection pool and retry settings
+type PostgresConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	MaxRetries      int           // retries after a transient error
+	RetryBackoff    time.Duration // delay before the first retry, doubled for each one after
+	ReadOnly        bool          // follow the database without migrating it, e.g. on a hot standby
+}
+
+// DefaultPostgresConfig returns the settings used when the DSN does not override them
+func DefaultPostgresConfig() PostgresConfig {
+	return PostgresConfig{
+		MaxOpenConns:    10,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		MaxRetries:      3,
+		RetryBackoff:    100 * time.Millisecond,
+	}
+}
+
+// parsePostgresDSN splits the pool and retry settings from a postgres:// URL,
+// returning the DSN to pass to the driver. Recognised query parameters are
+// pool_max_conns, pool_max_idle_conns, pool_max_conn_lifetime,
+// pool_max_conn_idle_time, max_retries and retry_backoff.
+func parsePostgresDSN(dsn string) (string, PostgresConfig, error) {
+	config := DefaultPostgresConfig()
+
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		// Key/value DSNs are passed through unchanged
+		return dsn, config, nil
+	}
+
+	query := u.Query()
+	ints := map[string]*int{
+		"pool_max_conns":      &config.MaxOpenConns,
+		"pool_max_idle_conns": &config.MaxIdleConns,
+		"max_retries":         &config.MaxRetries,
+	}
+	durations := map[string]*time.Duration{
+		"pool_max_conn_lifetime":  &config.ConnMaxLifetime,
+		"pool_max_conn_idle_time": &config.ConnMaxIdleTime,
+		"retry_backoff":           &config.RetryBackoff,
+	}
+	for key, target := range ints {
+		if value := query.Get(key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return "", config, fmt.Errorf("%w: %s must be a non-negative number", ErrInvalidInput, key)
+			}
+			*target = n
+			query.Del(key)
+		}
+	}
+	for key, target := range durations {
+		if value := query.Get(key); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return "", config, fmt.Errorf("%w: %s must be a duration such as 30s", ErrInvalidInput, key)
+			}
+			*target = d
+			query.Del(key)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), config, nil
+}
+
+// PostgresEmployeeManager implements EmployeeManager on a PostgreSQL database,
+// so several server instances can share the same data
+type PostgresEmployeeManager struct {
+	db     *sql.DB
+	config PostgresConfig
+	events EventBus
+	clock  Clock
+
+	insertStmt         *sql.Stmt
+	insertWithIDStmt   *sql.Stmt
+	updateStmt         *sql.Stmt
+	deleteStmt         *sql.Stmt
+	getStmt            *sql.Stmt
+	listStmt           *sql.Stmt
+	listByDeptStmt     *sql.Stmt
+	insertTransferStmt *sql.Stmt
+	transfersStmt      *sql.Stmt
+}
+
+// NewPostgresEmployeeManager connects to the database, creates or upgrades
+// the schema if needed and prepares the statements used by the manager
+func NewPostgresEmployeeManager(dsn string, clock Clock) (*PostgresEmployeeManager, error) {
+	driverDSN, config, err := parsePostgresDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return newPostgresEmployeeManager(driverDSN, config, clock)
+}
+
+func newPostgresEmployeeManager(driverDSN string, config PostgresConfig, clock Clock) (*PostgresEmployeeManager, error) {
+	db, err := sql.Open(postgresDriver, driverDSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres: %w", err)
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	m := &PostgresEmployeeManager{db: db, config: config, clock: clock}
+	schema, step := migratePostgres, "migrating"
+	if config.ReadOnly {
+		schema, step = checkPostgresSchema, "checking"
+	}
+	if err := m.retry(func() error { return schema(db) }); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s postgres schema: %w", step, err)
+	}
+	if err := m.prepare(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("preparing postgres statements: %w", err)
+	}
+	return m, nil
+}
+
+// prepare prepares every statement used by the manager
+func (m *PostgresEmployeeManager) prepare() error {
+	statements := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&m.insertStmt, `INSERT INTO employees (name, position, salary, department, join_date, birth_date, probation_end,
+			location, work_mode, time_zone)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`},
+		{&m.insertWithIDStmt, `INSERT INTO employees (id, name, position, salary, department, join_date, birth_date, probation_end,
+			location, work_mode, time_zone)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`},
+		{&m.updateStmt, `UPDATE employees SET name = $2, position = $3, salary = $4, department = $5,
+			join_date = $6, birth_date = $7, probation_end = $8, location = $9, work_mode = $10, time_zone = $11,
+			version = version + 1
+			WHERE id = $1 RETURNING version`},
+		{&m.deleteStmt, `DELETE FROM employees WHERE id = $1 RETURNING ` + employeeColumns},
+		{&m.getStmt, `SELECT ` + employeeColumns + ` FROM employees WHERE id = $1`},
+		{&m.listStmt, `SELECT ` + employeeColumns + ` FROM employees ORDER BY id`},
+		{&m.listByDeptStmt, `SELECT ` + employeeColumns + ` FROM employees WHERE department = $1 ORDER BY id`},
+		{&m.insertTransferStmt, `INSERT INTO transfers (employee_id, from_department, to_department, effective_date, recorded_at)
+			VALUES ($1, $2, $3, $4, $5)`},
+		{&m.transfersStmt, `SELECT employee_id, from_department, to_department, effective_date, recorded_at
+			FROM transfers WHERE employee_id = $1 ORDER BY recorded_at`},
+	}
+
+	for _, s := range statements {
+		stmt, err := m.db.Prepare(s.query)
+		if err != nil {
+			return err
+		}
+		*s.stmt = stmt
+	}
+	return nil
+}
+
+// sqlState returns the SQLSTATE code of a driver error, if it has one
+func sqlState(err error) string {
+	var coded interface{ SQLState() string }
+	if errors.As(err, &coded) {
+		return coded.SQLState()
+	}
+	return ""
+}
+
+// isTransient reports whether an operation that failed with err may succeed if retried
+func isTransient(err error) bool {
+	switch state := sqlState(err); {
+	case strings.HasPrefix(state, "08"): // connection exception
+		return true
+	case state == "40001", state == "40P01": // serialization failure, deadlock
+		return true
+	case state == "53300", state == "57P01": // too many connections, admin shutdown
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retry runs fn, retrying with exponential backoff while it fails with a transient error
+func (m *PostgresEmployeeManager) retry(fn func() error) error {
+	backoff := m.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= m.config.MaxRetries || !isTransient(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// nullTime converts an optional date to a nullable column value
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// scanEmployee reads an employee from a row selected with employeeColumns
+func scanEmployee(row interface{ Scan(dest ...any) error }) (*Employee, error) {
+	var e Employee
+	var birthDate, probationEnd sql.NullTime
+	err := row.Scan(&e.ID, &e.Name, &e.Position, &e.Salary, &e.Department, &e.JoinDate, &birthDate, &probationEnd,
+		&e.Location, &e.WorkMode, &e.TimeZone, &e.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEmployeeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if birthDate.Valid {
+		e.BirthDate = birthDate.Time
+	}
+	if probationEnd.Valid {
+		e.ProbationEnd = probationEnd.Time
+	}
+	e.normalizeTimes()
+	return &e, nil
+}
+
+// queryEmployees runs a prepared query that selects employeeColumns
+func (m *PostgresEmployeeManager) queryEmployees(stmt *sql.Stmt, args ...any) ([]*Employee, error) {
+	var employees []*Employee
+	err := m.retry(func() error {
+		rows, err := stmt.Query(args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		employees = make([]*Employee, 0)
+		for rows.Next() {
+			e, err := scanEmployee(rows)
+			if err != nil {
+				return err
+			}
+			employees = append(employees, e)
+		}
+		return rows.Err()
+	})
+	return employees, err
+}
+
+// publish notifies subscribers of a change
+func (m *PostgresEmployeeManager) publish(c Change) {
+	c.Time = m.clock.Now()
+	m.events.Publish(c.notification())
+}
+
+// Clock returns the clock the manager uses for validation and events
+func (m *PostgresEmployeeManager) Clock() Clock {
+	return m.clock
+}
+
+// Subscribe registers a function that is notified of manager events
+func (m *PostgresEmployeeManager) Subscribe(fn func(Event)) {
+	m.events.Subscribe(fn)
+}
+
+// Ping checks that the database can be reached
+func (m *PostgresEmployeeManager) Ping(ctx context.Context) error {
+	return m.db.PingContext(ctx)
+}
+
+// ServerTime returns the database server's clock, to compare with this host's
+func (m *PostgresEmployeeManager) ServerTime(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := m.db.QueryRowContext(ctx, `SELECT now()`).Scan(&now)
+	return now, err
+}
+
+// EventBacklog returns the number of the manager's events still being delivered
+func (m *PostgresEmployeeManager) EventBacklog() int {
+	return m.events.Backlog()
+}
+
+// Close closes the prepared statements and the connection pool
+func (m *PostgresEmployeeManager) Close() error {
+	for _, stmt := range []*sql.Stmt{
+		m.insertStmt, m.insertWithIDStmt, m.updateStmt, m.deleteStmt, m.getStmt,
+		m.listStmt, m.listByDeptStmt, m.insertTransferStmt, m.transfersStmt,
+	} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return m.db.Close()
+}
+
+// AddEmployee adds a new employee, letting the database assign the ID if it is 0
+func (m *PostgresEmployeeManager) AddEmployee(e *Employee) error {
+	if e == nil {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+	employeeCopy.Version = 1 // the column's default
+	args := []any{employeeCopy.Name, employeeCopy.Position, employeeCopy.Salary, employeeCopy.Department,
+		employeeCopy.JoinDate, nullTime(employeeCopy.BirthDate), nullTime(employeeCopy.ProbationEnd),
+		employeeCopy.Location, employeeCopy.WorkMode, employeeCopy.TimeZone}
+
+	// The insert and the sequence update run in one transaction. Until it
+	// commits nothing is written, so only those steps are retried; a failed
+	// commit may have been applied and is returned as it is.
+	var tx *sql.Tx
+	err := m.retry(func() error {
+		var err error
+		if tx, err = m.db.Begin(); err != nil {
+			return err
+		}
+		if err = m.insertEmployee(tx, &employeeCopy, args); err != nil {
+			tx.Rollback()
+		}
+		return err
+	})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if sqlState(err) == "23505" { // unique violation
+		return ErrDuplicateID
+	}
+	if err != nil {
+		return err
+	}
+
+	e.ID, e.Version = employeeCopy.ID, employeeCopy.Version
+	m.publish(Change{Type: EventEmployeeAdded, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// insertEmployee inserts an employee in tx, setting its ID if it is 0
+func (m *PostgresEmployeeManager) insertEmployee(tx *sql.Tx, e *Employee, args []any) error {
+	if e.ID == 0 {
+		return tx.Stmt(m.insertStmt).QueryRow(args...).Scan(&e.ID)
+	}
+	if _, err := tx.Stmt(m.insertWithIDStmt).Exec(append([]any{e.ID}, args...)...); err != nil {
+		return err
+	}
+	// Move the ID sequence past explicit IDs so later inserts do not collide
+	_, err := tx.Exec(`SELECT setval(pg_get_serial_sequence('employees', 'id'), (SELECT MAX(id) FROM employees))`)
+	return err
+}
+
+// RemoveEmployee removes an employee by ID
+func (m *PostgresEmployeeManager) RemoveEmployee(id int) error {
+	var removed *Employee
+	err := m.retry(func() error {
+		var err error
+		removed, err = scanEmployee(m.deleteStmt.QueryRow(id))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(Change{Type: EventEmployeeRemoved, EmployeeID: id, Employee: *removed})
+	return nil
+}
+
+// UpdateEmployee updates an existing employee
+func (m *PostgresEmployeeManager) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+
+	err := m.retry(func() error {
+		return m.updateStmt.QueryRow(employeeCopy.ID, employeeCopy.Name, employeeCopy.Position,
+			employeeCopy.Salary, employeeCopy.Department, employeeCopy.JoinDate,
+			nullTime(employeeCopy.BirthDate), nullTime(employeeCopy.ProbationEnd),
+			employeeCopy.Location, employeeCopy.WorkMode, employeeCopy.TimeZone).Scan(&employeeCopy.Version)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEmployeeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	e.Version = employeeCopy.Version
+
+	m.publish(Change{Type: EventEmployeeUpdated, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// GetEmployee retrieves an employee by ID
+func (m *PostgresEmployeeManager) GetEmployee(id int) (*Employee, error) {
+	var employee *Employee
+	err := m.retry(func() error {
+		var err error
+		employee, err = scanEmployee(m.getStmt.QueryRow(id))
+		return err
+	})
+	return employee, err
+}
+
+// ListEmployees returns a list of all employees, ordered by ID
+func (m *PostgresEmployeeManager) ListEmployees() ([]*Employee, error) {
+	return m.queryEmployees(m.listStmt)
+}
+
+// FilterEmployees returns employees that match the filter criteria.
+// The filter runs in Go, so every employee is loaded; if the database
+// cannot be read, the error is logged and no employees are returned.
+func (m *PostgresEmployeeManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	employees, err := m.ListEmployees()
+	if err != nil {
+		log.Printf("postgres: filtering employees: %v", err)
+		return make([]*Employee, 0)
+	}
+
+	result := make([]*Employee, 0)
+	for _, emp := range employees {
+		if filter(emp) {
+			result = append(result, emp)
+		}
+	}
+	return result
+}
+
+// ListByDepartment returns the employees in a department using the department
+// index. If the database cannot be read, the error is logged and no employees
+// are returned.
+func (m *PostgresEmployeeManager) ListByDepartment(dept Department) []*Employee {
+	employees, err := m.queryEmployees(m.listByDeptStmt, dept)
+	if err != nil {
+		log.Printf("postgres: listing %s: %v", dept, err)
+		return make([]*Employee, 0)
+	}
+	return employees
+}
+
+// RebuildIndexes rebuilds the indexes of the employees and transfers tables
+func (m *PostgresEmployeeManager) RebuildIndexes() error {
+	for _, table := range []string{"employees", "transfers"} {
+		if _, err := m.db.Exec("REINDEX TABLE " + table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferEmployee moves an employee to another department and records the
+// transfer in one transaction
+func (m *PostgresEmployeeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+
+	var transferred *Employee
+	var record TransferRecord
+	err := m.retry(func() error {
+		tx, err := m.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		employee, err := scanEmployee(tx.QueryRow(`SELECT `+employeeColumns+` FROM employees WHERE id = $1 FOR UPDATE`, id))
+		if err != nil {
+			return err
+		}
+		if employee.Department == newDept {
+			return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+		}
+
+		record = TransferRecord{
+			EmployeeID:     id,
+			FromDepartment: employee.Department,
+			ToDepartment:   newDept,
+			EffectiveDate:  effectiveDate.UTC(),
+			RecordedAt:     m.clock.Now(),
+		}
+		if _, err := tx.Exec(`UPDATE employees SET department = $2, version = version + 1 WHERE id = $1`, id, newDept); err != nil {
+			return err
+		}
+		if _, err := tx.Stmt(m.insertTransferStmt).Exec(record.EmployeeID, record.FromDepartment,
+			record.ToDepartment, record.EffectiveDate, record.RecordedAt); err != nil {
+			return err
+		}
+
+		employee.Department = newDept
+		employee.Version++
+		transferred = employee
+		return tx.Commit()
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(Change{
+		Type:       EventEmployeeTransferred,
+		EmployeeID: id,
+		Employee:   *transferred,
+		Transfer:   &record,
+	})
+	return nil
+}
+
+// TransferHistory returns the transfers recorded for an employee, oldest first.
+// If the database cannot be read, the error is logged and no transfers are
+// returned.
+func (m *PostgresEmployeeManager) TransferHistory(id int) []TransferRecord {
+	history := make([]TransferRecord, 0)
+	err := m.retry(func() error {
+		rows, err := m.transfersStmt.Query(id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		history = history[:0]
+		for rows.Next() {
+			var t TransferRecord
+			if err := rows.Scan(&t.EmployeeID, &t.FromDepartment, &t.ToDepartment, &t.EffectiveDate, &t.RecordedAt); err != nil {
+				return err
+			}
+			t.EffectiveDate = t.EffectiveDate.UTC()
+			t.RecordedAt = t.RecordedAt.UTC()
+			history = append(history, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		log.Printf("postgres: reading transfers of employee %d: %v", id, err)
+		return make([]TransferRecord, 0)
+	}
+	return history
+}
+
+func init() {
+	// Without a linked driver the backend could never connect, so it is only
+	// offered when the program is built with one
+	if !slices.Contains(sql.Drivers(), postgresDriver) {
+		return
+	}
+
+	RegisterStorage("postgres", func(dsn string, clock Clock) (Storage, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("%w: the postgres backend needs a -dsn connection string", ErrInvalidInput)
+		}
+		return NewPostgresEmployeeManager(dsn, clock)
+	})
+
+	// Read-only instances check the schema instead of migrating it, so they
+	// can follow a hot standby, which refuses every write
+	RegisterReadOnlyStorage("postgres", func(dsn string, clock Clock) (Storage, error) {
+		if dsn == "" {
+			return nil, fmt.Errorf("%w: the postgres backend needs a -dsn connection string", ErrInvalidInput)
+		}
+		driverDSN, config, err := parsePostgresDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		config.ReadOnly = true
+		return newPostgresEmployeeManager(driverDSN, config, clock)
+	})
+}
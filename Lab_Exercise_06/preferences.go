@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Preferences are the CLI settings remembered between runs of the menu, so
+// users do not give the same flags every time. Each is stored as the text of
+// its flag; LastSearch is a query in the form ParseEmployeeQuery reads. The
+// user's watches are kept here too.
+type Preferences struct {
+	Sort       string  `json:"sort,omitempty"`
+	Format     string  `json:"format,omitempty"`
+	Columns    string  `json:"columns,omitempty"`
+	Theme      string  `json:"theme,omitempty"`
+	TimeZone   string  `json:"time_zone,omitempty"`
+	Telemetry  string  `json:"telemetry,omitempty"`
+	LastSearch string  `json:"last_search,omitempty"`
+	Watches    []Watch `json:"watches,omitempty"`
+
+	path string
+}
+
+// preferenceFlags maps the flags that are remembered to their environment
+// variable, if any. An environment variable takes precedence over a saved
+// preference, and a flag given on the command line over both.
+var preferenceFlags = map[string]string{
+	"sort":      "",
+	"format":    "",
+	"columns":   "",
+	"theme":     "EMS_THEME",
+	"tz":        "EMS_TIMEZONE",
+	"telemetry": "EMS_TELEMETRY",
+}
+
+// preferencesOff is the -config value that turns preferences off
+const preferencesOff = "off"
+
+// appPreferences are the current user's preferences. main loads them; they
+// are not saved anywhere until then.
+var appPreferences = &Preferences{}
+
+// defaultPreferencesPath is the preferences file of a user in the system's
+// per-user configuration directory, such as ~/.config on Linux
+func defaultPreferencesPath(user string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(strings.TrimSpace(user))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "default"
+	}
+	return filepath.Join(dir, "employee-management", name+".json"), nil
+}
+
+// LoadPreferences reads the preferences file at path. A missing file gives
+// empty preferences, and an empty path gives preferences that are never saved.
+func LoadPreferences(path string) (*Preferences, error) {
+	p := &Preferences{path: path}
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("reading preferences %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the preferences to their file, creating its directory if needed
+func (p *Preferences) Save() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appShutdown.Guard(func() error {
+		if err := os.MkdirAll(filepath.Dir(p.path), 0o700); err != nil {
+			return err
+		}
+		tmp := p.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, p.path)
+	})
+}
+
+// field returns the preference stored for a flag in preferenceFlags
+func (p *Preferences) field(name string) *string {
+	switch name {
+	case "sort":
+		return &p.Sort
+	case "format":
+		return &p.Format
+	case "columns":
+		return &p.Columns
+	case "theme":
+		return &p.Theme
+	case "tz":
+		return &p.TimeZone
+	case "telemetry":
+		return &p.Telemetry
+	default:
+		return nil
+	}
+}
+
+// Apply sets the remembered flags that were not given on the command line or
+// through their environment variable to their saved values, and remembers
+// the ones that were given. It reports whether any preference changed.
+func (p *Preferences) Apply(fs *flag.FlagSet) (changed bool, err error) {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	for name, env := range preferenceFlags {
+		saved := p.field(name)
+		switch {
+		case given[name]:
+			value := fs.Lookup(name).Value.String()
+			changed = changed || *saved != value
+			*saved = value
+		case env != "" && os.Getenv(env) != "":
+		case *saved != "":
+			if err := fs.Set(name, *saved); err != nil {
+				return false, fmt.Errorf("saved preference %s: %w", name, err)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// RememberSearch saves the query of the last search, so it can be repeated
+// in a later session. A failure to save is only reported, since the search
+// itself succeeded.
+func (p *Preferences) RememberSearch(query url.Values) {
+	p.LastSearch = query.Encode()
+	if err := p.Save(); err != nil {
+		fmt.Println(warningText("Warning: the search could not be remembered: " + err.Error()))
+	}
+}
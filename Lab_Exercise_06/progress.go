@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often a progress bar is redrawn at most
+const progressInterval = 100 * time.Millisecond
+
+// progressWidth is the number of characters in a progress bar
+const progressWidth = 30
+
+// Progress draws a progress bar with an ETA for an operation over a known
+// number of items, or a spinner when the total is unknown. It only draws on
+// a terminal; otherwise Done prints a single summary line, so logs and pipes
+// are not filled with redraws.
+type Progress struct {
+	w     io.Writer
+	label string
+	total int
+	live  bool
+
+	mu    sync.Mutex
+	done  int
+	start time.Time
+	drawn time.Time
+	spin  int
+}
+
+// NewProgress starts a progress indicator for total items, or a spinner if
+// total is zero. It draws on f when f is a terminal.
+func NewProgress(f *os.File, label string, total int) *Progress {
+	return &Progress{w: f, label: label, total: total, live: isTerminal(f), start: time.Now()}
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Add records n more finished items
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done += n
+	if p.live && time.Since(p.drawn) >= progressInterval {
+		p.draw()
+		p.drawn = time.Now()
+	}
+}
+
+// eta estimates the time left from the average rate so far
+func (p *Progress) eta() time.Duration {
+	if p.done == 0 || p.total <= p.done {
+		return 0
+	}
+	perItem := time.Since(p.start) / time.Duration(p.done)
+	return (perItem * time.Duration(p.total-p.done)).Round(time.Second)
+}
+
+// draw redraws the progress line in place. The caller must hold the lock.
+func (p *Progress) draw() {
+	if p.total <= 0 {
+		p.spin++
+		fmt.Fprintf(p.w, "\r%s %c %d", p.label, `|/-\`[p.spin%4], p.done)
+		return
+	}
+
+	filled := progressWidth * min(p.done, p.total) / p.total
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", progressWidth-filled)
+	fmt.Fprintf(p.w, "\r%s [%s] %3d%% %d/%d ETA %s ", p.label, bar,
+		100*min(p.done, p.total)/p.total, p.done, p.total, p.eta())
+}
+
+// Done finishes the indicator with a summary of how many items were processed
+// and how long it took. err is the operation's error, if it stopped early.
+func (p *Progress) Done(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.live {
+		p.draw()
+		fmt.Fprintln(p.w)
+	}
+	elapsed := time.Since(p.start).Round(time.Millisecond)
+	switch {
+	case err != nil && p.total > 0:
+		fmt.Fprintf(p.w, "%s stopped after %d of %d in %s: %v\n", p.label, p.done, p.total, elapsed, err)
+	case err != nil:
+		fmt.Fprintf(p.w, "%s stopped after %d in %s: %v\n", p.label, p.done, elapsed, err)
+	case !p.live:
+		fmt.Fprintf(p.w, "%s: %d done in %s\n", p.label, p.done, elapsed)
+	}
+}
+
+// interruptContext returns a context that is cancelled when the user presses
+// Ctrl-C, for long operations that stop cleanly instead of killing the
+// program. Call stop when the operation ends to restore the default handling,
+// which is appShutdown's clean exit once it is listening.
+func interruptContext() (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if appShutdown.startOperation(cancel) {
+		return ctx, func() {
+			appShutdown.endOperation()
+			cancel()
+		}
+	}
+	cancel()
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// addEmployeesWithProgress adds employees one at a time, showing progress on
+// standard error, until they are all added or ctx is cancelled. It returns
+// how many were added; employees that fail validation are reported and skipped.
+func addEmployeesWithProgress(ctx context.Context, manager EmployeeManager, employees []*Employee, label string) (int, []error) {
+	progress := NewProgress(os.Stderr, label, len(employees))
+	added := 0
+	errs := make([]error, 0)
+	for _, e := range employees {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("%w: %d of %d added", ErrCancelled, added, len(employees)))
+			progress.Done(ErrCancelled)
+			return added, errs
+		}
+		if err := manager.AddEmployee(e); err != nil {
+			errs = append(errs, fmt.Errorf("error adding employee ID %d: %w", e.ID, err))
+		} else {
+			added++
+		}
+		progress.Add(1)
+	}
+	progress.Done(nil)
+	return added, errs
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// QRCode is a QR code symbol: Modules[row][col] is true for dark modules.
+// Codes are encoded in byte mode at error correction level M, which recovers
+// from about 15% damage, enough for a printed badge.
+type QRCode struct {
+	Size    int
+	Modules [][]bool
+}
+
+// qrVersion is the layout of a QR code version at error correction level M
+type qrVersion struct {
+	ecPerBlock int
+	blocks     []int // data codewords of each block
+	alignment  []int // centers of the alignment patterns, in both directions
+}
+
+// qrVersions are versions 1-10, which hold up to 213 bytes at level M
+var qrVersions = []qrVersion{
+	1:  {10, []int{16}, nil},
+	2:  {16, []int{28}, []int{6, 18}},
+	3:  {26, []int{44}, []int{6, 22}},
+	4:  {18, []int{32, 32}, []int{6, 26}},
+	5:  {24, []int{43, 43}, []int{6, 30}},
+	6:  {16, []int{27, 27, 27, 27}, []int{6, 34}},
+	7:  {18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	8:  {22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	9:  {22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	10: {26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// dataCodewords is the number of data codewords the version holds
+func (v qrVersion) dataCodewords() int {
+	n := 0
+	for _, b := range v.blocks {
+		n += b
+	}
+	return n
+}
+
+// ErrQRTooLong is returned for data that does not fit the largest supported version
+var ErrQRTooLong = errorsx.New(errorsx.ErrValidation, "", "data too long for a QR code")
+
+// EncodeQR encodes data as a QR code of the smallest version it fits in
+func EncodeQR(data []byte) (*QRCode, error) {
+	for version := 1; version < len(qrVersions); version++ {
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*qrVersions[version].dataCodewords() {
+			continue
+		}
+		codewords := qrAddErrorCorrection(qrDataCodewords(data, version, countBits), qrVersions[version])
+		return qrDraw(version, codewords), nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes, at most 213 fit", ErrQRTooLong, len(data))
+}
+
+// qrBits appends bits to a byte slice, most significant first
+type qrBits struct {
+	bytes []byte
+	n     int
+}
+
+func (b *qrBits) add(value, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if value>>i&1 == 1 {
+			b.bytes[b.n/8] |= 0x80 >> (b.n % 8)
+		}
+		b.n++
+	}
+}
+
+// qrDataCodewords encodes data in byte mode, padded to the capacity of the version
+func qrDataCodewords(data []byte, version, countBits int) []byte {
+	capacity := qrVersions[version].dataCodewords()
+	var bits qrBits
+	bits.add(0b0100, 4) // byte mode
+	bits.add(len(data), countBits)
+	for _, b := range data {
+		bits.add(int(b), 8)
+	}
+
+	// Terminator, then padding to a whole byte and alternating pad bytes
+	terminator := 8*capacity - bits.n
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.add(0, terminator)
+	bits.add(0, (8-bits.n%8)%8)
+	for pad := 0xEC; len(bits.bytes) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.add(pad, 8)
+	}
+	return bits.bytes
+}
+
+// gfMultiply multiplies in GF(256) with the QR code polynomial x^8+x^4+x^3+x^2+1
+func gfMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x1D
+		z ^= (y >> i & 1) * x
+	}
+	return z
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree
+func reedSolomonDivisor(degree int) []byte {
+	divisor := make([]byte, degree)
+	divisor[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range divisor {
+			divisor[j] = gfMultiply(divisor[j], root)
+			if j+1 < degree {
+				divisor[j] ^= divisor[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return divisor
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	remainder := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[len(remainder)-1] = 0
+		for i, d := range divisor {
+			remainder[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return remainder
+}
+
+// qrAddErrorCorrection splits the data into blocks, adds each block's error
+// correction codewords and interleaves the blocks
+func qrAddErrorCorrection(data []byte, v qrVersion) []byte {
+	divisor := reedSolomonDivisor(v.ecPerBlock)
+	blocks := make([][]byte, len(v.blocks))
+	ecc := make([][]byte, len(v.blocks))
+	longest := 0
+	for i, n := range v.blocks {
+		blocks[i], data = data[:n], data[n:]
+		ecc[i] = reedSolomonRemainder(blocks[i], divisor)
+		if n > longest {
+			longest = n
+		}
+	}
+
+	var result []byte
+	for i := 0; i < longest; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecc {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// qrMatrix is a QR code being drawn, tracking which modules belong to function patterns
+type qrMatrix struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func (m *qrMatrix) set(row, col int, dark bool) {
+	m.modules[row][col] = dark
+	m.function[row][col] = true
+}
+
+// qrMasks are the eight mask patterns, which invert the data modules they match
+var qrMasks = []func(row, col int) bool{
+	func(r, c int) bool { return (r+c)%2 == 0 },
+	func(r, c int) bool { return r%2 == 0 },
+	func(r, c int) bool { return c%3 == 0 },
+	func(r, c int) bool { return (r+c)%3 == 0 },
+	func(r, c int) bool { return (r/2+c/3)%2 == 0 },
+	func(r, c int) bool { return r*c%2+r*c%3 == 0 },
+	func(r, c int) bool { return (r*c%2+r*c%3)%2 == 0 },
+	func(r, c int) bool { return ((r+c)%2+r*c%3)%2 == 0 },
+}
+
+// qrDraw lays out the function patterns and codewords of a version and
+// applies the mask with the lowest penalty
+func qrDraw(version int, codewords []byte) *QRCode {
+	size := 17 + 4*version
+	m := &qrMatrix{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range m.modules {
+		m.modules[i] = make([]bool, size)
+		m.function[i] = make([]bool, size)
+	}
+
+	// Timing patterns, then finder patterns with their separators over them
+	for i := 0; i < size; i++ {
+		m.set(6, i, i%2 == 0)
+		m.set(i, 6, i%2 == 0)
+	}
+	for _, center := range [][2]int{{3, 3}, {3, size - 4}, {size - 4, 3}} {
+		for dr := -4; dr <= 4; dr++ {
+			for dc := -4; dc <= 4; dc++ {
+				r, c := center[0]+dr, center[1]+dc
+				if r >= 0 && r < size && c >= 0 && c < size {
+					dist := max(abs(dr), abs(dc))
+					m.set(r, c, dist != 2 && dist != 4)
+				}
+			}
+		}
+	}
+
+	// Alignment patterns, except where they would overlap a finder pattern
+	align := qrVersions[version].alignment
+	for i, r := range align {
+		for j, c := range align {
+			if (i == 0 && j == 0) || (i == 0 && j == len(align)-1) || (i == len(align)-1 && j == 0) {
+				continue
+			}
+			for dr := -2; dr <= 2; dr++ {
+				for dc := -2; dc <= 2; dc++ {
+					m.set(r+dr, c+dc, max(abs(dr), abs(dc)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas, and draw the version information from version 7
+	m.drawFormat(0)
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := size-11+i%3, i/3
+			m.set(b, a, dark)
+			m.set(a, b, dark)
+		}
+	}
+
+	// Codewords fill the remaining modules in two-column zigzags from the bottom right
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < size; vert++ {
+			row := vert
+			if upward {
+				row = size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				col := right - j
+				if !m.function[row][col] && i < len(codewords)*8 {
+					m.modules[row][col] = codewords[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+
+	best, bestPenalty := 0, -1
+	for mask := range qrMasks {
+		m.applyMask(mask)
+		m.drawFormat(mask)
+		if penalty := m.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		m.applyMask(mask) // masking twice restores the modules
+	}
+	m.applyMask(best)
+	m.drawFormat(best)
+	return &QRCode{Size: size, Modules: m.modules}
+}
+
+// applyMask inverts the data modules the mask matches
+func (m *qrMatrix) applyMask(mask int) {
+	for r := 0; r < m.size; r++ {
+		for c := 0; c < m.size; c++ {
+			if !m.function[r][c] && qrMasks[mask](r, c) {
+				m.modules[r][c] = !m.modules[r][c]
+			}
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information for level M and a mask
+func (m *qrMatrix) drawFormat(mask int) {
+	data := mask // level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		m.set(i, 8, bit(i))
+	}
+	m.set(7, 8, bit(6))
+	m.set(8, 8, bit(7))
+	m.set(8, 7, bit(8))
+	for i := 9; i < 15; i++ {
+		m.set(8, 14-i, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		m.set(8, m.size-1-i, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.set(m.size-15+i, 8, bit(i))
+	}
+	m.set(m.size-8, 8, true) // the dark module
+}
+
+// penalty scores how hard the symbol is to scan: long runs, 2x2 blocks,
+// patterns that look like finders, and an uneven balance of dark and light
+func (m *qrMatrix) penalty() int {
+	penalty := 0
+	at := func(r, c int, transpose bool) bool {
+		if transpose {
+			return m.modules[c][r]
+		}
+		return m.modules[r][c]
+	}
+	finder := []bool{true, false, true, true, true, false, true}
+
+	for _, transpose := range []bool{false, true} {
+		for r := 0; r < m.size; r++ {
+			run := 1
+			for c := 1; c <= m.size; c++ {
+				if c < m.size && at(r, c, transpose) == at(r, c-1, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+
+			for c := 0; c+7 <= m.size; c++ {
+				matches := true
+				for k, dark := range finder {
+					if at(r, c+k, transpose) != dark {
+						matches = false
+						break
+					}
+				}
+				if matches && (m.lightRun(r, c-4, c, transpose) || m.lightRun(r, c+7, c+11, transpose)) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for r := 0; r < m.size; r++ {
+		for c := 0; c < m.size; c++ {
+			if m.modules[r][c] {
+				dark++
+			}
+			if r+1 < m.size && c+1 < m.size {
+				v := m.modules[r][c]
+				if m.modules[r][c+1] == v && m.modules[r+1][c] == v && m.modules[r+1][c+1] == v {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := m.size * m.size
+	penalty += abs(dark*20-total*10) / total * 10
+	return penalty
+}
+
+// lightRun reports whether modules from and up to, but not including, to are
+// light along a row (or a column when transposed). Modules outside the symbol count as light.
+func (m *qrMatrix) lightRun(line, from, to int, transpose bool) bool {
+	for i := from; i < to; i++ {
+		if i < 0 || i >= m.size {
+			continue
+		}
+		dark := m.modules[line][i]
+		if transpose {
+			dark = m.modules[i][line]
+		}
+		if dark {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
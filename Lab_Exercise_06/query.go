@@ -0,0 +1,287 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// queryOperators are the comparisons a query term can make, longest first
+// so that ">=" is not read as ">"
+var queryOperators = []string{">=", "<=", "!=", ":", "=", ">", "<"}
+
+// queryFields are the fields a query term can test
+var queryFields = []string{"id", "name", "position", "department", "location", "mode", "salary", "joined", "born", "experience"}
+
+// queryTerm is one condition of a query, such as salary>80000
+type queryTerm struct {
+	Pos     int // offset of the term in the query, for error messages
+	Negated bool
+	Field   string
+	Op      string
+	Value   string
+}
+
+// queryError reports a problem with a term of the query
+func queryError(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: q at position %d: %s", ErrInvalidInput, pos+1, fmt.Sprintf(format, args...))
+}
+
+// splitQuery splits a query into its terms at spaces outside double quotes,
+// removing the quotes. It returns each term with its offset.
+func splitQuery(q string) ([]string, []int, error) {
+	var terms []string
+	var offsets []int
+	var term strings.Builder
+	start, quoted, inTerm := 0, false, false
+	for i, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if inTerm {
+				terms, offsets = append(terms, term.String()), append(offsets, start)
+				term.Reset()
+				inTerm = false
+			}
+			continue
+		default:
+			term.WriteRune(r)
+		}
+		if !inTerm {
+			start, inTerm = i, true
+		}
+	}
+	if quoted {
+		return nil, nil, queryError(start, "unterminated quote")
+	}
+	if inTerm {
+		terms, offsets = append(terms, term.String()), append(offsets, start)
+	}
+	return terms, offsets, nil
+}
+
+// parseQueryTerm splits a term into its field, operator and value
+func parseQueryTerm(text string, pos int) (queryTerm, error) {
+	t := queryTerm{Pos: pos}
+	if strings.HasPrefix(text, "-") {
+		t.Negated, text = true, text[1:]
+	}
+	end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && r != '_' })
+	if end <= 0 {
+		return t, queryError(pos, "%q is not a condition; write a field, an operator and a value, as in department:HR", text)
+	}
+	t.Field = strings.ToLower(text[:end])
+	for _, op := range queryOperators {
+		if strings.HasPrefix(text[end:], op) {
+			t.Op, t.Value = op, text[end+len(op):]
+			break
+		}
+	}
+	if t.Op == "" {
+		return t, queryError(pos, "%q has no operator (use %s)", text, strings.Join(queryOperators, " "))
+	}
+	if t.Value == "" {
+		return t, queryError(pos, "%s%s needs a value", t.Field, t.Op)
+	}
+	return t, nil
+}
+
+// comparison matches employees whose field compares with the value as op
+// says; compare returns how the employee's field compares with the value
+func comparison(op string, compare func(*Employee) int) Predicate[*Employee] {
+	return func(e *Employee) bool { return compared(op, compare(e)) }
+}
+
+// compared reports whether a comparison whose result is c satisfies op
+func compared(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "!=":
+		return c != 0
+	default: // ":" and "="
+		return c == 0
+	}
+}
+
+// computedPredicate matches employees whose computed field compares with
+// the value: as numbers if both are numbers, otherwise as text, with :
+// testing that it contains the value. Employees the field cannot be worked
+// out for match nothing.
+func (t queryTerm) computedPredicate(f *ComputedField, now time.Time) Predicate[*Employee] {
+	number, err := strconv.ParseFloat(t.Value, 64)
+	numeric := err == nil
+	value := strings.ToLower(t.Value)
+	return func(e *Employee) bool {
+		v, err := f.Value(e, now)
+		if err != nil {
+			return false
+		}
+		if n, ok := v.(float64); ok && numeric {
+			return compared(t.Op, cmp.Compare(n, number))
+		}
+		text := strings.ToLower(formatComputedValue(v))
+		if t.Op == ":" {
+			return strings.Contains(text, value)
+		}
+		return compared(t.Op, strings.Compare(text, value))
+	}
+}
+
+// predicate builds the predicate a term stands for
+func (t queryTerm) predicate(now time.Time) (Predicate[*Employee], error) {
+	ordered := t.Op != ":" && t.Op != "=" && t.Op != "!="
+	var p Predicate[*Employee]
+	switch t.Field {
+	case "name", "position", "location":
+		if ordered {
+			return nil, queryError(t.Pos, "%s can only be tested with :, = or !=", t.Field)
+		}
+		field := func(e *Employee) string { return e.Name }
+		switch t.Field {
+		case "position":
+			field = func(e *Employee) string { return e.Position }
+		case "location":
+			field = func(e *Employee) string { return e.Location }
+		}
+		if t.Op == ":" {
+			value := strings.ToLower(t.Value)
+			p = func(e *Employee) bool { return strings.Contains(strings.ToLower(field(e)), value) }
+		} else {
+			p = comparison(t.Op, func(e *Employee) int {
+				if strings.EqualFold(field(e), t.Value) {
+					return 0
+				}
+				return 1
+			})
+		}
+	case "department":
+		if ordered {
+			return nil, queryError(t.Pos, "department can only be tested with :, = or !=")
+		}
+		d, err := ParseDepartment(t.Value)
+		if err != nil {
+			return nil, queryError(t.Pos, "unknown department %q", t.Value)
+		}
+		p = comparison(t.Op, func(e *Employee) int { return cmp.Compare(e.Department, d) })
+	case "mode":
+		if ordered {
+			return nil, queryError(t.Pos, "mode can only be tested with :, = or !=")
+		}
+		m, err := StringToWorkMode(t.Value)
+		if err != nil {
+			return nil, queryError(t.Pos, "unknown work mode %q (on-site, hybrid or remote)", t.Value)
+		}
+		p = comparison(t.Op, func(e *Employee) int { return cmp.Compare(e.WorkMode, m) })
+	case "id", "salary", "experience":
+		v, err := strconv.ParseFloat(t.Value, 64)
+		if err != nil {
+			return nil, queryError(t.Pos, "%s must be compared with a number, not %q", t.Field, t.Value)
+		}
+		field := map[string]func(*Employee) float64{
+			"id":         func(e *Employee) float64 { return float64(e.ID) },
+			"salary":     func(e *Employee) float64 { return e.Salary },
+			"experience": func(e *Employee) float64 { return e.ExperienceAt(now) },
+		}[t.Field]
+		p = comparison(t.Op, func(e *Employee) int { return cmp.Compare(field(e), v) })
+	case "joined", "born":
+		v, err := parseDate(t.Value)
+		if err != nil {
+			return nil, queryError(t.Pos, "%s must be compared with a date in YYYY-MM-DD format, not %q", t.Field, t.Value)
+		}
+		day := formatDate(v)
+		field := func(e *Employee) time.Time { return e.JoinDate }
+		if t.Field == "born" {
+			field = func(e *Employee) time.Time { return e.BirthDate }
+		}
+		compare := comparison(t.Op, func(e *Employee) int { return cmp.Compare(formatDate(field(e)), day) })
+		// Employees without a birth date match no comparison with one
+		p = func(e *Employee) bool { return !field(e).IsZero() && compare(e) }
+	default:
+		if f := findComputedField(computedFields, t.Field); f != nil {
+			p = t.computedPredicate(f, now)
+			break
+		}
+		fields := append([]string(nil), queryFields...)
+		for _, f := range computedFields {
+			fields = append(fields, f.Name)
+		}
+		msg := fmt.Sprintf("unknown field %q (fields: %s)", t.Field, strings.Join(fields, ", "))
+		if s := closestWord(t.Field, fields); s != "" {
+			msg = fmt.Sprintf("unknown field %q; did you mean %s?", t.Field, s)
+		}
+		return nil, queryError(t.Pos, "%s", msg)
+	}
+	if t.Negated {
+		p = Not(p)
+	}
+	return p, nil
+}
+
+// ParseQuery parses a search such as `department:Engineering salary>80000`
+// into a predicate. Terms are separated by spaces and must all match. Each
+// compares a field with a value using : (contains, or equals for numbers,
+// dates and departments), =, !=, >, >=, < or <=; a leading - negates a term
+// and values with spaces are quoted, as in name:"Mary Ann".
+func ParseQuery(q string, now time.Time) (Predicate[*Employee], error) {
+	terms, offsets, err := splitQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	predicates := make([]Predicate[*Employee], 0, len(terms))
+	for i, text := range terms {
+		term, err := parseQueryTerm(text, offsets[i])
+		if err != nil {
+			return nil, err
+		}
+		p, err := term.predicate(now)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+	}
+	return And(predicates...), nil
+}
+
+// closestWord returns the word a mistyped one most likely meant, or "" if
+// none is close
+func closestWord(word string, words []string) string {
+	best, bestDistance := "", 3
+	for _, w := range words {
+		if d := editDistance(word, w); d < bestDistance {
+			best, bestDistance = w, d
+		}
+	}
+	return best
+}
+
+// editDistance counts the single-character edits that turn a into b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
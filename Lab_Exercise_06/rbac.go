@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Role constants using iota
+const (
+	RoleEmployee = iota
+	RoleManager
+	RoleHR
+	RoleAdmin
+)
+
+// Permission constants
+const (
+	PermViewPersonalData = iota
+	PermEditPersonalData
+	PermViewOwnData // view one's own record and personal data, for self-service
+	PermApproveChanges
+	PermManageNotifications // choose how one is notified, over the HTTP API too
+)
+
+// ErrPermissionDenied is returned when the current user's role does not allow an operation
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrUnauthenticated is returned when an API request does not say who makes it
+var ErrUnauthenticated = errors.New("authentication required")
+
+// rolePermissions lists the permissions granted to each role. main replaces
+// the grants of the roles in the -roles file.
+var rolePermissions = map[int][]int{
+	RoleEmployee: {PermViewOwnData, PermManageNotifications},
+	RoleManager:  {PermApproveChanges, PermManageNotifications},
+	RoleHR:       {PermViewPersonalData, PermEditPersonalData, PermManageNotifications},
+	RoleAdmin:    {PermApproveChanges, PermManageNotifications},
+}
+
+// RoleToString converts a role constant to string
+func RoleToString(role int) string {
+	switch role {
+	case RoleEmployee:
+		return "Employee"
+	case RoleManager:
+		return "Manager"
+	case RoleHR:
+		return "HR"
+	case RoleAdmin:
+		return "Admin"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToRole converts string to role constant
+func StringToRole(role string) (int, error) {
+	switch strings.ToLower(role) {
+	case "employee":
+		return RoleEmployee, nil
+	case "manager":
+		return RoleManager, nil
+	case "hr":
+		return RoleHR, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return -1, errors.New("invalid role")
+	}
+}
+
+// allPermissions lists every permission in constant order
+var allPermissions = []int{PermViewPersonalData, PermEditPersonalData, PermViewOwnData, PermApproveChanges, PermManageNotifications}
+
+// PermissionToString converts a permission constant to its name in roles files
+func PermissionToString(permission int) string {
+	switch permission {
+	case PermViewPersonalData:
+		return "view_personal_data"
+	case PermEditPersonalData:
+		return "edit_personal_data"
+	case PermViewOwnData:
+		return "view_own_data"
+	case PermApproveChanges:
+		return "approve_changes"
+	case PermManageNotifications:
+		return "manage_notifications"
+	default:
+		return "unknown"
+	}
+}
+
+// StringToPermission converts a permission name to its constant
+func StringToPermission(permission string) (int, error) {
+	for _, p := range allPermissions {
+		if strings.EqualFold(permission, PermissionToString(p)) {
+			return p, nil
+		}
+	}
+	names := make([]string, len(allPermissions))
+	for i, p := range allPermissions {
+		names[i] = PermissionToString(p)
+	}
+	return -1, fmt.Errorf("%w: unknown permission %q (%s)", ErrInvalidInput, permission, strings.Join(names, ", "))
+}
+
+// RoleGrant is a role and the permissions it is granted, as written in a roles file
+type RoleGrant struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleGrants returns the permissions currently granted to each role
+func RoleGrants() []RoleGrant {
+	grants := make([]RoleGrant, 0, len(rolePermissions))
+	for _, role := range []int{RoleEmployee, RoleManager, RoleHR, RoleAdmin} {
+		g := RoleGrant{Role: strings.ToLower(RoleToString(role)), Permissions: []string{}}
+		for _, p := range rolePermissions[role] {
+			g.Permissions = append(g.Permissions, PermissionToString(p))
+		}
+		grants = append(grants, g)
+	}
+	return grants
+}
+
+// LoadRolePermissions reads a roles file
+func LoadRolePermissions(path string) (map[int][]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := ParseRolePermissions(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return permissions, nil
+}
+
+// ParseRolePermissions reads a list of RoleGrant in JSON and returns the
+// permissions of every role. Roles the list leaves out keep the ones they have.
+func ParseRolePermissions(data []byte) (map[int][]int, error) {
+	var grants []RoleGrant
+	if err := json.Unmarshal(data, &grants); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	permissions := make(map[int][]int, len(rolePermissions))
+	for role, granted := range rolePermissions {
+		permissions[role] = granted
+	}
+	seen := make(map[int]bool)
+	for _, g := range grants {
+		role, err := StringToRole(g.Role)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown role %q (employee, manager, hr, admin)", ErrInvalidInput, g.Role)
+		}
+		if seen[role] {
+			return nil, fmt.Errorf("%w: role %q is defined twice", ErrInvalidInput, g.Role)
+		}
+		seen[role] = true
+		granted := []int{}
+		for _, name := range g.Permissions {
+			p, err := StringToPermission(name)
+			if err != nil {
+				return nil, fmt.Errorf("role %q: %w", g.Role, err)
+			}
+			if !slices.Contains(granted, p) {
+				granted = append(granted, p)
+			}
+		}
+		permissions[role] = granted
+	}
+	return permissions, nil
+}
+
+// User is the person operating the system
+type User struct {
+	Name       string
+	Role       int
+	EmployeeID int // the user's own employee record, 0 if not linked
+}
+
+// Can reports whether the user's role grants the permission
+func (u User) Can(permission int) bool {
+	for _, p := range rolePermissions[u.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns ErrPermissionDenied unless the user has the permission
+func (u User) Require(permission int) error {
+	if !u.Can(permission) {
+		return fmt.Errorf("%w: %s role cannot perform this operation", ErrPermissionDenied, RoleToString(u.Role))
+	}
+	return nil
+}
+
+// SelfServiceOnly reports whether the user may only view their own record,
+// in the self-service menu or over the HTTP API, as the employee role does
+func (u User) SelfServiceOnly() bool {
+	return u.Role == RoleEmployee
+}
+
+// RequireFor is like Require, but also lets users with PermViewOwnData view the
+// personal data of their own employee record
+func (u User) RequireFor(permission, employeeID int) error {
+	if permission == PermViewPersonalData && u.EmployeeID != 0 && u.EmployeeID == employeeID && u.Can(PermViewOwnData) {
+		return nil
+	}
+	return u.Require(permission)
+}
+
+// RequireOwnOr is like Require, but also lets users with PermViewOwnData act
+// on their own employee record, such as to ask for leave or clock in
+func (u User) RequireOwnOr(permission, employeeID int) error {
+	if u.EmployeeID != 0 && u.EmployeeID == employeeID && u.Can(PermViewOwnData) {
+		return nil
+	}
+	return u.Require(permission)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrReadOnly is returned for changes made through a store opened read-only
+var ErrReadOnly = errors.New("store is read-only")
+
+// readOnlyBackends are the factories that open a backend without taking
+// write locks or upgrading its schema, for backends that need one
+var readOnlyBackends = make(map[string]StorageFactory)
+
+// RegisterReadOnlyStorage registers how to open a backend read-only. Backends
+// without one are opened normally and only guarded by ReadOnlyStorage.
+// It panics if the name is registered twice or the factory is nil.
+func RegisterReadOnlyStorage(name string, factory StorageFactory) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	if factory == nil {
+		panic("storage: RegisterReadOnlyStorage factory is nil for " + name)
+	}
+	if _, exists := readOnlyBackends[name]; exists {
+		panic("storage: RegisterReadOnlyStorage called twice for " + name)
+	}
+	readOnlyBackends[name] = factory
+}
+
+// openStorageReadOnly opens the named backend with its read-only factory, if
+// it has one, without guarding it against writes
+func openStorageReadOnly(name, dsn string, clock Clock) (Storage, error) {
+	storageMu.RLock()
+	factory, ok := readOnlyBackends[name]
+	storageMu.RUnlock()
+
+	if !ok {
+		return OpenStorage(name, dsn, clock)
+	}
+	return factory(dsn, clock)
+}
+
+// OpenStorageReadOnly opens the named backend as a follower of the instance
+// that writes to it: reads see its live data, and changes fail with
+// ErrReadOnly
+func OpenStorageReadOnly(name, dsn string, clock Clock) (Storage, error) {
+	store, err := openStorageReadOnly(name, dsn, clock)
+	if err != nil {
+		return nil, err
+	}
+	return NewReadOnlyStorage(store), nil
+}
+
+// ReadOnlyStorage decorates a Storage so that every change fails with
+// ErrReadOnly. Reads and subscriptions are passed through.
+type ReadOnlyStorage struct {
+	Storage
+}
+
+// NewReadOnlyStorage wraps a store so it can only be read
+func NewReadOnlyStorage(store Storage) *ReadOnlyStorage {
+	return &ReadOnlyStorage{Storage: store}
+}
+
+// Unwrap returns the wrapped store
+func (r *ReadOnlyStorage) Unwrap() EmployeeManager {
+	return r.Storage
+}
+
+// AddEmployee fails with ErrReadOnly
+func (r *ReadOnlyStorage) AddEmployee(e *Employee) error {
+	return fmt.Errorf("%w: cannot add employees", ErrReadOnly)
+}
+
+// RemoveEmployee fails with ErrReadOnly
+func (r *ReadOnlyStorage) RemoveEmployee(id int) error {
+	return fmt.Errorf("%w: cannot remove employees", ErrReadOnly)
+}
+
+// UpdateEmployee fails with ErrReadOnly
+func (r *ReadOnlyStorage) UpdateEmployee(e *Employee) error {
+	return fmt.Errorf("%w: cannot update employees", ErrReadOnly)
+}
+
+// TransferEmployee fails with ErrReadOnly
+func (r *ReadOnlyStorage) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	return fmt.Errorf("%w: cannot transfer employees", ErrReadOnly)
+}
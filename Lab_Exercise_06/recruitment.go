@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// Candidate stages using iota
+const (
+	StageApplied = iota
+	StageInterviewed
+	StageOffer
+	StageHired
+	StageRejected
+)
+
+// StageToString converts a candidate stage constant to string
+func StageToString(stage int) string {
+	switch stage {
+	case StageApplied:
+		return "Applied"
+	case StageInterviewed:
+		return "Interviewed"
+	case StageOffer:
+		return "Offer"
+	case StageHired:
+		return "Hired"
+	case StageRejected:
+		return "Rejected"
+	default:
+		return "Unknown"
+	}
+}
+
+// Recruitment errors
+var (
+	ErrCandidateNotFound = errorsx.New(errorsx.ErrNotFound, "", "candidate not found")
+	ErrInvalidStage      = errorsx.New(errorsx.ErrConflict, "stage", "invalid stage transition")
+)
+
+// Candidate stores an applicant moving through the recruitment pipeline
+type Candidate struct {
+	ID            int
+	Name          string
+	Position      string
+	Department    Department
+	OfferedSalary float64
+	Stage         int
+	AppliedAt     time.Time
+	EmployeeID    int // set once the candidate is hired
+}
+
+// String returns a formatted string representation of the candidate
+func (c *Candidate) String() string {
+	s := fmt.Sprintf("Candidate %d: %s, %s (%s) - %s",
+		c.ID, c.Name, c.Position, c.Department, StageToString(c.Stage))
+	if c.Stage == StageOffer {
+		s += fmt.Sprintf(", offered $%.2f", c.OfferedSalary)
+	}
+	if c.Stage == StageHired {
+		s += fmt.Sprintf(", employee ID %d", c.EmployeeID)
+	}
+	return s
+}
+
+// Recruitment tracks candidates and converts hired candidates into employees
+type Recruitment struct {
+	candidates map[int]*Candidate
+	nextID     int
+	manager    EmployeeManager
+	clock      Clock
+}
+
+// NewRecruitment creates a recruitment pipeline that hires into the given manager
+func NewRecruitment(manager EmployeeManager) *Recruitment {
+	return &Recruitment{
+		candidates: make(map[int]*Candidate),
+		nextID:     1,
+		manager:    manager,
+		clock:      clockOf(manager),
+	}
+}
+
+// AddCandidate registers a new applicant in the Applied stage
+func (r *Recruitment) AddCandidate(c *Candidate) error {
+	if c == nil {
+		return ErrInvalidInput
+	}
+
+	// Candidates are validated with the same rules as employees
+	if err := validateEmployee(&Employee{Name: c.Name, Department: c.Department}, r.clock.Now()); err != nil {
+		return err
+	}
+
+	c.ID = r.nextID
+	r.nextID++
+	c.Stage = StageApplied
+	if c.AppliedAt.IsZero() {
+		c.AppliedAt = r.clock.Now()
+	}
+
+	candidateCopy := *c
+	r.candidates[c.ID] = &candidateCopy
+	return nil
+}
+
+// GetCandidate retrieves a candidate by ID
+func (r *Recruitment) GetCandidate(id int) (*Candidate, error) {
+	candidate, exists := r.candidates[id]
+	if !exists {
+		return nil, ErrCandidateNotFound
+	}
+	candidateCopy := *candidate
+	return &candidateCopy, nil
+}
+
+// ListCandidates returns all candidates ordered by ID
+func (r *Recruitment) ListCandidates() []*Candidate {
+	result := make([]*Candidate, 0, len(r.candidates))
+	for _, c := range r.candidates {
+		candidateCopy := *c
+		result = append(result, &candidateCopy)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// transition moves a candidate from one expected stage to the next
+func (r *Recruitment) transition(id, from, to int) (*Candidate, error) {
+	candidate, exists := r.candidates[id]
+	if !exists {
+		return nil, ErrCandidateNotFound
+	}
+	if candidate.Stage != from {
+		return nil, fmt.Errorf("%w: candidate is %s, expected %s",
+			ErrInvalidStage, StageToString(candidate.Stage), StageToString(from))
+	}
+	candidate.Stage = to
+	return candidate, nil
+}
+
+// Interview marks an applied candidate as interviewed
+func (r *Recruitment) Interview(id int) error {
+	_, err := r.transition(id, StageApplied, StageInterviewed)
+	return err
+}
+
+// MakeOffer extends a salary offer to an interviewed candidate
+func (r *Recruitment) MakeOffer(id int, salary float64) error {
+	candidate, exists := r.candidates[id]
+	if !exists {
+		return ErrCandidateNotFound
+	}
+	if err := validateEmployee(&Employee{Name: candidate.Name, Department: candidate.Department, Salary: salary}, r.clock.Now()); err != nil {
+		return err
+	}
+
+	candidate, err := r.transition(id, StageInterviewed, StageOffer)
+	if err != nil {
+		return err
+	}
+	candidate.OfferedSalary = salary
+	return nil
+}
+
+// Hire converts a candidate with an offer into an employee record. In a dry
+// run the hire is only reported, and the candidate is left at the offer stage.
+func (r *Recruitment) Hire(id int, joinDate time.Time) (*Employee, error) {
+	candidate, exists := r.candidates[id]
+	if !exists {
+		return nil, ErrCandidateNotFound
+	}
+	if candidate.Stage != StageOffer {
+		return nil, fmt.Errorf("%w: candidate is %s, expected %s",
+			ErrInvalidStage, StageToString(candidate.Stage), StageToString(StageOffer))
+	}
+
+	// ID 0 lets the manager assign the next employee ID
+	employee := &Employee{
+		Name:       candidate.Name,
+		Position:   candidate.Position,
+		Salary:     candidate.OfferedSalary,
+		Department: candidate.Department,
+		JoinDate:   joinDate,
+	}
+	if err := r.manager.AddEmployee(employee); err != nil {
+		return nil, err
+	}
+
+	// A dry run only previews the hire, so the candidate keeps their offer
+	if d, ok := capability[*DryRunManager](r.manager); ok {
+		d.report("would mark candidate %d (%s) as hired", id, candidate.Name)
+		return employee, nil
+	}
+	candidate.Stage = StageHired
+	candidate.EmployeeID = employee.ID
+	return employee, nil
+}
+
+// Reject removes a candidate from the pipeline at any stage before hiring
+func (r *Recruitment) Reject(id int) error {
+	candidate, exists := r.candidates[id]
+	if !exists {
+		return ErrCandidateNotFound
+	}
+	if candidate.Stage == StageHired || candidate.Stage == StageRejected {
+		return fmt.Errorf("%w: candidate is already %s", ErrInvalidStage, StageToString(candidate.Stage))
+	}
+	candidate.Stage = StageRejected
+	return nil
+}
+
+// recruitmentInteractive manages the recruitment pipeline through user interaction
+func recruitmentInteractive(recruitment *Recruitment, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Recruitment ==="))
+	fmt.Println("1. Add candidate")
+	fmt.Println("2. List candidates")
+	fmt.Println("3. Mark candidate as interviewed")
+	fmt.Println("4. Make offer")
+	fmt.Println("5. Hire candidate")
+	fmt.Println("6. Reject candidate")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		name, err := readString(reader, "Name: ")
+		if err != nil {
+			return err
+		}
+
+		position, err := readString(reader, "Position: ")
+		if err != nil {
+			return err
+		}
+
+		department, err := readDepartment(reader)
+		if err != nil {
+			return err
+		}
+
+		candidate := &Candidate{Name: name, Position: position, Department: department}
+		if err := recruitment.AddCandidate(candidate); err != nil {
+			return err
+		}
+		fmt.Printf("\nCandidate added with ID: %d\n", candidate.ID)
+
+	case 2:
+		candidates := recruitment.ListCandidates()
+		if len(candidates) == 0 {
+			fmt.Println("\nNo candidates found.")
+			return nil
+		}
+		fmt.Println()
+		for _, c := range candidates {
+			fmt.Println(c)
+		}
+
+	case 3, 4, 5, 6:
+		id, err := readInt(reader, "Enter candidate ID: ")
+		if err != nil {
+			return err
+		}
+
+		switch option {
+		case 3:
+			err = recruitment.Interview(id)
+		case 4:
+			var salary float64
+			salary, err = readFloat(reader, "Offered salary: ")
+			if err == nil {
+				err = recruitment.MakeOffer(id, salary)
+			}
+		case 5:
+			var joinDate time.Time
+			joinDate, err = readDate(reader, "Join Date")
+			if err == nil {
+				var employee *Employee
+				employee, err = recruitment.Hire(id, joinDate)
+				if _, dryRun := capability[*DryRunManager](recruitment.manager); err == nil && !dryRun {
+					fmt.Printf("\nCandidate hired as employee ID: %d\n", employee.ID)
+				}
+			}
+		case 6:
+			var ok bool
+			ok, err = confirm(reader, "Reject this candidate?", true)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("\n" + warningText("Operation cancelled."))
+				return nil
+			}
+			err = recruitment.Reject(id)
+		}
+		if err != nil {
+			return err
+		}
+
+		candidate, err := recruitment.GetCandidate(id)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\nCandidate updated:", candidate)
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	return nil
+}
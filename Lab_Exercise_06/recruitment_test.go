@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestHireDryRun(t *testing.T) {
+	store := NewInMemoryEmployeeManager()
+	recruitment := NewRecruitment(NewDryRunManager(store, io.Discard))
+	candidate := &Candidate{Name: "Ada Lovelace", Position: "Engineer", Department: Engineering}
+	if err := recruitment.AddCandidate(candidate); err != nil {
+		t.Fatal(err)
+	}
+	if err := recruitment.Interview(candidate.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := recruitment.MakeOffer(candidate.ID, 90000); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := recruitment.Hire(candidate.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := recruitment.GetCandidate(candidate.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Stage != StageOffer || got.EmployeeID != 0 {
+		t.Errorf("after a dry-run hire the candidate is %s with employee ID %d, want %s",
+			StageToString(got.Stage), got.EmployeeID, StageToString(StageOffer))
+	}
+	if employees, _ := store.ListEmployees(); len(employees) != 0 {
+		t.Errorf("a dry-run hire added %d employee(s)", len(employees))
+	}
+}
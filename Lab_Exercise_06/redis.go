@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errRedisNil is returned for a missing key
+var errRedisNil = errors.New("redis: nil")
+
+// redisClient is a minimal Redis client speaking RESP over a single
+// connection, enough for the cache's GET, SET and DEL commands
+type redisClient struct {
+	mu      sync.Mutex
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+// newRedisClient creates a client for the server at addr (host:port).
+// The connection is opened on first use and reopened after errors.
+func newRedisClient(addr string, timeout time.Duration) *redisClient {
+	return &redisClient{addr: addr, timeout: timeout}
+}
+
+// Do sends a command and returns its reply: a string, an int64, a []interface{}
+// or errRedisNil for a nil reply
+func (c *redisClient) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+		c.reader = bufio.NewReader(conn)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	reply, err := c.roundTrip(args)
+	var redisErr redisError
+	if err != nil && err != errRedisNil && !errors.As(err, &redisErr) {
+		// The connection is in an unknown state; start again next time
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+// roundTrip writes a command and reads the reply. The caller must hold the lock.
+func (c *redisClient) roundTrip(args []string) (interface{}, error) {
+	buf := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		buf = append(buf, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.reader)
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readRedisReply reads a single RESP reply
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = readRedisReply(r)
+			if err != nil && err != errRedisNil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
+
+// Get returns the value of a key, or errRedisNil if it is not set
+func (c *redisClient) Get(key string) (string, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil {
+		return "", err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+	return value, nil
+}
+
+// Set stores a value that expires after ttl
+func (c *redisClient) Set(key, value string, ttl time.Duration) error {
+	_, err := c.Do("SET", key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Del removes keys
+func (c *redisClient) Del(keys ...string) error {
+	_, err := c.Do(append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Close closes the connection
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
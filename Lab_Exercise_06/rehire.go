@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Alumnus is a former employee, as they were when they left
+type Alumnus struct {
+	Employee Employee
+	LeftAt   time.Time
+}
+
+// Tenure returns the length of the alumnus's service
+func (a Alumnus) Tenure() Tenure {
+	return a.Employee.TenureAt(a.LeftAt)
+}
+
+// Alumni returns the employees who were removed and not added back, from the
+// change log, ordered by ID
+func (m *InMemoryEmployeeManager) Alumni() []Alumnus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	alumni := make([]Alumnus, 0)
+	left := make(map[int]Alumnus)
+	for _, c := range m.log {
+		switch c.Type {
+		case EventEmployeeRemoved:
+			left[c.EmployeeID] = Alumnus{Employee: c.Employee, LeftAt: c.Time}
+		case EventEmployeeAdded:
+			delete(left, c.EmployeeID)
+		}
+	}
+	for _, a := range left {
+		alumni = append(alumni, a)
+	}
+	sort.Slice(alumni, func(i, j int) bool { return alumni[i].Employee.ID < alumni[j].Employee.ID })
+	return alumni
+}
+
+// sameDay reports whether two dates are the same calendar day, or either is unknown
+func sameDay(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return true
+	}
+	return formatDate(a) == formatDate(b)
+}
+
+// MatchAlumni returns the former employees a new employee may be: those with
+// the same name and, when both are known, the same birth date. Storage that
+// does not keep removed employees has no alumni.
+func MatchAlumni(manager EmployeeManager, e *Employee) []Alumnus {
+	store, ok := capability[interface{ Alumni() []Alumnus }](manager)
+	if !ok {
+		return nil
+	}
+
+	name := normalizeName(e.Name)
+	var matches []Alumnus
+	for _, a := range store.Alumni() {
+		if normalizeName(a.Employee.Name) == name && sameDay(a.Employee.BirthDate, e.BirthDate) {
+			matches = append(matches, a)
+		}
+	}
+	return matches
+}
+
+// Rehire adds a former employee back under their old ID, so their change
+// history continues, with a join date moved back by their prior service so
+// that tenure counts both periods but not the time away
+func Rehire(manager EmployeeManager, alumnus Alumnus, e *Employee) error {
+	prior := alumnus.LeftAt.Sub(alumnus.Employee.JoinDate)
+	if prior < 0 {
+		prior = 0
+	}
+
+	rehired := *e
+	rehired.ID = alumnus.Employee.ID
+	rehired.JoinDate = e.JoinDate.Add(-prior)
+	if rehired.BirthDate.IsZero() {
+		rehired.BirthDate = alumnus.Employee.BirthDate
+	}
+	if err := manager.AddEmployee(&rehired); err != nil {
+		return err
+	}
+	*e = rehired
+	return nil
+}
+
+// offerRehire asks whether a new employee is one of the former employees
+// matching them, and rehires them if so. It reports whether they were rehired.
+func offerRehire(manager EmployeeManager, reader *bufio.Reader, e *Employee) (bool, error) {
+	matches := MatchAlumni(manager, e)
+	if len(matches) == 0 {
+		return false, nil
+	}
+
+	fmt.Println("\n" + warningText("This person may be a former employee:"))
+	for i, a := range matches {
+		fmt.Printf("%d. %s (ID %d), %s in %s, left %s after %s\n", i+1, a.Employee.Name, a.Employee.ID,
+			a.Employee.Position, a.Employee.Department, formatDate(a.LeftAt), a.Tenure())
+	}
+
+	choice, err := readValue(reader, "Rehire which record (number, blank for a new employee): ", func(input string) (int, error) {
+		if input == "" {
+			return 0, nil
+		}
+		n, err := parseInt(input)
+		if err != nil {
+			return 0, err
+		}
+		if n < 1 || n > len(matches) {
+			return 0, fmt.Errorf("%w: please select a listed record", ErrInvalidInput)
+		}
+		return n, nil
+	})
+	if err != nil || choice == 0 {
+		return false, err
+	}
+
+	if err := Rehire(manager, matches[choice-1], e); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Reminder kinds using iota
+const (
+	ReminderWorkAnniversary = iota
+	ReminderBirthday
+)
+
+// Reminder is an upcoming work anniversary or birthday of an employee
+type Reminder struct {
+	EmployeeID int
+	Name       string
+	Kind       int
+	Date       time.Time
+	Years      int
+}
+
+// String returns a formatted string representation of the reminder
+func (r Reminder) String() string {
+	switch r.Kind {
+	case ReminderBirthday:
+		return fmt.Sprintf("%s  %s (ID %d): birthday, turns %d",
+			formatDate(r.Date), r.Name, r.EmployeeID, r.Years)
+	default:
+		return fmt.Sprintf("%s  %s (ID %d): %d-year work anniversary",
+			formatDate(r.Date), r.Name, r.EmployeeID, r.Years)
+	}
+}
+
+// anniversaryIn returns the anniversary of date in the given year, using the
+// calendar date in loc. February 29 falls on February 28 in non-leap years.
+func anniversaryIn(date time.Time, year int, loc *time.Location) time.Time {
+	date = date.In(loc)
+	month, day := date.Month(), date.Day()
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		day = 28
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// isLeapYear reports whether year is a leap year
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// nextAnniversary returns the first anniversary of date on or after from,
+// and how many years it marks
+func nextAnniversary(date, from time.Time) (time.Time, int) {
+	today := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	next := anniversaryIn(date, today.Year(), today.Location())
+	if next.Before(today) {
+		next = anniversaryIn(date, today.Year()+1, today.Location())
+	}
+	return next, next.Year() - date.In(today.Location()).Year()
+}
+
+// UpcomingReminders returns the work anniversaries and birthdays falling within
+// the given number of days from the start date, ordered by date
+func UpcomingReminders(manager EmployeeManager, from time.Time, days int) ([]Reminder, error) {
+	employees, err := employeeValues(manager)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	end := start.AddDate(0, 0, days)
+
+	reminders := make([]Reminder, 0)
+	add := func(emp *Employee, kind int, date time.Time) {
+		if date.IsZero() {
+			return
+		}
+		next, years := nextAnniversary(date, start)
+		if years <= 0 || next.After(end) {
+			return
+		}
+		reminders = append(reminders, Reminder{
+			EmployeeID: emp.ID,
+			Name:       emp.Name,
+			Kind:       kind,
+			Date:       next,
+			Years:      years,
+		})
+	}
+
+	for i := range employees {
+		emp := &employees[i]
+		add(emp, ReminderWorkAnniversary, emp.JoinDate)
+		add(emp, ReminderBirthday, emp.BirthDate)
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		if !reminders[i].Date.Equal(reminders[j].Date) {
+			return reminders[i].Date.Before(reminders[j].Date)
+		}
+		return reminders[i].EmployeeID < reminders[j].EmployeeID
+	})
+	return reminders, nil
+}
+
+// remindersInteractive lists upcoming reminders through user interaction
+func remindersInteractive(manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Upcoming Anniversaries & Birthdays ==="))
+
+	days, err := readInt(reader, "Number of days to look ahead [30]: ")
+	if err != nil {
+		return err
+	}
+	if days <= 0 {
+		days = 30
+	}
+
+	reminders, err := UpcomingReminders(manager, clockOf(manager).Now().In(userLocation), days)
+	if err != nil {
+		return err
+	}
+
+	if len(reminders) == 0 {
+		fmt.Printf("\nNo anniversaries or birthdays in the next %d days.\n", days)
+		return nil
+	}
+
+	fmt.Printf("\nFound %d reminder(s) in the next %d days:\n\n", len(reminders), days)
+	for _, r := range reminders {
+		fmt.Println(r)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Repair finds leave that disagrees with the employee records and with
+// itself, and unless dryRun is set, fixes what the records determine: the
+// requests and accounts of employees who are gone are dropped, and the
+// carried-over part of a balance is brought within it. Balances themselves
+// are only reported, since the leave keeps no ledger of the accruals and
+// balances set by hand they were made from. It returns the fixes, and the
+// problems left for a person to settle.
+func (l *Leave) Repair(dryRun bool) (fixed, left []string, err error) {
+	employees, err := employeeValues(l.manager)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(employees) == 0 && (len(l.requests) > 0 || len(l.accounts) > 0) {
+		// Everyone's leave would be dropped, which is more likely the wrong store
+		return nil, nil, fmt.Errorf("%w: the store has no employees; check -storage and -dsn before repairing leave against it", ErrInvalidInput)
+	}
+	employed := make(map[int]bool, len(employees))
+	for _, e := range employees {
+		employed[e.ID] = true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests := make([]*LeaveRequest, 0, len(l.requests))
+	for _, r := range l.requests {
+		if employed[r.EmployeeID] {
+			requests = append(requests, r)
+			continue
+		}
+		fixed = append(fixed, fmt.Sprintf("dropped leave request #%d of ID %d, who is no longer an employee", r.ID, r.EmployeeID))
+	}
+
+	ids := make([]int, 0, len(l.accounts))
+	for id := range l.accounts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	accounts := make(map[int]*LeaveAccount, len(l.accounts))
+	for _, id := range ids {
+		a := *l.accounts[id]
+		switch {
+		case !employed[id]:
+			fixed = append(fixed, fmt.Sprintf("dropped the leave account of ID %d, who is no longer an employee", id))
+			continue
+		case a.Balance < 0:
+			left = append(left, fmt.Sprintf("ID %d has a negative balance of %.2f day(s); set it from the Leave menu", id, a.Balance))
+		case a.Balance != roundDays(a.Balance):
+			fixed = append(fixed, fmt.Sprintf("rounded the balance of ID %d from %v to %.2f day(s)", id, a.Balance, roundDays(a.Balance)))
+			a.Balance = roundDays(a.Balance)
+		}
+		if a.Carried < 0 || a.Carried > max(a.Balance, 0) {
+			carried := min(max(a.Carried, 0), max(a.Balance, 0))
+			fixed = append(fixed, fmt.Sprintf("brought the %.2f day(s) ID %d carried over within their balance of %.2f, as %.2f", a.Carried, id, a.Balance, carried))
+			a.Carried = carried
+		}
+		accounts[id] = &a
+	}
+
+	if dryRun || len(fixed) == 0 {
+		return fixed, left, nil
+	}
+	oldRequests, oldAccounts := l.requests, l.accounts
+	l.requests, l.accounts = requests, accounts
+	if err := l.save(); err != nil {
+		l.requests, l.accounts = oldRequests, oldAccounts
+		return nil, nil, err
+	}
+	return fixed, left, nil
+}
+
+// runRepair implements the repair command, which rebuilds a store's indexes
+// from its records and fixes the leave that disagrees with them, checking
+// the indexes as doctor does before and after. Other instances using the
+// store and the leave file should be stopped first.
+func runRepair(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("repair", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storageName := fs.String("storage", "", "storage backend to repair (default the system configuration's, or memory)")
+	dsn := fs.String("dsn", "", "storage connection string")
+	systemPath := fs.String("system-config", "", "system configuration to take the storage from (default the one the setup wizard wrote; off to ignore it)")
+	leaveFile := fs.String("leave-file", "", "leave file to repair against the store's employees")
+	dryRun := fs.Bool("dry-run", false, "report what would be repaired without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", ErrInvalidInput, fs.Arg(0))
+	}
+
+	system, _, err := loadSystemConfig(*systemPath)
+	if err != nil {
+		return err
+	}
+	backend, connection := systemStorage(system, *storageName, *dsn)
+	store, check := checkStorage(backend, connection)
+	if store == nil {
+		writeDoctorChecks(stdout, []DoctorCheck{check})
+		return fmt.Errorf("the store could not be opened, so nothing was repaired")
+	}
+	defer store.Close()
+
+	problems := 0
+	before := checkIndexes(store)
+	fmt.Fprintf(stdout, "Indexes: %s\n", before.Detail)
+	rebuilder, ok := capability[interface{ RebuildIndexes() error }](store)
+	switch {
+	case !ok:
+		fmt.Fprintf(stdout, "  %s keeps no indexes to rebuild\n", backend)
+	case *dryRun:
+		fmt.Fprintln(stdout, "  would rebuild them")
+	default:
+		if err := rebuilder.RebuildIndexes(); err != nil {
+			return fmt.Errorf("rebuilding the indexes: %w", err)
+		}
+		after := checkIndexes(store)
+		fmt.Fprintf(stdout, "  rebuilt: %s\n", after.Detail)
+		if after.Status == checkFail {
+			problems++
+		}
+	}
+	if before.Status == checkFail && (!ok || *dryRun) {
+		problems++
+	}
+
+	if *leaveFile != "" {
+		leave, err := NewLeave(store, *leaveFile)
+		if err != nil {
+			return err
+		}
+		fixed, left, err := leave.Repair(*dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Leave: %d fix(es), %d problem(s) to settle by hand\n", len(fixed), len(left))
+		verb := "  "
+		if *dryRun {
+			verb = "  would have "
+			problems += len(fixed)
+		}
+		for _, f := range fixed {
+			fmt.Fprintf(stdout, "%s%s\n", verb, f)
+		}
+		for _, p := range left {
+			fmt.Fprintf(stdout, "  %s\n", warningText(p))
+		}
+		problems += len(left)
+	}
+
+	if problems > 0 {
+		if *dryRun {
+			return fmt.Errorf("%d problem(s) found; run repair without -dry-run to fix them", problems)
+		}
+		return fmt.Errorf("%d problem(s) could not be repaired", problems)
+	}
+	fmt.Fprintln(stdout, "\nNothing is left to repair.")
+	return nil
+}
@@ -0,0 +1,780 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrRuleRejected is returned when a deployment's rule rejects a change
+var ErrRuleRejected = errorsx.New(errorsx.ErrValidation, "", "rejected by rule")
+
+// Operations a rule can apply to
+const (
+	RuleOnAdd    = "add"
+	RuleOnUpdate = "update" // includes transfers
+)
+
+// ruleFields are the employee fields a rule expression can read, also as
+// old.<field> in update rules
+var ruleFields = []string{"id", "name", "position", "department", "location", "mode", "salary", "experience", "joined", "born"}
+
+// ruleAggregates are the figures a rule expression can read over the other
+// employees of the department, as department.<name>, or of the company, as
+// company.<name>
+var ruleAggregates = []string{"headcount", "median_salary", "average_salary"}
+
+// ruleSettable are the fields a rule can set
+var ruleSettable = []string{"name", "position", "salary"}
+
+// Rule is a deployment's own check on the changes made through the manager,
+// such as rejecting salaries over twice the department median. When its
+// condition holds, it either rejects the change with a message or sets
+// fields of the employee to the values of expressions.
+//
+// Expressions use numbers, "strings", true and false, the fields and
+// aggregates above, computed fields, + - * /, = != < <= > >=, and, or, not,
+// parentheses and the functions min, max, abs and round. Strings compare
+// without regard to case; dates are YYYY-MM-DD strings. An aggregate over no
+// employees is 0.
+type Rule struct {
+	Name   string            `json:"name"`
+	On     []string          `json:"on,omitempty"`     // add, update; both if empty
+	When   string            `json:"when,omitempty"`   // the condition; always if empty
+	Reject string            `json:"reject,omitempty"` // message to reject the change with
+	Set    map[string]string `json:"set,omitempty"`    // field -> expression of its new value
+
+	when ruleExpr
+	set  map[string]ruleExpr
+}
+
+// RuleSet is the rules of a deployment, applied in order
+type RuleSet struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// LoadRules reads and compiles a rules file
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules reads and compiles rules in JSON
+func ParseRules(data []byte) (*RuleSet, error) {
+	var rs RuleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	for i, r := range rs.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("%w: rule %q: %v", ErrInvalidInput, r.Name, err)
+		}
+	}
+	return &rs, nil
+}
+
+// compile checks a rule and parses its expressions
+func (r *Rule) compile() error {
+	if (r.Reject == "") == (len(r.Set) == 0) {
+		return fmt.Errorf("a rule needs either reject or set")
+	}
+	if len(r.On) == 0 {
+		r.On = []string{RuleOnAdd, RuleOnUpdate}
+	}
+	for _, op := range r.On {
+		if op != RuleOnAdd && op != RuleOnUpdate {
+			return fmt.Errorf("unknown operation %q in on (%s, %s)", op, RuleOnAdd, RuleOnUpdate)
+		}
+	}
+	names := ruleNames{old: !slices.Contains(r.On, RuleOnAdd), aggregates: true, computed: computedFields}
+
+	when := r.When
+	if strings.TrimSpace(when) == "" {
+		when = "true"
+	}
+	var err error
+	if r.when, err = compileRuleExpr(when, names); err != nil {
+		return fmt.Errorf("when: %v", err)
+	}
+	r.set = make(map[string]ruleExpr, len(r.Set))
+	for field, text := range r.Set {
+		if !slices.Contains(ruleSettable, field) {
+			return fmt.Errorf("cannot set %q (settable: %s)", field, strings.Join(ruleSettable, ", "))
+		}
+		if r.set[field], err = compileRuleExpr(text, names); err != nil {
+			return fmt.Errorf("set %s: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// Install applies the rules to every add and update made through h
+func (rs *RuleSet) Install(h *HookedManager) {
+	others := func() ([]*Employee, error) {
+		return h.Unwrap().ListEmployees()
+	}
+	h.BeforeAdd(func(e *Employee) error {
+		return rs.apply(RuleOnAdd, &ruleScope{employee: e, list: others, now: clockOf(h).Now()})
+	})
+	h.BeforeUpdate(func(old, e *Employee) error {
+		return rs.apply(RuleOnUpdate, &ruleScope{employee: e, old: old, list: others, now: clockOf(h).Now()})
+	})
+}
+
+// apply runs the rules for an operation on the employee in scope, setting
+// its fields or rejecting the change. A rule that cannot be evaluated
+// rejects the change rather than letting it through unchecked.
+func (rs *RuleSet) apply(op string, scope *ruleScope) error {
+	for _, r := range rs.Rules {
+		if !slices.Contains(r.On, op) {
+			continue
+		}
+		holds, err := evalRuleBool(r.when, scope)
+		if err != nil {
+			return fmt.Errorf("%w %q: %v", ErrRuleRejected, r.Name, err)
+		}
+		if !holds {
+			continue
+		}
+		if r.Reject != "" {
+			return fmt.Errorf("%w %q: %s", ErrRuleRejected, r.Name, r.Reject)
+		}
+
+		// Every value is computed from the employee before any is set
+		values := make(map[string]interface{}, len(r.set))
+		for field, expr := range r.set {
+			if values[field], err = expr(scope); err != nil {
+				return fmt.Errorf("%w %q: %v", ErrRuleRejected, r.Name, err)
+			}
+		}
+		for field, v := range values {
+			if err := setRuleField(scope.employee, field, v); err != nil {
+				return fmt.Errorf("%w %q: %v", ErrRuleRejected, r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setRuleField sets a settable field of an employee to a value
+func setRuleField(e *Employee, field string, v interface{}) error {
+	if field == "salary" {
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("salary must be set to a number, not %s", ruleTypeName(v))
+		}
+		e.Salary = n
+		return nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%s must be set to a string, not %s", field, ruleTypeName(v))
+	}
+	if field == "name" {
+		e.Name = s
+	} else {
+		e.Position = s
+	}
+	return nil
+}
+
+// ruleScope is what a rule expression can read while a change is checked
+type ruleScope struct {
+	employee *Employee
+	old      *Employee // nil when adding
+	list     func() ([]*Employee, error)
+	now      time.Time
+
+	others []*Employee // every other employee, listed on first use
+	listed bool
+}
+
+// lookup returns the value of a name in the scope
+func (s *ruleScope) lookup(name string) (interface{}, error) {
+	group, field, found := strings.Cut(name, ".")
+	if !found {
+		return ruleFieldValue(s.employee, name, s.now), nil
+	}
+	if group == "old" {
+		if s.old == nil {
+			return nil, fmt.Errorf("%s is only known when updating", name)
+		}
+		return ruleFieldValue(s.old, field, s.now), nil
+	}
+
+	if !s.listed {
+		all, err := s.list()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range all {
+			if e.ID != s.employee.ID {
+				s.others = append(s.others, e)
+			}
+		}
+		s.listed = true
+	}
+	var salaries []float64
+	for _, e := range s.others {
+		if group == "company" || e.Department == s.employee.Department {
+			salaries = append(salaries, e.Salary)
+		}
+	}
+	summary := Summarize(salaries)
+	switch field {
+	case "headcount":
+		return float64(summary.Count), nil
+	case "median_salary":
+		return summary.P50, nil
+	default: // average_salary
+		return summary.Mean, nil
+	}
+}
+
+// ruleFieldValue returns an employee field as a rule expression sees it
+func ruleFieldValue(e *Employee, field string, now time.Time) interface{} {
+	switch field {
+	case "id":
+		return float64(e.ID)
+	case "name":
+		return e.Name
+	case "position":
+		return e.Position
+	case "department":
+		return e.Department.String()
+	case "location":
+		return e.Location
+	case "mode":
+		return strings.ToLower(WorkModeToString(e.WorkMode))
+	case "salary":
+		return e.Salary
+	case "experience":
+		return e.ExperienceAt(now)
+	case "joined":
+		return formatDate(e.JoinDate)
+	default: // born
+		if e.BirthDate.IsZero() {
+			return ""
+		}
+		return formatDate(e.BirthDate)
+	}
+}
+
+// ruleExpr is a compiled rule expression
+type ruleExpr func(s *ruleScope) (interface{}, error)
+
+// evalRuleBool evaluates a condition
+func evalRuleBool(expr ruleExpr, s *ruleScope) (bool, error) {
+	v, err := expr(s)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("a condition must be true or false, not %s", ruleTypeName(v))
+	}
+	return b, nil
+}
+
+// ruleTypeName names the type of a value for error messages
+func ruleTypeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	default:
+		return "true or false"
+	}
+}
+
+// ruleToken is a token of a rule expression
+type ruleToken struct {
+	pos  int
+	kind byte // 'n' number, 's' string, 'i' name, 'o' operator or punctuation, 0 end
+	text string
+	num  float64
+}
+
+// ruleOperators are the operators and punctuation of rule expressions,
+// longest first
+var ruleOperators = []string{">=", "<=", "!=", "=", ">", "<", "+", "-", "*", "/", "(", ")", ","}
+
+// tokenizeRule splits an expression into tokens
+func tokenizeRule(text string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("at position %d: unterminated string", i+1)
+			}
+			tokens = append(tokens, ruleToken{pos: i, kind: 's', text: text[i+1 : i+1+end]})
+			i += end + 2
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(text) && (unicode.IsDigit(rune(text[j])) || text[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(text[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("at position %d: %q is not a number", i+1, text[i:j])
+			}
+			tokens = append(tokens, ruleToken{pos: i, kind: 'n', text: text[i:j], num: n})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(text) && (unicode.IsLetter(rune(text[j])) || unicode.IsDigit(rune(text[j])) || text[j] == '_' || text[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, ruleToken{pos: i, kind: 'i', text: strings.ToLower(text[i:j])})
+			i = j
+		default:
+			op := ""
+			for _, o := range ruleOperators {
+				if strings.HasPrefix(text[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("at position %d: unexpected %q", i+1, c)
+			}
+			tokens = append(tokens, ruleToken{pos: i, kind: 'o', text: op})
+			i += len(op)
+		}
+	}
+	return append(tokens, ruleToken{pos: len(text)}), nil
+}
+
+// ruleParser parses a rule expression by recursive descent. From loosest to
+// tightest binding: or, and, not, comparisons, + and -, * and /, unary minus.
+type ruleParser struct {
+	tokens []ruleToken
+	i      int
+	names  ruleNames
+}
+
+// ruleNames are the names an expression can use besides the employee's fields
+type ruleNames struct {
+	old        bool             // old.<field>, in rules only applied to updates
+	aggregates bool             // department.<aggregate> and company.<aggregate>
+	computed   []*ComputedField // computed fields, read like the employee's own
+}
+
+// compileRuleExpr parses an expression, checking the names it uses
+func compileRuleExpr(text string, names ruleNames) (ruleExpr, error) {
+	tokens, err := tokenizeRule(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{tokens: tokens, names: names}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != 0 {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return expr, nil
+}
+
+func (p *ruleParser) peek() ruleToken {
+	return p.tokens[p.i]
+}
+
+func (p *ruleParser) next() ruleToken {
+	t := p.tokens[p.i]
+	if t.kind != 0 {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators or keywords
+func (p *ruleParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if (t.kind == 'o' || t.kind == 'i') && slices.Contains(texts, t.text) {
+		p.i++
+		return t.text, true
+	}
+	return "", false
+}
+
+func (p *ruleParser) errorf(t ruleToken, format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *ruleParser) parseOr() (ruleExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		if _, ok := p.accept("or"); !ok {
+			break
+		}
+		var right ruleExpr
+		if right, err = p.parseAnd(); err == nil {
+			left = ruleLogic(left, right, true)
+		}
+	}
+	return left, err
+}
+
+func (p *ruleParser) parseAnd() (ruleExpr, error) {
+	left, err := p.parseNot()
+	for err == nil {
+		if _, ok := p.accept("and"); !ok {
+			break
+		}
+		var right ruleExpr
+		if right, err = p.parseNot(); err == nil {
+			left = ruleLogic(left, right, false)
+		}
+	}
+	return left, err
+}
+
+// ruleLogic combines two conditions with or (any) or and, evaluating the
+// right one only when needed
+func ruleLogic(left, right ruleExpr, any bool) ruleExpr {
+	return func(s *ruleScope) (interface{}, error) {
+		l, err := evalRuleBool(left, s)
+		if err != nil || l == any {
+			return l, err
+		}
+		return evalRuleBool(right, s)
+	}
+}
+
+func (p *ruleParser) parseNot() (ruleExpr, error) {
+	if _, ok := p.accept("not"); !ok {
+		return p.parseComparison()
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return func(s *ruleScope) (interface{}, error) {
+		v, err := evalRuleBool(operand, s)
+		return !v, err
+	}, nil
+}
+
+func (p *ruleParser) parseComparison() (ruleExpr, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("=", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return func(s *ruleScope) (interface{}, error) {
+		l, err := left(s)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(s)
+		if err != nil {
+			return nil, err
+		}
+		var c int
+		switch l := l.(type) {
+		case float64:
+			n, ok := r.(float64)
+			if !ok {
+				return nil, fmt.Errorf("cannot compare a number with %s", ruleTypeName(r))
+			}
+			c = cmpFloat(l, n)
+		case string:
+			str, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot compare a string with %s", ruleTypeName(r))
+			}
+			c = strings.Compare(strings.ToLower(l), strings.ToLower(str))
+		default:
+			b, ok := r.(bool)
+			if !ok || (op != "=" && op != "!=") {
+				return nil, fmt.Errorf("true and false can only be compared with = or != to each other")
+			}
+			if l != b {
+				c = 1
+			}
+		}
+		switch op {
+		case "=":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}, nil
+}
+
+// cmpFloat compares two numbers
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (p *ruleParser) parseSum() (ruleExpr, error) {
+	left, err := p.parseProduct()
+	for err == nil {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			break
+		}
+		var right ruleExpr
+		if right, err = p.parseProduct(); err == nil {
+			left = ruleArithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *ruleParser) parseProduct() (ruleExpr, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			break
+		}
+		var right ruleExpr
+		if right, err = p.parseUnary(); err == nil {
+			left = ruleArithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+// ruleArithmetic applies an arithmetic operator to two numbers; + also joins strings
+func ruleArithmetic(op string, left, right ruleExpr) ruleExpr {
+	return func(s *ruleScope) (interface{}, error) {
+		l, err := left(s)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(s)
+		if err != nil {
+			return nil, err
+		}
+		if ls, ok := l.(string); ok && op == "+" {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+		a, aok := l.(float64)
+		b, bok := r.(float64)
+		if !aok || !bok {
+			return nil, fmt.Errorf("%s needs two numbers, not %s and %s", op, ruleTypeName(l), ruleTypeName(r))
+		}
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		default:
+			if b == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return a / b, nil
+		}
+	}
+}
+
+func (p *ruleParser) parseUnary() (ruleExpr, error) {
+	if _, ok := p.accept("-"); !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func(s *ruleScope) (interface{}, error) {
+		v, err := operand(s)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", ruleTypeName(v))
+		}
+		return -n, nil
+	}, nil
+}
+
+func (p *ruleParser) parsePrimary() (ruleExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case 'n':
+		return func(*ruleScope) (interface{}, error) { return t.num, nil }, nil
+	case 's':
+		return func(*ruleScope) (interface{}, error) { return t.text, nil }, nil
+	case 'o':
+		if t.text == "(" {
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, p.errorf(p.peek(), "missing )")
+			}
+			return expr, nil
+		}
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	case 'i':
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t)
+		}
+		return p.parseName(t)
+	default:
+		return nil, p.errorf(t, "the expression ends too soon")
+	}
+}
+
+// parseName compiles a reference to a field, a computed field, an old field
+// or an aggregate
+func (p *ruleParser) parseName(t ruleToken) (ruleExpr, error) {
+	switch t.text {
+	case "true", "false":
+		v := t.text == "true"
+		return func(*ruleScope) (interface{}, error) { return v, nil }, nil
+	}
+	if f := findComputedField(p.names.computed, t.text); f != nil {
+		return f.expr, nil
+	}
+
+	group, field, found := strings.Cut(t.text, ".")
+	valid := !found && slices.Contains(ruleFields, t.text)
+	switch group {
+	case "old":
+		valid = slices.Contains(ruleFields, field)
+		if valid && !p.names.aggregates {
+			return nil, p.errorf(t, "%s can only be used in rules", t.text)
+		}
+		if valid && !p.names.old {
+			return nil, p.errorf(t, "%s is only known in rules applied to updates alone, with on: [\"update\"]", t.text)
+		}
+	case "department", "company":
+		valid = valid || (found && slices.Contains(ruleAggregates, field))
+		if valid && found && !p.names.aggregates {
+			return nil, p.errorf(t, "%s can only be used in rules", t.text)
+		}
+	}
+	if !valid {
+		fields := append([]string(nil), ruleFields...)
+		for _, f := range p.names.computed {
+			fields = append(fields, f.Name)
+		}
+		names := append(append([]string(nil), fields...), "old.<field>", "department.<aggregate>", "company.<aggregate>")
+		msg := fmt.Sprintf("unknown name %q (%s; aggregates: %s)", t.text, strings.Join(names, ", "), strings.Join(ruleAggregates, ", "))
+		if s := closestWord(t.text, fields); s != "" {
+			msg = fmt.Sprintf("unknown name %q; did you mean %s?", t.text, s)
+		}
+		return nil, p.errorf(t, "%s", msg)
+	}
+	name := t.text
+	return func(s *ruleScope) (interface{}, error) { return s.lookup(name) }, nil
+}
+
+// ruleFunctions are the functions rule expressions can call, by name
+var ruleFunctions = map[string]func(args []float64) (float64, error){
+	"min": func(args []float64) (float64, error) { return slices.Min(args), nil },
+	"max": func(args []float64) (float64, error) { return slices.Max(args), nil },
+	"abs": func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("abs takes one number")
+		}
+		return math.Abs(args[0]), nil
+	},
+	"round": func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("round takes one number")
+		}
+		return math.Round(args[0]), nil
+	},
+}
+
+// parseCall compiles a call of one of the ruleFunctions, whose opening
+// parenthesis has been read
+func (p *ruleParser) parseCall(t ruleToken) (ruleExpr, error) {
+	fn, ok := ruleFunctions[t.text]
+	if !ok {
+		names := make([]string, 0, len(ruleFunctions))
+		for name := range ruleFunctions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, p.errorf(t, "unknown function %q (%s)", t.text, strings.Join(names, ", "))
+	}
+	var args []ruleExpr
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if _, ok := p.accept(")"); !ok {
+		return nil, p.errorf(p.peek(), "missing ) after the arguments of %s", t.text)
+	}
+	return func(s *ruleScope) (interface{}, error) {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			v, err := arg(s)
+			if err != nil {
+				return nil, err
+			}
+			n, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%s takes numbers, not %s", t.text, ruleTypeName(v))
+			}
+			values[i] = n
+		}
+		return fn(values)
+	}, nil
+}
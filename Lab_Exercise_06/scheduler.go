@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Schedule decides when a recurring job runs
+type Schedule interface {
+	// Next returns the first run time after t
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// Every is a Schedule that runs a job at a fixed interval
+type Every time.Duration
+
+// Next returns t plus the interval
+func (e Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+func (e Every) String() string {
+	return "every " + time.Duration(e).String()
+}
+
+// DailyAt is a Schedule that runs a job once a day at a time of day in the
+// user's time zone
+type DailyAt struct {
+	Hour, Minute int
+}
+
+// Next returns the first occurrence of the time of day after t
+func (d DailyAt) Next(t time.Time) time.Time {
+	local := t.In(userLocation)
+	next := time.Date(local.Year(), local.Month(), local.Day(), d.Hour, d.Minute, 0, 0, userLocation)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next.UTC()
+}
+
+func (d DailyAt) String() string {
+	return fmt.Sprintf("daily at %02d:%02d", d.Hour, d.Minute)
+}
+
+// WeeklyAt is a Schedule that runs a job once a week on a day at a time of
+// day in the user's time zone
+type WeeklyAt struct {
+	Weekday      time.Weekday
+	Hour, Minute int
+}
+
+// Next returns the first occurrence of the day and time of day after t
+func (w WeeklyAt) Next(t time.Time) time.Time {
+	next := DailyAt{Hour: w.Hour, Minute: w.Minute}.Next(t).In(userLocation)
+	days := (int(w.Weekday) - int(next.Weekday()) + 7) % 7
+	return time.Date(next.Year(), next.Month(), next.Day()+days, w.Hour, w.Minute, 0, 0, userLocation).UTC()
+}
+
+func (w WeeklyAt) String() string {
+	return fmt.Sprintf("weekly on %s at %02d:%02d", w.Weekday, w.Hour, w.Minute)
+}
+
+// JobStatus describes a registered job and how its runs went
+type JobStatus struct {
+	Name         string
+	Schedule     string
+	NextRun      time.Time
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    string
+	Runs         int
+	Failures     int
+}
+
+// job is a registered job and its status
+type job struct {
+	schedule Schedule
+	run      func(ctx context.Context, now time.Time) error
+	status   JobStatus
+}
+
+// Scheduler runs recurring jobs on a clock. It checks for due jobs on every
+// tick, so jobs also run when a simulated clock is moved forward. A job that
+// fails is logged and retried at its next scheduled time.
+type Scheduler struct {
+	clock  Clock
+	logger *log.Logger
+
+	mu       sync.Mutex
+	jobs     map[string]*job
+	lastTick time.Time // wall time of the last check for due jobs
+}
+
+// NewScheduler creates a scheduler that reads the time from clock and logs failures to logger
+func NewScheduler(clock Clock, logger *log.Logger) *Scheduler {
+	return &Scheduler{clock: clock, logger: logger, jobs: make(map[string]*job)}
+}
+
+// Register adds a job. Its first run is the schedule's first time after now.
+func (s *Scheduler) Register(name string, schedule Schedule, run func(ctx context.Context, now time.Time) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("%w: job %q is already registered", ErrInvalidInput, name)
+	}
+	s.jobs[name] = &job{
+		schedule: schedule,
+		run:      run,
+		status: JobStatus{
+			Name:     name,
+			Schedule: schedule.String(),
+			NextRun:  schedule.Next(s.clock.Now()),
+		},
+	}
+	return nil
+}
+
+// Status returns the status of every job, ordered by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// LastTick returns the wall time the scheduler last checked for due jobs, or
+// the zero time if it has not run
+func (s *Scheduler) LastTick() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastTick
+}
+
+// RunDue runs every job whose next run time has passed, one at a time in name order
+func (s *Scheduler) RunDue(ctx context.Context) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	s.lastTick = time.Now()
+	due := make([]string, 0)
+	for name, j := range s.jobs {
+		if !j.status.NextRun.After(now) {
+			due = append(due, name)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(due)
+
+	for _, name := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		s.runJob(ctx, name, now)
+	}
+}
+
+// runJob runs one job and records the outcome. Jobs run without the lock held,
+// so they can take as long as they need without blocking Status.
+func (s *Scheduler) runJob(ctx context.Context, name string, now time.Time) {
+	s.mu.Lock()
+	j := s.jobs[name]
+	s.mu.Unlock()
+
+	start := time.Now()
+	err := safeRun(ctx, j.run, now)
+	elapsed := time.Since(start)
+
+	s.mu.Lock()
+	j.status.LastRun = now
+	j.status.LastDuration = elapsed
+	j.status.Runs++
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+	}
+	// Runs missed while the job was not due, such as while the process was
+	// stopped, are skipped rather than run back to back
+	j.status.NextRun = j.schedule.Next(now)
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Printf("Job %s failed after %s: %v", name, elapsed.Round(time.Millisecond), err)
+	}
+}
+
+// safeRun runs a job, turning a panic into an error so one broken job cannot stop the scheduler
+func safeRun(ctx context.Context, run func(ctx context.Context, now time.Time) error, now time.Time) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx, now)
+}
+
+// Run checks for due jobs every tick until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
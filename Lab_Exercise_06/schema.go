@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSchemaTooNew is returned when stored data was written by a newer build,
+// whose schema this build cannot read safely
+var ErrSchemaTooNew = errors.New("stored data has a newer schema")
+
+// Migration upgrades a backend's stored data from the previous schema
+// version to Version. Up runs inside the backend's transaction type T, so a
+// migration and the version it records are committed together.
+type Migration[T any] struct {
+	Version     int
+	Description string
+	Up          func(T) error
+}
+
+// latestVersion returns the schema version the migrations lead to
+func latestVersion[T any](migrations []Migration[T]) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// applyMigrations brings data at schema version current up to the latest
+// version, calling apply for each newer migration in order. apply runs the
+// migration and records its version in one transaction. Data newer than the
+// latest version is refused rather than read with the wrong schema.
+func applyMigrations[T any](backend string, current int, migrations []Migration[T], apply func(Migration[T]) error) error {
+	latest := latestVersion(migrations)
+	if current > latest {
+		return fmt.Errorf("%w: the %s data is at schema version %d but this build knows up to version %d; run a newer build instead of downgrading",
+			ErrSchemaTooNew, backend, current, latest)
+	}
+	for i, m := range migrations {
+		if i > 0 && m.Version != migrations[i-1].Version+1 {
+			return fmt.Errorf("%s migrations skip from version %d to %d", backend, migrations[i-1].Version, m.Version)
+		}
+		if m.Version <= current {
+			continue
+		}
+		if err := apply(m); err != nil {
+			return fmt.Errorf("migrating %s schema to version %d (%s): %w", backend, m.Version, m.Description, err)
+		}
+	}
+	return nil
+}
+
+// checkSchemaVersion is how a store opened read-only checks its data, since
+// it cannot migrate it: the data must already be at the latest version
+func checkSchemaVersion(backend string, current, latest int) error {
+	switch {
+	case current > latest:
+		return fmt.Errorf("%w: the %s data is at schema version %d but this build knows up to version %d; run a newer build",
+			ErrSchemaTooNew, backend, current, latest)
+	case current < latest:
+		return fmt.Errorf("%w: the %s data is at schema version %d and needs upgrading to version %d; open it read-write once first",
+			ErrReadOnly, backend, current, latest)
+	}
+	return nil
+}
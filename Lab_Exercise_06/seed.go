@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// defaultSampleSize is how many employees the sample data has unless asked for more
+const defaultSampleSize = 25
+
+var (
+	seedFirstNames = []string{
+		"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda",
+		"David", "Elizabeth", "William", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+		"Thomas", "Sarah", "Charles", "Karen", "Priya", "Arjun", "Wei", "Mei", "Carlos",
+		"Sofia", "Ahmed", "Fatima", "Kenji", "Yuki", "Olga", "Ivan", "Amara", "Kwame",
+	}
+	seedLastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+		"Rodriguez", "Martinez", "Hernandez", "Lopez", "Wilson", "Anderson", "Thomas",
+		"Taylor", "Moore", "Jackson", "Martin", "Lee", "Patel", "Sharma", "Chen", "Wang",
+		"Kim", "Nakamura", "Silva", "Okafor", "Mensah", "Ivanova", "Khan", "Novak",
+	}
+)
+
+// seedPosition is a job title and its salary distribution
+type seedPosition struct {
+	Title  string
+	Salary float64 // mean salary
+	Spread float64 // standard deviation of the salary
+	Weight int     // how common the position is within its department
+}
+
+// seedDepartment is a department's share of the workforce and its positions
+type seedDepartment struct {
+	Weight    int
+	Positions []seedPosition
+}
+
+// seedDepartments describes the generated workforce, indexed by department
+var seedDepartments = map[Department]seedDepartment{
+	HR: {Weight: 10, Positions: []seedPosition{
+		{"HR Assistant", 45000, 4000, 4},
+		{"Recruiter", 58000, 6000, 4},
+		{"HR Business Partner", 72000, 7000, 2},
+		{"HR Manager", 88000, 8000, 1},
+	}},
+	Engineering: {Weight: 40, Positions: []seedPosition{
+		{"Junior Software Engineer", 70000, 6000, 5},
+		{"Software Engineer", 92000, 9000, 8},
+		{"Senior Software Engineer", 125000, 12000, 5},
+		{"Staff Engineer", 160000, 15000, 1},
+		{"Engineering Manager", 150000, 14000, 2},
+	}},
+	Finance: {Weight: 15, Positions: []seedPosition{
+		{"Accountant", 62000, 5000, 5},
+		{"Financial Analyst", 75000, 7000, 4},
+		{"Controller", 105000, 9000, 1},
+		{"Finance Director", 135000, 12000, 1},
+	}},
+	Marketing: {Weight: 15, Positions: []seedPosition{
+		{"Marketing Coordinator", 48000, 4000, 4},
+		{"Marketing Specialist", 62000, 6000, 5},
+		{"Content Strategist", 68000, 6000, 2},
+		{"Marketing Manager", 95000, 9000, 1},
+	}},
+	Operations: {Weight: 20, Positions: []seedPosition{
+		{"Operations Associate", 42000, 3500, 6},
+		{"Logistics Coordinator", 52000, 4500, 4},
+		{"Operations Analyst", 64000, 6000, 3},
+		{"Operations Manager", 90000, 8000, 1},
+	}},
+}
+
+// SeedGenerator produces realistic fake employees. Generators created with the
+// same seed produce the same employees, so demos and load tests are repeatable.
+type SeedGenerator struct {
+	rng *rand.Rand
+	now time.Time
+}
+
+// NewSeedGenerator creates a generator whose employees joined no later than now
+func NewSeedGenerator(seed int64, now time.Time) *SeedGenerator {
+	return &SeedGenerator{rng: rand.New(rand.NewSource(seed)), now: now}
+}
+
+// pick returns an index chosen with probability proportional to its weight
+func (g *SeedGenerator) pick(weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := g.rng.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(weights) - 1
+}
+
+// Employee returns a new fake employee without an ID
+func (g *SeedGenerator) Employee() *Employee {
+	depts := AllDepartments()
+	weights := make([]int, len(depts))
+	for i, d := range depts {
+		weights[i] = seedDepartments[d].Weight
+	}
+	dept := depts[g.pick(weights)]
+
+	positions := seedDepartments[dept].Positions
+	weights = make([]int, len(positions))
+	for i, p := range positions {
+		weights[i] = p.Weight
+	}
+	position := positions[g.pick(weights)]
+
+	// Most people joined in the last few years; a long tail joined up to 15 years ago
+	days := int(g.rng.ExpFloat64() * 3 * 365)
+	if days > 15*365 {
+		days = g.rng.Intn(15 * 365)
+	}
+	joined := dateOf(g.now.In(userLocation).Date()).AddDate(0, 0, -days)
+
+	// Salaries rise about 2% per year of tenure, rounded to the nearest hundred
+	years := float64(days) / 365
+	salary := (position.Salary + g.rng.NormFloat64()*position.Spread) * math.Pow(1.02, years)
+	salary = math.Max(math.Round(salary/100)*100, 20000)
+
+	e := &Employee{
+		Name:       seedFirstNames[g.rng.Intn(len(seedFirstNames))] + " " + seedLastNames[g.rng.Intn(len(seedLastNames))],
+		Position:   position.Title,
+		Salary:     salary,
+		Department: dept,
+		JoinDate:   joined,
+	}
+
+	// Birth dates are optional, so leave some unknown
+	if g.rng.Intn(4) != 0 {
+		age := 21 + g.rng.Intn(20)
+		e.BirthDate = joined.AddDate(-age, -g.rng.Intn(12), -g.rng.Intn(28))
+	}
+
+	// Recent hires are still on a 90 day probation
+	if end := joined.AddDate(0, 0, 90); end.After(g.now) {
+		e.ProbationEnd = end
+	}
+	return e
+}
+
+// Employees returns n new fake employees
+func (g *SeedGenerator) Employees(n int) []*Employee {
+	employees := make([]*Employee, n)
+	for i := range employees {
+		employees[i] = g.Employee()
+	}
+	return employees
+}
+
+// addSampleData adds n generated employees to the manager
+func addSampleData(manager EmployeeManager, n int) {
+	generator := NewSeedGenerator(time.Now().UnixNano(), appClock.Now())
+
+	// Add employees using variadic function
+	errors := AddMultipleEmployees(manager, generator.Employees(n)...)
+	if len(errors) > 0 {
+		fmt.Println("Errors adding sample data:")
+		for _, err := range errors {
+			fmt.Println(err)
+		}
+	}
+}
+
+// sampleDataInteractive adds as many generated employees as the user asks
+// for. Ctrl-C stops adding and keeps the employees added so far.
+func sampleDataInteractive(manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Add Sample Data ==="))
+
+	n, err := readInt(reader, fmt.Sprintf("Number of employees (e.g. %d): ", defaultSampleSize))
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return fmt.Errorf("%w: please enter a positive number of employees", ErrInvalidInput)
+	}
+
+	release, err := lockBatch(manager, "seed")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	generator := NewSeedGenerator(time.Now().UnixNano(), appClock.Now())
+	ctx, stop := interruptContext()
+	defer stop()
+	if _, errs := addEmployeesWithProgress(ctx, manager, generator.Employees(n), "Adding sample data"); len(errs) > 0 {
+		for _, err := range errs {
+			if errors.Is(err, ErrCancelled) {
+				return err
+			}
+			fmt.Println(err)
+		}
+	}
+	fmt.Println("\n" + successText("Sample data added successfully!"))
+	return nil
+}
+
+// runSeed implements the seed command, which generates fake employees for
+// load tests and demos. They are added to a storage backend, or written to
+// standard output as JSON when no backend is given.
+func runSeed(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	n := fs.Int("n", 1000, "number of employees to generate")
+	seed := fs.Int64("seed", 1, "random seed; the same seed generates the same employees")
+	storageName := fs.String("storage", "", "add the employees to this storage backend instead of printing them")
+	dsn := fs.String("dsn", "", "storage connection string")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n <= 0 {
+		return fmt.Errorf("%w: -n must be positive", ErrInvalidInput)
+	}
+
+	employees := NewSeedGenerator(*seed, appClock.Now()).Employees(*n)
+
+	if *storageName == "" {
+		out := make([]employeeJSON, len(employees))
+		for i, e := range employees {
+			e.ID = i + 1
+			out[i] = toEmployeeJSON(e)
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	}
+
+	store, err := OpenStorage(*storageName, *dsn, appClock)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	release, err := lockBatch(store, "seed")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Ctrl-C stops adding and keeps the employees added so far
+	ctx, stop := interruptContext()
+	defer stop()
+	added, errs := addEmployeesWithProgress(ctx, store, employees, "Seeding")
+	fmt.Fprintf(stdout, "Added %d employees to %s\n", added, *storageName)
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d employees were not added, first: %w", len(employees)-added, len(employees), errs[0])
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// displaySelfServiceMenu displays the menu of the self-service mode
+func displaySelfServiceMenu() {
+	fmt.Println("\n========== Employee Self-Service ==========")
+	fmt.Println("1. View My Record")
+	fmt.Println("2. View My Transfer History")
+	fmt.Println("3. View My Emergency Contacts & Dependents")
+	fmt.Println("4. Export My Data")
+	fmt.Println("5. Request Leave")
+	fmt.Println("6. View My Leave")
+	fmt.Println("7. Clock In")
+	fmt.Println("8. Clock Out")
+	fmt.Println("0. Exit")
+	fmt.Println("===========================================")
+}
+
+// showOwnRecord prints the user's own employee record
+func showOwnRecord(manager EmployeeManager, user User) error {
+	employee, err := manager.GetEmployee(user.EmployeeID)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n" + headerText("=== My Record ==="))
+	fmt.Println(employee)
+	return nil
+}
+
+// showOwnTransfers prints the transfers recorded for the user
+func showOwnTransfers(manager EmployeeManager, user User) error {
+	h, ok := capability[interface{ TransferHistory(int) []TransferRecord }](manager)
+	if !ok {
+		return fmt.Errorf("%w: transfer history is not available for this storage", ErrInvalidInput)
+	}
+
+	history := h.TransferHistory(user.EmployeeID)
+	if len(history) == 0 {
+		fmt.Println("\nNo transfers recorded.")
+		return nil
+	}
+	fmt.Println("\n" + headerText("=== My Transfer History ==="))
+	for _, record := range history {
+		fmt.Printf("- %s\n", record)
+	}
+	return nil
+}
+
+// showOwnPersonalData prints the user's emergency contacts and dependents
+func showOwnPersonalData(records *PersonalRecords, user User) error {
+	contacts, err := records.EmergencyContacts(user, user.EmployeeID)
+	if err != nil {
+		return err
+	}
+	dependents, err := records.Dependents(user, user.EmployeeID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nEmergency contacts (%d):\n", len(contacts))
+	for _, c := range contacts {
+		fmt.Printf("- %s (%s) %s %s\n", c.Name, RelationshipToString(c.Relationship), c.Phone, c.Email)
+	}
+	fmt.Printf("\nDependents (%d):\n", len(dependents))
+	for _, d := range dependents {
+		fmt.Printf("- %s (%s) born %s\n", d.Name, RelationshipToString(d.Relationship), formatDate(d.DateOfBirth))
+	}
+	return nil
+}
+
+// exportOwnData writes the user's personal-data export to a file or the console
+func exportOwnData(records *PersonalRecords, user User, reader *bufio.Reader) error {
+	path, err := readString(reader, "Export file (leave blank to print): ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return records.ExportPersonalData(user, user.EmployeeID, os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := records.ExportPersonalData(user, user.EmployeeID, file); err != nil {
+		return err
+	}
+	fmt.Printf("\nYour data was exported to %s\n", path)
+	return nil
+}
+
+// requestOwnLeave asks for leave for the user, to be decided by an approver
+// unless the approval policy grants it at once
+func requestOwnLeave(leave *Leave, manager EmployeeManager, user User, reader *bufio.Reader) error {
+	start, end, halfStart, halfEnd, err := readLeaveDays(reader)
+	if err != nil {
+		return err
+	}
+	note, err := readString(reader, "Note (optional): ")
+	if err != nil {
+		return err
+	}
+	r, err := leave.Request(user, user.EmployeeID, start, end, halfStart, halfEnd, note)
+	if err != nil {
+		return err
+	}
+	appNotifications.Publish(leaveNotification(r, manager))
+	fmt.Println("\n" + successText(r.requested()))
+	return nil
+}
+
+// showOwnLeave prints the user's leave requests and the days they have left
+func showOwnLeave(leave *Leave, manager EmployeeManager, user User) error {
+	list, account, err := leave.ForEmployee(user, user.EmployeeID)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n" + headerText("=== My Leave ==="))
+	writeLeaveRequests(os.Stdout, list, manager, nil)
+	writeLeaveAccount(os.Stdout, account)
+	return nil
+}
+
+// clockOwn clocks the user in or out at the current time
+func clockOwn(clock *TimeClock, manager EmployeeManager, user User, in bool) error {
+	now := clockOf(manager).Now()
+	if in {
+		if _, err := clock.ClockIn(user, user.EmployeeID, now); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Clocked in at %s", formatDateTime(now))))
+		return nil
+	}
+	t, err := clock.ClockOut(user, user.EmployeeID, now)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n" + successText(fmt.Sprintf("Clocked out at %s after %.2f hours", formatDateTime(now), t.Hours())))
+	return nil
+}
+
+// runSelfService runs the menu for employees, who can view and export their
+// own record, ask for leave and clock in and out. Leave requests and time
+// entries are the only changes this mode can make.
+func runSelfService(manager EmployeeManager, records *PersonalRecords, leave *Leave, clock *TimeClock, user User, reader *bufio.Reader) {
+	employee, err := manager.GetEmployee(user.EmployeeID)
+	if err != nil {
+		fmt.Printf("%s no employee record with ID %d: %v\n", errorText("Error:"), user.EmployeeID, err)
+		return
+	}
+	fmt.Printf("Welcome to Employee Self-Service, %s!\n", employee.Name)
+
+	for {
+		displaySelfServiceMenu()
+
+		choice, err := readInt(reader, "Enter your choice: ")
+		if errors.Is(err, io.EOF) {
+			return
+		} else if err != nil {
+			fmt.Println(errorText("Error:"), err)
+			continue
+		}
+
+		switch choice {
+		case 1:
+			err = showOwnRecord(manager, user)
+		case 2:
+			err = showOwnTransfers(manager, user)
+		case 3:
+			err = showOwnPersonalData(records, user)
+		case 4:
+			err = exportOwnData(records, user, reader)
+		case 5:
+			err = requestOwnLeave(leave, manager, user, reader)
+		case 6:
+			err = showOwnLeave(leave, manager, user)
+		case 7, 8:
+			err = clockOwn(clock, manager, user, choice == 7)
+		case 0:
+			fmt.Println("\nGoodbye!")
+			return
+		default:
+			err = fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+		}
+
+		if errors.Is(err, ErrCancelled) {
+			fmt.Println("\n" + warningText("Operation cancelled."))
+		} else if err != nil {
+			fmt.Println(errorText("Error:"), err)
+		}
+	}
+}
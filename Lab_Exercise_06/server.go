@@ -0,0 +1,552 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// employeeJSON is the JSON representation of an employee in the HTTP API
+type employeeJSON struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Position     string  `json:"position"`
+	Salary       float64 `json:"salary"`
+	Department   string  `json:"department"`
+	JoinDate     string  `json:"join_date"`
+	BirthDate    string  `json:"birth_date,omitempty"`
+	ProbationEnd string  `json:"probation_end,omitempty"`
+	Location     string  `json:"location,omitempty"`
+	WorkMode     string  `json:"work_mode,omitempty"` // on-site if empty
+	TimeZone     string  `json:"time_zone,omitempty"`
+	// Computed holds the computed fields, by name. It is ignored when given.
+	Computed map[string]interface{} `json:"computed,omitempty"`
+	// CostCenters splits the salary between cost centers. It is ignored when given.
+	CostCenters []allocationJSON `json:"cost_centers,omitempty"`
+	// LegalEntity is the code of the entity whose ID range the employee is
+	// in. It is ignored when given.
+	LegalEntity string `json:"legal_entity,omitempty"`
+}
+
+// toEmployeeJSON converts an employee to its API representation
+func toEmployeeJSON(e *Employee) employeeJSON {
+	out := employeeJSON{
+		ID:         e.ID,
+		Name:       e.Name,
+		Position:   e.Position,
+		Salary:     e.Salary,
+		Department: e.Department.String(),
+		JoinDate:   formatDate(e.JoinDate),
+	}
+	if !e.BirthDate.IsZero() {
+		out.BirthDate = formatDate(e.BirthDate)
+	}
+	if !e.ProbationEnd.IsZero() {
+		out.ProbationEnd = formatDate(e.ProbationEnd)
+	}
+	out.Location = e.Location
+	out.WorkMode = strings.ToLower(WorkModeToString(e.WorkMode))
+	out.TimeZone = e.TimeZone
+	out.Computed = computedValues(e, appClock.Now())
+	out.CostCenters = allocationsJSON(e)
+	out.LegalEntity = entityCode(e.ID)
+	return out
+}
+
+// fromEmployeeJSON converts an employee from its API representation
+func fromEmployeeJSON(in employeeJSON) (*Employee, error) {
+	dept, err := ParseDepartment(in.Department)
+	if err != nil {
+		return nil, fieldError("department", ReasonUnknownDepartment, "unknown department %q", in.Department)
+	}
+	e := &Employee{ID: in.ID, Name: in.Name, Position: in.Position, Salary: in.Salary, Department: dept,
+		Location: in.Location, TimeZone: in.TimeZone}
+	if in.WorkMode != "" {
+		if e.WorkMode, err = StringToWorkMode(in.WorkMode); err != nil {
+			return nil, fieldError("work_mode", ReasonUnknownWorkMode, "unknown work mode %q", in.WorkMode)
+		}
+	}
+	dates := []struct {
+		field string
+		value string
+		dst   *time.Time
+	}{
+		{"join_date", in.JoinDate, &e.JoinDate},
+		{"birth_date", in.BirthDate, &e.BirthDate},
+		{"probation_end", in.ProbationEnd, &e.ProbationEnd},
+	}
+	for _, d := range dates {
+		if d.value == "" && d.field != "join_date" {
+			continue
+		}
+		if *d.dst, err = parseDate(d.value); err != nil {
+			return nil, fieldError(d.field, ReasonInvalidDate, "%s must be a date in YYYY-MM-DD format", d.field)
+		}
+	}
+	return e, nil
+}
+
+// maxRequestBody is the largest request body the API reads
+const maxRequestBody = 1 << 20
+
+// decodeEmployee reads an employee from a request body
+func decodeEmployee(w http.ResponseWriter, r *http.Request) (*Employee, error) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	var in employeeJSON
+	if err := dec.Decode(&in); err != nil {
+		return nil, decodeError(err)
+	}
+	return fromEmployeeJSON(in)
+}
+
+// reminderJSON is the JSON representation of a reminder in the HTTP API
+type reminderJSON struct {
+	EmployeeID int    `json:"employee_id"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Date       string `json:"date"`
+	Years      int    `json:"years"`
+}
+
+// Server exposes the employee manager over HTTP
+type Server struct {
+	manager     EmployeeManager
+	mux         *http.ServeMux
+	summary     *SummaryCache
+	idempotency *IdempotencyKeys
+	updates     sync.Mutex   // serializes updates that read the employee first
+	scheduler   *Scheduler   // set by EnableJobs
+	handler     http.Handler // the mux, wrapped by EnableTracing
+	apiKeys     APIKeys      // set by EnableAuthentication
+}
+
+// NewServer creates a Server with all API routes registered
+func NewServer(manager EmployeeManager) *Server {
+	s := &Server{
+		manager:     manager,
+		mux:         http.NewServeMux(),
+		summary:     NewSummaryCache(manager),
+		idempotency: NewIdempotencyKeys(clockOf(manager), idempotencyTTL),
+	}
+	s.handler = s.mux
+	s.handle("GET /employees", staffOnly, s.handleListEmployees)
+	s.handle("POST /employees", staffOnly, s.idempotency.Wrap(s.handleAddEmployee))
+	s.handle("GET /employees/{id}", ownOrStaff, s.handleGetEmployee)
+	s.handle("GET /employees/compare", staffOnly, s.handleCompare)
+	s.handle("PUT /employees/{id}", staffOnly, s.idempotency.Wrap(s.handleUpdateEmployee))
+	s.handle("PATCH /employees/{id}", staffOnly, s.idempotency.Wrap(s.handlePatchEmployee))
+	s.handle("GET /employees/{id}/badge", ownOrStaff, s.handleBadge)
+	s.handle("GET /reminders", staffOnly, s.handleReminders)
+	s.handle("GET /reports/summary", staffOnly, s.handleSummaryReport)
+	s.handle("GET /reports/changes", staffOnly, s.handleChangesReport)
+	s.handle("GET /dashboard", staffOnly, s.handleDashboard)
+	// Load balancers check health without a key
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	return s
+}
+
+// handle registers a route only authenticated users reach, and only if the
+// access rule allows them
+func (s *Server) handle(pattern string, allowed access, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Add("WWW-Authenticate", "Bearer")
+			w.Header().Add("WWW-Authenticate", `Basic realm="Employee Management System"`)
+			writeError(w, r, err)
+			return
+		}
+		if err := allowed(user, r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// EnableProfiling serves the runtime profiles of net/http/pprof under
+// /debug/pprof/. The profiles expose internals of the process, so only enable
+// this where the API is not reachable by untrusted clients.
+func (s *Server) EnableProfiling() {
+	s.handle("GET /debug/pprof/", staffOnly, pprof.Index)
+	s.handle("GET /debug/pprof/cmdline", staffOnly, pprof.Cmdline)
+	s.handle("GET /debug/pprof/profile", staffOnly, pprof.Profile)
+	s.handle("GET /debug/pprof/symbol", staffOnly, pprof.Symbol)
+	s.handle("GET /debug/pprof/trace", staffOnly, pprof.Trace)
+}
+
+// EnableAuthentication lets clients say who they are with API keys. Every
+// route but the health checks needs one, so without keys they are all refused.
+func (s *Server) EnableAuthentication(keys APIKeys) {
+	s.apiKeys = keys
+}
+
+// EnableTracing starts a span for every request, continuing the caller's trace
+// if the request carries one. Manager operations made while handling the
+// request become its children when the manager is a TracingManager.
+func (s *Server) EnableTracing(tracer Tracer) {
+	s.handler = traceRequests(tracer, s.mux)
+}
+
+// EnableTelemetry counts the routes requests are served by in the usage
+// report. It goes after EnableTracing, which replaces the handler.
+func (s *Server) EnableTelemetry(t *Telemetry) {
+	s.handler = countRequests(t, s.mux, s.handler)
+}
+
+// managerFor returns the manager to use while handling a request, so its
+// operations are traced as part of the request
+func (s *Server) managerFor(r *http.Request) EmployeeManager {
+	if t, ok := s.manager.(*TracingManager); ok {
+		return t.WithContext(r.Context())
+	}
+	return s.manager
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleListEmployees lists the employees matching the query's filters, ordered
+// by the sort parameter (id by default, or -salary and the like for descending
+// order). With a limit or cursor parameter it
+// returns one page, and links to the next page while there are more. The
+// fields parameter selects which fields of each employee are returned.
+func (s *Server) handleListEmployees(w http.ResponseWriter, r *http.Request) {
+	manager := s.managerFor(r)
+	match, err := ParseEmployeeQuery(r.URL.Query(), clockOf(manager).Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	listSort, err := ParseListSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePageRequest(r.URL.Query(), listSort)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	fields, err := parseFieldSelection(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	employees, err := employeeValues(manager)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	listSort.SortValues(employees)
+
+	out := make([]interface{}, 0, len(employees))
+	versions := make([][2]int, 0, len(employees))
+	last := -1
+	for i := range employees {
+		if !match(&employees[i]) || (page.After != nil && !listSort.Less(page.After, &employees[i])) {
+			continue
+		}
+		if page.Paged && len(out) == page.Limit {
+			setNextPageLink(w, r, listSort, &employees[last])
+			break
+		}
+		out = append(out, selectFields(toEmployeeJSON(&employees[i]), fields))
+		versions = append(versions, [2]int{employees[i].ID, employees[i].Version})
+		last = i
+	}
+	writeCacheableJSON(w, r, out, etagOf(r.URL.RawQuery, versions, w.Header().Get("Next-Cursor")))
+}
+
+// handleGetEmployee returns an employee, with only the fields the fields
+// parameter selects
+func (s *Server) handleGetEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, ErrInvalidID)
+		return
+	}
+	fields, err := parseFieldSelection(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	employee, err := employeeValue(s.managerFor(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCacheableJSON(w, r, selectFields(toEmployeeJSON(&employee), fields), employeeETag(&employee, fields))
+}
+
+// handleAddEmployee adds the employee in the body, assigning an ID unless it has one
+func (s *Server) handleAddEmployee(w http.ResponseWriter, r *http.Request) {
+	employee, err := decodeEmployee(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := s.managerFor(r).AddEmployee(employee); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := toEmployeeJSON(employee)
+	w.Header().Set("Location", fmt.Sprintf("/employees/%d", employee.ID))
+	w.Header().Set("ETag", employeeETag(employee, nil))
+	writeJSON(w, http.StatusCreated, out)
+}
+
+// handleUpdateEmployee replaces an employee with the one in the body
+func (s *Server) handleUpdateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, ErrInvalidID)
+		return
+	}
+	employee, err := decodeEmployee(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if employee.ID != 0 && employee.ID != id {
+		writeError(w, r, fieldError("id", ReasonImmutable, "the body is for employee %d, not %d", employee.ID, id))
+		return
+	}
+	employee.ID = id
+	s.updateEmployee(w, r, id, func(*Employee) (*Employee, error) { return employee, nil })
+}
+
+// updateEmployee updates an employee to the result of change and responds
+// with it. With If-Match, the update is refused with 412 if the employee has
+// changed since the client read it.
+func (s *Server) updateEmployee(w http.ResponseWriter, r *http.Request, id int, change func(current *Employee) (*Employee, error)) {
+	// Hold off other updates between reading the employee and updating it
+	s.updates.Lock()
+	defer s.updates.Unlock()
+	manager := s.managerFor(r)
+	if !checkIfMatch(w, r, manager, id) {
+		return
+	}
+	current, err := employeeValue(manager, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	employee, err := change(&current)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := manager.UpdateEmployee(employee); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := toEmployeeJSON(employee)
+	w.Header().Set("ETag", employeeETag(employee, nil))
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleReminders(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, ErrInvalidInput)
+			return
+		}
+		days = n
+	}
+
+	manager := s.managerFor(r)
+	reminders, err := UpcomingReminders(manager, clockOf(manager).Now().In(userLocation), days)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	out := make([]reminderJSON, 0, len(reminders))
+	for _, rem := range reminders {
+		kind := "work_anniversary"
+		if rem.Kind == ReminderBirthday {
+			kind = "birthday"
+		}
+		out = append(out, reminderJSON{
+			EmployeeID: rem.EmployeeID,
+			Name:       rem.Name,
+			Kind:       kind,
+			Date:       formatDate(rem.Date),
+			Years:      rem.Years,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// jobStatusJSON is the JSON representation of a scheduled job in the HTTP API
+type jobStatusJSON struct {
+	Name         string `json:"name"`
+	Schedule     string `json:"schedule"`
+	NextRun      string `json:"next_run"`
+	LastRun      string `json:"last_run,omitempty"`
+	LastDuration string `json:"last_duration,omitempty"`
+	LastError    string `json:"last_error,omitempty"`
+	Runs         int    `json:"runs"`
+	Failures     int    `json:"failures"`
+}
+
+// EnableJobs serves the status of the scheduler's jobs at /jobs and includes
+// the scheduler in the readiness check
+func (s *Server) EnableJobs(scheduler *Scheduler) {
+	s.scheduler = scheduler
+	s.handle("GET /jobs", staffOnly, func(w http.ResponseWriter, r *http.Request) {
+		statuses := scheduler.Status()
+		out := make([]jobStatusJSON, 0, len(statuses))
+		for _, st := range statuses {
+			j := jobStatusJSON{
+				Name:      st.Name,
+				Schedule:  st.Schedule,
+				NextRun:   st.NextRun.Format(time.RFC3339),
+				LastError: st.LastError,
+				Runs:      st.Runs,
+				Failures:  st.Failures,
+			}
+			if !st.LastRun.IsZero() {
+				j.LastRun = st.LastRun.Format(time.RFC3339)
+				j.LastDuration = st.LastDuration.String()
+			}
+			out = append(out, j)
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
+
+// EnableNotifications serves users' own notification settings at
+// /notifications/settings/{user}, to read, replace with PUT, delete, and test
+// by posting to their test resource. Users may only reach their own.
+func (s *Server) EnableNotifications(notifications *Notifications) {
+	s.handle("GET /notifications/settings/{user}", ownNotifications, func(w http.ResponseWriter, r *http.Request) {
+		user := r.PathValue("user")
+		settings, ok := notifications.Settings(user)
+		if !ok {
+			writeError(w, r, ErrNoNotificationSettings.WithValue(user))
+			return
+		}
+		writeJSON(w, http.StatusOK, settings)
+	})
+	s.handle("PUT /notifications/settings/{user}", ownNotifications, func(w http.ResponseWriter, r *http.Request) {
+		user := r.PathValue("user")
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		dec.DisallowUnknownFields()
+		var settings NotificationSettings
+		if err := dec.Decode(&settings); err != nil {
+			writeError(w, r, decodeError(err))
+			return
+		}
+		if settings.User != "" && settings.User != user {
+			writeError(w, r, fieldError("user", ReasonImmutable, "the body is for %s, not %s", settings.User, user))
+			return
+		}
+		settings.User = user
+		if err := notifications.Set(settings); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		settings, _ = notifications.Settings(user)
+		writeJSON(w, http.StatusOK, settings)
+	})
+	s.handle("DELETE /notifications/settings/{user}", ownNotifications, func(w http.ResponseWriter, r *http.Request) {
+		user := r.PathValue("user")
+		if err := notifications.Remove(user); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle("POST /notifications/settings/{user}/test", ownNotifications, func(w http.ResponseWriter, r *http.Request) {
+		user := r.PathValue("user")
+		if errs := notifications.Test(user, clockOf(s.manager).Now()); len(errs) > 0 {
+			writeError(w, r, errors.Join(errs...))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// schedulerTick is how often the scheduler checks for due jobs
+const schedulerTick = time.Minute
+
+// shutdownTimeout is how long requests in progress get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// runServer serves the HTTP API on addr, optionally with profiling and
+// tracing, and runs the recurring jobs, which alert on the documents
+// expiring. Nightly snapshots are written to snapshotDir if it is set.
+func runServer(addr string, manager EmployeeManager, documents *Documents, leave *Leave, clock *TimeClock, profiling bool, snapshotDir string, tracer Tracer) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := NewServer(manager)
+	if profiling {
+		server.EnableProfiling()
+		log.Printf("Profiling enabled at http://%s/debug/pprof/", addr)
+	}
+
+	logger := log.Default()
+	scheduler := NewScheduler(clockOf(manager), logger)
+	if err := registerStandardJobs(scheduler, manager, documents, leave, clock, server.summary, snapshotDir, logger); err != nil {
+		return err
+	}
+	server.EnableJobs(scheduler)
+	server.EnableAuthentication(appAPIKeys)
+	server.EnableNotifications(appNotifications)
+	if tracer != nil {
+		server.EnableTracing(tracer)
+	}
+	if appTelemetry != nil {
+		server.EnableTelemetry(appTelemetry)
+		if err := scheduler.Register("telemetry", DailyAt{Hour: 3}, telemetryJob(appTelemetry)); err != nil {
+			return err
+		}
+	}
+	go scheduler.Run(ctx, schedulerTick)
+
+	// On Ctrl-C or SIGTERM, finish the requests in progress and stop the jobs
+	// before the stores are closed
+	httpServer := &http.Server{Addr: addr, Handler: server}
+	appShutdown.OnStop(func() error {
+		cancel()
+		ctx, done := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer done()
+		return httpServer.Shutdown(ctx)
+	})
+
+	log.Printf("Serving employee API on %s (dashboard at http://%s/dashboard)", addr, addr)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// Shutting down; appShutdown exits once the cleanup is done
+	select {}
+}
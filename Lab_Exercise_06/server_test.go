@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testAPIKeys are the users the server tests make requests as: ann is in HR,
+// bob a manager and cat the employee with record 1. Each one's key is the
+// name followed by testKeySuffix.
+var testAPIKeys, _ = ParseAPIKeys([]byte(`[
+	{"key": "ann-0123456789abcdef0123456789abcdef", "user": "ann", "role": "hr"},
+	{"key": "bob-0123456789abcdef0123456789abcdef", "user": "bob", "role": "manager"},
+	{"key": "cat-0123456789abcdef0123456789abcdef", "user": "cat", "role": "employee", "employee_id": 1}
+]`))
+
+const testKeySuffix = "-0123456789abcdef0123456789abcdef"
+
+// newTestServer returns a server that accepts testAPIKeys
+func newTestServer(manager EmployeeManager) *Server {
+	server := NewServer(manager)
+	server.EnableAuthentication(testAPIKeys)
+	return server
+}
+
+// as makes a request carry the API key of a test user, or none for ""
+func as(user string, r *http.Request) *http.Request {
+	if user != "" {
+		r.Header.Set("Authorization", "Bearer "+user+testKeySuffix)
+	}
+	return r
+}
+
+func TestServerReportsStorageErrors(t *testing.T) {
+	manager := NewFakeManager()
+	manager.FailNext("AddEmployee", errors.New("connection reset"))
+	body := `{"name":"Ann","position":"Engineer","salary":70000,"department":"Engineering","join_date":"2023-05-01"}`
+
+	for _, want := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		w := httptest.NewRecorder()
+		newTestServer(manager).ServeHTTP(w, as("ann", httptest.NewRequest(http.MethodPost, "/employees", strings.NewReader(body))))
+		if w.Code != want {
+			t.Errorf("POST /employees returned %d, want %d: %s", w.Code, want, w.Body)
+		}
+	}
+	if calls := manager.CallsTo("AddEmployee"); len(calls) != 2 {
+		t.Errorf("AddEmployee was called %d time(s), want 2: %v", len(calls), manager.Calls())
+	}
+	if employees, _ := manager.ListEmployees(); len(employees) != 1 {
+		t.Errorf("%d employee(s) were stored, want 1", len(employees))
+	}
+}
+
+func TestAPIRequiresKeyAndRole(t *testing.T) {
+	server := newTestServer(NewFakeManager(benchEmployee(1), benchEmployee(2)))
+	update := `{"name":"Employee 1","position":"Lead","salary":90000,"department":"Engineering","join_date":"2015-01-02"}`
+
+	for _, c := range []struct {
+		method, target, user string
+		want                 int
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/employees", "", http.StatusUnauthorized},
+		{http.MethodGet, "/employees", "eve", http.StatusUnauthorized},
+		{http.MethodGet, "/employees", "ann", http.StatusOK},
+		{http.MethodGet, "/employees", "cat", http.StatusForbidden},
+		{http.MethodGet, "/employees/2", "bob", http.StatusOK},
+		{http.MethodGet, "/employees/1", "cat", http.StatusOK},
+		{http.MethodGet, "/employees/2", "cat", http.StatusForbidden},
+		{http.MethodGet, "/employees/2/badge", "cat", http.StatusForbidden},
+		{http.MethodPut, "/employees/1", "cat", http.StatusForbidden},
+		{http.MethodPatch, "/employees/1", "cat", http.StatusForbidden},
+		{http.MethodPost, "/employees", "cat", http.StatusForbidden},
+		{http.MethodGet, "/reports/summary", "", http.StatusUnauthorized},
+		{http.MethodGet, "/reports/summary", "cat", http.StatusForbidden},
+		{http.MethodGet, "/dashboard", "cat", http.StatusForbidden},
+		{http.MethodPut, "/employees/1", "ann", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, as(c.user, httptest.NewRequest(c.method, c.target, strings.NewReader(update))))
+		if w.Code != c.want {
+			t.Errorf("%s %s as %q returned %d, want %d: %s", c.method, c.target, c.user, w.Code, c.want, w.Body)
+		}
+	}
+
+	// Browsers opening the dashboard send the key as a basic password
+	r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	r.SetBasicAuth("ann", "ann"+testKeySuffix)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /dashboard with basic authentication returned %d, want 200", w.Code)
+	}
+}
+
+func TestNotificationSettingsAreOwnOnly(t *testing.T) {
+	notifications := NewNotificationsInMemory()
+	if err := notifications.Set(NotificationSettings{User: "bob", Channels: []string{"https://hooks.example.com/bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(NewFakeManager())
+	server.EnableNotifications(notifications)
+
+	for _, c := range []struct {
+		method, target, user string
+		want                 int
+	}{
+		{http.MethodGet, "/notifications/settings", "ann", http.StatusNotFound},
+		{http.MethodGet, "/notifications/settings/bob", "", http.StatusUnauthorized},
+		{http.MethodGet, "/notifications/settings/bob", "eve", http.StatusUnauthorized},
+		{http.MethodGet, "/notifications/settings/bob", "ann", http.StatusForbidden},
+		{http.MethodDelete, "/notifications/settings/bob", "ann", http.StatusForbidden},
+		{http.MethodPost, "/notifications/settings/bob/test", "", http.StatusUnauthorized},
+		{http.MethodPost, "/notifications/settings/bob/test", "ann", http.StatusForbidden},
+		{http.MethodGet, "/notifications/settings/bob", "bob", http.StatusOK},
+		{http.MethodGet, "/notifications/settings/ann", "ann", http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, as(c.user, httptest.NewRequest(c.method, c.target, nil)))
+		if w.Code != c.want {
+			t.Errorf("%s %s as %q returned %d, want %d: %s", c.method, c.target, c.user, w.Code, c.want, w.Body)
+		}
+	}
+	if _, ok := notifications.Settings("bob"); !ok {
+		t.Error("another user removed bob's settings")
+	}
+
+	// Roles without the permission are refused their own settings too
+	saved := rolePermissions
+	t.Cleanup(func() { rolePermissions = saved })
+	rolePermissions = map[int][]int{RoleManager: {PermApproveChanges}}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, as("bob", httptest.NewRequest(http.MethodGet, "/notifications/settings/bob", nil)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("GET own settings without the permission returned %d, want 403", w.Code)
+	}
+}
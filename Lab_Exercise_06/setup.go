@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// defaultProbationMonths is the probation the setup wizard suggests for new positions
+const defaultProbationMonths = 3
+
+// defaultSystemConfigPath is where the setup wizard writes the deployment's
+// configuration and main reads it from: system.yaml beside the users'
+// preferences
+func defaultSystemConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "employee-management", "system.yaml"), nil
+}
+
+// loadSystemConfig loads the system configuration at path, or at the default
+// path if path is empty, and makes it current. It returns no configuration
+// if path is off, or if nothing has been set up at the default path.
+func loadSystemConfig(path string) (*ConfigBundle, *RuleSet, error) {
+	if path == preferencesOff {
+		return nil, nil, nil
+	}
+	if path == "" {
+		var err error
+		if path, err = defaultSystemConfigPath(); err != nil {
+			return nil, nil, nil
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+	}
+	return LoadConfigBundle(path)
+}
+
+// systemStorage returns the backend and DSN a command opens: those given by
+// -storage and -dsn, or the system configuration's storage when neither is
+// given, or memory
+func systemStorage(system *ConfigBundle, backend, dsn string) (string, string) {
+	if backend != "" {
+		return backend, dsn
+	}
+	if system != nil && system.Storage != nil && dsn == "" {
+		return system.Storage.Backend, system.Storage.DSN
+	}
+	return "memory", dsn
+}
+
+// commandStorage loads the system configuration at path, as loadSystemConfig
+// does, and returns the backend and DSN a command opens
+func commandStorage(path, backend, dsn string) (string, string, error) {
+	system, _, err := loadSystemConfig(path)
+	if err != nil {
+		return "", "", err
+	}
+	backend, dsn = systemStorage(system, backend, dsn)
+	return backend, dsn, nil
+}
+
+// WriteSystemConfig writes a configuration bundle to path, creating its
+// directory if needed
+func WriteSystemConfig(path string, b *ConfigBundle) error {
+	data, err := marshalYAML(b)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf("# System configuration, written by setup on %s. Flags such as -storage and -positions override it.\n",
+		appClock.Now().In(userLocation).Format("2006-01-02 15:04 MST"))
+	data = append([]byte(header), data...)
+	return appShutdown.Guard(func() error {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	})
+}
+
+// SetupWizard walks an admin through setting up a deployment: the storage
+// backend, the payroll currency, the departments in use, the positions of
+// each with their salary bands, and the admin account. admin is the
+// account suggested.
+func SetupWizard(reader *bufio.Reader, admin string) (*ConfigBundle, error) {
+	fmt.Println("\n" + headerText("=== Setup ==="))
+	fmt.Printf("Press Enter to take the suggestion in brackets, or enter %s to abort.\n", cancelToken)
+	b := &ConfigBundle{Version: configBundleVersion, Roles: RoleGrants()}
+
+	fmt.Println("\n" + headerText("Storage"))
+	backends := StorageBackends()
+	fmt.Printf("Backends: %s\n", strings.Join(backends, ", "))
+	backend, err := readValue(reader, "Storage backend [memory]: ", func(input string) (string, error) {
+		if input == "" {
+			return "memory", nil
+		}
+		if !slices.Contains(backends, input) {
+			return "", fmt.Errorf("%w: unknown storage backend %q", ErrInvalidInput, input)
+		}
+		return input, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.Storage = &StorageSettings{Backend: backend}
+	if backend != "memory" {
+		// Opening the store now finds a wrong path or URL while it can still be corrected
+		if b.Storage.DSN, err = readValue(reader, "Connection string, such as a file path or database URL: ", func(input string) (string, error) {
+			store, err := OpenStorage(backend, input, appClock)
+			if err != nil {
+				return "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+			}
+			return input, store.Close()
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Println("\n" + headerText("Payroll"))
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	currency, err := readValue(reader, fmt.Sprintf("Currency (%s) [%s]: ", strings.Join(codes, ", "), payrollConfig.Currency), func(input string) (Currency, error) {
+		if input == "" {
+			input = payrollConfig.Currency
+		}
+		return ParseCurrency(input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.Payroll = []byte(fmt.Sprintf(`{"currency": %q}`, currency.Code))
+
+	fmt.Println("\n" + headerText("Departments"))
+	var names []string
+	for _, d := range AllDepartments() {
+		names = append(names, d.String())
+	}
+	if b.Departments, err = readValue(reader, fmt.Sprintf("Departments in use, separated by commas [%s]: ", strings.Join(names, ", ")), func(input string) ([]Department, error) {
+		if input == "" {
+			return AllDepartments(), nil
+		}
+		var departments []Department
+		for _, name := range splitList(input) {
+			d, err := ParseDepartment(name)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(departments, d) {
+				departments = append(departments, d)
+			}
+		}
+		if len(departments) == 0 {
+			return nil, fmt.Errorf("%w: choose at least one department", ErrInvalidInput)
+		}
+		return departments, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("\n" + headerText("Positions and salary bands"))
+	b.Positions = []PositionTemplate{}
+	for _, d := range b.Departments {
+		if err := setupPositions(reader, b, d); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Println("\n" + headerText("Admin account"))
+	if b.Admin, err = readValue(reader, fmt.Sprintf("User name of the admin [%s]: ", admin), func(input string) (string, error) {
+		if input == "" {
+			input = admin
+		}
+		if input == "" {
+			return "", fmt.Errorf("%w: the admin account needs a user name", ErrInvalidInput)
+		}
+		return input, nil
+	}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// setupPositions asks for the positions of a department, offering the
+// standard ones first
+func setupPositions(reader *bufio.Reader, b *ConfigBundle, d Department) error {
+	fmt.Printf("\n%s\n", d)
+	var standard []PositionTemplate
+	for _, t := range positionTemplates {
+		if t.Department == d {
+			standard = append(standard, t)
+			fmt.Printf("  %s\n", t)
+		}
+	}
+	if len(standard) > 0 {
+		answer, err := readString(reader, "Keep these positions? (y/n) [y]: ")
+		if err != nil {
+			return err
+		}
+		if answer == "" || strings.ToLower(answer) == "y" {
+			b.Positions = append(b.Positions, standard...)
+		}
+	}
+
+	for {
+		position, err := readValue(reader, fmt.Sprintf("Add a position to %s (blank when done): ", d), func(input string) (string, error) {
+			for _, t := range b.Positions {
+				if strings.EqualFold(t.Position, input) {
+					return "", fmt.Errorf("%w: position %q is already set up", ErrInvalidInput, t.Position)
+				}
+			}
+			return input, nil
+		})
+		if err != nil || position == "" {
+			return err
+		}
+		t := PositionTemplate{Position: position, Department: d}
+		if t.MinSalary, err = readValue(reader, "  Lowest salary of the band: ", func(input string) (float64, error) {
+			salary, err := parseFloat(input)
+			if err == nil && salary <= 0 {
+				err = fmt.Errorf("%w: the salary must be positive", ErrInvalidInput)
+			}
+			return salary, err
+		}); err != nil {
+			return err
+		}
+		if t.MaxSalary, err = readValue(reader, "  Highest salary of the band: ", func(input string) (float64, error) {
+			salary, err := parseFloat(input)
+			if err == nil && salary < t.MinSalary {
+				err = fmt.Errorf("%w: the highest salary must be at least %.2f", ErrInvalidInput, t.MinSalary)
+			}
+			return salary, err
+		}); err != nil {
+			return err
+		}
+		if t.ProbationMonths, err = readValue(reader, fmt.Sprintf("  Probation in months [%d]: ", defaultProbationMonths), func(input string) (int, error) {
+			if input == "" {
+				return defaultProbationMonths, nil
+			}
+			months, err := parseInt(input)
+			if err == nil && (months < 0 || months > 24) {
+				err = fmt.Errorf("%w: probation must be 0 to 24 months", ErrInvalidInput)
+			}
+			return months, err
+		}); err != nil {
+			return err
+		}
+		b.Positions = append(b.Positions, t)
+	}
+}
+
+// writeSetupSummary writes what the setup wizard is about to save
+func writeSetupSummary(w io.Writer, b *ConfigBundle) {
+	storage := b.Storage.Backend
+	if b.Storage.DSN != "" {
+		storage += " at " + b.Storage.DSN
+	}
+	var departments []string
+	for _, d := range b.Departments {
+		departments = append(departments, d.String())
+	}
+	fmt.Fprintln(w, "\n"+headerText("Summary"))
+	fmt.Fprintf(w, "Storage:     %s\n", storage)
+	var payroll PayrollConfig
+	json.Unmarshal(b.Payroll, &payroll)
+	fmt.Fprintf(w, "Currency:    %s\n", payroll.Currency)
+	fmt.Fprintf(w, "Departments: %s\n", strings.Join(departments, ", "))
+	fmt.Fprintf(w, "Positions:   %d\n", len(b.Positions))
+	for _, t := range b.Positions {
+		fmt.Fprintf(w, "  %s\n", t)
+	}
+	fmt.Fprintf(w, "Admin:       %s\n", b.Admin)
+}
+
+// runSetupWizard runs the wizard and writes the configuration to path once
+// the admin confirms it
+func runSetupWizard(reader *bufio.Reader, stdout io.Writer, path, admin string) (bool, error) {
+	b, err := SetupWizard(reader, admin)
+	if err != nil {
+		return false, err
+	}
+	// Check the configuration as it will be read back
+	data, err := marshalYAML(b)
+	if err != nil {
+		return false, err
+	}
+	if _, err := ParseConfigBundle(data); err != nil {
+		return false, err
+	}
+
+	writeSetupSummary(stdout, b)
+	answer, err := readString(reader, fmt.Sprintf("\nSave the configuration to %s? (y/n) [y]: ", path))
+	if err != nil {
+		return false, err
+	}
+	if answer != "" && strings.ToLower(answer) != "y" {
+		fmt.Fprintln(stdout, "Setup abandoned; nothing was saved.")
+		return false, nil
+	}
+	if err := WriteSystemConfig(path, b); err != nil {
+		return false, err
+	}
+	fmt.Fprintln(stdout, successText("Saved the system configuration to "+path))
+	return true, nil
+}
+
+// offerSetup asks on the first interactive run whether to set the deployment
+// up, and loads the configuration if it is
+func offerSetup(reader *bufio.Reader, admin string) (*ConfigBundle, *RuleSet, error) {
+	path, err := defaultSystemConfigPath()
+	if err != nil {
+		return nil, nil, nil
+	}
+	fmt.Println(warningText("This deployment has not been set up yet."))
+	answer, err := readString(reader, "Run the setup wizard now? (y/n) [y]: ")
+	if err != nil {
+		return nil, nil, err
+	}
+	if answer != "" && strings.ToLower(answer) != "y" {
+		fmt.Println("Run it later with the setup command, or start with -system-config off to stop being asked.")
+		return nil, nil, nil
+	}
+	saved, err := runSetupWizard(reader, os.Stdout, path, admin)
+	if err != nil || !saved {
+		return nil, nil, err
+	}
+	return LoadConfigBundle(path)
+}
+
+// runSetup runs the setup command, which sets the deployment up whether or
+// not it has been already
+func runSetup(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "file to write the system configuration to (default system.yaml in the user's config directory)")
+	force := fs.Bool("force", false, "replace a system configuration that already exists")
+	admin := fs.String("admin", os.Getenv("USER"), "admin account to suggest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	path := *output
+	if path == "" {
+		var err error
+		if path, err = defaultSystemConfigPath(); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		return fmt.Errorf("%w: %s is already set up; use -force to set it up again", errorsx.ErrConflict, path)
+	}
+	_, err := runSetupWizard(bufio.NewReader(stdin), stdout, path, *admin)
+	return err
+}
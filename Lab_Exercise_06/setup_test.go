@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestSystemStorage(t *testing.T) {
+	system := &ConfigBundle{Storage: &StorageSettings{Backend: "file", DSN: "employees.json"}}
+	tests := []struct {
+		system       *ConfigBundle
+		backend, dsn string
+		want         [2]string
+	}{
+		{nil, "", "", [2]string{"memory", ""}},
+		{system, "", "", [2]string{"file", "employees.json"}},
+		{system, "memory", "", [2]string{"memory", ""}},
+		{system, "file", "other.json", [2]string{"file", "other.json"}},
+		{system, "", "other.json", [2]string{"memory", "other.json"}},
+	}
+	for _, tt := range tests {
+		backend, dsn := systemStorage(tt.system, tt.backend, tt.dsn)
+		if got := [2]string{backend, dsn}; got != tt.want {
+			t.Errorf("storage %q, dsn %q gave %v, want %v", tt.backend, tt.dsn, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// employeeShard holds the employees whose IDs map to it
+type employeeShard struct {
+	mu        sync.RWMutex
+	employees map[int]*Employee
+	transfers map[int][]TransferRecord
+}
+
+// ShardedEmployeeManager implements EmployeeManager with employees spread over
+// shards by ID, each with its own lock, so concurrent writes to different
+// employees do not wait for each other. Reads of all employees visit the
+// shards one at a time, so they are not a single consistent snapshot.
+// Like the columnar store it keeps no change log.
+type ShardedEmployeeManager struct {
+	shards []*employeeShard
+	nextID atomic.Int64
+	events EventBus
+	clock  Clock
+}
+
+// NewShardedEmployeeManager creates a manager with the given number of shards.
+// A count below 1 uses four shards per CPU.
+func NewShardedEmployeeManager(shards int, clock Clock) *ShardedEmployeeManager {
+	if shards < 1 {
+		shards = 4 * runtime.NumCPU()
+	}
+	m := &ShardedEmployeeManager{shards: make([]*employeeShard, shards), clock: clock}
+	for i := range m.shards {
+		m.shards[i] = &employeeShard{
+			employees: make(map[int]*Employee),
+			transfers: make(map[int][]TransferRecord),
+		}
+	}
+	return m
+}
+
+// shard returns the shard holding an employee ID
+func (m *ShardedEmployeeManager) shard(id int) *employeeShard {
+	return m.shards[uint(id)%uint(len(m.shards))]
+}
+
+// Clock returns the clock the manager uses for validation and events
+func (m *ShardedEmployeeManager) Clock() Clock {
+	return m.clock
+}
+
+// Subscribe registers a function that is notified of manager events
+func (m *ShardedEmployeeManager) Subscribe(fn func(Event)) {
+	m.events.Subscribe(fn)
+}
+
+// EventBacklog returns the number of the manager's events still being delivered
+func (m *ShardedEmployeeManager) EventBacklog() int {
+	return m.events.Backlog()
+}
+
+// Close releases the manager's resources. The sharded manager holds none.
+func (m *ShardedEmployeeManager) Close() error {
+	return nil
+}
+
+// publish notifies subscribers of a change
+func (m *ShardedEmployeeManager) publish(c Change) {
+	c.Time = m.clock.Now()
+	m.events.Publish(c.notification())
+}
+
+// AddEmployee adds a new employee
+func (m *ShardedEmployeeManager) AddEmployee(e *Employee) error {
+	if e == nil {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	e.Version = 1
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+
+	if employeeCopy.ID == 0 {
+		// Auto-assign ID if not provided, skipping IDs that were set explicitly
+		for {
+			employeeCopy.ID = int(m.nextID.Add(1))
+			s := m.shard(employeeCopy.ID)
+			s.mu.Lock()
+			if _, exists := s.employees[employeeCopy.ID]; !exists {
+				s.employees[employeeCopy.ID] = &employeeCopy
+				s.mu.Unlock()
+				break
+			}
+			s.mu.Unlock()
+		}
+	} else {
+		s := m.shard(employeeCopy.ID)
+		s.mu.Lock()
+		if _, exists := s.employees[employeeCopy.ID]; exists {
+			s.mu.Unlock()
+			return ErrDuplicateID
+		}
+		s.employees[employeeCopy.ID] = &employeeCopy
+		s.mu.Unlock()
+	}
+
+	e.ID = employeeCopy.ID
+	m.publish(Change{Type: EventEmployeeAdded, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// RemoveEmployee removes an employee by ID
+func (m *ShardedEmployeeManager) RemoveEmployee(id int) error {
+	s := m.shard(id)
+	s.mu.Lock()
+	employee, exists := s.employees[id]
+	if !exists {
+		s.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+	delete(s.employees, id)
+	delete(s.transfers, id)
+	s.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeRemoved, EmployeeID: id, Employee: *employee})
+	return nil
+}
+
+// UpdateEmployee updates an existing employee
+func (m *ShardedEmployeeManager) UpdateEmployee(e *Employee) error {
+	if e == nil || e.ID == 0 {
+		return ErrInvalidInput
+	}
+	if err := validateEmployee(e, m.clock.Now()); err != nil {
+		return err
+	}
+
+	employeeCopy := *e
+	employeeCopy.normalizeTimes()
+
+	s := m.shard(e.ID)
+	s.mu.Lock()
+	existing, exists := s.employees[e.ID]
+	if !exists {
+		s.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+	employeeCopy.Version = existing.Version + 1
+	e.Version = employeeCopy.Version
+	// Store a new record rather than modifying the old one, which readers may be copying
+	s.employees[e.ID] = &employeeCopy
+	s.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeUpdated, EmployeeID: e.ID, Employee: employeeCopy})
+	return nil
+}
+
+// GetEmployee retrieves an employee by ID
+func (m *ShardedEmployeeManager) GetEmployee(id int) (*Employee, error) {
+	employee, err := m.EmployeeValue(id)
+	if err != nil {
+		return nil, err
+	}
+	return &employee, nil
+}
+
+// EmployeeValue retrieves an employee by ID as a value
+func (m *ShardedEmployeeManager) EmployeeValue(id int) (Employee, error) {
+	s := m.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	employee, exists := s.employees[id]
+	if !exists {
+		return Employee{}, ErrEmployeeNotFound
+	}
+	return *employee, nil
+}
+
+// EmployeeValues returns all employees as values, one shard at a time
+func (m *ShardedEmployeeManager) EmployeeValues() []Employee {
+	values := make([]Employee, 0)
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for _, emp := range s.employees {
+			values = append(values, *emp)
+		}
+		s.mu.RUnlock()
+	}
+	return values
+}
+
+// ListEmployees returns a list of all employees
+func (m *ShardedEmployeeManager) ListEmployees() ([]*Employee, error) {
+	values := m.EmployeeValues()
+	employees := make([]*Employee, len(values))
+	for i := range values {
+		employees[i] = &values[i]
+	}
+	return employees, nil
+}
+
+// FilterEmployees returns employees that match the filter criteria
+func (m *ShardedEmployeeManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	values := make([]Employee, 0)
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for _, emp := range s.employees {
+			if filter(emp) {
+				values = append(values, *emp)
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	result := make([]*Employee, len(values))
+	for i := range values {
+		result[i] = &values[i]
+	}
+	return result
+}
+
+// TransferEmployee moves an employee to another department and records the transfer
+func (m *ShardedEmployeeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+
+	s := m.shard(id)
+	s.mu.Lock()
+	employee, exists := s.employees[id]
+	if !exists {
+		s.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+	if employee.Department == newDept {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+	}
+
+	record := TransferRecord{
+		EmployeeID:     id,
+		FromDepartment: employee.Department,
+		ToDepartment:   newDept,
+		EffectiveDate:  effectiveDate.UTC(),
+		RecordedAt:     m.clock.Now(),
+	}
+	transferred := *employee
+	transferred.Department = newDept
+	transferred.Version++
+	s.employees[id] = &transferred
+	s.transfers[id] = append(s.transfers[id], record)
+	s.mu.Unlock()
+
+	m.publish(Change{Type: EventEmployeeTransferred, EmployeeID: id, Employee: transferred, Transfer: &record})
+	return nil
+}
+
+// TransferHistory returns the transfers recorded for an employee, oldest first
+func (m *ShardedEmployeeManager) TransferHistory(id int) []TransferRecord {
+	s := m.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := make([]TransferRecord, len(s.transfers[id]))
+	copy(history, s.transfers[id])
+	return history
+}
+
+func init() {
+	// The DSN is an optional shard count, e.g. -storage sharded -dsn 64
+	RegisterStorage("sharded", func(dsn string, clock Clock) (Storage, error) {
+		shards := 0
+		if dsn != "" {
+			n, err := strconv.Atoi(dsn)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: the sharded backend's -dsn is a shard count such as 64", ErrInvalidInput)
+			}
+			shards = n
+		}
+		return NewShardedEmployeeManager(shards, clock), nil
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Shutdown exits the program cleanly when it is interrupted with Ctrl-C or
+// stopped with SIGTERM. It stops taking new work, waits for writes in
+// progress, runs the registered cleanup, such as saving a snapshot and
+// closing the stores, and exits with the status a shell expects for the
+// signal. While an operation started with interruptContext is running,
+// Ctrl-C cancels that operation instead.
+type Shutdown struct {
+	writes sync.RWMutex // read-locked by writes that must not be cut short
+
+	mu        sync.Mutex
+	stops     []func() error
+	hooks     []func() error
+	operation context.CancelFunc
+	listening bool
+}
+
+// appShutdown handles the signals of the interactive menu and server mode
+var appShutdown = &Shutdown{}
+
+// OnExit registers f to run when the program exits on a signal. Hooks run in
+// the reverse order of registration, like deferred calls.
+func (s *Shutdown) OnExit(f func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, f)
+}
+
+// OnStop registers f to stop work that may start new writes, such as serving
+// requests. Stop functions run before Exit waits for writes in progress.
+func (s *Shutdown) OnStop(f func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stops = append(s.stops, f)
+}
+
+// Guard runs write so that an interrupt waits for it to finish
+func (s *Shutdown) Guard(write func() error) error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+	return write()
+}
+
+// signalExitCode is the status a shell reports for a process killed by sig,
+// 128 plus the signal number
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// Exit runs the stop functions, waits for guarded writes, runs the exit hooks
+// and exits with code
+func (s *Shutdown) Exit(code int) {
+	s.mu.Lock()
+	stops, hooks := s.stops, s.hooks
+	s.mu.Unlock()
+
+	run := func(fs []func() error) {
+		for i := len(fs) - 1; i >= 0; i-- {
+			if err := fs[i](); err != nil {
+				fmt.Fprintln(os.Stderr, errorText("Error:"), err)
+				if code == 0 {
+					code = 1
+				}
+			}
+		}
+	}
+	run(stops)
+	s.writes.Lock() // held until exit, so no new write starts
+	run(hooks)
+	os.Exit(code)
+}
+
+// Listen starts handling SIGINT and SIGTERM in the background. A second
+// signal during cleanup kills the program at once.
+func (s *Shutdown) Listen() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	s.mu.Lock()
+	s.listening = true
+	s.mu.Unlock()
+
+	go func() {
+		for sig := range signals {
+			s.mu.Lock()
+			cancel := s.operation
+			s.mu.Unlock()
+			if cancel != nil && sig == os.Interrupt {
+				cancel()
+				continue
+			}
+
+			signal.Stop(signals)
+			fmt.Fprintf(os.Stderr, "\n%s\n", warningText(fmt.Sprintf("Received %v, saving and exiting...", sig)))
+			s.Exit(signalExitCode(sig))
+		}
+	}()
+}
+
+// startOperation makes Ctrl-C cancel an operation instead of exiting. It
+// reports false when signals are not being handled, such as in commands.
+func (s *Shutdown) startOperation(cancel context.CancelFunc) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.listening {
+		return false
+	}
+	s.operation = cancel
+	return true
+}
+
+// endOperation makes Ctrl-C exit again
+func (s *Shutdown) endOperation() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operation = nil
+}
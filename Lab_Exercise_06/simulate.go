@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// ErrSimulationStale is returned when applying a simulation to employees
+// whose live records changed after the simulation was cloned from them
+var ErrSimulationStale = errorsx.New(errorsx.ErrConflict, "", "live data changed since the simulation started")
+
+// Simulation is a what-if sandbox cloned from live data. Hypothetical raises,
+// transfers, hires and exits are made to the clone, their effect on payroll
+// and budgets is compared with the live data, and the difference can then be
+// applied to the live data in one go.
+type Simulation struct {
+	live     EmployeeManager
+	plan     *BudgetPlan
+	sandbox  *InMemoryEmployeeManager
+	baseline map[int]Employee // the live employees as cloned
+}
+
+// NewSimulation clones the live employees into a new simulation. The
+// department budgets of plan are used to show the budget impact.
+func NewSimulation(live EmployeeManager, plan *BudgetPlan) (*Simulation, error) {
+	employees, err := live.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	s := &Simulation{
+		live:     live,
+		plan:     plan,
+		sandbox:  NewInMemoryEmployeeManagerWithClock(clockOf(live)),
+		baseline: make(map[int]Employee, len(employees)),
+	}
+	for _, e := range employees {
+		clone := *e
+		if err := s.sandbox.AddEmployee(&clone); err != nil {
+			return nil, fmt.Errorf("cloning employee %d: %w", e.ID, err)
+		}
+		s.baseline[e.ID] = *e
+	}
+	return s, nil
+}
+
+// Manager returns the simulated employees, which any change can be made to
+func (s *Simulation) Manager() EmployeeManager {
+	return s.sandbox
+}
+
+// Raise changes the salaries of the simulated employees a predicate matches
+// by a percentage, returning how many it changed
+func (s *Simulation) Raise(match Predicate[*Employee], percent float64) (int, error) {
+	if percent <= -100 {
+		return 0, fmt.Errorf("%w: a raise must be more than -100%%", ErrInvalidInput)
+	}
+	raised := 0
+	for _, e := range s.sandbox.FilterEmployees(match) {
+		e.Salary = roundCents(e.Salary * (1 + percent/100))
+		if err := s.sandbox.UpdateEmployee(e); err != nil {
+			return raised, fmt.Errorf("employee %d: %w", e.ID, err)
+		}
+		raised++
+	}
+	return raised, nil
+}
+
+// Hire adds count simulated new hires to a department
+func (s *Simulation) Hire(dept Department, position string, salary float64, count int) error {
+	if count < 1 {
+		return fmt.Errorf("%w: hire at least one employee", ErrInvalidInput)
+	}
+	now := clockOf(s.sandbox).Now()
+	for i := 1; i <= count; i++ {
+		e := &Employee{
+			Name:       fmt.Sprintf("New %s %d", position, i),
+			Position:   position,
+			Salary:     salary,
+			Department: dept,
+			JoinDate:   now,
+		}
+		if err := s.sandbox.AddEmployee(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundCents rounds an amount to the cent
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// SimulatedTransfer is a live employee the simulation moves to another department
+type SimulatedTransfer struct {
+	Employee *Employee // as simulated
+	From     Department
+}
+
+// SimulationDelta is what applying a simulation would change in the live data
+type SimulationDelta struct {
+	Hires     []*Employee
+	Transfers []SimulatedTransfer
+	Updates   []SyncUpdate // changes other than the department
+	Exits     []*Employee
+}
+
+// Empty reports whether the simulation changes nothing
+func (d *SimulationDelta) Empty() bool {
+	return len(d.Hires)+len(d.Transfers)+len(d.Updates)+len(d.Exits) == 0
+}
+
+// Delta compares the simulated employees with the live ones as cloned
+func (s *Simulation) Delta() (*SimulationDelta, error) {
+	simulated, err := s.sandbox.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(simulated, func(i, j int) bool { return simulated[i].ID < simulated[j].ID })
+
+	d := &SimulationDelta{}
+	kept := make(map[int]bool, len(simulated))
+	for _, e := range simulated {
+		before, ok := s.baseline[e.ID]
+		if !ok {
+			d.Hires = append(d.Hires, e)
+			continue
+		}
+		kept[e.ID] = true
+		if e.Department != before.Department {
+			d.Transfers = append(d.Transfers, SimulatedTransfer{Employee: e, From: before.Department})
+		}
+		moved := before
+		moved.Department = e.Department
+		if changes := DiffEmployees(&moved, e); len(changes) > 0 {
+			d.Updates = append(d.Updates, SyncUpdate{Employee: e, Changes: changes})
+		}
+	}
+	for _, id := range sortedKeys(s.baseline) {
+		if !kept[id] {
+			before := s.baseline[id]
+			d.Exits = append(d.Exits, &before)
+		}
+	}
+	return d, nil
+}
+
+// sortedKeys returns the IDs of a map of employees in order
+func sortedKeys(employees map[int]Employee) []int {
+	ids := make([]int, 0, len(employees))
+	for id := range employees {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// SimulationLine compares a department's live and simulated headcount and
+// payroll with its budget
+type SimulationLine struct {
+	Department          Department
+	Headcount           int
+	SimulatedHeadcount  int
+	Payroll             float64
+	SimulatedPayroll    float64
+	SalaryBudget        float64 // 0 if the department has none
+	BudgetedHeadcount   int
+	SimulatedOverBudget bool
+}
+
+// PayrollChange returns how much the simulation changes the department's payroll
+func (l SimulationLine) PayrollChange() float64 {
+	return l.SimulatedPayroll - l.Payroll
+}
+
+// Impact compares the payroll and headcount of every department before and
+// after the simulated changes, with the department budgets
+func (s *Simulation) Impact() ([]SimulationLine, error) {
+	simulated, err := s.sandbox.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]SimulationLine, len(AllDepartments()))
+	for i, dept := range AllDepartments() {
+		lines[i].Department = dept
+		if b, ok := s.plan.Budget(dept); ok {
+			lines[i].SalaryBudget, lines[i].BudgetedHeadcount = b.SalaryBudget, b.Headcount
+		}
+	}
+	for _, e := range s.baseline {
+		lines[e.Department].Headcount++
+		lines[e.Department].Payroll += e.Salary
+	}
+	for _, e := range simulated {
+		lines[e.Department].SimulatedHeadcount++
+		lines[e.Department].SimulatedPayroll += e.Salary
+	}
+	for i := range lines {
+		l := &lines[i]
+		l.SimulatedOverBudget = (l.SalaryBudget > 0 && l.SimulatedPayroll > l.SalaryBudget) ||
+			(l.BudgetedHeadcount > 0 && l.SimulatedHeadcount > l.BudgetedHeadcount)
+	}
+	return lines, nil
+}
+
+// Apply makes the simulated changes to the live data: exits, then
+// transfers, then other updates, then hires, which get live IDs. It refuses
+// with ErrSimulationStale if any employee it would change has changed live
+// since the simulation was cloned. Otherwise it continues past changes that
+// fail, such as those budgets or approvals stop, and returns their errors.
+func (s *Simulation) Apply() (SyncResult, []error) {
+	var result SyncResult
+	d, err := s.Delta()
+	if err != nil {
+		return result, []error{err}
+	}
+
+	var touched []int
+	for _, e := range d.Exits {
+		touched = append(touched, e.ID)
+	}
+	for _, t := range d.Transfers {
+		touched = append(touched, t.Employee.ID)
+	}
+	for _, u := range d.Updates {
+		if !slices.Contains(touched, u.Employee.ID) {
+			touched = append(touched, u.Employee.ID)
+		}
+	}
+	var stale []string
+	for _, id := range touched {
+		before := s.baseline[id]
+		current, err := s.live.GetEmployee(id)
+		if err != nil || len(DiffEmployees(&before, current)) > 0 {
+			stale = append(stale, fmt.Sprint(id))
+		}
+	}
+	if len(stale) > 0 {
+		return result, []error{fmt.Errorf("%w: employee(s) %s", ErrSimulationStale, strings.Join(stale, ", "))}
+	}
+
+	errs := make([]error, 0)
+	for _, e := range d.Exits {
+		if err := s.live.RemoveEmployee(e.ID); err != nil {
+			errs = append(errs, fmt.Errorf("error removing employee ID %d: %w", e.ID, err))
+		} else {
+			result.Removed++
+		}
+	}
+	transferred := make(map[int]bool)
+	for _, t := range d.Transfers {
+		if err := s.live.TransferEmployee(t.Employee.ID, t.Employee.Department, clockOf(s.live).Now()); err != nil {
+			errs = append(errs, fmt.Errorf("error transferring employee ID %d: %w", t.Employee.ID, err))
+		} else {
+			transferred[t.Employee.ID] = true
+			result.Updated++
+		}
+	}
+	for _, u := range d.Updates {
+		e := *u.Employee
+		if !transferred[e.ID] {
+			e.Department = s.baseline[e.ID].Department
+		}
+		if err := s.live.UpdateEmployee(&e); err != nil {
+			errs = append(errs, fmt.Errorf("error updating employee ID %d: %w", e.ID, err))
+		} else if !transferred[e.ID] {
+			result.Updated++
+		}
+	}
+	for _, hire := range d.Hires {
+		e := *hire
+		e.ID = 0
+		if err := s.live.AddEmployee(&e); err != nil {
+			errs = append(errs, fmt.Errorf("error adding %s: %w", e.Name, err))
+		} else {
+			result.Added++
+		}
+	}
+	return result, errs
+}
+
+// writeSimulationDelta writes the changes a simulation would make to the live data
+func writeSimulationDelta(w io.Writer, d *SimulationDelta) {
+	if d.Empty() {
+		fmt.Fprintln(w, "\nNothing has been changed in the simulation yet.")
+		return
+	}
+	fmt.Fprintln(w)
+	for _, e := range d.Hires {
+		fmt.Fprintf(w, "%s %s, %s in %s at $%.2f\n", successText("Hire"), e.Name, e.Position, e.Department, e.Salary)
+	}
+	for _, t := range d.Transfers {
+		fmt.Fprintf(w, "%s %s (ID %d) from %s to %s\n", warningText("Transfer"), t.Employee.Name, t.Employee.ID, t.From, t.Employee.Department)
+	}
+	for _, u := range d.Updates {
+		fmt.Fprintf(w, "%s %s (ID %d)\n", warningText("Update"), u.Employee.Name, u.Employee.ID)
+		for _, c := range u.Changes {
+			fmt.Fprintf(w, "  %s\n", c)
+		}
+	}
+	for _, e := range d.Exits {
+		fmt.Fprintf(w, "%s %s (ID %d) from %s\n", errorText("Exit"), e.Name, e.ID, e.Department)
+	}
+	fmt.Fprintf(w, "\n%d hire(s), %d transfer(s), %d update(s), %d exit(s)\n",
+		len(d.Hires), len(d.Transfers), len(d.Updates), len(d.Exits))
+}
+
+// writeSimulationImpact writes the live and simulated headcount and payroll
+// of each department, marking those the simulation takes over budget
+func writeSimulationImpact(w io.Writer, lines []SimulationLine) {
+	fmt.Fprintln(w, "\n=== Simulated Impact ===")
+	fmt.Fprintf(w, "%-12s %9s %9s %14s %14s %13s %14s\n",
+		"Department", "Headcount", "Simulated", "Payroll", "Simulated", "Change", "Salary Budget")
+	fmt.Fprintln(w, strings.Repeat("-", 104))
+	var total SimulationLine
+	for _, l := range lines {
+		budget, status := "-", ""
+		if l.SalaryBudget > 0 {
+			budget = fmt.Sprintf("%.2f", l.SalaryBudget)
+		}
+		if l.SimulatedOverBudget {
+			status = warningText("over budget")
+		}
+		line := fmt.Sprintf("%-12s %9d %9d %14.2f %14.2f %+13.2f %14s  %s",
+			l.Department, l.Headcount, l.SimulatedHeadcount, l.Payroll, l.SimulatedPayroll, l.PayrollChange(), budget, status)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+		total.Headcount += l.Headcount
+		total.SimulatedHeadcount += l.SimulatedHeadcount
+		total.Payroll += l.Payroll
+		total.SimulatedPayroll += l.SimulatedPayroll
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 104))
+	fmt.Fprintf(w, "%-12s %9d %9d %14.2f %14.2f %+13.2f\n",
+		"Total", total.Headcount, total.SimulatedHeadcount, total.Payroll, total.SimulatedPayroll, total.PayrollChange())
+}
+
+// simulationInteractive runs a what-if simulation cloned from the live data
+// until the user applies or discards it
+func simulationInteractive(manager EmployeeManager, plan *BudgetPlan, reader *bufio.Reader) error {
+	sim, err := NewSimulation(manager, plan)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n" + headerText("=== What-If Simulation ==="))
+	fmt.Printf("Cloned %d employee(s). Changes stay in the simulation until applied.\n", len(sim.baseline))
+
+	for {
+		fmt.Println("\n1. Raise salaries")
+		fmt.Println("2. Transfer employee")
+		fmt.Println("3. Hire")
+		fmt.Println("4. Remove employee")
+		fmt.Println("5. View impact")
+		fmt.Println("6. View changes")
+		fmt.Println("7. Apply changes to live data")
+		fmt.Println("0. Discard simulation")
+
+		option, err := readInt(reader, "\nSelect option: ")
+		if err != nil {
+			return err
+		}
+		switch option {
+		case 0:
+			fmt.Println("\nSimulation discarded.")
+			return nil
+		case 7:
+			d, err := sim.Delta()
+			if err != nil {
+				return err
+			}
+			writeSimulationDelta(os.Stdout, d)
+			if d.Empty() {
+				continue
+			}
+			ok, err := confirm(reader, "\nApply these changes to the live data?", true)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("\n" + warningText("Operation cancelled."))
+				continue
+			}
+			result, errs := sim.Apply()
+			for _, err := range errs {
+				fmt.Println(errorText("Error:"), err)
+			}
+			fmt.Printf("\nApplied: %s\n", result)
+			return nil
+		default:
+			if err := simulationStep(sim, option, reader); err != nil {
+				fmt.Println(errorText("Error:"), err)
+			}
+		}
+	}
+}
+
+// simulationStep makes or shows one change of a simulation
+func simulationStep(sim *Simulation, option int, reader *bufio.Reader) error {
+	sandbox := sim.Manager()
+	switch option {
+	case 1:
+		query, err := readString(reader, "Employees to raise, as a search (e.g. department:HR; blank for everyone): ")
+		if err != nil {
+			return err
+		}
+		match, err := ParseQuery(query, clockOf(sandbox).Now())
+		if err != nil {
+			return err
+		}
+		percent, err := readFloat(reader, "Raise (%): ")
+		if err != nil {
+			return err
+		}
+		n, err := sim.Raise(match, percent)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nGave %d employee(s) a %.1f%% raise in the simulation.\n", n, percent)
+	case 2:
+		id, err := readInt(reader, "Employee ID to transfer: ")
+		if err != nil {
+			return err
+		}
+		department, err := readDepartment(reader)
+		if err != nil {
+			return err
+		}
+		if err := sandbox.TransferEmployee(id, department, clockOf(sandbox).Now()); err != nil {
+			return err
+		}
+		fmt.Println("\nTransferred in the simulation.")
+	case 3:
+		department, err := readDepartment(reader)
+		if err != nil {
+			return err
+		}
+		position, err := readString(reader, "Position: ")
+		if err != nil {
+			return err
+		}
+		salary, err := readFloat(reader, "Salary: ")
+		if err != nil {
+			return err
+		}
+		count, err := readInt(reader, "How many: ")
+		if err != nil {
+			return err
+		}
+		if err := sim.Hire(department, position, salary, count); err != nil {
+			return err
+		}
+		fmt.Printf("\nHired %d in the simulation.\n", count)
+	case 4:
+		id, err := readInt(reader, "Employee ID to remove: ")
+		if err != nil {
+			return err
+		}
+		if err := sandbox.RemoveEmployee(id); err != nil {
+			return err
+		}
+		fmt.Println("\nRemoved in the simulation.")
+	case 5:
+		lines, err := sim.Impact()
+		if err != nil {
+			return err
+		}
+		writeSimulationImpact(os.Stdout, lines)
+	case 6:
+		d, err := sim.Delta()
+		if err != nil {
+			return err
+		}
+		writeSimulationDelta(os.Stdout, d)
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Summary describes the distribution of a set of values
+type Summary struct {
+	Count  int
+	Sum    float64
+	Mean   float64
+	StdDev float64 // population standard deviation
+	Min    float64
+	Max    float64
+	P25    float64
+	P50    float64
+	P75    float64
+	P90    float64
+}
+
+// Summarize computes the summary statistics of values. An empty slice gives a zero Summary.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var squares float64
+	for _, v := range sorted {
+		squares += (v - mean) * (v - mean)
+	}
+
+	return Summary{
+		Count:  len(sorted),
+		Sum:    sum,
+		Mean:   mean,
+		StdDev: math.Sqrt(squares / float64(len(sorted))),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		P25:    Percentile(sorted, 25),
+		P50:    Percentile(sorted, 50),
+		P75:    Percentile(sorted, 75),
+		P90:    Percentile(sorted, 90),
+	}
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted values, interpolating
+// linearly between the two nearest ranks
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// ExperienceBucket is a range of years of experience, [MinYears, MaxYears)
+type ExperienceBucket struct {
+	Label    string
+	MinYears float64
+	MaxYears float64
+}
+
+// experienceBuckets are the ranges experience is grouped into, in order
+var experienceBuckets = []ExperienceBucket{
+	{"< 1 year", 0, 1},
+	{"1-3 years", 1, 3},
+	{"3-5 years", 3, 5},
+	{"5-10 years", 5, 10},
+	{"10+ years", 10, math.Inf(1)},
+}
+
+// experienceBucket returns the index of the bucket holding the given years of experience
+func experienceBucket(years float64) int {
+	for i, b := range experienceBuckets {
+		if years < b.MaxYears {
+			return i
+		}
+	}
+	return len(experienceBuckets) - 1
+}
+
+// GroupStats holds the statistics of a group of employees
+type GroupStats struct {
+	Name       string
+	Salary     Summary
+	Experience Summary
+	Buckets    []int // employees per experience bucket, indexed like experienceBuckets
+}
+
+// groupStats computes the statistics of the employees in each group, ordered by group name
+func groupStats(employees []Employee, now time.Time, group func(*Employee) string) []GroupStats {
+	salaries := make(map[string][]float64)
+	experience := make(map[string][]float64)
+	buckets := make(map[string][]int)
+
+	for i := range employees {
+		e := &employees[i]
+		name := group(e)
+		years := e.ExperienceAt(now)
+		salaries[name] = append(salaries[name], e.Salary)
+		experience[name] = append(experience[name], years)
+		if buckets[name] == nil {
+			buckets[name] = make([]int, len(experienceBuckets))
+		}
+		buckets[name][experienceBucket(years)]++
+	}
+
+	names := make([]string, 0, len(salaries))
+	for name := range salaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]GroupStats, 0, len(names))
+	for _, name := range names {
+		stats = append(stats, GroupStats{
+			Name:       name,
+			Salary:     Summarize(salaries[name]),
+			Experience: Summarize(experience[name]),
+			Buckets:    buckets[name],
+		})
+	}
+	return stats
+}
+
+// StatsByDepartment computes salary and experience statistics for each department
+func StatsByDepartment(employees []Employee, now time.Time) []GroupStats {
+	return groupStats(employees, now, func(e *Employee) string { return e.Department.String() })
+}
+
+// StatsByPosition computes salary and experience statistics for each position
+func StatsByPosition(employees []Employee, now time.Time) []GroupStats {
+	return groupStats(employees, now, func(e *Employee) string { return e.Position })
+}
+
+// writeSalaryStats writes the salary distribution of each group as a table
+func writeSalaryStats(w io.Writer, title string, stats []GroupStats) {
+	fmt.Fprintf(w, "\n=== %s ===\n", title)
+	fmt.Fprintf(w, "%-24s %5s %11s %11s %11s %11s %11s %11s\n",
+		"", "Count", "Mean", "Std Dev", "P25", "Median", "P75", "P90")
+	for _, g := range stats {
+		s := g.Salary
+		fmt.Fprintf(w, "%-24s %5d %11.2f %11.2f %11.2f %11.2f %11.2f %11.2f\n",
+			g.Name, s.Count, s.Mean, s.StdDev, s.P25, s.P50, s.P75, s.P90)
+	}
+}
+
+// writeExperienceStats writes how many employees of each group fall into each experience bucket
+func writeExperienceStats(w io.Writer, title string, stats []GroupStats) {
+	fmt.Fprintf(w, "\n=== %s ===\n", title)
+	fmt.Fprintf(w, "%-24s", "")
+	for _, b := range experienceBuckets {
+		fmt.Fprintf(w, " %10s", b.Label)
+	}
+	fmt.Fprintf(w, " %8s\n", "Median")
+	for _, g := range stats {
+		fmt.Fprintf(w, "%-24s", g.Name)
+		for _, n := range g.Buckets {
+			fmt.Fprintf(w, " %10d", n)
+		}
+		fmt.Fprintf(w, " %8.1f\n", g.Experience.P50)
+	}
+}
+
+// writeHeadcountChart writes a bar chart of each group's headcount
+func writeHeadcountChart(w io.Writer, title string, stats []GroupStats) {
+	labels := make([]string, len(stats))
+	headcounts := make([]float64, len(stats))
+	for i, g := range stats {
+		labels[i] = g.Name
+		headcounts[i] = float64(g.Salary.Count)
+	}
+	fmt.Fprintf(w, "\n=== %s ===\n", title)
+	writeBarChart(w, labels, headcounts, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+}
+
+// writeSalaryHistogram writes a histogram of the employees' salaries
+func writeSalaryHistogram(w io.Writer, title string, employees []Employee) {
+	salaries := make([]float64, len(employees))
+	for i := range employees {
+		salaries[i] = employees[i].Salary
+	}
+	fmt.Fprintf(w, "\n=== %s ===\n", title)
+	writeHistogram(w, NewHistogram(salaries, salaryHistogramBins),
+		func(v float64) string { return fmt.Sprintf("$%.0f", v) })
+}
+
+// salaryHistogramBins is the number of salary ranges in the salary histogram
+const salaryHistogramBins = 8
+
+// reportsInteractive shows statistical reports on the workforce
+func reportsInteractive(manager EmployeeManager, loans *Loans, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Reports ==="))
+	fmt.Println("1. Salary statistics by department")
+	fmt.Println("2. Salary statistics by position")
+	fmt.Println("3. Experience distribution by department")
+	fmt.Println("4. Headcount chart by department")
+	fmt.Println("5. Salary histogram")
+	fmt.Println("6. Computed fields by department")
+	fmt.Println("7. Payroll forecast")
+	fmt.Println("8. Payroll by cost center")
+	fmt.Println("9. Headcount by location")
+	fmt.Println("10. Payroll run")
+	if legalEntities != nil {
+		fmt.Println("11. Legal entities (group consolidation)")
+	}
+
+	option, err := readInt(reader, "\nSelect report: ")
+	if err != nil {
+		return err
+	}
+
+	employees, err := employeeValues(manager)
+	if err != nil {
+		return err
+	}
+	// Reports other than payroll, which asks for its entity, and the group
+	// consolidation can be limited to one legal entity
+	if option >= 1 && option <= 9 {
+		entity, err := readEntity(reader, false)
+		if err != nil {
+			return err
+		}
+		employees = entity.Employees(employees)
+	}
+	if len(employees) == 0 {
+		fmt.Println("\nNo employees found.")
+		return nil
+	}
+	now := clockOf(manager).Now()
+
+	switch option {
+	case 1:
+		writeSalaryStats(os.Stdout, "Salary by Department", StatsByDepartment(employees, now))
+	case 2:
+		writeSalaryStats(os.Stdout, "Salary by Position", StatsByPosition(employees, now))
+	case 3:
+		writeExperienceStats(os.Stdout, "Experience by Department", StatsByDepartment(employees, now))
+	case 4:
+		writeHeadcountChart(os.Stdout, "Headcount by Department", StatsByDepartment(employees, now))
+	case 5:
+		writeSalaryHistogram(os.Stdout, "Salary Distribution", employees)
+	case 6:
+		if len(computedFields) == 0 {
+			fmt.Println("\nNo computed fields are defined; see the -fields flag.")
+			return nil
+		}
+		writeComputedStats(os.Stdout, computedFields, employees, now)
+	case 7:
+		return forecastInteractive(employees, now, reader)
+	case 8:
+		if costCenters == nil {
+			fmt.Println("\nNo cost centers are defined; see the -cost-centers flag.")
+			return nil
+		}
+		return ledgerInteractive(employees, reader)
+	case 9:
+		writeLocationReport(os.Stdout, HeadcountByLocation(employees))
+	case 10:
+		return payrollInteractive(manager, loans, now, reader)
+	case 11:
+		if legalEntities == nil {
+			return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+		}
+		writeEntityReport(os.Stdout, legalEntities.Summary(employees))
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+	return nil
+}
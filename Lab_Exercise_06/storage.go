@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Storage is an EmployeeManager backend that can be selected by name.
+// Backends publish events for every change and release their resources on Close.
+type Storage interface {
+	EmployeeManager
+	Subscribe(fn func(Event))
+	Close() error
+}
+
+// StorageFactory opens a storage backend. The dsn is backend specific,
+// such as a file path or a database connection string.
+type StorageFactory func(dsn string, clock Clock) (Storage, error)
+
+var (
+	storageMu       sync.RWMutex
+	storageBackends = make(map[string]StorageFactory)
+)
+
+// RegisterStorage makes a storage backend available by name.
+// It panics if the name is registered twice or the factory is nil.
+func RegisterStorage(name string, factory StorageFactory) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	if factory == nil {
+		panic("storage: RegisterStorage factory is nil for " + name)
+	}
+	if _, exists := storageBackends[name]; exists {
+		panic("storage: RegisterStorage called twice for " + name)
+	}
+	storageBackends[name] = factory
+}
+
+// StorageBackends returns the names of the registered backends, sorted
+func StorageBackends() []string {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+
+	names := make([]string, 0, len(storageBackends))
+	for name := range storageBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenStorage opens the named storage backend
+func OpenStorage(name, dsn string, clock Clock) (Storage, error) {
+	storageMu.RLock()
+	factory, ok := storageBackends[name]
+	storageMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown storage backend %q (available: %v)", ErrInvalidInput, name, StorageBackends())
+	}
+	return factory(dsn, clock)
+}
+
+// Close releases the manager's resources. The in-memory manager holds none.
+func (m *InMemoryEmployeeManager) Close() error {
+	return nil
+}
+
+func init() {
+	RegisterStorage("memory", func(dsn string, clock Clock) (Storage, error) {
+		return NewInMemoryEmployeeManagerWithClock(clock), nil
+	})
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/JoeDkhar/Go/errorsx"
+)
+
+// Readiness constants using iota, from most to least ready
+const (
+	ReadyNow = iota
+	ReadyOneToTwoYears
+	ReadyThreeToFiveYears
+)
+
+// ReadinessToString converts a readiness constant to string
+func ReadinessToString(readiness int) string {
+	switch readiness {
+	case ReadyNow:
+		return "Ready now"
+	case ReadyOneToTwoYears:
+		return "1-2 years"
+	case ReadyThreeToFiveYears:
+		return "3-5 years"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToReadiness converts string to readiness constant
+func StringToReadiness(readiness string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(readiness)) {
+	case "now", "ready now":
+		return ReadyNow, nil
+	case "1-2", "1-2 years":
+		return ReadyOneToTwoYears, nil
+	case "3-5", "3-5 years":
+		return ReadyThreeToFiveYears, nil
+	default:
+		return -1, fmt.Errorf("%w: unknown readiness %q (now, 1-2, 3-5)", ErrInvalidInput, readiness)
+	}
+}
+
+// ErrKeyRoleNotFound is returned for a position that is not a key role of its department
+var ErrKeyRoleNotFound = errorsx.New(errorsx.ErrNotFound, "", "key role not found")
+
+// Successor is an employee designated to take over a key role
+type Successor struct {
+	EmployeeID int
+	Readiness  int
+}
+
+// KeyRole is a critical position of a department and its designated successors
+type KeyRole struct {
+	Department Department
+	Position   string
+	Successors []Successor // ordered from most to least ready
+}
+
+// keyRoleID identifies a key role; positions are matched without regard to case
+type keyRoleID struct {
+	department Department
+	position   string
+}
+
+func keyRoleOf(dept Department, position string) keyRoleID {
+	return keyRoleID{dept, strings.ToLower(strings.TrimSpace(position))}
+}
+
+// SuccessionPlan tracks the key roles of each department and who could succeed to them
+type SuccessionPlan struct {
+	manager EmployeeManager
+	roles   map[keyRoleID]*KeyRole
+}
+
+// NewSuccessionPlan creates an empty succession plan for employees of the given manager
+func NewSuccessionPlan(manager EmployeeManager) *SuccessionPlan {
+	return &SuccessionPlan{
+		manager: manager,
+		roles:   make(map[keyRoleID]*KeyRole),
+	}
+}
+
+// AddKeyRole marks a position of a department as critical. Adding a role
+// that is already key keeps its successors.
+func (p *SuccessionPlan) AddKeyRole(dept Department, position string) error {
+	if !dept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+	if strings.TrimSpace(position) == "" {
+		return fmt.Errorf("%w: position cannot be empty", ErrInvalidInput)
+	}
+	id := keyRoleOf(dept, position)
+	if _, exists := p.roles[id]; !exists {
+		p.roles[id] = &KeyRole{Department: dept, Position: strings.TrimSpace(position)}
+	}
+	return nil
+}
+
+// RemoveKeyRole drops a key role and its successors from the plan
+func (p *SuccessionPlan) RemoveKeyRole(dept Department, position string) error {
+	id := keyRoleOf(dept, position)
+	if _, exists := p.roles[id]; !exists {
+		return ErrKeyRoleNotFound
+	}
+	delete(p.roles, id)
+	return nil
+}
+
+// Nominate designates an employee as a successor for a key role, or updates
+// their readiness if they already are one
+func (p *SuccessionPlan) Nominate(dept Department, position string, employeeID, readiness int) error {
+	role, exists := p.roles[keyRoleOf(dept, position)]
+	if !exists {
+		return ErrKeyRoleNotFound
+	}
+	if ReadinessToString(readiness) == "Unknown" {
+		return fmt.Errorf("%w: unknown readiness %d", ErrInvalidInput, readiness)
+	}
+	employee, err := p.manager.GetEmployee(employeeID)
+	if err != nil {
+		return err
+	}
+	if keyRoleOf(employee.Department, employee.Position) == keyRoleOf(dept, position) {
+		return fmt.Errorf("%w: %s already holds this role", ErrInvalidInput, employee.Name)
+	}
+
+	for i, s := range role.Successors {
+		if s.EmployeeID == employeeID {
+			role.Successors = append(role.Successors[:i], role.Successors[i+1:]...)
+			break
+		}
+	}
+	role.Successors = append(role.Successors, Successor{EmployeeID: employeeID, Readiness: readiness})
+	sort.SliceStable(role.Successors, func(i, j int) bool {
+		return role.Successors[i].Readiness < role.Successors[j].Readiness
+	})
+	return nil
+}
+
+// Withdraw removes an employee from the successors of a key role
+func (p *SuccessionPlan) Withdraw(dept Department, position string, employeeID int) error {
+	role, exists := p.roles[keyRoleOf(dept, position)]
+	if !exists {
+		return ErrKeyRoleNotFound
+	}
+	for i, s := range role.Successors {
+		if s.EmployeeID == employeeID {
+			role.Successors = append(role.Successors[:i], role.Successors[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: employee %d is not a successor for %s", ErrInvalidInput, employeeID, role.Position)
+}
+
+// KeyRoles returns copies of all key roles ordered by department, then position
+func (p *SuccessionPlan) KeyRoles() []KeyRole {
+	roles := make([]KeyRole, 0, len(p.roles))
+	for _, r := range p.roles {
+		role := *r
+		role.Successors = append([]Successor(nil), r.Successors...)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Department != roles[j].Department {
+			return roles[i].Department < roles[j].Department
+		}
+		return strings.ToLower(roles[i].Position) < strings.ToLower(roles[j].Position)
+	})
+	return roles
+}
+
+// Forget returns an event subscriber that drops removed employees from every
+// succession list, so the coverage report never counts a departed successor
+func (p *SuccessionPlan) Forget() func(Event) {
+	return func(ev Event) {
+		if ev.Type != EventEmployeeRemoved {
+			return
+		}
+		for _, role := range p.roles {
+			for i, s := range role.Successors {
+				if s.EmployeeID == ev.EmployeeID {
+					role.Successors = append(role.Successors[:i], role.Successors[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
+
+// SuccessionCoverage summarizes the key roles of a department
+type SuccessionCoverage struct {
+	Department Department
+	KeyRoles   int
+	Covered    int      // key roles with at least one successor
+	ReadyNow   int      // key roles with a successor who is ready now
+	Gaps       []string // key roles with no successor
+}
+
+// Coverage reports how well each department's key roles are covered, ordered by department
+func (p *SuccessionPlan) Coverage() []SuccessionCoverage {
+	var coverage []SuccessionCoverage
+	for _, role := range p.KeyRoles() {
+		if len(coverage) == 0 || coverage[len(coverage)-1].Department != role.Department {
+			coverage = append(coverage, SuccessionCoverage{Department: role.Department})
+		}
+		c := &coverage[len(coverage)-1]
+		c.KeyRoles++
+		if len(role.Successors) == 0 {
+			c.Gaps = append(c.Gaps, role.Position)
+			continue
+		}
+		c.Covered++
+		if role.Successors[0].Readiness == ReadyNow {
+			c.ReadyNow++
+		}
+	}
+	return coverage
+}
+
+// writeSuccessionPlan writes every key role with its incumbents and successors,
+// followed by the coverage of each department
+func writeSuccessionPlan(w io.Writer, plan *SuccessionPlan, manager EmployeeManager) {
+	roles := plan.KeyRoles()
+	if len(roles) == 0 {
+		fmt.Fprintln(w, "\nNo key roles defined.")
+		return
+	}
+
+	name := func(id int) string {
+		if e, err := manager.GetEmployee(id); err == nil {
+			return fmt.Sprintf("%s (ID %d)", e.Name, e.ID)
+		}
+		return fmt.Sprintf("employee %d", id)
+	}
+
+	fmt.Fprintln(w, "\n=== Key Roles ===")
+	for _, role := range roles {
+		incumbents := manager.FilterEmployees(func(e *Employee) bool {
+			return keyRoleOf(e.Department, e.Position) == keyRoleOf(role.Department, role.Position)
+		})
+		holders := make([]string, len(incumbents))
+		for i, e := range incumbents {
+			holders[i] = name(e.ID)
+		}
+		if len(holders) == 0 {
+			holders = []string{"vacant"}
+		}
+
+		fmt.Fprintf(w, "\n%s - %s, held by %s\n", role.Department, role.Position, strings.Join(holders, ", "))
+		if len(role.Successors) == 0 {
+			fmt.Fprintln(w, "  "+warningText("No successor"))
+		}
+		for _, s := range role.Successors {
+			fmt.Fprintf(w, "  %-30s %s\n", name(s.EmployeeID), ReadinessToString(s.Readiness))
+		}
+	}
+
+	fmt.Fprintln(w, "\n=== Coverage by Department ===")
+	fmt.Fprintf(w, "%-12s %9s %8s %10s  %s\n", "Department", "Key Roles", "Covered", "Ready Now", "Gaps")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, c := range plan.Coverage() {
+		gaps := "-"
+		if len(c.Gaps) > 0 {
+			gaps = strings.Join(c.Gaps, ", ")
+		}
+		fmt.Fprintf(w, "%-12s %9d %8d %10d  %s\n", c.Department, c.KeyRoles, c.Covered, c.ReadyNow, gaps)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+}
+
+// successionInteractive manages key roles and their successors through user interaction
+func successionInteractive(plan *SuccessionPlan, manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Succession Planning ==="))
+	fmt.Println("1. Add key role")
+	fmt.Println("2. Remove key role")
+	fmt.Println("3. Nominate successor")
+	fmt.Println("4. Withdraw successor")
+	fmt.Println("5. View succession plan")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+	if option == 5 {
+		writeSuccessionPlan(os.Stdout, plan, manager)
+		return nil
+	}
+	if option < 1 || option > 5 {
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	department, err := readDepartment(reader)
+	if err != nil {
+		return err
+	}
+	position, err := readString(reader, "Position: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		err = plan.AddKeyRole(department, position)
+	case 2:
+		var ok bool
+		ok, err = confirm(reader, "Remove this key role and its successors?", true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("\n" + warningText("Operation cancelled."))
+			return nil
+		}
+		err = plan.RemoveKeyRole(department, position)
+	case 3, 4:
+		var id int
+		id, err = readInt(reader, "Enter employee ID: ")
+		if err != nil {
+			return err
+		}
+		if option == 4 {
+			err = plan.Withdraw(department, position, id)
+			break
+		}
+		var readiness int
+		readiness, err = readValue(reader, "Readiness (now, 1-2, 3-5 years): ", StringToReadiness)
+		if err == nil {
+			err = plan.Nominate(department, position, id, readiness)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n" + successText("Succession plan updated!"))
+	writeSuccessionPlan(os.Stdout, plan, manager)
+	return nil
+}
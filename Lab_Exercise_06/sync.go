@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SyncUpdate is an existing employee whose imported row changes some fields
+type SyncUpdate struct {
+	Employee *Employee // the employee with the imported values
+	Changes  []FieldChange
+}
+
+// SyncPlan is what an upsert import would change: the rows that add
+// employees, the rows that update them, and, when asked, the employees the
+// file no longer lists
+type SyncPlan struct {
+	Adds      []*Employee
+	Updates   []SyncUpdate
+	Removes   []*Employee
+	Unchanged int
+	Skipped   []importRow
+
+	// Unmatched is set when rows could not be read well enough to match
+	// them, so no employee is removed: they may be one of those rows
+	Unmatched bool
+
+	// Resolved counts conflicting fields the strategy settled, and
+	// Conflicts holds the employees with fields left for manual resolution
+	Resolved  int
+	Conflicts []ImportConflict
+
+	synced  map[int]Employee // employees as the file has them, for the next baseline
+	removed []int
+}
+
+// SyncOptions controls how PlanSync treats employees the file and the store disagree on
+type SyncOptions struct {
+	RemoveMissing bool          // remove employees no row refers to
+	Strategy      int           // how to settle fields edited both locally and in the file
+	Baseline      *SyncBaseline // the last sync; nil takes every difference as a change in the file
+}
+
+// normalizeName lowercases a name and collapses its spaces, for matching
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// mergeImported returns the existing employee with the fields the imported
+// row has values for. Blank cells leave the field as it is, and so does a
+// name that only differs in case or spacing.
+func mergeImported(existing, imported *Employee) *Employee {
+	merged := *existing
+	if imported.Name != "" && normalizeName(imported.Name) != normalizeName(existing.Name) {
+		merged.Name = imported.Name
+	}
+	if imported.Position != "" {
+		merged.Position = imported.Position
+	}
+	if imported.Salary != 0 {
+		merged.Salary = imported.Salary
+	}
+	if imported.Department != -1 {
+		merged.Department = imported.Department
+	}
+	if !imported.JoinDate.IsZero() {
+		merged.JoinDate = imported.JoinDate
+	}
+	if !imported.BirthDate.IsZero() {
+		merged.BirthDate = imported.BirthDate
+	}
+	if imported.WorkMode != -1 {
+		merged.WorkMode = imported.WorkMode
+	}
+	if imported.Location != "" {
+		merged.Location = imported.Location
+	}
+	if imported.TimeZone != "" {
+		merged.TimeZone = imported.TimeZone
+	}
+	return &merged
+}
+
+// PlanSync compares the rows of a file with the manager's employees. A row
+// matches the employee with its ID or, when it has none, the only employee
+// with the same name and birth date. Matched rows update the fields they
+// have values for, merged with local edits since the baseline, unmatched
+// rows are added, and with RemoveMissing the employees no row refers to
+// are removed.
+func PlanSync(manager EmployeeManager, mapping *ImportMapping, header []string, rows [][]string, opts SyncOptions) (*SyncPlan, error) {
+	current, err := manager.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*Employee, len(current))
+	byName := make(map[string][]*Employee)
+	for _, e := range current {
+		byID[e.ID] = e
+		byName[normalizeName(e.Name)] = append(byName[normalizeName(e.Name)], e)
+	}
+
+	now := appClock.Now()
+	plan := &SyncPlan{synced: make(map[int]Employee)}
+	matchedBy := make(map[int]int)   // employee ID to the row that matched it
+	referenced := make(map[int]bool) // employees a row refers to, even one that is skipped
+	for i, row := range rows {
+		line := i + 2
+		skip := func(e *Employee, err error) {
+			plan.Skipped = append(plan.Skipped, importRow{Line: line, Employee: e, Err: err})
+		}
+
+		imported, err := mapping.Employee(header, row)
+		if err != nil {
+			skip(nil, err)
+			plan.Unmatched = true
+			continue
+		}
+
+		existing := byID[imported.ID]
+		if imported.ID == 0 {
+			var candidates []*Employee
+			for _, e := range byName[normalizeName(imported.Name)] {
+				if sameDay(e.BirthDate, imported.BirthDate) {
+					candidates = append(candidates, e)
+				}
+			}
+			for _, e := range candidates {
+				referenced[e.ID] = true
+			}
+			if len(candidates) > 1 {
+				skip(imported, fmt.Errorf("%w: %d employees are named %q; add an ID column to tell them apart",
+					ErrInvalidInput, len(candidates), imported.Name))
+				continue
+			}
+			if len(candidates) == 1 {
+				existing = candidates[0]
+			}
+		}
+
+		if existing == nil {
+			newImportedEmployee(imported)
+			if err := validateEmployee(imported, now); err != nil {
+				skip(imported, err)
+				continue
+			}
+			plan.Adds = append(plan.Adds, imported)
+			continue
+		}
+
+		referenced[existing.ID] = true
+		if first, ok := matchedBy[existing.ID]; ok {
+			skip(imported, fmt.Errorf("%w: row %d already updates employee ID %d", ErrInvalidInput, first, existing.ID))
+			continue
+		}
+		matchedBy[existing.ID] = line
+
+		importTime, err := mapping.LastUpdated(header, row)
+		if err != nil {
+			skip(imported, err)
+			continue
+		}
+		remote := mergeImported(existing, imported)
+		var base *Employee
+		if b, ok := opts.Baseline.lookup(existing.ID); ok {
+			base = &b
+		}
+		merge := mergeFields(existing, remote, base, opts.Strategy, localUpdated(manager, existing.ID), importTime)
+		if err := validateEmployee(merge.Employee, now); err != nil {
+			skip(merge.Employee, err)
+			continue
+		}
+
+		plan.synced[existing.ID] = *remote
+		plan.Resolved += merge.Resolved
+		if len(merge.Conflicts) > 0 {
+			plan.Conflicts = append(plan.Conflicts, ImportConflict{EmployeeID: existing.ID, Fields: merge.Conflicts, Import: *remote, Queued: now})
+		}
+		if changes := DiffEmployees(existing, merge.Employee); len(changes) > 0 {
+			plan.Updates = append(plan.Updates, SyncUpdate{Employee: merge.Employee, Changes: changes})
+		} else {
+			plan.Unchanged++
+		}
+	}
+
+	if opts.RemoveMissing && !plan.Unmatched {
+		for _, e := range current {
+			if !referenced[e.ID] {
+				plan.Removes = append(plan.Removes, e)
+			}
+		}
+		sort.Slice(plan.Removes, func(i, j int) bool { return plan.Removes[i].ID < plan.Removes[j].ID })
+	}
+	return plan, nil
+}
+
+// writeSyncPlan writes the changes a sync would make, then a one-line summary
+func writeSyncPlan(w io.Writer, plan *SyncPlan) {
+	for _, e := range plan.Adds {
+		fmt.Fprintf(w, "%s %s, %s in %s\n", successText("Add"), e.Name, e.Position, e.Department)
+	}
+	for _, u := range plan.Updates {
+		fmt.Fprintf(w, "%s %s (ID %d)\n", warningText("Update"), u.Employee.Name, u.Employee.ID)
+		for _, c := range u.Changes {
+			fmt.Fprintf(w, "  %s\n", c)
+		}
+	}
+	for _, e := range plan.Removes {
+		fmt.Fprintf(w, "%s %s (ID %d), not in the file\n", errorText("Remove"), e.Name, e.ID)
+	}
+	for _, r := range plan.Skipped {
+		fmt.Fprintf(w, "%s row %d: %v\n", warningText("Skipping"), r.Line, r.Err)
+	}
+	if plan.Unmatched {
+		fmt.Fprintln(w, warningText("No employees will be removed until every row can be read."))
+	}
+	for _, c := range plan.Conflicts {
+		fmt.Fprintf(w, "%s %s (ID %d): %s edited both locally and in the file\n",
+			warningText("Conflict"), c.Import.Name, c.EmployeeID, strings.Join(c.Fields, ", "))
+	}
+	fmt.Fprintf(w, "\n%d to add, %d to update, %d to remove, %d unchanged, %d skipped\n",
+		len(plan.Adds), len(plan.Updates), len(plan.Removes), plan.Unchanged, len(plan.Skipped))
+	if plan.Resolved > 0 || len(plan.Conflicts) > 0 {
+		fmt.Fprintf(w, "%d conflicting field(s) settled, %d employee(s) with conflicts to resolve by hand\n",
+			plan.Resolved, len(plan.Conflicts))
+	}
+}
+
+// SyncResult counts the changes a sync made
+type SyncResult struct {
+	Added, Updated, Removed int
+}
+
+// String returns the add/update/delete summary of a sync
+func (r SyncResult) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d removed", r.Added, r.Updated, r.Removed)
+}
+
+// Apply makes the planned changes, continuing past employees that fail and
+// returning their errors
+func (plan *SyncPlan) Apply(manager EmployeeManager) (SyncResult, []error) {
+	var result SyncResult
+	errs := make([]error, 0)
+	for _, e := range plan.Adds {
+		if err := manager.AddEmployee(e); err != nil {
+			errs = append(errs, fmt.Errorf("error adding %s: %w", e.Name, err))
+		} else {
+			plan.synced[e.ID] = *e
+			result.Added++
+		}
+	}
+	for _, u := range plan.Updates {
+		if err := manager.UpdateEmployee(u.Employee); err != nil {
+			errs = append(errs, fmt.Errorf("error updating employee ID %d: %w", u.Employee.ID, err))
+			delete(plan.synced, u.Employee.ID) // so the next sync offers the change again
+		} else {
+			result.Updated++
+		}
+	}
+	for _, e := range plan.Removes {
+		if err := manager.RemoveEmployee(e.ID); err != nil {
+			errs = append(errs, fmt.Errorf("error removing employee ID %d: %w", e.ID, err))
+		} else {
+			plan.removed = append(plan.removed, e.ID)
+			result.Removed++
+		}
+	}
+	return result, errs
+}
+
+// RecordBaseline updates a baseline with the employees as the file had them
+// and drops the ones that were removed, for the next sync to compare with
+func (plan *SyncPlan) RecordBaseline(b *SyncBaseline, at time.Time) {
+	for id, e := range plan.synced {
+		b.Employees[id] = e
+	}
+	for _, id := range plan.removed {
+		delete(b.Employees, id)
+	}
+	b.Synced = at
+}
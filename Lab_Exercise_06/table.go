@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// List format constants using iota
+const (
+	FormatDetail = iota
+	FormatTable
+	FormatMarkdown
+)
+
+// listFormat decides how employee lists and search results are shown.
+// main sets it from the -format flag.
+var listFormat = FormatDetail
+
+// FormatToString converts a list format constant to string
+func FormatToString(format int) string {
+	switch format {
+	case FormatDetail:
+		return "detail"
+	case FormatTable:
+		return "table"
+	case FormatMarkdown:
+		return "markdown"
+	default:
+		return "Unknown"
+	}
+}
+
+// StringToFormat converts string to list format constant
+func StringToFormat(format string) (int, error) {
+	switch strings.ToLower(format) {
+	case "detail", "":
+		return FormatDetail, nil
+	case "table":
+		return FormatTable, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	default:
+		return -1, fmt.Errorf("%w: format must be detail, table or markdown", ErrInvalidInput)
+	}
+}
+
+// Column is a column of the employee table
+type Column struct {
+	Name   string // used to select the column, as in -columns id,name
+	Header string
+	Right  bool // right-align, for numbers
+	Value  func(e *Employee) string
+}
+
+// tableColumns lists the columns the employee table can show
+var tableColumns = []Column{
+	{"id", "ID", true, func(e *Employee) string { return fmt.Sprint(e.ID) }},
+	{"name", "Name", false, func(e *Employee) string { return e.Name }},
+	{"position", "Position", false, func(e *Employee) string { return e.Position }},
+	{"department", "Department", false, func(e *Employee) string { return e.Department.String() }},
+	{"salary", "Salary", true, func(e *Employee) string { return fmt.Sprintf("%.2f", e.Salary) }},
+	{"joined", "Joined", false, func(e *Employee) string { return formatDate(e.JoinDate) }},
+	{"experience", "Experience", false, func(e *Employee) string { return e.Tenure().String() }},
+	{"birth_date", "Birth Date", false, func(e *Employee) string { return formatOptionalDate(e.BirthDate) }},
+	{"probation_end", "Probation Ends", false, func(e *Employee) string { return formatOptionalDate(e.ProbationEnd) }},
+	{"location", "Location", false, func(e *Employee) string { return e.Location }},
+	{"work_mode", "Work Mode", false, func(e *Employee) string { return WorkModeToString(e.WorkMode) }},
+	{"time_zone", "Local Time", false, func(e *Employee) string { return localTimeLabel(e, appClock.Now()) }},
+}
+
+// defaultColumns are the columns shown unless others are selected
+const defaultColumns = "id,name,position,department,salary,joined"
+
+// listColumns are the columns of the employee table. main sets them from the -columns flag.
+var listColumns, _ = ParseColumns(defaultColumns)
+
+// ParseColumns selects employee table columns from a comma-separated list of names
+func ParseColumns(list string) ([]Column, error) {
+	columns := make([]Column, 0)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range tableColumns {
+			if c.Name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if f := findComputedField(computedFields, name); !found && f != nil {
+			columns = append(columns, computedColumn(f))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown column %q (available: %s)", ErrInvalidInput, name, strings.Join(columnNames(), ", "))
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: select at least one column", ErrInvalidInput)
+	}
+	return columns, nil
+}
+
+// columnNames returns the names of the employee table columns, computed fields last
+func columnNames() []string {
+	names := make([]string, 0, len(tableColumns)+len(computedFields))
+	for _, c := range tableColumns {
+		names = append(names, c.Name)
+	}
+	for _, f := range computedFields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// writeEmployeeTable writes employees as a table of the given columns, each
+// as wide as its widest cell, or as a Markdown table
+func writeEmployeeTable(w io.Writer, columns []Column, employees []*Employee, markdown bool) {
+	rows := make([][]string, len(employees))
+	for i, e := range employees {
+		rows[i] = make([]string, len(columns))
+		for j, c := range columns {
+			rows[i][j] = c.Value(e)
+		}
+	}
+	writeTable(w, columns, rows, markdown)
+}
+
+// writeTable writes rows of cells under the headers of the columns, each
+// column as wide as its widest cell, or as a Markdown table. The columns'
+// Value functions are not used.
+func writeTable(w io.Writer, columns []Column, rows [][]string, markdown bool) {
+	if markdown {
+		writeMarkdownTable(w, columns, rows)
+		return
+	}
+
+	widths := make([]int, len(columns))
+	for j, c := range columns {
+		widths[j] = utf8.RuneCountInString(c.Header)
+		for _, row := range rows {
+			widths[j] = max(widths[j], utf8.RuneCountInString(row[j]))
+		}
+	}
+
+	cell := func(j int, s string) string {
+		pad := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(s))
+		if columns[j].Right {
+			return pad + s
+		}
+		return s + pad
+	}
+	line := func(cells []string) string {
+		out := make([]string, len(cells))
+		for j, s := range cells {
+			out[j] = cell(j, s)
+		}
+		return strings.TrimRight(strings.Join(out, "  "), " ")
+	}
+
+	headers := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for j, c := range columns {
+		headers[j] = c.Header
+		rules[j] = strings.Repeat("-", widths[j])
+	}
+	fmt.Fprintln(w, headerText(line(headers)))
+	fmt.Fprintln(w, line(rules))
+	for _, row := range rows {
+		fmt.Fprintln(w, line(row))
+	}
+}
+
+// writeMarkdownTable writes rows as a GitHub-flavored Markdown table
+func writeMarkdownTable(w io.Writer, columns []Column, rows [][]string) {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	headers := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for j, c := range columns {
+		headers[j] = escape.Replace(c.Header)
+		rules[j] = "---"
+		if c.Right {
+			rules[j] = "--:"
+		}
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(headers, " | "))
+	fmt.Fprintf(w, "|%s|\n", strings.Join(rules, "|"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for j, s := range row {
+			cells[j] = escape.Replace(s)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
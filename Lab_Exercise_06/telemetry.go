@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Telemetry settings
+const (
+	telemetryOff     = "off"   // the default: nothing is counted
+	telemetryLocal   = "local" // reports are only written to the telemetry log
+	telemetryVersion = 1       // layout of UsageReport
+	telemetryTimeout = 5 * time.Second
+)
+
+// UsageReport is what telemetry sends: how often each feature was used, how
+// big the data set is and which kinds of error came up. It holds nothing
+// that identifies the deployment, its users or its employees.
+type UsageReport struct {
+	Version   int            `json:"version"`
+	Date      string         `json:"date"` // the day, without the time
+	Mode      string         `json:"mode"` // menu or server
+	Program   string         `json:"program"`
+	Storage   string         `json:"storage"`
+	Employees string         `json:"employees"` // a size bucket, such as 100-999
+	Features  map[string]int `json:"features"`  // menu options and API routes used
+	Errors    map[string]int `json:"errors"`    // error codes, as the API names them
+}
+
+// TelemetryRecord is an entry of the telemetry log: a report, and where it
+// was sent
+type TelemetryRecord struct {
+	Report UsageReport `json:"report"`
+	SentTo string      `json:"sent_to,omitempty"` // empty if it was only recorded
+	Error  string      `json:"error,omitempty"`   // why sending it failed
+}
+
+// Telemetry counts the use of features and errors for the usage reports
+// users opt in to with -telemetry. A nil *Telemetry, as when telemetry is
+// off, counts nothing.
+type Telemetry struct {
+	mu       sync.Mutex
+	endpoint string // where reports are sent; empty to only log them
+	logPath  string
+	mode     string
+	storage  string
+	size     func() int // the number of employees, or -1 if unknown
+	features map[string]int
+	errors   map[string]int
+	client   *http.Client
+}
+
+// appTelemetry counts usage when the user has opted in. main sets it from
+// the -telemetry flag.
+var appTelemetry *Telemetry
+
+// defaultTelemetryLogPath is the telemetry log beside the users' preferences
+func defaultTelemetryLogPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "employee-management", "telemetry.jsonl"), nil
+}
+
+// NewTelemetry returns the telemetry of a -telemetry setting: off (or
+// empty), local, or the http or https URL to send reports to. Off gives
+// nil. Every report is also appended to the log at logPath, so what was
+// sent can be looked at.
+func NewTelemetry(setting, logPath, mode, storage string, size func() int) (*Telemetry, error) {
+	if setting == "" || setting == telemetryOff {
+		return nil, nil
+	}
+	t := &Telemetry{logPath: logPath, mode: mode, storage: storage, size: size, client: &http.Client{Timeout: telemetryTimeout}}
+	if setting != telemetryLocal {
+		u, err := url.Parse(setting)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: -telemetry must be off, local or an http(s) URL, not %q", ErrInvalidInput, setting)
+		}
+		t.endpoint = setting
+	}
+	t.reset()
+	return t, nil
+}
+
+// Notice tells the user that telemetry is on, and where its reports go
+func (t *Telemetry) Notice() string {
+	where := "recorded in " + t.logPath + ", and not sent,"
+	if t.endpoint != "" {
+		where = "sent to " + t.endpoint + " and recorded in " + t.logPath
+	}
+	return fmt.Sprintf("Telemetry is on: anonymous usage counts are %s when the program exits, and daily when serving. "+
+		"The telemetry command shows them; -telemetry=off turns it off.", where)
+}
+
+// reset starts counting afresh
+func (t *Telemetry) reset() {
+	t.features = make(map[string]int)
+	t.errors = make(map[string]int)
+}
+
+// Feature counts a use of a feature
+func (t *Telemetry) Feature(name string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.features[name]++
+}
+
+// Error counts an error by its kind, never by its message
+func (t *Telemetry) Error(err error) {
+	if t == nil || err == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errors[errorCategory(err)]++
+}
+
+// errorCategory returns the code the API gives an error, which names its
+// kind without any of its detail
+func errorCategory(err error) string {
+	for _, c := range apiErrorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeInternal
+}
+
+// sizeBucket rounds a number of employees down to a power of ten, so
+// reports tell small deployments from large ones and no more
+func sizeBucket(n int) string {
+	switch {
+	case n < 0:
+		return "unknown"
+	case n == 0:
+		return "0"
+	}
+	low := 1
+	for low*10 <= n && low < 10000 {
+		low *= 10
+	}
+	if low == 10000 {
+		return "10000+"
+	}
+	return fmt.Sprintf("%d-%d", low, low*10-1)
+}
+
+// Report returns the report of what has been counted so far
+func (t *Telemetry) Report(now time.Time) UsageReport {
+	t.mu.Lock()
+	features, errs := make(map[string]int, len(t.features)), make(map[string]int, len(t.errors))
+	for k, v := range t.features {
+		features[k] = v
+	}
+	for k, v := range t.errors {
+		errs[k] = v
+	}
+	t.mu.Unlock()
+
+	size := -1
+	if t.size != nil {
+		size = t.size()
+	}
+	return UsageReport{
+		Version:   telemetryVersion,
+		Date:      now.UTC().Format("2006-01-02"),
+		Mode:      t.mode,
+		Program:   fmt.Sprintf("%s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH),
+		Storage:   t.storage,
+		Employees: sizeBucket(size),
+		Features:  features,
+		Errors:    errs,
+	}
+}
+
+// Flush sends the report of what has been counted, logs it and starts
+// counting afresh. Nothing is sent when nothing was counted. A report that
+// cannot be sent is logged with the reason, and is not sent again.
+func (t *Telemetry) Flush(now time.Time) error {
+	if t == nil {
+		return nil
+	}
+	report := t.Report(now)
+	if len(report.Features) == 0 && len(report.Errors) == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	t.reset()
+	t.mu.Unlock()
+
+	record := TelemetryRecord{Report: report}
+	if t.endpoint != "" {
+		record.SentTo = t.endpoint
+		if err := t.send(report); err != nil {
+			record.Error = err.Error()
+		}
+	}
+	if t.logPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.logPath), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// send posts a report to the endpoint
+func (t *Telemetry) send(report UsageReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// countRequests counts the API routes of mux that requests are served by
+func countRequests(t *Telemetry, mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			t.Feature(pattern)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// telemetryJob sends the server's usage report once a day
+func telemetryJob(t *Telemetry) func(context.Context, time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		return t.Flush(now)
+	}
+}
+
+// readTelemetryLog reads the records of a telemetry log, oldest first. A
+// missing log has none.
+func readTelemetryLog(path string) ([]TelemetryRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []TelemetryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var r TelemetryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+// runTelemetry implements the telemetry command, which shows the usage
+// reports that were recorded and sent, exactly as they were sent
+func runTelemetry(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("telemetry", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	logPath := fs.String("log", "", "telemetry log to read (default telemetry.jsonl in the user's config directory)")
+	last := fs.Int("n", 10, "show this many of the latest reports (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	path := *logPath
+	if path == "" {
+		var err error
+		if path, err = defaultTelemetryLogPath(); err != nil {
+			return err
+		}
+	}
+	records, err := readTelemetryLog(path)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(stdout, "No usage reports have been recorded in %s. Telemetry is off unless -telemetry is local or a URL.\n", path)
+		return nil
+	}
+	if *last > 0 && len(records) > *last {
+		records = records[len(records)-*last:]
+	}
+	for _, r := range records {
+		switch {
+		case r.SentTo == "":
+			fmt.Fprintf(stdout, "Report of %s, recorded only:\n", r.Report.Date)
+		case r.Error != "":
+			fmt.Fprintf(stdout, "Report of %s, not sent to %s: %s\n", r.Report.Date, r.SentTo, r.Error)
+		default:
+			fmt.Fprintf(stdout, "Report of %s, sent to %s:\n", r.Report.Date, r.SentTo)
+		}
+		data, _ := json.MarshalIndent(r.Report, "", "  ")
+		fmt.Fprintf(stdout, "%s\n\n", data)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// PositionTemplate holds the defaults used when hiring into a standard position
+type PositionTemplate struct {
+	Position        string     `json:"position"`
+	Department      Department `json:"department"`
+	MinSalary       float64    `json:"min_salary"`
+	MaxSalary       float64    `json:"max_salary"`
+	ProbationMonths int        `json:"probation_months"`
+}
+
+// positionTemplates lists the standard positions new employees can start
+// from. main replaces them from the -positions flag.
+var positionTemplates = []PositionTemplate{
+	{Position: "Software Engineer I", Department: Engineering, MinSalary: 60000, MaxSalary: 80000, ProbationMonths: 6},
+	{Position: "Software Engineer II", Department: Engineering, MinSalary: 80000, MaxSalary: 105000, ProbationMonths: 3},
+	{Position: "HR Generalist", Department: HR, MinSalary: 50000, MaxSalary: 70000, ProbationMonths: 3},
+	{Position: "Financial Analyst", Department: Finance, MinSalary: 60000, MaxSalary: 85000, ProbationMonths: 3},
+	{Position: "Marketing Specialist", Department: Marketing, MinSalary: 50000, MaxSalary: 70000, ProbationMonths: 3},
+	{Position: "Operations Coordinator", Department: Operations, MinSalary: 45000, MaxSalary: 60000, ProbationMonths: 6},
+}
+
+// LoadPositionTemplates reads a positions file
+func LoadPositionTemplates(path string) ([]PositionTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := ParsePositionTemplates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return templates, nil
+}
+
+// ParsePositionTemplates reads and checks position templates in JSON: a list
+// of positions, each named once, with a salary band and probation period
+func ParsePositionTemplates(data []byte) ([]PositionTemplate, error) {
+	var templates []PositionTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	seen := make(map[string]bool)
+	for _, t := range templates {
+		key := strings.ToLower(t.Position)
+		switch {
+		case strings.TrimSpace(t.Position) == "":
+			return nil, fmt.Errorf("%w: every position needs a name", ErrInvalidInput)
+		case seen[key]:
+			return nil, fmt.Errorf("%w: position %q is defined twice", ErrInvalidInput, t.Position)
+		case t.MinSalary <= 0 || t.MaxSalary < t.MinSalary:
+			return nil, fmt.Errorf("%w: position %q has salary band $%.2f-$%.2f", ErrInvalidInput, t.Position, t.MinSalary, t.MaxSalary)
+		case t.ProbationMonths < 0 || t.ProbationMonths > 24:
+			return nil, fmt.Errorf("%w: position %q has a probation of %d months, not 0 to 24", ErrInvalidInput, t.Position, t.ProbationMonths)
+		}
+		seen[key] = true
+	}
+	return templates, nil
+}
+
+// String returns a formatted string representation of the template
+func (t PositionTemplate) String() string {
+	return fmt.Sprintf("%s (%s) $%.2f-$%.2f, %d month probation",
+		t.Position, t.Department, t.MinSalary, t.MaxSalary, t.ProbationMonths)
+}
+
+// DefaultSalary returns the midpoint of the template's salary band
+func (t PositionTemplate) DefaultSalary() float64 {
+	return (t.MinSalary + t.MaxSalary) / 2
+}
+
+// InBand reports whether a salary falls within the template's salary band
+func (t PositionTemplate) InBand(salary float64) bool {
+	return salary >= t.MinSalary && salary <= t.MaxSalary
+}
+
+// ProbationEnd returns the last day of probation for an employee joining on
+// joinDate: the day before the same day ProbationMonths later, or that
+// month's last day if it is shorter, so August 31 and six months give
+// February 28
+func (t PositionTemplate) ProbationEnd(joinDate time.Time) time.Time {
+	if t.ProbationMonths <= 0 {
+		return time.Time{}
+	}
+	year, month, day := joinDate.In(userLocation).Date()
+	end := time.Date(year, month+time.Month(t.ProbationMonths), 1, 0, 0, 0, 0, time.UTC)
+	if day == 1 {
+		// The day before the first is the last day of the month before
+		return dateOf(end.Year(), end.Month(), 0)
+	}
+	return dateOf(end.Year(), end.Month(), min(day-1, daysIn(end.Year(), end.Month())))
+}
+
+// FindTemplate looks up a template by position name, ignoring case
+func FindTemplate(position string) (PositionTemplate, bool) {
+	for _, t := range positionTemplates {
+		if strings.EqualFold(t.Position, position) {
+			return t, true
+		}
+	}
+	return PositionTemplate{}, false
+}
+
+// readTemplate lets the user pick a position template, returning nil for none
+func readTemplate(reader *bufio.Reader) (*PositionTemplate, error) {
+	fmt.Println("\nPosition templates:")
+	fmt.Println("0. None")
+	for i, t := range positionTemplates {
+		fmt.Printf("%d. %s\n", i+1, t)
+	}
+
+	return readValue(reader, fmt.Sprintf("Start from template (0-%d): ", len(positionTemplates)), func(input string) (*PositionTemplate, error) {
+		choice, err := parseInt(input)
+		if err != nil {
+			return nil, err
+		}
+		if choice == 0 {
+			return nil, nil
+		}
+		if choice < 0 || choice > len(positionTemplates) {
+			return nil, fmt.Errorf("%w: please select a valid template", ErrInvalidInput)
+		}
+		template := positionTemplates[choice-1]
+		return &template, nil
+	})
+}
+
+// readFromTemplate fills in an employee from a template, asking only for the
+// values that differ from the template's defaults
+func readFromTemplate(reader *bufio.Reader, template PositionTemplate, employee *Employee) error {
+	fmt.Println("\nLeave blank to keep the template value:")
+
+	position, err := readString(reader, fmt.Sprintf("Position [%s]: ", template.Position))
+	if err != nil {
+		return err
+	}
+	employee.Position = template.Position
+	if position != "" {
+		employee.Position = position
+	}
+
+	prompt := fmt.Sprintf("Salary [%.2f] ($%.2f-$%.2f): ", template.DefaultSalary(), template.MinSalary, template.MaxSalary)
+	employee.Salary, err = readValue(reader, prompt, func(input string) (float64, error) {
+		if input == "" {
+			return template.DefaultSalary(), nil
+		}
+		salary, err := parseSalary(input)
+		if err != nil {
+			return 0, err
+		}
+		if !template.InBand(salary) {
+			return 0, fmt.Errorf("%w: salary must be between $%.2f and $%.2f for %s",
+				ErrInvalidInput, template.MinSalary, template.MaxSalary, template.Position)
+		}
+		return salary, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	employee.Department = template.Department
+	fmt.Printf("\nDepartment: %s. Change department? (y/n)\n", template.Department)
+	changeDept, err := readString(reader, "Choice: ")
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(changeDept) == "y" {
+		employee.Department, err = readDepartment(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	employee.JoinDate, err = readDate(reader, "Join Date")
+	if err != nil {
+		return err
+	}
+	employee.ProbationEnd = template.ProbationEnd(employee.JoinDate)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProbationEnd(t *testing.T) {
+	userLocation = time.UTC
+	tests := []struct {
+		join   string
+		months int
+		want   string
+	}{
+		{"2024-01-15", 6, "2024-07-14"},
+		{"2024-01-01", 3, "2024-03-31"},
+		{"2023-08-31", 6, "2024-02-29"},
+		{"2024-08-31", 6, "2025-02-28"},
+		{"2023-08-30", 6, "2024-02-29"},
+		{"2024-03-31", 1, "2024-04-30"},
+		{"2024-11-30", 3, "2025-02-28"},
+		{"2024-05-20", 0, ""},
+	}
+	for _, tt := range tests {
+		join, err := time.Parse("2006-01-02", tt.join)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end := PositionTemplate{ProbationMonths: tt.months}.ProbationEnd(join)
+		got := ""
+		if !end.IsZero() {
+			got = end.Format("2006-01-02")
+		}
+		if got != tt.want {
+			t.Errorf("%d month(s) from %s ends %q, want %q", tt.months, tt.join, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tenure is a calendar-accurate length of service
+type Tenure struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// String returns a formatted string representation of the tenure
+func (t Tenure) String() string {
+	return fmt.Sprintf("%d years, %d months, %d days", t.Years, t.Months, t.Days)
+}
+
+// TotalMonths returns the tenure in whole months
+func (t Tenure) TotalMonths() int {
+	return t.Years*12 + t.Months
+}
+
+// daysIn returns the number of days in a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// monthsAfter returns the date months calendar months after date, on the
+// same day of the month, or on the month's last day if it is shorter
+func monthsAfter(date time.Time, months int) time.Time {
+	y, m, d := date.Date()
+	first := time.Date(y, m+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	return time.Date(first.Year(), first.Month(), min(d, daysIn(first.Year(), first.Month())), 0, 0, 0, 0, date.Location())
+}
+
+// tenureBetween counts whole years, months and days from start to end by the
+// calendar, so leap years and month lengths are handled exactly. A month
+// from a day its end month does not have, such as January 31, ends on the
+// month's last day, as anniversaries of February 29 do.
+// Dates are taken in the user's time zone; a start after end yields a zero tenure.
+func tenureBetween(start, end time.Time) Tenure {
+	start, end = start.In(userLocation), end.In(userLocation)
+	sy, sm, sd := start.Date()
+	ey, em, ed := end.Date()
+	if ey < sy || (ey == sy && (em < sm || (em == sm && ed <= sd))) {
+		return Tenure{}
+	}
+
+	// Count days between calendar dates in UTC, where every day is 24 hours
+	from := time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC)
+	to := time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC)
+	months := (ey-sy)*12 + int(em) - int(sm)
+	anchor := monthsAfter(from, months)
+	if anchor.After(to) {
+		months--
+		anchor = monthsAfter(from, months)
+	}
+	days := int(to.Sub(anchor).Hours() / 24)
+	return Tenure{Years: months / 12, Months: months % 12, Days: days}
+}
+
+// TenureAt returns the employee's length of service on the given date
+func (e *Employee) TenureAt(t time.Time) Tenure {
+	return tenureBetween(e.JoinDate, t)
+}
+
+// Tenure returns the employee's length of service as of today on the application clock
+func (e *Employee) Tenure() Tenure {
+	return e.TenureAt(appClock.Now())
+}
+
+// ExperienceAt returns the years of service on the given date, with the
+// fraction measured against the actual length of the current service year
+func (e *Employee) ExperienceAt(t time.Time) float64 {
+	if !t.After(e.JoinDate) {
+		return 0
+	}
+
+	years := e.TenureAt(t).Years
+	joinYear := e.JoinDate.In(userLocation).Year()
+	last := anniversaryIn(e.JoinDate, joinYear+years, userLocation)
+	next := anniversaryIn(e.JoinDate, joinYear+years+1, userLocation)
+	return float64(years) + t.Sub(last).Hours()/next.Sub(last).Hours()
+}
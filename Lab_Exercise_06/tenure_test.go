@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTenureBetween(t *testing.T) {
+	userLocation = time.UTC
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		start, end string
+		want       Tenure
+	}{
+		{"2023-01-15", "2023-03-10", Tenure{0, 1, 23}},
+		{"2023-01-31", "2023-02-28", Tenure{0, 1, 0}},
+		{"2023-01-31", "2023-03-01", Tenure{0, 1, 1}},
+		{"2023-01-30", "2023-03-30", Tenure{0, 2, 0}},
+		{"2023-03-31", "2023-04-30", Tenure{0, 1, 0}},
+		{"2023-05-31", "2023-06-29", Tenure{0, 0, 29}},
+		{"2023-12-31", "2024-02-29", Tenure{0, 2, 0}},
+		{"2023-12-31", "2024-03-31", Tenure{0, 3, 0}},
+		{"2020-02-29", "2021-02-28", Tenure{1, 0, 0}},
+		{"2020-02-29", "2021-03-01", Tenure{1, 0, 1}},
+		{"2020-02-29", "2024-02-29", Tenure{4, 0, 0}},
+		{"2021-02-28", "2024-02-29", Tenure{3, 0, 1}},
+		{"2024-02-29", "2024-03-29", Tenure{0, 1, 0}},
+		{"2019-06-15", "2024-06-14", Tenure{4, 11, 30}},
+		{"2024-06-15", "2024-06-15", Tenure{}},
+		{"2024-06-15", "2023-06-15", Tenure{}},
+	}
+	for _, tt := range tests {
+		if got := tenureBetween(date(tt.start), date(tt.end)); got != tt.want {
+			t.Errorf("tenureBetween(%s, %s) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Theme holds the ANSI SGR parameters, such as "1;31" for bold red, used for
+// each kind of message. An empty parameter leaves that kind uncolored.
+type Theme struct {
+	Error   string
+	Success string
+	Warning string
+	Header  string
+}
+
+// themes are the color themes that -theme can select
+var themes = map[string]Theme{
+	"default":       {Error: "31", Success: "32", Warning: "33", Header: "1;36"},
+	"high-contrast": {Error: "1;91", Success: "1;92", Warning: "1;93", Header: "1;97;44"},
+	// mono relies on weight and underline only, for terminals or readers without color
+	"mono": {Error: "1", Success: "", Warning: "4", Header: "1"},
+}
+
+// colorTheme colors console output, or is nil to print plain text. main sets
+// it from the -theme and -no-color flags when standard output is a terminal.
+var colorTheme *Theme
+
+// ThemeNames returns the names of the color themes, sorted
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StringToTheme looks up a color theme by name
+func StringToTheme(name string) (Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	theme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, fmt.Errorf("%w: unknown theme %q (available: %s)", ErrInvalidInput, name, strings.Join(ThemeNames(), ", "))
+	}
+	return theme, nil
+}
+
+// colorsSupported reports whether f should get colored output. Colors are
+// left out when NO_COLOR is set (https://no-color.org), when TERM is dumb and
+// when f is not a terminal, such as when output is piped to a file.
+func colorsSupported(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// paint wraps s in the escape codes of an SGR parameter, unless colors are off
+func paint(sgr, s string) string {
+	if colorTheme == nil || sgr == "" {
+		return s
+	}
+	return "\x1b[" + sgr + "m" + s + "\x1b[0m"
+}
+
+// errorText colors s as an error
+func errorText(s string) string {
+	if colorTheme == nil {
+		return s
+	}
+	return paint(colorTheme.Error, s)
+}
+
+// successText colors s as a success message
+func successText(s string) string {
+	if colorTheme == nil {
+		return s
+	}
+	return paint(colorTheme.Success, s)
+}
+
+// warningText colors s as a warning
+func warningText(s string) string {
+	if colorTheme == nil {
+		return s
+	}
+	return paint(colorTheme.Warning, s)
+}
+
+// headerText colors s as a heading
+func headerText(s string) string {
+	if colorTheme == nil {
+		return s
+	}
+	return paint(colorTheme.Header, s)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Date layouts used for input and display
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// userLocation is the time zone dates are entered and displayed in.
+// All times are stored in UTC and only converted when parsed or rendered.
+var userLocation = time.Local
+
+// setUserLocation sets the time zone by IANA name (e.g. "Asia/Kolkata").
+// An empty name keeps the system's local time zone.
+func setUserLocation(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidInput, name)
+	}
+	userLocation = loc
+	return nil
+}
+
+// timeZones caches the time zones loaded by name, since loading one reads
+// the time zone database
+var timeZones sync.Map // name -> *time.Location
+
+// loadTimeZone returns the time zone with an IANA name, loading it once
+func loadTimeZone(name string) (*time.Location, error) {
+	if loc, ok := timeZones.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	timeZones.Store(name, loc)
+	return loc, nil
+}
+
+// parseDate parses a YYYY-MM-DD date as midnight in the user's time zone, returned in UTC
+func parseDate(input string) (time.Time, error) {
+	date, err := time.ParseInLocation(dateLayout, input, userLocation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.UTC(), nil
+}
+
+// parseDateTime parses a YYYY-MM-DD HH:MM time, with or without seconds,
+// in the user's time zone, returned in UTC
+func parseDateTime(input string) (time.Time, error) {
+	t, err := time.ParseInLocation(dateTimeLayout, input, userLocation)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02 15:04", input, userLocation)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// dateOf returns midnight of the given day in the user's time zone, in UTC
+func dateOf(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, userLocation).UTC()
+}
+
+// today returns midnight of the current day in the user's time zone, in UTC
+func today() time.Time {
+	return dateOf(appClock.Now().In(userLocation).Date())
+}
+
+// formatDate renders the date of t in the user's time zone
+func formatDate(t time.Time) string {
+	return t.In(userLocation).Format(dateLayout)
+}
+
+// formatDateTime renders t in the user's time zone
+func formatDateTime(t time.Time) string {
+	return t.In(userLocation).Format(dateTimeLayout)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is a timed operation within a trace
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. A span started from a context that holds another span
+// becomes its child. Extract continues a trace started by the caller of an
+// HTTP request, from W3C traceparent headers.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+	Extract(ctx context.Context, header http.Header) context.Context
+}
+
+// TracerFactory creates a tracer from a connection string, such as a collector
+// endpoint. The returned function flushes and stops the tracer.
+type TracerFactory func(dsn string) (Tracer, func() error, error)
+
+var (
+	tracersMu sync.Mutex
+	tracers   = make(map[string]TracerFactory)
+)
+
+// RegisterTracer makes a tracer available to -trace under a name
+func RegisterTracer(name string, factory TracerFactory) {
+	tracersMu.Lock()
+	defer tracersMu.Unlock()
+	tracers[name] = factory
+}
+
+// TracerNames returns the names of the registered tracers, sorted
+func TracerNames() []string {
+	tracersMu.Lock()
+	defer tracersMu.Unlock()
+
+	names := make([]string, 0, len(tracers))
+	for name := range tracers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenTracer creates the tracer registered under name
+func OpenTracer(name, dsn string) (Tracer, func() error, error) {
+	tracersMu.Lock()
+	factory, ok := tracers[name]
+	tracersMu.Unlock()
+
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unknown tracer %q (available: %s)", ErrInvalidInput, name,
+			strings.Join(TracerNames(), ", "))
+	}
+	return factory(dsn)
+}
+
+// logSpanContext identifies a span of the log tracer
+type logSpanContext struct {
+	traceID string
+	spanID  string
+}
+
+type logSpanKey struct{}
+
+// logTracer is a Tracer that writes every finished span as a line of text, for
+// seeing where time goes without running a collector
+type logTracer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// logSpan is a span of the log tracer
+type logSpan struct {
+	tracer *logTracer
+	name   string
+	ctx    logSpanContext
+	parent string
+	start  time.Time
+	attrs  []string
+	err    error
+}
+
+// randomID returns n random bytes as hex, for trace and span IDs
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Start starts a span, as a child of the span in ctx if there is one
+func (t *logTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &logSpan{tracer: t, name: name, start: time.Now()}
+	if parent, ok := ctx.Value(logSpanKey{}).(logSpanContext); ok {
+		span.ctx.traceID = parent.traceID
+		span.parent = parent.spanID
+	} else {
+		span.ctx.traceID = randomID(16)
+	}
+	span.ctx.spanID = randomID(8)
+	return context.WithValue(ctx, logSpanKey{}, span.ctx), span
+}
+
+// Extract continues the trace of a traceparent header such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (t *logTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	parts := strings.Split(header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	return context.WithValue(ctx, logSpanKey{}, logSpanContext{traceID: parts[1], spanID: parts[2]})
+}
+
+func (s *logSpan) SetAttribute(key string, value interface{}) {
+	s.attrs = append(s.attrs, fmt.Sprintf("%s=%v", key, value))
+}
+
+func (s *logSpan) RecordError(err error) {
+	s.err = err
+}
+
+// End writes the span
+func (s *logSpan) End() {
+	line := fmt.Sprintf("trace=%s span=%s", s.ctx.traceID, s.ctx.spanID)
+	if s.parent != "" {
+		line += " parent=" + s.parent
+	}
+	line += fmt.Sprintf(" name=%s duration=%s", s.name, time.Since(s.start))
+	if len(s.attrs) > 0 {
+		line += " " + strings.Join(s.attrs, " ")
+	}
+	if s.err != nil {
+		line += fmt.Sprintf(" error=%q", s.err)
+	}
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	fmt.Fprintln(s.tracer.out, line)
+}
+
+// TracingManager decorates an EmployeeManager with a span around every
+// operation. The manager methods take no context, so the server gives each
+// request its own copy with WithContext, making the spans children of the
+// request's span. It should be the outermost decorator, so its spans include
+// the time spent in caches and storage.
+type TracingManager struct {
+	inner  EmployeeManager
+	tracer Tracer
+	ctx    context.Context
+}
+
+// NewTracingManager creates a TracingManager whose spans start new traces
+func NewTracingManager(inner EmployeeManager, tracer Tracer) *TracingManager {
+	return &TracingManager{inner: inner, tracer: tracer, ctx: context.Background()}
+}
+
+// WithContext returns a copy of the manager whose spans are children of the span in ctx
+func (t *TracingManager) WithContext(ctx context.Context) EmployeeManager {
+	c := *t
+	c.ctx = ctx
+	return &c
+}
+
+// Unwrap returns the wrapped manager
+func (t *TracingManager) Unwrap() EmployeeManager {
+	return t.inner
+}
+
+// start starts a span for a manager operation
+func (t *TracingManager) start(operation string) Span {
+	_, span := t.tracer.Start(t.ctx, "EmployeeManager."+operation)
+	return span
+}
+
+// endSpan records the operation's error, if any, and ends the span
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// AddEmployee adds an employee in a span
+func (t *TracingManager) AddEmployee(e *Employee) (err error) {
+	span := t.start("AddEmployee")
+	defer func() { endSpan(span, err) }()
+	return t.inner.AddEmployee(e)
+}
+
+// RemoveEmployee removes an employee in a span
+func (t *TracingManager) RemoveEmployee(id int) (err error) {
+	span := t.start("RemoveEmployee")
+	span.SetAttribute("employee.id", id)
+	defer func() { endSpan(span, err) }()
+	return t.inner.RemoveEmployee(id)
+}
+
+// UpdateEmployee updates an employee in a span
+func (t *TracingManager) UpdateEmployee(e *Employee) (err error) {
+	span := t.start("UpdateEmployee")
+	if e != nil {
+		span.SetAttribute("employee.id", e.ID)
+	}
+	defer func() { endSpan(span, err) }()
+	return t.inner.UpdateEmployee(e)
+}
+
+// GetEmployee retrieves an employee in a span
+func (t *TracingManager) GetEmployee(id int) (_ *Employee, err error) {
+	span := t.start("GetEmployee")
+	span.SetAttribute("employee.id", id)
+	defer func() { endSpan(span, err) }()
+	return t.inner.GetEmployee(id)
+}
+
+// ListEmployees lists employees in a span
+func (t *TracingManager) ListEmployees() (employees []*Employee, err error) {
+	span := t.start("ListEmployees")
+	defer func() {
+		span.SetAttribute("employees", len(employees))
+		endSpan(span, err)
+	}()
+	return t.inner.ListEmployees()
+}
+
+// FilterEmployees filters employees in a span
+func (t *TracingManager) FilterEmployees(filter func(*Employee) bool) []*Employee {
+	span := t.start("FilterEmployees")
+	employees := t.inner.FilterEmployees(filter)
+	span.SetAttribute("employees", len(employees))
+	span.End()
+	return employees
+}
+
+// TransferEmployee transfers an employee in a span
+func (t *TracingManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) (err error) {
+	span := t.start("TransferEmployee")
+	span.SetAttribute("employee.id", id)
+	defer func() { endSpan(span, err) }()
+	return t.inner.TransferEmployee(id, newDept, effectiveDate)
+}
+
+// EmployeeValue retrieves an employee by value in a span
+func (t *TracingManager) EmployeeValue(id int) (_ Employee, err error) {
+	span := t.start("EmployeeValue")
+	span.SetAttribute("employee.id", id)
+	defer func() { endSpan(span, err) }()
+	return employeeValue(t.inner, id)
+}
+
+// EmployeeValues returns all employees by value in a span
+func (t *TracingManager) EmployeeValues() []Employee {
+	span := t.start("EmployeeValues")
+	values, err := employeeValues(t.inner)
+	span.SetAttribute("employees", len(values))
+	endSpan(span, err)
+	return values
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// traceRequests wraps a handler with a span per request, continuing the
+// caller's trace when the request carries one
+func traceRequests(tracer Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracer.Extract(r.Context(), r.Header)
+		ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		// The route is only known once the mux has matched the request
+		if r.Pattern != "" {
+			span.SetAttribute("http.route", r.Pattern)
+		}
+		span.SetAttribute("http.status_code", rec.status)
+		if rec.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%s", http.StatusText(rec.status)))
+		}
+	})
+}
+
+func init() {
+	// The DSN is an optional file to write spans to; by default they go to stderr
+	RegisterTracer("log", func(dsn string) (Tracer, func() error, error) {
+		if dsn == "" {
+			return &logTracer{out: os.Stderr}, func() error { return nil }, nil
+		}
+		f, err := os.OpenFile(dsn, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &logTracer{out: f}, f.Close, nil
+	})
+}
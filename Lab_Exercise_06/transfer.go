@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TransferRecord stores a single department transfer in an employee's history
+type TransferRecord struct {
+	EmployeeID     int
+	FromDepartment Department
+	ToDepartment   Department
+	EffectiveDate  time.Time
+	RecordedAt     time.Time
+}
+
+// String returns a formatted string representation of the transfer
+func (t TransferRecord) String() string {
+	return fmt.Sprintf("%s -> %s (effective %s)",
+		t.FromDepartment, t.ToDepartment,
+		formatDate(t.EffectiveDate))
+}
+
+// TransferEmployee moves an employee to another department, records the
+// transfer in the employee's history and notifies subscribers
+func (m *InMemoryEmployeeManager) TransferEmployee(id int, newDept Department, effectiveDate time.Time) error {
+	if !newDept.Valid() {
+		return fmt.Errorf("%w: please select a valid department", ErrInvalidInput)
+	}
+
+	m.mu.Lock()
+	employee, exists := m.state.employees[id]
+	if !exists {
+		m.mu.Unlock()
+		return ErrEmployeeNotFound
+	}
+	if employee.Department == newDept {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: employee is already in %s", ErrInvalidInput, newDept)
+	}
+
+	now := m.clock.Now()
+	record := TransferRecord{
+		EmployeeID:     id,
+		FromDepartment: employee.Department,
+		ToDepartment:   newDept,
+		EffectiveDate:  effectiveDate.UTC(),
+		RecordedAt:     now,
+	}
+
+	transferred := *employee
+	transferred.Department = newDept
+	transferred.Version++
+	c := m.record(Change{
+		Type:       EventEmployeeTransferred,
+		EmployeeID: id,
+		Time:       now,
+		Employee:   transferred,
+		Transfer:   &record,
+	})
+	m.mu.Unlock()
+
+	m.events.Publish(c.notification())
+	return nil
+}
+
+// TransferHistory returns the transfers recorded for an employee, oldest first
+func (m *InMemoryEmployeeManager) TransferHistory(id int) []TransferRecord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]TransferRecord, len(m.state.transfers[id]))
+	copy(history, m.state.transfers[id])
+	return history
+}
+
+// ListByDepartment returns the employees in a department using the department index
+func (m *InMemoryEmployeeManager) ListByDepartment(dept Department) []*Employee {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]int, 0, len(m.state.deptIndex[dept]))
+	for id := range m.state.deptIndex[dept] {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	// Copy the records into one backing array to prevent modification of the originals
+	values := make([]Employee, len(ids))
+	result := make([]*Employee, len(ids))
+	for i, id := range ids {
+		values[i] = *m.state.employees[id]
+		result[i] = &values[i]
+	}
+	return result
+}
+
+// RebuildIndexes rebuilds the department index from the employee records
+func (m *InMemoryEmployeeManager) RebuildIndexes() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.deptIndex = make(map[Department]map[int]struct{})
+	for id, e := range m.state.employees {
+		m.state.indexDepartment(id, e.Department)
+	}
+	return nil
+}
+
+// Subscribe registers a function that is notified of manager events
+func (m *InMemoryEmployeeManager) Subscribe(fn func(Event)) {
+	m.events.Subscribe(fn)
+}
+
+// EventBacklog returns the number of the manager's events still being delivered
+func (m *InMemoryEmployeeManager) EventBacklog() int {
+	return m.events.Backlog()
+}
+
+// transferEmployeeInteractive transfers an employee through user interaction
+func transferEmployeeInteractive(manager EmployeeManager, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Transfer Employee ==="))
+
+	id, err := readInt(reader, "Enter employee ID to transfer: ")
+	if err != nil {
+		return err
+	}
+
+	employee, err := manager.GetEmployee(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nCurrent department: %s\n", employee.Department)
+
+	department, err := readDepartment(reader)
+	if err != nil {
+		return err
+	}
+
+	effectiveDate, err := readDate(reader, "Effective Date")
+	if err != nil {
+		return err
+	}
+
+	ok, err := confirm(reader, fmt.Sprintf("\nTransfer %s from %s to %s effective %s?", employee.Name,
+		employee.Department, department, formatDate(effectiveDate)), false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("\n" + warningText("Operation cancelled."))
+		return nil
+	}
+
+	err = manager.TransferEmployee(id, department, effectiveDate)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n" + successText("Employee transferred successfully!"))
+
+	if h, ok := capability[interface{ TransferHistory(int) []TransferRecord }](manager); ok {
+		fmt.Println("\nTransfer history:")
+		for _, record := range h.TransferHistory(id) {
+			fmt.Printf("- %s\n", record)
+		}
+	}
+	return nil
+}
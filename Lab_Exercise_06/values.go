@@ -0,0 +1,93 @@
+package main
+
+// EmployeeValueReader is implemented by managers that can return employees by
+// value. The in-memory managers never modify a stored record in place (every
+// change stores a new one), so a value read only has to copy the record out:
+// EmployeeValue needs no heap allocation and EmployeeValues needs one for the
+// whole list, where the pointer API allocates a defensive copy per employee.
+type EmployeeValueReader interface {
+	EmployeeValue(id int) (Employee, error)
+	EmployeeValues() []Employee
+}
+
+// employeeValues returns all employees by value, using the manager's value
+// reader when it has one
+func employeeValues(manager EmployeeManager) ([]Employee, error) {
+	if reader, ok := capability[EmployeeValueReader](manager); ok {
+		return reader.EmployeeValues(), nil
+	}
+
+	employees, err := manager.ListEmployees()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Employee, len(employees))
+	for i, e := range employees {
+		values[i] = *e
+	}
+	return values, nil
+}
+
+// employeeValue returns an employee by value, using the manager's value reader
+// when it has one
+func employeeValue(manager EmployeeManager, id int) (Employee, error) {
+	if reader, ok := capability[EmployeeValueReader](manager); ok {
+		return reader.EmployeeValue(id)
+	}
+
+	employee, err := manager.GetEmployee(id)
+	if err != nil {
+		return Employee{}, err
+	}
+	return *employee, nil
+}
+
+// EmployeeValue retrieves an employee by ID as a value
+func (m *InMemoryEmployeeManager) EmployeeValue(id int) (Employee, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	employee, exists := m.state.employees[id]
+	if !exists {
+		return Employee{}, ErrEmployeeNotFound
+	}
+	return *employee, nil
+}
+
+// EmployeeValues returns all employees as values
+func (m *InMemoryEmployeeManager) EmployeeValues() []Employee {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := make([]Employee, 0, len(m.state.employees))
+	for _, emp := range m.state.employees {
+		values = append(values, *emp)
+	}
+	return values
+}
+
+// EmployeeValue retrieves an employee by ID as a value
+func (m *ColumnarEmployeeManager) EmployeeValue(id int) (Employee, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i, exists := m.rows[id]
+	if !exists {
+		return Employee{}, ErrEmployeeNotFound
+	}
+	var e Employee
+	m.row(i, &e)
+	return e, nil
+}
+
+// EmployeeValues returns all employees as values
+func (m *ColumnarEmployeeManager) EmployeeValues() []Employee {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := make([]Employee, len(m.ids))
+	for i := range m.ids {
+		m.row(i, &values[i])
+	}
+	return values
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watch metrics, measured over the employees a watch's search matches
+const (
+	MetricCount         = "count"
+	MetricTotalSalary   = "salary"
+	MetricAverageSalary = "average_salary"
+)
+
+// watchOperators are the comparisons a watch condition can use
+var watchOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// webhookTimeout is how long a webhook gets to accept a notification
+const webhookTimeout = 5 * time.Second
+
+// Watch is a saved search with a threshold, such as "count > 50" over
+// department=Engineering. Subscribers are notified when a change makes the
+// condition become true; they are not notified again until it has been false.
+type Watch struct {
+	Name      string   `json:"name"`
+	Query     string   `json:"query"` // in the form ParseEmployeeQuery reads
+	Metric    string   `json:"metric"`
+	Operator  string   `json:"operator"`
+	Threshold float64  `json:"threshold"`
+	Notify    []string `json:"notify,omitempty"` // notification targets; the console if empty
+}
+
+// Condition returns the watch's condition as it is entered, such as "count > 50"
+func (w Watch) Condition() string {
+	return fmt.Sprintf("%s %s %s", w.Metric, w.Operator, strconv.FormatFloat(w.Threshold, 'f', -1, 64))
+}
+
+// String returns a one-line description of the watch
+func (w Watch) String() string {
+	query := w.Query
+	if query == "" {
+		query = "all employees"
+	}
+	return fmt.Sprintf("%s: %s over %s", w.Name, w.Condition(), query)
+}
+
+// ParseWatchCondition parses a condition such as "count > 50" or
+// "salary >= 1000000" into a metric, an operator and a threshold
+func ParseWatchCondition(text string) (metric, operator string, threshold float64, err error) {
+	for _, op := range watchOperators {
+		left, right, found := strings.Cut(text, op)
+		if !found {
+			continue
+		}
+		metric = strings.ToLower(strings.TrimSpace(left))
+		switch metric {
+		case MetricCount, MetricTotalSalary, MetricAverageSalary:
+		default:
+			return "", "", 0, fmt.Errorf("%w: unknown metric %q (available: %s, %s, %s)", ErrInvalidInput,
+				metric, MetricCount, MetricTotalSalary, MetricAverageSalary)
+		}
+		threshold, err = strconv.ParseFloat(strings.TrimSpace(right), 64)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("%w: the threshold must be a number", ErrInvalidInput)
+		}
+		return metric, op, threshold, nil
+	}
+	return "", "", 0, fmt.Errorf("%w: a condition compares a metric with a number, as in \"count > 50\"", ErrInvalidInput)
+}
+
+// Validate checks that the watch's search, condition and targets can be used
+func (w Watch) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("%w: a watch needs a name", ErrInvalidInput)
+	}
+	query, err := url.ParseQuery(w.Query)
+	if err != nil {
+		return fmt.Errorf("%w: the search cannot be read: %v", ErrInvalidInput, err)
+	}
+	if _, err := ParseEmployeeQuery(query, appClock.Now()); err != nil {
+		return err
+	}
+	if _, _, _, err := ParseWatchCondition(w.Condition()); err != nil {
+		return err
+	}
+	for _, target := range w.Notify {
+		if _, err := OpenNotifier(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Measure computes the watch's metric over the employees its search matches
+func (w Watch) Measure(manager EmployeeManager) (float64, error) {
+	query, err := url.ParseQuery(w.Query)
+	if err != nil {
+		return 0, err
+	}
+	matches, err := ParseEmployeeQuery(query, clockOf(manager).Now())
+	if err != nil {
+		return 0, err
+	}
+
+	employees := manager.FilterEmployees(matches)
+	total := 0.0
+	for _, e := range employees {
+		total += e.Salary
+	}
+	switch w.Metric {
+	case MetricTotalSalary:
+		return total, nil
+	case MetricAverageSalary:
+		if len(employees) == 0 {
+			return 0, nil
+		}
+		return total / float64(len(employees)), nil
+	default:
+		return float64(len(employees)), nil
+	}
+}
+
+// Holds reports whether value meets the watch's condition
+func (w Watch) Holds(value float64) bool {
+	switch w.Operator {
+	case ">":
+		return value > w.Threshold
+	case ">=":
+		return value >= w.Threshold
+	case "<":
+		return value < w.Threshold
+	case "<=":
+		return value <= w.Threshold
+	case "=":
+		return value == w.Threshold
+	case "!=":
+		return value != w.Threshold
+	default:
+		return false
+	}
+}
+
+// Alert is sent when a change makes a watch's condition become true
+type Alert struct {
+	Watch       Watch     `json:"watch"`
+	Value       float64   `json:"value"`
+	Environment string    `json:"environment"`
+	Cause       string    `json:"cause"` // the change that triggered the alert
+	Time        time.Time `json:"time"`
+}
+
+// String returns a one-line description of the alert
+func (a Alert) String() string {
+	return fmt.Sprintf("Watch %q triggered in %s: %s is now %s (%s)", a.Watch.Name, a.Environment,
+		a.Watch.Metric, strconv.FormatFloat(a.Value, 'f', -1, 64), a.Cause)
+}
+
+// Notification converts the alert to the notification its targets are sent.
+// Webhooks get the alert itself.
+func (a Alert) Notification() Notification {
+	return Notification{
+		Event:   NotifyWatchAlert,
+		Subject: fmt.Sprintf("Watch %q triggered", a.Watch.Name),
+		Text:    a.String(),
+		Detail:  "Watch: " + a.Watch.String(),
+		Time:    a.Time,
+		label:   "Watch alert",
+		payload: a,
+	}
+}
+
+// Notifier delivers notifications somewhere
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// consoleNotifier prints notifications
+type consoleNotifier struct {
+	w io.Writer
+}
+
+func (c consoleNotifier) Notify(n Notification) error {
+	label := n.label
+	if label == "" {
+		label = "Notification"
+	}
+	_, err := fmt.Fprintln(c.w, "\n"+warningText(label+":")+" "+n.Text)
+	if err == nil && (n.Event == NotifyDigest || n.Event == NotifyWeeklyDigest) {
+		_, err = fmt.Fprintln(c.w, n.Detail)
+	}
+	return err
+}
+
+// webhookNotifier posts notifications as JSON to a URL
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w webhookNotifier) Notify(n Notification) error {
+	var payload interface{} = n
+	if n.payload != nil {
+		payload = n.payload
+	}
+	return w.post(payload)
+}
+
+// post sends a JSON body to the webhook
+func (w webhookNotifier) post(payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+// slackNotifier posts notifications as messages to a Slack incoming webhook
+type slackNotifier struct {
+	webhookNotifier
+}
+
+func (s slackNotifier) Notify(n Notification) error {
+	text := fmt.Sprintf("*%s*\n%s", n.Subject, n.Text)
+	if n.Detail != "" {
+		text += "\n```" + n.Detail + "```"
+	}
+	return s.post(map[string]string{"text": text})
+}
+
+// emailNotifier mails notifications through an SMTP server
+type emailNotifier struct {
+	server string
+	from   string
+	to     []string
+}
+
+func (e emailNotifier) Notify(n Notification) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n\r\n", n.Subject)
+	fmt.Fprintf(&msg, "%s\r\n", n.Text)
+	if n.Detail != "" {
+		fmt.Fprintf(&msg, "\r\n%s\r\n", strings.ReplaceAll(n.Detail, "\n", "\r\n"))
+	}
+	return smtp.SendMail(e.server, nil, e.from, e.to, []byte(msg.String()))
+}
+
+// slackWebhookHost is the host of Slack's incoming webhook URLs
+const slackWebhookHost = "hooks.slack.com"
+
+// notifierKind names the channel of a notification target: console, email,
+// slack or webhook
+func notifierKind(target string) string {
+	switch n, _ := OpenNotifier(target); n.(type) {
+	case emailNotifier:
+		return "email"
+	case slackNotifier:
+		return "slack"
+	case webhookNotifier:
+		return "webhook"
+	default:
+		return "console"
+	}
+}
+
+// OpenNotifier creates the notifier of a target: "console", an http or https
+// webhook URL, a Slack incoming webhook URL, or a mailto URL naming its SMTP
+// server, such as mailto:hr@example.com?smtp=localhost:25&from=ems@example.com
+func OpenNotifier(target string) (Notifier, error) {
+	if target == "" || target == "console" {
+		return consoleNotifier{w: os.Stdout}, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: notification target %q: %v", ErrInvalidInput, target, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		webhook := webhookNotifier{url: target, client: &http.Client{Timeout: webhookTimeout}}
+		if strings.EqualFold(u.Hostname(), slackWebhookHost) {
+			return slackNotifier{webhook}, nil
+		}
+		return webhook, nil
+	case "mailto":
+		to := strings.Split(u.Opaque, ",")
+		server, from := u.Query().Get("smtp"), u.Query().Get("from")
+		if u.Opaque == "" || server == "" || from == "" {
+			return nil, fmt.Errorf("%w: an email target needs recipients, smtp and from, as in mailto:hr@example.com?smtp=localhost:25&from=ems@example.com", ErrInvalidInput)
+		}
+		return emailNotifier{server: server, from: from, to: to}, nil
+	default:
+		return nil, fmt.Errorf("%w: notification target %q must be console, a webhook URL or a mailto URL", ErrInvalidInput, target)
+	}
+}
+
+// Watchlist checks watches after every change to the stores it watches. It
+// remembers whether each condition held, so an alert is only sent when a
+// change makes it become true.
+type Watchlist struct {
+	mu       sync.Mutex
+	watches  []Watch
+	managers map[string]EmployeeManager // by environment name
+	holding  map[string]bool            // by environment and watch name
+}
+
+// NewWatchlist creates a watchlist of saved watches
+func NewWatchlist(watches []Watch) (*Watchlist, error) {
+	for _, w := range watches {
+		if err := w.Validate(); err != nil {
+			return nil, fmt.Errorf("watch %q: %w", w.Name, err)
+		}
+	}
+	return &Watchlist{
+		watches:  append([]Watch(nil), watches...),
+		managers: make(map[string]EmployeeManager),
+		holding:  make(map[string]bool),
+	}, nil
+}
+
+// Watches returns the watches, in the order they were added
+func (l *Watchlist) Watches() []Watch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Watch(nil), l.watches...)
+}
+
+// Add adds a watch. Its condition is measured now, so that only later changes
+// that make it true send an alert.
+func (l *Watchlist) Add(w Watch) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, existing := range l.watches {
+		if existing.Name == w.Name {
+			return fmt.Errorf("%w: a watch named %q already exists", ErrInvalidInput, w.Name)
+		}
+	}
+	l.watches = append(l.watches, w)
+	for env, manager := range l.managers {
+		l.prime(env, manager, w)
+	}
+	return nil
+}
+
+// Remove removes the watch with the given name
+func (l *Watchlist) Remove(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, w := range l.watches {
+		if w.Name == name {
+			l.watches = append(l.watches[:i], l.watches[i+1:]...)
+			for env := range l.managers {
+				delete(l.holding, env+"/"+name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no watch named %q", ErrInvalidInput, name)
+}
+
+// prime records whether a watch's condition holds before any change is seen.
+// The caller must hold the lock.
+func (l *Watchlist) prime(env string, manager EmployeeManager, w Watch) {
+	value, err := w.Measure(manager)
+	l.holding[env+"/"+w.Name] = err == nil && w.Holds(value)
+}
+
+// Subscriber returns an event subscriber that checks the watches after every
+// change to manager, which belongs to the named environment
+func (l *Watchlist) Subscriber(env string, manager EmployeeManager) func(Event) {
+	l.mu.Lock()
+	l.managers[env] = manager
+	for _, w := range l.watches {
+		l.prime(env, manager, w)
+	}
+	l.mu.Unlock()
+
+	return func(ev Event) {
+		switch ev.Type {
+		case EventEmployeeAdded, EventEmployeeUpdated, EventEmployeeRemoved, EventEmployeeTransferred:
+		default:
+			return
+		}
+
+		alerts := make([]Alert, 0)
+		l.mu.Lock()
+		for _, w := range l.watches {
+			value, err := w.Measure(manager)
+			if err != nil {
+				continue
+			}
+			key := env + "/" + w.Name
+			holds, held := w.Holds(value), l.holding[key]
+			l.holding[key] = holds
+			if holds && !held {
+				alerts = append(alerts, Alert{Watch: w, Value: value, Environment: env, Cause: ev.Message, Time: ev.Time})
+			}
+		}
+		l.mu.Unlock()
+
+		for _, a := range alerts {
+			deliverAlert(a)
+		}
+	}
+}
+
+// deliverAlert sends an alert to each of its watch's targets, reporting
+// failures on the console so one broken target does not hide the others,
+// then to the users who are notified of watch alerts
+func deliverAlert(a Alert) {
+	targets := a.Watch.Notify
+	if len(targets) == 0 {
+		targets = []string{"console"}
+	}
+	for _, target := range targets {
+		notifier, err := OpenNotifier(target)
+		if err == nil {
+			err = notifier.Notify(a.Notification())
+		}
+		if err != nil {
+			fmt.Printf("%s watch %q could not notify %s: %v\n", errorText("Error:"), a.Watch.Name, target, err)
+		}
+	}
+	appNotifications.Publish(a.Notification())
+}
+
+// watchesInteractive adds, lists and removes watches, which are saved with
+// the user's preferences
+func watchesInteractive(watchlist *Watchlist, reader *bufio.Reader) error {
+	fmt.Println("\n" + headerText("=== Watches ==="))
+	fmt.Println("1. Add watch")
+	fmt.Println("2. List watches")
+	fmt.Println("3. Remove watch")
+
+	option, err := readInt(reader, "\nSelect option: ")
+	if err != nil {
+		return err
+	}
+
+	switch option {
+	case 1:
+		name, err := readString(reader, "Watch name: ")
+		if err != nil {
+			return err
+		}
+
+		prompt := "Search, such as department=Engineering (leave blank for all employees): "
+		if appPreferences.LastSearch != "" {
+			prompt = fmt.Sprintf("Search, such as department=Engineering (leave blank for the last search, %s): ", appPreferences.LastSearch)
+		}
+		query, err := readString(reader, prompt)
+		if err != nil {
+			return err
+		}
+		if query == "" {
+			query = appPreferences.LastSearch
+		}
+
+		condition, err := readValue(reader, "Condition, such as count > 50 or average_salary >= 90000: ", func(input string) (Watch, error) {
+			metric, op, threshold, err := ParseWatchCondition(input)
+			return Watch{Metric: metric, Operator: op, Threshold: threshold}, err
+		})
+		if err != nil {
+			return err
+		}
+
+		targets, err := readString(reader, "Notify (console, webhook URL, Slack webhook URL or mailto URL, comma-separated; blank for console): ")
+		if err != nil {
+			return err
+		}
+
+		w := Watch{Name: name, Query: query, Metric: condition.Metric, Operator: condition.Operator, Threshold: condition.Threshold}
+		for _, target := range strings.Split(targets, ",") {
+			if target = strings.TrimSpace(target); target != "" {
+				w.Notify = append(w.Notify, target)
+			}
+		}
+		if err := watchlist.Add(w); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Watch %q added.", w.Name)))
+
+	case 2:
+		watches := watchlist.Watches()
+		if len(watches) == 0 {
+			fmt.Println("\nNo watches defined.")
+			return nil
+		}
+		fmt.Println()
+		for _, w := range watches {
+			targets := "console"
+			if len(w.Notify) > 0 {
+				targets = strings.Join(w.Notify, ", ")
+			}
+			fmt.Printf("- %s (notify %s)\n", w, targets)
+		}
+		return nil
+
+	case 3:
+		name, err := readString(reader, "Watch name: ")
+		if err != nil {
+			return err
+		}
+		if err := watchlist.Remove(name); err != nil {
+			return err
+		}
+		fmt.Println("\n" + successText(fmt.Sprintf("Watch %q removed.", name)))
+
+	default:
+		return fmt.Errorf("%w: please select a valid option", ErrInvalidInput)
+	}
+
+	appPreferences.Watches = watchlist.Watches()
+	if err := appPreferences.Save(); err != nil {
+		fmt.Println(warningText("Warning: watches could not be saved: " + err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The configuration bundle is YAML, which people find easier to read and edit
+// than JSON, but the standard library has no YAML package. marshalYAML and
+// unmarshalYAML cover the part of YAML that configuration needs: block
+// mappings and sequences, plain, quoted and literal block scalars, empty and
+// simple flow collections, and comments. Values go through their JSON
+// encoding, so any type that encodes as JSON encodes as YAML, and mapping
+// keys keep their order.
+
+// yamlMapping is a mapping whose keys keep the order they were written in
+type yamlMapping []yamlPair
+
+// yamlPair is a key of a mapping and its value
+type yamlPair struct {
+	key   string
+	value interface{}
+}
+
+// marshalYAML returns the YAML encoding of v
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeOrderedJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	switch node := node.(type) {
+	case yamlMapping, []interface{}:
+		if isEmptyCollection(node) {
+			buf.WriteString(yamlScalar(node) + "\n")
+		} else {
+			writeYAML(&buf, node, 0)
+		}
+	default:
+		buf.WriteString(yamlScalar(node) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalYAML decodes YAML into v, as json.Unmarshal would decode the
+// same document written as JSON
+func unmarshalYAML(data []byte, v interface{}) error {
+	raw, err := yamlToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// yamlToJSON converts a YAML document to JSON, keeping the order of keys
+func yamlToJSON(data []byte) ([]byte, error) {
+	p := &yamlParser{}
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("%w: YAML line %d: indent with spaces, not tabs", ErrInvalidInput, i+1)
+		}
+		if trimmed == "---" && len(p.lines) == 0 {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: len(line) - len(trimmed), text: trimmed, raw: line})
+	}
+	p.skipBlank()
+	var node interface{}
+	if p.pos < len(p.lines) {
+		var err error
+		if node, err = p.parseNode(p.lines[p.pos].indent); err != nil {
+			return nil, err
+		}
+	}
+	if p.skipBlank(); p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		return nil, fmt.Errorf("%w: YAML line %d: unexpected indentation", ErrInvalidInput, l.number)
+	}
+	var buf bytes.Buffer
+	if err := writeOrderedJSON(&buf, node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeOrderedJSON reads a JSON value into mappings, slices and scalars,
+// keeping the order of object keys
+func decodeOrderedJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := yamlMapping{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, yamlPair{key.(string), value})
+		}
+		_, err := dec.Token()
+		return m, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
+
+// writeOrderedJSON writes a value read by decodeOrderedJSON or the YAML parser as JSON
+func writeOrderedJSON(w *bytes.Buffer, node interface{}) error {
+	switch node := node.(type) {
+	case yamlMapping:
+		w.WriteByte('{')
+		for i, pair := range node {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(quoteYAML(pair.key))
+			w.WriteByte(':')
+			if err := writeOrderedJSON(w, pair.value); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+	case []interface{}:
+		w.WriteByte('[')
+		for i, item := range node {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeOrderedJSON(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+	case string:
+		w.WriteString(quoteYAML(node))
+	default:
+		data, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+	return nil
+}
+
+// isEmptyCollection reports whether node is a mapping or sequence with nothing in it
+func isEmptyCollection(node interface{}) bool {
+	switch node := node.(type) {
+	case yamlMapping:
+		return len(node) == 0
+	case []interface{}:
+		return len(node) == 0
+	}
+	return false
+}
+
+// isCollection reports whether node is a mapping or sequence with something in it
+func isCollection(node interface{}) bool {
+	switch node.(type) {
+	case yamlMapping, []interface{}:
+		return !isEmptyCollection(node)
+	}
+	return false
+}
+
+// writeYAML writes a non-empty mapping or sequence in block style, indented
+// by indent spaces
+func writeYAML(w io.Writer, node interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch node := node.(type) {
+	case yamlMapping:
+		for _, pair := range node {
+			writeYAMLEntry(w, pad+yamlKey(pair.key)+":", pair.value, indent)
+		}
+	case []interface{}:
+		for _, item := range node {
+			if m, ok := item.(yamlMapping); ok && len(m) > 0 {
+				// The first key goes on the dash line, the rest line up under it
+				var first bytes.Buffer
+				writeYAML(&first, m[:1], indent+2)
+				fmt.Fprint(w, pad+"- "+strings.TrimPrefix(first.String(), pad+"  "))
+				writeYAML(w, m[1:], indent+2)
+				continue
+			}
+			writeYAMLEntry(w, pad+"-", item, indent)
+		}
+	}
+}
+
+// writeYAMLEntry writes a value after the key or dash that introduces it
+func writeYAMLEntry(w io.Writer, lead string, value interface{}, indent int) {
+	switch {
+	case isCollection(value):
+		fmt.Fprintln(w, lead)
+		writeYAML(w, value, indent+2)
+	case isLiteralBlock(value):
+		s := value.(string)
+		chomp := "|-"
+		if strings.HasSuffix(s, "\n") {
+			chomp, s = "|", strings.TrimSuffix(s, "\n")
+		}
+		fmt.Fprintln(w, lead+" "+chomp)
+		pad := strings.Repeat(" ", indent+2)
+		for _, line := range strings.Split(s, "\n") {
+			if line == "" {
+				fmt.Fprintln(w)
+			} else {
+				fmt.Fprintln(w, pad+line)
+			}
+		}
+	default:
+		fmt.Fprintln(w, lead+" "+yamlScalar(value))
+	}
+}
+
+// isLiteralBlock reports whether a value is a string best written as a
+// literal block: several lines that the block keeps exactly
+func isLiteralBlock(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(strings.TrimSuffix(s, "\n"), "\n") &&
+		!strings.HasPrefix(s, " ") && !strings.HasSuffix(s, "\n\n") &&
+		!strings.ContainsAny(s, "\r\t") && !strings.Contains(s, " \n")
+}
+
+// yamlKey returns a mapping key, quoted if it has to be
+func yamlKey(key string) string {
+	if plainYAML(key) {
+		return key
+	}
+	return quoteYAML(key)
+}
+
+// yamlScalar returns a scalar, or an empty collection, as YAML
+func yamlScalar(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case json.Number:
+		return value.String()
+	case string:
+		if plainYAML(value) {
+			return value
+		}
+		return quoteYAML(value)
+	case yamlMapping:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// plainYAML reports whether a string can be written without quotes and read
+// back as the same string
+func plainYAML(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return false
+		}
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off":
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err != nil
+}
+
+// quoteYAML returns a double-quoted YAML string, which uses JSON's escapes and
+// so is also a JSON string
+func quoteYAML(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// yamlLine is a line of a YAML document with its indentation split off
+type yamlLine struct {
+	number int
+	indent int
+	text   string // without the indentation
+	raw    string // as written, for literal blocks
+}
+
+// yamlParser reads block-style YAML line by line
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// errorf returns an error about the current line
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	number := 0
+	if p.pos < len(p.lines) {
+		number = p.lines[p.pos].number
+	} else if len(p.lines) > 0 {
+		number = p.lines[len(p.lines)-1].number
+	}
+	return fmt.Errorf("%w: YAML line %d: %s", ErrInvalidInput, number, fmt.Sprintf(format, args...))
+}
+
+// skipBlank moves past blank lines and comments
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) {
+		text := p.lines[p.pos].text
+		if text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// isSequenceItem reports whether a line starts an item of a sequence
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseNode parses the mapping, sequence or scalar starting at the current
+// line, which is indented by indent
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	l := p.lines[p.pos]
+	switch {
+	case isSequenceItem(l.text):
+		return p.parseSequence(indent)
+	case splitYAMLKey(l.text) >= 0:
+		return p.parseMapping(indent)
+	default:
+		p.pos++
+		return parseYAMLScalar(stripYAMLComment(l.text))
+	}
+}
+
+// parseSequence parses the items of a block sequence indented by indent
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	list := []interface{}{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent < indent || !isSequenceItem(l.text) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		rest := strings.TrimPrefix(l.text, "-")
+		content := strings.TrimLeft(rest, " ")
+		if content == "" || strings.HasPrefix(content, "#") {
+			p.pos++
+			item, err := p.parseChild(indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			continue
+		}
+		if header := stripYAMLComment(content); header == "|" || header == "|-" || header == "|+" {
+			p.pos++
+			list = append(list, p.parseLiteral(indent, header))
+			continue
+		}
+		// Read what follows the dash as if it started its own line, so a
+		// mapping begun there continues on the lines under it
+		p.lines[p.pos] = yamlLine{number: l.number, indent: indent + 1 + len(rest) - len(content), text: content, raw: l.raw}
+		item, err := p.parseNode(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// parseMapping parses the keys of a block mapping indented by indent
+func (p *yamlParser) parseMapping(indent int) (yamlMapping, error) {
+	m := yamlMapping{}
+	seen := make(map[string]bool)
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		colon := splitYAMLKey(l.text)
+		if colon < 0 {
+			if isSequenceItem(l.text) {
+				break
+			}
+			return nil, p.errorf("expected key: value")
+		}
+		key, err := parseYAMLKey(l.text[:colon])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if seen[key] {
+			return nil, p.errorf("key %q appears twice", key)
+		}
+		seen[key] = true
+
+		value := stripYAMLComment(strings.TrimSpace(l.text[colon+1:]))
+		p.pos++
+		var node interface{}
+		switch {
+		case value == "":
+			node, err = p.parseChild(indent)
+		case value == "|" || value == "|-" || value == "|+":
+			node = p.parseLiteral(indent, value)
+		default:
+			node, err = parseYAMLScalar(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, yamlPair{key, node})
+	}
+	return m, nil
+}
+
+// parseChild parses the value on the lines under a key or dash indented by
+// indent. A sequence under a key may be indented as much as the key.
+func (p *yamlParser) parseChild(indent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	l := p.lines[p.pos]
+	if l.indent > indent || (l.indent == indent && isSequenceItem(l.text)) {
+		return p.parseNode(l.indent)
+	}
+	return nil, nil
+}
+
+// parseLiteral reads the lines of a literal block scalar under a key
+// indented by indent
+func (p *yamlParser) parseLiteral(indent int, header string) string {
+	var lines []string
+	block := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if l.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if l.indent <= indent {
+			break
+		}
+		if block < 0 {
+			block = l.indent
+		}
+		if l.indent < block {
+			break
+		}
+		lines = append(lines, l.raw[block:])
+	}
+	// Trailing blank lines belong to the block only when it keeps them
+	text := strings.Join(lines, "\n")
+	trimmed := strings.TrimRight(text, "\n")
+	switch header {
+	case "|-":
+		return trimmed
+	case "|+":
+		return text + "\n"
+	default:
+		return trimmed + "\n"
+	}
+}
+
+// splitYAMLKey returns where the colon after a mapping key is, or -1 if the
+// line is not a key. Colons inside quotes, and not followed by a space or
+// the end of the line, do not count.
+func splitYAMLKey(text string) int {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return -1
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			if i == 0 || strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseYAMLKey returns a mapping key, unquoting it if it is quoted
+func parseYAMLKey(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		value, err := parseYAMLScalar(text)
+		if err != nil {
+			return "", err
+		}
+		return value.(string), nil
+	}
+	return text, nil
+}
+
+// stripYAMLComment removes a comment after a value, outside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// parseYAMLScalar parses a scalar, or a flow sequence or mapping on one line
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "\""):
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return nil, fmt.Errorf("%w: bad double-quoted string %s", ErrInvalidInput, text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("%w: unterminated single-quoted string %s", ErrInvalidInput, text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("%w: unterminated flow sequence %s", ErrInvalidInput, text)
+		}
+		list := []interface{}{}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		if strings.TrimSpace(text[1:len(text)-1]) != "" || !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("%w: only empty flow mappings {} are supported, not %s", ErrInvalidInput, text)
+		}
+		return yamlMapping{}, nil
+	}
+	switch strings.ToLower(text) {
+	case "null", "~":
+		return nil, nil
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return json.Number(text), nil
+	}
+	return text, nil
+}
+
+// splitYAMLFlow splits the items of a flow sequence at commas outside quotes
+func splitYAMLFlow(text string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return items
+}
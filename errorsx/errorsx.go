@@ -0,0 +1,54 @@
+// Package errorsx holds the kinds of error shared by the exercises. Every
+// sentinel error is of one kind, so callers can handle a whole kind with
+// errors.Is(err, errorsx.ErrNotFound) without knowing each exercise's
+// sentinels.
+package errorsx
+
+import "errors"
+
+// Kinds of error
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict")
+)
+
+// Error is a typed error of one kind. Sentinels are made with New and
+// matched with errors.Is; errors.As reaches the structured fields.
+type Error struct {
+	Kind    error       // ErrNotFound, ErrValidation or ErrConflict
+	Field   string      // the field at fault, if any
+	Value   interface{} // the offending value, set by WithValue
+	Message string
+	base    *Error // the sentinel a WithValue copy was made from
+}
+
+// New makes a sentinel error of a kind
+func New(kind error, field, message string) *Error {
+	return &Error{Kind: kind, Field: field, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether the error is of the target kind
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the sentinel a WithValue copy was made from
+func (e *Error) Unwrap() error {
+	if e.base == nil {
+		return nil
+	}
+	return e.base
+}
+
+// WithValue returns the error carrying the offending value. It still
+// matches the sentinel with errors.Is.
+func (e *Error) WithValue(value interface{}) error {
+	c := *e
+	c.Value, c.base = value, e
+	return &c
+}
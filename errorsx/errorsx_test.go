@@ -0,0 +1,22 @@
+package errorsx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithValueMatchesSentinelAndKind(t *testing.T) {
+	sentinel := New(ErrNotFound, "id", "employee not found")
+	err := sentinel.WithValue(42)
+
+	if !errors.Is(err, sentinel) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("%v does not match its sentinel and kind", err)
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Errorf("%v matches another kind", err)
+	}
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Field != "id" || typed.Value != 42 {
+		t.Errorf("errors.As gave %+v, want field id and value 42", typed)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/JoeDkhar/Go
+
+go 1.23